@@ -5,6 +5,7 @@ import (
 	"os"
 	"wx_channel/internal/app"
 	"wx_channel/internal/config"
+	"wx_channel/internal/database"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -14,6 +15,7 @@ var (
 	cfgFile string
 	port    int
 	dev     string
+	quality string
 )
 
 var rootCmd = &cobra.Command{
@@ -22,6 +24,10 @@ var rootCmd = &cobra.Command{
 	Long:  `A tool to download videos from WeChat Channels with auto-decryption and de-duplication.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		cfg := config.Load()
+		if _, err := database.ParseQualityPolicy(cfg.DownloadQuality); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 		// 应用标志到配置
 		if port != 0 {
 			cfg.SetPort(port)
@@ -50,10 +56,12 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.wx_channel/config.yaml)")
 	rootCmd.PersistentFlags().IntVarP(&port, "port", "p", 0, "Proxy server network port")
 	rootCmd.PersistentFlags().StringVarP(&dev, "dev", "d", "", "Proxy server network device")
+	rootCmd.PersistentFlags().StringVar(&quality, "quality", "", "Default download quality: original, best, smallest, <=720p or a format id")
 
 	// 绑定标志到 viper
 	_ = viper.BindPFlag("port", rootCmd.PersistentFlags().Lookup("port"))
 	_ = viper.BindPFlag("dev", rootCmd.PersistentFlags().Lookup("dev"))
+	_ = viper.BindPFlag("download_quality", rootCmd.PersistentFlags().Lookup("quality"))
 }
 
 func initConfig() {
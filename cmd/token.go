@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"wx_channel/internal/config"
+	"wx_channel/internal/database"
+	"wx_channel/internal/services"
+	"wx_channel/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	tokenScopes  string
+	tokenExpires time.Duration
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "管理控制台 API 令牌",
+	Long: `管理控制台 API 的命名令牌。令牌按权限范围访问 API：
+  read      查看记录和统计
+  download  管理下载队列、批量下载、抓取和关注列表
+  settings  修改设置和脚本规则
+  admin     全部权限，包括删除记录和管理令牌
+
+创建第一个令牌后，控制台 API 必须携带令牌（或 secret_token）访问。`,
+}
+
+var tokenCreateCmd = &cobra.Command{
+	Use:   "create [name]",
+	Short: "创建令牌（明文只显示一次）",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		service := openTokenService()
+
+		req := &services.APITokenRequest{Name: args[0], Scopes: strings.Split(tokenScopes, ",")}
+		if tokenExpires > 0 {
+			expiresAt := time.Now().Add(tokenExpires)
+			req.ExpiresAt = &expiresAt
+		}
+		token, plain, err := service.Create(req)
+		if err != nil {
+			fmt.Printf("创建令牌失败: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("已创建令牌 %s（%s）\n", token.Name, strings.Join(token.Scopes, ","))
+		if token.ExpiresAt != nil {
+			fmt.Printf("过期时间: %s\n", token.ExpiresAt.Format(time.RFC3339))
+		}
+		fmt.Printf("\n%s\n\n", plain)
+		fmt.Println("请妥善保存，令牌明文不会再次显示。请求时通过 X-Local-Auth 或 Authorization: Bearer 携带。")
+	},
+}
+
+var tokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出令牌",
+	Run: func(cmd *cobra.Command, args []string) {
+		tokens, err := openTokenService().List()
+		if err != nil {
+			fmt.Printf("读取令牌失败: %v\n", err)
+			os.Exit(1)
+		}
+		if len(tokens) == 0 {
+			fmt.Println("没有令牌")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tPREFIX\tSCOPES\tEXPIRES\tLAST USED")
+		for _, t := range tokens {
+			fmt.Fprintf(w, "%s\t%s…\t%s\t%s\t%s\n", t.Name, t.Prefix, strings.Join(t.Scopes, ","),
+				formatTokenTime(t.ExpiresAt, "never"), formatTokenTime(t.LastUsedAt, "-"))
+		}
+		w.Flush()
+	},
+}
+
+var tokenRevokeCmd = &cobra.Command{
+	Use:   "revoke [name|id]",
+	Short: "删除令牌",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := openTokenService().Revoke(args[0]); err != nil {
+			fmt.Printf("删除令牌失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("已删除令牌 %s\n", args[0])
+	},
+}
+
+// openTokenService 打开下载目录中的 records.db（与运行中的程序共用）
+func openTokenService() *services.APITokenService {
+	openRecordsDB()
+	return services.NewAPITokenService()
+}
+
+// openRecordsDB 初始化下载目录中的 records.db
+func openRecordsDB() {
+	cfg := config.Load()
+	downloadsDir, err := utils.ResolveDownloadDir(cfg.DownloadsDir)
+	if err != nil {
+		fmt.Printf("解析下载目录失败: %v\n", err)
+		os.Exit(1)
+	}
+	if err := database.Initialize(&database.Config{DBPath: filepath.Join(downloadsDir, "records.db")}); err != nil {
+		fmt.Printf("初始化数据库失败: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func formatTokenTime(t *time.Time, empty string) string {
+	if t == nil {
+		return empty
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func init() {
+	tokenCreateCmd.Flags().StringVar(&tokenScopes, "scopes", database.ScopeRead, "Comma-separated scopes: read, download, settings, admin")
+	tokenCreateCmd.Flags().DurationVar(&tokenExpires, "expires", 0, "Token lifetime, e.g. 720h (default: never expires)")
+
+	rootCmd.AddCommand(tokenCmd)
+	tokenCmd.AddCommand(tokenCreateCmd, tokenListCmd, tokenRevokeCmd)
+}
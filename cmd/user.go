@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"wx_channel/internal/database"
+	"wx_channel/internal/services"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	userScopes   string
+	userPassword string
+)
+
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "管理控制台用户",
+	Long: `管理 Web 控制台的本地用户。用户按与 API 令牌相同的权限范围访问控制台：
+  read      查看记录和统计
+  download  管理下载队列、批量下载、抓取和关注列表
+  settings  修改设置和脚本规则
+  admin     全部权限，包括删除记录、管理令牌和用户
+
+创建第一个用户后，控制台必须登录（或携带 API 令牌）访问，第一个用户总是拥有 admin 权限。`,
+}
+
+var userCreateCmd = &cobra.Command{
+	Use:   "create [username]",
+	Short: "创建用户",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		service := openUserService()
+		user, err := service.Create(&services.UserRequest{
+			Username: args[0],
+			Password: readUserPassword(),
+			Scopes:   strings.Split(userScopes, ","),
+		})
+		if err != nil {
+			fmt.Printf("创建用户失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("已创建用户 %s（%s）\n", user.Username, strings.Join(user.Scopes, ","))
+	},
+}
+
+var userListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出用户",
+	Run: func(cmd *cobra.Command, args []string) {
+		users, err := openUserService().List()
+		if err != nil {
+			fmt.Printf("读取用户失败: %v\n", err)
+			os.Exit(1)
+		}
+		if len(users) == 0 {
+			fmt.Println("没有用户")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "USERNAME\tSCOPES\tLAST LOGIN")
+		for _, u := range users {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", u.Username, strings.Join(u.Scopes, ","), formatTokenTime(u.LastLoginAt, "-"))
+		}
+		w.Flush()
+	},
+}
+
+var userPasswdCmd = &cobra.Command{
+	Use:   "passwd [username]",
+	Short: "重置用户密码（同时注销该用户的全部会话）",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if _, err := openUserService().Update(args[0], &services.UserUpdateRequest{Password: readUserPassword()}); err != nil {
+			fmt.Printf("重置密码失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("已重置用户 %s 的密码\n", args[0])
+	},
+}
+
+var userDeleteCmd = &cobra.Command{
+	Use:   "delete [username]",
+	Short: "删除用户",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if _, err := openUserService().Delete(args[0]); err != nil {
+			fmt.Printf("删除用户失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("已删除用户 %s\n", args[0])
+	},
+}
+
+// openUserService 打开下载目录中的 records.db（与运行中的程序共用）
+func openUserService() *services.UserService {
+	openRecordsDB()
+	return services.NewUserService()
+}
+
+// readUserPassword 返回 --password 参数，未指定时从标准输入读取一行
+func readUserPassword() string {
+	if userPassword != "" {
+		return userPassword
+	}
+	fmt.Print("密码: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		fmt.Printf("读取密码失败: %v\n", err)
+		os.Exit(1)
+	}
+	return strings.TrimRight(line, "\r\n")
+}
+
+func init() {
+	userCreateCmd.Flags().StringVar(&userScopes, "scopes", database.ScopeRead+","+database.ScopeDownload, "Comma-separated scopes: read, download, settings, admin")
+	for _, c := range []*cobra.Command{userCreateCmd, userPasswdCmd} {
+		c.Flags().StringVar(&userPassword, "password", "", "Password (default: read from stdin)")
+	}
+
+	rootCmd.AddCommand(userCmd)
+	userCmd.AddCommand(userCreateCmd, userListCmd, userPasswdCmd, userDeleteCmd)
+}
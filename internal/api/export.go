@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"wx_channel/internal/database"
 	"wx_channel/internal/response"
 	"wx_channel/internal/services"
 )
@@ -125,3 +126,70 @@ func (h *ExportAPI) HandleExportBrowseHistory(w http.ResponseWriter, r *http.Req
 	w.WriteHeader(http.StatusOK)
 	w.Write(result.Data)
 }
+
+// HandleExportComments 导出评论
+// GET /api/export/comments?format=csv|json&videoId=&author=
+func (h *ExportAPI) HandleExportComments(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorWithStatus(w, http.StatusMethodNotAllowed, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// 确定格式（默认 csv）
+	format := services.ExportFormat(strings.ToLower(r.URL.Query().Get("format")))
+	if format != services.ExportFormatCSV && format != services.ExportFormatJSON {
+		format = services.ExportFormatCSV
+	}
+
+	filter := &database.CommentAnalyticsFilter{
+		VideoID: r.URL.Query().Get("videoId"),
+		Author:  r.URL.Query().Get("author"),
+	}
+
+	// 执行导出
+	result, err := h.service.ExportComments(format, filter)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// 设置文件下载响应头
+	w.Header().Set("Content-Type", result.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", result.Filename))
+	w.WriteHeader(http.StatusOK)
+	w.Write(result.Data)
+}
+
+// HandleExportAudit 导出审计记录
+// GET /api/export/audit?format=csv|json&actor=&action=&targetType=&targetId=&since=&until=
+func (h *ExportAPI) HandleExportAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorWithStatus(w, http.StatusMethodNotAllowed, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// 确定格式（默认 csv）
+	format := services.ExportFormat(strings.ToLower(r.URL.Query().Get("format")))
+	if format != services.ExportFormatCSV && format != services.ExportFormatJSON {
+		format = services.ExportFormatCSV
+	}
+
+	filter, err := services.ParseAuditFilter(r.URL.Query())
+	if err != nil {
+		response.ErrorWithStatus(w, http.StatusBadRequest, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// 执行导出
+	result, err := h.service.ExportAuditLog(format, filter)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// 设置文件下载响应头
+	w.Header().Set("Content-Type", result.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", result.Filename))
+	w.WriteHeader(http.StatusOK)
+	w.Write(result.Data)
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// OpenAPISpec 本地 API 的 OpenAPI 3 文档，新增或修改路由时需同步更新（router 和 pkg/client 的测试会检查）
+//
+//go:embed openapi.json
+var OpenAPISpec []byte
+
+// OpenAPIService 提供 OpenAPI 文档
+type OpenAPIService struct{}
+
+// NewOpenAPIService 创建 OpenAPI 文档服务
+func NewOpenAPIService() *OpenAPIService {
+	return &OpenAPIService{}
+}
+
+// GetSpec 返回 OpenAPI 文档
+func (s *OpenAPIService) GetSpec(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(OpenAPISpec)
+}
+
+// RegisterRoutes 注册路由
+func (s *OpenAPIService) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/v1/openapi.json", s.GetSpec)
+}
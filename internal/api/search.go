@@ -5,9 +5,9 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
-	"time"
 
 	"wx_channel/internal/response"
+	"wx_channel/internal/services"
 	"wx_channel/internal/websocket"
 )
 
@@ -23,9 +23,12 @@ func NewSearchService(hub *websocket.Hub) *SearchService {
 
 // SearchContactRequest 搜索账号请求参数
 type SearchContactRequest struct {
-	Keyword  string `json:"keyword"`
-	Page     int    `json:"page"`
-	PageSize int    `json:"page_size"`
+	Keyword    string `json:"keyword"`
+	Type       int    `json:"type"`        // 1=账号（默认） 2=直播 3=视频
+	Cursor     string `json:"cursor"`      // 上一次返回的游标
+	NextMarker string `json:"next_marker"` // 兼容旧参数：微信原始分页标记
+	MaxPages   int    `json:"max_pages"`   // 连续获取的页数，默认 1
+	Refresh    bool   `json:"refresh"`     // 跳过缓存
 }
 
 // SearchContact 搜索账号
@@ -34,9 +37,13 @@ func (s *SearchService) SearchContact(w http.ResponseWriter, r *http.Request) {
 
 	// 支持 GET 和 POST
 	if r.Method == http.MethodGet {
-		req.Keyword = r.URL.Query().Get("keyword")
-		req.Page, _ = strconv.Atoi(r.URL.Query().Get("page"))
-		req.PageSize, _ = strconv.Atoi(r.URL.Query().Get("page_size"))
+		q := r.URL.Query()
+		req.Keyword = q.Get("keyword")
+		req.Type, _ = strconv.Atoi(q.Get("type"))
+		req.Cursor = q.Get("cursor")
+		req.NextMarker = q.Get("next_marker")
+		req.MaxPages, _ = strconv.Atoi(q.Get("max_pages"))
+		req.Refresh, _ = strconv.ParseBool(q.Get("refresh"))
 	} else if r.Method == http.MethodPost {
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			response.Error(w, 400, "Invalid request body")
@@ -53,46 +60,21 @@ func (s *SearchService) SearchContact(w http.ResponseWriter, r *http.Request) {
 		response.Error(w, 400, "keyword too long (max 100 characters)")
 		return
 	}
-
-	// 默认分页
-	if req.Page <= 0 {
-		req.Page = 1
-	}
-	if req.PageSize <= 0 || req.PageSize > 50 {
-		req.PageSize = 20
-	}
-
-	// 调用前端 API
-	body := websocket.SearchContactBody{
-		Keyword: req.Keyword,
-	}
-
-	data, err := s.hub.CallAPI("key:channels:contact_list", body, 60*time.Second)
-	if err != nil {
-		if strings.Contains(err.Error(), "no available client") {
-			response.ErrorWithStatus(w, http.StatusServiceUnavailable, http.StatusServiceUnavailable, "WeChat client not connected. Please open the target page.")
-			return
-		}
-		response.Error(w, http.StatusInternalServerError, err.Error())
-		return
+	if req.Type < 1 || req.Type > 3 {
+		req.Type = 1
 	}
 
-	// 解析返回数据以支持分页
-	var result interface{}
-	if err := json.Unmarshal(data, &result); err != nil {
-		response.Success(w, json.RawMessage(data))
-		return
-	}
-
-	response.Success(w, result)
+	query := websocket.PageQuery{Key: websocket.APIKeyContactList, Query: req.Keyword, Type: req.Type, Marker: req.NextMarker}
+	s.respondPages(w, query, req.Cursor, req.MaxPages, req.Refresh, "WeChat client not connected. Please open the target page.")
 }
 
 // GetFeedListRequest 获取视频列表请求参数
 type GetFeedListRequest struct {
 	Username   string `json:"username"`
-	NextMarker string `json:"next_marker"`
-	Page       int    `json:"page"`
-	PageSize   int    `json:"page_size"`
+	Cursor     string `json:"cursor"`      // 上一次返回的游标
+	NextMarker string `json:"next_marker"` // 兼容旧参数：微信原始分页标记
+	MaxPages   int    `json:"max_pages"`   // 连续获取的页数，默认 1
+	Refresh    bool   `json:"refresh"`     // 跳过缓存
 }
 
 // GetFeedList 获取账号的视频列表
@@ -100,10 +82,12 @@ func (s *SearchService) GetFeedList(w http.ResponseWriter, r *http.Request) {
 	var req GetFeedListRequest
 
 	if r.Method == http.MethodGet {
-		req.Username = r.URL.Query().Get("username")
-		req.NextMarker = r.URL.Query().Get("next_marker")
-		req.Page, _ = strconv.Atoi(r.URL.Query().Get("page"))
-		req.PageSize, _ = strconv.Atoi(r.URL.Query().Get("page_size"))
+		q := r.URL.Query()
+		req.Username = q.Get("username")
+		req.Cursor = q.Get("cursor")
+		req.NextMarker = q.Get("next_marker")
+		req.MaxPages, _ = strconv.Atoi(q.Get("max_pages"))
+		req.Refresh, _ = strconv.ParseBool(q.Get("refresh"))
 	} else if r.Method == http.MethodPost {
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			response.Error(w, 400, "Invalid request body")
@@ -116,37 +100,47 @@ func (s *SearchService) GetFeedList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 默认分页
-	if req.Page <= 0 {
-		req.Page = 1
-	}
-	if req.PageSize <= 0 || req.PageSize > 50 {
-		req.PageSize = 20
-	}
+	query := websocket.PageQuery{Key: websocket.APIKeyFeedList, Query: req.Username, Marker: req.NextMarker}
+	s.respondPages(w, query, req.Cursor, req.MaxPages, req.Refresh, "WeChat client not connected")
+}
 
-	// 调用前端 API
-	body := websocket.FeedListBody{
-		Username:   req.Username,
-		NextMarker: req.NextMarker,
+// respondPages 按游标获取一页或多页，返回最后一页的原始响应并附加统一的分页字段：
+// list（合并后的列表）、cursor、next_marker、has_more、pages、cached
+func (s *SearchService) respondPages(w http.ResponseWriter, query websocket.PageQuery, cursor string, maxPages int, refresh bool, notConnectedMsg string) {
+	if cursor != "" {
+		var err error
+		if query, err = query.CursorQuery(cursor); err != nil {
+			response.Error(w, 400, "invalid cursor")
+			return
+		}
+	}
+	if maxPages > websocket.MaxPagesPerRequest {
+		response.Error(w, 400, "max_pages too large (max 50)")
+		return
 	}
 
-	data, err := s.hub.CallAPI("key:channels:feed_list", body, 60*time.Second)
+	result, err := s.hub.Pager().FetchPages(query, maxPages, refresh)
 	if err != nil {
 		if strings.Contains(err.Error(), "no available client") {
-			response.ErrorWithStatus(w, http.StatusServiceUnavailable, http.StatusServiceUnavailable, "WeChat client not connected")
+			response.ErrorWithStatus(w, http.StatusServiceUnavailable, http.StatusServiceUnavailable, notConnectedMsg)
 			return
 		}
 		response.Error(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	var result interface{}
-	if err := json.Unmarshal(data, &result); err != nil {
-		response.Success(w, json.RawMessage(data))
-		return
+	data := map[string]interface{}{}
+	if err := json.Unmarshal(result.Raw, &data); err != nil {
+		data = map[string]interface{}{}
 	}
+	data["list"] = result.Items
+	data["cursor"] = result.Cursor
+	data["next_marker"] = result.NextMarker
+	data["has_more"] = result.HasMore
+	data["pages"] = result.Pages
+	data["cached"] = result.Cached
 
-	response.Success(w, result)
+	response.Success(w, data)
 }
 
 // GetFeedProfileRequest 获取视频详情请求参数
@@ -154,6 +148,7 @@ type GetFeedProfileRequest struct {
 	ObjectID string `json:"object_id"`
 	NonceID  string `json:"nonce_id"`
 	URL      string `json:"url"`
+	Fresh    bool   `json:"fresh"` // 跳过缓存
 }
 
 // GetFeedProfile 获取视频详情
@@ -164,6 +159,7 @@ func (s *SearchService) GetFeedProfile(w http.ResponseWriter, r *http.Request) {
 		req.ObjectID = r.URL.Query().Get("object_id")
 		req.NonceID = r.URL.Query().Get("nonce_id")
 		req.URL = r.URL.Query().Get("url")
+		req.Fresh = r.URL.Query().Get("fresh") == "true"
 	} else if r.Method == http.MethodPost {
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			response.Error(w, 400, "Invalid request body")
@@ -183,7 +179,7 @@ func (s *SearchService) GetFeedProfile(w http.ResponseWriter, r *http.Request) {
 		URL:      req.URL,
 	}
 
-	data, err := s.hub.CallAPI("key:channels:feed_profile", body, 60*time.Second)
+	data, cached, err := services.GetFeedProfileService(s.hub).Get(body, req.Fresh)
 	if err != nil {
 		if strings.Contains(err.Error(), "no available client") {
 			response.ErrorWithStatus(w, http.StatusServiceUnavailable, http.StatusServiceUnavailable, "WeChat client not connected")
@@ -192,6 +188,11 @@ func (s *SearchService) GetFeedProfile(w http.ResponseWriter, r *http.Request) {
 		response.Error(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	if cached {
+		w.Header().Set("X-Cache", "HIT")
+	} else {
+		w.Header().Set("X-Cache", "MISS")
+	}
 
 	var result interface{}
 	if err := json.Unmarshal(data, &result); err != nil {
@@ -204,9 +205,13 @@ func (s *SearchService) GetFeedProfile(w http.ResponseWriter, r *http.Request) {
 
 // GetStatus 获取 WebSocket 连接状态
 func (s *SearchService) GetStatus(w http.ResponseWriter, r *http.Request) {
+	capabilities := s.hub.CapabilityStatus()
 	status := map[string]interface{}{
-		"connected": s.hub.ClientCount() > 0,
-		"clients":   s.hub.ClientCount(),
+		"connected":    s.hub.ClientCount() > 0,
+		"clients":      s.hub.ClientCount(),
+		"degraded":     capabilities.Degraded,
+		"capabilities": capabilities,
+		"rate_limit":   s.hub.RateLimiter().State(),
 	}
 	response.Success(w, status)
 }
@@ -16,7 +16,6 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/qtgolang/SunnyNet/SunnyNet"
-	"github.com/qtgolang/SunnyNet/public"
 
 	"wx_channel/internal/api"
 	"wx_channel/internal/assets"
@@ -68,9 +67,8 @@ type App struct {
 	// 路由器
 	APIRouter *router.APIRouter
 
-	// 拦截器
-	requestInterceptors  []router.Interceptor
-	responseInterceptors []router.Interceptor
+	// 拦截器链
+	pipeline *router.Pipeline
 }
 
 // 全局变量，用于将 SunnyNet C 风格回调桥接到 App 方法
@@ -101,6 +99,11 @@ func NewApp(cfgParam *config.Config) *App {
 
 	// 根据配置设置负载均衡选择器
 	app.configureLoadBalancer()
+	app.WSHub.Pager().SetTTL(app.Cfg.PageCacheTTL)
+	app.configureRateLimiter()
+
+	// 注入脚本能力退化通知
+	app.configureCapabilityWebhook()
 
 	return app
 }
@@ -147,6 +150,11 @@ func (app *App) Run() {
 		if app.TranscriptionService != nil {
 			app.TranscriptionService.StopServer()
 		}
+		if app.pipeline != nil && app.pipeline.Recorder != nil {
+			if err := app.pipeline.Recorder.Flush(); err != nil {
+				utils.Warn("保存 HAR 录制文件失败: %v", err)
+			}
+		}
 		database.Close()
 		if os_env == "darwin" {
 			proxy.DisableProxyInMacOS(proxy.ProxySettings{
@@ -192,6 +200,14 @@ func (app *App) Run() {
 
 	app.printEnvConfig()
 
+	// 视频详情缓存（依赖数据库）
+	services.GetFeedProfileService(app.WSHub).SetTTL(app.Cfg.FeedProfileCacheTTL)
+
+	// 继续发送上次退出前未完成的 Webhook 推送
+	if database.GetDB() != nil {
+		services.GetWebhookService().ResumePending()
+	}
+
 	app.ConsoleAPIHandler = handlers.NewConsoleAPIHandler(app.Cfg, app.WSHub)
 	app.WebSocketHandler = handlers.NewWebSocketHandler()
 
@@ -211,7 +227,7 @@ func (app *App) Run() {
 	app.TranscriptionService = services.NewTranscriptionService()
 
 	// BatchHandler (Injecting GopeedService and TranscriptionService)
-	app.BatchHandler = handlers.NewBatchHandler(app.Cfg, app.GopeedService, app.TranscriptionService)
+	app.BatchHandler = handlers.NewBatchHandler(app.Cfg, app.WSHub, app.GopeedService, app.TranscriptionService)
 
 	// ScriptHandler
 	app.ScriptHandler = handlers.NewScriptHandler(
@@ -235,17 +251,23 @@ func (app *App) Run() {
 	)
 
 	// 初始化拦截器
-	app.requestInterceptors = []router.Interceptor{
-		app.StaticFileHandler,
-		app.APIRouter,
-		app.APIHandler,
-		app.UploadHandler,
-		app.RecordHandler,
-		app.BatchHandler,
-		app.CommentHandler,
+	app.pipeline = &router.Pipeline{
+		Request: []router.Interceptor{
+			app.StaticFileHandler,
+			app.APIRouter,
+			app.APIHandler,
+			app.UploadHandler,
+			app.RecordHandler,
+			app.BatchHandler,
+			app.CommentHandler,
+		},
+		Response: []router.Interceptor{
+			app.ScriptHandler,
+		},
 	}
-	app.responseInterceptors = []router.Interceptor{
-		app.ScriptHandler,
+	if app.Cfg.HARRecordFile != "" {
+		app.pipeline.Recorder = router.NewHARRecorder(app.Cfg.HARRecordFile, app.Cfg.HARRecordHosts, app.Cfg.HARRecordSensitiveHeaders, app.Cfg.Version)
+		utils.Info("HAR 录制已开启: %s", app.Cfg.HARRecordFile)
 	}
 
 	existing, err1 := certificate.CheckCertificate("SunnyNet")
@@ -306,6 +328,18 @@ func (app *App) Run() {
 	go app.WSHub.Run()
 	utils.Info("✓ WebSocket Hub 已启动")
 
+	// 关注列表轮询（独立运行，不依赖云端）
+	if app.Cfg.WatchEnabled {
+		services.GetWatchService(app.WSHub).Start()
+		utils.Info("✓ 关注列表新视频检查已启用")
+	}
+
+	// 回收站自动清空
+	if app.Cfg.TrashRetentionDays > 0 {
+		services.GetTrashService().Start()
+		utils.Info("✓ 回收站自动清空已启用（保留 %d 天）", app.Cfg.TrashRetentionDays)
+	}
+
 	wsPort := app.Port + 1
 	go app.startWebSocketServer(wsPort)
 	utils.Info("Web Console: http://localhost:%d/console (内网可访问)", wsPort)
@@ -381,20 +415,8 @@ func (app *App) HandleRequest(Conn *SunnyNet.HttpConn) {
 		}
 	}()
 
-	if Conn.Type == public.HttpSendRequest {
-		Conn.Request.Header.Del("Accept-Encoding")
-
-		for _, interceptor := range app.requestInterceptors {
-			if interceptor != nil && interceptor.Handle(Conn) {
-				return
-			}
-		}
-	} else if Conn.Type == public.HttpResponseOK {
-		for _, interceptor := range app.responseInterceptors {
-			if interceptor != nil && interceptor.Handle(Conn) {
-				return
-			}
-		}
+	if app.pipeline != nil {
+		app.pipeline.Handle(Conn)
 	}
 }
 
@@ -597,3 +619,28 @@ func (app *App) configureLoadBalancer() {
 
 	app.WSHub.SetSelector(selector)
 }
+
+// configureRateLimiter 根据配置设置页面 API 节流
+func (app *App) configureRateLimiter() {
+	cfg := websocket.DefaultRateLimitConfig()
+	for key, interval := range app.Cfg.APIRateIntervals {
+		cfg.Intervals[key] = interval
+	}
+	cfg.Jitter = app.Cfg.APIRateJitter
+	cfg.MaxConcurrentPerClient = app.Cfg.APIMaxConcurrentPerClient
+	if app.Cfg.APIMaxBackoff > 0 {
+		cfg.MaxBackoff = app.Cfg.APIMaxBackoff
+	}
+	app.WSHub.RateLimiter().SetConfig(cfg)
+}
+
+// configureCapabilityWebhook 注入脚本能力退化时推送 capability.regressed Webhook
+func (app *App) configureCapabilityWebhook() {
+	app.WSHub.SetCapabilityRegressionHandler(func(r websocket.CapabilityRegression) {
+		// Webhook 保存在数据库中，数据库不可用时不推送（Hub 已记录日志）
+		if database.GetDB() == nil {
+			return
+		}
+		services.GetWebhookService().Dispatch(database.WebhookEventCapabilityRegressed, r)
+	})
+}
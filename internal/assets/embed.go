@@ -51,3 +51,6 @@ var APIClientJS []byte
 
 //go:embed inject/keep_alive.js
 var KeepAliveJS []byte
+
+//go:embed inject/script_rules.json
+var ScriptRulesJSON []byte
@@ -12,12 +12,86 @@ window.__wx_api_client = {
   heartbeatTimer: null,
   lastHeartbeatTime: 0,
   missedHeartbeats: 0,
+  apiLoaded: false,
+  capabilityStage: 'connect',
+  settleDelay: 15000,
 
   // 初始化
   init: function () {
     this.connect();
     this.setupVisibilityHandler();
     this.setupBeforeUnloadHandler();
+    this.setupCapabilityHandshake();
+  },
+
+  // 设置能力握手：连接时、APILoaded 后、页面稳定后分别上报一次
+  setupCapabilityHandshake: function () {
+    var self = this;
+
+    if (window.WXE && window.WXE.onAPILoaded) {
+      window.WXE.onAPILoaded(function () {
+        self.apiLoaded = true;
+        if (self.capabilityStage === 'connect') {
+          self.capabilityStage = 'api_loaded';
+        }
+        // 等 WXU 处理完 APILoaded 再上报
+        setTimeout(function () {
+          self.reportCapabilities();
+        }, 0);
+      });
+    }
+
+    setTimeout(function () {
+      self.capabilityStage = 'settled';
+      self.reportCapabilities();
+    }, this.settleDelay);
+  },
+
+  // 收集已安装的钩子和找到的前端 API 函数
+  collectCapabilities: function () {
+    var apis = [];
+    var groups = {
+      API: window.WXU && window.WXU.API,
+      API2: window.WXU && window.WXU.API2
+    };
+    Object.keys(groups).forEach(function (group) {
+      var methods = groups[group];
+      if (!methods) return;
+      Object.keys(methods).forEach(function (name) {
+        if (typeof methods[name] === 'function') {
+          apis.push(group + '.' + name);
+        }
+      });
+    });
+
+    return {
+      stage: this.capabilityStage,
+      page: location.pathname,
+      url: location.href,
+      hooks: {
+        eventbus: !!window.WXE,
+        utils: !!window.WXU,
+        api_loaded: this.apiLoaded,
+        store: typeof window.__wx_channels_store__ === 'object',
+        comment_collector: typeof window.__wx_channels_start_comment_collection === 'function'
+      },
+      apis: apis
+    };
+  },
+
+  // 上报能力握手
+  reportCapabilities: function () {
+    if (!this.connected || !this.ws) {
+      return;
+    }
+
+    try {
+      var report = this.collectCapabilities();
+      this.ws.send(JSON.stringify({ type: 'capabilities', data: report }));
+      console.log('[API客户端] 🤝 能力握手已上报:', report.stage, report.hooks, report.apis.length + ' 个 API');
+    } catch (err) {
+      console.error('[API客户端] 上报能力握手失败:', err);
+    }
   },
 
   // 设置页面可见性监听
@@ -153,6 +227,9 @@ window.__wx_api_client = {
 
         // 启动心跳
         self.startHeartbeat();
+
+        // 上报能力握手（重连后后端需要重新获取）
+        self.reportCapabilities();
       };
 
       this.ws.onmessage = function (event) {
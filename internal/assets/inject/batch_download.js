@@ -417,6 +417,7 @@ function __export_batch_video_list__() {
       cgiId: cgiId,           // [新增] 接口ID
       url: v.url || (media && (media.url + (media.urlToken || ''))),
       key: v.key || (media && (media.decodeKey || media.decryptKey)) || '',
+      nonceId: v.nonce_id || v.objectNonceId || '',
       coverUrl: v.coverUrl || v.thumbUrl || (media && media.thumbUrl),
       duration: v.duration || (media && (media.videoPlayLen * 1000 || media.durationMs)),
       size: v.size || (media && media.fileSize),
@@ -846,6 +847,7 @@ async function __batch_download_selected__() {
         title: video.title || video.id || String(Date.now()),
         author: authorName,
         key: video.key || '',
+        nonceId: video.nonceId || '',
         resolution: resolution
       };
     });
@@ -194,6 +194,7 @@ async function __wx_channels_handle_click_download__(spec) {
     title: filename,
     author: authorName,
     key: _profile.key || '',
+    nonceId: _profile.nonce_id || '',
     forceSave: false,
     resolution: resolution,
     width: width,
@@ -9,12 +9,15 @@ import (
 	"io"
 	"math/rand"
 	"net/http"
+	"net/url"
 	"os"
 	"sync"
 	"time"
 
 	"wx_channel/internal/config"
+	"wx_channel/internal/database"
 	"wx_channel/internal/metrics"
+	"wx_channel/internal/services"
 	"wx_channel/internal/utils"
 	hubws "wx_channel/internal/websocket"
 
@@ -39,6 +42,8 @@ type Connector struct {
 	baseDelay  time.Duration // 基础延迟
 	maxDelay   time.Duration // 最大延迟
 
+	audit *services.AuditService // 记录云端下发的指令
+
 	// 性能优化
 	gzipPool      sync.Pool    // 复用 gzip.Writer
 	metricsClient *http.Client // 复用 HTTP 客户端
@@ -69,6 +74,7 @@ func NewConnector(cfg *config.Config, localHub *hubws.Hub) *Connector {
 			New: func() interface{} { return gzip.NewWriter(nil) },
 		},
 		metricsClient: &http.Client{Timeout: 5 * time.Second},
+		audit:         services.NewAuditService(),
 	}
 
 	if c.clientID == "" {
@@ -462,6 +468,16 @@ func (c *Connector) handleAPICall(reqID string, data json.RawMessage) {
 		return
 	}
 
+	c.audit.Record(&database.AuditEntry{
+		Actor:      database.AuditActorCloud,
+		ActorType:  database.AuditActorCloud,
+		Action:     "cloud.api_call",
+		TargetType: "api",
+		TargetIDs:  []string{call.Key},
+		SourceIP:   c.hubHost(),
+		Detail:     "request=" + reqID,
+	})
+
 	// 检查本地 Hub 是否可用
 	if c.local == nil {
 		utils.LogError("本地 Hub 未初始化")
@@ -481,7 +497,14 @@ func (c *Connector) handleAPICall(reqID string, data json.RawMessage) {
 		timeout = 3 * time.Minute // 搜索操作
 	}
 
-	respData, err := c.local.CallAPI(call.Key, call.Body, timeout)
+	// 视频详情走数据库缓存，其余通过分页缓存调用，遍历账号视频列表时不会重复请求微信
+	var respData json.RawMessage
+	var err error
+	if call.Key == hubws.APIKeyFeedProfile {
+		respData, err = c.callFeedProfile(call.Body, timeout)
+	} else {
+		respData, err = c.local.Pager().CallAPI(call.Key, call.Body, timeout)
+	}
 	if err != nil {
 		utils.LogError("API 调用失败: %v", err)
 
@@ -505,6 +528,29 @@ func (c *Connector) handleAPICall(reqID string, data json.RawMessage) {
 	c.sendResponse(reqID, true, respData, "")
 }
 
+// hubHost 返回云端 Hub 的主机地址，作为审计记录的来源
+func (c *Connector) hubHost() string {
+	u, err := url.Parse(c.cfg.CloudHubURL)
+	if err != nil {
+		return c.cfg.CloudHubURL
+	}
+	return u.Hostname()
+}
+
+// callFeedProfile 获取视频详情，请求体中 fresh=true 时跳过缓存
+func (c *Connector) callFeedProfile(body json.RawMessage, timeout time.Duration) (json.RawMessage, error) {
+	var req struct {
+		hubws.FeedProfileBody
+		Fresh bool `json:"fresh"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil || (req.ObjectID == "" && req.URL == "") {
+		// 其他字段写法（object_id 等）由注入脚本解析，不经过缓存
+		return c.local.Pager().CallAPI(hubws.APIKeyFeedProfile, body, timeout)
+	}
+	data, _, err := services.GetFeedProfileService(c.local).Get(req.FeedProfileBody, req.Fresh)
+	return data, err
+}
+
 // transformSearchResponse 转换搜索响应数据为统一格式
 func (c *Connector) transformSearchResponse(requestBody, responseData json.RawMessage) json.RawMessage {
 	// 解析请求参数，获取 type
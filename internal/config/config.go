@@ -53,6 +53,7 @@ type Config struct {
 	DownloadRetryCount     int           `mapstructure:"download_retry_count"`
 	DownloadResumeEnabled  bool          `mapstructure:"download_resume_enabled"`
 	DownloadTimeout        time.Duration `mapstructure:"download_timeout"`
+	DownloadQuality        string        `mapstructure:"download_quality"` // 默认画质策略，为空时使用控制台设置
 
 	// 日志配置
 	LogFile      string `mapstructure:"log_file"`
@@ -66,6 +67,33 @@ type Config struct {
 	// UI 功能开关
 	ShowLogButton bool `mapstructure:"show_log_button"`
 
+	// 脚本补丁规则文件（不存在时使用内置规则，修改后自动重新加载）
+	ScriptRulesFile string `mapstructure:"script_rules_file"`
+
+	// HAR 录制（用于离线回放与回归测试，文件路径为空时不录制）
+	HARRecordFile  string   `mapstructure:"har_record_file"`
+	HARRecordHosts []string `mapstructure:"har_record_hosts"`
+	// 录制 Cookie、Authorization 等敏感头部的原始值（默认记录为 [redacted]）
+	HARRecordSensitiveHeaders bool `mapstructure:"har_record_sensitive_headers"`
+
+	// 搜索 / 视频列表分页缓存有效期（0 表示不缓存）
+	PageCacheTTL time.Duration `mapstructure:"page_cache_ttl"`
+
+	// 视频详情（feed_profile）数据库缓存有效期（0 表示不缓存）
+	FeedProfileCacheTTL time.Duration `mapstructure:"feed_profile_cache_ttl"`
+
+	// 页面 API 节流：每个接口的最小调用间隔、随机抖动、每个页面的并发上限和出错退避上限
+	APIRateIntervals          map[string]time.Duration `mapstructure:"api_rate_intervals"`
+	APIRateJitter             time.Duration            `mapstructure:"api_rate_jitter"`
+	APIMaxConcurrentPerClient int                      `mapstructure:"api_max_concurrent_per_client"`
+	APIMaxBackoff             time.Duration            `mapstructure:"api_max_backoff"`
+
+	// 关注列表：定期检查关注作者的新视频（仅在视频号页面连接时）
+	WatchEnabled bool `mapstructure:"watch_enabled"`
+
+	// 回收站：删除的记录和文件保留的天数，超过后永久删除（0 表示不自动清空）
+	TrashRetentionDays int `mapstructure:"trash_retention_days"`
+
 	// 云端管理配置
 	CloudEnabled bool   `mapstructure:"cloud_enabled"` // 是否启用云端管理功能
 	CloudHubURL  string `mapstructure:"cloud_hub_url"` // 中央服务器地址 (e.g., ws://hub.example.com/ws/client)
@@ -194,7 +222,7 @@ func setDefaults() {
 	viper.SetDefault("cert_install_delay", 3*time.Second)
 	viper.SetDefault("save_delay", 500*time.Millisecond)
 
-	viper.SetDefault("web_console_token", "@dongzuren")
+	viper.SetDefault("web_console_token", "")
 
 	viper.SetDefault("upload_chunk_concurrency", 4)
 	viper.SetDefault("upload_merge_concurrency", 1)
@@ -203,6 +231,7 @@ func setDefaults() {
 	viper.SetDefault("download_retry_count", 3)
 	viper.SetDefault("download_resume_enabled", true)
 	viper.SetDefault("download_timeout", 30*time.Minute)
+	viper.SetDefault("download_quality", "")
 
 	viper.SetDefault("log_file", "logs/wx_channel.log")
 	viper.SetDefault("max_log_size_mb", 5)
@@ -211,6 +240,22 @@ func setDefaults() {
 	viper.SetDefault("save_search_data", false)
 	viper.SetDefault("save_page_js", false)
 	viper.SetDefault("show_log_button", false)
+	viper.SetDefault("script_rules_file", "script_rules.json")
+	viper.SetDefault("har_record_file", "")
+	viper.SetDefault("har_record_hosts", []string{"channels.weixin.qq.com", "res.wx.qq.com"})
+	viper.SetDefault("har_record_sensitive_headers", false)
+	viper.SetDefault("page_cache_ttl", 5*time.Minute)
+	viper.SetDefault("feed_profile_cache_ttl", 30*time.Minute)
+	viper.SetDefault("api_rate_intervals", map[string]interface{}{
+		"key:channels:contact_list": "2s",
+		"key:channels:feed_list":    "2s",
+		"key:channels:feed_profile": "1s",
+	})
+	viper.SetDefault("api_rate_jitter", 500*time.Millisecond)
+	viper.SetDefault("api_max_concurrent_per_client", 2)
+	viper.SetDefault("api_max_backoff", time.Minute)
+	viper.SetDefault("watch_enabled", true)
+	viper.SetDefault("trash_retention_days", 30)
 
 	viper.SetDefault("cloud_enabled", false) // 默认不启用云端管理
 	viper.SetDefault("cloud_hub_url", "ws://wx.dujulaoren.com/ws/client")
@@ -458,6 +503,11 @@ func loadFromDatabase(config *Config) {
 	}
 }
 
+// Current 返回已加载的全局配置，尚未加载时返回 nil，不会读取或创建配置文件
+func Current() *Config {
+	return globalConfig
+}
+
 // Get 获取全局配置
 func Get() *Config {
 	if globalConfig == nil {
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// APITokenRepository 处理 API 令牌的数据库操作
+type APITokenRepository struct {
+	db *sql.DB
+}
+
+// NewAPITokenRepository 创建一个新的 APITokenRepository
+func NewAPITokenRepository() *APITokenRepository {
+	return &APITokenRepository{db: GetDB()}
+}
+
+// apiTokenColumns 是 APIToken 对应的查询列
+const apiTokenColumns = `id, name, prefix, scopes, expires_at, last_used_at, created_at`
+
+// Create 保存新令牌，tokenHash 为令牌明文的 SHA-256
+func (r *APITokenRepository) Create(token *APIToken, tokenHash string) error {
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+	_, err := r.db.Exec(`
+		INSERT INTO api_tokens (id, name, token_hash, prefix, scopes, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		token.ID, token.Name, tokenHash, token.Prefix, strings.Join(token.Scopes, ","), token.ExpiresAt, token.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create api token: %w", err)
+	}
+	return nil
+}
+
+// GetByHash 按令牌哈希查找，不存在时返回 nil
+func (r *APITokenRepository) GetByHash(tokenHash string) (*APIToken, error) {
+	row := r.db.QueryRow(`SELECT `+apiTokenColumns+` FROM api_tokens WHERE token_hash = ?`, tokenHash)
+	token, err := scanAPIToken(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return token, err
+}
+
+// GetByName 按名称查找，不存在时返回 nil
+func (r *APITokenRepository) GetByName(name string) (*APIToken, error) {
+	row := r.db.QueryRow(`SELECT `+apiTokenColumns+` FROM api_tokens WHERE name = ?`, name)
+	token, err := scanAPIToken(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return token, err
+}
+
+// List 按创建时间列出全部令牌
+func (r *APITokenRepository) List() ([]APIToken, error) {
+	rows, err := r.db.Query(`SELECT ` + apiTokenColumns + ` FROM api_tokens ORDER BY created_at ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []APIToken{}
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *token)
+	}
+	return tokens, rows.Err()
+}
+
+// Exists 判断是否创建过令牌
+func (r *APITokenRepository) Exists() (bool, error) {
+	var exists bool
+	if err := r.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM api_tokens)`).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check api tokens: %w", err)
+	}
+	return exists, nil
+}
+
+// Delete 按 ID 或名称删除令牌，返回删除的数量
+func (r *APITokenRepository) Delete(idOrName string) (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM api_tokens WHERE id = ? OR name = ?`, idOrName, idOrName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete api token: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// TouchLastUsed 更新令牌的最近使用时间
+func (r *APITokenRepository) TouchLastUsed(id string, at time.Time) error {
+	if _, err := r.db.Exec(`UPDATE api_tokens SET last_used_at = ? WHERE id = ?`, at, id); err != nil {
+		return fmt.Errorf("failed to update api token last used: %w", err)
+	}
+	return nil
+}
+
+// scanAPIToken 扫描一行令牌数据
+func scanAPIToken(row rowScanner) (*APIToken, error) {
+	token := &APIToken{}
+	var scopes string
+	var expiresAt, lastUsedAt sql.NullTime
+	err := row.Scan(&token.ID, &token.Name, &token.Prefix, &scopes, &expiresAt, &lastUsedAt, &token.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan api token: %w", err)
+	}
+	token.Scopes = strings.Split(scopes, ",")
+	if expiresAt.Valid {
+		token.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		token.LastUsedAt = &lastUsedAt.Time
+	}
+	return token, nil
+}
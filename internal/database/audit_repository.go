@@ -0,0 +1,156 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AuditRepository 处理审计记录的数据库操作。audit_log 由触发器保证只能追加
+type AuditRepository struct {
+	db *sql.DB
+}
+
+// NewAuditRepository 创建一个新的 AuditRepository
+func NewAuditRepository() *AuditRepository {
+	return &AuditRepository{db: GetDB()}
+}
+
+// auditColumns 是 AuditEntry 对应的查询列
+const auditColumns = `id, created_at, actor, actor_type, action, target_type, target_ids,
+	before_value, after_value, source_ip, detail`
+
+// Append 追加一条审计记录
+func (r *AuditRepository) Append(entry *AuditEntry) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	result, err := r.db.Exec(`
+		INSERT INTO audit_log (created_at, actor, actor_type, action, target_type, target_ids,
+			before_value, after_value, source_ip, detail)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.CreatedAt, entry.Actor, entry.ActorType, entry.Action, entry.TargetType, strings.Join(entry.TargetIDs, ","),
+		string(entry.Before), string(entry.After), entry.SourceIP, entry.Detail,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to append audit entry: %w", err)
+	}
+	entry.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// List 按时间倒序分页查询审计记录
+func (r *AuditRepository) List(filter *AuditFilter, params *PaginationParams) (*PagedResult[AuditEntry], error) {
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize < 1 {
+		params.PageSize = 20
+	}
+	if params.PageSize > 100 {
+		params.PageSize = 100
+	}
+
+	where, args := auditFilterClause(filter)
+	var total int64
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM audit_log "+where, args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count audit entries: %w", err)
+	}
+
+	rows, err := r.db.Query("SELECT "+auditColumns+" FROM audit_log "+where+" ORDER BY id DESC LIMIT ? OFFSET ?",
+		append(args, params.PageSize, (params.Page-1)*params.PageSize)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries, err := scanAuditEntries(rows)
+	if err != nil {
+		return nil, err
+	}
+	return NewPagedResult(entries, total, params.Page, params.PageSize), nil
+}
+
+// ListForExport 按时间顺序获取符合条件的全部审计记录
+func (r *AuditRepository) ListForExport(filter *AuditFilter) ([]AuditEntry, error) {
+	where, args := auditFilterClause(filter)
+	rows, err := r.db.Query("SELECT "+auditColumns+" FROM audit_log "+where+" ORDER BY id ASC", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries for export: %w", err)
+	}
+	defer rows.Close()
+	return scanAuditEntries(rows)
+}
+
+// auditFilterClause 根据过滤条件构建 WHERE 子句
+func auditFilterClause(filter *AuditFilter) (string, []interface{}) {
+	if filter == nil {
+		return "", nil
+	}
+	var conditions []string
+	var args []interface{}
+	if filter.Actor != "" {
+		conditions = append(conditions, "actor = ?")
+		args = append(args, filter.Actor)
+	}
+	if filter.ActorType != "" {
+		conditions = append(conditions, "actor_type = ?")
+		args = append(args, filter.ActorType)
+	}
+	if strings.HasSuffix(filter.Action, ".") {
+		conditions = append(conditions, "action LIKE ?")
+		args = append(args, filter.Action+"%")
+	} else if filter.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.TargetType != "" {
+		conditions = append(conditions, "target_type = ?")
+		args = append(args, filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		conditions = append(conditions, "(',' || target_ids || ',') LIKE ?")
+		args = append(args, "%,"+filter.TargetID+",%")
+	}
+	if filter.Since != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, *filter.Since)
+	}
+	if filter.Until != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, *filter.Until)
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// scanAuditEntries 扫描审计记录结果集
+func scanAuditEntries(rows *sql.Rows) ([]AuditEntry, error) {
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		var targetIDs, before, after string
+		if err := rows.Scan(
+			&e.ID, &e.CreatedAt, &e.Actor, &e.ActorType, &e.Action, &e.TargetType, &targetIDs,
+			&before, &after, &e.SourceIP, &e.Detail,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		e.TargetIDs = []string{}
+		if targetIDs != "" {
+			e.TargetIDs = strings.Split(targetIDs, ",")
+		}
+		if before != "" {
+			e.Before = json.RawMessage(before)
+		}
+		if after != "" {
+			e.After = json.RawMessage(after)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
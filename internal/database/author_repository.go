@@ -0,0 +1,159 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AuthorSortColumns 作者列表可用的排序列
+var AuthorSortColumns = map[string]bool{
+	"name": true, "browse_count": true, "download_count": true,
+	"downloaded_size": true, "last_browse_time": true, "last_download_time": true,
+}
+
+// authorStatsQuery 按作者名称汇总浏览记录和下载记录（不含回收站中的记录），
+// 最近时间以 julianday 比较，避免不同时区的时间字符串按字典序比较出错
+const authorStatsQuery = `
+	SELECT author AS name, COALESCE(MAX(author_id), '') AS author_id,
+		SUM(browse_count) AS browse_count, SUM(download_count) AS download_count,
+		SUM(downloaded_size) AS downloaded_size,
+		MAX(last_browse) AS last_browse_time, MAX(last_download) AS last_download_time
+	FROM (
+		SELECT author, NULLIF(MAX(author_id), '') AS author_id, COUNT(*) AS browse_count,
+			0 AS download_count, 0 AS downloaded_size,
+			MAX(julianday(browse_time)) AS last_browse, NULL AS last_download
+		FROM browse_history
+		WHERE deleted_at IS NULL AND author != ''
+		GROUP BY author
+		UNION ALL
+		SELECT author, NULL, 0, COUNT(*), COALESCE(SUM(file_size), 0),
+			NULL, MAX(julianday(download_time))
+		FROM download_records
+		WHERE deleted_at IS NULL AND author != ''
+		GROUP BY author
+	)
+	GROUP BY author`
+
+// AuthorRepository 处理作者汇总查询，数据来自 browse_history 和 download_records
+type AuthorRepository struct {
+	db *sql.DB
+}
+
+// NewAuthorRepository 创建新的作者仓库
+func NewAuthorRepository() *AuthorRepository {
+	return &AuthorRepository{db: GetDB()}
+}
+
+// List 获取分页和排序的作者汇总
+func (r *AuthorRepository) List(params *AuthorFilterParams) (*PagedResult[AuthorStats], error) {
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize < 1 {
+		params.PageSize = 20
+	}
+	if params.PageSize > 100 {
+		params.PageSize = 100
+	}
+	if !AuthorSortColumns[params.SortBy] {
+		params.SortBy = "last_browse_time"
+	}
+
+	var conditions []string
+	var args []interface{}
+	if params.Query != "" {
+		conditions = append(conditions, "name LIKE ?")
+		args = append(args, "%"+params.Query+"%")
+	}
+	if len(params.Names) > 0 {
+		placeholders := make([]string, len(params.Names))
+		for i, name := range params.Names {
+			placeholders[i] = "?"
+			args = append(args, name)
+		}
+		conditions = append(conditions, fmt.Sprintf("name IN (%s)", strings.Join(placeholders, ",")))
+	}
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int64
+	err := r.db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM (%s) %s", authorStatsQuery, whereClause), args...).Scan(&total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count authors: %w", err)
+	}
+
+	sortOrder := "DESC"
+	if !params.SortDesc {
+		sortOrder = "ASC"
+	}
+	offset := (params.Page - 1) * params.PageSize
+
+	query := fmt.Sprintf(`
+		SELECT name, author_id, browse_count, download_count, downloaded_size,
+			strftime('%%Y-%%m-%%dT%%H:%%M:%%SZ', last_browse_time),
+			strftime('%%Y-%%m-%%dT%%H:%%M:%%SZ', last_download_time)
+		FROM (%s)
+		%s
+		ORDER BY %s %s, name
+		LIMIT ? OFFSET ?
+	`, authorStatsQuery, whereClause, params.SortBy, sortOrder)
+
+	rows, err := r.db.Query(query, append(args, params.PageSize, offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list authors: %w", err)
+	}
+	defer rows.Close()
+
+	authors := []AuthorStats{}
+	for rows.Next() {
+		var a AuthorStats
+		var lastBrowse, lastDownload sql.NullString
+		err := rows.Scan(&a.Name, &a.AuthorID, &a.BrowseCount, &a.DownloadCount, &a.DownloadedSize, &lastBrowse, &lastDownload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan author: %w", err)
+		}
+		a.LastBrowseTime = parseUTCTime(lastBrowse)
+		a.LastDownloadTime = parseUTCTime(lastDownload)
+		authors = append(authors, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list authors: %w", err)
+	}
+
+	return NewPagedResult(authors, total, params.Page, params.PageSize), nil
+}
+
+// GetByNames 获取这些作者的汇总，不存在的作者不返回
+func (r *AuthorRepository) GetByNames(names []string) ([]AuthorStats, error) {
+	authors := []AuthorStats{}
+	// List 每页最多 100 条，分批查询
+	for start := 0; start < len(names); start += 100 {
+		end := min(start+100, len(names))
+		result, err := r.List(&AuthorFilterParams{
+			PaginationParams: PaginationParams{PageSize: end - start, SortBy: "name"},
+			Names:            names[start:end],
+		})
+		if err != nil {
+			return nil, err
+		}
+		authors = append(authors, result.Items...)
+	}
+	return authors, nil
+}
+
+// parseUTCTime 解析 strftime 输出的 UTC 时间，转换为本地时间
+func parseUTCTime(s sql.NullString) *time.Time {
+	if !s.Valid {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, s.String)
+	if err != nil {
+		return nil
+	}
+	t = t.Local()
+	return &t
+}
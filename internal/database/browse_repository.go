@@ -7,6 +7,12 @@ import (
 	"time"
 )
 
+// BrowseSortColumns 浏览记录列表可用的排序列
+var BrowseSortColumns = map[string]bool{
+	"browse_time": true, "title": true, "author": true,
+	"duration": true, "size": true, "created_at": true,
+}
+
 // BrowseHistoryRepository 处理浏览历史数据库操作
 type BrowseHistoryRepository struct {
 	db *sql.DB
@@ -23,18 +29,23 @@ func (r *BrowseHistoryRepository) Create(record *BrowseRecord) error {
 	record.CreatedAt = now
 	record.UpdatedAt = now
 
+	// 重新浏览回收站中的视频时，以新记录替换回收站中的旧记录
+	if _, err := r.db.Exec("DELETE FROM browse_history WHERE id = ? AND deleted_at IS NOT NULL", record.ID); err != nil {
+		return fmt.Errorf("failed to replace trashed browse record: %w", err)
+	}
+
 	query := `
 		INSERT INTO browse_history (
 			id, title, author, author_id, duration, size, resolution, cover_url, video_url,
 			decrypt_key, browse_time, like_count, comment_count, fav_count, forward_count, page_url,
-			created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			nonce_id, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	_, err := r.db.Exec(query,
 		record.ID, record.Title, record.Author, record.AuthorID,
 		record.Duration, record.Size, record.Resolution, record.CoverURL, record.VideoURL,
 		record.DecryptKey, record.BrowseTime, record.LikeCount, record.CommentCount,
-		record.FavCount, record.ForwardCount, record.PageURL, record.CreatedAt, record.UpdatedAt,
+		record.FavCount, record.ForwardCount, record.PageURL, record.NonceID, record.CreatedAt, record.UpdatedAt,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create browse record: %w", err)
@@ -48,15 +59,15 @@ func (r *BrowseHistoryRepository) GetByID(id string) (*BrowseRecord, error) {
 		SELECT id, title, author, author_id, duration, size, COALESCE(resolution, '') as resolution, cover_url, video_url,
 			decrypt_key, browse_time, like_count, comment_count, 
 			COALESCE(fav_count, 0) as fav_count, COALESCE(forward_count, 0) as forward_count, page_url,
-			created_at, updated_at
-		FROM browse_history WHERE id = ?
+			COALESCE(nonce_id, '') as nonce_id, created_at, updated_at
+		FROM browse_history WHERE id = ? AND deleted_at IS NULL
 	`
 	record := &BrowseRecord{}
 	err := r.db.QueryRow(query, id).Scan(
 		&record.ID, &record.Title, &record.Author, &record.AuthorID,
 		&record.Duration, &record.Size, &record.Resolution, &record.CoverURL, &record.VideoURL,
 		&record.DecryptKey, &record.BrowseTime, &record.LikeCount, &record.CommentCount,
-		&record.FavCount, &record.ForwardCount, &record.PageURL, &record.CreatedAt, &record.UpdatedAt,
+		&record.FavCount, &record.ForwardCount, &record.PageURL, &record.NonceID, &record.CreatedAt, &record.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -75,14 +86,14 @@ func (r *BrowseHistoryRepository) Update(record *BrowseRecord) error {
 		UPDATE browse_history SET
 			title = ?, author = ?, author_id = ?, duration = ?, size = ?, resolution = ?,
 			cover_url = ?, video_url = ?, decrypt_key = ?, browse_time = ?, like_count = ?,
-			comment_count = ?, fav_count = ?, forward_count = ?, page_url = ?, updated_at = ?
+			comment_count = ?, fav_count = ?, forward_count = ?, page_url = ?, nonce_id = ?, updated_at = ?
 		WHERE id = ?
 	`
 	result, err := r.db.Exec(query,
 		record.Title, record.Author, record.AuthorID, record.Duration,
 		record.Size, record.Resolution, record.CoverURL, record.VideoURL, record.DecryptKey, record.BrowseTime,
 		record.LikeCount, record.CommentCount, record.FavCount, record.ForwardCount,
-		record.PageURL, record.UpdatedAt, record.ID,
+		record.PageURL, record.NonceID, record.UpdatedAt, record.ID,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update browse record: %w", err)
@@ -94,10 +105,10 @@ func (r *BrowseHistoryRepository) Update(record *BrowseRecord) error {
 	return nil
 }
 
-// Delete 根据 ID 删除浏览记录
+// Delete 根据 ID 将浏览记录移入回收站
 func (r *BrowseHistoryRepository) Delete(id string) error {
-	query := "DELETE FROM browse_history WHERE id = ?"
-	result, err := r.db.Exec(query, id)
+	query := "UPDATE browse_history SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL"
+	result, err := r.db.Exec(query, time.Now(), id)
 	if err != nil {
 		return fmt.Errorf("failed to delete browse record: %w", err)
 	}
@@ -108,20 +119,20 @@ func (r *BrowseHistoryRepository) Delete(id string) error {
 	return nil
 }
 
-// DeleteMany 根据 ID 删除多条浏览记录
+// DeleteMany 根据 ID 将多条浏览记录移入回收站
 func (r *BrowseHistoryRepository) DeleteMany(ids []string) (int64, error) {
 	if len(ids) == 0 {
 		return 0, nil
 	}
 
 	placeholders := make([]string, len(ids))
-	args := make([]interface{}, len(ids))
+	args := []interface{}{time.Now()}
 	for i, id := range ids {
 		placeholders[i] = "?"
-		args[i] = id
+		args = append(args, id)
 	}
 
-	query := fmt.Sprintf("DELETE FROM browse_history WHERE id IN (%s)", strings.Join(placeholders, ","))
+	query := fmt.Sprintf("UPDATE browse_history SET deleted_at = ? WHERE id IN (%s) AND deleted_at IS NULL", strings.Join(placeholders, ","))
 	result, err := r.db.Exec(query, args...)
 	if err != nil {
 		return 0, fmt.Errorf("failed to delete browse records: %w", err)
@@ -129,9 +140,9 @@ func (r *BrowseHistoryRepository) DeleteMany(ids []string) (int64, error) {
 	return result.RowsAffected()
 }
 
-// Clear 删除所有浏览记录
+// Clear 将所有浏览记录移入回收站
 func (r *BrowseHistoryRepository) Clear() error {
-	_, err := r.db.Exec("DELETE FROM browse_history")
+	_, err := r.db.Exec("UPDATE browse_history SET deleted_at = ? WHERE deleted_at IS NULL", time.Now())
 	if err != nil {
 		return fmt.Errorf("failed to clear browse history: %w", err)
 	}
@@ -155,17 +166,13 @@ func (r *BrowseHistoryRepository) List(params *PaginationParams) (*PagedResult[B
 	}
 
 	// Validate sort column
-	validColumns := map[string]bool{
-		"browse_time": true, "title": true, "author": true,
-		"duration": true, "size": true, "created_at": true,
-	}
-	if !validColumns[params.SortBy] {
+	if !BrowseSortColumns[params.SortBy] {
 		params.SortBy = "browse_time"
 	}
 
 	// Count total
 	var total int64
-	err := r.db.QueryRow("SELECT COUNT(*) FROM browse_history").Scan(&total)
+	err := r.db.QueryRow("SELECT COUNT(*) FROM browse_history WHERE deleted_at IS NULL").Scan(&total)
 	if err != nil {
 		return nil, fmt.Errorf("failed to count browse records: %w", err)
 	}
@@ -181,8 +188,9 @@ func (r *BrowseHistoryRepository) List(params *PaginationParams) (*PagedResult[B
 		SELECT id, title, author, author_id, duration, size, COALESCE(resolution, '') as resolution, cover_url, video_url,
 			decrypt_key, browse_time, like_count, comment_count, 
 			COALESCE(fav_count, 0) as fav_count, COALESCE(forward_count, 0) as forward_count, page_url,
-			created_at, updated_at
+			COALESCE(nonce_id, '') as nonce_id, created_at, updated_at
 		FROM browse_history
+		WHERE deleted_at IS NULL
 		ORDER BY %s %s
 		LIMIT ? OFFSET ?
 	`, params.SortBy, sortOrder)
@@ -200,7 +208,7 @@ func (r *BrowseHistoryRepository) List(params *PaginationParams) (*PagedResult[B
 			&record.ID, &record.Title, &record.Author, &record.AuthorID,
 			&record.Duration, &record.Size, &record.Resolution, &record.CoverURL, &record.VideoURL,
 			&record.DecryptKey, &record.BrowseTime, &record.LikeCount, &record.CommentCount,
-			&record.FavCount, &record.ForwardCount, &record.PageURL, &record.CreatedAt, &record.UpdatedAt,
+			&record.FavCount, &record.ForwardCount, &record.PageURL, &record.NonceID, &record.CreatedAt, &record.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan browse record: %w", err)
@@ -233,7 +241,7 @@ func (r *BrowseHistoryRepository) Search(query string, params *PaginationParams)
 	// Count total
 	var total int64
 	err := r.db.QueryRow(
-		"SELECT COUNT(*) FROM browse_history WHERE title LIKE ? OR author LIKE ?",
+		"SELECT COUNT(*) FROM browse_history WHERE (title LIKE ? OR author LIKE ?) AND deleted_at IS NULL",
 		searchPattern, searchPattern,
 	).Scan(&total)
 	if err != nil {
@@ -246,9 +254,9 @@ func (r *BrowseHistoryRepository) Search(query string, params *PaginationParams)
 		SELECT id, title, author, author_id, duration, size, COALESCE(resolution, '') as resolution, cover_url, video_url,
 			decrypt_key, browse_time, like_count, comment_count, 
 			COALESCE(fav_count, 0) as fav_count, COALESCE(forward_count, 0) as forward_count, page_url,
-			created_at, updated_at
+			COALESCE(nonce_id, '') as nonce_id, created_at, updated_at
 		FROM browse_history
-		WHERE title LIKE ? OR author LIKE ?
+		WHERE (title LIKE ? OR author LIKE ?) AND deleted_at IS NULL
 		ORDER BY browse_time DESC
 		LIMIT ? OFFSET ?
 	`
@@ -266,7 +274,7 @@ func (r *BrowseHistoryRepository) Search(query string, params *PaginationParams)
 			&record.ID, &record.Title, &record.Author, &record.AuthorID,
 			&record.Duration, &record.Size, &record.Resolution, &record.CoverURL, &record.VideoURL,
 			&record.DecryptKey, &record.BrowseTime, &record.LikeCount, &record.CommentCount,
-			&record.FavCount, &record.ForwardCount, &record.PageURL, &record.CreatedAt, &record.UpdatedAt,
+			&record.FavCount, &record.ForwardCount, &record.PageURL, &record.NonceID, &record.CreatedAt, &record.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan browse record: %w", err)
@@ -281,10 +289,94 @@ func (r *BrowseHistoryRepository) Search(query string, params *PaginationParams)
 	return NewPagedResult(records, total, params.Page, params.PageSize), nil
 }
 
+// ListFiltered 获取按时间范围、关键词和作者过滤的浏览记录，时间范围作用于 browse_time
+func (r *BrowseHistoryRepository) ListFiltered(params *FilterParams) (*PagedResult[BrowseRecord], error) {
+	// Set defaults
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize < 1 {
+		params.PageSize = 20
+	}
+	if params.PageSize > 100 {
+		params.PageSize = 100
+	}
+	if !BrowseSortColumns[params.SortBy] {
+		params.SortBy = "browse_time"
+	}
+
+	conditions := []string{"deleted_at IS NULL"}
+	var args []interface{}
+	if params.StartDate != nil {
+		conditions = append(conditions, "browse_time >= ?")
+		args = append(args, *params.StartDate)
+	}
+	if params.EndDate != nil {
+		conditions = append(conditions, "browse_time <= ?")
+		args = append(args, *params.EndDate)
+	}
+	if params.Query != "" {
+		conditions = append(conditions, "(title LIKE ? OR author LIKE ?)")
+		searchPattern := "%" + params.Query + "%"
+		args = append(args, searchPattern, searchPattern)
+	}
+	if params.Author != "" {
+		conditions = append(conditions, "author = ?")
+		args = append(args, params.Author)
+	}
+	whereClause := "WHERE " + strings.Join(conditions, " AND ")
+
+	var total int64
+	err := r.db.QueryRow("SELECT COUNT(*) FROM browse_history "+whereClause, args...).Scan(&total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count browse records: %w", err)
+	}
+
+	sortOrder := "DESC"
+	if !params.SortDesc {
+		sortOrder = "ASC"
+	}
+	offset := (params.Page - 1) * params.PageSize
+
+	query := fmt.Sprintf(`
+		SELECT id, title, author, author_id, duration, size, COALESCE(resolution, '') as resolution, cover_url, video_url,
+			decrypt_key, browse_time, like_count, comment_count,
+			COALESCE(fav_count, 0) as fav_count, COALESCE(forward_count, 0) as forward_count, page_url,
+			COALESCE(nonce_id, '') as nonce_id, created_at, updated_at
+		FROM browse_history
+		%s
+		ORDER BY %s %s
+		LIMIT ? OFFSET ?
+	`, whereClause, params.SortBy, sortOrder)
+
+	rows, err := r.db.Query(query, append(args, params.PageSize, offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list browse records: %w", err)
+	}
+	defer rows.Close()
+
+	records := []BrowseRecord{}
+	for rows.Next() {
+		var record BrowseRecord
+		err := rows.Scan(
+			&record.ID, &record.Title, &record.Author, &record.AuthorID,
+			&record.Duration, &record.Size, &record.Resolution, &record.CoverURL, &record.VideoURL,
+			&record.DecryptKey, &record.BrowseTime, &record.LikeCount, &record.CommentCount,
+			&record.FavCount, &record.ForwardCount, &record.PageURL, &record.NonceID, &record.CreatedAt, &record.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan browse record: %w", err)
+		}
+		records = append(records, record)
+	}
+
+	return NewPagedResult(records, total, params.Page, params.PageSize), nil
+}
+
 // Count 返回浏览记录的总数
 func (r *BrowseHistoryRepository) Count() (int64, error) {
 	var count int64
-	err := r.db.QueryRow("SELECT COUNT(*) FROM browse_history").Scan(&count)
+	err := r.db.QueryRow("SELECT COUNT(*) FROM browse_history WHERE deleted_at IS NULL").Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count browse records: %w", err)
 	}
@@ -301,8 +393,9 @@ func (r *BrowseHistoryRepository) GetRecent(limit int) ([]BrowseRecord, error) {
 		SELECT id, title, author, author_id, duration, size, COALESCE(resolution, '') as resolution, cover_url, video_url,
 			decrypt_key, browse_time, like_count, comment_count, 
 			COALESCE(fav_count, 0) as fav_count, COALESCE(forward_count, 0) as forward_count, page_url,
-			created_at, updated_at
+			COALESCE(nonce_id, '') as nonce_id, created_at, updated_at
 		FROM browse_history
+		WHERE deleted_at IS NULL
 		ORDER BY browse_time DESC
 		LIMIT ?
 	`
@@ -320,7 +413,7 @@ func (r *BrowseHistoryRepository) GetRecent(limit int) ([]BrowseRecord, error) {
 			&record.ID, &record.Title, &record.Author, &record.AuthorID,
 			&record.Duration, &record.Size, &record.Resolution, &record.CoverURL, &record.VideoURL,
 			&record.DecryptKey, &record.BrowseTime, &record.LikeCount, &record.CommentCount,
-			&record.FavCount, &record.ForwardCount, &record.PageURL, &record.CreatedAt, &record.UpdatedAt,
+			&record.FavCount, &record.ForwardCount, &record.PageURL, &record.NonceID, &record.CreatedAt, &record.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan browse record: %w", err)
@@ -335,9 +428,24 @@ func (r *BrowseHistoryRepository) GetRecent(limit int) ([]BrowseRecord, error) {
 	return records, nil
 }
 
-// DeleteBefore 删除指定日期前的所有记录
+// UpdateVideoSource 更新浏览记录的下载链接和解密密钥，返回更新的行数
+func (r *BrowseHistoryRepository) UpdateVideoSource(id, videoURL, decryptKey string) (int64, error) {
+	result, err := r.db.Exec(
+		"UPDATE browse_history SET video_url = ?, decrypt_key = ?, updated_at = ? WHERE id = ?",
+		videoURL, decryptKey, time.Now(), id,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update browse record video source: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// DeleteBefore 将指定日期前的所有记录移入回收站
 func (r *BrowseHistoryRepository) DeleteBefore(date time.Time) (int64, error) {
-	result, err := r.db.Exec("DELETE FROM browse_history WHERE browse_time < ?", date)
+	result, err := r.db.Exec(
+		"UPDATE browse_history SET deleted_at = ? WHERE browse_time < ? AND deleted_at IS NULL",
+		time.Now(), date,
+	)
 	if err != nil {
 		return 0, fmt.Errorf("failed to delete old browse records: %w", err)
 	}
@@ -350,8 +458,9 @@ func (r *BrowseHistoryRepository) GetAll() ([]BrowseRecord, error) {
 		SELECT id, title, author, author_id, duration, size, COALESCE(resolution, '') as resolution, cover_url, video_url,
 			decrypt_key, browse_time, like_count, comment_count, 
 			COALESCE(fav_count, 0) as fav_count, COALESCE(forward_count, 0) as forward_count, page_url,
-			created_at, updated_at
+			COALESCE(nonce_id, '') as nonce_id, created_at, updated_at
 		FROM browse_history
+		WHERE deleted_at IS NULL
 		ORDER BY browse_time DESC
 	`
 
@@ -368,7 +477,7 @@ func (r *BrowseHistoryRepository) GetAll() ([]BrowseRecord, error) {
 			&record.ID, &record.Title, &record.Author, &record.AuthorID,
 			&record.Duration, &record.Size, &record.Resolution, &record.CoverURL, &record.VideoURL,
 			&record.DecryptKey, &record.BrowseTime, &record.LikeCount, &record.CommentCount,
-			&record.FavCount, &record.ForwardCount, &record.PageURL, &record.CreatedAt, &record.UpdatedAt,
+			&record.FavCount, &record.ForwardCount, &record.PageURL, &record.NonceID, &record.CreatedAt, &record.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan browse record: %w", err)
@@ -400,9 +509,9 @@ func (r *BrowseHistoryRepository) GetByIDs(ids []string) ([]BrowseRecord, error)
 		SELECT id, title, author, author_id, duration, size, COALESCE(resolution, '') as resolution, cover_url, video_url,
 			decrypt_key, browse_time, like_count, comment_count, 
 			COALESCE(fav_count, 0) as fav_count, COALESCE(forward_count, 0) as forward_count, page_url,
-			created_at, updated_at
+			COALESCE(nonce_id, '') as nonce_id, created_at, updated_at
 		FROM browse_history
-		WHERE id IN (%s)
+		WHERE id IN (%s) AND deleted_at IS NULL
 		ORDER BY browse_time DESC
 	`, strings.Join(placeholders, ","))
 
@@ -419,7 +528,7 @@ func (r *BrowseHistoryRepository) GetByIDs(ids []string) ([]BrowseRecord, error)
 			&record.ID, &record.Title, &record.Author, &record.AuthorID,
 			&record.Duration, &record.Size, &record.Resolution, &record.CoverURL, &record.VideoURL,
 			&record.DecryptKey, &record.BrowseTime, &record.LikeCount, &record.CommentCount,
-			&record.FavCount, &record.ForwardCount, &record.PageURL, &record.CreatedAt, &record.UpdatedAt,
+			&record.FavCount, &record.ForwardCount, &record.PageURL, &record.NonceID, &record.CreatedAt, &record.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan browse record: %w", err)
@@ -433,3 +542,110 @@ func (r *BrowseHistoryRepository) GetByIDs(ids []string) ([]BrowseRecord, error)
 
 	return records, nil
 }
+
+// ListTrashed 按移入回收站的时间倒序分页获取回收站中的浏览记录
+func (r *BrowseHistoryRepository) ListTrashed(params *PaginationParams) (*PagedResult[BrowseRecord], error) {
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize < 1 {
+		params.PageSize = 20
+	}
+	if params.PageSize > 100 {
+		params.PageSize = 100
+	}
+
+	var total int64
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM browse_history WHERE deleted_at IS NOT NULL").Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count trashed browse records: %w", err)
+	}
+
+	query := `
+		SELECT id, title, author, author_id, duration, size, COALESCE(resolution, '') as resolution, cover_url, video_url,
+			decrypt_key, browse_time, like_count, comment_count,
+			COALESCE(fav_count, 0) as fav_count, COALESCE(forward_count, 0) as forward_count, page_url,
+			COALESCE(nonce_id, '') as nonce_id, created_at, updated_at, deleted_at
+		FROM browse_history
+		WHERE deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC
+		LIMIT ? OFFSET ?
+	`
+	rows, err := r.db.Query(query, params.PageSize, (params.Page-1)*params.PageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trashed browse records: %w", err)
+	}
+	defer rows.Close()
+
+	records := []BrowseRecord{}
+	for rows.Next() {
+		var record BrowseRecord
+		var deletedAt sql.NullTime
+		err := rows.Scan(
+			&record.ID, &record.Title, &record.Author, &record.AuthorID,
+			&record.Duration, &record.Size, &record.Resolution, &record.CoverURL, &record.VideoURL,
+			&record.DecryptKey, &record.BrowseTime, &record.LikeCount, &record.CommentCount,
+			&record.FavCount, &record.ForwardCount, &record.PageURL, &record.NonceID, &record.CreatedAt, &record.UpdatedAt,
+			&deletedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan browse record: %w", err)
+		}
+		if deletedAt.Valid {
+			record.DeletedAt = &deletedAt.Time
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return NewPagedResult(records, total, params.Page, params.PageSize), nil
+}
+
+// Restore 将回收站中的浏览记录恢复，返回恢复的数量
+func (r *BrowseHistoryRepository) Restore(ids []string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	query := fmt.Sprintf(
+		"UPDATE browse_history SET deleted_at = NULL WHERE id IN (%s) AND deleted_at IS NOT NULL",
+		strings.TrimSuffix(strings.Repeat("?,", len(ids)), ","),
+	)
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to restore browse records: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// Purge 从回收站中永久删除浏览记录，返回删除的数量
+func (r *BrowseHistoryRepository) Purge(ids []string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	query := fmt.Sprintf(
+		"DELETE FROM browse_history WHERE id IN (%s) AND deleted_at IS NOT NULL",
+		strings.TrimSuffix(strings.Repeat("?,", len(ids)), ","),
+	)
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge browse records: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// PurgeBefore 永久删除移入回收站早于 date 的浏览记录
+func (r *BrowseHistoryRepository) PurgeBefore(date time.Time) (int64, error) {
+	result, err := r.db.Exec("DELETE FROM browse_history WHERE deleted_at IS NOT NULL AND deleted_at < ?", date)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge browse records: %w", err)
+	}
+	return result.RowsAffected()
+}
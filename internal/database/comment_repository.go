@@ -0,0 +1,811 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// CommentRepository 处理评论数据库操作
+type CommentRepository struct {
+	db *sql.DB
+}
+
+// NewCommentRepository 创建一个新的 CommentRepository
+func NewCommentRepository() *CommentRepository {
+	return &CommentRepository{db: GetDB()}
+}
+
+// commentRunSessionGap 同一视频两次上报间隔小于该值时视为同一次采集（脚本会随滚动加载多次上报）
+const commentRunSessionGap = 10 * time.Minute
+
+// SaveVideoComments 在一个事务中写入视频信息及其评论，并记录到本次采集批次。
+// 只有新增或内容变化的评论会被重写；fullUpdate 为 true 且本批次覆盖了整个评论区时，
+// 之前存在但本批次未出现的评论会被标记为已删除。
+func (r *CommentRepository) SaveVideoComments(video *CommentVideo, comments []Comment, fullUpdate bool) (*CommentRun, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	if video.LastCollectedAt.IsZero() {
+		video.LastCollectedAt = now
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO comment_videos (
+			video_id, video_title, video_author, comment_count, original_comment_count,
+			first_collected_at, last_collected_at
+		) VALUES (?, ?, ?, 0, ?, ?, ?)
+		ON CONFLICT(video_id) DO UPDATE SET
+			video_title = CASE WHEN excluded.video_title != '' THEN excluded.video_title ELSE comment_videos.video_title END,
+			video_author = CASE WHEN excluded.video_author != '' THEN excluded.video_author ELSE comment_videos.video_author END,
+			original_comment_count = excluded.original_comment_count,
+			last_collected_at = excluded.last_collected_at
+	`, video.VideoID, video.VideoTitle, r.lookupVideoAuthor(tx, video), video.OriginalCommentCount, video.LastCollectedAt, video.LastCollectedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save comment video: %w", err)
+	}
+
+	runID, err := r.openRun(tx, video.VideoID, video.LastCollectedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range comments {
+		c := &comments[i]
+		if c.ID == "" {
+			continue
+		}
+		c.VideoID = video.VideoID
+		if err := r.saveComment(tx, c, runID, now); err != nil {
+			return nil, err
+		}
+	}
+
+	// 之前存在、本次完整采集中未出现的评论视为已删除；再次出现时会在 saveComment 中恢复。
+	// 注入脚本在加载中途也可能上报，只有本批次确实覆盖了整个评论区时才标记删除，否则本批次记为部分采集
+	if fullUpdate {
+		if fullUpdate, err = r.coversThread(tx, video, runID); err != nil {
+			return nil, err
+		}
+	}
+	if fullUpdate {
+		_, err = tx.Exec(`
+			UPDATE comments SET deleted_run = ?, deleted_at = ?
+			WHERE video_id = ? AND last_seen_run < ? AND deleted_run IS NULL
+		`, runID, now, video.VideoID, runID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to flag deleted comments: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE comment_runs SET
+			updated_at = ?,
+			full_update = CASE WHEN ? THEN 1 ELSE full_update END,
+			total_count = (SELECT COUNT(*) FROM comments WHERE video_id = ? AND last_seen_run = ?),
+			new_count = (SELECT COUNT(*) FROM comments WHERE video_id = ? AND first_seen_run = ?),
+			changed_count = (SELECT COUNT(*) FROM comment_changes WHERE run_id = ?),
+			deleted_count = (SELECT COUNT(*) FROM comments WHERE video_id = ? AND deleted_run = ?)
+		WHERE id = ?
+	`, video.LastCollectedAt, fullUpdate, video.VideoID, runID, video.VideoID, runID, runID, video.VideoID, runID, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update comment run: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE comment_videos
+		SET comment_count = (SELECT COUNT(*) FROM comments WHERE video_id = ? AND deleted_run IS NULL)
+		WHERE video_id = ?
+	`, video.VideoID, video.VideoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update comment count: %w", err)
+	}
+
+	run, err := scanCommentRun(tx.QueryRow("SELECT "+commentRunColumns+" FROM comment_runs WHERE id = ?", runID))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit comments: %w", err)
+	}
+	return run, nil
+}
+
+// coversThread 判断批次是否覆盖了整个评论区：本批次看到的评论数（含回复）不少于视频的原始评论数。
+// 原始评论数未知时以上报方的完整采集标记为准
+func (r *CommentRepository) coversThread(tx *sql.Tx, video *CommentVideo, runID int64) (bool, error) {
+	if video.OriginalCommentCount <= 0 {
+		return true, nil
+	}
+	var seen int64
+	err := tx.QueryRow(`
+		SELECT COUNT(*) FROM comments
+		WHERE video_id = ? AND last_seen_run = ?
+	`, video.VideoID, runID).Scan(&seen)
+	if err != nil {
+		return false, fmt.Errorf("failed to count collected comments: %w", err)
+	}
+	return seen >= video.OriginalCommentCount, nil
+}
+
+// openRun 返回视频当前的采集批次：距上次上报不超过 commentRunSessionGap 时沿用，否则新建
+func (r *CommentRepository) openRun(tx *sql.Tx, videoID string, collectedAt time.Time) (int64, error) {
+	var runID int64
+	var lastActivity time.Time
+	err := tx.QueryRow(`
+		SELECT id, updated_at FROM comment_runs WHERE video_id = ? ORDER BY id DESC LIMIT 1
+	`, videoID).Scan(&runID, &lastActivity)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to get comment run: %w", err)
+	}
+	if err == nil && collectedAt.Sub(lastActivity) < commentRunSessionGap {
+		return runID, nil
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO comment_runs (video_id, started_at, updated_at) VALUES (?, ?, ?)
+	`, videoID, collectedAt, collectedAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create comment run: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// saveComment 写入单条评论：新评论插入，内容变化时记录变更并更新索引，否则只刷新点赞数和可见批次
+func (r *CommentRepository) saveComment(tx *sql.Tx, c *Comment, runID int64, now time.Time) error {
+	var commentTime interface{}
+	if !c.CommentTime.IsZero() {
+		commentTime = c.CommentTime
+	}
+
+	var rowID int64
+	var oldContent, oldAuthor string
+	err := tx.QueryRow(`
+		SELECT id, COALESCE(content, ''), COALESCE(author, '') FROM comments WHERE video_id = ? AND comment_id = ?
+	`, c.VideoID, c.ID).Scan(&rowID, &oldContent, &oldAuthor)
+
+	switch {
+	case err == sql.ErrNoRows:
+		result, err := tx.Exec(`
+			INSERT INTO comments (
+				comment_id, video_id, parent_id, reply_to_id, reply_to_nickname,
+				author, author_avatar, content, like_count, reply_count, ip_region,
+				comment_time, first_seen_run, last_seen_run, created_at, updated_at
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`,
+			c.ID, c.VideoID, c.ParentID, c.ReplyToID, c.ReplyToNickname,
+			c.Author, c.AuthorAvatar, c.Content, c.LikeCount, c.ReplyCount, c.IPRegion,
+			commentTime, runID, runID, now, now,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save comment %s: %w", c.ID, err)
+		}
+		if rowID, err = result.LastInsertId(); err != nil {
+			return fmt.Errorf("failed to get comment row id: %w", err)
+		}
+		return r.indexComment(tx, rowID, c)
+
+	case err != nil:
+		return fmt.Errorf("failed to get comment %s: %w", c.ID, err)
+
+	case oldContent != c.Content || oldAuthor != c.Author:
+		_, err = tx.Exec(`
+			INSERT INTO comment_changes (run_id, video_id, comment_id, old_content, new_content, changed_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(run_id, video_id, comment_id) DO UPDATE SET
+				new_content = excluded.new_content,
+				changed_at = excluded.changed_at
+		`, runID, c.VideoID, c.ID, oldContent, c.Content, now)
+		if err != nil {
+			return fmt.Errorf("failed to record comment change %s: %w", c.ID, err)
+		}
+		_, err = tx.Exec(`
+			UPDATE comments SET
+				author = ?, author_avatar = ?, content = ?, ip_region = ?, comment_time = ?,
+				like_count = ?, reply_count = ?, last_seen_run = ?,
+				deleted_run = NULL, deleted_at = NULL, updated_at = ?
+			WHERE id = ?
+		`, c.Author, c.AuthorAvatar, c.Content, c.IPRegion, commentTime,
+			c.LikeCount, c.ReplyCount, runID, now, rowID)
+		if err != nil {
+			return fmt.Errorf("failed to update comment %s: %w", c.ID, err)
+		}
+		return r.indexComment(tx, rowID, c)
+
+	default:
+		_, err = tx.Exec(`
+			UPDATE comments SET
+				like_count = ?, reply_count = ?, last_seen_run = ?,
+				deleted_run = NULL, deleted_at = NULL, updated_at = ?
+			WHERE id = ?
+		`, c.LikeCount, c.ReplyCount, runID, now, rowID)
+		if err != nil {
+			return fmt.Errorf("failed to update comment %s: %w", c.ID, err)
+		}
+		return nil
+	}
+}
+
+// indexComment 同步全文索引（docid 与 comments.id 对应）
+func (r *CommentRepository) indexComment(tx *sql.Tx, rowID int64, c *Comment) error {
+	if _, err := tx.Exec("DELETE FROM comments_fts WHERE docid = ?", rowID); err != nil {
+		return fmt.Errorf("failed to update comment index: %w", err)
+	}
+	if _, err := tx.Exec("INSERT INTO comments_fts (docid, body) VALUES (?, ?)", rowID, segmentForFTS(c.Author+" "+c.Content)); err != nil {
+		return fmt.Errorf("failed to update comment index: %w", err)
+	}
+	return nil
+}
+
+// lookupVideoAuthor 从浏览记录或下载记录中查找视频作者
+func (r *CommentRepository) lookupVideoAuthor(tx *sql.Tx, video *CommentVideo) string {
+	if video.VideoAuthor != "" {
+		return video.VideoAuthor
+	}
+	var author string
+	_ = tx.QueryRow(`
+		SELECT COALESCE(
+			(SELECT author FROM browse_history WHERE id = ?),
+			(SELECT author FROM download_records WHERE video_id = ? LIMIT 1),
+			''
+		)
+	`, video.VideoID, video.VideoID).Scan(&author)
+	video.VideoAuthor = author
+	return author
+}
+
+// ListByVideo 获取视频的所有评论（平铺，按评论时间升序，包含已标记删除的评论）
+func (r *CommentRepository) ListByVideo(videoID string) ([]Comment, error) {
+	return r.queryComments(`
+		SELECT comment_id, video_id, COALESCE(parent_id, ''), COALESCE(reply_to_id, ''),
+			COALESCE(reply_to_nickname, ''), COALESCE(author, ''), COALESCE(author_avatar, ''),
+			COALESCE(content, ''), like_count, reply_count, COALESCE(ip_region, ''),
+			comment_time, deleted_at, created_at, updated_at
+		FROM comments
+		WHERE video_id = ?
+		ORDER BY comment_time ASC, id ASC
+	`, videoID)
+}
+
+// GetVideo 获取已采集评论的视频信息
+func (r *CommentRepository) GetVideo(videoID string) (*CommentVideo, error) {
+	video := &CommentVideo{}
+	var firstCollected, lastCollected sql.NullTime
+	err := r.db.QueryRow(`
+		SELECT video_id, COALESCE(video_title, ''), COALESCE(video_author, ''), comment_count, original_comment_count,
+			first_collected_at, last_collected_at
+		FROM comment_videos WHERE video_id = ?
+	`, videoID).Scan(
+		&video.VideoID, &video.VideoTitle, &video.VideoAuthor, &video.CommentCount, &video.OriginalCommentCount,
+		&firstCollected, &lastCollected,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment video: %w", err)
+	}
+	video.FirstCollectedAt = firstCollected.Time
+	video.LastCollectedAt = lastCollected.Time
+	return video, nil
+}
+
+// ListVideos 获取已采集评论的视频列表（按最近采集时间倒序）
+func (r *CommentRepository) ListVideos(params *PaginationParams) (*PagedResult[CommentVideo], error) {
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize < 1 {
+		params.PageSize = 20
+	}
+	if params.PageSize > 100 {
+		params.PageSize = 100
+	}
+
+	var total int64
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM comment_videos").Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count comment videos: %w", err)
+	}
+
+	rows, err := r.db.Query(`
+		SELECT video_id, COALESCE(video_title, ''), COALESCE(video_author, ''), comment_count, original_comment_count,
+			first_collected_at, last_collected_at
+		FROM comment_videos
+		ORDER BY last_collected_at DESC
+		LIMIT ? OFFSET ?
+	`, params.PageSize, (params.Page-1)*params.PageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list comment videos: %w", err)
+	}
+	defer rows.Close()
+
+	videos := []CommentVideo{}
+	for rows.Next() {
+		var video CommentVideo
+		var firstCollected, lastCollected sql.NullTime
+		if err := rows.Scan(
+			&video.VideoID, &video.VideoTitle, &video.VideoAuthor, &video.CommentCount, &video.OriginalCommentCount,
+			&firstCollected, &lastCollected,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan comment video: %w", err)
+		}
+		video.FirstCollectedAt = firstCollected.Time
+		video.LastCollectedAt = lastCollected.Time
+		videos = append(videos, video)
+	}
+
+	return NewPagedResult(videos, total, params.Page, params.PageSize), nil
+}
+
+// Search 在所有已采集评论中进行全文搜索，videoID 不为空时限定在该视频内
+func (r *CommentRepository) Search(query, videoID string, params *PaginationParams) (*PagedResult[CommentSearchResult], error) {
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize < 1 {
+		params.PageSize = 20
+	}
+	if params.PageSize > 100 {
+		params.PageSize = 100
+	}
+
+	match := buildFTSMatch(query)
+	if match == "" {
+		return NewPagedResult([]CommentSearchResult{}, 0, params.Page, params.PageSize), nil
+	}
+
+	where := "comments_fts MATCH ?"
+	args := []interface{}{match}
+	if videoID != "" {
+		where += " AND c.video_id = ?"
+		args = append(args, videoID)
+	}
+
+	var total int64
+	countQuery := fmt.Sprintf(`
+		SELECT COUNT(*) FROM comments_fts
+		JOIN comments c ON c.id = comments_fts.docid
+		WHERE %s
+	`, where)
+	if err := r.db.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count comment search results: %w", err)
+	}
+
+	searchQuery := fmt.Sprintf(`
+		SELECT %s
+		FROM comments_fts
+		JOIN comments c ON c.id = comments_fts.docid
+		LEFT JOIN comment_videos v ON v.video_id = c.video_id
+		WHERE %s
+		ORDER BY c.like_count DESC, c.comment_time DESC
+		LIMIT ? OFFSET ?
+	`, commentResultColumns, where)
+	args = append(args, params.PageSize, (params.Page-1)*params.PageSize)
+
+	rows, err := r.db.Query(searchQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search comments: %w", err)
+	}
+	defer rows.Close()
+
+	results, err := scanCommentResults(rows)
+	if err != nil {
+		return nil, err
+	}
+	return NewPagedResult(results, total, params.Page, params.PageSize), nil
+}
+
+// commentFilterClause 根据统计范围构建 WHERE 子句（评论表别名为 c）
+func commentFilterClause(filter *CommentAnalyticsFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if filter.VideoID != "" {
+		conditions = append(conditions, "c.video_id = ?")
+		args = append(args, filter.VideoID)
+	}
+	if filter.Author != "" {
+		conditions = append(conditions, "c.video_id IN (SELECT video_id FROM comment_videos WHERE video_author = ?)")
+		args = append(args, filter.Author)
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// GetAnalytics 统计评论量时间线、活跃评论者、IP 属地分布和最高赞评论
+func (r *CommentRepository) GetAnalytics(filter *CommentAnalyticsFilter) (*CommentAnalytics, error) {
+	if filter.Limit < 1 || filter.Limit > 100 {
+		filter.Limit = 10
+	}
+	timeFormat := "%Y-%m-%d"
+	if filter.Interval == "hour" {
+		timeFormat = "%Y-%m-%d %H:00"
+	}
+
+	where, args := commentFilterClause(filter)
+	analytics := &CommentAnalytics{
+		VideoID:       filter.VideoID,
+		Author:        filter.Author,
+		Timeline:      []CommentTimeBucket{},
+		TopCommenters: []CommentAuthorStat{},
+		IPRegions:     []CommentRegionStat{},
+	}
+
+	err := r.db.QueryRow(fmt.Sprintf(`
+		SELECT COUNT(DISTINCT c.video_id), COUNT(*),
+			COALESCE(SUM(CASE WHEN c.parent_id = '' THEN 1 ELSE 0 END), 0),
+			COUNT(DISTINCT NULLIF(c.author, '')),
+			COALESCE(SUM(c.like_count), 0)
+		FROM comments c %s
+	`, where), args...).Scan(
+		&analytics.VideoCount, &analytics.TotalComments, &analytics.TopLevelComments,
+		&analytics.UniqueCommenters, &analytics.TotalLikes,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment summary: %w", err)
+	}
+	analytics.Replies = analytics.TotalComments - analytics.TopLevelComments
+
+	// 评论量时间线（按本地时间分桶，缺少评论时间的评论不计入）
+	timelineWhere := "WHERE c.comment_time IS NOT NULL"
+	if where != "" {
+		timelineWhere = where + " AND c.comment_time IS NOT NULL"
+	}
+	rows, err := r.db.Query(fmt.Sprintf(`
+		SELECT strftime('%s', c.comment_time, 'localtime') AS bucket, COUNT(*)
+		FROM comments c %s
+		GROUP BY bucket
+		ORDER BY bucket
+	`, timeFormat, timelineWhere), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment timeline: %w", err)
+	}
+	for rows.Next() {
+		var bucket CommentTimeBucket
+		var t sql.NullString
+		if err := rows.Scan(&t, &bucket.Count); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan comment timeline: %w", err)
+		}
+		bucket.Time = t.String
+		analytics.Timeline = append(analytics.Timeline, bucket)
+	}
+	rows.Close()
+
+	// 活跃评论者
+	rows, err = r.db.Query(fmt.Sprintf(`
+		SELECT c.author, COUNT(*) AS cnt, COALESCE(SUM(c.like_count), 0)
+		FROM comments c %s
+		GROUP BY c.author
+		HAVING c.author != ''
+		ORDER BY cnt DESC, c.author
+		LIMIT ?
+	`, where), append(args, filter.Limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top commenters: %w", err)
+	}
+	for rows.Next() {
+		var stat CommentAuthorStat
+		if err := rows.Scan(&stat.Author, &stat.Count, &stat.Likes); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan top commenters: %w", err)
+		}
+		analytics.TopCommenters = append(analytics.TopCommenters, stat)
+	}
+	rows.Close()
+
+	// IP 属地分布（全部属地，空值归为“未知”）
+	rows, err = r.db.Query(fmt.Sprintf(`
+		SELECT COALESCE(NULLIF(c.ip_region, ''), '未知') AS region, COUNT(*) AS cnt
+		FROM comments c %s
+		GROUP BY region
+		ORDER BY cnt DESC, region
+	`, where), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ip region distribution: %w", err)
+	}
+	for rows.Next() {
+		var stat CommentRegionStat
+		if err := rows.Scan(&stat.Region, &stat.Count); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan ip region distribution: %w", err)
+		}
+		analytics.IPRegions = append(analytics.IPRegions, stat)
+	}
+	rows.Close()
+
+	// 最高赞评论
+	rows, err = r.db.Query(fmt.Sprintf(`
+		SELECT %s
+		FROM comments c
+		LEFT JOIN comment_videos v ON v.video_id = c.video_id
+		%s
+		ORDER BY c.like_count DESC, c.comment_time DESC
+		LIMIT ?
+	`, commentResultColumns, where), append(args, filter.Limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get most liked comments: %w", err)
+	}
+	defer rows.Close()
+	if analytics.MostLiked, err = scanCommentResults(rows); err != nil {
+		return nil, err
+	}
+
+	return analytics, nil
+}
+
+// ListForExport 获取指定范围内的全部评论（附带视频标题，用于导出）
+func (r *CommentRepository) ListForExport(filter *CommentAnalyticsFilter) ([]CommentSearchResult, error) {
+	where, args := commentFilterClause(filter)
+	rows, err := r.db.Query(fmt.Sprintf(`
+		SELECT %s
+		FROM comments c
+		LEFT JOIN comment_videos v ON v.video_id = c.video_id
+		%s
+		ORDER BY c.video_id, c.comment_time ASC, c.id ASC
+	`, commentResultColumns, where), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list comments for export: %w", err)
+	}
+	defer rows.Close()
+	return scanCommentResults(rows)
+}
+
+// ListRuns 获取视频的采集批次（按时间倒序）
+func (r *CommentRepository) ListRuns(videoID string) ([]CommentRun, error) {
+	rows, err := r.db.Query("SELECT "+commentRunColumns+" FROM comment_runs WHERE video_id = ? ORDER BY id DESC", videoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list comment runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []CommentRun{}
+	for rows.Next() {
+		run, err := scanCommentRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, *run)
+	}
+	return runs, rows.Err()
+}
+
+// GetRun 获取指定批次，不存在或不属于该视频时返回 nil
+func (r *CommentRepository) GetRun(videoID string, runID int64) (*CommentRun, error) {
+	run, err := scanCommentRun(r.db.QueryRow("SELECT "+commentRunColumns+" FROM comment_runs WHERE id = ? AND video_id = ?", runID, videoID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return run, err
+}
+
+// GetRunDiff 比较同一视频的两个采集批次（from < to）：
+// 新增为 to 中存在而 from 中不存在的评论，删除为 from 中存在、并在 (from, to] 区间内被完整采集确认删除的评论，
+// 修改为 (from, to] 区间内记录到的内容变化。部分采集的批次不会产生删除
+func (r *CommentRepository) GetRunDiff(videoID string, fromRunID, toRunID int64) (*CommentRunDiff, error) {
+	from, err := r.GetRun(videoID, fromRunID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := r.GetRun(videoID, toRunID)
+	if err != nil {
+		return nil, err
+	}
+	if from == nil || to == nil {
+		return nil, fmt.Errorf("comment run not found for video %s", videoID)
+	}
+	if from.ID >= to.ID {
+		return nil, fmt.Errorf("from run must be earlier than to run")
+	}
+
+	diff := &CommentRunDiff{VideoID: videoID, From: from, To: to, Changed: []CommentChange{}}
+
+	const listQuery = `
+		SELECT comment_id, video_id, COALESCE(parent_id, ''), COALESCE(reply_to_id, ''),
+			COALESCE(reply_to_nickname, ''), COALESCE(author, ''), COALESCE(author_avatar, ''),
+			COALESCE(content, ''), like_count, reply_count, COALESCE(ip_region, ''),
+			comment_time, deleted_at, created_at, updated_at
+		FROM comments
+		WHERE video_id = ? AND %s
+		ORDER BY comment_time ASC, id ASC
+	`
+	if diff.Added, err = r.queryComments(fmt.Sprintf(listQuery, "first_seen_run > ? AND first_seen_run <= ? AND last_seen_run >= ?"),
+		videoID, from.ID, to.ID, to.ID); err != nil {
+		return nil, err
+	}
+	if diff.Deleted, err = r.queryComments(fmt.Sprintf(listQuery, "first_seen_run <= ? AND deleted_run > ? AND deleted_run <= ?"),
+		videoID, from.ID, from.ID, to.ID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(`
+		SELECT run_id, comment_id, COALESCE(old_content, ''), COALESCE(new_content, ''), changed_at
+		FROM comment_changes
+		WHERE video_id = ? AND run_id > ? AND run_id <= ?
+		ORDER BY run_id, id
+	`, videoID, from.ID, to.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list comment changes: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var change CommentChange
+		var changedAt sql.NullTime
+		if err := rows.Scan(&change.RunID, &change.CommentID, &change.OldContent, &change.NewContent, &changedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan comment change: %w", err)
+		}
+		change.ChangedAt = changedAt.Time
+		diff.Changed = append(diff.Changed, change)
+	}
+
+	return diff, rows.Err()
+}
+
+// queryComments 执行返回评论列的查询
+func (r *CommentRepository) queryComments(query string, args ...interface{}) ([]Comment, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query comments: %w", err)
+	}
+	defer rows.Close()
+
+	comments := []Comment{}
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, *c)
+	}
+	return comments, rows.Err()
+}
+
+// DeleteByVideo 删除视频的所有评论及其索引
+func (r *CommentRepository) DeleteByVideo(videoID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM comments_fts WHERE docid IN (SELECT id FROM comments WHERE video_id = ?)", videoID); err != nil {
+		return fmt.Errorf("failed to delete comment index: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM comments WHERE video_id = ?", videoID); err != nil {
+		return fmt.Errorf("failed to delete comments: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM comment_changes WHERE video_id = ?", videoID); err != nil {
+		return fmt.Errorf("failed to delete comment changes: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM comment_runs WHERE video_id = ?", videoID); err != nil {
+		return fmt.Errorf("failed to delete comment runs: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM comment_videos WHERE video_id = ?", videoID); err != nil {
+		return fmt.Errorf("failed to delete comment video: %w", err)
+	}
+	return tx.Commit()
+}
+
+// commentResultColumns 是 CommentSearchResult 对应的查询列（评论表别名 c，视频表别名 v）
+const commentResultColumns = `c.comment_id, c.video_id, COALESCE(c.parent_id, ''), COALESCE(c.reply_to_id, ''),
+			COALESCE(c.reply_to_nickname, ''), COALESCE(c.author, ''), COALESCE(c.author_avatar, ''),
+			COALESCE(c.content, ''), c.like_count, c.reply_count, COALESCE(c.ip_region, ''),
+			c.comment_time, c.deleted_at, c.created_at, c.updated_at,
+			COALESCE(v.video_title, '')`
+
+// scanCommentResults 扫描带视频标题的评论结果
+func scanCommentResults(rows *sql.Rows) ([]CommentSearchResult, error) {
+	results := []CommentSearchResult{}
+	for rows.Next() {
+		var result CommentSearchResult
+		var commentTime, deletedAt sql.NullTime
+		if err := rows.Scan(
+			&result.ID, &result.VideoID, &result.ParentID, &result.ReplyToID,
+			&result.ReplyToNickname, &result.Author, &result.AuthorAvatar,
+			&result.Content, &result.LikeCount, &result.ReplyCount, &result.IPRegion,
+			&commentTime, &deletedAt, &result.CreatedAt, &result.UpdatedAt,
+			&result.VideoTitle,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan comment result: %w", err)
+		}
+		result.CommentTime = commentTime.Time
+		if deletedAt.Valid {
+			result.DeletedAt = &deletedAt.Time
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// commentRunColumns 是 CommentRun 对应的查询列
+const commentRunColumns = `id, video_id, started_at, updated_at, full_update,
+	total_count, new_count, changed_count, deleted_count`
+
+// rowScanner 同时适配 *sql.Row 和 *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanCommentRun 扫描一行采集批次数据
+func scanCommentRun(row rowScanner) (*CommentRun, error) {
+	run := &CommentRun{}
+	err := row.Scan(
+		&run.ID, &run.VideoID, &run.StartedAt, &run.UpdatedAt, &run.FullUpdate,
+		&run.TotalCount, &run.NewCount, &run.ChangedCount, &run.DeletedCount,
+	)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan comment run: %w", err)
+	}
+	return run, nil
+}
+
+// scanComment 扫描一行评论数据
+func scanComment(rows *sql.Rows) (*Comment, error) {
+	c := &Comment{}
+	var commentTime, deletedAt sql.NullTime
+	err := rows.Scan(
+		&c.ID, &c.VideoID, &c.ParentID, &c.ReplyToID,
+		&c.ReplyToNickname, &c.Author, &c.AuthorAvatar,
+		&c.Content, &c.LikeCount, &c.ReplyCount, &c.IPRegion,
+		&commentTime, &deletedAt, &c.CreatedAt, &c.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan comment: %w", err)
+	}
+	c.CommentTime = commentTime.Time
+	if deletedAt.Valid {
+		c.DeletedAt = &deletedAt.Time
+	}
+	return c, nil
+}
+
+// segmentForFTS 在每个中日韩字符两侧插入空格。
+// FTS4 的分词器不会切分中文，这样配合短语查询即可实现中文子串匹配。
+func segmentForFTS(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if isCJK(r) {
+			b.WriteRune(' ')
+			b.WriteRune(r)
+			b.WriteRune(' ')
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// buildFTSMatch 将用户输入转换为 FTS MATCH 表达式：每个关键词作为短语，多个关键词为 AND 关系
+func buildFTSMatch(query string) string {
+	var phrases []string
+	for _, term := range strings.Fields(query) {
+		term = strings.Map(func(r rune) rune {
+			if r == '"' || r == '*' || r == '-' || r == '^' {
+				return ' '
+			}
+			return r
+		}, term)
+		segmented := strings.Join(strings.Fields(segmentForFTS(term)), " ")
+		if segmented != "" {
+			phrases = append(phrases, `"`+segmented+`"`)
+		}
+	}
+	return strings.Join(phrases, " ")
+}
+
+// isCJK 判断是否为中日韩文字
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
@@ -0,0 +1,225 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// CrawlRepository 处理账号视频抓取任务的数据库操作
+type CrawlRepository struct {
+	db *sql.DB
+}
+
+// NewCrawlRepository 创建一个新的 CrawlRepository
+func NewCrawlRepository() *CrawlRepository {
+	return &CrawlRepository{db: GetDB()}
+}
+
+// crawlJobColumns 是 CrawlJob 对应的查询列
+const crawlJobColumns = `id, username, author, status, enqueue, keyword, since, until,
+	max_pages, interval_ms, next_marker, pages, discovered_count, enqueued_count,
+	error_message, created_at, updated_at, finished_at`
+
+// crawlVideoColumns 是 CrawlVideo 对应的查询列
+const crawlVideoColumns = `job_id, video_id, nonce_id, title, author, cover_url, video_url,
+	decrypt_key, duration, size, media_type, publish_time, enqueued, discovered_at`
+
+// CreateJob 创建抓取任务
+func (r *CrawlRepository) CreateJob(job *CrawlJob) error {
+	now := time.Now()
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	result, err := r.db.Exec(`
+		INSERT INTO crawl_jobs (username, author, status, enqueue, keyword, since, until,
+			max_pages, interval_ms, next_marker, pages, discovered_count, enqueued_count,
+			error_message, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		job.Username, job.Author, job.Status, job.Enqueue, job.Keyword, job.Since, job.Until,
+		job.MaxPages, job.IntervalMs, job.NextMarker, job.Pages, job.DiscoveredCount, job.EnqueuedCount,
+		job.ErrorMessage, job.CreatedAt, job.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create crawl job: %w", err)
+	}
+	job.ID, err = result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get crawl job id: %w", err)
+	}
+	return nil
+}
+
+// UpdateJob 保存任务状态和进度
+func (r *CrawlRepository) UpdateJob(job *CrawlJob) error {
+	job.UpdatedAt = time.Now()
+	_, err := r.db.Exec(`
+		UPDATE crawl_jobs SET author = ?, status = ?, next_marker = ?, pages = ?,
+			discovered_count = ?, enqueued_count = ?, error_message = ?, updated_at = ?, finished_at = ?
+		WHERE id = ?`,
+		job.Author, job.Status, job.NextMarker, job.Pages,
+		job.DiscoveredCount, job.EnqueuedCount, job.ErrorMessage, job.UpdatedAt, job.FinishedAt,
+		job.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update crawl job: %w", err)
+	}
+	return nil
+}
+
+// GetJob 获取任务，不存在时返回 nil
+func (r *CrawlRepository) GetJob(id int64) (*CrawlJob, error) {
+	job, err := scanCrawlJob(r.db.QueryRow("SELECT "+crawlJobColumns+" FROM crawl_jobs WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return job, err
+}
+
+// ListJobs 获取所有任务（按创建时间倒序）
+func (r *CrawlRepository) ListJobs() ([]CrawlJob, error) {
+	return r.queryJobs("SELECT " + crawlJobColumns + " FROM crawl_jobs ORDER BY id DESC")
+}
+
+// ListJobsByStatus 获取指定状态的任务
+func (r *CrawlRepository) ListJobsByStatus(status string) ([]CrawlJob, error) {
+	return r.queryJobs("SELECT "+crawlJobColumns+" FROM crawl_jobs WHERE status = ? ORDER BY id", status)
+}
+
+// queryJobs 执行返回任务列的查询
+func (r *CrawlRepository) queryJobs(query string, args ...interface{}) ([]CrawlJob, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list crawl jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []CrawlJob{}
+	for rows.Next() {
+		job, err := scanCrawlJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+// SaveVideos 保存本页发现的视频，返回其中首次发现的视频（已存在的只刷新链接和密钥）
+func (r *CrawlRepository) SaveVideos(jobID int64, videos []CrawlVideo) ([]CrawlVideo, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	added := []CrawlVideo{}
+	for i := range videos {
+		v := &videos[i]
+		v.JobID = jobID
+		v.DiscoveredAt = now
+
+		result, err := tx.Exec(`
+			INSERT INTO crawl_videos (`+crawlVideoColumns+`)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0, ?)
+			ON CONFLICT(job_id, video_id) DO NOTHING`,
+			v.JobID, v.VideoID, v.NonceID, v.Title, v.Author, v.CoverURL, v.VideoURL,
+			v.DecryptKey, v.Duration, v.Size, v.MediaType, v.PublishTime, v.DiscoveredAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save crawl video: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			added = append(added, *v)
+			continue
+		}
+
+		// 视频链接带有时效性的 token，重复抓取时更新
+		if _, err := tx.Exec(`
+			UPDATE crawl_videos SET video_url = ?, decrypt_key = ?, cover_url = ?
+			WHERE job_id = ? AND video_id = ?`,
+			v.VideoURL, v.DecryptKey, v.CoverURL, v.JobID, v.VideoID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to update crawl video: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit crawl videos: %w", err)
+	}
+	return added, nil
+}
+
+// MarkEnqueued 标记视频已加入下载队列
+func (r *CrawlRepository) MarkEnqueued(jobID int64, videoIDs []string) error {
+	for _, id := range videoIDs {
+		if _, err := r.db.Exec("UPDATE crawl_videos SET enqueued = 1 WHERE job_id = ? AND video_id = ?", jobID, id); err != nil {
+			return fmt.Errorf("failed to mark crawl video enqueued: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListVideos 获取任务发现的视频（按发布时间倒序）
+func (r *CrawlRepository) ListVideos(jobID int64, params *PaginationParams) (*PagedResult[CrawlVideo], error) {
+	var total int64
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM crawl_videos WHERE job_id = ?", jobID).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count crawl videos: %w", err)
+	}
+
+	rows, err := r.db.Query("SELECT "+crawlVideoColumns+` FROM crawl_videos WHERE job_id = ?
+		ORDER BY publish_time DESC, video_id DESC LIMIT ? OFFSET ?`,
+		jobID, params.PageSize, (params.Page-1)*params.PageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list crawl videos: %w", err)
+	}
+	defer rows.Close()
+
+	videos := []CrawlVideo{}
+	for rows.Next() {
+		v := CrawlVideo{}
+		var publishTime sql.NullTime
+		if err := rows.Scan(
+			&v.JobID, &v.VideoID, &v.NonceID, &v.Title, &v.Author, &v.CoverURL, &v.VideoURL,
+			&v.DecryptKey, &v.Duration, &v.Size, &v.MediaType, &publishTime, &v.Enqueued, &v.DiscoveredAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan crawl video: %w", err)
+		}
+		if publishTime.Valid {
+			v.PublishTime = &publishTime.Time
+		}
+		videos = append(videos, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return NewPagedResult(videos, total, params.Page, params.PageSize), nil
+}
+
+// scanCrawlJob 扫描一行任务数据
+func scanCrawlJob(row rowScanner) (*CrawlJob, error) {
+	job := &CrawlJob{}
+	var since, until, finishedAt sql.NullTime
+	err := row.Scan(
+		&job.ID, &job.Username, &job.Author, &job.Status, &job.Enqueue, &job.Keyword, &since, &until,
+		&job.MaxPages, &job.IntervalMs, &job.NextMarker, &job.Pages, &job.DiscoveredCount, &job.EnqueuedCount,
+		&job.ErrorMessage, &job.CreatedAt, &job.UpdatedAt, &finishedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan crawl job: %w", err)
+	}
+	if since.Valid {
+		job.Since = &since.Time
+	}
+	if until.Valid {
+		job.Until = &until.Time
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	return job, nil
+}
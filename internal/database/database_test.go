@@ -2,6 +2,8 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -188,6 +190,59 @@ func TestDownloadRecordRepository(t *testing.T) {
 	}
 }
 
+func TestDownloadRecordMediaInfoFilter(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewDownloadRecordRepository()
+
+	for _, id := range []string{"portrait-1080", "landscape-720", "unprobed"} {
+		if err := repo.Create(&DownloadRecord{ID: id, Title: id, Status: DownloadStatusCompleted, DownloadTime: time.Now()}); err != nil {
+			t.Fatalf("Failed to create download record: %v", err)
+		}
+	}
+
+	updates := []*DownloadRecord{
+		{ID: "portrait-1080", Width: 1080, Height: 1920, VideoCodec: "hevc", Container: "mp4", Bitrate: 4000000, FPS: 30, MediaDuration: 12.5},
+		{ID: "landscape-720", Width: 1280, Height: 720, VideoCodec: "h264", Container: "mp4", Bitrate: 1500000, FPS: 25, MediaDuration: 60},
+	}
+	for _, u := range updates {
+		if err := repo.UpdateMediaInfo(u); err != nil {
+			t.Fatalf("Failed to update media info: %v", err)
+		}
+	}
+
+	retrieved, err := repo.GetByID("portrait-1080")
+	if err != nil {
+		t.Fatalf("Failed to get download record: %v", err)
+	}
+	if retrieved.Height != 1920 || retrieved.VideoCodec != "hevc" || retrieved.FPS != 30 || retrieved.MediaDuration != 12.5 {
+		t.Errorf("Media info not persisted: %+v", retrieved)
+	}
+
+	result, err := repo.List(&FilterParams{
+		PaginationParams: PaginationParams{Page: 1, PageSize: 10},
+		MinResolution:    1080,
+	})
+	if err != nil {
+		t.Fatalf("Failed to list download records: %v", err)
+	}
+	if result.Total != 1 || result.Items[0].ID != "portrait-1080" {
+		t.Errorf("Expected only portrait-1080 for >=1080p, got %+v", result.Items)
+	}
+
+	result, err = repo.List(&FilterParams{
+		PaginationParams: PaginationParams{Page: 1, PageSize: 10},
+		VideoCodec:       "h264",
+	})
+	if err != nil {
+		t.Fatalf("Failed to list download records: %v", err)
+	}
+	if result.Total != 1 || result.Items[0].ID != "landscape-720" {
+		t.Errorf("Expected only landscape-720 for h264, got %+v", result.Items)
+	}
+}
+
 func TestQueueRepository(t *testing.T) {
 	cleanup := setupTestDB(t)
 	defer cleanup()
@@ -316,3 +371,1135 @@ func TestSettingsRepository(t *testing.T) {
 		t.Error("Expected validation error for high concurrent limit")
 	}
 }
+
+func TestCommentRepository(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewCommentRepository()
+
+	video := &CommentVideo{VideoID: "video-1", VideoTitle: "黄金提炼", OriginalCommentCount: 3}
+	comments := []Comment{
+		{ID: "c1", Author: "张三", Content: "这一堆老表能提炼出多少黄金", LikeCount: 10, IPRegion: "广东", CommentTime: time.Unix(1700000000, 0)},
+		{ID: "c2", ParentID: "c1", ReplyToID: "c1", Author: "李四", Content: "估计不到一克", LikeCount: 2, CommentTime: time.Unix(1700000100, 0)},
+		{ID: "c3", Author: "Alice", Content: "Great video about gold refining", CommentTime: time.Unix(1700000200, 0)},
+	}
+
+	run, err := repo.SaveVideoComments(video, comments, true)
+	if err != nil {
+		t.Fatalf("Failed to save comments: %v", err)
+	}
+	if run.TotalCount != 3 || run.NewCount != 3 {
+		t.Errorf("Expected 3 new comments in run, got %+v", run)
+	}
+
+	// 重复采集同一批评论应更新而不是新增
+	comments[0].LikeCount = 20
+	if _, err := repo.SaveVideoComments(video, comments[:1], false); err != nil {
+		t.Fatalf("Failed to re-save comments: %v", err)
+	}
+
+	list, err := repo.ListByVideo("video-1")
+	if err != nil {
+		t.Fatalf("Failed to list comments: %v", err)
+	}
+	if len(list) != 3 {
+		t.Fatalf("Expected 3 comments, got %d", len(list))
+	}
+	if list[0].ID != "c1" || list[0].LikeCount != 20 || list[0].IPRegion != "广东" {
+		t.Errorf("Unexpected first comment: %+v", list[0])
+	}
+	if list[1].ParentID != "c1" {
+		t.Errorf("Expected reply parent 'c1', got '%s'", list[1].ParentID)
+	}
+
+	v, err := repo.GetVideo("video-1")
+	if err != nil || v == nil {
+		t.Fatalf("Failed to get comment video: %v", err)
+	}
+	if v.CommentCount != 3 {
+		t.Errorf("Expected comment count 3, got %d", v.CommentCount)
+	}
+
+	// 中文子串搜索
+	result, err := repo.Search("黄金", "", &PaginationParams{Page: 1, PageSize: 10})
+	if err != nil {
+		t.Fatalf("Failed to search comments: %v", err)
+	}
+	if result.Total != 1 || result.Items[0].ID != "c1" || result.Items[0].VideoTitle != "黄金提炼" {
+		t.Errorf("Unexpected search result for '黄金': %+v", result.Items)
+	}
+
+	// 英文词搜索（大小写不敏感）
+	result, err = repo.Search("GOLD", "video-1", &PaginationParams{Page: 1, PageSize: 10})
+	if err != nil {
+		t.Fatalf("Failed to search comments: %v", err)
+	}
+	if result.Total != 1 || result.Items[0].ID != "c3" {
+		t.Errorf("Unexpected search result for 'GOLD': %+v", result.Items)
+	}
+
+	// 按作者搜索
+	result, err = repo.Search("李四", "", &PaginationParams{Page: 1, PageSize: 10})
+	if err != nil {
+		t.Fatalf("Failed to search comments: %v", err)
+	}
+	if result.Total != 1 || result.Items[0].ID != "c2" {
+		t.Errorf("Unexpected search result for '李四': %+v", result.Items)
+	}
+
+	if err := repo.DeleteByVideo("video-1"); err != nil {
+		t.Fatalf("Failed to delete comments: %v", err)
+	}
+	result, err = repo.Search("黄金", "", &PaginationParams{Page: 1, PageSize: 10})
+	if err != nil {
+		t.Fatalf("Failed to search comments: %v", err)
+	}
+	if result.Total != 0 {
+		t.Errorf("Expected no results after delete, got %d", result.Total)
+	}
+}
+
+func TestCommentAnalytics(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// 视频作者来自浏览记录
+	browseRepo := NewBrowseHistoryRepository()
+	if err := browseRepo.Create(&BrowseRecord{ID: "video-1", Title: "V1", Author: "创作者A", BrowseTime: time.Now()}); err != nil {
+		t.Fatalf("Failed to create browse record: %v", err)
+	}
+
+	repo := NewCommentRepository()
+	day1 := time.Date(2025, 1, 1, 10, 0, 0, 0, time.Local)
+	day2 := day1.AddDate(0, 0, 1)
+	if _, err := repo.SaveVideoComments(&CommentVideo{VideoID: "video-1"}, []Comment{
+		{ID: "a", Author: "张三", Content: "first", LikeCount: 5, IPRegion: "广东", CommentTime: day1},
+		{ID: "b", Author: "张三", Content: "second", LikeCount: 1, IPRegion: "广东", CommentTime: day2},
+		{ID: "c", ParentID: "a", Author: "李四", Content: "reply", LikeCount: 9, CommentTime: day2},
+	}, true); err != nil {
+		t.Fatalf("Failed to save comments: %v", err)
+	}
+	if _, err := repo.SaveVideoComments(&CommentVideo{VideoID: "video-2"}, []Comment{
+		{ID: "d", Author: "王五", Content: "other video", LikeCount: 100, IPRegion: "北京", CommentTime: day1},
+	}, true); err != nil {
+		t.Fatalf("Failed to save comments: %v", err)
+	}
+
+	analytics, err := repo.GetAnalytics(&CommentAnalyticsFilter{Author: "创作者A"})
+	if err != nil {
+		t.Fatalf("Failed to get analytics: %v", err)
+	}
+	if analytics.VideoCount != 1 || analytics.TotalComments != 3 || analytics.Replies != 1 || analytics.UniqueCommenters != 2 || analytics.TotalLikes != 15 {
+		t.Errorf("Unexpected summary: %+v", analytics)
+	}
+	if len(analytics.Timeline) != 2 || analytics.Timeline[0].Time != "2025-01-01" || analytics.Timeline[1].Count != 2 {
+		t.Errorf("Unexpected timeline: %+v", analytics.Timeline)
+	}
+	if len(analytics.TopCommenters) == 0 || analytics.TopCommenters[0].Author != "张三" || analytics.TopCommenters[0].Count != 2 {
+		t.Errorf("Unexpected top commenters: %+v", analytics.TopCommenters)
+	}
+	if len(analytics.IPRegions) != 2 || analytics.IPRegions[0].Region != "广东" || analytics.IPRegions[1].Region != "未知" {
+		t.Errorf("Unexpected ip regions: %+v", analytics.IPRegions)
+	}
+	if len(analytics.MostLiked) == 0 || analytics.MostLiked[0].ID != "c" {
+		t.Errorf("Unexpected most liked: %+v", analytics.MostLiked)
+	}
+
+	all, err := repo.GetAnalytics(&CommentAnalyticsFilter{})
+	if err != nil {
+		t.Fatalf("Failed to get analytics: %v", err)
+	}
+	if all.TotalComments != 4 || all.MostLiked[0].ID != "d" {
+		t.Errorf("Unexpected global analytics: %+v", all)
+	}
+
+	exported, err := repo.ListForExport(&CommentAnalyticsFilter{VideoID: "video-2"})
+	if err != nil {
+		t.Fatalf("Failed to list comments for export: %v", err)
+	}
+	if len(exported) != 1 || exported[0].ID != "d" {
+		t.Errorf("Unexpected export rows: %+v", exported)
+	}
+}
+
+func TestCommentRunDiff(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewCommentRepository()
+	t0 := time.Now().Add(-2 * time.Hour)
+
+	run1, err := repo.SaveVideoComments(&CommentVideo{VideoID: "v", LastCollectedAt: t0}, []Comment{
+		{ID: "keep", Content: "hello"},
+		{ID: "edit", Content: "before"},
+		{ID: "gone", Content: "will be removed"},
+	}, true)
+	if err != nil {
+		t.Fatalf("Failed to save run 1: %v", err)
+	}
+
+	// 同一会话内的多次上报（滚动加载）合并为一个批次
+	merged, err := repo.SaveVideoComments(&CommentVideo{VideoID: "v", LastCollectedAt: t0.Add(time.Minute)}, []Comment{
+		{ID: "keep", Content: "hello"},
+		{ID: "edit", Content: "before"},
+		{ID: "gone", Content: "will be removed"},
+		{ID: "late", Content: "loaded after scroll"},
+	}, true)
+	if err != nil {
+		t.Fatalf("Failed to save merged run: %v", err)
+	}
+	if merged.ID != run1.ID || merged.NewCount != 4 {
+		t.Errorf("Expected saves within session to merge into run %d, got %+v", run1.ID, merged)
+	}
+
+	run2, err := repo.SaveVideoComments(&CommentVideo{VideoID: "v", LastCollectedAt: t0.Add(time.Hour)}, []Comment{
+		{ID: "keep", Content: "hello", LikeCount: 3},
+		{ID: "edit", Content: "after"},
+		{ID: "late", Content: "loaded after scroll"},
+		{ID: "new", Content: "brand new"},
+	}, true)
+	if err != nil {
+		t.Fatalf("Failed to save run 2: %v", err)
+	}
+	if run2.ID == run1.ID {
+		t.Fatal("Expected a new run after the session gap")
+	}
+	if run2.NewCount != 1 || run2.ChangedCount != 1 || run2.DeletedCount != 1 || run2.TotalCount != 4 {
+		t.Errorf("Unexpected run 2 stats: %+v", run2)
+	}
+
+	diff, err := repo.GetRunDiff("v", run1.ID, run2.ID)
+	if err != nil {
+		t.Fatalf("Failed to diff runs: %v", err)
+	}
+	if len(diff.Added) != 1 || diff.Added[0].ID != "new" {
+		t.Errorf("Unexpected added: %+v", diff.Added)
+	}
+	if len(diff.Deleted) != 1 || diff.Deleted[0].ID != "gone" || diff.Deleted[0].DeletedAt == nil {
+		t.Errorf("Unexpected deleted: %+v", diff.Deleted)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].CommentID != "edit" || diff.Changed[0].OldContent != "before" || diff.Changed[0].NewContent != "after" {
+		t.Errorf("Unexpected changed: %+v", diff.Changed)
+	}
+
+	v, err := repo.GetVideo("v")
+	if err != nil {
+		t.Fatalf("Failed to get video: %v", err)
+	}
+	if v.CommentCount != 4 {
+		t.Errorf("Expected 4 visible comments, got %d", v.CommentCount)
+	}
+
+	if _, err := repo.GetRunDiff("v", run2.ID, run1.ID); err == nil {
+		t.Error("Expected error when from run is not earlier than to run")
+	}
+
+	// 中途停止的采集只加载到一部分评论：记为部分采集，未加载的评论不算删除
+	run3, err := repo.SaveVideoComments(&CommentVideo{VideoID: "v", OriginalCommentCount: 4, LastCollectedAt: t0.Add(2 * time.Hour)}, []Comment{
+		{ID: "keep", Content: "hello", LikeCount: 3},
+		{ID: "new", Content: "brand new"},
+	}, true)
+	if err != nil {
+		t.Fatalf("Failed to save run 3: %v", err)
+	}
+	if run3.FullUpdate || run3.DeletedCount != 0 || run3.TotalCount != 2 {
+		t.Errorf("Expected partial run 3, got %+v", run3)
+	}
+	diff, err = repo.GetRunDiff("v", run2.ID, run3.ID)
+	if err != nil {
+		t.Fatalf("Failed to diff partial run: %v", err)
+	}
+	if len(diff.Deleted) != 0 {
+		t.Errorf("Expected no deletions from a partial run, got %+v", diff.Deleted)
+	}
+	if v, _ := repo.GetVideo("v"); v == nil || v.CommentCount != 4 {
+		t.Errorf("Expected 4 visible comments after partial run, got %+v", v)
+	}
+
+	// 跨过部分采集的差异仍包含之前完整采集确认的删除
+	diff, err = repo.GetRunDiff("v", run1.ID, run3.ID)
+	if err != nil {
+		t.Fatalf("Failed to diff runs: %v", err)
+	}
+	if len(diff.Deleted) != 1 || diff.Deleted[0].ID != "gone" {
+		t.Errorf("Unexpected deleted across partial run: %+v", diff.Deleted)
+	}
+}
+
+func TestCrawlRepository(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewCrawlRepository()
+	job := &CrawlJob{Username: "v2_author@finder", Status: CrawlStatusRunning, Enqueue: true, IntervalMs: 3000}
+	if err := repo.CreateJob(job); err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+
+	older := time.Now().Add(-48 * time.Hour)
+	newer := time.Now().Add(-time.Hour)
+	added, err := repo.SaveVideos(job.ID, []CrawlVideo{
+		{VideoID: "a", Title: "older", VideoURL: "https://x/a?token=1", MediaType: 4, PublishTime: &older},
+		{VideoID: "b", Title: "newer", VideoURL: "https://x/b?token=1", MediaType: 4, PublishTime: &newer},
+	})
+	if err != nil {
+		t.Fatalf("Failed to save videos: %v", err)
+	}
+	if len(added) != 2 {
+		t.Fatalf("Expected 2 new videos, got %d", len(added))
+	}
+
+	// 重复抓取到的视频不算新发现，但会刷新带时效的链接
+	added, err = repo.SaveVideos(job.ID, []CrawlVideo{
+		{VideoID: "b", Title: "newer", VideoURL: "https://x/b?token=2", MediaType: 4, PublishTime: &newer},
+		{VideoID: "c", Title: "no time", MediaType: 2},
+	})
+	if err != nil {
+		t.Fatalf("Failed to save videos: %v", err)
+	}
+	if len(added) != 1 || added[0].VideoID != "c" {
+		t.Fatalf("Expected only c to be new, got %+v", added)
+	}
+
+	if err := repo.MarkEnqueued(job.ID, []string{"b"}); err != nil {
+		t.Fatalf("Failed to mark enqueued: %v", err)
+	}
+
+	result, err := repo.ListVideos(job.ID, &PaginationParams{Page: 1, PageSize: 10})
+	if err != nil {
+		t.Fatalf("Failed to list videos: %v", err)
+	}
+	if result.Total != 3 || len(result.Items) != 3 {
+		t.Fatalf("Expected 3 videos, got total=%d items=%d", result.Total, len(result.Items))
+	}
+	if result.Items[0].VideoID != "b" || !result.Items[0].Enqueued || result.Items[0].VideoURL != "https://x/b?token=2" {
+		t.Errorf("Unexpected first video: %+v", result.Items[0])
+	}
+	if result.Items[2].PublishTime != nil {
+		t.Errorf("Expected video without publish time last, got %+v", result.Items[2])
+	}
+
+	job.Status = CrawlStatusPaused
+	job.NextMarker = "m1"
+	job.Pages = 1
+	job.DiscoveredCount = 3
+	if err := repo.UpdateJob(job); err != nil {
+		t.Fatalf("Failed to update job: %v", err)
+	}
+	got, err := repo.GetJob(job.ID)
+	if err != nil || got == nil {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+	if got.Status != CrawlStatusPaused || got.NextMarker != "m1" || got.DiscoveredCount != 3 || !got.Enqueue {
+		t.Errorf("Unexpected job: %+v", got)
+	}
+
+	paused, err := repo.ListJobsByStatus(CrawlStatusPaused)
+	if err != nil || len(paused) != 1 {
+		t.Errorf("Expected 1 paused job, got %d (%v)", len(paused), err)
+	}
+	if missing, err := repo.GetJob(job.ID + 1); err != nil || missing != nil {
+		t.Errorf("Expected nil for missing job, got %+v (%v)", missing, err)
+	}
+}
+
+func TestWatchRepository(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewWatchRepository()
+	author := &WatchAuthor{Username: "v2_a@finder", Policy: WatchPolicyNotify, IntervalMinutes: 30, Enabled: true}
+	if err := repo.CreateAuthor(author); err != nil {
+		t.Fatalf("Failed to create author: %v", err)
+	}
+	if err := repo.CreateAuthor(author); err == nil {
+		t.Error("Expected error when watching the same author twice")
+	}
+
+	seen := time.Now().Add(-time.Hour)
+	author.Policy = WatchPolicyEnqueue
+	author.LastSeenAt = &seen
+	author.LastCheckedAt = &seen
+	if err := repo.UpdateAuthor(author); err != nil {
+		t.Fatalf("Failed to update author: %v", err)
+	}
+	got, err := repo.GetAuthor(author.Username)
+	if err != nil || got == nil {
+		t.Fatalf("Failed to get author: %v", err)
+	}
+	if got.Policy != WatchPolicyEnqueue || got.LastSeenAt == nil || !got.LastSeenAt.Equal(seen) || !got.Enabled {
+		t.Errorf("Unexpected author: %+v", got)
+	}
+
+	// 检查结果只更新检查相关的列，不覆盖检查期间修改的策略
+	stale := *got
+	stale.Policy = WatchPolicyNotify
+	stale.Nickname = "作者"
+	stale.ErrorMessage = "rate limited"
+	checked := time.Now()
+	stale.LastCheckedAt = &checked
+	if err := repo.UpdateCheckResult(&stale, 3); err != nil {
+		t.Fatalf("Failed to update check result: %v", err)
+	}
+	got, err = repo.GetAuthor(author.Username)
+	if err != nil || got == nil {
+		t.Fatalf("Failed to get author: %v", err)
+	}
+	if got.Policy != WatchPolicyEnqueue || got.Nickname != "作者" || got.ErrorMessage != "rate limited" ||
+		got.NewVideoCount != 3 || got.LastCheckedAt == nil || !got.LastCheckedAt.Equal(checked) {
+		t.Errorf("Unexpected author after check: %+v", got)
+	}
+
+	published := time.Now()
+	added, err := repo.SaveVideos([]WatchVideo{
+		{Username: author.Username, VideoID: "n1", Title: "new 1", MediaType: 4, PublishTime: &published},
+		{Username: author.Username, VideoID: "n2", Title: "new 2", MediaType: 4, PublishTime: &published},
+	})
+	if err != nil || len(added) != 2 {
+		t.Fatalf("Expected 2 saved videos, got %d (%v)", len(added), err)
+	}
+	added, err = repo.SaveVideos([]WatchVideo{{Username: author.Username, VideoID: "n1"}})
+	if err != nil || len(added) != 0 {
+		t.Errorf("Expected duplicate video to be ignored, got %d (%v)", len(added), err)
+	}
+	if err := repo.MarkEnqueued(author.Username, []string{"n2"}); err != nil {
+		t.Fatalf("Failed to mark enqueued: %v", err)
+	}
+
+	result, err := repo.ListVideos(author.Username, &PaginationParams{Page: 1, PageSize: 10})
+	if err != nil {
+		t.Fatalf("Failed to list videos: %v", err)
+	}
+	if result.Total != 2 {
+		t.Fatalf("Expected 2 videos, got %d", result.Total)
+	}
+	enqueued := 0
+	for _, v := range result.Items {
+		if v.Enqueued {
+			enqueued++
+		}
+	}
+	if enqueued != 1 {
+		t.Errorf("Expected 1 enqueued video, got %d", enqueued)
+	}
+
+	// 取消关注时级联删除检测到的视频
+	if err := repo.DeleteAuthor(author.Username); err != nil {
+		t.Fatalf("Failed to delete author: %v", err)
+	}
+	if err := repo.DeleteAuthor(author.Username); err != sql.ErrNoRows {
+		t.Errorf("Expected sql.ErrNoRows for missing author, got %v", err)
+	}
+	result, err = repo.ListVideos("", &PaginationParams{Page: 1, PageSize: 10})
+	if err != nil || result.Total != 0 {
+		t.Errorf("Expected videos to be deleted with author, got %d (%v)", result.Total, err)
+	}
+}
+
+func TestFeedProfileCacheRepository(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewFeedProfileCacheRepository()
+	now := time.Now()
+	if err := repo.Put(&FeedProfileCache{
+		ObjectID: "o1", NonceID: "n1", Data: []byte(`{"v":1}`),
+		FetchedAt: now, ExpiresAt: now.Add(time.Minute),
+	}); err != nil {
+		t.Fatalf("Failed to put cache: %v", err)
+	}
+
+	entry, err := repo.Get("o1", "n1")
+	if err != nil || entry == nil || string(entry.Data) != `{"v":1}` {
+		t.Fatalf("Unexpected cache entry: %+v (%v)", entry, err)
+	}
+	if entry, _ := repo.Get("o1", "other"); entry != nil {
+		t.Error("Expected miss for another nonceId")
+	}
+
+	// 覆盖写入
+	if err := repo.Put(&FeedProfileCache{
+		ObjectID: "o1", NonceID: "n1", Data: []byte(`{"v":2}`),
+		FetchedAt: now, ExpiresAt: now.Add(time.Minute),
+	}); err != nil {
+		t.Fatalf("Failed to overwrite cache: %v", err)
+	}
+	if entry, _ := repo.Get("o1", "n1"); entry == nil || string(entry.Data) != `{"v":2}` {
+		t.Errorf("Expected overwritten entry, got %+v", entry)
+	}
+
+	// 过期的条目不返回，并可被清理
+	if err := repo.Put(&FeedProfileCache{
+		ObjectID: "o2", NonceID: "n2", Data: []byte(`{}`),
+		FetchedAt: now.Add(-time.Hour), ExpiresAt: now.Add(-time.Minute),
+	}); err != nil {
+		t.Fatalf("Failed to put expired cache: %v", err)
+	}
+	if entry, _ := repo.Get("o2", "n2"); entry != nil {
+		t.Error("Expected expired entry to be ignored")
+	}
+	if n, err := repo.DeleteExpired(); err != nil || n != 1 {
+		t.Errorf("Expected 1 expired entry deleted, got %d (%v)", n, err)
+	}
+}
+
+func TestVideoSourceRefresh(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	browseRepo := NewBrowseHistoryRepository()
+	if err := browseRepo.Create(&BrowseRecord{
+		ID: "v1", Title: "t", VideoURL: "http://old", DecryptKey: "k1", NonceID: "n1", BrowseTime: time.Now(),
+	}); err != nil {
+		t.Fatalf("Failed to create browse record: %v", err)
+	}
+	queueRepo := NewQueueRepository()
+	if err := queueRepo.Add(&QueueItem{
+		ID: "q1", VideoID: "v1", Title: "t", VideoURL: "http://old", DecryptKey: "k1",
+		Status: QueueStatusFailed, AddedTime: time.Now(), NonceID: "n1",
+	}); err != nil {
+		t.Fatalf("Failed to add queue item: %v", err)
+	}
+
+	if nonceID, err := queueRepo.GetNonceID("v1"); err != nil || nonceID != "n1" {
+		t.Errorf("Expected queue nonce n1, got %q (%v)", nonceID, err)
+	}
+	if nonceID, _ := queueRepo.GetNonceID("missing"); nonceID != "" {
+		t.Errorf("Expected empty nonce for missing video, got %q", nonceID)
+	}
+
+	if n, err := browseRepo.UpdateVideoSource("v1", "http://new", "k2"); err != nil || n != 1 {
+		t.Fatalf("Failed to update browse video source: %d (%v)", n, err)
+	}
+	if n, err := queueRepo.UpdateVideoSource("v1", "http://new", "k2"); err != nil || n != 1 {
+		t.Fatalf("Failed to update queue video source: %d (%v)", n, err)
+	}
+
+	record, _ := browseRepo.GetByID("v1")
+	if record == nil || record.VideoURL != "http://new" || record.DecryptKey != "k2" || record.NonceID != "n1" {
+		t.Errorf("Unexpected browse record: %+v", record)
+	}
+	item, _ := queueRepo.GetByID("q1")
+	if item == nil || item.VideoURL != "http://new" || item.DecryptKey != "k2" || item.NonceID != "n1" {
+		t.Errorf("Unexpected queue item: %+v", item)
+	}
+}
+
+func TestVideoFormatRepository(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewVideoFormatRepository()
+	if err := repo.Save("v1", []VideoFormat{
+		{FileFormat: "sd", Width: 480, Height: 854, BitRate: 600},
+		{FileFormat: "hd", Width: 1080, Height: 1920, BitRate: 2400, CodingFormat: "h265"},
+		{FileFormat: "md", Width: 720, Height: 1280, BitRate: 1200},
+	}); err != nil {
+		t.Fatalf("Failed to save formats: %v", err)
+	}
+	formats, err := repo.List("v1")
+	if err != nil {
+		t.Fatalf("Failed to list formats: %v", err)
+	}
+	if len(formats) != 3 || formats[0].FileFormat != "hd" || formats[2].FileFormat != "sd" {
+		t.Fatalf("Unexpected formats order: %+v", formats)
+	}
+	if formats[0].CodingFormat != "h265" || formats[1].ShortSide() != 720 {
+		t.Errorf("Unexpected format fields: %+v", formats)
+	}
+
+	// 再次保存时替换原有编码
+	if err := repo.Save("v1", []VideoFormat{{FileFormat: "hd", Width: 1080, Height: 1920}}); err != nil {
+		t.Fatalf("Failed to replace formats: %v", err)
+	}
+	if formats, _ := repo.List("v1"); len(formats) != 1 {
+		t.Errorf("Expected 1 format after replace, got %d", len(formats))
+	}
+
+	queueRepo := NewQueueRepository()
+	if err := queueRepo.Add(&QueueItem{
+		ID: "q1", VideoID: "v1", Title: "t", VideoURL: "http://v", Status: QueueStatusPending,
+		AddedTime: time.Now(), Quality: "<=720p",
+	}); err != nil {
+		t.Fatalf("Failed to add queue item: %v", err)
+	}
+	if item, err := queueRepo.GetByID("q1"); err != nil || item.Quality != "<=720p" {
+		t.Errorf("Expected queue quality <=720p, got %+v (%v)", item, err)
+	}
+
+	cases := []struct {
+		quality string
+		want    QualityPolicy
+		wantErr bool
+	}{
+		{"", QualityPolicy{Mode: QualityOriginal}, false},
+		{"Best", QualityPolicy{Mode: QualityBest}, false},
+		{"smallest", QualityPolicy{Mode: QualitySmallest}, false},
+		{"<=720p", QualityPolicy{Mode: QualityModeMaxHeight, MaxHeight: 720}, false},
+		{"≤1080p", QualityPolicy{Mode: QualityModeMaxHeight, MaxHeight: 1080}, false},
+		{"480", QualityPolicy{Mode: QualityModeMaxHeight, MaxHeight: 480}, false},
+		{"xWT111", QualityPolicy{Mode: QualityModeFormat, Format: "xWT111"}, false},
+		{"<= abc", QualityPolicy{}, true},
+	}
+	for _, c := range cases {
+		got, err := ParseQualityPolicy(c.quality)
+		if (err != nil) != c.wantErr || got != c.want {
+			t.Errorf("ParseQualityPolicy(%q) = %+v, %v", c.quality, got, err)
+		}
+	}
+
+	settingsRepo := NewSettingsRepository()
+	settings := DefaultSettings()
+	settings.DownloadQuality = "bad quality"
+	if err := settingsRepo.Validate(settings); err == nil {
+		t.Error("Expected validation error for invalid quality")
+	}
+	settings.DownloadQuality = "best"
+	if err := settingsRepo.SaveAndValidate(settings); err != nil {
+		t.Fatalf("Failed to save settings: %v", err)
+	}
+	if loaded, _ := settingsRepo.Load(); loaded.DownloadQuality != "best" {
+		t.Errorf("Expected download quality best, got %q", loaded.DownloadQuality)
+	}
+}
+
+func TestAPITokenRepository(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewAPITokenRepository()
+	if exists, err := repo.Exists(); err != nil || exists {
+		t.Fatalf("Expected no tokens, got %v (%v)", exists, err)
+	}
+
+	expires := time.Now().Add(time.Hour)
+	token := &APIToken{ID: "t1", Name: "viewer", Prefix: "wxc_1234", Scopes: []string{ScopeRead}, ExpiresAt: &expires}
+	if err := repo.Create(token, "hash1"); err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	if err := repo.Create(&APIToken{ID: "t2", Name: "viewer", Scopes: []string{ScopeAdmin}}, "hash2"); err == nil {
+		t.Error("Expected duplicate name to fail")
+	}
+
+	got, err := repo.GetByHash("hash1")
+	if err != nil || got == nil || got.Name != "viewer" || got.ExpiresAt == nil {
+		t.Fatalf("Unexpected token by hash: %+v (%v)", got, err)
+	}
+	if !got.HasScope(ScopeRead) || got.HasScope(ScopeDownload) {
+		t.Errorf("Unexpected scopes: %v", got.Scopes)
+	}
+	if got.Expired(time.Now()) || !got.Expired(expires.Add(time.Second)) {
+		t.Error("Unexpected expiry check")
+	}
+	if missing, _ := repo.GetByHash("missing"); missing != nil {
+		t.Error("Expected nil for unknown hash")
+	}
+
+	if err := repo.TouchLastUsed("t1", time.Now()); err != nil {
+		t.Fatalf("Failed to touch token: %v", err)
+	}
+	tokens, err := repo.List()
+	if err != nil || len(tokens) != 1 || tokens[0].LastUsedAt == nil {
+		t.Fatalf("Unexpected token list: %+v (%v)", tokens, err)
+	}
+
+	if n, err := repo.Delete("viewer"); err != nil || n != 1 {
+		t.Fatalf("Failed to delete token by name: %d (%v)", n, err)
+	}
+	if exists, _ := repo.Exists(); exists {
+		t.Error("Expected no tokens after delete")
+	}
+
+	admin := &APIToken{Scopes: []string{ScopeAdmin}}
+	if !admin.HasScope(ScopeSettings) {
+		t.Error("Expected admin to have every scope")
+	}
+	if scopes, err := ParseScopes(" Read, download,read "); err != nil || len(scopes) != 2 {
+		t.Errorf("Unexpected parsed scopes: %v (%v)", scopes, err)
+	}
+	if _, err := ParseScopes("read,owner"); err == nil {
+		t.Error("Expected invalid scope to fail")
+	}
+	if _, err := ParseScopes(""); err == nil {
+		t.Error("Expected empty scopes to fail")
+	}
+}
+
+func TestAuditRepository(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewAuditRepository()
+	entries := []*AuditEntry{
+		{Actor: "ops", ActorType: AuditActorToken, Action: "downloads.delete", TargetType: "download_record", TargetIDs: []string{"d1", "d12"}, SourceIP: "127.0.0.1"},
+		{Actor: "ops", ActorType: AuditActorToken, Action: "settings.update", TargetType: "settings",
+			Before: json.RawMessage(`{"theme":"light"}`), After: json.RawMessage(`{"theme":"dark"}`)},
+		{Actor: AuditActorCloud, ActorType: AuditActorCloud, Action: "cloud.api_call", TargetType: "api", TargetIDs: []string{"key:channels:feed_profile"}},
+		{Actor: AuditActorSystem, ActorType: AuditActorSystem, Action: "downloads.clear", TargetType: "download_record"},
+	}
+	for _, e := range entries {
+		if err := repo.Append(e); err != nil {
+			t.Fatalf("Failed to append audit entry: %v", err)
+		}
+	}
+
+	all, err := repo.List(nil, &PaginationParams{Page: 1, PageSize: 10})
+	if err != nil || all.Total != 4 || all.Items[0].Action != "downloads.clear" {
+		t.Fatalf("Unexpected audit list: %+v (%v)", all, err)
+	}
+	if got := all.Items[2]; string(got.After) != `{"theme":"dark"}` || string(got.Before) != `{"theme":"light"}` {
+		t.Errorf("Unexpected settings values: %s -> %s", got.Before, got.After)
+	}
+
+	tests := []struct {
+		name   string
+		filter AuditFilter
+		want   int64
+	}{
+		{"actor", AuditFilter{Actor: "ops"}, 2},
+		{"action prefix", AuditFilter{Action: "downloads."}, 2},
+		{"exact action", AuditFilter{Action: "downloads.delete"}, 1},
+		{"target id", AuditFilter{TargetID: "d1"}, 1},
+		{"actor type", AuditFilter{ActorType: AuditActorCloud, TargetType: "api"}, 1},
+	}
+	for _, tt := range tests {
+		result, err := repo.List(&tt.filter, &PaginationParams{Page: 1, PageSize: 10})
+		if err != nil || result.Total != tt.want {
+			t.Errorf("%s: expected %d entries, got %+v (%v)", tt.name, tt.want, result, err)
+		}
+	}
+
+	future := time.Now().Add(time.Hour)
+	if result, _ := repo.List(&AuditFilter{Since: &future}, &PaginationParams{Page: 1, PageSize: 10}); result.Total != 0 {
+		t.Errorf("Expected no entries after %v, got %d", future, result.Total)
+	}
+
+	exported, err := repo.ListForExport(&AuditFilter{Actor: "ops"})
+	if err != nil || len(exported) != 2 || exported[0].Action != "downloads.delete" || len(exported[0].TargetIDs) != 2 {
+		t.Fatalf("Unexpected export: %+v (%v)", exported, err)
+	}
+
+	// 审计记录只能追加
+	if _, err := repo.db.Exec(`UPDATE audit_log SET actor = 'x'`); err == nil {
+		t.Error("Expected update to be rejected")
+	}
+	if _, err := repo.db.Exec(`DELETE FROM audit_log`); err == nil {
+		t.Error("Expected delete to be rejected")
+	}
+}
+
+func TestTrashSoftDelete(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	downloads := NewDownloadRecordRepository()
+	for _, id := range []string{"d1", "d2"} {
+		if err := downloads.Create(&DownloadRecord{ID: id, VideoID: id, Title: id, FilePath: "/downloads/" + id + ".mp4",
+			Status: DownloadStatusCompleted, DownloadTime: time.Now()}); err != nil {
+			t.Fatalf("Failed to create download record: %v", err)
+		}
+	}
+
+	if err := downloads.Delete("d1"); err != nil {
+		t.Fatalf("Failed to delete download record: %v", err)
+	}
+	if record, _ := downloads.GetByID("d1"); record != nil {
+		t.Error("Expected trashed record to be hidden from GetByID")
+	}
+	if count, _ := downloads.Count(); count != 1 {
+		t.Errorf("Expected 1 active record, got %d", count)
+	}
+	trashed, err := downloads.List(&FilterParams{PaginationParams: PaginationParams{Page: 1, PageSize: 10}, Trashed: true})
+	if err != nil || trashed.Total != 1 || trashed.Items[0].ID != "d1" || trashed.Items[0].DeletedAt == nil {
+		t.Fatalf("Unexpected trash list: %+v (%v)", trashed, err)
+	}
+
+	if err := downloads.SetTrashPath("d1", "/downloads/.trash/d1/d1.mp4"); err != nil {
+		t.Fatalf("Failed to set trash path: %v", err)
+	}
+	records, err := downloads.GetTrashed([]string{"d1", "d2"}, nil)
+	if err != nil || len(records) != 1 || records[0].TrashPath != "/downloads/.trash/d1/d1.mp4" {
+		t.Fatalf("Unexpected trashed records: %+v (%v)", records, err)
+	}
+	past := time.Now().Add(-time.Hour)
+	if records, _ := downloads.GetTrashed(nil, &past); len(records) != 0 {
+		t.Errorf("Expected no records trashed before %v, got %d", past, len(records))
+	}
+
+	if n, err := downloads.Restore([]string{"d1", "d2"}); err != nil || n != 1 {
+		t.Fatalf("Expected 1 restored record, got %d (%v)", n, err)
+	}
+	if record, _ := downloads.GetByID("d1"); record == nil {
+		t.Fatal("Expected restored record")
+	}
+
+	// 永久删除只作用于回收站中的记录
+	if n, _ := downloads.Purge([]string{"d2"}); n != 0 {
+		t.Errorf("Expected active record not to be purged, got %d", n)
+	}
+	downloads.DeleteMany([]string{"d2"})
+	if n, err := downloads.Purge([]string{"d2"}); err != nil || n != 1 {
+		t.Errorf("Expected 1 purged record, got %d (%v)", n, err)
+	}
+
+	browse := NewBrowseHistoryRepository()
+	record := &BrowseRecord{ID: "b1", Title: "Browse", Author: "Author", BrowseTime: time.Now()}
+	if err := browse.Create(record); err != nil {
+		t.Fatalf("Failed to create browse record: %v", err)
+	}
+	if err := browse.Delete("b1"); err != nil {
+		t.Fatalf("Failed to delete browse record: %v", err)
+	}
+	if result, _ := browse.List(&PaginationParams{Page: 1, PageSize: 10}); result.Total != 0 {
+		t.Errorf("Expected trashed browse record to be hidden, got %d", result.Total)
+	}
+	browseTrash, err := browse.ListTrashed(&PaginationParams{Page: 1, PageSize: 10})
+	if err != nil || browseTrash.Total != 1 || browseTrash.Items[0].DeletedAt == nil {
+		t.Fatalf("Unexpected browse trash: %+v (%v)", browseTrash, err)
+	}
+	if n, err := browse.Restore([]string{"b1"}); err != nil || n != 1 {
+		t.Fatalf("Expected 1 restored browse record, got %d (%v)", n, err)
+	}
+
+	// 再次浏览已移入回收站的视频时替换回收站中的记录
+	browse.Delete("b1")
+	if err := browse.Create(record); err != nil {
+		t.Fatalf("Failed to recreate trashed browse record: %v", err)
+	}
+	if got, _ := browse.GetByID("b1"); got == nil {
+		t.Error("Expected recreated browse record")
+	}
+
+	browse.Clear()
+	if n, err := browse.PurgeBefore(time.Now().Add(time.Second)); err != nil || n != 1 {
+		t.Errorf("Expected 1 purged browse record, got %d (%v)", n, err)
+	}
+}
+
+func TestTrashThenRecreateDownload(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	downloads := NewDownloadRecordRepository()
+	record := &DownloadRecord{ID: "v1", VideoID: "v1", Title: "Old", FilePath: "/downloads/v1.mp4",
+		Status: DownloadStatusCompleted, DownloadTime: time.Now()}
+	if err := downloads.Create(record); err != nil {
+		t.Fatalf("Failed to create download record: %v", err)
+	}
+	downloads.Delete("v1")
+	downloads.SetTrashPath("v1", "/downloads/.trash/v1/v1.mp4")
+
+	// 重新下载同一视频不能覆盖回收站中的记录，否则回收站文件会失去记录
+	again := &DownloadRecord{ID: "v1", VideoID: "v1", Title: "New", FilePath: "/downloads/v1.mp4",
+		Status: DownloadStatusCompleted, DownloadTime: time.Now()}
+	if err := downloads.Create(again); !errors.Is(err, ErrDownloadInTrash) {
+		t.Fatalf("Expected ErrDownloadInTrash, got %v", err)
+	}
+	records, err := downloads.GetTrashed([]string{"v1"}, nil)
+	if err != nil || len(records) != 1 || records[0].Title != "Old" || records[0].TrashPath != "/downloads/.trash/v1/v1.mp4" {
+		t.Fatalf("Expected trashed record to be kept, got %+v (%v)", records, err)
+	}
+
+	// 回收站中的记录换成新 ID 后可以重新创建，回收站中的记录和文件位置不变
+	detached, err := downloads.DetachTrashed("v1")
+	if err != nil || detached == "" || detached == "v1" {
+		t.Fatalf("Failed to detach trashed record: %q (%v)", detached, err)
+	}
+	if other, err := downloads.DetachTrashed("v1"); err != nil || other != "" {
+		t.Errorf("Expected nothing left to detach, got %q (%v)", other, err)
+	}
+	if err := downloads.Create(again); err != nil {
+		t.Fatalf("Failed to recreate download record: %v", err)
+	}
+	if got, _ := downloads.GetByID("v1"); got == nil || got.Title != "New" {
+		t.Errorf("Expected recreated record, got %+v", got)
+	}
+	records, err = downloads.GetTrashed(nil, nil)
+	if err != nil || len(records) != 1 || records[0].ID != detached || records[0].VideoID != "v1" ||
+		records[0].Title != "Old" || records[0].TrashPath != "/downloads/.trash/v1/v1.mp4" {
+		t.Fatalf("Expected detached record to stay in the trash, got %+v (%v)", records, err)
+	}
+	if n, err := downloads.Restore([]string{detached}); err != nil || n != 1 {
+		t.Errorf("Expected detached record to be restorable, got %d (%v)", n, err)
+	}
+
+	// 未删除的同 ID 记录仍被覆盖
+	again.Title = "Newer"
+	if err := downloads.Create(again); err != nil {
+		t.Fatalf("Failed to replace download record: %v", err)
+	}
+	if got, _ := downloads.GetByID("v1"); got == nil || got.Title != "Newer" {
+		t.Errorf("Expected replaced record, got %+v", got)
+	}
+}
+
+func TestWebhookRepository(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewWebhookRepository()
+	hook := &Webhook{ID: "hook-1", Name: "n8n", URL: "http://127.0.0.1:5678/webhook/x",
+		Events: []string{WebhookEventDownloadCompleted, WebhookEventBatchFinished}, Secret: "s3cret", Enabled: true}
+	if err := repo.Create(hook); err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+	disabled := &Webhook{ID: "hook-2", Name: "bot", URL: "http://127.0.0.1/bot", Events: []string{WebhookEventAll}}
+	if err := repo.Create(disabled); err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+
+	got, err := repo.GetByID("hook-1")
+	if err != nil || got == nil || !got.HasSecret || got.Secret != "s3cret" || len(got.Events) != 2 {
+		t.Fatalf("Unexpected webhook: %+v (%v)", got, err)
+	}
+	if !got.Subscribes(WebhookEventBatchFinished) || got.Subscribes(WebhookEventTranscriptReady) {
+		t.Errorf("Unexpected subscriptions: %v", got.Events)
+	}
+	if !disabled.Subscribes(WebhookEventTranscriptFailed) {
+		t.Error("Expected * to subscribe to all events")
+	}
+	enabled, err := repo.ListEnabled()
+	if err != nil || len(enabled) != 1 || enabled[0].ID != "hook-1" {
+		t.Fatalf("Unexpected enabled webhooks: %+v (%v)", enabled, err)
+	}
+
+	if _, err := ParseWebhookEvents([]string{"download.completed", "nope"}); err == nil {
+		t.Error("Expected invalid event to be rejected")
+	}
+
+	// 只保留最近的 keep 条推送记录
+	for i := 0; i < 4; i++ {
+		d := &WebhookDelivery{WebhookID: "hook-1", Event: WebhookEventDownloadCompleted,
+			Payload: json.RawMessage(`{"event":"download.completed"}`), Status: WebhookDeliveryPending}
+		if err := repo.CreateDelivery(d, 3); err != nil {
+			t.Fatalf("Failed to create delivery: %v", err)
+		}
+		if i == 3 {
+			d.Status, d.Attempts, d.ResponseCode = WebhookDeliverySuccess, 2, 200
+			if err := repo.UpdateDelivery(d); err != nil {
+				t.Fatalf("Failed to update delivery: %v", err)
+			}
+		}
+	}
+	deliveries, err := repo.ListDeliveries("hook-1", &PaginationParams{Page: 1, PageSize: 10})
+	if err != nil || deliveries.Total != 3 {
+		t.Fatalf("Unexpected deliveries: %+v (%v)", deliveries, err)
+	}
+	if latest := deliveries.Items[0]; latest.Status != WebhookDeliverySuccess || latest.Attempts != 2 || string(latest.Payload) != `{"event":"download.completed"}` {
+		t.Errorf("Unexpected latest delivery: %+v", latest)
+	}
+
+	if n, err := repo.Delete("hook-1"); err != nil || n != 1 {
+		t.Fatalf("Expected 1 deleted webhook, got %d (%v)", n, err)
+	}
+	if deliveries, _ := repo.ListDeliveries("hook-1", &PaginationParams{Page: 1, PageSize: 10}); deliveries.Total != 0 {
+		t.Errorf("Expected deliveries to be deleted, got %d", deliveries.Total)
+	}
+}
+
+func TestAuthorRepository(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	browseRepo := NewBrowseHistoryRepository()
+	downloadRepo := NewDownloadRecordRepository()
+	authorRepo := NewAuthorRepository()
+
+	now := time.Now().Truncate(time.Second)
+	browses := []*BrowseRecord{
+		{ID: "v1", Title: "Alpha One", Author: "alice", AuthorID: "a-1", BrowseTime: now.Add(-48 * time.Hour)},
+		{ID: "v2", Title: "Alpha Two", Author: "alice", AuthorID: "a-1", BrowseTime: now.Add(-time.Hour)},
+		{ID: "v3", Title: "Beta", Author: "bob", AuthorID: "b-1", BrowseTime: now.Add(-2 * time.Hour)},
+	}
+	for _, b := range browses {
+		if err := browseRepo.Create(b); err != nil {
+			t.Fatalf("Failed to create browse record: %v", err)
+		}
+	}
+	downloads := []*DownloadRecord{
+		{ID: "d1", VideoID: "v1", Title: "Alpha One", Author: "alice", FileSize: 100, Status: DownloadStatusCompleted, DownloadTime: now},
+		{ID: "d2", VideoID: "v1", Title: "Alpha One", Author: "alice", FileSize: 50, Status: DownloadStatusFailed, DownloadTime: now},
+		{ID: "d3", VideoID: "v9", Title: "Gamma", Author: "carol", FileSize: 10, Status: DownloadStatusCompleted, DownloadTime: now},
+	}
+	for _, d := range downloads {
+		if err := downloadRepo.Create(d); err != nil {
+			t.Fatalf("Failed to create download record: %v", err)
+		}
+	}
+
+	// 浏览记录按作者和时间过滤
+	start := now.Add(-3 * time.Hour)
+	filtered, err := browseRepo.ListFiltered(&FilterParams{
+		PaginationParams: PaginationParams{Page: 1, PageSize: 10, SortBy: "browse_time", SortDesc: true},
+		StartDate:        &start,
+		Author:           "alice",
+	})
+	if err != nil {
+		t.Fatalf("Failed to list filtered browse records: %v", err)
+	}
+	if filtered.Total != 1 || filtered.Items[0].ID != "v2" {
+		t.Errorf("Expected only v2, got %+v", filtered.Items)
+	}
+
+	// 按视频 ID 批量查询下载记录
+	byVideo, err := downloadRepo.ListByVideoIDs([]string{"v1", "v2"})
+	if err != nil {
+		t.Fatalf("Failed to list downloads by video IDs: %v", err)
+	}
+	if len(byVideo) != 2 {
+		t.Errorf("Expected 2 downloads for v1, got %d", len(byVideo))
+	}
+
+	// 只有下载记录的作者也出现在汇总中
+	result, err := authorRepo.List(&AuthorFilterParams{
+		PaginationParams: PaginationParams{Page: 1, PageSize: 10, SortBy: "name"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to list authors: %v", err)
+	}
+	if result.Total != 3 {
+		t.Fatalf("Expected 3 authors, got %d", result.Total)
+	}
+	alice := result.Items[0]
+	if alice.Name != "alice" || alice.AuthorID != "a-1" || alice.BrowseCount != 2 ||
+		alice.DownloadCount != 2 || alice.DownloadedSize != 150 {
+		t.Errorf("Unexpected stats for alice: %+v", alice)
+	}
+	if alice.LastBrowseTime == nil || !alice.LastBrowseTime.Equal(now.Add(-time.Hour)) {
+		t.Errorf("Expected last browse time %v, got %v", now.Add(-time.Hour), alice.LastBrowseTime)
+	}
+	carol := result.Items[2]
+	if carol.Name != "carol" || carol.BrowseCount != 0 || carol.LastBrowseTime != nil || carol.LastDownloadTime == nil {
+		t.Errorf("Unexpected stats for carol: %+v", carol)
+	}
+
+	// 回收站中的记录不计入
+	if _, err := downloadRepo.DeleteMany([]string{"d2"}); err != nil {
+		t.Fatalf("Failed to delete download record: %v", err)
+	}
+	authors, err := authorRepo.GetByNames([]string{"alice", "nobody"})
+	if err != nil {
+		t.Fatalf("Failed to get authors by names: %v", err)
+	}
+	if len(authors) != 1 || authors[0].DownloadCount != 1 || authors[0].DownloadedSize != 100 {
+		t.Errorf("Expected alice with 1 download, got %+v", authors)
+	}
+}
+
+func TestUserRepository(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewUserRepository()
+	sessions := NewUserSessionRepository()
+	if exists, err := repo.Exists(); err != nil || exists {
+		t.Fatalf("Expected no users, got %v (%v)", exists, err)
+	}
+
+	user := &User{ID: "u1", Username: "Alice", Scopes: []string{ScopeRead, ScopeDownload}}
+	if err := repo.Create(user, "hash1"); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if err := repo.Create(&User{ID: "u2", Username: "alice", Scopes: []string{ScopeAdmin}}, "hash2"); err == nil {
+		t.Error("Expected duplicate username (case-insensitive) to fail")
+	}
+
+	got, err := repo.GetByUsername("ALICE")
+	if err != nil || got == nil || got.ID != "u1" || got.LastLoginAt != nil {
+		t.Fatalf("Unexpected user by username: %+v (%v)", got, err)
+	}
+	if !got.HasScope(ScopeDownload) || got.HasScope(ScopeSettings) {
+		t.Errorf("Unexpected scopes: %v", got.Scopes)
+	}
+	if hash, _ := repo.GetPasswordHash("u1"); hash != "hash1" {
+		t.Errorf("Unexpected password hash: %q", hash)
+	}
+	if missing, _ := repo.GetByID("missing"); missing != nil {
+		t.Error("Expected nil for unknown user")
+	}
+
+	prefs := &UserPreferences{Theme: ThemeDark, PageSizes: map[string]int{"browse": 50}}
+	if err := repo.UpdatePreferences("u1", prefs); err != nil {
+		t.Fatalf("Failed to update preferences: %v", err)
+	}
+	if err := repo.TouchLogin("u1", time.Now()); err != nil {
+		t.Fatalf("Failed to touch login: %v", err)
+	}
+	got, _ = repo.GetByID("u1")
+	if got.Preferences.Theme != ThemeDark || got.Preferences.PageSizes["browse"] != 50 || got.LastLoginAt == nil {
+		t.Errorf("Unexpected preferences after update: %+v", got)
+	}
+
+	// 会话按过期时间清理，删除用户时一并删除
+	now := time.Now()
+	for _, s := range []*UserSession{
+		{ID: "s1", UserID: "u1", CSRFToken: "c1", CreatedAt: now, LastSeenAt: now, ExpiresAt: now.Add(time.Hour)},
+		{ID: "s2", UserID: "u1", CSRFToken: "c2", CreatedAt: now, LastSeenAt: now, ExpiresAt: now.Add(-time.Hour)},
+		{ID: "s3", UserID: "u1", CSRFToken: "c3", CreatedAt: now, LastSeenAt: now, ExpiresAt: now.Add(time.Hour)},
+	} {
+		if err := sessions.Create(s); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+	}
+	if session, err := sessions.Get("s1"); err != nil || session == nil || session.CSRFToken != "c1" {
+		t.Fatalf("Unexpected session: %+v (%v)", session, err)
+	}
+	if n, err := sessions.DeleteExpired(now); err != nil || n != 1 {
+		t.Errorf("Expected 1 expired session deleted, got %d (%v)", n, err)
+	}
+	if n, err := sessions.DeleteByUser("u1", "s1"); err != nil || n != 1 {
+		t.Errorf("Expected 1 other session deleted, got %d (%v)", n, err)
+	}
+	if n, err := repo.Delete("u1"); err != nil || n != 1 {
+		t.Fatalf("Failed to delete user: %d (%v)", n, err)
+	}
+	if session, _ := sessions.Get("s1"); session != nil {
+		t.Error("Expected sessions to be deleted with the user")
+	}
+
+	if err := (&UserPreferences{Theme: "blue"}).Validate(); err == nil {
+		t.Error("Expected invalid theme to fail")
+	}
+	if err := (&UserPreferences{PageSizes: map[string]int{"queue": 10}}).Validate(); err == nil {
+		t.Error("Expected unknown page to fail")
+	}
+	if err := (&UserPreferences{PageSizes: map[string]int{"downloads": 101}}).Validate(); err == nil {
+		t.Error("Expected page size over 100 to fail")
+	}
+}
+
+func TestInitiatedByRoundTrip(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	queueRepo := NewQueueRepository()
+	item := &QueueItem{ID: "q1", VideoID: "v1", Title: "Video", Status: QueueStatusPending, AddedTime: time.Now(), InitiatedBy: "alice"}
+	if err := queueRepo.Add(item); err != nil {
+		t.Fatalf("Failed to add queue item: %v", err)
+	}
+	if got, err := queueRepo.GetByID("q1"); err != nil || got == nil || got.InitiatedBy != "alice" {
+		t.Fatalf("Unexpected queue item: %+v (%v)", got, err)
+	}
+
+	downloadRepo := NewDownloadRecordRepository()
+	now := time.Now()
+	for _, d := range []*DownloadRecord{
+		{ID: "d1", VideoID: "v1", Title: "Video", Status: DownloadStatusCompleted, DownloadTime: now, InitiatedBy: "alice"},
+		{ID: "d2", VideoID: "v2", Title: "Other", Status: DownloadStatusCompleted, DownloadTime: now},
+	} {
+		if err := downloadRepo.Create(d); err != nil {
+			t.Fatalf("Failed to create download record: %v", err)
+		}
+	}
+	if got, err := downloadRepo.GetByID("d1"); err != nil || got == nil || got.InitiatedBy != "alice" {
+		t.Fatalf("Unexpected download record: %+v (%v)", got, err)
+	}
+	result, err := downloadRepo.List(&FilterParams{PaginationParams: PaginationParams{Page: 1, PageSize: 10}, InitiatedBy: "alice"})
+	if err != nil {
+		t.Fatalf("Failed to list download records: %v", err)
+	}
+	if result.Total != 1 || result.Items[0].ID != "d1" {
+		t.Errorf("Expected only alice's download, got %+v", result.Items)
+	}
+}
@@ -2,11 +2,20 @@ package database
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 )
 
+// DownloadSortColumns 下载记录列表可用的排序列
+var DownloadSortColumns = map[string]bool{
+	"download_time": true, "title": true, "author": true,
+	"file_size": true, "status": true, "created_at": true,
+	"height": true, "bitrate": true, "media_duration": true,
+	"deleted_at": true,
+}
+
 // DownloadRecordRepository 处理下载记录数据库操作
 type DownloadRecordRepository struct {
 	db *sql.DB
@@ -17,33 +26,58 @@ func NewDownloadRecordRepository() *DownloadRecordRepository {
 	return &DownloadRecordRepository{db: GetDB()}
 }
 
-// Create 插入新的下载记录
+// ErrDownloadInTrash 回收站中已有相同 ID 的下载记录。新记录不会覆盖它，
+// 否则移入 .trash 的文件会失去记录，恢复和自动清空都不会再处理
+var ErrDownloadInTrash = errors.New("download record with the same id is in the trash")
+
+// Create 插入新的下载记录，已存在的同 ID 记录会被覆盖；
+// 同 ID 记录在回收站中时返回 ErrDownloadInTrash
 func (r *DownloadRecordRepository) Create(record *DownloadRecord) error {
 	now := time.Now()
 	record.CreatedAt = now
 	record.UpdatedAt = now
 
 	query := `
-		INSERT OR REPLACE INTO download_records (
+		INSERT INTO download_records (
 			id, video_id, title, author, cover_url, duration, file_size, file_path,
 			format, resolution, status, download_time, error_message,
 			like_count, comment_count, forward_count, fav_count,
 			transcript_path, transcript_status,
-			created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			container, video_codec, audio_codec, bitrate, fps, width, height, media_duration,
+			initiated_by, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			video_id = excluded.video_id, title = excluded.title, author = excluded.author,
+			cover_url = excluded.cover_url, duration = excluded.duration, file_size = excluded.file_size,
+			file_path = excluded.file_path, format = excluded.format, resolution = excluded.resolution,
+			status = excluded.status, download_time = excluded.download_time,
+			error_message = excluded.error_message, like_count = excluded.like_count,
+			comment_count = excluded.comment_count, forward_count = excluded.forward_count,
+			fav_count = excluded.fav_count, transcript_path = excluded.transcript_path,
+			transcript_status = excluded.transcript_status, container = excluded.container,
+			video_codec = excluded.video_codec, audio_codec = excluded.audio_codec,
+			bitrate = excluded.bitrate, fps = excluded.fps, width = excluded.width, height = excluded.height,
+			media_duration = excluded.media_duration, initiated_by = excluded.initiated_by,
+			created_at = excluded.created_at, updated_at = excluded.updated_at
+		WHERE download_records.deleted_at IS NULL
 	`
-	_, err := r.db.Exec(query,
+	result, err := r.db.Exec(query,
 		record.ID, record.VideoID, record.Title, record.Author, record.CoverURL,
 		record.Duration, record.FileSize, record.FilePath, record.Format,
 		record.Resolution, record.Status, record.DownloadTime,
 		record.ErrorMessage,
 		record.LikeCount, record.CommentCount, record.ForwardCount, record.FavCount,
 		record.TranscriptPath, record.TranscriptStatus,
-		record.CreatedAt, record.UpdatedAt,
+		record.Container, record.VideoCodec, record.AudioCodec, record.Bitrate,
+		record.FPS, record.Width, record.Height, record.MediaDuration,
+		record.InitiatedBy, record.CreatedAt, record.UpdatedAt,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create download record: %w", err)
 	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrDownloadInTrash
+	}
 	return nil
 }
 
@@ -55,8 +89,12 @@ func (r *DownloadRecordRepository) GetByID(id string) (*DownloadRecord, error) {
 			like_count, comment_count, forward_count, fav_count,
 			COALESCE(transcript_path, '') as transcript_path,
 			COALESCE(transcript_status, '') as transcript_status,
+			COALESCE(container, '') as container, COALESCE(video_codec, '') as video_codec,
+			COALESCE(audio_codec, '') as audio_codec, COALESCE(bitrate, 0) as bitrate,
+			COALESCE(fps, 0) as fps, COALESCE(width, 0) as width, COALESCE(height, 0) as height,
+			COALESCE(media_duration, 0) as media_duration, COALESCE(initiated_by, '') as initiated_by,
 			created_at, updated_at
-		FROM download_records WHERE id = ?
+		FROM download_records WHERE id = ? AND deleted_at IS NULL
 	`
 	record := &DownloadRecord{}
 	var filePath, format, resolution, errorMessage, coverURL, transcriptPath, transcriptStatus sql.NullString
@@ -67,6 +105,8 @@ func (r *DownloadRecordRepository) GetByID(id string) (*DownloadRecord, error) {
 		&errorMessage,
 		&record.LikeCount, &record.CommentCount, &record.ForwardCount, &record.FavCount,
 		&transcriptPath, &transcriptStatus,
+		&record.Container, &record.VideoCodec, &record.AudioCodec, &record.Bitrate,
+		&record.FPS, &record.Width, &record.Height, &record.MediaDuration, &record.InitiatedBy,
 		&record.CreatedAt, &record.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
@@ -95,6 +135,8 @@ func (r *DownloadRecordRepository) Update(record *DownloadRecord) error {
 			file_path = ?, format = ?, resolution = ?, status = ?,
 			download_time = ?, error_message = ?,
 			transcript_path = ?, transcript_status = ?,
+			container = ?, video_codec = ?, audio_codec = ?, bitrate = ?,
+			fps = ?, width = ?, height = ?, media_duration = ?,
 			updated_at = ?
 		WHERE id = ?
 	`
@@ -103,6 +145,8 @@ func (r *DownloadRecordRepository) Update(record *DownloadRecord) error {
 		record.FileSize, record.FilePath, record.Format, record.Resolution,
 		record.Status, record.DownloadTime, record.ErrorMessage,
 		record.TranscriptPath, record.TranscriptStatus,
+		record.Container, record.VideoCodec, record.AudioCodec, record.Bitrate,
+		record.FPS, record.Width, record.Height, record.MediaDuration,
 		record.UpdatedAt, record.ID,
 	)
 	if err != nil {
@@ -115,10 +159,39 @@ func (r *DownloadRecordRepository) Update(record *DownloadRecord) error {
 	return nil
 }
 
-// Delete 根据 ID 删除下载记录
+// UpdateMediaInfo 更新 ffprobe 探测到的媒体信息
+func (r *DownloadRecordRepository) UpdateMediaInfo(record *DownloadRecord) error {
+	record.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE download_records SET
+			duration = ?, resolution = ?,
+			container = ?, video_codec = ?, audio_codec = ?, bitrate = ?,
+			fps = ?, width = ?, height = ?, media_duration = ?,
+			updated_at = ?
+		WHERE id = ?
+	`
+	result, err := r.db.Exec(query,
+		record.Duration, record.Resolution,
+		record.Container, record.VideoCodec, record.AudioCodec, record.Bitrate,
+		record.FPS, record.Width, record.Height, record.MediaDuration,
+		record.UpdatedAt, record.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update media info: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("download record not found: %s", record.ID)
+	}
+	return nil
+}
+
+// Delete 根据 ID 将下载记录移入回收站
 func (r *DownloadRecordRepository) Delete(id string) error {
-	query := "DELETE FROM download_records WHERE id = ?"
-	result, err := r.db.Exec(query, id)
+	now := time.Now()
+	query := "UPDATE download_records SET deleted_at = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL"
+	result, err := r.db.Exec(query, now, now, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete download record: %w", err)
 	}
@@ -129,20 +202,21 @@ func (r *DownloadRecordRepository) Delete(id string) error {
 	return nil
 }
 
-// DeleteMany 根据 ID 删除多条下载记录
+// DeleteMany 根据 ID 将多条下载记录移入回收站
 func (r *DownloadRecordRepository) DeleteMany(ids []string) (int64, error) {
 	if len(ids) == 0 {
 		return 0, nil
 	}
 
 	placeholders := make([]string, len(ids))
-	args := make([]interface{}, len(ids))
+	now := time.Now()
+	args := []interface{}{now, now}
 	for i, id := range ids {
 		placeholders[i] = "?"
-		args[i] = id
+		args = append(args, id)
 	}
 
-	query := fmt.Sprintf("DELETE FROM download_records WHERE id IN (%s)", strings.Join(placeholders, ","))
+	query := fmt.Sprintf("UPDATE download_records SET deleted_at = ?, updated_at = ? WHERE id IN (%s) AND deleted_at IS NULL", strings.Join(placeholders, ","))
 	result, err := r.db.Exec(query, args...)
 	if err != nil {
 		return 0, fmt.Errorf("failed to delete download records: %w", err)
@@ -150,9 +224,10 @@ func (r *DownloadRecordRepository) DeleteMany(ids []string) (int64, error) {
 	return result.RowsAffected()
 }
 
-// Clear 删除所有下载记录
+// Clear 将所有下载记录移入回收站
 func (r *DownloadRecordRepository) Clear() error {
-	_, err := r.db.Exec("DELETE FROM download_records")
+	now := time.Now()
+	_, err := r.db.Exec("UPDATE download_records SET deleted_at = ?, updated_at = ? WHERE deleted_at IS NULL", now, now)
 	if err != nil {
 		return fmt.Errorf("failed to clear download records: %w", err)
 	}
@@ -176,16 +251,15 @@ func (r *DownloadRecordRepository) List(params *FilterParams) (*PagedResult[Down
 	}
 
 	// Validate sort column
-	validColumns := map[string]bool{
-		"download_time": true, "title": true, "author": true,
-		"file_size": true, "status": true, "created_at": true,
-	}
-	if !validColumns[params.SortBy] {
+	if !DownloadSortColumns[params.SortBy] {
 		params.SortBy = "download_time"
 	}
 
 	// Build WHERE clause
-	var conditions []string
+	conditions := []string{"deleted_at IS NULL"}
+	if params.Trashed {
+		conditions[0] = "deleted_at IS NOT NULL"
+	}
 	var args []interface{}
 
 	if params.StartDate != nil {
@@ -205,11 +279,31 @@ func (r *DownloadRecordRepository) List(params *FilterParams) (*PagedResult[Down
 		searchPattern := "%" + params.Query + "%"
 		args = append(args, searchPattern, searchPattern)
 	}
-
-	whereClause := ""
-	if len(conditions) > 0 {
-		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	if params.MinResolution > 0 {
+		// 以短边判断清晰度，竖屏 1080x1920 同样视为 1080p
+		conditions = append(conditions, "MIN(width, height) >= ?")
+		args = append(args, params.MinResolution)
 	}
+	if params.VideoCodec != "" {
+		conditions = append(conditions, "video_codec = ?")
+		args = append(args, params.VideoCodec)
+	}
+	if params.Author != "" {
+		conditions = append(conditions, "author = ?")
+		args = append(args, params.Author)
+	}
+	if params.InitiatedBy != "" {
+		conditions = append(conditions, "initiated_by = ?")
+		args = append(args, params.InitiatedBy)
+	}
+	if params.TranscriptStatus == "none" {
+		conditions = append(conditions, "COALESCE(transcript_status, '') = ''")
+	} else if params.TranscriptStatus != "" {
+		conditions = append(conditions, "transcript_status = ?")
+		args = append(args, params.TranscriptStatus)
+	}
+
+	whereClause := "WHERE " + strings.Join(conditions, " AND ")
 
 	// Count total
 	var total int64
@@ -232,7 +326,11 @@ func (r *DownloadRecordRepository) List(params *FilterParams) (*PagedResult[Down
 			like_count, comment_count, forward_count, fav_count,
 			COALESCE(transcript_path, '') as transcript_path,
 			COALESCE(transcript_status, '') as transcript_status,
-			created_at, updated_at
+			COALESCE(container, '') as container, COALESCE(video_codec, '') as video_codec,
+			COALESCE(audio_codec, '') as audio_codec, COALESCE(bitrate, 0) as bitrate,
+			COALESCE(fps, 0) as fps, COALESCE(width, 0) as width, COALESCE(height, 0) as height,
+			COALESCE(media_duration, 0) as media_duration, COALESCE(initiated_by, '') as initiated_by,
+			created_at, updated_at, deleted_at, COALESCE(trash_path, '') as trash_path
 		FROM download_records
 		%s
 		ORDER BY %s %s
@@ -246,43 +344,17 @@ func (r *DownloadRecordRepository) List(params *FilterParams) (*PagedResult[Down
 	}
 	defer rows.Close()
 
-	var records []DownloadRecord
-	for rows.Next() {
-		var record DownloadRecord
-		var filePath, format, resolution, errorMessage, coverURL, transcriptPath, transcriptStatus sql.NullString
-		err := rows.Scan(
-			&record.ID, &record.VideoID, &record.Title, &record.Author, &coverURL,
-			&record.Duration, &record.FileSize, &filePath, &format,
-			&resolution, &record.Status, &record.DownloadTime,
-			&errorMessage,
-			&record.LikeCount, &record.CommentCount, &record.ForwardCount, &record.FavCount,
-			&transcriptPath, &transcriptStatus,
-			&record.CreatedAt, &record.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan download record: %w", err)
-		}
-		record.CoverURL = coverURL.String
-		record.FilePath = filePath.String
-		record.Format = format.String
-		record.Resolution = resolution.String
-		record.ErrorMessage = errorMessage.String
-		record.TranscriptPath = transcriptPath.String
-		record.TranscriptStatus = transcriptStatus.String
-		records = append(records, record)
-	}
-
-	if records == nil {
-		records = []DownloadRecord{}
+	records, err := scanTrashableDownloadRecords(rows)
+	if err != nil {
+		return nil, err
 	}
-
 	return NewPagedResult(records, total, params.Page, params.PageSize), nil
 }
 
 // Count 返回下载记录的总数
 func (r *DownloadRecordRepository) Count() (int64, error) {
 	var count int64
-	err := r.db.QueryRow("SELECT COUNT(*) FROM download_records").Scan(&count)
+	err := r.db.QueryRow("SELECT COUNT(*) FROM download_records WHERE deleted_at IS NULL").Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count download records: %w", err)
 	}
@@ -292,7 +364,7 @@ func (r *DownloadRecordRepository) Count() (int64, error) {
 // CountByStatus 返回指定状态的记录数
 func (r *DownloadRecordRepository) CountByStatus(status string) (int64, error) {
 	var count int64
-	err := r.db.QueryRow("SELECT COUNT(*) FROM download_records WHERE status = ?", status).Scan(&count)
+	err := r.db.QueryRow("SELECT COUNT(*) FROM download_records WHERE status = ? AND deleted_at IS NULL", status).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count download records by status: %w", err)
 	}
@@ -304,7 +376,7 @@ func (r *DownloadRecordRepository) CountToday() (int64, error) {
 	var count int64
 	today := time.Now().Format("2006-01-02")
 	err := r.db.QueryRow(
-		"SELECT COUNT(*) FROM download_records WHERE date(download_time) = ?",
+		"SELECT COUNT(*) FROM download_records WHERE date(download_time) = ? AND deleted_at IS NULL",
 		today,
 	).Scan(&count)
 	if err != nil {
@@ -325,8 +397,13 @@ func (r *DownloadRecordRepository) GetRecent(limit int) ([]DownloadRecord, error
 			like_count, comment_count, forward_count, fav_count,
 			COALESCE(transcript_path, '') as transcript_path,
 			COALESCE(transcript_status, '') as transcript_status,
+			COALESCE(container, '') as container, COALESCE(video_codec, '') as video_codec,
+			COALESCE(audio_codec, '') as audio_codec, COALESCE(bitrate, 0) as bitrate,
+			COALESCE(fps, 0) as fps, COALESCE(width, 0) as width, COALESCE(height, 0) as height,
+			COALESCE(media_duration, 0) as media_duration, COALESCE(initiated_by, '') as initiated_by,
 			created_at, updated_at
 		FROM download_records
+		WHERE deleted_at IS NULL
 		ORDER BY download_time DESC
 		LIMIT ?
 	`
@@ -348,6 +425,8 @@ func (r *DownloadRecordRepository) GetRecent(limit int) ([]DownloadRecord, error
 			&errorMessage,
 			&record.LikeCount, &record.CommentCount, &record.ForwardCount, &record.FavCount,
 			&transcriptPath, &transcriptStatus,
+			&record.Container, &record.VideoCodec, &record.AudioCodec, &record.Bitrate,
+			&record.FPS, &record.Width, &record.Height, &record.MediaDuration, &record.InitiatedBy,
 			&record.CreatedAt, &record.UpdatedAt,
 		)
 		if err != nil {
@@ -370,9 +449,13 @@ func (r *DownloadRecordRepository) GetRecent(limit int) ([]DownloadRecord, error
 	return records, nil
 }
 
-// DeleteBefore 删除指定日期前的所有记录
+// DeleteBefore 将指定日期前的所有记录移入回收站
 func (r *DownloadRecordRepository) DeleteBefore(date time.Time) (int64, error) {
-	result, err := r.db.Exec("DELETE FROM download_records WHERE download_time < ?", date)
+	now := time.Now()
+	result, err := r.db.Exec(
+		"UPDATE download_records SET deleted_at = ?, updated_at = ? WHERE download_time < ? AND deleted_at IS NULL",
+		now, now, date,
+	)
 	if err != nil {
 		return 0, fmt.Errorf("failed to delete old download records: %w", err)
 	}
@@ -387,8 +470,13 @@ func (r *DownloadRecordRepository) GetAll() ([]DownloadRecord, error) {
 			like_count, comment_count, forward_count, fav_count,
 			COALESCE(transcript_path, '') as transcript_path,
 			COALESCE(transcript_status, '') as transcript_status,
+			COALESCE(container, '') as container, COALESCE(video_codec, '') as video_codec,
+			COALESCE(audio_codec, '') as audio_codec, COALESCE(bitrate, 0) as bitrate,
+			COALESCE(fps, 0) as fps, COALESCE(width, 0) as width, COALESCE(height, 0) as height,
+			COALESCE(media_duration, 0) as media_duration, COALESCE(initiated_by, '') as initiated_by,
 			created_at, updated_at
 		FROM download_records
+		WHERE deleted_at IS NULL
 		ORDER BY download_time DESC
 	`
 
@@ -409,6 +497,8 @@ func (r *DownloadRecordRepository) GetAll() ([]DownloadRecord, error) {
 			&errorMessage,
 			&record.LikeCount, &record.CommentCount, &record.ForwardCount, &record.FavCount,
 			&transcriptPath, &transcriptStatus,
+			&record.Container, &record.VideoCodec, &record.AudioCodec, &record.Bitrate,
+			&record.FPS, &record.Width, &record.Height, &record.MediaDuration, &record.InitiatedBy,
 			&record.CreatedAt, &record.UpdatedAt,
 		)
 		if err != nil {
@@ -450,9 +540,13 @@ func (r *DownloadRecordRepository) GetByIDs(ids []string) ([]DownloadRecord, err
 			like_count, comment_count, forward_count, fav_count,
 			COALESCE(transcript_path, '') as transcript_path,
 			COALESCE(transcript_status, '') as transcript_status,
+			COALESCE(container, '') as container, COALESCE(video_codec, '') as video_codec,
+			COALESCE(audio_codec, '') as audio_codec, COALESCE(bitrate, 0) as bitrate,
+			COALESCE(fps, 0) as fps, COALESCE(width, 0) as width, COALESCE(height, 0) as height,
+			COALESCE(media_duration, 0) as media_duration, COALESCE(initiated_by, '') as initiated_by,
 			created_at, updated_at
 		FROM download_records
-		WHERE id IN (%s)
+		WHERE id IN (%s) AND deleted_at IS NULL
 		ORDER BY download_time DESC
 	`, strings.Join(placeholders, ","))
 
@@ -473,6 +567,8 @@ func (r *DownloadRecordRepository) GetByIDs(ids []string) ([]DownloadRecord, err
 			&errorMessage,
 			&record.LikeCount, &record.CommentCount, &record.ForwardCount, &record.FavCount,
 			&transcriptPath, &transcriptStatus,
+			&record.Container, &record.VideoCodec, &record.AudioCodec, &record.Bitrate,
+			&record.FPS, &record.Width, &record.Height, &record.MediaDuration, &record.InitiatedBy,
 			&record.CreatedAt, &record.UpdatedAt,
 		)
 		if err != nil {
@@ -495,6 +591,44 @@ func (r *DownloadRecordRepository) GetByIDs(ids []string) ([]DownloadRecord, err
 	return records, nil
 }
 
+// ListByVideoIDs 获取这些视频的下载记录，按下载时间倒序
+func (r *DownloadRecordRepository) ListByVideoIDs(videoIDs []string) ([]DownloadRecord, error) {
+	if len(videoIDs) == 0 {
+		return []DownloadRecord{}, nil
+	}
+
+	placeholders := make([]string, len(videoIDs))
+	args := make([]interface{}, len(videoIDs))
+	for i, id := range videoIDs {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, video_id, title, author, COALESCE(cover_url, '') as cover_url, duration, file_size, file_path,
+			format, resolution, status, download_time, error_message,
+			like_count, comment_count, forward_count, fav_count,
+			COALESCE(transcript_path, '') as transcript_path,
+			COALESCE(transcript_status, '') as transcript_status,
+			COALESCE(container, '') as container, COALESCE(video_codec, '') as video_codec,
+			COALESCE(audio_codec, '') as audio_codec, COALESCE(bitrate, 0) as bitrate,
+			COALESCE(fps, 0) as fps, COALESCE(width, 0) as width, COALESCE(height, 0) as height,
+			COALESCE(media_duration, 0) as media_duration, COALESCE(initiated_by, '') as initiated_by,
+			created_at, updated_at, deleted_at, COALESCE(trash_path, '') as trash_path
+		FROM download_records
+		WHERE video_id IN (%s) AND deleted_at IS NULL
+		ORDER BY download_time DESC
+	`, strings.Join(placeholders, ","))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list download records by video IDs: %w", err)
+	}
+	defer rows.Close()
+
+	return scanTrashableDownloadRecords(rows)
+}
+
 // GetChartData 返回过去 N 天的下载统计
 func (r *DownloadRecordRepository) GetChartData(days int) ([]string, []int64, error) {
 	if days < 1 {
@@ -511,7 +645,7 @@ func (r *DownloadRecordRepository) GetChartData(days int) ([]string, []int64, er
 
 		var count int64
 		err := r.db.QueryRow(
-			"SELECT COUNT(*) FROM download_records WHERE date(download_time) = ?",
+			"SELECT COUNT(*) FROM download_records WHERE date(download_time) = ? AND deleted_at IS NULL",
 			dateStr,
 		).Scan(&count)
 		if err != nil {
@@ -537,7 +671,7 @@ func (r *DownloadRecordRepository) UpdateTranscriptStatus(id, status, transcript
 func (r *DownloadRecordRepository) GetTotalFileSize() (int64, error) {
 	var total sql.NullInt64
 	err := r.db.QueryRow(
-		"SELECT SUM(file_size) FROM download_records WHERE status = ?",
+		"SELECT SUM(file_size) FROM download_records WHERE status = ? AND deleted_at IS NULL",
 		DownloadStatusCompleted,
 	).Scan(&total)
 	if err != nil {
@@ -545,3 +679,142 @@ func (r *DownloadRecordRepository) GetTotalFileSize() (int64, error) {
 	}
 	return total.Int64, nil
 }
+
+// SetTrashPath 记录文件在回收站中的位置
+func (r *DownloadRecordRepository) SetTrashPath(id, trashPath string) error {
+	if _, err := r.db.Exec(`UPDATE download_records SET trash_path = ? WHERE id = ?`, trashPath, id); err != nil {
+		return fmt.Errorf("failed to update trash path: %w", err)
+	}
+	return nil
+}
+
+// GetTrashed 获取回收站中的记录：ids 不为空时按 ID，否则按移入时间早于 before 的记录（before 为空时全部）
+func (r *DownloadRecordRepository) GetTrashed(ids []string, before *time.Time) ([]DownloadRecord, error) {
+	conditions := []string{"deleted_at IS NOT NULL"}
+	var args []interface{}
+	if len(ids) > 0 {
+		conditions = append(conditions, "id IN ("+strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")+")")
+		for _, id := range ids {
+			args = append(args, id)
+		}
+	}
+	if before != nil {
+		conditions = append(conditions, "deleted_at < ?")
+		args = append(args, *before)
+	}
+
+	query := `
+		SELECT id, video_id, title, author, COALESCE(cover_url, '') as cover_url, duration, file_size, file_path,
+			format, resolution, status, download_time, error_message,
+			like_count, comment_count, forward_count, fav_count,
+			COALESCE(transcript_path, '') as transcript_path,
+			COALESCE(transcript_status, '') as transcript_status,
+			COALESCE(container, '') as container, COALESCE(video_codec, '') as video_codec,
+			COALESCE(audio_codec, '') as audio_codec, COALESCE(bitrate, 0) as bitrate,
+			COALESCE(fps, 0) as fps, COALESCE(width, 0) as width, COALESCE(height, 0) as height,
+			COALESCE(media_duration, 0) as media_duration, COALESCE(initiated_by, '') as initiated_by,
+			created_at, updated_at, deleted_at, COALESCE(trash_path, '') as trash_path
+		FROM download_records
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY deleted_at ASC
+	`
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trashed download records: %w", err)
+	}
+	defer rows.Close()
+	return scanTrashableDownloadRecords(rows)
+}
+
+// Restore 将回收站中的记录恢复，返回恢复的数量
+func (r *DownloadRecordRepository) Restore(ids []string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	args := []interface{}{time.Now()}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	query := fmt.Sprintf(
+		"UPDATE download_records SET deleted_at = NULL, trash_path = '', updated_at = ? WHERE id IN (%s) AND deleted_at IS NOT NULL",
+		strings.TrimSuffix(strings.Repeat("?,", len(ids)), ","),
+	)
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to restore download records: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// DetachTrashed 把回收站中 ID 为 id 的记录改为新 ID 并返回新 ID，没有该记录时返回空字符串。
+// 记录仍在回收站中，移入回收站的文件不变，原 ID 可以写入重新下载的记录
+func (r *DownloadRecordRepository) DetachTrashed(id string) (string, error) {
+	newID := fmt.Sprintf("%s~%d", id, time.Now().UnixNano())
+	result, err := r.db.Exec(
+		"UPDATE download_records SET id = ?, updated_at = ? WHERE id = ? AND deleted_at IS NOT NULL",
+		newID, time.Now(), id,
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to detach trashed download record: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return "", err
+	}
+	return newID, nil
+}
+
+// Purge 从回收站中永久删除记录，返回删除的数量
+func (r *DownloadRecordRepository) Purge(ids []string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	query := fmt.Sprintf(
+		"DELETE FROM download_records WHERE id IN (%s) AND deleted_at IS NOT NULL",
+		strings.TrimSuffix(strings.Repeat("?,", len(ids)), ","),
+	)
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge download records: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// scanTrashableDownloadRecords 扫描包含 deleted_at 和 trash_path 列的下载记录
+func scanTrashableDownloadRecords(rows *sql.Rows) ([]DownloadRecord, error) {
+	records := []DownloadRecord{}
+	for rows.Next() {
+		var record DownloadRecord
+		var filePath, format, resolution, errorMessage, coverURL, transcriptPath, transcriptStatus sql.NullString
+		var deletedAt sql.NullTime
+		err := rows.Scan(
+			&record.ID, &record.VideoID, &record.Title, &record.Author, &coverURL,
+			&record.Duration, &record.FileSize, &filePath, &format,
+			&resolution, &record.Status, &record.DownloadTime,
+			&errorMessage,
+			&record.LikeCount, &record.CommentCount, &record.ForwardCount, &record.FavCount,
+			&transcriptPath, &transcriptStatus,
+			&record.Container, &record.VideoCodec, &record.AudioCodec, &record.Bitrate,
+			&record.FPS, &record.Width, &record.Height, &record.MediaDuration, &record.InitiatedBy,
+			&record.CreatedAt, &record.UpdatedAt, &deletedAt, &record.TrashPath,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan download record: %w", err)
+		}
+		record.CoverURL = coverURL.String
+		record.FilePath = filePath.String
+		record.Format = format.String
+		record.Resolution = resolution.String
+		record.ErrorMessage = errorMessage.String
+		record.TranscriptPath = transcriptPath.String
+		record.TranscriptStatus = transcriptStatus.String
+		if deletedAt.Valid {
+			record.DeletedAt = &deletedAt.Time
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
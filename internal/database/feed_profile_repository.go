@@ -0,0 +1,68 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// FeedProfileCacheRepository 处理视频详情缓存的数据库操作
+type FeedProfileCacheRepository struct {
+	db *sql.DB
+}
+
+// NewFeedProfileCacheRepository 创建一个新的 FeedProfileCacheRepository
+func NewFeedProfileCacheRepository() *FeedProfileCacheRepository {
+	return &FeedProfileCacheRepository{db: GetDB()}
+}
+
+// Get 获取未过期的缓存，不存在或已过期时返回 nil
+func (r *FeedProfileCacheRepository) Get(objectID, nonceID string) (*FeedProfileCache, error) {
+	entry := &FeedProfileCache{}
+	var data string
+	err := r.db.QueryRow(`
+		SELECT object_id, nonce_id, data, fetched_at, expires_at FROM feed_profile_cache
+		WHERE object_id = ? AND nonce_id = ? AND expires_at > ?`,
+		objectID, nonceID, time.Now(),
+	).Scan(&entry.ObjectID, &entry.NonceID, &data, &entry.FetchedAt, &entry.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feed profile cache: %w", err)
+	}
+	entry.Data = []byte(data)
+	return entry, nil
+}
+
+// Put 写入或覆盖缓存
+func (r *FeedProfileCacheRepository) Put(entry *FeedProfileCache) error {
+	_, err := r.db.Exec(`
+		INSERT INTO feed_profile_cache (object_id, nonce_id, data, fetched_at, expires_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(object_id, nonce_id) DO UPDATE SET
+			data = excluded.data, fetched_at = excluded.fetched_at, expires_at = excluded.expires_at`,
+		entry.ObjectID, entry.NonceID, string(entry.Data), entry.FetchedAt, entry.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save feed profile cache: %w", err)
+	}
+	return nil
+}
+
+// Delete 删除一条缓存
+func (r *FeedProfileCacheRepository) Delete(objectID, nonceID string) error {
+	if _, err := r.db.Exec("DELETE FROM feed_profile_cache WHERE object_id = ? AND nonce_id = ?", objectID, nonceID); err != nil {
+		return fmt.Errorf("failed to delete feed profile cache: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired 清理过期的缓存，返回删除的条数
+func (r *FeedProfileCacheRepository) DeleteExpired() (int64, error) {
+	result, err := r.db.Exec("DELETE FROM feed_profile_cache WHERE expires_at <= ?", time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired feed profile cache: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -236,6 +236,390 @@ ALTER TABLE download_records ADD COLUMN fav_count INTEGER DEFAULT 0;
 -- Add transcript_path and transcript_status columns for speech-to-text feature
 ALTER TABLE download_records ADD COLUMN transcript_path TEXT DEFAULT '';
 ALTER TABLE download_records ADD COLUMN transcript_status TEXT DEFAULT '';
+`,
+	},
+	{
+		Version:     10,
+		Description: "Add ffprobe media metadata columns to download_records table",
+		Up: `
+-- Add real media metadata probed by ffprobe after download
+ALTER TABLE download_records ADD COLUMN container TEXT DEFAULT '';
+ALTER TABLE download_records ADD COLUMN video_codec TEXT DEFAULT '';
+ALTER TABLE download_records ADD COLUMN audio_codec TEXT DEFAULT '';
+ALTER TABLE download_records ADD COLUMN bitrate INTEGER DEFAULT 0;
+ALTER TABLE download_records ADD COLUMN fps REAL DEFAULT 0;
+ALTER TABLE download_records ADD COLUMN width INTEGER DEFAULT 0;
+ALTER TABLE download_records ADD COLUMN height INTEGER DEFAULT 0;
+ALTER TABLE download_records ADD COLUMN media_duration REAL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_download_records_height ON download_records(height);
+`,
+	},
+	{
+		Version:     11,
+		Description: "Create comment_videos, comments and comments_fts tables for structured comment storage",
+		Up: `
+-- Videos whose comments have been collected
+CREATE TABLE IF NOT EXISTS comment_videos (
+    video_id TEXT PRIMARY KEY,
+    video_title TEXT DEFAULT '',
+    comment_count INTEGER DEFAULT 0,
+    original_comment_count INTEGER DEFAULT 0,
+    first_collected_at DATETIME,
+    last_collected_at DATETIME
+);
+
+-- Individual comments; parent_id is empty for top-level comments
+CREATE TABLE IF NOT EXISTS comments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    comment_id TEXT NOT NULL,
+    video_id TEXT NOT NULL,
+    parent_id TEXT DEFAULT '',
+    reply_to_id TEXT DEFAULT '',
+    reply_to_nickname TEXT DEFAULT '',
+    author TEXT DEFAULT '',
+    author_avatar TEXT DEFAULT '',
+    content TEXT DEFAULT '',
+    like_count INTEGER DEFAULT 0,
+    reply_count INTEGER DEFAULT 0,
+    ip_region TEXT DEFAULT '',
+    comment_time DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(video_id, comment_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_comments_video_id ON comments(video_id);
+CREATE INDEX IF NOT EXISTS idx_comments_parent_id ON comments(video_id, parent_id);
+CREATE INDEX IF NOT EXISTS idx_comments_comment_time ON comments(comment_time);
+
+-- Full-text index over comment content and author; docid = comments.id
+CREATE VIRTUAL TABLE IF NOT EXISTS comments_fts USING fts4(body, tokenize=unicode61);
+`,
+	},
+	{
+		Version:     12,
+		Description: "Add video_author column to comment_videos table for per-author comment analytics",
+		Up: `
+ALTER TABLE comment_videos ADD COLUMN video_author TEXT DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_comment_videos_video_author ON comment_videos(video_author);
+
+-- Backfill from browse history and download records
+UPDATE comment_videos SET video_author = COALESCE(
+    (SELECT author FROM browse_history WHERE browse_history.id = comment_videos.video_id),
+    (SELECT author FROM download_records WHERE download_records.video_id = comment_videos.video_id LIMIT 1),
+    ''
+);
+`,
+	},
+	{
+		Version:     13,
+		Description: "Create comment_runs and comment_changes tables for incremental comment collection",
+		Up: `
+-- One row per collection session of a video
+CREATE TABLE IF NOT EXISTS comment_runs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    video_id TEXT NOT NULL,
+    started_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    full_update INTEGER DEFAULT 0,
+    total_count INTEGER DEFAULT 0,
+    new_count INTEGER DEFAULT 0,
+    changed_count INTEGER DEFAULT 0,
+    deleted_count INTEGER DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_comment_runs_video_id ON comment_runs(video_id, id);
+
+-- Content changes detected during a run
+CREATE TABLE IF NOT EXISTS comment_changes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id INTEGER NOT NULL,
+    video_id TEXT NOT NULL,
+    comment_id TEXT NOT NULL,
+    old_content TEXT DEFAULT '',
+    new_content TEXT DEFAULT '',
+    changed_at DATETIME,
+    UNIQUE(run_id, video_id, comment_id)
+);
+
+-- Track in which runs each comment was seen and when it disappeared
+ALTER TABLE comments ADD COLUMN first_seen_run INTEGER DEFAULT 0;
+ALTER TABLE comments ADD COLUMN last_seen_run INTEGER DEFAULT 0;
+ALTER TABLE comments ADD COLUMN deleted_run INTEGER;
+ALTER TABLE comments ADD COLUMN deleted_at DATETIME;
+CREATE INDEX IF NOT EXISTS idx_comments_seen_runs ON comments(video_id, first_seen_run, last_seen_run);
+`,
+	},
+	{
+		Version:     14,
+		Description: "Create crawl_jobs and crawl_videos tables for author feed crawling",
+		Up: `
+-- One row per author feed crawl; next_marker allows resuming after pause or restart
+CREATE TABLE IF NOT EXISTS crawl_jobs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    username TEXT NOT NULL,
+    author TEXT DEFAULT '',
+    status TEXT NOT NULL,
+    enqueue INTEGER DEFAULT 0,
+    keyword TEXT DEFAULT '',
+    since DATETIME,
+    until DATETIME,
+    max_pages INTEGER DEFAULT 0,
+    interval_ms INTEGER DEFAULT 0,
+    next_marker TEXT DEFAULT '',
+    pages INTEGER DEFAULT 0,
+    discovered_count INTEGER DEFAULT 0,
+    enqueued_count INTEGER DEFAULT 0,
+    error_message TEXT DEFAULT '',
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    finished_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_crawl_jobs_status ON crawl_jobs(status);
+
+-- Videos discovered by a crawl
+CREATE TABLE IF NOT EXISTS crawl_videos (
+    job_id INTEGER NOT NULL,
+    video_id TEXT NOT NULL,
+    nonce_id TEXT DEFAULT '',
+    title TEXT DEFAULT '',
+    author TEXT DEFAULT '',
+    cover_url TEXT DEFAULT '',
+    video_url TEXT DEFAULT '',
+    decrypt_key TEXT DEFAULT '',
+    duration INTEGER DEFAULT 0,
+    size INTEGER DEFAULT 0,
+    media_type INTEGER DEFAULT 0,
+    publish_time DATETIME,
+    enqueued INTEGER DEFAULT 0,
+    discovered_at DATETIME NOT NULL,
+    PRIMARY KEY (job_id, video_id)
+);
+CREATE INDEX IF NOT EXISTS idx_crawl_videos_publish_time ON crawl_videos(job_id, publish_time);
+`,
+	},
+	{
+		Version:     15,
+		Description: "Create watch_authors and watch_videos tables for local author watch-list",
+		Up: `
+-- Authors polled periodically for new videos; last_seen_at is the newest createtime seen
+CREATE TABLE IF NOT EXISTS watch_authors (
+    username TEXT PRIMARY KEY,
+    nickname TEXT DEFAULT '',
+    head_url TEXT DEFAULT '',
+    policy TEXT NOT NULL DEFAULT 'notify',
+    keyword TEXT DEFAULT '',
+    interval_minutes INTEGER DEFAULT 30,
+    enabled INTEGER DEFAULT 1,
+    last_seen_at DATETIME,
+    last_checked_at DATETIME,
+    new_video_count INTEGER DEFAULT 0,
+    error_message TEXT DEFAULT '',
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+-- New videos detected for watched authors
+CREATE TABLE IF NOT EXISTS watch_videos (
+    username TEXT NOT NULL,
+    video_id TEXT NOT NULL,
+    nonce_id TEXT DEFAULT '',
+    title TEXT DEFAULT '',
+    author TEXT DEFAULT '',
+    cover_url TEXT DEFAULT '',
+    video_url TEXT DEFAULT '',
+    decrypt_key TEXT DEFAULT '',
+    duration INTEGER DEFAULT 0,
+    size INTEGER DEFAULT 0,
+    media_type INTEGER DEFAULT 0,
+    publish_time DATETIME,
+    enqueued INTEGER DEFAULT 0,
+    detected_at DATETIME NOT NULL,
+    PRIMARY KEY (username, video_id),
+    FOREIGN KEY (username) REFERENCES watch_authors(username) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_watch_videos_detected_at ON watch_videos(detected_at);
+`,
+	},
+	{
+		Version:     16,
+		Description: "Create feed_profile_cache table for caching feed_profile responses",
+		Up: `
+-- Raw key:channels:feed_profile responses keyed by objectId/nonceId
+CREATE TABLE IF NOT EXISTS feed_profile_cache (
+    object_id TEXT NOT NULL,
+    nonce_id TEXT NOT NULL,
+    data TEXT NOT NULL,
+    fetched_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+    PRIMARY KEY (object_id, nonce_id)
+);
+CREATE INDEX IF NOT EXISTS idx_feed_profile_cache_expires_at ON feed_profile_cache(expires_at);
+`,
+	},
+	{
+		Version:     17,
+		Description: "Add nonce_id column to browse_history and download_queue tables",
+		Up: `
+-- Add nonce_id column to browse_history table for refreshing expired video URLs
+ALTER TABLE browse_history ADD COLUMN nonce_id TEXT DEFAULT '';
+
+-- Add nonce_id column to download_queue table for refreshing expired video URLs
+ALTER TABLE download_queue ADD COLUMN nonce_id TEXT DEFAULT '';
+`,
+	},
+	{
+		Version:     18,
+		Description: "Create video_formats table and add quality column to download_queue",
+		Up: `
+-- Encodes (media spec) available for each video
+CREATE TABLE IF NOT EXISTS video_formats (
+    video_id TEXT NOT NULL,
+    file_format TEXT NOT NULL,
+    width INTEGER DEFAULT 0,
+    height INTEGER DEFAULT 0,
+    bit_rate INTEGER DEFAULT 0,
+    coding_format TEXT DEFAULT '',
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY (video_id, file_format)
+);
+
+-- Quality policy chosen when the item was queued
+ALTER TABLE download_queue ADD COLUMN quality TEXT DEFAULT '';
+`,
+	},
+	{
+		Version:     19,
+		Description: "Create api_tokens table",
+		Up: `
+-- Named API tokens for the local console API (only the SHA-256 hash is stored)
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    token_hash TEXT NOT NULL UNIQUE,
+    prefix TEXT DEFAULT '',
+    scopes TEXT NOT NULL,
+    expires_at DATETIME,
+    last_used_at DATETIME,
+    created_at DATETIME NOT NULL
+);
+`,
+	},
+	{
+		Version:     20,
+		Description: "Create append-only audit_log table",
+		Up: `
+-- Who did what to which records: destructive and configuration actions
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at DATETIME NOT NULL,
+    actor TEXT NOT NULL,
+    actor_type TEXT NOT NULL,
+    action TEXT NOT NULL,
+    target_type TEXT DEFAULT '',
+    target_ids TEXT DEFAULT '',
+    before_value TEXT DEFAULT '',
+    after_value TEXT DEFAULT '',
+    source_ip TEXT DEFAULT '',
+    detail TEXT DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log(action);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor);
+
+-- Entries can only be appended
+CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+`,
+	},
+	{
+		Version:     21,
+		Description: "Add soft delete (trash) columns to download_records and browse_history",
+		Up: `
+-- Deleted records stay in the trash until restored or purged
+ALTER TABLE download_records ADD COLUMN deleted_at DATETIME;
+-- Where the file was moved to under download_dir/.trash (file_path keeps the original path)
+ALTER TABLE download_records ADD COLUMN trash_path TEXT DEFAULT '';
+ALTER TABLE browse_history ADD COLUMN deleted_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_download_records_deleted_at ON download_records(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_browse_history_deleted_at ON browse_history(deleted_at);
+`,
+	},
+	{
+		Version:     22,
+		Description: "Create webhooks and webhook_deliveries tables",
+		Up: `
+-- Outgoing webhooks for download, transcription and batch lifecycle events
+CREATE TABLE IF NOT EXISTS webhooks (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    url TEXT NOT NULL,
+    events TEXT NOT NULL,
+    secret TEXT DEFAULT '',
+    enabled INTEGER DEFAULT 1,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+-- Delivery history (the most recent deliveries of each webhook are kept)
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    webhook_id TEXT NOT NULL,
+    event TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL,
+    attempts INTEGER DEFAULT 0,
+    response_code INTEGER DEFAULT 0,
+    error TEXT DEFAULT '',
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id);
+`,
+	},
+	{
+		Version:     23,
+		Description: "Create users and user_sessions tables and add initiated_by to download_queue and download_records",
+		Up: `
+-- Local console accounts (bcrypt password hashes)
+CREATE TABLE IF NOT EXISTS users (
+    id TEXT PRIMARY KEY,
+    username TEXT NOT NULL UNIQUE COLLATE NOCASE,
+    password_hash TEXT NOT NULL,
+    scopes TEXT NOT NULL,
+    preferences TEXT DEFAULT '{}',
+    last_login_at DATETIME,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+-- Cookie sessions (only the SHA-256 hash of the session token is stored)
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    csrf_token TEXT NOT NULL,
+    user_agent TEXT DEFAULT '',
+    source_ip TEXT DEFAULT '',
+    created_at DATETIME NOT NULL,
+    last_seen_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_sessions_user_id ON user_sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_user_sessions_expires_at ON user_sessions(expires_at);
+
+-- Console user who queued the download
+ALTER TABLE download_queue ADD COLUMN initiated_by TEXT DEFAULT '';
+ALTER TABLE download_records ADD COLUMN initiated_by TEXT DEFAULT '';
 `,
 	},
 }
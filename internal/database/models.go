@@ -1,6 +1,11 @@
 package database
 
 import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -22,8 +27,12 @@ type BrowseRecord struct {
 	FavCount     int64     `json:"favCount"`
 	ForwardCount int64     `json:"forwardCount"`
 	PageURL      string    `json:"pageUrl"`
+	NonceID      string    `json:"nonceId"` // objectNonceId，用于重新获取过期的视频链接
 	CreatedAt    time.Time `json:"createdAt"`
 	UpdatedAt    time.Time `json:"updatedAt"`
+	DeletedAt    *time.Time `json:"deletedAt,omitempty"` // 移入回收站的时间，仅回收站列表返回
+
+	Formats []VideoFormat `json:"formats,omitempty"` // 可选的画质，不是 browse_history 的列
 }
 
 // DownloadRecord 表示视频下载记录
@@ -47,8 +56,19 @@ type DownloadRecord struct {
 	FavCount         int64     `json:"favCount"`
 	TranscriptPath   string    `json:"transcriptPath"`
 	TranscriptStatus string    `json:"transcriptStatus"` // "", "in_progress", "completed", "failed"
+	Container        string    `json:"container"`        // ffprobe 探测的封装格式，如 "mov,mp4,m4a,3gp,3g2,mj2"
+	VideoCodec       string    `json:"videoCodec"`       // 如 "h264", "hevc"
+	AudioCodec       string    `json:"audioCodec"`       // 如 "aac"
+	Bitrate          int64     `json:"bitrate"`          // 总码率（bit/s）
+	FPS              float64   `json:"fps"`              // 帧率
+	Width            int       `json:"width"`            // 实际宽度（像素）
+	Height           int       `json:"height"`           // 实际高度（像素）
+	MediaDuration    float64   `json:"mediaDuration"`    // 精确时长（秒）
+	InitiatedBy      string    `json:"initiatedBy"`      // 发起下载的控制台用户，其他来源为空
 	CreatedAt        time.Time `json:"createdAt"`
 	UpdatedAt        time.Time `json:"updatedAt"`
+	DeletedAt        *time.Time `json:"deletedAt,omitempty"` // 移入回收站的时间，仅回收站列表返回
+	TrashPath        string     `json:"trashPath,omitempty"` // 文件在回收站中的位置，FilePath 保留原路径
 }
 
 // DownloadStatus 常量
@@ -67,6 +87,118 @@ const (
 	TranscriptStatusFailed     = "failed"
 )
 
+// Comment 表示一条视频评论（一级评论或其回复）
+type Comment struct {
+	ID              string     `json:"id"`
+	VideoID         string     `json:"videoId"`
+	ParentID        string     `json:"parentId"` // 所属一级评论 ID，一级评论为空
+	ReplyToID       string     `json:"replyToId"`
+	ReplyToNickname string     `json:"replyToNickname"`
+	Author          string     `json:"author"`
+	AuthorAvatar    string     `json:"authorAvatar"`
+	Content         string     `json:"content"`
+	LikeCount       int64      `json:"likeCount"`
+	ReplyCount      int64      `json:"replyCount"`
+	IPRegion        string     `json:"ipRegion"`
+	CommentTime     time.Time  `json:"commentTime"`
+	DeletedAt       *time.Time `json:"deletedAt,omitempty"` // 在后续采集中消失（可能被删除或折叠）的时间
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+	Replies         []Comment  `json:"replies,omitempty"`
+}
+
+// CommentVideo 表示已采集评论的视频
+type CommentVideo struct {
+	VideoID              string    `json:"videoId"`
+	VideoTitle           string    `json:"videoTitle"`
+	VideoAuthor          string    `json:"videoAuthor"` // 视频作者（来自浏览/下载记录）
+	CommentCount         int64     `json:"commentCount"`
+	OriginalCommentCount int64     `json:"originalCommentCount"`
+	FirstCollectedAt     time.Time `json:"firstCollectedAt"`
+	LastCollectedAt      time.Time `json:"lastCollectedAt"`
+}
+
+// CommentRun 表示一次评论采集批次
+type CommentRun struct {
+	ID           int64     `json:"id"`
+	VideoID      string    `json:"videoId"`
+	StartedAt    time.Time `json:"startedAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+	FullUpdate   bool      `json:"fullUpdate"`
+	TotalCount   int64     `json:"totalCount"`   // 本批次看到的评论数
+	NewCount     int64     `json:"newCount"`     // 本批次首次出现的评论数
+	ChangedCount int64     `json:"changedCount"` // 内容发生变化的评论数
+	DeletedCount int64     `json:"deletedCount"` // 本批次中消失的评论数
+}
+
+// CommentChange 表示一条评论在某个批次中的内容变化
+type CommentChange struct {
+	RunID      int64     `json:"runId"`
+	CommentID  string    `json:"commentId"`
+	OldContent string    `json:"oldContent"`
+	NewContent string    `json:"newContent"`
+	ChangedAt  time.Time `json:"changedAt"`
+}
+
+// CommentRunDiff 表示两个采集批次之间的差异
+type CommentRunDiff struct {
+	VideoID string          `json:"videoId"`
+	From    *CommentRun     `json:"from"`
+	To      *CommentRun     `json:"to"`
+	Added   []Comment       `json:"added"`
+	Deleted []Comment       `json:"deleted"`
+	Changed []CommentChange `json:"changed"`
+}
+
+// CommentSearchResult 表示评论全文搜索的一条结果
+type CommentSearchResult struct {
+	Comment
+	VideoTitle string `json:"videoTitle"`
+}
+
+// CommentAnalyticsFilter 指定评论统计/导出的范围，均为空时统计全部评论
+type CommentAnalyticsFilter struct {
+	VideoID  string `json:"videoId"`
+	Author   string `json:"author"`   // 视频作者
+	Interval string `json:"interval"` // 时间线粒度: day（默认）, hour
+	Limit    int    `json:"limit"`    // 排行榜条数，默认 10
+}
+
+// CommentAnalytics 表示评论统计结果
+type CommentAnalytics struct {
+	VideoID          string                `json:"videoId,omitempty"`
+	Author           string                `json:"author,omitempty"`
+	VideoCount       int64                 `json:"videoCount"`
+	TotalComments    int64                 `json:"totalComments"`
+	TopLevelComments int64                 `json:"topLevelComments"`
+	Replies          int64                 `json:"replies"`
+	UniqueCommenters int64                 `json:"uniqueCommenters"`
+	TotalLikes       int64                 `json:"totalLikes"`
+	Timeline         []CommentTimeBucket   `json:"timeline"`
+	TopCommenters    []CommentAuthorStat   `json:"topCommenters"`
+	IPRegions        []CommentRegionStat   `json:"ipRegions"`
+	MostLiked        []CommentSearchResult `json:"mostLiked"`
+}
+
+// CommentTimeBucket 表示时间线上的一个统计桶
+type CommentTimeBucket struct {
+	Time  string `json:"time"`
+	Count int64  `json:"count"`
+}
+
+// CommentAuthorStat 表示评论者统计
+type CommentAuthorStat struct {
+	Author string `json:"author"`
+	Count  int64  `json:"count"`
+	Likes  int64  `json:"likes"`
+}
+
+// CommentRegionStat 表示 IP 属地分布
+type CommentRegionStat struct {
+	Region string `json:"region"`
+	Count  int64  `json:"count"`
+}
+
 // QueueItem 表示下载队列项目
 type QueueItem struct {
 	ID              string    `json:"id"`
@@ -90,6 +222,9 @@ type QueueItem struct {
 	ChunksCompleted int       `json:"chunksCompleted"`
 	RetryCount      int       `json:"retryCount"`
 	ErrorMessage    string    `json:"errorMessage"`
+	NonceID         string    `json:"nonceId"` // objectNonceId，用于重新获取过期的视频链接
+	Quality         string    `json:"quality"`     // 画质策略，为空时使用设置中的默认策略
+	InitiatedBy     string    `json:"initiatedBy"` // 加入队列的控制台用户，抓取、关注列表等自动加入时为空
 	CreatedAt       time.Time `json:"createdAt"`
 	UpdatedAt       time.Time `json:"updatedAt"`
 }
@@ -103,6 +238,434 @@ const (
 	QueueStatusFailed      = "failed"
 )
 
+// CrawlJob 表示一次账号视频列表抓取任务
+type CrawlJob struct {
+	ID              int64      `json:"id"`
+	Username        string     `json:"username"`
+	Author          string     `json:"author"`
+	Status          string     `json:"status"` // running, paused, completed, failed, cancelled
+	Enqueue         bool       `json:"enqueue"`
+	Keyword         string     `json:"keyword"`
+	Since           *time.Time `json:"since,omitempty"`
+	Until           *time.Time `json:"until,omitempty"`
+	MaxPages        int        `json:"maxPages"`
+	IntervalMs      int        `json:"intervalMs"`
+	NextMarker      string     `json:"nextMarker"`
+	Pages           int        `json:"pages"`
+	DiscoveredCount int        `json:"discoveredCount"`
+	EnqueuedCount   int        `json:"enqueuedCount"`
+	ErrorMessage    string     `json:"errorMessage"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+	FinishedAt      *time.Time `json:"finishedAt,omitempty"`
+}
+
+// CrawlJobStatus 常量
+const (
+	CrawlStatusRunning   = "running"
+	CrawlStatusPaused    = "paused"
+	CrawlStatusCompleted = "completed"
+	CrawlStatusFailed    = "failed"
+	CrawlStatusCancelled = "cancelled"
+)
+
+// CrawlVideo 表示抓取任务发现的视频
+type CrawlVideo struct {
+	JobID        int64      `json:"jobId"`
+	VideoID      string     `json:"videoId"`
+	NonceID      string     `json:"nonceId"`
+	Title        string     `json:"title"`
+	Author       string     `json:"author"`
+	CoverURL     string     `json:"coverUrl"`
+	VideoURL     string     `json:"videoUrl"`
+	DecryptKey   string     `json:"decryptKey"`
+	Duration     int64      `json:"duration"` // 毫秒
+	Size         int64      `json:"size"`
+	MediaType    int        `json:"mediaType"` // 4=视频 2=图片
+	PublishTime  *time.Time `json:"publishTime,omitempty"`
+	Enqueued     bool       `json:"enqueued"`
+	DiscoveredAt time.Time  `json:"discoveredAt"`
+}
+
+// WatchAuthor 表示关注列表中的作者，定期检查是否有新视频
+type WatchAuthor struct {
+	Username        string     `json:"username"`
+	Nickname        string     `json:"nickname"`
+	HeadURL         string     `json:"headUrl"`
+	Policy          string     `json:"policy"`  // notify, enqueue
+	Keyword         string     `json:"keyword"` // 自动入队的标题关键词，多个用逗号分隔
+	IntervalMinutes int        `json:"intervalMinutes"`
+	Enabled         bool       `json:"enabled"`
+	LastSeenAt      *time.Time `json:"lastSeenAt,omitempty"` // 已见过的最新视频发布时间
+	LastCheckedAt   *time.Time `json:"lastCheckedAt,omitempty"`
+	NewVideoCount   int        `json:"newVideoCount"`
+	ErrorMessage    string     `json:"errorMessage"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+}
+
+// WatchPolicy 常量
+const (
+	WatchPolicyNotify  = "notify"
+	WatchPolicyEnqueue = "enqueue"
+)
+
+// WatchVideo 表示关注作者新发布的视频
+type WatchVideo struct {
+	Username    string     `json:"username"`
+	VideoID     string     `json:"videoId"`
+	NonceID     string     `json:"nonceId"`
+	Title       string     `json:"title"`
+	Author      string     `json:"author"`
+	CoverURL    string     `json:"coverUrl"`
+	VideoURL    string     `json:"videoUrl"`
+	DecryptKey  string     `json:"decryptKey"`
+	Duration    int64      `json:"duration"` // 毫秒
+	Size        int64      `json:"size"`
+	MediaType   int        `json:"mediaType"`
+	PublishTime *time.Time `json:"publishTime,omitempty"`
+	Enqueued    bool       `json:"enqueued"`
+	DetectedAt  time.Time  `json:"detectedAt"`
+}
+
+// FeedProfileCache 表示缓存的视频详情响应
+type FeedProfileCache struct {
+	ObjectID  string          `json:"objectId"`
+	NonceID   string          `json:"nonceId"`
+	Data      json.RawMessage `json:"data"`
+	FetchedAt time.Time       `json:"fetchedAt"`
+	ExpiresAt time.Time       `json:"expiresAt"`
+}
+
+// VideoFormat 表示视频的一个可选编码（media.spec 中的一项）
+type VideoFormat struct {
+	VideoID      string    `json:"videoId"`
+	FileFormat   string    `json:"fileFormat"` // 例如 "xWT111"，下载时作为 X-snsvideoflag 参数
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	BitRate      int64     `json:"bitRate"` // kbps
+	CodingFormat string    `json:"codingFormat"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// ShortSide 返回短边像素数（竖屏视频的 720p 指宽度为 720）
+func (f *VideoFormat) ShortSide() int {
+	if f.Width > 0 && f.Width < f.Height {
+		return f.Width
+	}
+	return f.Height
+}
+
+// 画质策略
+const (
+	QualityOriginal = "original" // 使用捕获时的链接，不指定编码
+	QualityBest     = "best"     // 分辨率最高（相同时码率最高）
+	QualitySmallest = "smallest" // 码率最低
+
+	QualityModeMaxHeight = "max"    // 短边不超过 MaxHeight 中最好的
+	QualityModeFormat    = "format" // 指定编码
+)
+
+// QualityPolicy 解析后的画质策略
+type QualityPolicy struct {
+	Mode      string // QualityOriginal、QualityBest、QualitySmallest、QualityModeMaxHeight 或 QualityModeFormat
+	MaxHeight int
+	Format    string
+}
+
+var (
+	qualityMaxHeightPattern = regexp.MustCompile(`^(?:<=|≤)?\s*(\d{3,4})p?$`)
+	qualityFormatPattern    = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+)
+
+// ParseQualityPolicy 解析画质策略："original"、"best"、"smallest"、"<=720p"（也可写作 "≤720p" 或 "720p"）
+// 或者编码名（如 "xWT111"）。空字符串视为 original
+func ParseQualityPolicy(quality string) (QualityPolicy, error) {
+	q := strings.TrimSpace(quality)
+	switch strings.ToLower(q) {
+	case "", QualityOriginal:
+		return QualityPolicy{Mode: QualityOriginal}, nil
+	case QualityBest:
+		return QualityPolicy{Mode: QualityBest}, nil
+	case QualitySmallest:
+		return QualityPolicy{Mode: QualitySmallest}, nil
+	}
+	if m := qualityMaxHeightPattern.FindStringSubmatch(strings.ToLower(q)); m != nil {
+		height, _ := strconv.Atoi(m[1])
+		return QualityPolicy{Mode: QualityModeMaxHeight, MaxHeight: height}, nil
+	}
+	if qualityFormatPattern.MatchString(q) {
+		return QualityPolicy{Mode: QualityModeFormat, Format: q}, nil
+	}
+	return QualityPolicy{}, fmt.Errorf("invalid quality: %s", quality)
+}
+
+// APIToken 表示控制台 API 的命名令牌，数据库中只保存令牌的哈希
+type APIToken struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // 令牌明文的前几位，便于识别
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// APIToken 权限范围常量
+const (
+	ScopeRead     = "read"     // 查看浏览记录、下载记录、统计等
+	ScopeDownload = "download" // 管理下载队列、批量下载、抓取和关注列表
+	ScopeSettings = "settings" // 修改设置和脚本规则
+	ScopeAdmin    = "admin"    // 全部权限，包括删除记录和管理令牌
+)
+
+// ValidScopes 所有可用的权限范围
+var ValidScopes = []string{ScopeRead, ScopeDownload, ScopeSettings, ScopeAdmin}
+
+// HasScope 判断令牌是否拥有指定权限，admin 拥有全部权限
+func (t *APIToken) HasScope(scope string) bool {
+	return hasScope(t.Scopes, scope)
+}
+
+// hasScope 判断权限列表是否包含指定权限，admin 拥有全部权限
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired 判断令牌在 now 时是否已过期
+func (t *APIToken) Expired(now time.Time) bool {
+	return t.ExpiresAt != nil && !now.Before(*t.ExpiresAt)
+}
+
+// ParseScopes 解析逗号分隔的权限范围，去重并校验
+func ParseScopes(value string) ([]string, error) {
+	scopes := []string{}
+	seen := make(map[string]bool)
+	for _, s := range strings.Split(value, ",") {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s == "" || seen[s] {
+			continue
+		}
+		valid := false
+		for _, v := range ValidScopes {
+			if s == v {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("invalid scope: %s", s)
+		}
+		seen[s] = true
+		scopes = append(scopes, s)
+	}
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+	return scopes, nil
+}
+
+// User 表示控制台的本地用户，数据库中只保存密码的 bcrypt 哈希
+type User struct {
+	ID          string          `json:"id"`
+	Username    string          `json:"username"`
+	Scopes      []string        `json:"scopes"` // 与 API 令牌相同的权限范围
+	Preferences UserPreferences `json:"preferences"`
+	LastLoginAt *time.Time      `json:"lastLoginAt,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+}
+
+// HasScope 判断用户是否拥有指定权限，admin 拥有全部权限
+func (u *User) HasScope(scope string) bool {
+	return hasScope(u.Scopes, scope)
+}
+
+// UserPreferences 表示用户的控制台偏好，保存为 users.preferences 中的 JSON
+type UserPreferences struct {
+	Theme     string         `json:"theme"`     // light、dark，为空时使用浏览器本地的设置
+	PageSizes map[string]int `json:"pageSizes"` // 页面 -> 每页条数，见 PreferencePages
+}
+
+// UserPreferences 主题常量
+const (
+	ThemeLight = "light"
+	ThemeDark  = "dark"
+)
+
+// PreferencePages 可以设置每页条数的控制台页面
+var PreferencePages = []string{"browse", "downloads", "trash", "comments", "audit"}
+
+// Validate 校验偏好设置
+func (p *UserPreferences) Validate() error {
+	if p.Theme != "" && p.Theme != ThemeLight && p.Theme != ThemeDark {
+		return fmt.Errorf("invalid theme: %s", p.Theme)
+	}
+	for page, size := range p.PageSizes {
+		valid := false
+		for _, v := range PreferencePages {
+			if page == v {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("invalid page: %s", page)
+		}
+		if size < 1 || size > 100 {
+			return fmt.Errorf("page size for %s must be between 1 and 100", page)
+		}
+	}
+	return nil
+}
+
+// UserSession 表示一个控制台登录会话，ID 为会话令牌的 SHA-256 哈希
+type UserSession struct {
+	ID         string    `json:"-"`
+	UserID     string    `json:"userId"`
+	CSRFToken  string    `json:"-"`
+	UserAgent  string    `json:"userAgent"`
+	SourceIP   string    `json:"sourceIp"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+// AuditEntry 表示一条审计记录（只追加，不修改、不删除）
+type AuditEntry struct {
+	ID         int64           `json:"id"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	Actor      string          `json:"actor"`     // 用户名、令牌名称、cloud 或 system
+	ActorType  string          `json:"actorType"` // user, token, anonymous, cloud, system
+	Action     string          `json:"action"`    // 如 downloads.delete、settings.update
+	TargetType string          `json:"targetType"`
+	TargetIDs  []string        `json:"targetIds"`
+	Before     json.RawMessage `json:"before,omitempty"` // 修改前的值，仅包含变化的字段
+	After      json.RawMessage `json:"after,omitempty"`  // 修改后的值
+	SourceIP   string          `json:"sourceIp"`
+	Detail     string          `json:"detail,omitempty"`
+}
+
+// AuditEntry 操作者类型常量
+const (
+	AuditActorToken     = "token"
+	AuditActorUser      = "user"
+	AuditActorAnonymous = "anonymous"
+	AuditActorCloud     = "cloud"
+	AuditActorSystem    = "system"
+)
+
+// AuditFilter 审计记录的查询条件，为空的字段不过滤
+type AuditFilter struct {
+	Actor      string     `json:"actor"`
+	ActorType  string     `json:"actorType"`
+	Action     string     `json:"action"` // 以 . 结尾时按前缀匹配，如 downloads.
+	TargetType string     `json:"targetType"`
+	TargetID   string     `json:"targetId"`
+	Since      *time.Time `json:"since"`
+	Until      *time.Time `json:"until"`
+}
+
+// Webhook 表示一个出站 Webhook，订阅的事件发生时以 POST 推送 JSON
+type Webhook struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`    // 订阅的事件，* 表示全部
+	Secret    string    `json:"-"`         // HMAC-SHA256 签名密钥，只写不读
+	HasSecret bool      `json:"hasSecret"` // 是否设置了签名密钥
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Webhook 事件常量
+const (
+	WebhookEventAll                 = "*"
+	WebhookEventDownloadCompleted   = "download.completed"
+	WebhookEventDownloadFailed      = "download.failed"
+	WebhookEventTranscriptReady     = "transcript.ready"
+	WebhookEventTranscriptFailed    = "transcript.failed"
+	WebhookEventBatchFinished       = "batch.finished"
+	WebhookEventCapabilityRegressed = "capability.regressed"
+	WebhookEventTest                = "webhook.test" // 只通过测试接口发送
+)
+
+// ValidWebhookEvents 所有可订阅的事件
+var ValidWebhookEvents = []string{
+	WebhookEventAll,
+	WebhookEventDownloadCompleted,
+	WebhookEventDownloadFailed,
+	WebhookEventTranscriptReady,
+	WebhookEventTranscriptFailed,
+	WebhookEventBatchFinished,
+	WebhookEventCapabilityRegressed,
+}
+
+// Subscribes 判断 Webhook 是否订阅了指定事件
+func (w *Webhook) Subscribes(event string) bool {
+	for _, e := range w.Events {
+		if e == event || e == WebhookEventAll {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseWebhookEvents 去重并校验订阅的事件
+func ParseWebhookEvents(events []string) ([]string, error) {
+	result := []string{}
+	seen := make(map[string]bool)
+	for _, e := range events {
+		e = strings.ToLower(strings.TrimSpace(e))
+		if e == "" || seen[e] {
+			continue
+		}
+		valid := false
+		for _, v := range ValidWebhookEvents {
+			if e == v {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("invalid webhook event: %s", e)
+		}
+		seen[e] = true
+		result = append(result, e)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("at least one event is required")
+	}
+	return result, nil
+}
+
+// WebhookDelivery 表示一次 Webhook 推送及其结果
+type WebhookDelivery struct {
+	ID           int64           `json:"id"`
+	WebhookID    string          `json:"webhookId"`
+	Event        string          `json:"event"`
+	Payload      json.RawMessage `json:"payload"`
+	Status       string          `json:"status"` // pending, success, failed
+	Attempts     int             `json:"attempts"`
+	ResponseCode int             `json:"responseCode,omitempty"`
+	Error        string          `json:"error,omitempty"`
+	CreatedAt    time.Time       `json:"createdAt"`
+	UpdatedAt    time.Time       `json:"updatedAt"`
+}
+
+// WebhookDelivery 状态常量
+const (
+	WebhookDeliveryPending = "pending"
+	WebhookDeliverySuccess = "success"
+	WebhookDeliveryFailed  = "failed"
+)
+
 // Settings 表示应用程序设置
 type Settings struct {
 	DownloadDir           string `json:"downloadDir"`
@@ -120,6 +683,9 @@ type Settings struct {
 	WhisperModelPath      string `json:"whisperModelPath"`
 	TranscriptionLanguage      string `json:"transcriptionLanguage"`
 	DeleteVideoAfterTranscript bool   `json:"deleteVideoAfterTranscript"`
+	ThumbnailEnabled           bool   `json:"thumbnailEnabled"`
+	ThumbnailKeyframes         int    `json:"thumbnailKeyframes"`
+	DownloadQuality            string `json:"downloadQuality"` // 默认画质策略，见 ParseQualityPolicy
 }
 
 // DefaultSettings 返回默认设置
@@ -140,6 +706,9 @@ func DefaultSettings() *Settings {
 		WhisperModelPath:      "",
 		TranscriptionLanguage:      "zh",
 		DeleteVideoAfterTranscript: false,
+		ThumbnailEnabled:           true,
+		ThumbnailKeyframes:         6,
+		DownloadQuality:            QualityOriginal,
 	}
 }
 
@@ -158,6 +727,31 @@ type FilterParams struct {
 	EndDate   *time.Time `json:"endDate"`
 	Status    string     `json:"status"`
 	Query     string     `json:"query"`
+	Author    string     `json:"author"` // 作者名称，精确匹配
+	// 以下仅作用于下载记录（基于 ffprobe 探测的媒体信息）
+	MinResolution    int    `json:"minResolution"` // 短边像素下限，如 1080 表示 ≥1080p
+	VideoCodec       string `json:"videoCodec"`
+	TranscriptStatus string `json:"transcriptStatus"` // 转写状态，"none" 表示未转写
+	InitiatedBy      string `json:"initiatedBy"`      // 发起下载的控制台用户
+	Trashed          bool   `json:"trashed"`          // 只列出回收站中的记录
+}
+
+// AuthorStats 表示一个作者的浏览和下载汇总，按作者名称合并浏览记录和下载记录
+type AuthorStats struct {
+	Name             string     `json:"name"`
+	AuthorID         string     `json:"authorId"` // 来自浏览记录，只有下载记录的作者为空
+	BrowseCount      int64      `json:"browseCount"`
+	DownloadCount    int64      `json:"downloadCount"`
+	DownloadedSize   int64      `json:"downloadedSize"` // 下载文件的总大小（字节）
+	LastBrowseTime   *time.Time `json:"lastBrowseTime"`
+	LastDownloadTime *time.Time `json:"lastDownloadTime"`
+}
+
+// AuthorFilterParams 表示作者列表的过滤参数
+type AuthorFilterParams struct {
+	PaginationParams
+	Query string   `json:"query"` // 作者名称关键词
+	Names []string `json:"names"` // 只返回这些作者
 }
 
 // PagedResult 表示分页结果
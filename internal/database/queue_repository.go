@@ -28,15 +28,15 @@ func (r *QueueRepository) Add(item *QueueItem) error {
 			id, video_id, title, author, cover_url, video_url, decrypt_key, duration, resolution, total_size, downloaded_size,
 			status, priority, added_time, start_time, speed, chunk_size,
 			chunks_total, chunks_completed, retry_count, error_message,
-			created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			nonce_id, quality, initiated_by, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	_, err := r.db.Exec(query,
 		item.ID, item.VideoID, item.Title, item.Author, item.CoverURL, item.VideoURL, item.DecryptKey,
 		item.Duration, item.Resolution, item.TotalSize, item.DownloadedSize, item.Status, item.Priority,
 		item.AddedTime, item.StartTime, item.Speed, item.ChunkSize,
 		item.ChunksTotal, item.ChunksCompleted, item.RetryCount,
-		item.ErrorMessage, item.CreatedAt, item.UpdatedAt,
+		item.ErrorMessage, item.NonceID, item.Quality, item.InitiatedBy, item.CreatedAt, item.UpdatedAt,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to add queue item: %w", err)
@@ -51,7 +51,7 @@ func (r *QueueRepository) GetByID(id string) (*QueueItem, error) {
 			COALESCE(duration, 0) as duration, COALESCE(resolution, '') as resolution, total_size, downloaded_size,
 			status, priority, added_time, start_time, speed, chunk_size,
 			chunks_total, chunks_completed, retry_count, error_message,
-			created_at, updated_at
+			COALESCE(nonce_id, '') as nonce_id, COALESCE(quality, '') as quality, COALESCE(initiated_by, '') as initiated_by, created_at, updated_at
 		FROM download_queue WHERE id = ?
 	`
 	item := &QueueItem{}
@@ -65,7 +65,7 @@ func (r *QueueRepository) GetByID(id string) (*QueueItem, error) {
 		&item.Duration, &resolution, &item.TotalSize, &item.DownloadedSize, &item.Status, &item.Priority,
 		&item.AddedTime, &startTime, &item.Speed, &item.ChunkSize,
 		&item.ChunksTotal, &item.ChunksCompleted, &item.RetryCount,
-		&errorMessage, &item.CreatedAt, &item.UpdatedAt,
+		&errorMessage, &item.NonceID, &item.Quality, &item.InitiatedBy, &item.CreatedAt, &item.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -92,14 +92,14 @@ func (r *QueueRepository) Update(item *QueueItem) error {
 			video_id = ?, title = ?, author = ?, cover_url = ?, video_url = ?, decrypt_key = ?, duration = ?, total_size = ?,
 			downloaded_size = ?, status = ?, priority = ?, added_time = ?,
 			start_time = ?, speed = ?, chunk_size = ?, chunks_total = ?,
-			chunks_completed = ?, retry_count = ?, error_message = ?, updated_at = ?
+			chunks_completed = ?, retry_count = ?, error_message = ?, nonce_id = ?, quality = ?, updated_at = ?
 		WHERE id = ?
 	`
 	result, err := r.db.Exec(query,
 		item.VideoID, item.Title, item.Author, item.CoverURL, item.VideoURL, item.DecryptKey, item.Duration, item.TotalSize,
 		item.DownloadedSize, item.Status, item.Priority, item.AddedTime,
 		item.StartTime, item.Speed, item.ChunkSize, item.ChunksTotal,
-		item.ChunksCompleted, item.RetryCount, item.ErrorMessage,
+		item.ChunksCompleted, item.RetryCount, item.ErrorMessage, item.NonceID, item.Quality,
 		item.UpdatedAt, item.ID,
 	)
 	if err != nil {
@@ -163,7 +163,7 @@ func (r *QueueRepository) List() ([]QueueItem, error) {
 			COALESCE(duration, 0) as duration, total_size, downloaded_size,
 			status, priority, added_time, start_time, speed, chunk_size,
 			chunks_total, chunks_completed, retry_count, error_message,
-			created_at, updated_at
+			COALESCE(nonce_id, '') as nonce_id, COALESCE(quality, '') as quality, COALESCE(initiated_by, '') as initiated_by, created_at, updated_at
 		FROM download_queue
 		ORDER BY priority DESC, added_time ASC
 	`
@@ -186,7 +186,7 @@ func (r *QueueRepository) List() ([]QueueItem, error) {
 			&item.Duration, &item.TotalSize, &item.DownloadedSize, &item.Status, &item.Priority,
 			&item.AddedTime, &startTime, &item.Speed, &item.ChunkSize,
 			&item.ChunksTotal, &item.ChunksCompleted, &item.RetryCount,
-			&errorMessage, &item.CreatedAt, &item.UpdatedAt,
+			&errorMessage, &item.NonceID, &item.Quality, &item.InitiatedBy, &item.CreatedAt, &item.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan queue item: %w", err)
@@ -214,7 +214,7 @@ func (r *QueueRepository) ListByStatus(status string) ([]QueueItem, error) {
 			COALESCE(duration, 0) as duration, total_size, downloaded_size,
 			status, priority, added_time, start_time, speed, chunk_size,
 			chunks_total, chunks_completed, retry_count, error_message,
-			created_at, updated_at
+			COALESCE(nonce_id, '') as nonce_id, COALESCE(quality, '') as quality, COALESCE(initiated_by, '') as initiated_by, created_at, updated_at
 		FROM download_queue
 		WHERE status = ?
 		ORDER BY priority DESC, added_time ASC
@@ -238,7 +238,7 @@ func (r *QueueRepository) ListByStatus(status string) ([]QueueItem, error) {
 			&item.Duration, &item.TotalSize, &item.DownloadedSize, &item.Status, &item.Priority,
 			&item.AddedTime, &startTime, &item.Speed, &item.ChunkSize,
 			&item.ChunksTotal, &item.ChunksCompleted, &item.RetryCount,
-			&errorMessage, &item.CreatedAt, &item.UpdatedAt,
+			&errorMessage, &item.NonceID, &item.Quality, &item.InitiatedBy, &item.CreatedAt, &item.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan queue item: %w", err)
@@ -291,6 +291,33 @@ func (r *QueueRepository) UpdateProgress(id string, downloadedSize int64, chunks
 	return nil
 }
 
+// GetNonceID 获取视频的 objectNonceId，队列中没有记录时返回空字符串
+func (r *QueueRepository) GetNonceID(videoID string) (string, error) {
+	var nonceID string
+	err := r.db.QueryRow(
+		"SELECT nonce_id FROM download_queue WHERE video_id = ? AND nonce_id != '' LIMIT 1", videoID,
+	).Scan(&nonceID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get queue item nonce id: %w", err)
+	}
+	return nonceID, nil
+}
+
+// UpdateVideoSource 更新视频的下载链接和解密密钥（同一视频的所有队列项目），返回更新的行数
+func (r *QueueRepository) UpdateVideoSource(videoID, videoURL, decryptKey string) (int64, error) {
+	result, err := r.db.Exec(
+		"UPDATE download_queue SET video_url = ?, decrypt_key = ?, updated_at = ? WHERE video_id = ?",
+		videoURL, decryptKey, time.Now(), videoID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update queue item video source: %w", err)
+	}
+	return result.RowsAffected()
+}
+
 // Reorder 根据新顺序更新队列项目的优先级
 func (r *QueueRepository) Reorder(ids []string) error {
 	if len(ids) == 0 {
@@ -349,7 +376,7 @@ func (r *QueueRepository) GetNextPending() (*QueueItem, error) {
 			COALESCE(duration, 0) as duration, total_size, downloaded_size,
 			status, priority, added_time, start_time, speed, chunk_size,
 			chunks_total, chunks_completed, retry_count, error_message,
-			created_at, updated_at
+			COALESCE(nonce_id, '') as nonce_id, COALESCE(quality, '') as quality, COALESCE(initiated_by, '') as initiated_by, created_at, updated_at
 		FROM download_queue
 		WHERE status = ?
 		ORDER BY priority DESC, added_time ASC
@@ -365,7 +392,7 @@ func (r *QueueRepository) GetNextPending() (*QueueItem, error) {
 		&item.Duration, &item.TotalSize, &item.DownloadedSize, &item.Status, &item.Priority,
 		&item.AddedTime, &startTime, &item.Speed, &item.ChunkSize,
 		&item.ChunksTotal, &item.ChunksCompleted, &item.RetryCount,
-		&errorMessage, &item.CreatedAt, &item.UpdatedAt,
+		&errorMessage, &item.NonceID, &item.Quality, &item.InitiatedBy, &item.CreatedAt, &item.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -34,6 +34,9 @@ const (
 	SettingKeyWhisperModelPath       = "whisper_model_path"
 	SettingKeyTranscriptionLanguage      = "transcription_language"
 	SettingKeyDeleteVideoAfterTranscript = "delete_video_after_transcript"
+	SettingKeyThumbnailEnabled           = "thumbnail_enabled"
+	SettingKeyThumbnailKeyframes         = "thumbnail_keyframes"
+	SettingKeyDownloadQuality            = "download_quality"
 )
 
 // Get 根据键获取设置值
@@ -157,6 +160,17 @@ func (r *SettingsRepository) Load() (*Settings, error) {
 	if v, ok := settingsMap[SettingKeyDeleteVideoAfterTranscript]; ok {
 		settings.DeleteVideoAfterTranscript = v == "true"
 	}
+	if v, ok := settingsMap[SettingKeyThumbnailEnabled]; ok {
+		settings.ThumbnailEnabled = v == "true"
+	}
+	if v, ok := settingsMap[SettingKeyThumbnailKeyframes]; ok && v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			settings.ThumbnailKeyframes = n
+		}
+	}
+	if v, ok := settingsMap[SettingKeyDownloadQuality]; ok && v != "" {
+		settings.DownloadQuality = v
+	}
 
 	return settings, nil
 }
@@ -193,6 +207,9 @@ func (r *SettingsRepository) Save(settings *Settings) error {
 		SettingKeyWhisperModelPath:      settings.WhisperModelPath,
 		SettingKeyTranscriptionLanguage:      settings.TranscriptionLanguage,
 		SettingKeyDeleteVideoAfterTranscript: strconv.FormatBool(settings.DeleteVideoAfterTranscript),
+		SettingKeyThumbnailEnabled:           strconv.FormatBool(settings.ThumbnailEnabled),
+		SettingKeyThumbnailKeyframes:         strconv.Itoa(settings.ThumbnailKeyframes),
+		SettingKeyDownloadQuality:            settings.DownloadQuality,
 	}
 
 	for key, value := range settingsMap {
@@ -247,6 +264,16 @@ func (r *SettingsRepository) Validate(settings *Settings) error {
 		}
 	}
 
+	// Validate thumbnail keyframe count (0 to 24)
+	if settings.ThumbnailKeyframes < 0 || settings.ThumbnailKeyframes > 24 {
+		return fmt.Errorf("thumbnail keyframes must be between 0 and 24")
+	}
+
+	// Validate download quality policy
+	if _, err := ParseQualityPolicy(settings.DownloadQuality); err != nil {
+		return err
+	}
+
 	return nil
 }
 
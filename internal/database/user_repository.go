@@ -0,0 +1,259 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// UserRepository 处理控制台用户的数据库操作
+type UserRepository struct {
+	db *sql.DB
+}
+
+// NewUserRepository 创建一个新的 UserRepository
+func NewUserRepository() *UserRepository {
+	return &UserRepository{db: GetDB()}
+}
+
+// userColumns 是 User 对应的查询列
+const userColumns = `id, username, scopes, COALESCE(preferences, '{}'), last_login_at, created_at, updated_at`
+
+// Create 保存新用户，passwordHash 为密码的 bcrypt 哈希
+func (r *UserRepository) Create(user *User, passwordHash string) error {
+	now := time.Now()
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	prefs, err := json.Marshal(user.Preferences)
+	if err != nil {
+		return fmt.Errorf("failed to encode user preferences: %w", err)
+	}
+	_, err = r.db.Exec(`
+		INSERT INTO users (id, username, password_hash, scopes, preferences, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		user.ID, user.Username, passwordHash, strings.Join(user.Scopes, ","), string(prefs), user.CreatedAt, user.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	return nil
+}
+
+// GetByID 按 ID 查找用户，不存在时返回 nil
+func (r *UserRepository) GetByID(id string) (*User, error) {
+	user, err := scanUser(r.db.QueryRow(`SELECT `+userColumns+` FROM users WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return user, err
+}
+
+// GetByUsername 按用户名（不区分大小写）查找用户，不存在时返回 nil
+func (r *UserRepository) GetByUsername(username string) (*User, error) {
+	user, err := scanUser(r.db.QueryRow(`SELECT `+userColumns+` FROM users WHERE username = ?`, username))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return user, err
+}
+
+// GetPasswordHash 获取用户的密码哈希，用户不存在时返回空字符串
+func (r *UserRepository) GetPasswordHash(id string) (string, error) {
+	var hash string
+	err := r.db.QueryRow(`SELECT password_hash FROM users WHERE id = ?`, id).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get password hash: %w", err)
+	}
+	return hash, nil
+}
+
+// List 按创建时间列出全部用户
+func (r *UserRepository) List() ([]User, error) {
+	rows, err := r.db.Query(`SELECT ` + userColumns + ` FROM users ORDER BY created_at ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	return users, rows.Err()
+}
+
+// Exists 判断是否创建过用户
+func (r *UserRepository) Exists() (bool, error) {
+	var exists bool
+	if err := r.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM users)`).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check users: %w", err)
+	}
+	return exists, nil
+}
+
+// UpdateScopes 更新用户的权限范围
+func (r *UserRepository) UpdateScopes(id string, scopes []string) error {
+	_, err := r.db.Exec(`UPDATE users SET scopes = ?, updated_at = ? WHERE id = ?`, strings.Join(scopes, ","), time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update user scopes: %w", err)
+	}
+	return nil
+}
+
+// UpdatePassword 更新用户的密码哈希
+func (r *UserRepository) UpdatePassword(id, passwordHash string) error {
+	_, err := r.db.Exec(`UPDATE users SET password_hash = ?, updated_at = ? WHERE id = ?`, passwordHash, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update user password: %w", err)
+	}
+	return nil
+}
+
+// UpdatePreferences 更新用户的控制台偏好
+func (r *UserRepository) UpdatePreferences(id string, prefs *UserPreferences) error {
+	data, err := json.Marshal(prefs)
+	if err != nil {
+		return fmt.Errorf("failed to encode user preferences: %w", err)
+	}
+	_, err = r.db.Exec(`UPDATE users SET preferences = ?, updated_at = ? WHERE id = ?`, string(data), time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update user preferences: %w", err)
+	}
+	return nil
+}
+
+// TouchLogin 更新用户的最近登录时间
+func (r *UserRepository) TouchLogin(id string, at time.Time) error {
+	if _, err := r.db.Exec(`UPDATE users SET last_login_at = ? WHERE id = ?`, at, id); err != nil {
+		return fmt.Errorf("failed to update user last login: %w", err)
+	}
+	return nil
+}
+
+// Delete 删除用户及其会话，返回删除的数量
+func (r *UserRepository) Delete(id string) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM user_sessions WHERE user_id = ?`, id); err != nil {
+		return 0, fmt.Errorf("failed to delete user sessions: %w", err)
+	}
+	result, err := tx.Exec(`DELETE FROM users WHERE id = ?`, id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete user: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// scanUser 扫描一行用户数据
+func scanUser(row rowScanner) (*User, error) {
+	user := &User{}
+	var scopes, prefs string
+	var lastLoginAt sql.NullTime
+	err := row.Scan(&user.ID, &user.Username, &scopes, &prefs, &lastLoginAt, &user.CreatedAt, &user.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan user: %w", err)
+	}
+	user.Scopes = strings.Split(scopes, ",")
+	if err := json.Unmarshal([]byte(prefs), &user.Preferences); err != nil {
+		return nil, fmt.Errorf("failed to decode user preferences: %w", err)
+	}
+	if lastLoginAt.Valid {
+		user.LastLoginAt = &lastLoginAt.Time
+	}
+	return user, nil
+}
+
+// UserSessionRepository 处理控制台登录会话的数据库操作
+type UserSessionRepository struct {
+	db *sql.DB
+}
+
+// NewUserSessionRepository 创建一个新的 UserSessionRepository
+func NewUserSessionRepository() *UserSessionRepository {
+	return &UserSessionRepository{db: GetDB()}
+}
+
+// Create 保存新会话
+func (r *UserSessionRepository) Create(session *UserSession) error {
+	_, err := r.db.Exec(`
+		INSERT INTO user_sessions (id, user_id, csrf_token, user_agent, source_ip, created_at, last_seen_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		session.ID, session.UserID, session.CSRFToken, session.UserAgent, session.SourceIP,
+		session.CreatedAt, session.LastSeenAt, session.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create user session: %w", err)
+	}
+	return nil
+}
+
+// Get 按 ID 查找会话（包括已过期的会话），不存在时返回 nil
+func (r *UserSessionRepository) Get(id string) (*UserSession, error) {
+	session := &UserSession{}
+	err := r.db.QueryRow(`
+		SELECT id, user_id, csrf_token, COALESCE(user_agent, ''), COALESCE(source_ip, ''), created_at, last_seen_at, expires_at
+		FROM user_sessions WHERE id = ?`, id,
+	).Scan(&session.ID, &session.UserID, &session.CSRFToken, &session.UserAgent, &session.SourceIP,
+		&session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user session: %w", err)
+	}
+	return session, nil
+}
+
+// Touch 更新会话的最近使用时间和过期时间
+func (r *UserSessionRepository) Touch(id string, lastSeenAt, expiresAt time.Time) error {
+	_, err := r.db.Exec(`UPDATE user_sessions SET last_seen_at = ?, expires_at = ? WHERE id = ?`, lastSeenAt, expiresAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to touch user session: %w", err)
+	}
+	return nil
+}
+
+// Delete 删除会话
+func (r *UserSessionRepository) Delete(id string) error {
+	if _, err := r.db.Exec(`DELETE FROM user_sessions WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete user session: %w", err)
+	}
+	return nil
+}
+
+// DeleteByUser 删除用户除 exceptID 以外的全部会话，返回删除的数量
+func (r *UserSessionRepository) DeleteByUser(userID, exceptID string) (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM user_sessions WHERE user_id = ? AND id != ?`, userID, exceptID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete user sessions: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// DeleteExpired 删除在 now 之前过期的会话，返回删除的数量
+func (r *UserSessionRepository) DeleteExpired(now time.Time) (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM user_sessions WHERE expires_at < ?`, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired user sessions: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// VideoFormatRepository 处理视频可选编码的数据库操作
+type VideoFormatRepository struct {
+	db *sql.DB
+}
+
+// NewVideoFormatRepository 创建一个新的 VideoFormatRepository
+func NewVideoFormatRepository() *VideoFormatRepository {
+	return &VideoFormatRepository{db: GetDB()}
+}
+
+// Save 替换视频的全部可选编码
+func (r *VideoFormatRepository) Save(videoID string, formats []VideoFormat) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM video_formats WHERE video_id = ?", videoID); err != nil {
+		return fmt.Errorf("failed to clear video formats: %w", err)
+	}
+
+	now := time.Now()
+	for i := range formats {
+		f := &formats[i]
+		f.VideoID = videoID
+		f.UpdatedAt = now
+		_, err := tx.Exec(`
+			INSERT INTO video_formats (video_id, file_format, width, height, bit_rate, coding_format, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(video_id, file_format) DO NOTHING`,
+			f.VideoID, f.FileFormat, f.Width, f.Height, f.BitRate, f.CodingFormat, f.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save video format: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit video formats: %w", err)
+	}
+	return nil
+}
+
+// List 获取视频的可选编码（按分辨率、码率从高到低）
+func (r *VideoFormatRepository) List(videoID string) ([]VideoFormat, error) {
+	rows, err := r.db.Query(`
+		SELECT video_id, file_format, width, height, bit_rate, coding_format, updated_at
+		FROM video_formats WHERE video_id = ?
+		ORDER BY width * height DESC, bit_rate DESC`, videoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list video formats: %w", err)
+	}
+	defer rows.Close()
+
+	formats := []VideoFormat{}
+	for rows.Next() {
+		var f VideoFormat
+		if err := rows.Scan(&f.VideoID, &f.FileFormat, &f.Width, &f.Height, &f.BitRate, &f.CodingFormat, &f.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan video format: %w", err)
+		}
+		formats = append(formats, f)
+	}
+	return formats, rows.Err()
+}
@@ -0,0 +1,228 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// WatchRepository 处理关注列表的数据库操作
+type WatchRepository struct {
+	db *sql.DB
+}
+
+// NewWatchRepository 创建一个新的 WatchRepository
+func NewWatchRepository() *WatchRepository {
+	return &WatchRepository{db: GetDB()}
+}
+
+// watchAuthorColumns 是 WatchAuthor 对应的查询列
+const watchAuthorColumns = `username, nickname, head_url, policy, keyword, interval_minutes, enabled,
+	last_seen_at, last_checked_at, new_video_count, error_message, created_at, updated_at`
+
+// watchVideoColumns 是 WatchVideo 对应的查询列
+const watchVideoColumns = `username, video_id, nonce_id, title, author, cover_url, video_url,
+	decrypt_key, duration, size, media_type, publish_time, enqueued, detected_at`
+
+// CreateAuthor 添加关注作者
+func (r *WatchRepository) CreateAuthor(a *WatchAuthor) error {
+	now := time.Now()
+	a.CreatedAt = now
+	a.UpdatedAt = now
+
+	_, err := r.db.Exec(`
+		INSERT INTO watch_authors (`+watchAuthorColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		a.Username, a.Nickname, a.HeadURL, a.Policy, a.Keyword, a.IntervalMinutes, a.Enabled,
+		a.LastSeenAt, a.LastCheckedAt, a.NewVideoCount, a.ErrorMessage, a.CreatedAt, a.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create watch author: %w", err)
+	}
+	return nil
+}
+
+// UpdateAuthor 保存作者的设置和检查状态
+func (r *WatchRepository) UpdateAuthor(a *WatchAuthor) error {
+	a.UpdatedAt = time.Now()
+	_, err := r.db.Exec(`
+		UPDATE watch_authors SET nickname = ?, head_url = ?, policy = ?, keyword = ?, interval_minutes = ?,
+			enabled = ?, last_seen_at = ?, last_checked_at = ?, new_video_count = ?, error_message = ?, updated_at = ?
+		WHERE username = ?`,
+		a.Nickname, a.HeadURL, a.Policy, a.Keyword, a.IntervalMinutes,
+		a.Enabled, a.LastSeenAt, a.LastCheckedAt, a.NewVideoCount, a.ErrorMessage, a.UpdatedAt,
+		a.Username,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update watch author: %w", err)
+	}
+	return nil
+}
+
+// UpdateCheckResult 只写入一次检查的结果：检查时间、最新发布时间、错误信息，并把 newVideos 累加到新视频数。
+// 检查期间通过 UpdateAuthor 修改的策略、间隔、启用状态不会被检查开始时读到的旧值覆盖；昵称只在为空时补全
+func (r *WatchRepository) UpdateCheckResult(a *WatchAuthor, newVideos int) error {
+	a.UpdatedAt = time.Now()
+	_, err := r.db.Exec(`
+		UPDATE watch_authors SET nickname = CASE WHEN nickname = '' THEN ? ELSE nickname END,
+			last_seen_at = ?, last_checked_at = ?, error_message = ?, new_video_count = new_video_count + ?, updated_at = ?
+		WHERE username = ?`,
+		a.Nickname, a.LastSeenAt, a.LastCheckedAt, a.ErrorMessage, newVideos, a.UpdatedAt,
+		a.Username,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update watch author check result: %w", err)
+	}
+	return nil
+}
+
+// GetAuthor 获取关注作者，不存在时返回 nil
+func (r *WatchRepository) GetAuthor(username string) (*WatchAuthor, error) {
+	a, err := scanWatchAuthor(r.db.QueryRow("SELECT "+watchAuthorColumns+" FROM watch_authors WHERE username = ?", username))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return a, err
+}
+
+// ListAuthors 获取所有关注作者（按添加时间排序）
+func (r *WatchRepository) ListAuthors() ([]WatchAuthor, error) {
+	rows, err := r.db.Query("SELECT " + watchAuthorColumns + " FROM watch_authors ORDER BY created_at")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list watch authors: %w", err)
+	}
+	defer rows.Close()
+
+	authors := []WatchAuthor{}
+	for rows.Next() {
+		a, err := scanWatchAuthor(rows)
+		if err != nil {
+			return nil, err
+		}
+		authors = append(authors, *a)
+	}
+	return authors, rows.Err()
+}
+
+// DeleteAuthor 取消关注，同时删除检测到的视频
+func (r *WatchRepository) DeleteAuthor(username string) error {
+	result, err := r.db.Exec("DELETE FROM watch_authors WHERE username = ?", username)
+	if err != nil {
+		return fmt.Errorf("failed to delete watch author: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SaveVideos 保存检测到的新视频，返回其中首次保存的视频
+func (r *WatchRepository) SaveVideos(videos []WatchVideo) ([]WatchVideo, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	added := []WatchVideo{}
+	for i := range videos {
+		v := &videos[i]
+		v.DetectedAt = now
+
+		result, err := tx.Exec(`
+			INSERT INTO watch_videos (`+watchVideoColumns+`)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0, ?)
+			ON CONFLICT(username, video_id) DO NOTHING`,
+			v.Username, v.VideoID, v.NonceID, v.Title, v.Author, v.CoverURL, v.VideoURL,
+			v.DecryptKey, v.Duration, v.Size, v.MediaType, v.PublishTime, v.DetectedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save watch video: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			added = append(added, *v)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit watch videos: %w", err)
+	}
+	return added, nil
+}
+
+// MarkEnqueued 标记视频已加入下载队列
+func (r *WatchRepository) MarkEnqueued(username string, videoIDs []string) error {
+	for _, id := range videoIDs {
+		if _, err := r.db.Exec("UPDATE watch_videos SET enqueued = 1 WHERE username = ? AND video_id = ?", username, id); err != nil {
+			return fmt.Errorf("failed to mark watch video enqueued: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListVideos 获取检测到的新视频（按检测时间倒序），username 为空时返回所有作者的
+func (r *WatchRepository) ListVideos(username string, params *PaginationParams) (*PagedResult[WatchVideo], error) {
+	where := ""
+	args := []interface{}{}
+	if username != "" {
+		where = " WHERE username = ?"
+		args = append(args, username)
+	}
+
+	var total int64
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM watch_videos"+where, args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count watch videos: %w", err)
+	}
+
+	args = append(args, params.PageSize, (params.Page-1)*params.PageSize)
+	rows, err := r.db.Query("SELECT "+watchVideoColumns+" FROM watch_videos"+where+
+		" ORDER BY detected_at DESC, publish_time DESC LIMIT ? OFFSET ?", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list watch videos: %w", err)
+	}
+	defer rows.Close()
+
+	videos := []WatchVideo{}
+	for rows.Next() {
+		v := WatchVideo{}
+		var publishTime sql.NullTime
+		if err := rows.Scan(
+			&v.Username, &v.VideoID, &v.NonceID, &v.Title, &v.Author, &v.CoverURL, &v.VideoURL,
+			&v.DecryptKey, &v.Duration, &v.Size, &v.MediaType, &publishTime, &v.Enqueued, &v.DetectedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan watch video: %w", err)
+		}
+		if publishTime.Valid {
+			v.PublishTime = &publishTime.Time
+		}
+		videos = append(videos, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return NewPagedResult(videos, total, params.Page, params.PageSize), nil
+}
+
+// scanWatchAuthor 扫描一行关注作者数据
+func scanWatchAuthor(row rowScanner) (*WatchAuthor, error) {
+	a := &WatchAuthor{}
+	var lastSeen, lastChecked sql.NullTime
+	err := row.Scan(
+		&a.Username, &a.Nickname, &a.HeadURL, &a.Policy, &a.Keyword, &a.IntervalMinutes, &a.Enabled,
+		&lastSeen, &lastChecked, &a.NewVideoCount, &a.ErrorMessage, &a.CreatedAt, &a.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan watch author: %w", err)
+	}
+	if lastSeen.Valid {
+		a.LastSeenAt = &lastSeen.Time
+	}
+	if lastChecked.Valid {
+		a.LastCheckedAt = &lastChecked.Time
+	}
+	return a, nil
+}
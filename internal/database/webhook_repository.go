@@ -0,0 +1,224 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WebhookRepository 处理出站 Webhook 及其推送记录的数据库操作
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+// NewWebhookRepository 创建一个新的 WebhookRepository
+func NewWebhookRepository() *WebhookRepository {
+	return &WebhookRepository{db: GetDB()}
+}
+
+// webhookColumns 是 Webhook 对应的查询列
+const webhookColumns = `id, name, url, events, secret, enabled, created_at, updated_at`
+
+// webhookDeliveryColumns 是 WebhookDelivery 对应的查询列
+const webhookDeliveryColumns = `id, webhook_id, event, payload, status, attempts, response_code, error, created_at, updated_at`
+
+// Create 保存新的 Webhook
+func (r *WebhookRepository) Create(hook *Webhook) error {
+	now := time.Now()
+	hook.CreatedAt = now
+	hook.UpdatedAt = now
+	_, err := r.db.Exec(`
+		INSERT INTO webhooks (id, name, url, events, secret, enabled, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		hook.ID, hook.Name, hook.URL, strings.Join(hook.Events, ","), hook.Secret, hook.Enabled, hook.CreatedAt, hook.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+	hook.HasSecret = hook.Secret != ""
+	return nil
+}
+
+// GetByID 按 ID 查找，不存在时返回 nil
+func (r *WebhookRepository) GetByID(id string) (*Webhook, error) {
+	row := r.db.QueryRow(`SELECT `+webhookColumns+` FROM webhooks WHERE id = ?`, id)
+	hook, err := scanWebhook(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return hook, err
+}
+
+// List 按创建时间列出全部 Webhook
+func (r *WebhookRepository) List() ([]Webhook, error) {
+	return r.query(`SELECT ` + webhookColumns + ` FROM webhooks ORDER BY created_at ASC`)
+}
+
+// ListEnabled 列出已启用的 Webhook
+func (r *WebhookRepository) ListEnabled() ([]Webhook, error) {
+	return r.query(`SELECT ` + webhookColumns + ` FROM webhooks WHERE enabled = 1 ORDER BY created_at ASC`)
+}
+
+// Update 更新 Webhook 的全部可修改字段
+func (r *WebhookRepository) Update(hook *Webhook) error {
+	hook.UpdatedAt = time.Now()
+	_, err := r.db.Exec(`
+		UPDATE webhooks SET name = ?, url = ?, events = ?, secret = ?, enabled = ?, updated_at = ?
+		WHERE id = ?`,
+		hook.Name, hook.URL, strings.Join(hook.Events, ","), hook.Secret, hook.Enabled, hook.UpdatedAt, hook.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook: %w", err)
+	}
+	hook.HasSecret = hook.Secret != ""
+	return nil
+}
+
+// Delete 删除 Webhook 及其推送记录，返回删除的 Webhook 数量
+func (r *WebhookRepository) Delete(id string) (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM webhooks WHERE id = ?`, id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	if _, err := r.db.Exec(`DELETE FROM webhook_deliveries WHERE webhook_id = ?`, id); err != nil {
+		return 0, fmt.Errorf("failed to delete webhook deliveries: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// CreateDelivery 保存一条推送记录，并只保留该 Webhook 最近的 keep 条记录
+func (r *WebhookRepository) CreateDelivery(delivery *WebhookDelivery, keep int) error {
+	now := time.Now()
+	delivery.CreatedAt = now
+	delivery.UpdatedAt = now
+	result, err := r.db.Exec(`
+		INSERT INTO webhook_deliveries (webhook_id, event, payload, status, attempts, response_code, error, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		delivery.WebhookID, delivery.Event, string(delivery.Payload), delivery.Status, delivery.Attempts,
+		delivery.ResponseCode, delivery.Error, delivery.CreatedAt, delivery.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+	delivery.ID, _ = result.LastInsertId()
+
+	if keep > 0 {
+		_, err = r.db.Exec(`
+			DELETE FROM webhook_deliveries WHERE webhook_id = ? AND id <= (
+				SELECT id FROM webhook_deliveries WHERE webhook_id = ? ORDER BY id DESC LIMIT 1 OFFSET ?
+			)`, delivery.WebhookID, delivery.WebhookID, keep)
+		if err != nil {
+			return fmt.Errorf("failed to prune webhook deliveries: %w", err)
+		}
+	}
+	return nil
+}
+
+// UpdateDelivery 更新推送的状态、尝试次数和最后一次响应
+func (r *WebhookRepository) UpdateDelivery(delivery *WebhookDelivery) error {
+	delivery.UpdatedAt = time.Now()
+	_, err := r.db.Exec(`
+		UPDATE webhook_deliveries SET status = ?, attempts = ?, response_code = ?, error = ?, updated_at = ?
+		WHERE id = ?`,
+		delivery.Status, delivery.Attempts, delivery.ResponseCode, delivery.Error, delivery.UpdatedAt, delivery.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// ListDeliveries 按时间倒序分页列出 Webhook 的推送记录
+func (r *WebhookRepository) ListDeliveries(webhookID string, params *PaginationParams) (*PagedResult[WebhookDelivery], error) {
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize < 1 {
+		params.PageSize = 20
+	}
+	if params.PageSize > 100 {
+		params.PageSize = 100
+	}
+
+	var total int64
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM webhook_deliveries WHERE webhook_id = ?`, webhookID).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count webhook deliveries: %w", err)
+	}
+
+	rows, err := r.db.Query(`SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries WHERE webhook_id = ?
+		ORDER BY id DESC LIMIT ? OFFSET ?`, webhookID, params.PageSize, (params.Page-1)*params.PageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries, err := scanWebhookDeliveries(rows)
+	if err != nil {
+		return nil, err
+	}
+	return NewPagedResult(deliveries, total, params.Page, params.PageSize), nil
+}
+
+// ListPendingDeliveries 按创建顺序列出全部未完成的推送记录
+func (r *WebhookRepository) ListPendingDeliveries() ([]WebhookDelivery, error) {
+	rows, err := r.db.Query(`SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries WHERE status = ? ORDER BY id`,
+		WebhookDeliveryPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+	return scanWebhookDeliveries(rows)
+}
+
+// scanWebhookDeliveries 扫描推送记录列表
+func scanWebhookDeliveries(rows *sql.Rows) ([]WebhookDelivery, error) {
+	deliveries := []WebhookDelivery{}
+	for rows.Next() {
+		var d WebhookDelivery
+		var payload string
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.Event, &payload, &d.Status, &d.Attempts,
+			&d.ResponseCode, &d.Error, &d.CreatedAt, &d.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		d.Payload = json.RawMessage(payload)
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// query 执行查询并扫描 Webhook 列表
+func (r *WebhookRepository) query(query string, args ...interface{}) ([]Webhook, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	hooks := []Webhook{}
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, *hook)
+	}
+	return hooks, rows.Err()
+}
+
+// scanWebhook 扫描一行 Webhook 数据
+func scanWebhook(row rowScanner) (*Webhook, error) {
+	hook := &Webhook{}
+	var events string
+	err := row.Scan(&hook.ID, &hook.Name, &hook.URL, &events, &hook.Secret, &hook.Enabled, &hook.CreatedAt, &hook.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan webhook: %w", err)
+	}
+	hook.Events = strings.Split(events, ",")
+	hook.HasSecret = hook.Secret != ""
+	return hook, nil
+}
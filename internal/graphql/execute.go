@@ -0,0 +1,671 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// 错误码，放在错误的 extensions.code 中
+const (
+	CodeParseFailed      = "parse_failed"      // 查询语法错误
+	CodeValidationFailed = "validation_failed" // 字段、参数或变量不合法
+	CodeQueryTooDeep     = "query_too_deep"    // 超过 Schema.MaxDepth
+	CodeQueryTooComplex  = "query_too_complex" // 超过 Schema.MaxComplexity 或 maxSelections
+	CodeNotAllowed       = "not_allowed"       // 非查询操作
+	CodeResolverFailed   = "resolver_failed"   // 字段解析失败，该字段为 null
+)
+
+// Request GraphQL 请求
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Response GraphQL 响应，校验失败时没有 data
+type Response struct {
+	Data   *OrderedMap `json:"data,omitempty"`
+	Errors []*Error    `json:"errors,omitempty"`
+}
+
+// Location 查询文档中的位置，行列均从 1 开始
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Error GraphQL 错误
+type Error struct {
+	Message    string                 `json:"message"`
+	Locations  []Location             `json:"locations,omitempty"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// Code 返回错误码
+func (e *Error) Code() string {
+	code, _ := e.Extensions["code"].(string)
+	return code
+}
+
+func codeExtensions(code string) map[string]interface{} {
+	return map[string]interface{}{"code": code}
+}
+
+func newError(code string, loc Location, format string, args ...interface{}) *Error {
+	return &Error{Message: fmt.Sprintf(format, args...), Locations: []Location{loc}, Extensions: codeExtensions(code)}
+}
+
+// OrderedMap 按查询中的字段顺序输出 JSON 对象
+type OrderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newOrderedMap() *OrderedMap {
+	return &OrderedMap{values: make(map[string]interface{})}
+}
+
+// Set 设置键值，新键追加到末尾
+func (m *OrderedMap) Set(key string, value interface{}) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// Get 读取键值
+func (m *OrderedMap) Get(key string) (interface{}, bool) {
+	v, ok := m.values[key]
+	return v, ok
+}
+
+// MarshalJSON 实现 json.Marshaler
+func (m *OrderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// ============================================================================
+// 执行入口
+// ============================================================================
+
+// Execute 解析、校验并执行查询。
+// 语法错误、校验失败和超出限制时只返回 errors；字段解析失败时该字段为 null，错误追加到 errors
+func (s *Schema) Execute(ctx context.Context, req *Request) *Response {
+	doc, err := Parse(req.Query)
+	if err != nil {
+		return &Response{Errors: []*Error{asError(err)}}
+	}
+
+	op, gqlErr := selectOperation(doc, req.OperationName)
+	if gqlErr != nil {
+		return &Response{Errors: []*Error{gqlErr}}
+	}
+
+	vars, errs := coerceVariables(op, req.Variables)
+	if len(errs) > 0 {
+		return &Response{Errors: errs}
+	}
+
+	v := &validator{schema: s, doc: doc, vars: vars, budget: maxSelections}
+	complexity := v.selectionSet(s.Query, op.SelectionSet, 1)
+	if v.budget < 0 {
+		return &Response{Errors: []*Error{newError(CodeQueryTooComplex, op.Loc,
+			"query expands to more than %d selections", maxSelections)}}
+	}
+	if len(v.errs) > 0 {
+		return &Response{Errors: v.errs}
+	}
+	if s.MaxComplexity > 0 && complexity > s.MaxComplexity {
+		return &Response{Errors: []*Error{newError(CodeQueryTooComplex, op.Loc,
+			"query complexity %d exceeds the limit of %d", complexity, s.MaxComplexity)}}
+	}
+
+	e := &executor{ctx: ctx, schema: s, doc: doc, vars: vars}
+	data := e.selectionSet(s.Query, nil, op.SelectionSet, nil)
+	return &Response{Data: data, Errors: e.errs}
+}
+
+func asError(err error) *Error {
+	if e, ok := err.(*Error); ok {
+		return e
+	}
+	return &Error{Message: err.Error()}
+}
+
+func selectOperation(doc *Document, name string) (*Operation, *Error) {
+	var op *Operation
+	switch {
+	case name != "":
+		for _, o := range doc.Operations {
+			if o.Name == name {
+				op = o
+			}
+		}
+		if op == nil {
+			return nil, &Error{Message: fmt.Sprintf("unknown operation %q", name), Extensions: codeExtensions(CodeValidationFailed)}
+		}
+	case len(doc.Operations) == 1:
+		op = doc.Operations[0]
+	case len(doc.Operations) == 0:
+		return nil, &Error{Message: "document contains no operations", Extensions: codeExtensions(CodeValidationFailed)}
+	default:
+		return nil, &Error{Message: "operationName is required when the document contains multiple operations", Extensions: codeExtensions(CodeValidationFailed)}
+	}
+	if op.Type != "query" {
+		return nil, newError(CodeNotAllowed, op.Loc, "%s operations are not supported, only queries are allowed", op.Type)
+	}
+	return op, nil
+}
+
+// coerceVariables 合并变量默认值，检查必填变量
+func coerceVariables(op *Operation, input map[string]interface{}) (map[string]interface{}, []*Error) {
+	vars := make(map[string]interface{})
+	var errs []*Error
+	for _, def := range op.Variables {
+		if v, ok := input[def.Name]; ok && v != nil {
+			vars[def.Name] = v
+			continue
+		}
+		if def.Default != nil {
+			vars[def.Name] = def.Default
+			continue
+		}
+		if def.Required {
+			errs = append(errs, newError(CodeValidationFailed, def.Loc, "variable $%s of type %s is required", def.Name, def.Type))
+		}
+		vars[def.Name] = unsetVariable{}
+	}
+	return vars, errs
+}
+
+// unsetVariable 已声明但未提供且没有默认值的变量，引用它的参数视为未传
+type unsetVariable struct{}
+
+// ============================================================================
+// 字段收集：展开片段、处理 @skip/@include，按返回键合并同名字段
+// ============================================================================
+
+type fieldGroup struct {
+	key    string
+	fields []*Field
+}
+
+// maxSelections 校验时最多展开的选择数（字段、内联片段和片段引用），
+// 防止片段层层互相引用时展开结果远大于查询文本
+const maxSelections = 10000
+
+type fieldCollector struct {
+	doc     *Document
+	vars    map[string]interface{}
+	onError func(*Error)
+	// budget 剩余可展开的选择数，为 nil 时不限制；耗尽后变为负数并停止展开
+	budget *int
+}
+
+func (c *fieldCollector) collect(t *Object, sels []Selection) []*fieldGroup {
+	var groups []*fieldGroup
+	index := make(map[string]*fieldGroup)
+	c.walk(t, sels, map[string]bool{}, map[string]bool{}, &groups, index)
+	return groups
+}
+
+// walk 展开选择集。visiting 是当前引用链上的片段，用于发现循环引用；
+// expanded 是本选择集内已展开过的片段，同一片段再次引用不会带来新字段，直接跳过
+func (c *fieldCollector) walk(t *Object, sels []Selection, visiting, expanded map[string]bool, groups *[]*fieldGroup, index map[string]*fieldGroup) {
+	for _, sel := range sels {
+		if c.budget != nil {
+			if *c.budget--; *c.budget < 0 {
+				return
+			}
+		}
+		switch s := sel.(type) {
+		case *Field:
+			if !c.included(s.Directives) {
+				continue
+			}
+			key := s.ResponseKey()
+			g, ok := index[key]
+			if !ok {
+				g = &fieldGroup{key: key}
+				index[key] = g
+				*groups = append(*groups, g)
+			} else if g.fields[0].Name != s.Name {
+				c.error(newError(CodeValidationFailed, s.Loc, "fields %q and %q conflict because they share the response name %q", g.fields[0].Name, s.Name, key))
+				continue
+			}
+			g.fields = append(g.fields, s)
+		case *InlineFragment:
+			if !c.included(s.Directives) || !c.matches(t, s.TypeCondition, s.Loc) {
+				continue
+			}
+			c.walk(t, s.SelectionSet, visiting, expanded, groups, index)
+		case *FragmentSpread:
+			if !c.included(s.Directives) {
+				continue
+			}
+			frag, ok := c.doc.Fragments[s.Name]
+			if !ok {
+				c.error(newError(CodeValidationFailed, s.Loc, "unknown fragment %q", s.Name))
+				continue
+			}
+			if visiting[s.Name] {
+				c.error(newError(CodeValidationFailed, s.Loc, "fragment %q spreads itself", s.Name))
+				continue
+			}
+			if expanded[s.Name] || !c.matches(t, frag.TypeCondition, s.Loc) {
+				continue
+			}
+			visiting[s.Name] = true
+			expanded[s.Name] = true
+			c.walk(t, frag.SelectionSet, visiting, expanded, groups, index)
+			delete(visiting, s.Name)
+		}
+	}
+}
+
+// matches 片段的类型条件必须是当前对象类型；没有接口和联合类型，其他类型名视为错误
+func (c *fieldCollector) matches(t *Object, condition string, loc Location) bool {
+	if condition == "" || condition == t.Name {
+		return true
+	}
+	c.error(newError(CodeValidationFailed, loc, "fragment on %q cannot be spread on type %q", condition, t.Name))
+	return false
+}
+
+// included 处理 @skip(if:) 和 @include(if:)
+func (c *fieldCollector) included(dirs []*Directive) bool {
+	for _, dir := range dirs {
+		if dir.Name != "skip" && dir.Name != "include" {
+			c.error(newError(CodeValidationFailed, dir.Loc, "unknown directive @%s", dir.Name))
+			continue
+		}
+		var cond interface{}
+		found := false
+		for _, arg := range dir.Arguments {
+			if arg.Name != "if" {
+				c.error(newError(CodeValidationFailed, arg.Loc, "unknown argument %q on @%s", arg.Name, dir.Name))
+				continue
+			}
+			v, err := resolveValue(arg.Value, c.vars)
+			if err != nil {
+				c.error(newError(CodeValidationFailed, arg.Loc, "%s", err))
+				continue
+			}
+			cond, found = v, true
+		}
+		b, ok := cond.(bool)
+		if !found || !ok {
+			c.error(newError(CodeValidationFailed, dir.Loc, "@%s requires a Boolean argument \"if\"", dir.Name))
+			continue
+		}
+		if (dir.Name == "skip") == b {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *fieldCollector) error(err *Error) {
+	if c.onError != nil {
+		c.onError(err)
+	}
+}
+
+// mergedSelections 合并同一返回键下各字段的子选择集
+func mergedSelections(fields []*Field) []Selection {
+	if len(fields) == 1 {
+		return fields[0].SelectionSet
+	}
+	var sels []Selection
+	for _, f := range fields {
+		sels = append(sels, f.SelectionSet...)
+	}
+	return sels
+}
+
+// ============================================================================
+// 参数
+// ============================================================================
+
+// resolveValue 把字面量中的变量引用替换为变量值
+func resolveValue(v interface{}, vars map[string]interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case Variable:
+		value, ok := vars[val.Name]
+		if !ok {
+			return nil, fmt.Errorf("variable $%s is not defined", val.Name)
+		}
+		if _, unset := value.(unsetVariable); unset {
+			return nil, nil
+		}
+		return value, nil
+	case []interface{}:
+		list := make([]interface{}, len(val))
+		for i, item := range val {
+			r, err := resolveValue(item, vars)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = r
+		}
+		return list, nil
+	case map[string]interface{}:
+		return nil, fmt.Errorf("input objects are not supported")
+	}
+	return v, nil
+}
+
+// coerceArgs 校验字段参数并填充默认值
+func coerceArgs(defs Args, args []*Argument, vars map[string]interface{}, loc Location) (map[string]interface{}, []*Error) {
+	result := make(map[string]interface{}, len(defs))
+	var errs []*Error
+	given := make(map[string]bool, len(args))
+	for _, arg := range args {
+		given[arg.Name] = true
+		def, ok := defs[arg.Name]
+		if !ok {
+			errs = append(errs, newError(CodeValidationFailed, arg.Loc, "unknown argument %q", arg.Name))
+			continue
+		}
+		raw, err := resolveValue(arg.Value, vars)
+		if err != nil {
+			errs = append(errs, newError(CodeValidationFailed, arg.Loc, "argument %q: %s", arg.Name, err))
+			continue
+		}
+		if raw == nil {
+			// null 或未提供的可选变量视为未传参数
+			given[arg.Name] = false
+			continue
+		}
+		value, err := coerceInput(def, raw)
+		if err != nil {
+			errs = append(errs, newError(CodeValidationFailed, arg.Loc, "argument %q: %s", arg.Name, err))
+			continue
+		}
+		result[arg.Name] = value
+	}
+	for name, def := range defs {
+		if given[name] {
+			continue
+		}
+		if def.Required {
+			errs = append(errs, newError(CodeValidationFailed, loc, "argument %q of type %s is required", name, def.Type))
+			continue
+		}
+		if def.Default != nil {
+			result[name] = def.Default
+		}
+	}
+	return result, errs
+}
+
+func coerceInput(def *Arg, v interface{}) (interface{}, error) {
+	if list, ok := def.Type.(*List); ok {
+		items, isList := v.([]interface{})
+		if !isList {
+			items = []interface{}{v}
+		}
+		elem := &Arg{Type: list.OfType, Enum: def.Enum}
+		result := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			r, err := coerceInput(elem, item)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, r)
+		}
+		return result, nil
+	}
+
+	scalar, ok := def.Type.(*Scalar)
+	if !ok {
+		return nil, fmt.Errorf("unsupported argument type %s", def.Type)
+	}
+	if len(def.Enum) > 0 {
+		var s string
+		switch e := v.(type) {
+		case EnumValue:
+			s = string(e)
+		case string:
+			s = e
+		}
+		for _, allowed := range def.Enum {
+			if s == allowed {
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("expected one of %s", strings.Join(def.Enum, ", "))
+	}
+	if _, isEnum := v.(EnumValue); isEnum {
+		return nil, fmt.Errorf("expected %s, got enum value %s", scalar.Name, v)
+	}
+	value, ok := scalar.ParseValue(v)
+	if !ok {
+		return nil, fmt.Errorf("expected %s, got %v", scalar.Name, v)
+	}
+	return value, nil
+}
+
+// ============================================================================
+// 校验：字段存在性、参数、选择集，并计算深度和开销
+// ============================================================================
+
+type validator struct {
+	schema *Schema
+	doc    *Document
+	vars   map[string]interface{}
+	errs   []*Error
+	deep   bool // 已报告超出深度
+	budget int  // 剩余可展开的选择数，见 maxSelections
+}
+
+func (v *validator) error(err *Error) {
+	v.errs = append(v.errs, err)
+}
+
+// selectionSet 校验选择集，返回其开销
+func (v *validator) selectionSet(t *Object, sels []Selection, depth int) int {
+	collector := &fieldCollector{doc: v.doc, vars: v.vars, onError: v.error, budget: &v.budget}
+	total := 0
+	groups := collector.collect(t, sels)
+	if v.budget < 0 {
+		return 0
+	}
+	for _, g := range groups {
+		f := g.fields[0]
+		if f.Name == "__typename" {
+			if len(f.Arguments) > 0 || len(f.SelectionSet) > 0 {
+				v.error(newError(CodeValidationFailed, f.Loc, "__typename takes no arguments or selections"))
+			}
+			continue
+		}
+		def, ok := t.Fields[f.Name]
+		if !ok {
+			v.error(newError(CodeValidationFailed, f.Loc, "cannot query field %q on type %q", f.Name, t.Name))
+			continue
+		}
+
+		var args map[string]interface{}
+		argsValid := true
+		for _, field := range g.fields {
+			a, errs := coerceArgs(def.Args, field.Arguments, v.vars, field.Loc)
+			if len(errs) > 0 {
+				argsValid = false
+				v.errs = append(v.errs, errs...)
+			}
+			if args == nil {
+				args = a
+			}
+		}
+
+		if v.schema.MaxDepth > 0 && depth > v.schema.MaxDepth {
+			if !v.deep {
+				v.deep = true
+				v.error(newError(CodeQueryTooDeep, f.Loc, "query depth exceeds the limit of %d", v.schema.MaxDepth))
+			}
+			continue
+		}
+
+		cost := def.Cost
+		obj, isObject := namedType(def.Type).(*Object)
+		subs := mergedSelections(g.fields)
+		switch {
+		case isObject && len(subs) == 0:
+			v.error(newError(CodeValidationFailed, f.Loc, "field %q of type %s must have a selection of subfields", f.Name, def.Type))
+		case !isObject && len(subs) > 0:
+			v.error(newError(CodeValidationFailed, f.Loc, "field %q of type %s must not have a selection of subfields", f.Name, def.Type))
+		case isObject:
+			size := 1
+			// 参数不合法时查询不会执行，不再按参数估算列表长度
+			if def.ListSize != nil && argsValid {
+				size = def.ListSize(args)
+			}
+			cost += size * v.selectionSet(obj, subs, depth+1)
+		}
+		total += cost
+	}
+	return total
+}
+
+// namedType 去掉列表包装
+func namedType(t Type) Type {
+	for {
+		l, ok := t.(*List)
+		if !ok {
+			return t
+		}
+		t = l.OfType
+	}
+}
+
+// ============================================================================
+// 执行
+// ============================================================================
+
+type executor struct {
+	ctx    context.Context
+	schema *Schema
+	doc    *Document
+	vars   map[string]interface{}
+	errs   []*Error
+}
+
+func (e *executor) selectionSet(t *Object, source interface{}, sels []Selection, path []interface{}) *OrderedMap {
+	result := newOrderedMap()
+	collector := &fieldCollector{doc: e.doc, vars: e.vars}
+	for _, g := range collector.collect(t, sels) {
+		f := g.fields[0]
+		if f.Name == "__typename" {
+			result.Set(g.key, t.Name)
+			continue
+		}
+		def := t.Fields[f.Name]
+		fieldPath := appendPath(path, g.key)
+		args, _ := coerceArgs(def.Args, f.Arguments, e.vars, f.Loc)
+
+		var value interface{}
+		if def.Resolve != nil {
+			var err error
+			value, err = def.Resolve(ResolveParams{Context: e.ctx, Source: source, Args: args})
+			if err != nil {
+				e.fieldError(f, fieldPath, err.Error())
+				result.Set(g.key, nil)
+				continue
+			}
+		} else {
+			value = defaultResolve(source, f.Name)
+		}
+		result.Set(g.key, e.complete(def.Type, f, mergedSelections(g.fields), value, fieldPath))
+	}
+	return result
+}
+
+func (e *executor) complete(t Type, f *Field, sels []Selection, value interface{}, path []interface{}) interface{} {
+	if isNil(value) {
+		return nil
+	}
+	switch typ := t.(type) {
+	case *List:
+		rv := reflect.ValueOf(value)
+		for rv.Kind() == reflect.Ptr {
+			rv = rv.Elem()
+		}
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			e.fieldError(f, path, fmt.Sprintf("expected a list for field %q", f.Name))
+			return nil
+		}
+		items := make([]interface{}, rv.Len())
+		for i := range items {
+			item := rv.Index(i)
+			if item.Kind() == reflect.Struct && item.CanAddr() {
+				item = item.Addr()
+			}
+			items[i] = e.complete(typ.OfType, f, sels, item.Interface(), appendPath(path, i))
+		}
+		return items
+	case *Object:
+		return e.selectionSet(typ, value, sels, path)
+	case *Scalar:
+		rv := reflect.ValueOf(value)
+		if rv.Kind() == reflect.Ptr && rv.Type().Elem().Kind() != reflect.Struct {
+			value = rv.Elem().Interface()
+		}
+		result, ok := typ.Serialize(value)
+		if !ok {
+			e.fieldError(f, path, fmt.Sprintf("cannot serialize %T as %s", value, typ.Name))
+			return nil
+		}
+		return result
+	}
+	return nil
+}
+
+func (e *executor) fieldError(f *Field, path []interface{}, message string) {
+	e.errs = append(e.errs, &Error{
+		Message:    message,
+		Locations:  []Location{f.Loc},
+		Path:       path,
+		Extensions: codeExtensions(CodeResolverFailed),
+	})
+}
+
+func appendPath(path []interface{}, key interface{}) []interface{} {
+	p := make([]interface{}, len(path), len(path)+1)
+	copy(p, path)
+	return append(p, key)
+}
+
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
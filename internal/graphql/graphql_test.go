@@ -0,0 +1,237 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testItem struct {
+	ID      string    `json:"id"`
+	Title   string    `json:"title"`
+	Size    int64     `json:"size"`
+	Created time.Time `json:"created"`
+}
+
+func newTestSchema() *Schema {
+	item := &Object{Name: "Item"}
+	item.Fields = Fields{
+		"id":      {Type: ID},
+		"title":   {Type: String},
+		"size":    {Type: Int},
+		"created": {Type: DateTime},
+		"related": {
+			Type: item,
+			Cost: 1,
+			Resolve: func(p ResolveParams) (interface{}, error) {
+				return p.Source, nil
+			},
+		},
+		"broken": {
+			Type: String,
+			Resolve: func(p ResolveParams) (interface{}, error) {
+				return nil, errors.New("boom")
+			},
+		},
+	}
+	items := []testItem{
+		{ID: "1", Title: "one", Size: 10, Created: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{ID: "2", Title: "two", Size: 20},
+		{ID: "3", Title: "three", Size: 30},
+	}
+	query := &Object{Name: "Query", Fields: Fields{
+		"items": {
+			Type: &List{OfType: item},
+			Args: Args{
+				"limit": {Type: Int, Default: 2},
+				"order": {Type: String, Default: "asc", Enum: []string{"asc", "desc"}},
+			},
+			Cost:     1,
+			ListSize: func(args map[string]interface{}) int { return args["limit"].(int) },
+			Resolve: func(p ResolveParams) (interface{}, error) {
+				result := append([]testItem(nil), items...)
+				if p.Args["order"] == "desc" {
+					for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+						result[i], result[j] = result[j], result[i]
+					}
+				}
+				return result[:min(p.Args["limit"].(int), len(result))], nil
+			},
+		},
+		"item": {
+			Type: item,
+			Args: Args{"id": {Type: ID, Required: true}},
+			Cost: 1,
+			Resolve: func(p ResolveParams) (interface{}, error) {
+				for i := range items {
+					if items[i].ID == p.Args["id"] {
+						return &items[i], nil
+					}
+				}
+				return nil, nil
+			},
+		},
+	}}
+	return &Schema{Query: query, MaxDepth: 4, MaxComplexity: 20}
+}
+
+func execute(t *testing.T, query string, variables map[string]interface{}) (string, *Response) {
+	t.Helper()
+	resp := newTestSchema().Execute(context.Background(), &Request{Query: query, Variables: variables})
+	if resp.Data == nil {
+		return "", resp
+	}
+	data, err := json.Marshal(resp.Data)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	return string(data), resp
+}
+
+func TestExecute_SelectionAndArguments(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		want      string
+	}{
+		{
+			name:  "defaults and field order",
+			query: `{ items { title id } }`,
+			want:  `{"items":[{"title":"one","id":"1"},{"title":"two","id":"2"}]}`,
+		},
+		{
+			name:  "alias, enum literal and typename",
+			query: `query { last: items(limit: 1, order: desc) { __typename id } }`,
+			want:  `{"last":[{"__typename":"Item","id":"3"}]}`,
+		},
+		{
+			name:      "variables and fragments",
+			query:     `query Q($id: ID!) { item(id: $id) { ...F ... on Item { size } } } fragment F on Item { id created }`,
+			variables: map[string]interface{}{"id": "1"},
+			want:      `{"item":{"id":"1","created":"2024-01-02T03:04:05Z","size":10}}`,
+		},
+		{
+			name:  "zero time is null and missing object is null",
+			query: `{ item(id: 2) { created } missing: item(id: "9") { id } }`,
+			want:  `{"item":{"created":null},"missing":null}`,
+		},
+		{
+			name:      "skip and include",
+			query:     `query($yes: Boolean = true) { item(id: "1") { id @skip(if: $yes) title @include(if: $yes) } }`,
+			want:      `{"item":{"title":"one"}}`,
+			variables: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, resp := execute(t, tt.query, tt.variables)
+			if len(resp.Errors) > 0 {
+				t.Fatalf("unexpected errors: %v", resp.Errors[0])
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecute_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		code      string
+	}{
+		{"syntax error", `{ items { id }`, nil, CodeParseFailed},
+		{"mutation", `mutation { items { id } }`, nil, CodeNotAllowed},
+		{"unknown field", `{ items { nope } }`, nil, CodeValidationFailed},
+		{"unknown argument", `{ items(nope: 1) { id } }`, nil, CodeValidationFailed},
+		{"invalid enum", `{ items(order: sideways) { id } }`, nil, CodeValidationFailed},
+		{"missing required argument", `{ item { id } }`, nil, CodeValidationFailed},
+		{"missing selection", `{ items }`, nil, CodeValidationFailed},
+		{"missing variable", `query($id: ID!) { item(id: $id) { id } }`, nil, CodeValidationFailed},
+		{"wrong variable type", `query($n: Int) { items(limit: $n) { id } }`, map[string]interface{}{"n": "x"}, CodeValidationFailed},
+		{"fragment cycle", `{ item(id: 1) { ...A } } fragment A on Item { ...B } fragment B on Item { ...A }`, nil, CodeValidationFailed},
+		{"too deep", `{ item(id: 1) { related { related { related { id } } } } }`, nil, CodeQueryTooDeep},
+		{"too complex", `{ items(limit: 10) { related { related { id } } } }`, nil, CodeQueryTooComplex},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, resp := execute(t, tt.query, tt.variables)
+			if resp.Data != nil {
+				t.Fatalf("expected no data, got %v", resp.Data)
+			}
+			if len(resp.Errors) == 0 || resp.Errors[0].Code() != tt.code {
+				t.Fatalf("expected %s, got %+v", tt.code, resp.Errors)
+			}
+		})
+	}
+}
+
+func TestExecute_SyntaxErrorLocation(t *testing.T) {
+	_, resp := execute(t, "{\n  items { id ) }\n}", nil)
+	if len(resp.Errors) != 1 || len(resp.Errors[0].Locations) != 1 {
+		t.Fatalf("expected one located error, got %+v", resp.Errors)
+	}
+	if loc := resp.Errors[0].Locations[0]; loc.Line != 2 || loc.Column != 14 {
+		t.Errorf("expected 2:14, got %d:%d", loc.Line, loc.Column)
+	}
+}
+
+func TestExecute_ResolverErrorKeepsPartialData(t *testing.T) {
+	got, resp := execute(t, `{ items(limit: 1) { id broken } }`, nil)
+	if got != `{"items":[{"id":"1","broken":null}]}` {
+		t.Errorf("unexpected data: %s", got)
+	}
+	if len(resp.Errors) != 1 || resp.Errors[0].Code() != CodeResolverFailed {
+		t.Fatalf("expected one resolver error, got %+v", resp.Errors)
+	}
+	path, _ := json.Marshal(resp.Errors[0].Path)
+	if string(path) != `["items",0,"broken"]` {
+		t.Errorf("unexpected error path: %s", path)
+	}
+}
+
+func TestExecute_NestedFragmentSpreads(t *testing.T) {
+	// 每层片段引用下一层两次，逐次展开需要 2^22 次
+	var query strings.Builder
+	query.WriteString(`{ ...F0 }`)
+	for i := 0; i < 22; i++ {
+		fmt.Fprintf(&query, ` fragment F%d on Query { ...F%d ...F%d }`, i, i+1, i+1)
+	}
+	query.WriteString(` fragment F22 on Query { item(id: 1) { id } }`)
+
+	start := time.Now()
+	got, resp := execute(t, query.String(), nil)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("validation took %v", elapsed)
+	}
+	if len(resp.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", resp.Errors[0])
+	}
+	if got != `{"item":{"id":"1"}}` {
+		t.Errorf("unexpected data: %s", got)
+	}
+}
+
+func TestExecute_TooManySelections(t *testing.T) {
+	var query strings.Builder
+	query.WriteString(`{ item(id: 1) {`)
+	for i := 0; i <= maxSelections; i++ {
+		fmt.Fprintf(&query, ` a%d: id`, i)
+	}
+	query.WriteString(` } }`)
+
+	_, resp := execute(t, query.String(), nil)
+	if resp.Data != nil {
+		t.Fatalf("expected no data, got %v", resp.Data)
+	}
+	if len(resp.Errors) != 1 || resp.Errors[0].Code() != CodeQueryTooComplex {
+		t.Fatalf("expected %s, got %+v", CodeQueryTooComplex, resp.Errors)
+	}
+}
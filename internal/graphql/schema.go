@@ -0,0 +1,317 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// Schema 定义
+// 只支持查询：对象、列表和标量类型，参数只接受标量或标量列表
+// ============================================================================
+
+// Type 输出或输入类型：*Scalar、*Object 或 *List
+type Type interface {
+	String() string
+}
+
+// Scalar 标量类型
+type Scalar struct {
+	Name        string
+	Description string
+	// Serialize 把解析结果转换为 JSON 值，ok 为 false 表示类型不匹配
+	Serialize func(v interface{}) (result interface{}, ok bool)
+	// ParseValue 把参数值（字面量或变量）转换为 Go 值，ok 为 false 表示类型不匹配
+	ParseValue func(v interface{}) (result interface{}, ok bool)
+}
+
+func (s *Scalar) String() string { return s.Name }
+
+// Object 对象类型
+type Object struct {
+	Name        string
+	Description string
+	Fields      Fields
+}
+
+func (o *Object) String() string { return o.Name }
+
+// List 列表类型
+type List struct {
+	OfType Type
+}
+
+func (l *List) String() string { return "[" + l.OfType.String() + "]" }
+
+// Fields 对象的字段
+type Fields map[string]*FieldDef
+
+// FieldDef 字段定义
+type FieldDef struct {
+	Type        Type
+	Description string
+	Args        Args
+	// Cost 解析该字段的开销，访问数据库的字段为 1，直接读取属性的字段为 0
+	Cost int
+	// ListSize 返回字段结果中的元素个数上限（如 pageSize），用于把子字段的开销乘以元素个数
+	ListSize func(args map[string]interface{}) int
+	// Resolve 为空时按 JSON 标签从父对象读取同名属性
+	Resolve ResolveFunc
+}
+
+// Args 字段参数
+type Args map[string]*Arg
+
+// Arg 参数定义
+type Arg struct {
+	Type        Type // *Scalar 或 *List
+	Description string
+	Default     interface{}
+	Required    bool
+	Enum        []string // 非空时只接受其中的值（字符串或枚举字面量）
+}
+
+// ResolveFunc 字段解析函数
+type ResolveFunc func(p ResolveParams) (interface{}, error)
+
+// ResolveParams 字段解析参数
+type ResolveParams struct {
+	Context context.Context
+	Source  interface{}            // 父对象
+	Args    map[string]interface{} // 已校验并填充默认值的参数
+}
+
+// Schema 查询入口及限制
+type Schema struct {
+	Query *Object
+	// MaxDepth 字段的最大嵌套层数，0 表示不限制
+	MaxDepth int
+	// MaxComplexity 查询的最大开销，0 表示不限制。
+	// 开销 = 各字段的 Cost + ListSize × 子字段的开销
+	MaxComplexity int
+}
+
+// ============================================================================
+// 标量
+// ============================================================================
+
+var (
+	// String 字符串
+	String = &Scalar{Name: "String", Serialize: serializeString, ParseValue: parseString}
+	// ID 标识符，输出为字符串
+	ID = &Scalar{Name: "ID", Serialize: serializeString, ParseValue: parseID}
+	// Int 32 位整数
+	Int = &Scalar{Name: "Int", Serialize: serializeInt, ParseValue: parseInt}
+	// Float 浮点数
+	Float = &Scalar{Name: "Float", Serialize: serializeFloat, ParseValue: parseFloat}
+	// Boolean 布尔值
+	Boolean = &Scalar{Name: "Boolean", Serialize: serializeBoolean, ParseValue: parseBoolean}
+	// DateTime RFC 3339 时间，零值输出为 null
+	DateTime = &Scalar{Name: "DateTime", Serialize: serializeDateTime, ParseValue: parseDateTime}
+	// Date YYYY-MM-DD 日期，解析为本地时间零点
+	Date = &Scalar{Name: "Date", Serialize: serializeDate, ParseValue: parseDate}
+)
+
+func serializeString(v interface{}) (interface{}, bool) {
+	switch s := v.(type) {
+	case string:
+		return s, true
+	case fmt.Stringer:
+		return s.String(), true
+	}
+	if n, ok := toInt64(v); ok {
+		return strconv.FormatInt(n, 10), true
+	}
+	return nil, false
+}
+
+func parseString(v interface{}) (interface{}, bool) {
+	s, ok := v.(string)
+	return s, ok
+}
+
+func parseID(v interface{}) (interface{}, bool) {
+	switch id := v.(type) {
+	case string:
+		return id, true
+	case int:
+		return strconv.Itoa(id), true
+	case float64:
+		if id == math.Trunc(id) {
+			return strconv.FormatInt(int64(id), 10), true
+		}
+	}
+	return nil, false
+}
+
+func serializeInt(v interface{}) (interface{}, bool) {
+	n, ok := toInt64(v)
+	if !ok {
+		return nil, false
+	}
+	// 超出 32 位的值（如文件大小）仍按整数输出，JSON 数字没有位数限制
+	return n, true
+}
+
+func parseInt(v interface{}) (interface{}, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case float64:
+		if n == math.Trunc(n) && n >= math.MinInt32 && n <= math.MaxInt32 {
+			return int(n), true
+		}
+	}
+	return nil, false
+}
+
+func serializeFloat(v interface{}) (interface{}, bool) {
+	switch f := v.(type) {
+	case float64:
+		return f, true
+	case float32:
+		return float64(f), true
+	}
+	if n, ok := toInt64(v); ok {
+		return float64(n), true
+	}
+	return nil, false
+}
+
+func parseFloat(v interface{}) (interface{}, bool) {
+	switch f := v.(type) {
+	case float64:
+		return f, true
+	case int:
+		return float64(f), true
+	}
+	return nil, false
+}
+
+func serializeBoolean(v interface{}) (interface{}, bool) {
+	b, ok := v.(bool)
+	return b, ok
+}
+
+func parseBoolean(v interface{}) (interface{}, bool) {
+	b, ok := v.(bool)
+	return b, ok
+}
+
+func serializeDateTime(v interface{}) (interface{}, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		if t.IsZero() {
+			return nil, true
+		}
+		return t.Format(time.RFC3339), true
+	case *time.Time:
+		if t == nil || t.IsZero() {
+			return nil, true
+		}
+		return t.Format(time.RFC3339), true
+	}
+	return nil, false
+}
+
+func parseDateTime(v interface{}) (interface{}, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, false
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	return t, err == nil
+}
+
+func serializeDate(v interface{}) (interface{}, bool) {
+	if t, ok := v.(time.Time); ok {
+		return t.Format("2006-01-02"), true
+	}
+	return nil, false
+}
+
+func parseDate(v interface{}) (interface{}, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, false
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	return t, err == nil
+}
+
+func toInt64(v interface{}) (int64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint()), true
+	}
+	return 0, false
+}
+
+// ============================================================================
+// 默认解析：按 JSON 标签读取结构体字段，或按键读取 map
+// ============================================================================
+
+var jsonFieldCache sync.Map // reflect.Type -> map[string][]int
+
+func defaultResolve(source interface{}, name string) interface{} {
+	rv := reflect.ValueOf(source)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		v := rv.MapIndex(reflect.ValueOf(name))
+		if !v.IsValid() {
+			return nil
+		}
+		return v.Interface()
+	case reflect.Struct:
+		index, ok := jsonFields(rv.Type())[name]
+		if !ok {
+			return nil
+		}
+		return rv.FieldByIndex(index).Interface()
+	}
+	return nil
+}
+
+func jsonFields(t reflect.Type) map[string][]int {
+	if cached, ok := jsonFieldCache.Load(t); ok {
+		return cached.(map[string][]int)
+	}
+	fields := make(map[string][]int)
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		name := f.Name
+		if tag := f.Tag.Get("json"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			if n, _, _ := strings.Cut(tag, ","); n != "" {
+				name = n
+			}
+		}
+		if _, exists := fields[name]; !exists {
+			fields[name] = f.Index
+		}
+	}
+	jsonFieldCache.Store(t, fields)
+	return fields
+}
@@ -19,6 +19,7 @@ import (
 	"wx_channel/internal/response"
 	"wx_channel/internal/services"
 	"wx_channel/internal/utils"
+	"wx_channel/internal/websocket"
 
 	"github.com/qtgolang/SunnyNet/SunnyNet"
 )
@@ -28,10 +29,14 @@ type BatchHandler struct {
 	downloadService      *services.DownloadRecordService
 	gopeedService        *services.GopeedService // Injected Gopeed Service
 	transcriptionService *services.TranscriptionService
+	thumbnailService     *services.ThumbnailService
+	mediaProbeService    *services.MediaProbeService
 	mu                   sync.RWMutex
 	tasks                []BatchTask
 	running              bool
 	cancelFunc           context.CancelFunc // 用于取消时立即中断下载
+	urlRefresher         *services.VideoURLRefresher
+	formatService        *services.VideoFormatService
 }
 
 // BatchTask 批量下载任务
@@ -44,6 +49,9 @@ type BatchTask struct {
 	Key             string  `json:"key,omitempty"`             // 加密密钥（新方式，后端生成解密数组）
 	DecryptorPrefix string  `json:"decryptorPrefix,omitempty"` // 解密前缀（旧方式，前端传递）
 	PrefixLen       int     `json:"prefixLen,omitempty"`
+	NonceID         string  `json:"nonceId,omitempty"` // objectNonceId，链接过期时用于重新获取
+	Quality         string  `json:"quality,omitempty"` // 画质策略，为空时使用批次或默认策略
+	Format          string  `json:"format,omitempty"`  // 实际选中的编码
 	Status          string  `json:"status"` // pending, downloading, done, failed
 	Error           string  `json:"error,omitempty"`
 	Progress        float64 `json:"progress,omitempty"`
@@ -138,11 +146,15 @@ func (t *BatchTask) GetCover() string {
 }
 
 // NewBatchHandler 创建批量下载处理器
-func NewBatchHandler(cfg *config.Config, gopeedService *services.GopeedService, transcriptionService *services.TranscriptionService) *BatchHandler {
+func NewBatchHandler(cfg *config.Config, wsHub *websocket.Hub, gopeedService *services.GopeedService, transcriptionService *services.TranscriptionService) *BatchHandler {
 	return &BatchHandler{
 		downloadService:      services.NewDownloadRecordService(),
 		gopeedService:        gopeedService,
 		transcriptionService: transcriptionService,
+		thumbnailService:     services.NewThumbnailService(),
+		mediaProbeService:    services.NewMediaProbeService(),
+		urlRefresher:         services.NewVideoURLRefresher(wsHub),
+		formatService:        services.NewVideoFormatService(),
 		tasks:                make([]BatchTask, 0),
 	}
 }
@@ -212,6 +224,7 @@ func (h *BatchHandler) HandleBatchStart(Conn *SunnyNet.HttpConn) bool {
 		Videos          []BatchTask `json:"videos"`
 		ForceRedownload bool        `json:"forceRedownload"`
 		PageSource      string      `json:"pageSource,omitempty"` // 页面来源
+		Quality         string      `json:"quality,omitempty"`    // 整批的画质策略
 	}
 
 	utils.Info("📥 [批量下载] 开始解析 JSON...")
@@ -253,11 +266,25 @@ func (h *BatchHandler) HandleBatchStart(Conn *SunnyNet.HttpConn) bool {
 		h.sendErrorResponse(Conn, fmt.Errorf("视频列表为空"))
 		return true
 	}
+	if _, err := database.ParseQualityPolicy(req.Quality); err != nil {
+		h.sendErrorResponse(Conn, err)
+		return true
+	}
+	for _, v := range req.Videos {
+		if _, err := database.ParseQualityPolicy(v.Quality); err != nil {
+			h.sendErrorResponse(Conn, err)
+			return true
+		}
+	}
 
 	// 初始化任务
 	h.mu.Lock()
 	h.tasks = make([]BatchTask, len(req.Videos))
 	for i, v := range req.Videos {
+		quality := v.Quality
+		if quality == "" {
+			quality = req.Quality
+		}
 		h.tasks[i] = BatchTask{
 			ID:              v.ID,
 			URL:             v.URL,
@@ -267,6 +294,8 @@ func (h *BatchHandler) HandleBatchStart(Conn *SunnyNet.HttpConn) bool {
 			Key:             v.Key,
 			DecryptorPrefix: v.DecryptorPrefix,
 			PrefixLen:       v.PrefixLen,
+			NonceID:         v.NonceID,
+			Quality:         quality,
 			Status:          "pending",
 			// 保留额外字段
 			Duration:     v.Duration,
@@ -356,6 +385,7 @@ func (h *BatchHandler) startBatchDownload(forceRedownload bool) {
 				h.mu.Lock()
 				task := &h.tasks[taskIdx]
 				task.Status = "downloading"
+				h.publishProgressLocked(task)
 				h.mu.Unlock()
 
 				utils.Info("📥 [Worker %d] 开始下载: %s", workerID, task.Title)
@@ -364,17 +394,28 @@ func (h *BatchHandler) startBatchDownload(forceRedownload bool) {
 				err := h.downloadVideo(ctx, task, downloadsDir, forceRedownload, taskIdx)
 
 				h.mu.Lock()
+				webhookEvent := database.WebhookEventDownloadCompleted
 				if err != nil {
 					task.Status = "failed"
 					task.Error = err.Error()
 					task.Progress = 0
+					webhookEvent = database.WebhookEventDownloadFailed
 					utils.Error("❌ [Worker %d] 失败: %s - %v", workerID, task.Title, err)
 				} else {
 					task.Status = "done"
 					task.Progress = 100
 					utils.Info("✅ [Worker %d] 完成: %s", workerID, task.Title)
 				}
+				h.publishProgressLocked(task)
+				webhookData := services.WebhookDownloadData{
+					Source:  "batch",
+					VideoID: task.ID,
+					Title:   task.Title,
+					Author:  task.GetAuthor(),
+					Error:   task.Error,
+				}
 				h.mu.Unlock()
+				services.GetWebhookService().Dispatch(webhookEvent, webhookData)
 			}
 		}(w)
 	}
@@ -396,6 +437,7 @@ func (h *BatchHandler) startBatchDownload(forceRedownload bool) {
 			close(taskChan)
 			wg.Wait()
 			utils.Info("⏹️ [批量下载] 已取消")
+			h.notifyFinished(true)
 			return
 		case taskChan <- i:
 			pendingCount++
@@ -413,18 +455,61 @@ func (h *BatchHandler) startBatchDownload(forceRedownload bool) {
 	wg.Wait()
 
 	// 统计结果
+	data := h.notifyFinished(ctx.Err() != nil)
+	utils.Info("✅ [批量下载] 全部完成！成功: %d, 失败: %d", data.Done, data.Failed)
+}
+
+// notifyFinished 统计本批任务的结果并推送 batch.finished Webhook
+func (h *BatchHandler) notifyFinished(cancelled bool) services.WebhookBatchData {
 	h.mu.RLock()
-	done, failed := 0, 0
+	data := services.WebhookBatchData{Total: len(h.tasks), Cancelled: cancelled}
 	for _, t := range h.tasks {
 		if t.Status == "done" {
-			done++
+			data.Done++
 		} else if t.Status == "failed" {
-			failed++
+			data.Failed++
 		}
 	}
 	h.mu.RUnlock()
 
-	utils.Info("✅ [批量下载] 全部完成！成功: %d, 失败: %d", done, failed)
+	services.GetWebhookService().Dispatch(database.WebhookEventBatchFinished, data)
+	return data
+}
+
+// BatchProgressEvent 批量下载任务状态或进度变化事件
+type BatchProgressEvent struct {
+	Type   string                 `json:"type"`
+	Total  int                    `json:"total"`
+	Done   int                    `json:"done"`
+	Failed int                    `json:"failed"`
+	Task   map[string]interface{} `json:"task"`
+}
+
+// publishProgressLocked 发布 batch_progress 事件，调用方需持有 h.mu
+func (h *BatchHandler) publishProgressLocked(task *BatchTask) {
+	event := BatchProgressEvent{
+		Type:  services.EventBatchProgress,
+		Total: len(h.tasks),
+		Task: map[string]interface{}{
+			"id":           task.ID,
+			"title":        task.Title,
+			"authorName":   task.GetAuthor(),
+			"status":       task.Status,
+			"progress":     task.Progress,
+			"downloadedMB": task.DownloadedMB,
+			"totalMB":      task.TotalMB,
+			"error":        task.Error,
+		},
+	}
+	for _, t := range h.tasks {
+		switch t.Status {
+		case "done":
+			event.Done++
+		case "failed":
+			event.Failed++
+		}
+	}
+	services.GetEventBus().Publish(services.EventBatchProgress, event)
 }
 
 // downloadVideo 下载单个视频（带重试和断点续传）
@@ -480,6 +565,14 @@ func (h *BatchHandler) downloadVideo(ctx context.Context, task *BatchTask, downl
 	}
 	var lastErr error
 
+	h.applyTaskQuality(task)
+
+	// 链接中的过期时间已过时，下载前先刷新链接
+	refreshed := false
+	if services.VideoURLExpired(task.URL, time.Now()) {
+		refreshed = h.refreshTaskURL(task)
+	}
+
 	for retry := 0; retry < maxRetries; retry++ {
 		// 检查是否取消
 		select {
@@ -515,6 +608,16 @@ func (h *BatchHandler) downloadVideo(ctx context.Context, task *BatchTask, downl
 			// 下载成功，保存到下载记录数据库
 			h.saveDownloadRecord(task, filePath, "completed")
 
+			// 探测真实媒体信息
+			if h.mediaProbeService != nil {
+				h.mediaProbeService.ProbeRecordAsync(task.ID)
+			}
+
+			// 生成本地缩略图
+			if h.thumbnailService != nil {
+				h.thumbnailService.GenerateAsync(task.ID)
+			}
+
 			// 自动语音转文字
 			if h.transcriptionService != nil && h.transcriptionService.IsAutoRunEnabled() {
 				h.transcriptionService.TranscribeAsync(task.ID)
@@ -532,6 +635,15 @@ func (h *BatchHandler) downloadVideo(ctx context.Context, task *BatchTask, downl
 		if task.DecryptorPrefix != "" || !resumeEnabled {
 			os.Remove(filePath + ".tmp")
 		}
+
+		// 链接过期（403/404）时重新获取链接后立即重试，不计入重试次数
+		if !refreshed && ctx.Err() == nil && h.urlRefresher != nil && h.urlRefresher.Expired(ctx, task.URL) {
+			refreshed = true
+			if h.refreshTaskURL(task) {
+				os.Remove(filePath + ".tmp")
+				retry--
+			}
+		}
 	}
 
 	// 记录最终失败的详细错误
@@ -539,6 +651,53 @@ func (h *BatchHandler) downloadVideo(ctx context.Context, task *BatchTask, downl
 	return fmt.Errorf("下载失败（已重试 %d 次）: %v", maxRetries, lastErr)
 }
 
+// applyTaskQuality 按画质策略选择编码并更新任务的下载链接
+func (h *BatchHandler) applyTaskQuality(task *BatchTask) {
+	if h.formatService == nil || task.ID == "" {
+		return
+	}
+	videoURL, format, err := h.formatService.Resolve(task.ID, task.URL, task.Quality)
+	if err != nil {
+		utils.Warn("⚠️ [批量下载] 选择画质失败: %s - %v", task.Title, err)
+		return
+	}
+	if format == nil {
+		return
+	}
+
+	h.mu.Lock()
+	task.URL = videoURL
+	task.Format = format.FileFormat
+	if format.Width > 0 && format.Height > 0 {
+		task.Resolution = fmt.Sprintf("%dx%d", format.Width, format.Height)
+	}
+	h.mu.Unlock()
+	utils.Info("🎞️ [批量下载] 使用编码 %s: %s", format.FileFormat, task.Title)
+}
+
+// refreshTaskURL 通过视频详情重新获取任务的下载链接和密钥，成功时返回 true
+func (h *BatchHandler) refreshTaskURL(task *BatchTask) bool {
+	if h.urlRefresher == nil || task.ID == "" {
+		return false
+	}
+	h.mu.RLock()
+	nonceID, staleURL, staleKey := task.NonceID, task.URL, task.GetKey()
+	h.mu.RUnlock()
+
+	videoURL, decryptKey, err := h.urlRefresher.Refresh(task.ID, nonceID, staleURL, staleKey)
+	if err != nil {
+		utils.Warn("⚠️ [批量下载] 刷新过期链接失败: %s - %v", task.Title, err)
+		return false
+	}
+
+	h.mu.Lock()
+	task.URL = videoURL
+	task.Key = decryptKey
+	h.mu.Unlock()
+	utils.Info("🔄 [批量下载] 已刷新过期链接: %s", task.Title)
+	return true
+}
+
 // downloadVideoOnce 执行一次下载尝试（支持断点续传）
 func (h *BatchHandler) downloadVideoOnce(ctx context.Context, task *BatchTask, filePath string, taskIdx int) error {
 	// 使用 Gopeed 下载
@@ -572,6 +731,7 @@ func (h *BatchHandler) downloadVideoOnce(ctx context.Context, task *BatchTask, f
 				task.Progress = progress * 100 // 转换为百分比
 				task.DownloadedMB = float64(downloaded) / (1024 * 1024)
 				task.TotalMB = float64(total) / (1024 * 1024)
+				h.publishProgressLocked(task)
 				// 也可以根据需要计算 SizeMB 字符串
 				if total > 0 {
 					task.SizeMB = fmt.Sprintf("%.2fMB", task.TotalMB)
@@ -10,6 +10,8 @@ import (
 	"time"
 
 	"wx_channel/internal/config"
+	"wx_channel/internal/database"
+	"wx_channel/internal/services"
 	"wx_channel/internal/utils"
 
 	"github.com/qtgolang/SunnyNet/SunnyNet"
@@ -17,11 +19,14 @@ import (
 
 // CommentHandler 评论数据处理器
 type CommentHandler struct {
+	commentService *services.CommentService
 }
 
 // NewCommentHandler 创建评论处理器
 func NewCommentHandler(cfg *config.Config) *CommentHandler {
-	return &CommentHandler{}
+	return &CommentHandler{
+		commentService: services.NewCommentService(),
+	}
 }
 
 // getConfig 获取当前配置（动态获取最新配置）
@@ -85,6 +90,7 @@ func (h *CommentHandler) HandleSaveCommentData(Conn *SunnyNet.HttpConn) bool {
 		VideoTitle           string                   `json:"videoTitle"`
 		OriginalCommentCount int                      `json:"originalCommentCount"`
 		Timestamp            int64                    `json:"timestamp"`
+		IsFullUpdate         bool                     `json:"isFullUpdate"`
 	}
 
 	body, err := io.ReadAll(Conn.Request.Body)
@@ -111,8 +117,11 @@ func (h *CommentHandler) HandleSaveCommentData(Conn *SunnyNet.HttpConn) bool {
 		return true
 	}
 
+	// 写入评论数据库，便于按视频查询楼层、全文搜索和批次对比
+	run := h.ingestComments(requestData.Comments, requestData.VideoID, requestData.VideoTitle, requestData.OriginalCommentCount, requestData.Timestamp, requestData.IsFullUpdate)
+
 	// 保存评论数据
-	if err := h.saveCommentData(requestData.Comments, requestData.VideoID, requestData.VideoTitle, requestData.OriginalCommentCount, requestData.Timestamp); err != nil {
+	if err := h.saveCommentData(requestData.Comments, requestData.VideoID, requestData.VideoTitle, requestData.OriginalCommentCount, requestData.Timestamp, run); err != nil {
 		utils.HandleError(err, "保存评论数据")
 		h.sendErrorResponse(Conn, err)
 		return true
@@ -122,8 +131,9 @@ func (h *CommentHandler) HandleSaveCommentData(Conn *SunnyNet.HttpConn) bool {
 	return true
 }
 
-// saveCommentData 保存评论数据到文件
-func (h *CommentHandler) saveCommentData(comments []map[string]interface{}, videoID, videoTitle string, originalCommentCount int, timestamp int64) error {
+// saveCommentData 保存评论数据到文件。
+// run 不为空时按采集批次命名，同一批次内的多次上报覆盖同一个快照文件。
+func (h *CommentHandler) saveCommentData(comments []map[string]interface{}, videoID, videoTitle string, originalCommentCount int, timestamp int64, run *database.CommentRun) error {
 	if len(comments) == 0 {
 		return nil
 	}
@@ -143,7 +153,9 @@ func (h *CommentHandler) saveCommentData(comments []map[string]interface{}, vide
 
 	// 按日期组织目录
 	saveTime := time.Now()
-	if timestamp > 0 {
+	if run != nil {
+		saveTime = run.StartedAt
+	} else if timestamp > 0 {
 		saveTime = time.Unix(0, timestamp*int64(time.Millisecond))
 	}
 
@@ -169,7 +181,12 @@ func (h *CommentHandler) saveCommentData(comments []map[string]interface{}, vide
 			saveTime.Format("20060102_150405"))
 	}
 
-	targetPath := utils.GenerateUniqueFilename(dateDir, fileName, 100)
+	var targetPath string
+	if run != nil {
+		targetPath = filepath.Join(dateDir, fileName)
+	} else {
+		targetPath = utils.GenerateUniqueFilename(dateDir, fileName, 100)
+	}
 
 	// 计算实际总评论数（一级 + 二级）
 	totalComments := len(comments)
@@ -189,6 +206,9 @@ func (h *CommentHandler) saveCommentData(comments []map[string]interface{}, vide
 		"saved_at":             saveTime.Format(time.RFC3339),
 		"timestamp":            timestamp,
 	}
+	if run != nil {
+		commentData["runId"] = run.ID
+	}
 
 	// 保存JSON数据
 	dataBytes, err := json.MarshalIndent(commentData, "", "  ")
@@ -219,6 +239,27 @@ func (h *CommentHandler) saveCommentData(comments []map[string]interface{}, vide
 	return nil
 }
 
+// ingestComments 将评论写入数据库并返回所属采集批次（失败只记录日志并返回 nil，不影响 JSON 文件的保存）
+func (h *CommentHandler) ingestComments(comments []map[string]interface{}, videoID, videoTitle string, originalCommentCount int, timestamp int64, fullUpdate bool) *database.CommentRun {
+	if len(comments) == 0 || videoID == "" || h.commentService == nil {
+		return nil
+	}
+
+	collectedAt := time.Now()
+	if timestamp > 0 {
+		collectedAt = time.UnixMilli(timestamp)
+	}
+
+	run, err := h.commentService.Ingest(videoID, videoTitle, originalCommentCount, comments, collectedAt, fullUpdate)
+	if err != nil {
+		utils.Warn("评论写入数据库失败 [%s]: %v", videoID, err)
+		return nil
+	}
+	utils.LogInfo("[评论入库] 视频=%s | 批次=%d | 总数=%d | 新增=%d | 修改=%d | 消失=%d",
+		videoID, run.ID, run.TotalCount, run.NewCount, run.ChangedCount, run.DeletedCount)
+	return run
+}
+
 // sendEmptyResponse 发送空JSON响应
 func (h *CommentHandler) sendEmptyResponse(Conn *SunnyNet.HttpConn) {
 	headers := http.Header{}
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -26,6 +28,7 @@ import (
 // ConsoleAPIHandler 处理 Web 控制台的 REST API 请求
 type ConsoleAPIHandler struct {
 	browseService        *services.BrowseHistoryService
+	formatService        *services.VideoFormatService
 	downloadService      *services.DownloadRecordService
 	queueService         *services.QueueService
 	settingsRepo         *database.SettingsRepository
@@ -33,6 +36,19 @@ type ConsoleAPIHandler struct {
 	exportService        *services.ExportService
 	searchService        *services.SearchService
 	transcriptionService *services.TranscriptionService
+	thumbnailService     *services.ThumbnailService
+	mediaProbeService    *services.MediaProbeService
+	commentService       *services.CommentService
+	scriptPatchService   *services.ScriptPatchService
+	crawlService         *services.CrawlService
+	watchService         *services.WatchService
+	tokenService         *services.APITokenService
+	userService          *services.UserService
+	auditService         *services.AuditService
+	trashService         *services.TrashService
+	eventBus             *services.EventBus
+	webhookService       *services.WebhookService
+	graphqlService       *services.GraphQLService
 	wsHub                *websocket.Hub
 }
 
@@ -40,8 +56,23 @@ const maxJSONBodyBytes = 8 << 20 // 8MB
 
 // NewConsoleAPIHandler 创建一个新的 ConsoleAPIHandler
 func NewConsoleAPIHandler(cfg *config.Config, wsHub *websocket.Hub) *ConsoleAPIHandler {
+	// 抓取任务加入下载队列时同样广播队列变更
+	crawlService := services.GetCrawlService(wsHub)
+	crawlService.SetEnqueuedHandler(func(items []database.QueueItem) {
+		hub := GetWebSocketHub()
+		for i := range items {
+			hub.BroadcastQueueAdd(&items[i])
+		}
+	})
+
+	watchService := services.GetWatchService(wsHub)
+	watchService.SetNewVideosHandler(func(event services.WatchEvent) {
+		GetWebSocketHub().BroadcastWatchNewVideos(event)
+	})
+
 	return &ConsoleAPIHandler{
 		browseService:        services.NewBrowseHistoryService(),
+		formatService:        services.NewVideoFormatService(),
 		downloadService:      services.NewDownloadRecordService(),
 		queueService:         services.NewQueueService(),
 		settingsRepo:         database.NewSettingsRepository(),
@@ -49,6 +80,19 @@ func NewConsoleAPIHandler(cfg *config.Config, wsHub *websocket.Hub) *ConsoleAPIH
 		exportService:        services.NewExportService(),
 		searchService:        services.NewSearchService(),
 		transcriptionService: services.NewTranscriptionService(),
+		thumbnailService:     services.NewThumbnailService(),
+		mediaProbeService:    services.NewMediaProbeService(),
+		commentService:       services.NewCommentService(),
+		scriptPatchService:   services.GetScriptPatchService(cfg),
+		crawlService:         crawlService,
+		watchService:         watchService,
+		tokenService:         services.NewAPITokenService(),
+		userService:          services.NewUserService(),
+		auditService:         services.NewAuditService(),
+		trashService:         services.GetTrashService(),
+		eventBus:             services.GetEventBus(),
+		webhookService:       services.GetWebhookService(),
+		graphqlService:       services.NewGraphQLService(),
 		wsHub:                wsHub,
 	}
 }
@@ -58,6 +102,16 @@ func (h *ConsoleAPIHandler) getConfig() *config.Config {
 	return config.Get()
 }
 
+// TokenService 返回处理器使用的令牌服务，供认证中间件共享同一实例（存在性缓存一致）
+func (h *ConsoleAPIHandler) TokenService() *services.APITokenService {
+	return h.tokenService
+}
+
+// UserService 返回处理器使用的用户服务，供认证中间件共享同一实例（登录锁定状态一致）
+func (h *ConsoleAPIHandler) UserService() *services.UserService {
+	return h.userService
+}
+
 // APIResponse 表示标准 API 响应
 type APIResponse struct {
 	Success bool        `json:"success"`
@@ -118,7 +172,7 @@ func (h *ConsoleAPIHandler) setCORSHeaders(w http.ResponseWriter, r *http.Reques
 		w.Header().Set("Vary", "Origin")
 	}
 	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Local-Auth, Authorization")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Local-Auth, Authorization, X-CSRF-Token")
 	w.Header().Set("Access-Control-Max-Age", "86400")
 }
 
@@ -199,6 +253,18 @@ func getFilterParams(r *http.Request) *database.FilterParams {
 	if query := r.URL.Query().Get("query"); query != "" {
 		params.Query = query
 	}
+	if minRes := r.URL.Query().Get("minResolution"); minRes != "" {
+		// 支持 "1080" 或 "1080p"
+		if v, err := strconv.Atoi(strings.TrimSuffix(strings.ToLower(minRes), "p")); err == nil && v > 0 {
+			params.MinResolution = v
+		}
+	}
+	if codec := r.URL.Query().Get("videoCodec"); codec != "" {
+		params.VideoCodec = codec
+	}
+	if initiatedBy := r.URL.Query().Get("initiatedBy"); initiatedBy != "" {
+		params.InitiatedBy = initiatedBy
+	}
 
 	return params
 }
@@ -264,6 +330,36 @@ func (h *ConsoleAPIHandler) HandleBrowseGet(w http.ResponseWriter, r *http.Reque
 	h.sendSuccess(w, r, record)
 }
 
+// HandleBrowseFormats 处理 GET /api/browse/:id/formats - 可选编码及按画质策略选中的编码
+func (h *ConsoleAPIHandler) HandleBrowseFormats(w http.ResponseWriter, r *http.Request, id string) {
+	if h.HandleCORS(w, r) {
+		return
+	}
+
+	formats, err := h.formatService.List(id)
+	if err != nil {
+		h.sendError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	quality := r.URL.Query().Get("quality")
+	if quality == "" {
+		quality = h.formatService.DefaultQuality()
+	}
+	policy, err := database.ParseQualityPolicy(quality)
+	if err != nil {
+		h.sendError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.sendSuccess(w, r, map[string]interface{}{
+		"videoId":  id,
+		"formats":  formats,
+		"quality":  quality,
+		"selected": services.SelectVideoFormat(formats, policy),
+	})
+}
+
 // HandleBrowseDelete 处理 DELETE /api/browse/:id - 删除单条记录
 func (h *ConsoleAPIHandler) HandleBrowseDelete(w http.ResponseWriter, r *http.Request, id string) {
 	if h.HandleCORS(w, r) {
@@ -275,6 +371,11 @@ func (h *ConsoleAPIHandler) HandleBrowseDelete(w http.ResponseWriter, r *http.Re
 		h.sendError(w, r, http.StatusInternalServerError, err.Error())
 		return
 	}
+	h.auditService.RecordRequest(r, &database.AuditEntry{
+		Action:     "browse.delete",
+		TargetType: "browse_record",
+		TargetIDs:  []string{id},
+	})
 
 	h.sendSuccessMessage(w, r, "record deleted")
 }
@@ -303,6 +404,12 @@ func (h *ConsoleAPIHandler) HandleBrowseDeleteMany(w http.ResponseWriter, r *htt
 		h.sendError(w, r, http.StatusInternalServerError, err.Error())
 		return
 	}
+	h.auditService.RecordRequest(r, &database.AuditEntry{
+		Action:     "browse.delete",
+		TargetType: "browse_record",
+		TargetIDs:  req.IDs,
+		Detail:     fmt.Sprintf("deleted=%d", count),
+	})
 
 	h.sendSuccess(w, r, map[string]interface{}{
 		"deleted": count,
@@ -320,6 +427,10 @@ func (h *ConsoleAPIHandler) HandleBrowseClear(w http.ResponseWriter, r *http.Req
 		h.sendError(w, r, http.StatusInternalServerError, err.Error())
 		return
 	}
+	h.auditService.RecordRequest(r, &database.AuditEntry{
+		Action:     "browse.clear",
+		TargetType: "browse_record",
+	})
 
 	h.sendSuccessMessage(w, r, "all browse records cleared")
 }
@@ -344,7 +455,9 @@ func (h *ConsoleAPIHandler) HandleBrowseAPI(w http.ResponseWriter, r *http.Reque
 
 	switch r.Method {
 	case "GET":
-		if id != "" {
+		if id != "" && strings.HasSuffix(path, "/formats") {
+			h.HandleBrowseFormats(w, r, id)
+		} else if id != "" {
 			h.HandleBrowseGet(w, r, id)
 		} else {
 			h.HandleBrowseList(w, r)
@@ -400,6 +513,26 @@ func (h *ConsoleAPIHandler) HandleDownloadsGet(w http.ResponseWriter, r *http.Re
 	h.sendSuccess(w, r, record)
 }
 
+// HandleDownloadsProbe 处理 POST /api/downloads/:id/probe - 重新探测媒体信息
+func (h *ConsoleAPIHandler) HandleDownloadsProbe(w http.ResponseWriter, r *http.Request, id string) {
+	if h.HandleCORS(w, r) {
+		return
+	}
+
+	if !h.mediaProbeService.IsAvailable() {
+		h.sendError(w, r, http.StatusServiceUnavailable, "ffprobe not available")
+		return
+	}
+
+	record, err := h.mediaProbeService.ProbeRecord(r.Context(), id)
+	if err != nil {
+		h.sendError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.sendSuccess(w, r, record)
+}
+
 // HandleDownloadsDelete 处理 DELETE /api/downloads/:id - 删除单条记录
 func (h *ConsoleAPIHandler) HandleDownloadsDelete(w http.ResponseWriter, r *http.Request, id string) {
 	if h.HandleCORS(w, r) {
@@ -414,6 +547,12 @@ func (h *ConsoleAPIHandler) HandleDownloadsDelete(w http.ResponseWriter, r *http
 		h.sendError(w, r, http.StatusInternalServerError, err.Error())
 		return
 	}
+	h.auditService.RecordRequest(r, &database.AuditEntry{
+		Action:     "downloads.delete",
+		TargetType: "download_record",
+		TargetIDs:  []string{id},
+		Detail:     fmt.Sprintf("deleteFiles=%t", deleteFiles),
+	})
 
 	h.sendSuccessMessage(w, r, "record deleted")
 }
@@ -443,12 +582,38 @@ func (h *ConsoleAPIHandler) HandleDownloadsDeleteMany(w http.ResponseWriter, r *
 		h.sendError(w, r, http.StatusInternalServerError, err.Error())
 		return
 	}
+	h.auditService.RecordRequest(r, &database.AuditEntry{
+		Action:     "downloads.delete",
+		TargetType: "download_record",
+		TargetIDs:  req.IDs,
+		Detail:     fmt.Sprintf("deleted=%d deleteFiles=%t", count, req.DeleteFiles),
+	})
 
 	h.sendSuccess(w, r, map[string]interface{}{
 		"deleted": count,
 	})
 }
 
+// HandleDownloadsClear 处理 DELETE /api/downloads/clear?deleteFiles=true - 清空所有记录
+func (h *ConsoleAPIHandler) HandleDownloadsClear(w http.ResponseWriter, r *http.Request) {
+	if h.HandleCORS(w, r) {
+		return
+	}
+
+	deleteFiles := r.URL.Query().Get("deleteFiles") == "true"
+	if err := h.downloadService.Clear(deleteFiles); err != nil {
+		h.sendError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.auditService.RecordRequest(r, &database.AuditEntry{
+		Action:     "downloads.clear",
+		TargetType: "download_record",
+		Detail:     fmt.Sprintf("deleteFiles=%t", deleteFiles),
+	})
+
+	h.sendSuccessMessage(w, r, "all download records cleared")
+}
+
 // HandleDownloadsAPI 路由下载记录 API 请求
 func (h *ConsoleAPIHandler) HandleDownloadsAPI(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
@@ -458,6 +623,12 @@ func (h *ConsoleAPIHandler) HandleDownloadsAPI(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	// DELETE /api/downloads/clear - 必须在提取 ID 之前检查
+	if path == "/api/downloads/clear" && r.Method == "DELETE" {
+		h.HandleDownloadsClear(w, r)
+		return
+	}
+
 	// 从路径提取 ID
 	id := extractIDFromPath(path, "/api/downloads")
 
@@ -474,6 +645,12 @@ func (h *ConsoleAPIHandler) HandleDownloadsAPI(w http.ResponseWriter, r *http.Re
 		} else {
 			h.HandleDownloadsDeleteMany(w, r)
 		}
+	case "POST":
+		if id != "" && strings.HasSuffix(path, "/probe") {
+			h.HandleDownloadsProbe(w, r, id)
+		} else {
+			h.sendError(w, r, http.StatusNotFound, "not found")
+		}
 	default:
 		h.sendError(w, r, http.StatusMethodNotAllowed, "method not allowed")
 	}
@@ -517,8 +694,14 @@ func (h *ConsoleAPIHandler) HandleQueueAdd(w http.ResponseWriter, r *http.Reques
 		h.sendError(w, r, http.StatusBadRequest, "no videos provided")
 		return
 	}
+	for _, v := range req.Videos {
+		if _, err := database.ParseQualityPolicy(v.Quality); err != nil {
+			h.sendError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
 
-	items, err := h.queueService.AddToQueue(req.Videos)
+	items, err := h.queueService.AddToQueueAs(services.InitiatedBy(r.Context()), req.Videos)
 	if err != nil {
 		h.sendError(w, r, http.StatusInternalServerError, err.Error())
 		return
@@ -583,10 +766,19 @@ func (h *ConsoleAPIHandler) HandleQueueRemove(w http.ResponseWriter, r *http.Req
 	}
 
 	err := h.queueService.RemoveFromQueue(id)
+	if errors.Is(err, services.ErrQueueItemNotFound) {
+		h.sendError(w, r, http.StatusNotFound, err.Error())
+		return
+	}
 	if err != nil {
 		h.sendError(w, r, http.StatusInternalServerError, err.Error())
 		return
 	}
+	h.auditService.RecordRequest(r, &database.AuditEntry{
+		Action:     "queue.remove",
+		TargetType: "queue_item",
+		TargetIDs:  []string{id},
+	})
 
 	// 通过 WebSocket 广播队列移除
 	GetWebSocketHub().BroadcastQueueRemove(id)
@@ -773,6 +965,13 @@ func (h *ConsoleAPIHandler) HandleSettingsUpdate(w http.ResponseWriter, r *http.
 		return
 	}
 
+	// 记录修改前的值用于审计
+	previous, err := h.settingsRepo.Load()
+	if err != nil {
+		h.sendError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
 	// 验证并保存设置
 	// Requirements: 11.3, 11.4 - 验证分片大小 (1-100MB) 和并发限制 (1-5)
 	if err := h.settingsRepo.SaveAndValidate(&settings); err != nil {
@@ -780,6 +979,15 @@ func (h *ConsoleAPIHandler) HandleSettingsUpdate(w http.ResponseWriter, r *http.
 		return
 	}
 
+	if before, after := services.AuditChanges(previous, &settings); after != nil {
+		h.auditService.RecordRequest(r, &database.AuditEntry{
+			Action:     "settings.update",
+			TargetType: "settings",
+			Before:     before,
+			After:      after,
+		})
+	}
+
 	h.sendSuccessMessage(w, r, "settings updated")
 }
 
@@ -1137,6 +1345,23 @@ func (h *ConsoleAPIHandler) HandleVerifyToken(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	// 命名 API 令牌：返回名称和权限范围，控制台据此使用该令牌访问 API
+	if token, err := h.tokenService.Authenticate(req.Token); err == nil {
+		h.sendSuccess(w, r, map[string]interface{}{
+			"valid":   true,
+			"message": "token verified",
+			"name":    token.Name,
+			"scopes":  token.Scopes,
+		})
+		return
+	}
+
+	// 创建过用户后控制台必须以用户身份登录，共享的 web_console_token 不再有效
+	if h.userService.Enabled() {
+		h.sendError(w, r, http.StatusUnauthorized, "user login required")
+		return
+	}
+
 	cfg := h.getConfig()
 	// 如果未配置 token，则允许访问
 	if cfg == nil || cfg.WebConsoleToken == "" {
@@ -1148,7 +1373,7 @@ func (h *ConsoleAPIHandler) HandleVerifyToken(w http.ResponseWriter, r *http.Req
 	}
 
 	// 验证 token
-	if req.Token == cfg.WebConsoleToken {
+	if subtle.ConstantTimeCompare([]byte(req.Token), []byte(cfg.WebConsoleToken)) == 1 {
 		h.sendSuccess(w, r, map[string]interface{}{
 			"valid":   true,
 			"message": "token verified",
@@ -1358,7 +1583,7 @@ func (h *ConsoleAPIHandler) HandleVideoStream(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	if r.Method != "GET" {
+	if r.Method != "GET" && r.Method != "HEAD" {
 		h.sendError(w, r, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
@@ -1419,57 +1644,10 @@ func (h *ConsoleAPIHandler) HandleVideoStream(w http.ResponseWriter, r *http.Req
 	// 设置 CORS 头
 	h.setCORSHeaders(w, r)
 
-	// 处理视频跳转的范围请求
-	fileSize := fileInfo.Size()
-	rangeHeader := r.Header.Get("Range")
-
-	if rangeHeader != "" {
-		// 解析范围头
-		var start, end int64
-		_, err := fmt.Sscanf(rangeHeader, "bytes=%d-%d", &start, &end)
-		if err != nil {
-			// 尝试不带结束位置解析
-			_, err = fmt.Sscanf(rangeHeader, "bytes=%d-", &start)
-			if err != nil {
-				h.sendError(w, r, http.StatusBadRequest, "invalid range header")
-				return
-			}
-			end = fileSize - 1
-		}
-
-		// 验证范围
-		if start < 0 || start >= fileSize || end >= fileSize || start > end {
-			w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", fileSize))
-			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
-			return
-		}
-
-		// 跳转到起始位置
-		_, err = file.Seek(start, 0)
-		if err != nil {
-			h.sendError(w, r, http.StatusInternalServerError, "failed to seek file")
-			return
-		}
-
-		// 设置部分内容的响应头
-		w.Header().Set("Content-Type", contentType)
-		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, fileSize))
-		w.Header().Set("Content-Length", fmt.Sprintf("%d", end-start+1))
-		w.Header().Set("Accept-Ranges", "bytes")
-		w.WriteHeader(http.StatusPartialContent)
-
-		// 复制请求的范围
-		io.CopyN(w, file, end-start+1)
-	} else {
-		// 完整文件请求
-		w.Header().Set("Content-Type", contentType)
-		w.Header().Set("Content-Length", fmt.Sprintf("%d", fileSize))
-		w.Header().Set("Accept-Ranges", "bytes")
-		w.WriteHeader(http.StatusOK)
-
-		// 复制整个文件
-		io.Copy(w, file)
-	}
+	// 范围请求（视频跳转）、HEAD 和条件请求由 http.ServeContent 处理，
+	// 响应体按需从文件读取，经代理端口时以流式返回
+	w.Header().Set("Content-Type", contentType)
+	http.ServeContent(w, r, fileInfo.Name(), fileInfo.ModTime(), file)
 }
 
 // isPathWithinBase returns true when targetPath is within baseDir.
@@ -1815,3 +1993,948 @@ func (h *ConsoleAPIHandler) handleOpenTranscript(w http.ResponseWriter, r *http.
 
 	h.sendSuccessMessage(w, r, "已打开转写文件")
 }
+
+// ============================================================================
+// 缩略图 API 处理器
+// ============================================================================
+
+// thumbnailCacheMaxAge 缩略图缓存时间（7 天）
+const thumbnailCacheMaxAge = 7 * 24 * 60 * 60
+
+// HandleThumbnailsAPI 路由缩略图 API 请求
+//   - GET  /api/thumbnails/:id           封面（缺失时按需生成）
+//   - GET  /api/thumbnails/:id/list      已缓存的缩略图列表
+//   - GET  /api/thumbnails/:id/sheet     场景切换拼图
+//   - GET  /api/thumbnails/:id/:name     关键帧，例如 keyframe_01.jpg
+//   - POST /api/thumbnails/:id           重新生成
+func (h *ConsoleAPIHandler) HandleThumbnailsAPI(w http.ResponseWriter, r *http.Request) {
+	if h.HandleCORS(w, r) {
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/thumbnails")
+	path = strings.TrimPrefix(path, "/")
+	parts := strings.SplitN(path, "/", 2)
+	if len(parts) == 0 || parts[0] == "" {
+		h.sendError(w, r, http.StatusBadRequest, "record ID required")
+		return
+	}
+
+	id := parts[0]
+	name := ""
+	if len(parts) > 1 {
+		name = parts[1]
+	}
+
+	switch {
+	case r.Method == "POST" && name == "":
+		h.handleThumbnailGenerate(w, r, id)
+	case r.Method == "GET" && name == "list":
+		h.handleThumbnailList(w, r, id)
+	case r.Method == "GET" && name == "":
+		h.serveThumbnail(w, r, id, services.ThumbnailCoverFile)
+	case r.Method == "GET" && name == "sheet":
+		h.serveThumbnail(w, r, id, services.ThumbnailSheetFile)
+	case r.Method == "GET":
+		h.serveThumbnail(w, r, id, name)
+	default:
+		h.sendError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleThumbnailGenerate 重新生成缩略图
+func (h *ConsoleAPIHandler) handleThumbnailGenerate(w http.ResponseWriter, r *http.Request, id string) {
+	set, err := h.thumbnailService.Generate(r.Context(), id)
+	if err != nil {
+		h.sendError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.sendSuccess(w, r, set)
+}
+
+// handleThumbnailList 返回已缓存的缩略图列表
+func (h *ConsoleAPIHandler) handleThumbnailList(w http.ResponseWriter, r *http.Request, id string) {
+	set, err := h.thumbnailService.List(id)
+	if err != nil {
+		h.sendError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.sendSuccess(w, r, set)
+}
+
+// serveThumbnail 输出缩略图文件，带缓存头（private，需要认证的内容不进入共享缓存）并支持条件请求。
+// 封面不存在时同步生成一次，以便旧的下载记录也能离线显示。
+func (h *ConsoleAPIHandler) serveThumbnail(w http.ResponseWriter, r *http.Request, id, name string) {
+	thumbPath, err := h.thumbnailService.GetPath(id, name)
+	if os.IsNotExist(err) && name == services.ThumbnailCoverFile {
+		if _, genErr := h.thumbnailService.Generate(r.Context(), id); genErr != nil {
+			h.sendError(w, r, http.StatusNotFound, genErr.Error())
+			return
+		}
+		thumbPath, err = h.thumbnailService.GetPath(id, name)
+	}
+	if os.IsNotExist(err) {
+		h.sendError(w, r, http.StatusNotFound, "thumbnail not found")
+		return
+	}
+	if err != nil {
+		h.sendError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	file, err := os.Open(thumbPath)
+	if err != nil {
+		h.sendError(w, r, http.StatusInternalServerError, "failed to open thumbnail")
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		h.sendError(w, r, http.StatusInternalServerError, "failed to access thumbnail")
+		return
+	}
+
+	h.setCORSHeaders(w, r)
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", thumbnailCacheMaxAge))
+	w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()))
+	http.ServeContent(w, r, name, info.ModTime(), file)
+}
+
+// ============================================================================
+// 评论 API 处理器
+// ============================================================================
+
+// HandleCommentsAPI 处理 /api/comments 请求
+// GET /api/comments?videoId=xxx        - 视频评论楼层（一级评论 + 回复）
+// GET /api/comments?q=关键词[&videoId=] - 全文搜索已采集的评论
+// GET /api/comments                    - 已采集评论的视频列表
+// DELETE /api/comments?videoId=xxx     - 删除视频的评论
+func (h *ConsoleAPIHandler) HandleCommentsAPI(w http.ResponseWriter, r *http.Request) {
+	if h.HandleCORS(w, r) {
+		return
+	}
+
+	videoID := r.URL.Query().Get("videoId")
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	switch r.Method {
+	case "GET":
+		switch {
+		case query != "":
+			h.handleCommentsSearch(w, r, query, videoID)
+		case videoID != "":
+			h.handleCommentsThread(w, r, videoID)
+		default:
+			result, err := h.commentService.ListVideos(getPaginationParams(r))
+			if err != nil {
+				h.sendError(w, r, http.StatusInternalServerError, err.Error())
+				return
+			}
+			h.sendSuccess(w, r, result)
+		}
+	case "DELETE":
+		if videoID == "" {
+			h.sendError(w, r, http.StatusBadRequest, "videoId is required")
+			return
+		}
+		if err := h.commentService.Delete(videoID); err != nil {
+			h.sendError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		h.auditService.RecordRequest(r, &database.AuditEntry{
+			Action:     "comments.delete",
+			TargetType: "video",
+			TargetIDs:  []string{videoID},
+		})
+		h.sendSuccessMessage(w, r, "comments deleted")
+	default:
+		h.sendError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// HandleCommentAnalytics 处理 GET /api/comments/analytics?videoId=&author=&interval=day|hour&limit=
+func (h *ConsoleAPIHandler) HandleCommentAnalytics(w http.ResponseWriter, r *http.Request) {
+	if h.HandleCORS(w, r) {
+		return
+	}
+	if r.Method != "GET" {
+		h.sendError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	filter := &database.CommentAnalyticsFilter{
+		VideoID:  r.URL.Query().Get("videoId"),
+		Author:   r.URL.Query().Get("author"),
+		Interval: r.URL.Query().Get("interval"),
+	}
+	if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil {
+		filter.Limit = limit
+	}
+
+	analytics, err := h.commentService.GetAnalytics(filter)
+	if err != nil {
+		h.sendError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.sendSuccess(w, r, analytics)
+}
+
+// HandleCommentRuns 处理 GET /api/comments/runs?videoId= - 视频的评论采集批次
+func (h *ConsoleAPIHandler) HandleCommentRuns(w http.ResponseWriter, r *http.Request) {
+	if h.HandleCORS(w, r) {
+		return
+	}
+	if r.Method != "GET" {
+		h.sendError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	videoID := r.URL.Query().Get("videoId")
+	if videoID == "" {
+		h.sendError(w, r, http.StatusBadRequest, "videoId is required")
+		return
+	}
+
+	runs, err := h.commentService.ListRuns(videoID)
+	if err != nil {
+		h.sendError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.sendSuccess(w, r, runs)
+}
+
+// HandleCommentDiff 处理 GET /api/comments/diff?videoId=&from=&to= - 两个采集批次之间的差异
+// 省略 to 时取最新批次，省略 from 时取 to 的上一个批次
+func (h *ConsoleAPIHandler) HandleCommentDiff(w http.ResponseWriter, r *http.Request) {
+	if h.HandleCORS(w, r) {
+		return
+	}
+	if r.Method != "GET" {
+		h.sendError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	videoID := r.URL.Query().Get("videoId")
+	if videoID == "" {
+		h.sendError(w, r, http.StatusBadRequest, "videoId is required")
+		return
+	}
+
+	var fromRunID, toRunID int64
+	if v := r.URL.Query().Get("from"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			h.sendError(w, r, http.StatusBadRequest, "invalid from run id")
+			return
+		}
+		fromRunID = id
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			h.sendError(w, r, http.StatusBadRequest, "invalid to run id")
+			return
+		}
+		toRunID = id
+	}
+
+	diff, err := h.commentService.GetDiff(videoID, fromRunID, toRunID)
+	if err != nil {
+		h.sendError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.sendSuccess(w, r, diff)
+}
+
+// handleCommentsThread 返回视频的评论楼层
+func (h *ConsoleAPIHandler) handleCommentsThread(w http.ResponseWriter, r *http.Request, videoID string) {
+	thread, err := h.commentService.GetThread(videoID)
+	if err != nil {
+		h.sendError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if thread == nil {
+		h.sendError(w, r, http.StatusNotFound, "no comments collected for this video")
+		return
+	}
+	h.sendSuccess(w, r, thread)
+}
+
+// handleCommentsSearch 全文搜索评论
+func (h *ConsoleAPIHandler) handleCommentsSearch(w http.ResponseWriter, r *http.Request, query, videoID string) {
+	result, err := h.commentService.Search(query, videoID, getPaginationParams(r))
+	if err != nil {
+		h.sendError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.sendSuccess(w, r, result)
+}
+
+// ============================================================================
+// 脚本补丁规则 API 处理器
+// ============================================================================
+
+// HandleScriptRulesAPI 处理 /api/script-rules 请求
+// GET /api/script-rules         - 规则版本、来源及最近一次页面加载的命中情况
+// POST /api/script-rules/reload - 立即重新加载规则文件
+func (h *ConsoleAPIHandler) HandleScriptRulesAPI(w http.ResponseWriter, r *http.Request) {
+	if h.HandleCORS(w, r) {
+		return
+	}
+
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	switch {
+	case r.Method == "GET" && strings.HasSuffix(path, "/script-rules"):
+		h.sendSuccess(w, r, h.scriptPatchService.Report())
+	case r.Method == "POST" && strings.HasSuffix(path, "/script-rules/reload"):
+		if err := h.scriptPatchService.Reload(); err != nil {
+			h.sendError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.auditService.RecordRequest(r, &database.AuditEntry{
+			Action:     "script_rules.reload",
+			TargetType: "script_rules",
+		})
+		h.sendSuccess(w, r, h.scriptPatchService.Report())
+	default:
+		h.sendError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// ============================================================================
+// 账号视频抓取 API 处理器
+// ============================================================================
+
+// HandleCrawlAPI 处理 /api/crawl 请求
+// GET /api/crawl                  - 抓取任务列表
+// POST /api/crawl                 - 创建抓取任务
+// GET /api/crawl/:id              - 任务进度
+// GET /api/crawl/:id/videos       - 任务发现的视频（分页）
+// POST /api/crawl/:id/pause       - 暂停任务
+// POST /api/crawl/:id/resume      - 从上次位置继续
+// POST /api/crawl/:id/cancel      - 取消任务
+func (h *ConsoleAPIHandler) HandleCrawlAPI(w http.ResponseWriter, r *http.Request) {
+	if h.HandleCORS(w, r) {
+		return
+	}
+
+	pathParts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/crawl"), "/"), "/")
+	if pathParts[0] == "" {
+		switch r.Method {
+		case "GET":
+			jobs, err := h.crawlService.List()
+			if err != nil {
+				h.sendError(w, r, http.StatusInternalServerError, err.Error())
+				return
+			}
+			h.sendSuccess(w, r, jobs)
+		case "POST":
+			h.handleCrawlStart(w, r)
+		default:
+			h.sendError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		}
+		return
+	}
+
+	id, err := strconv.ParseInt(pathParts[0], 10, 64)
+	if err != nil {
+		h.sendError(w, r, http.StatusBadRequest, "invalid job ID")
+		return
+	}
+	action := ""
+	if len(pathParts) > 1 {
+		action = pathParts[1]
+	}
+
+	switch {
+	case r.Method == "GET" && action == "":
+		job, err := h.crawlService.Get(id)
+		if err != nil {
+			h.sendError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if job == nil {
+			h.sendError(w, r, http.StatusNotFound, "crawl job not found")
+			return
+		}
+		h.sendSuccess(w, r, job)
+	case r.Method == "GET" && action == "videos":
+		result, err := h.crawlService.ListVideos(id, getPaginationParams(r))
+		if err != nil {
+			h.sendError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		h.sendSuccess(w, r, result)
+	case r.Method == "POST" && (action == "pause" || action == "resume" || action == "cancel"):
+		var job *database.CrawlJob
+		switch action {
+		case "pause":
+			job, err = h.crawlService.Pause(id)
+		case "resume":
+			job, err = h.crawlService.Resume(id)
+		default:
+			job, err = h.crawlService.Cancel(id)
+		}
+		if err != nil {
+			status := http.StatusConflict
+			if errors.Is(err, services.ErrCrawlJobNotFound) {
+				status = http.StatusNotFound
+			}
+			h.sendError(w, r, status, err.Error())
+			return
+		}
+		h.sendSuccess(w, r, job)
+	default:
+		h.sendError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleCrawlStart 处理 POST /api/crawl - 创建并启动抓取任务
+func (h *ConsoleAPIHandler) handleCrawlStart(w http.ResponseWriter, r *http.Request) {
+	var req services.CrawlRequest
+	if err := h.parseJSON(r, &req); err != nil {
+		h.sendError(w, r, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.Username) == "" {
+		h.sendError(w, r, http.StatusBadRequest, "username is required")
+		return
+	}
+
+	job, err := h.crawlService.Start(&req)
+	if err != nil {
+		h.sendError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.sendSuccess(w, r, job)
+}
+
+// ============================================================================
+// 关注列表 API 处理器
+// ============================================================================
+
+// HandleWatchAPI 处理 /api/watch 请求
+// GET /api/watch                    - 关注列表
+// POST /api/watch                   - 添加关注作者
+// GET /api/watch/videos?username=   - 检测到的新视频（分页）
+// GET /api/watch/:username          - 作者的检查状态
+// PUT /api/watch/:username          - 修改策略、间隔、关键词或启用状态
+// DELETE /api/watch/:username       - 取消关注
+// POST /api/watch/:username/check   - 立即检查
+func (h *ConsoleAPIHandler) HandleWatchAPI(w http.ResponseWriter, r *http.Request) {
+	if h.HandleCORS(w, r) {
+		return
+	}
+
+	pathParts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/watch"), "/"), "/")
+	username, action := pathParts[0], ""
+	if len(pathParts) > 1 {
+		action = pathParts[1]
+	}
+	if u, err := url.PathUnescape(username); err == nil {
+		username = u
+	}
+
+	switch {
+	case username == "" && r.Method == "GET":
+		authors, err := h.watchService.List()
+		if err != nil {
+			h.sendError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		h.sendSuccess(w, r, authors)
+	case username == "" && r.Method == "POST":
+		var req services.WatchAuthorRequest
+		if err := h.parseJSON(r, &req); err != nil {
+			h.sendError(w, r, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		if strings.TrimSpace(req.Username) == "" {
+			h.sendError(w, r, http.StatusBadRequest, "username is required")
+			return
+		}
+		author, err := h.watchService.Add(&req)
+		if err != nil {
+			h.sendWatchError(w, r, err)
+			return
+		}
+		h.sendSuccess(w, r, author)
+	case username == "videos" && action == "" && r.Method == "GET":
+		result, err := h.watchService.ListVideos(r.URL.Query().Get("username"), getPaginationParams(r))
+		if err != nil {
+			h.sendError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		h.sendSuccess(w, r, result)
+	case username != "" && action == "" && r.Method == "GET":
+		author, err := h.watchService.Get(username)
+		if err != nil {
+			h.sendError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if author == nil {
+			h.sendError(w, r, http.StatusNotFound, services.ErrWatchAuthorNotFound.Error())
+			return
+		}
+		h.sendSuccess(w, r, author)
+	case username != "" && action == "" && r.Method == "PUT":
+		var req services.WatchAuthorRequest
+		if err := h.parseJSON(r, &req); err != nil {
+			h.sendError(w, r, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		author, err := h.watchService.Update(username, &req)
+		if err != nil {
+			h.sendWatchError(w, r, err)
+			return
+		}
+		h.sendSuccess(w, r, author)
+	case username != "" && action == "" && r.Method == "DELETE":
+		if err := h.watchService.Remove(username); err != nil {
+			h.sendWatchError(w, r, err)
+			return
+		}
+		h.auditService.RecordRequest(r, &database.AuditEntry{
+			Action:     "watch.remove",
+			TargetType: "author",
+			TargetIDs:  []string{username},
+		})
+		h.sendSuccessMessage(w, r, "author unwatched")
+	case username != "" && action == "check" && r.Method == "POST":
+		videos, err := h.watchService.Check(username)
+		if errors.Is(err, services.ErrWatchAuthorNotFound) {
+			h.sendError(w, r, http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			// 页面未连接或请求微信失败
+			h.sendError(w, r, http.StatusBadGateway, err.Error())
+			return
+		}
+		h.sendSuccess(w, r, videos)
+	default:
+		h.sendError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// HandleTokensAPI 路由 API 令牌管理请求
+// GET /api/tokens - 列出令牌
+// POST /api/tokens - 创建令牌，明文只在响应中返回一次
+// DELETE /api/tokens/:idOrName - 删除令牌
+func (h *ConsoleAPIHandler) HandleTokensAPI(w http.ResponseWriter, r *http.Request) {
+	if h.HandleCORS(w, r) {
+		return
+	}
+
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/tokens"), "/")
+	if u, err := url.PathUnescape(id); err == nil {
+		id = u
+	}
+
+	switch {
+	case id == "" && r.Method == "GET":
+		tokens, err := h.tokenService.List()
+		if err != nil {
+			h.sendError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		h.sendSuccess(w, r, tokens)
+	case id == "" && r.Method == "POST":
+		var req services.APITokenRequest
+		if err := h.parseJSON(r, &req); err != nil {
+			h.sendError(w, r, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		token, plain, err := h.tokenService.Create(&req)
+		if err != nil {
+			h.sendTokenError(w, r, err)
+			return
+		}
+		h.auditService.RecordRequest(r, &database.AuditEntry{
+			Action:     "tokens.create",
+			TargetType: "api_token",
+			TargetIDs:  []string{token.Name},
+			Detail:     "scopes=" + strings.Join(token.Scopes, ","),
+		})
+		h.sendSuccess(w, r, map[string]interface{}{
+			"token":  token,
+			"secret": plain,
+		})
+	case id != "" && r.Method == "DELETE":
+		if err := h.tokenService.Revoke(id); err != nil {
+			h.sendTokenError(w, r, err)
+			return
+		}
+		h.auditService.RecordRequest(r, &database.AuditEntry{
+			Action:     "tokens.revoke",
+			TargetType: "api_token",
+			TargetIDs:  []string{id},
+		})
+		h.sendSuccessMessage(w, r, "token revoked")
+	default:
+		h.sendError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// HandleWebhooksAPI 路由出站 Webhook 管理请求
+// GET /api/webhooks - 列出 Webhook
+// POST /api/webhooks - 添加 Webhook
+// GET/PUT/DELETE /api/webhooks/:id - 查看、修改、删除 Webhook
+// GET /api/webhooks/:id/deliveries - 分页查看推送记录
+// POST /api/webhooks/:id/test - 立即发送一次测试事件
+func (h *ConsoleAPIHandler) HandleWebhooksAPI(w http.ResponseWriter, r *http.Request) {
+	if h.HandleCORS(w, r) {
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/webhooks"), "/"), "/")
+	id, action := parts[0], ""
+	if len(parts) > 1 {
+		action = parts[1]
+	}
+	if len(parts) > 2 {
+		h.sendError(w, r, http.StatusNotFound, "not found")
+		return
+	}
+
+	switch {
+	case id == "" && r.Method == "GET":
+		hooks, err := h.webhookService.List()
+		if err != nil {
+			h.sendError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		h.sendSuccess(w, r, hooks)
+	case id == "" && r.Method == "POST":
+		var req services.WebhookRequest
+		if err := h.parseJSON(r, &req); err != nil {
+			h.sendError(w, r, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		hook, err := h.webhookService.Create(&req)
+		if err != nil {
+			h.sendWebhookError(w, r, err)
+			return
+		}
+		h.auditService.RecordRequest(r, &database.AuditEntry{
+			Action:     "webhooks.create",
+			TargetType: "webhook",
+			TargetIDs:  []string{hook.ID},
+			Detail:     hook.URL,
+		})
+		h.sendSuccess(w, r, hook)
+	case id != "" && action == "" && r.Method == "GET":
+		hook, err := h.webhookService.Get(id)
+		if err != nil {
+			h.sendWebhookError(w, r, err)
+			return
+		}
+		h.sendSuccess(w, r, hook)
+	case id != "" && action == "" && r.Method == "PUT":
+		var req services.WebhookRequest
+		if err := h.parseJSON(r, &req); err != nil {
+			h.sendError(w, r, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		before, err := h.webhookService.Get(id)
+		if err != nil {
+			h.sendWebhookError(w, r, err)
+			return
+		}
+		hook, err := h.webhookService.Update(id, &req)
+		if err != nil {
+			h.sendWebhookError(w, r, err)
+			return
+		}
+		beforeValue, afterValue := services.AuditChanges(before, hook)
+		h.auditService.RecordRequest(r, &database.AuditEntry{
+			Action:     "webhooks.update",
+			TargetType: "webhook",
+			TargetIDs:  []string{hook.ID},
+			Before:     beforeValue,
+			After:      afterValue,
+		})
+		h.sendSuccess(w, r, hook)
+	case id != "" && action == "" && r.Method == "DELETE":
+		if err := h.webhookService.Delete(id); err != nil {
+			h.sendWebhookError(w, r, err)
+			return
+		}
+		h.auditService.RecordRequest(r, &database.AuditEntry{
+			Action:     "webhooks.delete",
+			TargetType: "webhook",
+			TargetIDs:  []string{id},
+		})
+		h.sendSuccessMessage(w, r, "webhook deleted")
+	case id != "" && action == "deliveries" && r.Method == "GET":
+		result, err := h.webhookService.Deliveries(id, getPaginationParams(r))
+		if err != nil {
+			h.sendWebhookError(w, r, err)
+			return
+		}
+		h.sendSuccess(w, r, result)
+	case id != "" && action == "test" && r.Method == "POST":
+		delivery, err := h.webhookService.Test(id)
+		if err != nil {
+			h.sendWebhookError(w, r, err)
+			return
+		}
+		h.sendSuccess(w, r, delivery)
+	default:
+		h.sendError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// sendWebhookError 将 Webhook 服务的错误映射为 HTTP 状态码
+func (h *ConsoleAPIHandler) sendWebhookError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, services.ErrWebhookNotFound):
+		h.sendError(w, r, http.StatusNotFound, err.Error())
+	default:
+		h.sendError(w, r, http.StatusBadRequest, err.Error())
+	}
+}
+
+// HandleTrashAPI 路由回收站请求，type 为 downloads 或 browse
+// GET /api/trash/:type              - 回收站中的记录（分页，按删除时间倒序）
+// POST /api/trash/:type/restore     - 恢复 {"ids": [...]}，文件移回原路径
+// DELETE /api/trash/:type           - 永久删除 {"ids": [...]}，{"all": true} 清空该类型的回收站
+func (h *ConsoleAPIHandler) HandleTrashAPI(w http.ResponseWriter, r *http.Request) {
+	if h.HandleCORS(w, r) {
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/trash"), "/"), "/")
+	trashType := parts[0]
+	action := ""
+	if len(parts) > 1 {
+		action = parts[1]
+	}
+
+	switch {
+	case action == "" && r.Method == "GET":
+		result, err := h.trashService.List(trashType, getPaginationParams(r))
+		if err != nil {
+			h.sendTrashError(w, r, err)
+			return
+		}
+		h.sendSuccess(w, r, result)
+	case action == "restore" && r.Method == "POST":
+		var req struct {
+			IDs []string `json:"ids"`
+		}
+		if err := h.parseJSON(r, &req); err != nil {
+			h.sendError(w, r, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		if len(req.IDs) == 0 {
+			h.sendError(w, r, http.StatusBadRequest, "no IDs provided")
+			return
+		}
+		result, err := h.trashService.Restore(trashType, req.IDs)
+		if err != nil {
+			h.sendTrashError(w, r, err)
+			return
+		}
+		h.auditService.RecordRequest(r, &database.AuditEntry{
+			Action:     "trash.restore",
+			TargetType: trashType,
+			TargetIDs:  req.IDs,
+			Detail:     fmt.Sprintf("records=%d files=%d", result.Records, result.Files),
+		})
+		h.sendSuccess(w, r, result)
+	case action == "" && r.Method == "DELETE":
+		var req struct {
+			IDs []string `json:"ids"`
+			All bool     `json:"all"`
+		}
+		if err := h.parseJSON(r, &req); err != nil {
+			h.sendError(w, r, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		if len(req.IDs) == 0 && !req.All {
+			h.sendError(w, r, http.StatusBadRequest, "no IDs provided")
+			return
+		}
+		if req.All {
+			req.IDs = nil
+		}
+		result, err := h.trashService.Purge(trashType, req.IDs)
+		if err != nil {
+			h.sendTrashError(w, r, err)
+			return
+		}
+		h.auditService.RecordRequest(r, &database.AuditEntry{
+			Action:     "trash.purge",
+			TargetType: trashType,
+			TargetIDs:  req.IDs,
+			Detail:     fmt.Sprintf("all=%t records=%d files=%d", req.All, result.Records, result.Files),
+		})
+		h.sendSuccess(w, r, result)
+	default:
+		h.sendError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// sendTrashError 将回收站服务的错误映射为 HTTP 状态码
+func (h *ConsoleAPIHandler) sendTrashError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, services.ErrTrashType) {
+		h.sendError(w, r, http.StatusNotFound, err.Error())
+		return
+	}
+	h.sendError(w, r, http.StatusInternalServerError, err.Error())
+}
+
+// HandleAuditAPI 处理 GET /api/audit - 分页查询审计记录
+// 过滤参数：actor、actorType、action（以 . 结尾时按前缀匹配）、targetType、targetId、since、until
+func (h *ConsoleAPIHandler) HandleAuditAPI(w http.ResponseWriter, r *http.Request) {
+	if h.HandleCORS(w, r) {
+		return
+	}
+	if r.Method != "GET" {
+		h.sendError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	filter, err := services.ParseAuditFilter(r.URL.Query())
+	if err != nil {
+		h.sendError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	result, err := h.auditService.List(filter, getPaginationParams(r))
+	if err != nil {
+		h.sendError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.sendSuccess(w, r, result)
+}
+
+// sseHeartbeatInterval SSE 连接空闲时发送注释行的间隔，避免被代理断开
+const sseHeartbeatInterval = 15 * time.Second
+
+// sseTopics 是 /api/events 支持的事件类型
+var sseTopics = map[string]bool{
+	services.EventDownloadProgress:      true,
+	services.EventQueueChange:           true,
+	services.EventStatsUpdate:           true,
+	services.EventWatchNewVideos:        true,
+	services.EventTranscriptionProgress: true,
+	services.EventBatchProgress:         true,
+}
+
+// HandleEventsAPI 处理 GET /api/events - 以 Server-Sent Events 推送控制台事件
+// 查询参数 topics 按逗号分隔的事件类型过滤（为空时推送全部）；
+// 重连时通过 Last-Event-ID 请求头（或 lastEventId 参数）补发缓冲区中错过的事件
+func (h *ConsoleAPIHandler) HandleEventsAPI(w http.ResponseWriter, r *http.Request) {
+	if h.HandleCORS(w, r) {
+		return
+	}
+	if r.Method != "GET" {
+		h.sendError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var topics []string
+	for _, topic := range strings.Split(r.URL.Query().Get("topics"), ",") {
+		topic = strings.TrimSpace(topic)
+		if topic == "" {
+			continue
+		}
+		if !sseTopics[topic] {
+			h.sendError(w, r, http.StatusBadRequest, "unknown topic: "+topic)
+			return
+		}
+		topics = append(topics, topic)
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+	var lastID int64
+	if lastEventID != "" {
+		id, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || id < 0 {
+			h.sendError(w, r, http.StatusBadRequest, "invalid Last-Event-ID")
+			return
+		}
+		lastID = id
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.sendError(w, r, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	sub, replay := h.eventBus.Subscribe(topics, lastID)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: 3000\n\n")
+	for _, event := range replay {
+		if writeSSEEvent(w, event) != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				// 消费过慢被断开，客户端带 Last-Event-ID 重连即可续传
+				return
+			}
+			if writeSSEEvent(w, event) != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprintf(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeSSEEvent 按 SSE 格式写出一个事件
+func writeSSEEvent(w io.Writer, event services.Event) error {
+	_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+	return err
+}
+
+// sendTokenError 将令牌服务的错误映射为 HTTP 状态码
+func (h *ConsoleAPIHandler) sendTokenError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, services.ErrAPITokenNotFound):
+		h.sendError(w, r, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrAPITokenExists):
+		h.sendError(w, r, http.StatusConflict, err.Error())
+	default:
+		h.sendError(w, r, http.StatusBadRequest, err.Error())
+	}
+}
+
+// sendWatchError 将关注列表服务的错误映射为 HTTP 状态码
+func (h *ConsoleAPIHandler) sendWatchError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, services.ErrWatchAuthorNotFound):
+		h.sendError(w, r, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrWatchAuthorExists):
+		h.sendError(w, r, http.StatusConflict, err.Error())
+	default:
+		h.sendError(w, r, http.StatusBadRequest, err.Error())
+	}
+}
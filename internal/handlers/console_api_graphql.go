@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"wx_channel/internal/graphql"
+)
+
+// HandleGraphQLAPI 处理 /api/graphql 的只读查询。
+// GET 从 query、variables（JSON）和 operationName 参数读取请求，POST 读取 JSON 请求体。
+// 响应为 GraphQL 标准格式 {data, errors}；请求无法执行（语法错误、校验失败、超出限制）时返回 400
+func (h *ConsoleAPIHandler) HandleGraphQLAPI(w http.ResponseWriter, r *http.Request) {
+	if h.HandleCORS(w, r) {
+		return
+	}
+
+	req := &graphql.Request{}
+	switch r.Method {
+	case http.MethodGet:
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if variables := r.URL.Query().Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				h.sendGraphQLError(w, r, http.StatusBadRequest, "variables must be a JSON object")
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := h.parseJSON(r, req); err != nil {
+			h.sendGraphQLError(w, r, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		h.sendGraphQLError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if req.Query == "" {
+		h.sendGraphQLError(w, r, http.StatusBadRequest, "query is required")
+		return
+	}
+
+	resp := h.graphqlService.Execute(r.Context(), req)
+	status := http.StatusOK
+	if resp.Data == nil {
+		status = http.StatusBadRequest
+	}
+	h.sendJSON(w, r, status, resp)
+}
+
+// sendGraphQLError 以 GraphQL 格式发送请求级错误
+func (h *ConsoleAPIHandler) sendGraphQLError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	h.sendJSON(w, r, status, &graphql.Response{Errors: []*graphql.Error{{Message: message}}})
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"bufio"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -9,6 +10,10 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"wx_channel/internal/response"
+	"wx_channel/internal/services"
 )
 
 func TestIsPathWithinBase(t *testing.T) {
@@ -74,6 +79,126 @@ func TestHandleQueueFail_InvalidJSONReturnsBadRequest(t *testing.T) {
 	}
 }
 
+func TestHandleCommentsAPI_DeleteRequiresVideoID(t *testing.T) {
+	handler := &ConsoleAPIHandler{}
+	req := httptest.NewRequest(http.MethodDelete, "/api/comments", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleCommentsAPI(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+
+	var resp APIResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Error != "videoId is required" {
+		t.Fatalf("error = %q, want %q", resp.Error, "videoId is required")
+	}
+}
+
+func TestHandleCommentDiff_InvalidRunID(t *testing.T) {
+	handler := &ConsoleAPIHandler{}
+	req := httptest.NewRequest(http.MethodGet, "/api/comments/diff?videoId=v1&from=abc", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleCommentDiff(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+
+	var resp APIResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Error != "invalid from run id" {
+		t.Fatalf("error = %q, want %q", resp.Error, "invalid from run id")
+	}
+}
+
+func TestHandleThumbnailsAPI_RejectsPathTraversal(t *testing.T) {
+	handler := &ConsoleAPIHandler{thumbnailService: services.NewThumbnailService()}
+
+	paths := []string{
+		"/api/thumbnails/rec1/../../config.yaml",
+		"/api/thumbnails/rec1/..%2F..%2Fconfig.yaml",
+		"/api/thumbnails/rec1/keyframe_../../x.jpg",
+		"/api/thumbnails/rec1/keyframe_01.jpg/../../x",
+		"/api/thumbnails/../../etc/passwd",
+		"/api/thumbnails/../cover.jpg",
+		"/api/thumbnails/..%2F..%2F/cover.jpg",
+	}
+	for _, path := range paths {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost/", nil)
+		req.URL.Path, _ = url.PathUnescape(path)
+		rr := httptest.NewRecorder()
+
+		handler.HandleThumbnailsAPI(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: status = %d, want %d", path, rr.Code, http.StatusBadRequest)
+		}
+		if ct := rr.Header().Get("Content-Type"); strings.HasPrefix(ct, "image/") {
+			t.Errorf("GET %s: served a file (%s)", path, ct)
+		}
+	}
+}
+
+func TestHandleCrawlAPI_InvalidRequests(t *testing.T) {
+	handler := &ConsoleAPIHandler{}
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		want   string
+	}{
+		{http.MethodPost, "/api/crawl", `{"enqueue":true}`, "username is required"},
+		{http.MethodPost, "/api/crawl", `{`, "invalid request body"},
+		{http.MethodGet, "/api/crawl/abc", "", "invalid job ID"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		rr := httptest.NewRecorder()
+
+		handler.HandleCrawlAPI(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("%s %s: status = %d, want %d", tt.method, tt.path, rr.Code, http.StatusBadRequest)
+		}
+		var resp APIResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Error != tt.want {
+			t.Fatalf("%s %s: error = %q, want %q", tt.method, tt.path, resp.Error, tt.want)
+		}
+	}
+}
+
+func TestHandleWatchAPI_AddRequiresUsername(t *testing.T) {
+	handler := &ConsoleAPIHandler{}
+	req := httptest.NewRequest(http.MethodPost, "/api/watch", strings.NewReader(`{"policy":"enqueue"}`))
+	rr := httptest.NewRecorder()
+
+	handler.HandleWatchAPI(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+
+	var resp APIResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Error != "username is required" {
+		t.Fatalf("error = %q, want %q", resp.Error, "username is required")
+	}
+}
+
 func TestValidateVideoPlayTargetURL(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -262,3 +387,212 @@ func TestVideoProxyHTTPClient_CheckRedirect(t *testing.T) {
 		t.Fatalf("unexpected redirect validation error: %v", err)
 	}
 }
+
+func TestHandleEventsAPI_UnknownTopic(t *testing.T) {
+	handler := &ConsoleAPIHandler{eventBus: services.NewEventBus()}
+	req := httptest.NewRequest(http.MethodGet, "/api/events?topics=queue_change,nope", nil)
+	rec := httptest.NewRecorder()
+
+	handler.HandleEventsAPI(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestHandleEventsAPI_ResumeAndFilter(t *testing.T) {
+	bus := services.NewEventBus()
+	handler := &ConsoleAPIHandler{eventBus: bus}
+	server := httptest.NewServer(http.HandlerFunc(handler.HandleEventsAPI))
+	defer server.Close()
+
+	bus.Publish(services.EventQueueChange, map[string]string{"action": "add"})    // 1
+	bus.Publish(services.EventStatsUpdate, map[string]string{})                   // 2
+	bus.Publish(services.EventQueueChange, map[string]string{"action": "remove"}) // 3
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"?topics=queue_change", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		t.Fatalf("unexpected content type: %s", ct)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	readID := func() string {
+		t.Helper()
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("read failed: %v", err)
+			}
+			if strings.HasPrefix(line, "id: ") {
+				return strings.TrimSpace(strings.TrimPrefix(line, "id: "))
+			}
+		}
+	}
+
+	// 只补发 ID 1 之后的 queue_change
+	if id := readID(); id != "3" {
+		t.Fatalf("expected replayed event 3, got %s", id)
+	}
+
+	for bus.SubscriberCount() == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	bus.Publish(services.EventStatsUpdate, map[string]string{})
+	bus.Publish(services.EventQueueChange, map[string]string{"action": "update"})
+	if id := readID(); id != "5" {
+		t.Fatalf("expected live event 5, got %s", id)
+	}
+}
+
+func TestV2HandlerFunc_InvalidBody(t *testing.T) {
+	handler := &ConsoleAPIHandler{}
+
+	cases := []struct {
+		body string
+		code string
+	}{
+		{"", "invalid_request"},
+		{"{", "invalid_request"},
+		{`{"ids":["1"],"unknown":true}`, "invalid_request"},
+		{`{"ids":["1"]} {}`, "invalid_request"},
+		{`{}`, "validation_failed"},
+		{`{"ids":["1"],"all":true}`, "validation_failed"},
+		{`{"ids":["1","1"]}`, "validation_failed"},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodDelete, "/api/v2/browse", strings.NewReader(c.body))
+		w := httptest.NewRecorder()
+		V2HandlerFunc(handler.V2DeleteBrowseMany).ServeHTTP(w, req)
+
+		var resp struct {
+			Error response.APIError `json:"error"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("body %q: invalid JSON: %v", c.body, err)
+		}
+		if string(resp.Error.Code) != c.code {
+			t.Errorf("body %q: expected code %s, got %q (%s)", c.body, c.code, resp.Error.Code, resp.Error.Message)
+		}
+	}
+}
+
+func TestV2AddToQueue_ValidationDetails(t *testing.T) {
+	handler := &ConsoleAPIHandler{}
+
+	body := `{"videos":[{"videoId":"v1","videoUrl":"https://example.com/v.mp4"},{"videoId":"","videoUrl":"ftp://x","quality":"4k?"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v2/queue", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	V2HandlerFunc(handler.V2AddToQueue).ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422, got %d", w.Code)
+	}
+	var resp struct {
+		Error response.APIError `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	fields := make(map[string]bool)
+	for _, d := range resp.Error.Details {
+		fields[d.Field] = true
+	}
+	for _, field := range []string{"videos[1].videoId", "videos[1].videoUrl", "videos[1].quality"} {
+		if !fields[field] {
+			t.Errorf("Expected error for %s, got %+v", field, resp.Error.Details)
+		}
+	}
+	if len(resp.Error.Details) != 3 {
+		t.Errorf("Expected 3 field errors, got %+v", resp.Error.Details)
+	}
+}
+
+func TestHandleGraphQLAPI_RequestErrors(t *testing.T) {
+	handler := &ConsoleAPIHandler{graphqlService: services.NewGraphQLService()}
+
+	cases := []struct {
+		method string
+		target string
+		body   string
+		status int
+		code   string
+	}{
+		{http.MethodPost, "/api/graphql", "{", http.StatusBadRequest, ""},
+		{http.MethodPost, "/api/graphql", `{}`, http.StatusBadRequest, ""},
+		{http.MethodGet, "/api/graphql?query=%7Bqueue%7Bid%7D%7D&variables=x", "", http.StatusBadRequest, ""},
+		{http.MethodPut, "/api/graphql", `{"query":"{ queue { id } }"}`, http.StatusMethodNotAllowed, ""},
+		{http.MethodPost, "/api/graphql", `{"query":"mutation { queue { id } }"}`, http.StatusBadRequest, "not_allowed"},
+		{http.MethodGet, "/api/graphql?query=" + url.QueryEscape("{ downloads { items { nope } } }"), "", http.StatusBadRequest, "validation_failed"},
+		{http.MethodPost, "/api/graphql", `{"query":"{ authors(pageSize: 100) { items { downloads(pageSize: 100) { items { browse { id } } } } } }"}`, http.StatusBadRequest, "query_too_complex"},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.target, strings.NewReader(c.body))
+		w := httptest.NewRecorder()
+		handler.HandleGraphQLAPI(w, req)
+
+		if w.Code != c.status {
+			t.Errorf("%s %s %s: expected status %d, got %d", c.method, c.target, c.body, c.status, w.Code)
+			continue
+		}
+		var resp struct {
+			Data   interface{} `json:"data"`
+			Errors []struct {
+				Message    string            `json:"message"`
+				Extensions map[string]string `json:"extensions"`
+			} `json:"errors"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		if resp.Data != nil || len(resp.Errors) != 1 {
+			t.Errorf("%s %s %s: expected a single error without data, got %s", c.method, c.target, c.body, w.Body.String())
+			continue
+		}
+		if resp.Errors[0].Extensions["code"] != c.code {
+			t.Errorf("%s %s %s: expected code %q, got %q", c.method, c.target, c.body, c.code, resp.Errors[0].Extensions["code"])
+		}
+	}
+}
+
+func TestHandleAuthAPI_RequestErrors(t *testing.T) {
+	handler := &ConsoleAPIHandler{}
+
+	cases := []struct {
+		method string
+		target string
+		body   string
+		status int
+	}{
+		{http.MethodPost, "/api/auth/login", "{", http.StatusBadRequest},
+		{http.MethodPost, "/api/auth/login", `{"username":"alice"}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/auth/login", `{"username":" ","password":"secret"}`, http.StatusBadRequest},
+		{http.MethodGet, "/api/auth/login", "", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/api/auth/unknown", "", http.StatusNotFound},
+		// 未以用户身份登录时不能修改偏好和密码
+		{http.MethodPut, "/api/auth/me/preferences", `{"theme":"dark"}`, http.StatusUnauthorized},
+		{http.MethodPut, "/api/auth/me/password", `{"currentPassword":"a","newPassword":"bbbbbb"}`, http.StatusUnauthorized},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.target, strings.NewReader(c.body))
+		w := httptest.NewRecorder()
+		handler.HandleAuthAPI(w, req)
+
+		if w.Code != c.status {
+			t.Errorf("%s %s %s: expected status %d, got %d", c.method, c.target, c.body, c.status, w.Code)
+			continue
+		}
+		var resp APIResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		if resp.Success || resp.Error == "" {
+			t.Errorf("%s %s: expected error response, got %s", c.method, c.target, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"wx_channel/internal/database"
+	"wx_channel/internal/services"
+)
+
+// loginRequest 登录请求
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// changePasswordRequest 修改自己密码的请求
+type changePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+// HandleAuthAPI 路由控制台登录相关请求
+// POST /api/auth/login - 用户名密码登录，设置会话 Cookie
+// POST /api/auth/logout - 注销当前会话
+// GET /api/auth/me - 当前登录用户、CSRF 令牌以及是否启用了用户登录
+// PUT /api/auth/me/preferences - 保存当前用户的控制台偏好
+// PUT /api/auth/me/password - 修改当前用户的密码，并注销其他会话
+func (h *ConsoleAPIHandler) HandleAuthAPI(w http.ResponseWriter, r *http.Request) {
+	if h.HandleCORS(w, r) {
+		return
+	}
+
+	action := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/auth"), "/")
+	switch {
+	case action == "login" && r.Method == "POST":
+		h.handleLogin(w, r)
+	case action == "logout" && r.Method == "POST":
+		h.handleLogout(w, r)
+	case action == "me" && r.Method == "GET":
+		h.handleCurrentUser(w, r)
+	case action == "me/preferences" && r.Method == "PUT":
+		h.handleUpdatePreferences(w, r)
+	case action == "me/password" && r.Method == "PUT":
+		h.handleChangePassword(w, r)
+	case action == "login" || action == "logout" || action == "me" ||
+		action == "me/preferences" || action == "me/password":
+		h.sendError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+	default:
+		h.sendError(w, r, http.StatusNotFound, "not found")
+	}
+}
+
+// handleLogin 校验用户名和密码，成功后设置 HttpOnly 会话 Cookie 并返回 CSRF 令牌
+func (h *ConsoleAPIHandler) handleLogin(w http.ResponseWriter, r *http.Request) {
+	var req loginRequest
+	if err := h.parseJSON(r, &req); err != nil {
+		h.sendError(w, r, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.Username) == "" || req.Password == "" {
+		h.sendError(w, r, http.StatusBadRequest, "username and password are required")
+		return
+	}
+
+	sourceIP := services.RequestSourceIP(r)
+	user, session, plain, err := h.userService.Login(req.Username, req.Password, r.UserAgent(), sourceIP)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidCredentials):
+			h.recordLoginAudit("auth.login_failed", strings.TrimSpace(req.Username), database.AuditActorAnonymous, sourceIP)
+			h.sendError(w, r, http.StatusUnauthorized, err.Error())
+		case errors.Is(err, services.ErrLoginLocked):
+			h.sendError(w, r, http.StatusTooManyRequests, err.Error())
+		default:
+			h.sendError(w, r, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     services.SessionCookieName,
+		Value:    plain,
+		Path:     "/",
+		MaxAge:   int(services.SessionTTL / time.Second),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	h.recordLoginAudit("auth.login", user.Username, database.AuditActorUser, sourceIP)
+	h.sendSuccess(w, r, map[string]interface{}{
+		"user":      user,
+		"csrfToken": session.CSRFToken,
+		"expiresAt": session.ExpiresAt,
+	})
+}
+
+// recordLoginAudit 记录登录结果，登录请求尚未认证，操作者取自请求中的用户名
+func (h *ConsoleAPIHandler) recordLoginAudit(action, username, actorType, sourceIP string) {
+	h.auditService.Record(&database.AuditEntry{
+		Actor:      username,
+		ActorType:  actorType,
+		Action:     action,
+		TargetType: "user",
+		TargetIDs:  []string{username},
+		SourceIP:   sourceIP,
+	})
+}
+
+// handleLogout 删除当前会话并清除 Cookie
+func (h *ConsoleAPIHandler) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(services.SessionCookieName); err == nil {
+		if err := h.userService.Logout(cookie.Value); err != nil {
+			h.sendError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     services.SessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	h.sendSuccessMessage(w, r, "logged out")
+}
+
+// handleCurrentUser 返回当前登录用户；未登录时 user 为 null，
+// usersEnabled 为 true 时控制台应显示登录界面
+func (h *ConsoleAPIHandler) handleCurrentUser(w http.ResponseWriter, r *http.Request) {
+	data := map[string]interface{}{
+		"user":         nil,
+		"csrfToken":    "",
+		"usersEnabled": h.userService.Enabled(),
+	}
+	if user := services.UserFromContext(r.Context()); user != nil {
+		data["user"] = user
+		if session := services.UserSessionFromContext(r.Context()); session != nil {
+			data["csrfToken"] = session.CSRFToken
+			data["expiresAt"] = session.ExpiresAt
+		}
+	}
+	h.sendSuccess(w, r, data)
+}
+
+// handleUpdatePreferences 保存当前用户的主题和每页条数
+func (h *ConsoleAPIHandler) handleUpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	user := services.UserFromContext(r.Context())
+	if user == nil {
+		h.sendError(w, r, http.StatusUnauthorized, "user login required")
+		return
+	}
+	var prefs database.UserPreferences
+	if err := h.parseJSON(r, &prefs); err != nil {
+		h.sendError(w, r, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	updated, err := h.userService.UpdatePreferences(user.ID, &prefs)
+	if err != nil {
+		h.sendUserError(w, r, err)
+		return
+	}
+	h.sendSuccess(w, r, updated)
+}
+
+// handleChangePassword 校验当前密码后修改密码
+func (h *ConsoleAPIHandler) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	user := services.UserFromContext(r.Context())
+	session := services.UserSessionFromContext(r.Context())
+	if user == nil || session == nil {
+		h.sendError(w, r, http.StatusUnauthorized, "user login required")
+		return
+	}
+	var req changePasswordRequest
+	if err := h.parseJSON(r, &req); err != nil {
+		h.sendError(w, r, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := h.userService.ChangePassword(user.ID, session.ID, req.CurrentPassword, req.NewPassword); err != nil {
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			h.sendError(w, r, http.StatusBadRequest, "current password is incorrect")
+			return
+		}
+		h.sendUserError(w, r, err)
+		return
+	}
+	h.auditService.RecordRequest(r, &database.AuditEntry{
+		Action:     "auth.password",
+		TargetType: "user",
+		TargetIDs:  []string{user.Username},
+	})
+	h.sendSuccessMessage(w, r, "password changed")
+}
+
+// HandleUsersAPI 路由控制台用户管理请求
+// GET /api/users - 列出用户
+// POST /api/users - 创建用户
+// GET/PUT/DELETE /api/users/:id - 查看、修改（权限范围、重置密码）、删除用户，:id 也可以是用户名
+func (h *ConsoleAPIHandler) HandleUsersAPI(w http.ResponseWriter, r *http.Request) {
+	if h.HandleCORS(w, r) {
+		return
+	}
+
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/users"), "/")
+	if u, err := url.PathUnescape(id); err == nil {
+		id = u
+	}
+
+	switch {
+	case id == "" && r.Method == "GET":
+		users, err := h.userService.List()
+		if err != nil {
+			h.sendError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		h.sendSuccess(w, r, users)
+	case id == "" && r.Method == "POST":
+		var req services.UserRequest
+		if err := h.parseJSON(r, &req); err != nil {
+			h.sendError(w, r, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		user, err := h.userService.Create(&req)
+		if err != nil {
+			h.sendUserError(w, r, err)
+			return
+		}
+		h.auditService.RecordRequest(r, &database.AuditEntry{
+			Action:     "users.create",
+			TargetType: "user",
+			TargetIDs:  []string{user.Username},
+			Detail:     "scopes=" + strings.Join(user.Scopes, ","),
+		})
+		h.sendSuccess(w, r, user)
+	case id != "" && r.Method == "GET":
+		user, err := h.userService.Get(id)
+		if err != nil {
+			h.sendUserError(w, r, err)
+			return
+		}
+		h.sendSuccess(w, r, user)
+	case id != "" && r.Method == "PUT":
+		var req services.UserUpdateRequest
+		if err := h.parseJSON(r, &req); err != nil {
+			h.sendError(w, r, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		user, err := h.userService.Update(id, &req)
+		if err != nil {
+			h.sendUserError(w, r, err)
+			return
+		}
+		detail := "scopes=" + strings.Join(user.Scopes, ",")
+		if req.Password != "" {
+			detail += ", password reset"
+		}
+		h.auditService.RecordRequest(r, &database.AuditEntry{
+			Action:     "users.update",
+			TargetType: "user",
+			TargetIDs:  []string{user.Username},
+			Detail:     detail,
+		})
+		h.sendSuccess(w, r, user)
+	case id != "" && r.Method == "DELETE":
+		user, err := h.userService.Delete(id)
+		if err != nil {
+			h.sendUserError(w, r, err)
+			return
+		}
+		h.auditService.RecordRequest(r, &database.AuditEntry{
+			Action:     "users.delete",
+			TargetType: "user",
+			TargetIDs:  []string{user.Username},
+		})
+		h.sendSuccessMessage(w, r, "user deleted")
+	default:
+		h.sendError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// sendUserError 将用户服务的错误映射为 HTTP 状态码
+func (h *ConsoleAPIHandler) sendUserError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		h.sendError(w, r, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrUserExists), errors.Is(err, services.ErrLastAdmin):
+		h.sendError(w, r, http.StatusConflict, err.Error())
+	default:
+		h.sendError(w, r, http.StatusBadRequest, err.Error())
+	}
+}
@@ -0,0 +1,471 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"wx_channel/internal/database"
+	"wx_channel/internal/response"
+	"wx_channel/internal/services"
+)
+
+// ============================================================================
+// /api/v2 处理器
+// 路由见 router.registerV2Routes：路径参数通过 r.PathValue 读取，
+// 请求体通过 decodeV2Body 解析并校验，错误统一为 {"error": {"code", "message", "details"}}
+// ============================================================================
+
+// v2MaxBatchSize 批量操作一次最多处理的 ID 数
+const v2MaxBatchSize = 1000
+
+// v2DeleteRequest 批量删除：ids 和 all 二选一
+type v2DeleteRequest struct {
+	IDs         []string `json:"ids"`
+	All         bool     `json:"all"`
+	DeleteFiles bool     `json:"deleteFiles"` // 仅下载记录：永久删除时是否同时删除文件
+}
+
+func (req *v2DeleteRequest) validate(v *v2Validator) {
+	v.Check(len(req.IDs) > 0 || req.All, "ids", "ids or all is required")
+	v.Check(len(req.IDs) == 0 || !req.All, "all", "cannot be combined with ids")
+	validateV2IDs(v, "ids", req.IDs)
+}
+
+// v2DeleteResult 批量删除的结果，all 为 true 时只返回 cleared
+type v2DeleteResult struct {
+	Deleted int64 `json:"deleted"`
+	Cleared bool  `json:"cleared,omitempty"`
+}
+
+// v2QueueAddRequest 添加到下载队列
+type v2QueueAddRequest struct {
+	Videos []services.VideoInfo `json:"videos"`
+}
+
+func (req *v2QueueAddRequest) validate(v *v2Validator) {
+	v.Check(len(req.Videos) > 0, "videos", "at least one video is required")
+	v.Check(len(req.Videos) <= v2MaxBatchSize, "videos", fmt.Sprintf("at most %d videos per request", v2MaxBatchSize))
+	for i, video := range req.Videos {
+		field := fmt.Sprintf("videos[%d]", i)
+		v.Check(strings.TrimSpace(video.VideoID) != "", field+".videoId", "is required")
+		u, err := url.Parse(video.VideoURL)
+		v.Check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", field+".videoUrl", "must be an http or https URL")
+		_, err = database.ParseQualityPolicy(video.Quality)
+		v.Check(err == nil, field+".quality", "unsupported quality policy")
+	}
+}
+
+// v2QueueReorderRequest 重新排列下载队列
+type v2QueueReorderRequest struct {
+	IDs []string `json:"ids"`
+}
+
+func (req *v2QueueReorderRequest) validate(v *v2Validator) {
+	v.Check(len(req.IDs) > 0, "ids", "is required")
+	validateV2IDs(v, "ids", req.IDs)
+}
+
+// v2SettingsRequest 更新设置，需要提供完整的设置
+type v2SettingsRequest struct {
+	database.Settings
+}
+
+func (req *v2SettingsRequest) validate(v *v2Validator) {
+	if err := (&database.SettingsRepository{}).Validate(&req.Settings); err != nil {
+		v.Check(false, "settings", err.Error())
+	}
+}
+
+// validateV2IDs 校验 ID 列表：不能为空字符串、不能重复、数量不超过 v2MaxBatchSize
+func validateV2IDs(v *v2Validator, field string, ids []string) {
+	v.Check(len(ids) <= v2MaxBatchSize, field, fmt.Sprintf("at most %d ids per request", v2MaxBatchSize))
+	seen := make(map[string]bool, len(ids))
+	for i, id := range ids {
+		v.Check(strings.TrimSpace(id) != "", fmt.Sprintf("%s[%d]", field, i), "must not be empty")
+		v.Check(!seen[id], fmt.Sprintf("%s[%d]", field, i), "duplicate id")
+		seen[id] = true
+	}
+}
+
+// ---------------------------------------------------------------------------
+// 浏览记录
+// ---------------------------------------------------------------------------
+
+// V2ListBrowse 处理 GET /api/v2/browse - 分页列表，带 query 时搜索
+func (h *ConsoleAPIHandler) V2ListBrowse(r *http.Request) (interface{}, error) {
+	q := newV2Query(r)
+	params := q.Pagination(database.BrowseSortColumns, "browse_time")
+	search := q.String("query")
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+	if search != "" {
+		return h.browseService.Search(search, params)
+	}
+	return h.browseService.List(params)
+}
+
+// V2GetBrowse 处理 GET /api/v2/browse/{id}
+func (h *ConsoleAPIHandler) V2GetBrowse(r *http.Request) (interface{}, error) {
+	return h.v2BrowseRecord(r.PathValue("id"))
+}
+
+// V2GetBrowseFormats 处理 GET /api/v2/browse/{id}/formats - 可选编码及按画质策略选中的编码
+func (h *ConsoleAPIHandler) V2GetBrowseFormats(r *http.Request) (interface{}, error) {
+	q := newV2Query(r)
+	quality := q.String("quality")
+	if quality == "" {
+		quality = h.formatService.DefaultQuality()
+	}
+	policy, err := database.ParseQualityPolicy(quality)
+	q.Check(err == nil, "quality", "unsupported quality policy")
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+
+	id := r.PathValue("id")
+	formats, err := h.formatService.List(id)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"videoId":  id,
+		"formats":  formats,
+		"quality":  quality,
+		"selected": services.SelectVideoFormat(formats, policy),
+	}, nil
+}
+
+// V2DeleteBrowse 处理 DELETE /api/v2/browse/{id} - 移入回收站
+func (h *ConsoleAPIHandler) V2DeleteBrowse(r *http.Request) (interface{}, error) {
+	id := r.PathValue("id")
+	if _, err := h.v2BrowseRecord(id); err != nil {
+		return nil, err
+	}
+	if err := h.browseService.Delete(id); err != nil {
+		return nil, err
+	}
+	h.auditService.RecordRequest(r, &database.AuditEntry{
+		Action:     "browse.delete",
+		TargetType: "browse_record",
+		TargetIDs:  []string{id},
+	})
+	return v2DeleteResult{Deleted: 1}, nil
+}
+
+// V2DeleteBrowseMany 处理 DELETE /api/v2/browse - 按 ids 批量删除或 all 清空
+func (h *ConsoleAPIHandler) V2DeleteBrowseMany(r *http.Request) (interface{}, error) {
+	var req v2DeleteRequest
+	if err := decodeV2Body(r, &req); err != nil {
+		return nil, err
+	}
+
+	if req.All {
+		if err := h.browseService.Clear(); err != nil {
+			return nil, err
+		}
+		h.auditService.RecordRequest(r, &database.AuditEntry{
+			Action:     "browse.clear",
+			TargetType: "browse_record",
+		})
+		return v2DeleteResult{Cleared: true}, nil
+	}
+
+	count, err := h.browseService.DeleteMany(req.IDs)
+	if err != nil {
+		return nil, err
+	}
+	h.auditService.RecordRequest(r, &database.AuditEntry{
+		Action:     "browse.delete",
+		TargetType: "browse_record",
+		TargetIDs:  req.IDs,
+		Detail:     fmt.Sprintf("deleted=%d", count),
+	})
+	return v2DeleteResult{Deleted: count}, nil
+}
+
+// v2BrowseRecord 获取浏览记录，不存在时返回 not_found
+func (h *ConsoleAPIHandler) v2BrowseRecord(id string) (*database.BrowseRecord, error) {
+	record, err := h.browseService.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, v2NotFound("browse record not found")
+	}
+	return record, nil
+}
+
+// ---------------------------------------------------------------------------
+// 下载记录
+// ---------------------------------------------------------------------------
+
+// V2ListDownloads 处理 GET /api/v2/downloads - 带过滤的分页列表
+func (h *ConsoleAPIHandler) V2ListDownloads(r *http.Request) (interface{}, error) {
+	q := newV2Query(r)
+	params := &database.FilterParams{
+		PaginationParams: *q.Pagination(database.DownloadSortColumns, "download_time"),
+		StartDate:        q.Date("startDate"),
+		EndDate:          q.Date("endDate"),
+		Status:           q.String("status"),
+		Query:            q.String("query"),
+		MinResolution:    q.Int("minResolution", 0, 0, 1<<16),
+		VideoCodec:       q.String("videoCodec"),
+		InitiatedBy:      q.String("initiatedBy"),
+	}
+	switch params.Status {
+	case "", database.DownloadStatusPending, database.DownloadStatusInProgress, database.DownloadStatusCompleted, database.DownloadStatusFailed:
+	default:
+		q.Check(false, "status", "must be one of pending, in_progress, completed, failed")
+	}
+	if params.EndDate != nil {
+		// 包含结束日期当天
+		end := params.EndDate.AddDate(0, 0, 1).Add(-1)
+		params.EndDate = &end
+	}
+	q.Check(params.StartDate == nil || params.EndDate == nil || !params.EndDate.Before(*params.StartDate), "endDate", "must not be before startDate")
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+	return h.downloadService.List(params)
+}
+
+// V2GetDownload 处理 GET /api/v2/downloads/{id}
+func (h *ConsoleAPIHandler) V2GetDownload(r *http.Request) (interface{}, error) {
+	return h.v2DownloadRecord(r.PathValue("id"))
+}
+
+// V2ProbeDownload 处理 POST /api/v2/downloads/{id}/probe - 用 ffprobe 重新探测编码信息
+func (h *ConsoleAPIHandler) V2ProbeDownload(r *http.Request) (interface{}, error) {
+	id := r.PathValue("id")
+	if _, err := h.v2DownloadRecord(id); err != nil {
+		return nil, err
+	}
+	if !h.mediaProbeService.IsAvailable() {
+		return nil, response.NewAPIError(http.StatusServiceUnavailable, response.CodeUnavailable, "ffprobe not available")
+	}
+	return h.mediaProbeService.ProbeRecord(r.Context(), id)
+}
+
+// V2DeleteDownload 处理 DELETE /api/v2/downloads/{id}?deleteFiles= - 移入回收站
+func (h *ConsoleAPIHandler) V2DeleteDownload(r *http.Request) (interface{}, error) {
+	q := newV2Query(r)
+	deleteFiles := q.Bool("deleteFiles")
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+
+	id := r.PathValue("id")
+	if _, err := h.v2DownloadRecord(id); err != nil {
+		return nil, err
+	}
+	if err := h.downloadService.Delete(id, deleteFiles); err != nil {
+		return nil, err
+	}
+	h.auditService.RecordRequest(r, &database.AuditEntry{
+		Action:     "downloads.delete",
+		TargetType: "download_record",
+		TargetIDs:  []string{id},
+		Detail:     fmt.Sprintf("deleteFiles=%t", deleteFiles),
+	})
+	return v2DeleteResult{Deleted: 1}, nil
+}
+
+// V2DeleteDownloadsMany 处理 DELETE /api/v2/downloads - 按 ids 批量删除或 all 清空
+func (h *ConsoleAPIHandler) V2DeleteDownloadsMany(r *http.Request) (interface{}, error) {
+	var req v2DeleteRequest
+	if err := decodeV2Body(r, &req); err != nil {
+		return nil, err
+	}
+
+	if req.All {
+		if err := h.downloadService.Clear(req.DeleteFiles); err != nil {
+			return nil, err
+		}
+		h.auditService.RecordRequest(r, &database.AuditEntry{
+			Action:     "downloads.clear",
+			TargetType: "download_record",
+			Detail:     fmt.Sprintf("deleteFiles=%t", req.DeleteFiles),
+		})
+		return v2DeleteResult{Cleared: true}, nil
+	}
+
+	count, err := h.downloadService.DeleteMany(req.IDs, req.DeleteFiles)
+	if err != nil {
+		return nil, err
+	}
+	h.auditService.RecordRequest(r, &database.AuditEntry{
+		Action:     "downloads.delete",
+		TargetType: "download_record",
+		TargetIDs:  req.IDs,
+		Detail:     fmt.Sprintf("deleted=%d deleteFiles=%t", count, req.DeleteFiles),
+	})
+	return v2DeleteResult{Deleted: count}, nil
+}
+
+// v2DownloadRecord 获取下载记录，不存在时返回 not_found
+func (h *ConsoleAPIHandler) v2DownloadRecord(id string) (*database.DownloadRecord, error) {
+	record, err := h.downloadService.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, v2NotFound("download record not found")
+	}
+	return record, nil
+}
+
+// ---------------------------------------------------------------------------
+// 下载队列
+// ---------------------------------------------------------------------------
+
+// V2ListQueue 处理 GET /api/v2/queue
+func (h *ConsoleAPIHandler) V2ListQueue(r *http.Request) (interface{}, error) {
+	return h.queueService.GetQueue()
+}
+
+// V2AddToQueue 处理 POST /api/v2/queue - 返回新建的队列项
+func (h *ConsoleAPIHandler) V2AddToQueue(r *http.Request) (interface{}, error) {
+	var req v2QueueAddRequest
+	if err := decodeV2Body(r, &req); err != nil {
+		return nil, err
+	}
+
+	items, err := h.queueService.AddToQueueAs(services.InitiatedBy(r.Context()), req.Videos)
+	if err != nil {
+		return nil, err
+	}
+	hub := GetWebSocketHub()
+	for i := range items {
+		hub.BroadcastQueueAdd(&items[i])
+	}
+	return items, nil
+}
+
+// V2PauseQueueItem 处理 POST /api/v2/queue/{id}/pause - 只能暂停下载中的项目
+func (h *ConsoleAPIHandler) V2PauseQueueItem(r *http.Request) (interface{}, error) {
+	id := r.PathValue("id")
+	if err := h.queueService.Pause(id); err != nil {
+		return nil, err
+	}
+	return h.v2BroadcastQueueItem(id)
+}
+
+// V2ResumeQueueItem 处理 POST /api/v2/queue/{id}/resume - 只能恢复暂停的项目
+func (h *ConsoleAPIHandler) V2ResumeQueueItem(r *http.Request) (interface{}, error) {
+	id := r.PathValue("id")
+	if err := h.queueService.Resume(id); err != nil {
+		return nil, err
+	}
+	return h.v2BroadcastQueueItem(id)
+}
+
+// V2RemoveQueueItem 处理 DELETE /api/v2/queue/{id}
+func (h *ConsoleAPIHandler) V2RemoveQueueItem(r *http.Request) (interface{}, error) {
+	id := r.PathValue("id")
+	if err := h.queueService.RemoveFromQueue(id); err != nil {
+		return nil, err
+	}
+	h.auditService.RecordRequest(r, &database.AuditEntry{
+		Action:     "queue.remove",
+		TargetType: "queue_item",
+		TargetIDs:  []string{id},
+	})
+	GetWebSocketHub().BroadcastQueueRemove(id)
+	return v2DeleteResult{Deleted: 1}, nil
+}
+
+// V2ReorderQueue 处理 PUT /api/v2/queue/order - 返回重新排列后的队列
+func (h *ConsoleAPIHandler) V2ReorderQueue(r *http.Request) (interface{}, error) {
+	var req v2QueueReorderRequest
+	if err := decodeV2Body(r, &req); err != nil {
+		return nil, err
+	}
+
+	if err := h.queueService.Reorder(req.IDs); err != nil {
+		return nil, err
+	}
+	queue, err := h.queueService.GetQueue()
+	if err != nil {
+		return nil, err
+	}
+	GetWebSocketHub().BroadcastQueueReorder(queue)
+	return queue, nil
+}
+
+// v2BroadcastQueueItem 广播并返回更新后的队列项
+func (h *ConsoleAPIHandler) v2BroadcastQueueItem(id string) (interface{}, error) {
+	item, err := h.queueService.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, v2NotFound("queue item not found")
+	}
+	GetWebSocketHub().BroadcastQueueUpdate(item)
+	return item, nil
+}
+
+// ---------------------------------------------------------------------------
+// 设置、统计和搜索
+// ---------------------------------------------------------------------------
+
+// V2GetSettings 处理 GET /api/v2/settings
+func (h *ConsoleAPIHandler) V2GetSettings(r *http.Request) (interface{}, error) {
+	return h.settingsRepo.Load()
+}
+
+// V2UpdateSettings 处理 PUT /api/v2/settings - 返回保存后的设置
+func (h *ConsoleAPIHandler) V2UpdateSettings(r *http.Request) (interface{}, error) {
+	var req v2SettingsRequest
+	if err := decodeV2Body(r, &req); err != nil {
+		return nil, err
+	}
+
+	previous, err := h.settingsRepo.Load()
+	if err != nil {
+		return nil, err
+	}
+	if err := h.settingsRepo.Save(&req.Settings); err != nil {
+		return nil, err
+	}
+	if before, after := services.AuditChanges(previous, &req.Settings); after != nil {
+		h.auditService.RecordRequest(r, &database.AuditEntry{
+			Action:     "settings.update",
+			TargetType: "settings",
+			Before:     before,
+			After:      after,
+		})
+	}
+	return &req.Settings, nil
+}
+
+// V2GetStats 处理 GET /api/v2/stats
+func (h *ConsoleAPIHandler) V2GetStats(r *http.Request) (interface{}, error) {
+	return h.statsService.GetStatistics()
+}
+
+// V2GetStatsChart 处理 GET /api/v2/stats/chart?days= - 最近 days 天（1-30，默认 7）的下载数量
+func (h *ConsoleAPIHandler) V2GetStatsChart(r *http.Request) (interface{}, error) {
+	q := newV2Query(r)
+	days := q.Int("days", 7, 1, 30)
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+	return h.statsService.GetChartData(days)
+}
+
+// V2Search 处理 GET /api/v2/search?q=&limit= - 在浏览和下载记录中全局搜索
+func (h *ConsoleAPIHandler) V2Search(r *http.Request) (interface{}, error) {
+	q := newV2Query(r)
+	query := q.String("q")
+	limit := q.Int("limit", 20, 1, 100)
+	q.Check(utf8.RuneCountInString(query) >= 2, "q", "must be at least 2 characters")
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+	return h.searchService.Search(query, limit)
+}
@@ -10,10 +10,9 @@ import (
 	"strings"
 
 	"wx_channel/internal/config"
+	"wx_channel/internal/services"
 	"wx_channel/internal/utils"
 
-	"wx_channel/pkg/util"
-
 	"github.com/qtgolang/SunnyNet/SunnyNet"
 	sunnyPublic "github.com/qtgolang/SunnyNet/public"
 )
@@ -36,6 +35,8 @@ type ScriptHandler struct {
 	apiClientJS     []byte
 	keepAliveJS     []byte
 	version         string
+	cfg             *config.Config
+	patchService    *services.ScriptPatchService
 }
 
 // NewScriptHandler 创建脚本处理器
@@ -57,12 +58,17 @@ func NewScriptHandler(cfg *config.Config, coreJS, decryptJS, downloadJS, homeJS,
 		apiClientJS:     apiClientJS,
 		keepAliveJS:     keepAliveJS,
 		version:         version,
+		cfg:             cfg,
+		patchService:    services.GetScriptPatchService(cfg),
 	}
 }
 
-// getConfig 获取当前配置（动态获取最新配置）
+// getConfig 获取当前配置（动态获取最新配置），全局配置未加载时使用创建时传入的配置
 func (h *ScriptHandler) getConfig() *config.Config {
-	return config.Get()
+	if cfg := config.Current(); cfg != nil {
+		return cfg
+	}
+	return h.cfg
 }
 
 // Handle implements router.Interceptor
@@ -131,6 +137,7 @@ func (h *ScriptHandler) HandleHTMLResponse(Conn *SunnyNet.HttpConn, host, path s
 	if host == "channels.weixin.qq.com" && (path == "/web/pages/feed" || path == "/web/pages/home" || path == "/web/pages/profile" || path == "/web/pages/s") {
 		// 根据页面路径注入不同的脚本
 		injectedScripts := h.buildInjectedScripts(path)
+		h.patchService.BeginPageLoad(path)
 		html = strings.Replace(html, "<head>", "<head>\n"+injectedScripts, 1)
 		utils.LogFileInfo("页面已成功加载！")
 		utils.LogFileInfo("已添加视频缓存监控和提醒功能")
@@ -169,26 +176,10 @@ func (h *ScriptHandler) HandleJavaScriptResponse(Conn *SunnyNet.HttpConn, host,
 	content = importReg.ReplaceAllString(content, `import"$1.js`+h.version+`"`)
 	Conn.Response.Header.Set("__debug", "replace_script")
 
-	// 处理不同的JS文件
-	content, handled := h.handleIndexPublish(path, content)
-	if handled {
-		Conn.Response.Body = io.NopCloser(bytes.NewBuffer([]byte(content)))
-		return true
-	}
-	content, handled = h.handleVirtualSvgIcons(path, content)
-	if handled {
-		Conn.Response.Body = io.NopCloser(bytes.NewBuffer([]byte(content)))
-		return true
-	}
-
-	content, handled = h.handleWorkerRelease(path, content)
-	if handled {
-		Conn.Response.Body = io.NopCloser(bytes.NewBuffer([]byte(content)))
-		return true
-	}
-	content, handled = h.handleConnectPublish(Conn, path, content)
-	if handled {
-		return true
+	// 按补丁规则改写微信前端 JS
+	content, headers, _ := h.patchService.Apply(path, content)
+	for key, value := range headers {
+		Conn.Response.Header.Set(key, value)
 	}
 
 	Conn.Response.Body = io.NopCloser(bytes.NewBuffer([]byte(content)))
@@ -1221,177 +1212,6 @@ func (h *ScriptHandler) getVideoCacheNotificationScript() string {
 	</script>`
 }
 
-// handleIndexPublish 处理index.publish JS文件
-func (h *ScriptHandler) handleIndexPublish(path string, content string) (string, bool) {
-	if !util.Includes(path, "/t/wx_fed/finder/web/web-finder/res/js/index.publish") {
-		return content, false
-	}
-
-	utils.LogFileInfo("[Home数据采集] 正在处理 index.publish 文件")
-
-	regexp1 := regexp.MustCompile(`this.sourceBuffer.appendBuffer\(h\),`)
-	replaceStr1 := `(() => {
-if (window.__wx_channels_store__) {
-window.__wx_channels_store__.buffers.push(h);
-// 添加缓存监控
-if (window.__wx_channels_video_cache_monitor) {
-    window.__wx_channels_video_cache_monitor.addBuffer(h);
-}
-}
-})(),this.sourceBuffer.appendBuffer(h),`
-	if regexp1.MatchString(content) {
-		utils.LogFileInfo("视频播放已成功加载！")
-		utils.LogFileInfo("视频缓冲将被监控，完成时会有提醒")
-		utils.LogFileInfo("[视频播放] 视频播放器已加载 | Path=%s", path)
-	}
-	content = regexp1.ReplaceAllString(content, replaceStr1)
-	regexp2 := regexp.MustCompile(`if\(f.cmd===re.MAIN_THREAD_CMD.AUTO_CUT`)
-	replaceStr2 := `if(f.cmd==="CUT"){
-	if (window.__wx_channels_store__) {
-	// console.log("CUT", f, __wx_channels_store__.profile.key);
-	window.__wx_channels_store__.keys[__wx_channels_store__.profile.key]=f.decryptor_array;
-	}
-}
-if(f.cmd===re.MAIN_THREAD_CMD.AUTO_CUT`
-	content = regexp2.ReplaceAllString(content, replaceStr2)
-
-	return content, true
-}
-
-// handleVirtualSvgIcons 处理virtual_svg-icons-register JS文件
-func (h *ScriptHandler) handleVirtualSvgIcons(path string, content string) (string, bool) {
-	if !util.Includes(path, "/t/wx_fed/finder/web/web-finder/res/js/virtual_svg-icons-register") {
-		return content, false
-	}
-
-	// 拦截 finderPcFlow - 首页推荐视频列表（参考 wx_channels_download 项目）
-	pcFlowRegex := regexp.MustCompile(`(?s)async\s+finderPcFlow\s*\(([^)]+)\)\s*\{(.*?)\}\s*async`)
-	if pcFlowRegex.MatchString(content) {
-		utils.LogFileInfo("[API拦截] ✅ 在virtual_svg-icons-register中成功拦截 finderPcFlow 函数")
-		pcFlowReplace := `async finderPcFlow($1){var result=await(async()=>{$2})();if(result&&result.data&&result.data.object){var feeds=result.data.object;console.log("[API拦截] finderPcFlow 触发 PCFlowLoaded",feeds.length);WXU.emit(WXU.Events.PCFlowLoaded,{feeds:feeds,params:$1});}return result;}async`
-		content = pcFlowRegex.ReplaceAllString(content, pcFlowReplace)
-	}
-
-	// 拦截 finderStream - 另一种首页推荐列表
-	streamRegex := regexp.MustCompile(`(?s)async\s+finderStream\s*\(([^)]+)\)\s*\{(.*?)\}\s*async`)
-	if streamRegex.MatchString(content) {
-		utils.LogFileInfo("[API拦截] ✅ 在virtual_svg-icons-register中成功拦截 finderStream 函数")
-		streamReplace := `async finderStream($1){var result=await(async()=>{$2})();if(result&&result.data&&result.data.object){var feeds=result.data.object;console.log("[API拦截] finderStream 触发 PCFlowLoaded",feeds.length);WXU.emit(WXU.Events.PCFlowLoaded,{feeds:feeds,params:$1});}return result;}async`
-		content = streamRegex.ReplaceAllString(content, streamReplace)
-	}
-
-	// 拦截 finderGetCommentDetail - 视频详情（参考 wx_channels_download 项目）
-	feedProfileRegex := regexp.MustCompile(`(?s)async\s+finderGetCommentDetail\s*\(([^)]+)\)\s*\{(.*?)\}\s*async`)
-	if feedProfileRegex.MatchString(content) {
-		utils.LogFileInfo("[API拦截] ✅ 在virtual_svg-icons-register中成功拦截 finderGetCommentDetail 函数")
-		feedProfileReplace := `async finderGetCommentDetail($1){var result=await(async()=>{$2})();var feed=result.data.object;console.log("[API拦截] finderGetCommentDetail 触发 FeedProfileLoaded");WXU.emit(WXU.Events.FeedProfileLoaded,feed);return result;}async`
-		content = feedProfileRegex.ReplaceAllString(content, feedProfileReplace)
-	}
-
-	// 拦截 Profile 页面的视频列表数据 - 使用事件系统（参考 wx_channels_download 项目）
-	profileListRegex := regexp.MustCompile(`(?s)async\s+finderUserPage\s*\(([^)]+)\)\s*\{return(.*?)\}\s*async`)
-	if profileListRegex.MatchString(content) {
-		utils.LogFileInfo("[API拦截] ✅ 在virtual_svg-icons-register中成功拦截 finderUserPage 函数")
-		profileListReplace := `async finderUserPage($1){console.log("[Profile API] finderUserPage 调用参数:",$1);var result=await(async()=>{return$2})();console.log("[Profile API] finderUserPage 原始结果:",result);if(result&&result.data&&result.data.object){var feeds=result.data.object;console.log("[Profile API] 提取到",feeds.length,"个视频");WXU.emit(WXU.Events.UserFeedsLoaded,feeds);}else{console.warn("[Profile API] result.data.object 为空",result);}return result;}async`
-		content = profileListRegex.ReplaceAllString(content, profileListReplace)
-	}
-
-	// 拦截 Profile 页面的直播回放列表数据 - 使用事件系统
-	liveListRegex := regexp.MustCompile(`(?s)async\s+finderLiveUserPage\s*\(([^)]+)\)\s*\{return(.*?)\}\s*async`)
-	if liveListRegex.MatchString(content) {
-		utils.LogFileInfo("[API拦截] ✅ 在virtual_svg-icons-register中成功拦截 finderLiveUserPage 函数")
-		liveListReplace := `async finderLiveUserPage($1){console.log("[Profile API] finderLiveUserPage 调用参数:",$1);var result=await(async()=>{return$2})();console.log("[Profile API] finderLiveUserPage 原始结果:",result);if(result&&result.data&&result.data.object){var feeds=result.data.object;console.log("[Profile API] 提取到",feeds.length,"个直播回放");WXU.emit(WXU.Events.UserLiveReplayLoaded,feeds);}else{console.warn("[Profile API] result.data.object 为空",result);}return result;}async`
-		content = liveListRegex.ReplaceAllString(content, liveListReplace)
-	}
-
-	// 拦截分类视频列表API - finderGetRecommend（首页、美食、生活等分类tab）
-	categoryFeedsRegex := regexp.MustCompile(`(?s)async\s+finderGetRecommend\s*\(([^)]+)\)\s*\{(.*?)\}\s*async`)
-	if categoryFeedsRegex.MatchString(content) {
-		utils.LogFileInfo("[API拦截] ✅ 在virtual_svg-icons-register中成功拦截 finderGetRecommend 函数")
-		categoryFeedsReplace := `async finderGetRecommend($1){var result=await(async()=>{$2})();if(result&&result.data&&result.data.object){var feeds=result.data.object;WXU.emit(WXU.Events.CategoryFeedsLoaded,{feeds:feeds,params:$1});}return result;}async`
-		content = categoryFeedsRegex.ReplaceAllString(content, categoryFeedsReplace)
-	}
-
-	// 拦截搜索API - finderPCSearch（PC端搜索）
-	// 函数格式: async finderPCSearch(n){...return(...),t}async
-	// 在最后的 return 之前插入代码，然后保持 ,t}async 不变
-	searchPCRegex := regexp.MustCompile(`(async finderPCSearch\([^)]+\)\{.*?)(,t\}async)`)
-
-	if searchPCRegex.MatchString(content) {
-		utils.LogFileInfo("[API拦截] ✅ 在virtual_svg-icons-register中成功拦截 finderPCSearch 函数")
-		// 在 ,t 之前插入代码，保持 ,t}async 完整
-		// 从 acctList 中提取正在直播的账号，添加调试日志
-		searchPCReplace := `$1,t&&t.data&&(function(){var lives=t.data.liveObjectList||[];var accounts=[];var liveCount=0;if(t.data.acctList){t.data.acctList.forEach(function(info){if(info.liveStatus===1){liveCount++;console.log("[搜索API] 发现直播账号:",info.contact?info.contact.nickname:"未知",info.liveStatus,info.liveInfo);}if(info.liveStatus===1&&info.liveInfo){lives.push({id:info.contact.username,objectId:info.contact.username,nickname:info.contact.nickname,username:info.contact.username,description:info.liveInfo.description||"",streamUrl:info.liveInfo.streamUrl,coverUrl:info.liveInfo.media&&info.liveInfo.media[0]?info.liveInfo.media[0].thumbUrl:"",thumbUrl:info.liveInfo.media&&info.liveInfo.media[0]?info.liveInfo.media[0].thumbUrl:"",liveInfo:info.liveInfo,type:"live"});}accounts.push(info);});}if(liveCount>0){console.log("[搜索API] 共发现",liveCount,"个直播账号，成功提取",lives.length,"个");}var searchData={feeds:t.data.objectList||[],accounts:accounts,lives:lives};WXU.emit("SearchResultLoaded",searchData);})()$2`
-		content = searchPCRegex.ReplaceAllString(content, searchPCReplace)
-	} else {
-		utils.LogFileInfo("[API拦截] ❌ 在virtual_svg-icons-register中未找到 finderPCSearch 函数")
-	}
-
-	// 拦截搜索API - finderSearch（移动端搜索）
-	// 使用非贪婪匹配，匹配到最后的 ,t}async 模式
-	searchRegex := regexp.MustCompile(`(async finderSearch\([^)]+\)\{.*?)(,t\}async)`)
-
-	if searchRegex.MatchString(content) {
-		utils.LogFileInfo("[API拦截] ✅ 在virtual_svg-icons-register中成功拦截 finderSearch 函数")
-		// 从 infoList 中提取正在直播的账号，添加调试日志
-		searchReplace := `$1,t&&t.data&&(function(){var lives=[];var accounts=[];var liveCount=0;if(t.data.infoList){t.data.infoList.forEach(function(info){if(info.liveStatus===1){liveCount++;console.log("[搜索API] 发现直播账号:",info.contact?info.contact.nickname:"未知",info.liveStatus,info.liveInfo);}if(info.liveStatus===1&&info.liveInfo){lives.push({id:info.contact.username,objectId:info.contact.username,nickname:info.contact.nickname,username:info.contact.username,description:info.liveInfo.description||"",streamUrl:info.liveInfo.streamUrl,coverUrl:info.liveInfo.media&&info.liveInfo.media[0]?info.liveInfo.media[0].thumbUrl:"",thumbUrl:info.liveInfo.media&&info.liveInfo.media[0]?info.liveInfo.media[0].thumbUrl:"",liveInfo:info.liveInfo,type:"live"});}accounts.push(info);});}if(liveCount>0){console.log("[搜索API] 共发现",liveCount,"个直播账号，成功提取",lives.length,"个");}var searchData={feeds:t.data.objectList||[],accounts:accounts,lives:lives};WXU.emit("SearchResultLoaded",searchData);})()$2`
-		content = searchRegex.ReplaceAllString(content, searchReplace)
-	} else {
-		utils.LogFileInfo("[API拦截] ❌ 在virtual_svg-icons-register中未找到 finderSearch 函数")
-	}
-
-	// 拦截 export 语句，提取所有导出的 API 函数
-	// 格式: export{xxx as yyy,zzz as www,...}
-	exportBlockRegex := regexp.MustCompile(`export\s*\{([^}]+)\}`)
-	exportRegex := regexp.MustCompile(`export\s*\{`)
-
-	if exportBlockRegex.MatchString(content) {
-		utils.LogFileInfo("[API拦截] ✅ 在virtual_svg-icons-register中找到 export 语句")
-
-		// 提取 export 块中的内容
-		matches := exportBlockRegex.FindStringSubmatch(content)
-		if len(matches) >= 2 {
-			exportContent := matches[1]
-			utils.LogFileInfo("[API拦截] Export 内容: %s", exportContent[:min(100, len(exportContent))])
-
-			// 解析导出的函数名
-			items := strings.Split(exportContent, ",")
-			var locals []string
-			for _, item := range items {
-				p := strings.TrimSpace(item)
-				if p == "" {
-					continue
-				}
-				// 处理 "xxx as yyy" 格式
-				idx := strings.Index(p, " as ")
-				local := p
-				if idx != -1 {
-					local = strings.TrimSpace(p[:idx])
-				}
-				if local != "" && local != " " {
-					locals = append(locals, local)
-				}
-			}
-
-			if len(locals) > 0 {
-				utils.LogFileInfo("[API拦截] 提取到 %d 个导出函数", len(locals))
-				apiMethods := "{" + strings.Join(locals, ",") + "}"
-				// 转义 $ 符号
-				apiMethodsEscaped := strings.ReplaceAll(apiMethods, "$", "$$")
-
-				// 在 export 之前插入 API 加载事件
-				jsWXAPI := ";WXU.emit(WXU.Events.APILoaded," + apiMethodsEscaped + ");export{"
-				content = exportRegex.ReplaceAllString(content, jsWXAPI)
-				utils.LogFileInfo("[API拦截] ✅ 已注入 APILoaded 事件")
-			}
-		}
-	} else {
-		utils.LogFileInfo("[API拦截] ❌ 在virtual_svg-icons-register中未找到 export 语句")
-	}
-
-	return content, true
-}
-
 // min 返回两个整数中的较小值
 func min(a, b int) int {
 	if a < b {
@@ -1400,71 +1220,6 @@ func min(a, b int) int {
 	return b
 }
 
-// handleWorkerRelease 处理worker_release JS文件
-func (h *ScriptHandler) handleWorkerRelease(path string, content string) (string, bool) {
-	if !util.Includes(path, "worker_release") {
-		return content, false
-	}
-
-	regex := regexp.MustCompile(`fmp4Index:p.fmp4Index`)
-	replaceStr := `decryptor_array:p.decryptor_array,fmp4Index:p.fmp4Index`
-	content = regex.ReplaceAllString(content, replaceStr)
-	return content, true
-}
-
-// handleConnectPublish 处理connect.publish JS文件（参考 wx_channels_download 项目的实现）
-func (h *ScriptHandler) handleConnectPublish(Conn *SunnyNet.HttpConn, path string, content string) (string, bool) {
-	if !util.Includes(path, "connect.publish") {
-		return content, false
-	}
-
-	utils.LogFileInfo("[Home数据采集] ✅ 正在处理 connect.publish 文件")
-
-	// 首先找到 flowTab 对应的变量名（可能是 yt, nn 或其他）
-	// 格式: flowTab:变量名,flowTabId:
-	flowTabReg := regexp.MustCompile(`flowTab:([a-zA-Z]{1,}),flowTabId:`)
-	flowTabVar := "yt" // 默认值
-	if matches := flowTabReg.FindStringSubmatch(content); len(matches) > 1 {
-		flowTabVar = matches[1]
-		utils.LogFileInfo("[Home数据采集] ✅ 找到 flowTab 变量名: %s", flowTabVar)
-	} else {
-		utils.LogFileInfo("[Home数据采集] ⚠️ 未找到 flowTab 变量名，使用默认值: %s", flowTabVar)
-	}
-
-	// 参考 wx_channels_download 项目的正则表达式，匹配函数定义而不是函数调用
-	// 原始代码格式: goToNextFlowFeed:函数名 或 goToPrevFlowFeed:函数名
-	goToNextFlowReg := regexp.MustCompile(`goToNextFlowFeed:([a-zA-Z]{1,})`)
-	goToPrevFlowReg := regexp.MustCompile(`goToPrevFlowFeed:([a-zA-Z]{1,})`)
-
-	// 替换 goToNextFlowFeed 函数定义 - 使用 WXU.emit 发送事件（与 wx_channels_download 完全一致）
-	if goToNextFlowReg.MatchString(content) {
-		utils.LogFileInfo("[Home数据采集] ✅ 在connect.publish中成功拦截 goToNextFlowFeed 函数定义")
-		// 使用动态获取的 flowTab 变量名
-		jsGoNextFeed := fmt.Sprintf("goToNextFlowFeed:async function(v){await $1(v);console.log('goToNextFlowFeed',%s);if(!%s||!%s.value.feeds){return;}var feed=%s.value.feeds[%s.value.currentFeedIndex];console.log('before GotoNextFeed',%s,feed);WXU.emit(WXU.Events.GotoNextFeed,feed);}", flowTabVar, flowTabVar, flowTabVar, flowTabVar, flowTabVar, flowTabVar)
-		content = goToNextFlowReg.ReplaceAllString(content, jsGoNextFeed)
-	} else {
-		utils.LogFileInfo("[Home数据采集] ❌ 在connect.publish中未找到 goToNextFlowFeed 函数定义")
-	}
-
-	// 替换 goToPrevFlowFeed 函数定义 - 使用 WXU.emit 发送事件
-	if goToPrevFlowReg.MatchString(content) {
-		utils.LogFileInfo("[Home数据采集] ✅ 在connect.publish中成功拦截 goToPrevFlowFeed 函数定义")
-		// 使用动态获取的 flowTab 变量名
-		jsGoPrevFeed := fmt.Sprintf("goToPrevFlowFeed:async function(v){await $1(v);console.log('goToPrevFlowFeed',%s);if(!%s||!%s.value.feeds){return;}var feed=%s.value.feeds[%s.value.currentFeedIndex];console.log('before GotoPrevFeed',%s,feed);WXU.emit(WXU.Events.GotoPrevFeed,feed);}", flowTabVar, flowTabVar, flowTabVar, flowTabVar, flowTabVar, flowTabVar)
-		content = goToPrevFlowReg.ReplaceAllString(content, jsGoPrevFeed)
-	} else {
-		utils.LogFileInfo("[Home数据采集] ❌ 在connect.publish中未找到 goToPrevFlowFeed 函数定义")
-	}
-
-	// 禁用浏览器缓存，确保每次都能拦截到最新的代码
-	Conn.Response.Header.Set("Cache-Control", "no-cache, no-store, must-revalidate")
-	Conn.Response.Header.Set("Pragma", "no-cache")
-	Conn.Response.Header.Set("Expires", "0")
-
-	Conn.Response.Body = io.NopCloser(bytes.NewBuffer([]byte(content)))
-	return content, true
-}
-
 // getCommentCaptureScript 获取评论采集脚本 (优化版 - 基于 Pinia 订阅)
 func (h *ScriptHandler) getCommentCaptureScript() string {
 	return `<script>
@@ -1577,7 +1332,7 @@ func (h *ScriptHandler) getCommentCaptureScript() string {
 	}
 
 	// 保存评论数据到后端
-	function saveComments(comments, totalExpected) {
+	function saveComments(comments, totalExpected, isComplete) {
 		if (!comments || comments.length === 0) return;
 		
 		var store = findFeedStore();
@@ -1601,7 +1356,7 @@ func (h *ScriptHandler) getCommentCaptureScript() string {
 				videoTitle: videoInfo.title,
 				originalCommentCount: totalExpected || 0,
 				timestamp: Date.now(),
-				isFullUpdate: true
+				isFullUpdate: !!isComplete
 			})
 		}).catch(function(err) {
 			console.error('[评论采集] 保存失败:', err);
@@ -1620,7 +1375,7 @@ func (h *ScriptHandler) getCommentCaptureScript() string {
 		var delay = isComplete ? 1000 : 5000;
 		
 		saveDebounceTimer = setTimeout(function() {
-			saveComments(comments, totalCount);
+			saveComments(comments, totalCount, isComplete);
 		}, delay);
 	}
 
@@ -2061,7 +1816,7 @@ func (h *ScriptHandler) getCommentCaptureScript() string {
 							// 强制保存最终结果
 							var finalItems = store.commentList.dataList.items;
 							var finalFormatted = formatComments(finalItems);
-							saveComments(finalFormatted, total);
+							saveComments(finalFormatted, total, true);
 
 							// 输出详细的二级回复报告
 							verifyCommentAllExpanded(finalItems);
@@ -2083,7 +1838,7 @@ func (h *ScriptHandler) getCommentCaptureScript() string {
 								// 强制保存最终结果 (即使是不完整的)
 								var finalItems = store.commentList.dataList.items;
 								var finalFormatted = formatComments(finalItems);
-								saveComments(finalFormatted, total);
+								saveComments(finalFormatted, total, false);
 
 								// 输出详细的二级回复报告
 								verifyCommentAllExpanded(finalItems);
@@ -2121,11 +1876,11 @@ func (h *ScriptHandler) getCommentCaptureScript() string {
 			        }, 1500 + Math.random() * 1000); // 1.5-2.5秒间隔
 			    } else {
 					// 用户选择不继续，保存当前数据
-					saveComments(formatted, total);
+					saveComments(formatted, total, false);
 					alert('已保存当前采集的 ' + stats.total + ' 条评论。');
 				}
 			} else {
-				saveComments(formatted, total);
+				saveComments(formatted, total, true);
 			    alert('正在保存评论...\n已加载: ' + stats.total + '\n总数: ' + total + '\n(已全部加载完成)');
 			}
 		} else {
@@ -30,12 +30,16 @@ import (
 
 // UploadHandler 文件上传处理器
 type UploadHandler struct {
-	downloadService *services.DownloadRecordService
-	gopeedService   *services.GopeedService // Injected Gopeed Service
-	chunkSem        chan struct{}
-	mergeSem        chan struct{}
-	wsHub           *websocket.Hub
-	activeDownloads sync.Map // map[string]context.CancelFunc
+	downloadService   *services.DownloadRecordService
+	thumbnailService  *services.ThumbnailService
+	mediaProbeService *services.MediaProbeService
+	gopeedService     *services.GopeedService // Injected Gopeed Service
+	chunkSem          chan struct{}
+	mergeSem          chan struct{}
+	wsHub             *websocket.Hub
+	urlRefresher      *services.VideoURLRefresher
+	formatService     *services.VideoFormatService
+	activeDownloads   sync.Map // map[string]context.CancelFunc
 }
 
 // NewUploadHandler 创建上传处理器
@@ -49,11 +53,15 @@ func NewUploadHandler(cfg *config.Config, wsHub *websocket.Hub, gopeedService *s
 		mg = 1
 	}
 	return &UploadHandler{
-		downloadService: services.NewDownloadRecordService(),
-		gopeedService:   gopeedService,
-		chunkSem:        make(chan struct{}, ch),
-		mergeSem:        make(chan struct{}, mg),
-		wsHub:           wsHub,
+		downloadService:   services.NewDownloadRecordService(),
+		thumbnailService:  services.NewThumbnailService(),
+		mediaProbeService: services.NewMediaProbeService(),
+		gopeedService:     gopeedService,
+		chunkSem:          make(chan struct{}, ch),
+		mergeSem:          make(chan struct{}, mg),
+		wsHub:             wsHub,
+		urlRefresher:      services.NewVideoURLRefresher(wsHub),
+		formatService:     services.NewVideoFormatService(),
 	}
 }
 
@@ -993,11 +1001,13 @@ func (h *UploadHandler) HandleDownloadVideo(Conn *SunnyNet.HttpConn) bool {
 		Title        string `json:"title"`
 		Author       string `json:"author"`
 		Key          string `json:"key"`        // 解密key（可选）
+		NonceID      string `json:"nonceId"`    // objectNonceId（可选），链接过期时用于重新获取
 		ForceSave    bool   `json:"forceSave"`  // 是否强制保存（即使文件已存在）
 		Resolution   string `json:"resolution"` // 分辨率字符串（如 "1080x1920" 或 "1080p"）
 		Width        int    `json:"width"`      // 视频宽度（可选）
 		Height       int    `json:"height"`     // 视频高度（可选）
 		FileFormat   string `json:"fileFormat"` // 文件格式（如 "hd", "sd" 等）
+		Quality      string `json:"quality"`    // 画质策略（可选，如 "best"、"<=720p"）
 		LikeCount    int64  `json:"likeCount"`
 		CommentCount int64  `json:"commentCount"`
 		ForwardCount int64  `json:"forwardCount"`
@@ -1015,6 +1025,21 @@ func (h *UploadHandler) HandleDownloadVideo(Conn *SunnyNet.HttpConn) bool {
 		return true
 	}
 
+	// 页面未指定编码或请求指定了画质策略时，按策略选择编码
+	if req.Quality != "" || services.VideoURLFormat(req.VideoURL) == "" {
+		videoURL, format, err := h.formatService.Resolve(req.VideoID, req.VideoURL, req.Quality)
+		if err != nil {
+			h.sendErrorResponse(Conn, fmt.Errorf("画质参数无效: %w", err))
+			return true
+		}
+		if format != nil {
+			req.VideoURL = videoURL
+			req.FileFormat = format.FileFormat
+			req.Width, req.Height = format.Width, format.Height
+			utils.Info("📐 [视频下载] 按画质策略选择编码: %s (%dx%d)", format.FileFormat, format.Width, format.Height)
+		}
+	}
+
 	// 创建作者目录
 	authorFolder := utils.CleanFolderName(req.Author)
 	if authorFolder == "" {
@@ -1194,7 +1219,19 @@ func (h *UploadHandler) HandleDownloadVideo(Conn *SunnyNet.HttpConn) bool {
 		connections = cfg.DownloadConnections
 	}
 
+	// 链接中的过期时间已过时，下载前先刷新链接
+	if services.VideoURLExpired(req.VideoURL, time.Now()) {
+		h.refreshVideoURL(&req.VideoURL, &req.Key, req.VideoID, req.NonceID)
+	}
+
 	err = h.gopeedService.DownloadSync(downloadCtx, req.VideoURL, tmpPath, connections, onProgress)
+	// 链接过期（403/404）时重新获取链接后重试一次
+	if err != nil && downloadCtx.Err() == nil && h.urlRefresher.Expired(downloadCtx, req.VideoURL) &&
+		h.refreshVideoURL(&req.VideoURL, &req.Key, req.VideoID, req.NonceID) {
+		os.Remove(tmpPath)
+		err = h.gopeedService.DownloadSync(downloadCtx, req.VideoURL, tmpPath, connections, onProgress)
+	}
+	needDecrypt = req.Key != ""
 	if err != nil {
 		utils.Error("❌ [视频下载] Gopeed 下载失败: %v", err)
 		h.sendErrorResponse(Conn, fmt.Errorf("下载失败: %v", err))
@@ -1260,6 +1297,8 @@ func (h *UploadHandler) HandleDownloadVideo(Conn *SunnyNet.HttpConn) bool {
 			utils.Error("保存下载记录失败: %v", err)
 		} else {
 			utils.Info("已保存下载记录: %s", record.Title)
+			h.mediaProbeService.ProbeRecordAsync(record.ID)
+			h.thumbnailService.GenerateAsync(record.ID)
 		}
 	}
 
@@ -1684,6 +1723,21 @@ func (h *UploadHandler) sendJSONResponse(Conn *SunnyNet.HttpConn, statusCode int
 	Conn.StopRequest(statusCode, string(respBytes), headers)
 }
 
+// refreshVideoURL 通过视频详情重新获取过期的下载链接和密钥，成功时更新 videoURL 和 key
+func (h *UploadHandler) refreshVideoURL(videoURL, key *string, videoID, nonceID string) bool {
+	if h.urlRefresher == nil || videoID == "" {
+		return false
+	}
+	newURL, newKey, err := h.urlRefresher.Refresh(videoID, nonceID, *videoURL, *key)
+	if err != nil {
+		utils.Warn("⚠️ [视频下载] 刷新过期链接失败: %v", err)
+		return false
+	}
+	*videoURL = newURL
+	*key = newKey
+	return true
+}
+
 // sendErrorResponse 发送错误响应
 func (h *UploadHandler) sendErrorResponse(Conn *SunnyNet.HttpConn, err error) {
 	headers := http.Header{}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"wx_channel/internal/database"
+	"wx_channel/internal/response"
+	"wx_channel/internal/services"
+)
+
+// v2MaxBodySize /api/v2 请求体的大小上限
+const v2MaxBodySize = 1 << 20
+
+// V2HandlerFunc /api/v2 的处理函数：返回的数据以 {"data": ...} 输出；
+// 错误为 *response.APIError 时按其状态码和错误码输出，其他错误按 toAPIError 转换
+type V2HandlerFunc func(r *http.Request) (interface{}, error)
+
+// ServeHTTP 实现 http.Handler
+func (f V2HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	data, err := f(r)
+	if err != nil {
+		response.V2Error(w, toAPIError(err))
+		return
+	}
+	response.V2Success(w, http.StatusOK, data)
+}
+
+// toAPIError 将服务层的错误转换为 /api/v2 错误
+func toAPIError(err error) *response.APIError {
+	var apiErr *response.APIError
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr
+	case errors.Is(err, services.ErrQueueItemNotFound):
+		return response.NewAPIError(http.StatusNotFound, response.CodeNotFound, err.Error())
+	case errors.Is(err, services.ErrQueueItemState):
+		return response.NewAPIError(http.StatusConflict, response.CodeConflict, err.Error())
+	default:
+		return response.NewAPIError(http.StatusInternalServerError, response.CodeInternal, err.Error())
+	}
+}
+
+// v2NotFound 资源不存在
+func v2NotFound(message string) *response.APIError {
+	return response.NewAPIError(http.StatusNotFound, response.CodeNotFound, message)
+}
+
+// v2Validator 收集字段校验错误
+type v2Validator struct {
+	errs []response.FieldError
+}
+
+// Check ok 为 false 时记录字段错误
+func (v *v2Validator) Check(ok bool, field, message string) {
+	if !ok {
+		v.errs = append(v.errs, response.FieldError{Field: field, Message: message})
+	}
+}
+
+// Err 有字段错误时返回 validation_failed
+func (v *v2Validator) Err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return &response.APIError{
+		Status:  http.StatusUnprocessableEntity,
+		Code:    response.CodeValidationFailed,
+		Message: "request validation failed",
+		Details: v.errs,
+	}
+}
+
+// v2Request /api/v2 的请求体 DTO
+type v2Request interface {
+	validate(v *v2Validator)
+}
+
+// decodeV2Body 解析请求体并校验：JSON 格式错误、未知字段或多余内容返回 invalid_request，
+// 校验失败返回 validation_failed
+func decodeV2Body(r *http.Request, req v2Request) error {
+	dec := json.NewDecoder(io.LimitReader(r.Body, v2MaxBodySize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(req); err != nil {
+		message := "invalid request body: " + err.Error()
+		if errors.Is(err, io.EOF) {
+			message = "request body is required"
+		}
+		return response.NewAPIError(http.StatusBadRequest, response.CodeInvalidRequest, message)
+	}
+	if dec.More() {
+		return response.NewAPIError(http.StatusBadRequest, response.CodeInvalidRequest, "invalid request body: unexpected data after JSON value")
+	}
+
+	var v v2Validator
+	req.validate(&v)
+	return v.Err()
+}
+
+// v2Query 解析查询参数，格式错误记为字段错误
+type v2Query struct {
+	values url.Values
+	v2Validator
+}
+
+func newV2Query(r *http.Request) *v2Query {
+	return &v2Query{values: r.URL.Query()}
+}
+
+// String 返回去掉首尾空白的参数值
+func (q *v2Query) String(name string) string {
+	return strings.TrimSpace(q.values.Get(name))
+}
+
+// Int 解析整数参数，未提供时返回 def，超出 [min, max] 时记为错误
+func (q *v2Query) Int(name string, def, min, max int) int {
+	raw := q.String(name)
+	if raw == "" {
+		return def
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		q.Check(false, name, "must be an integer")
+		return def
+	}
+	q.Check(n >= min && n <= max, name, fmt.Sprintf("must be between %d and %d", min, max))
+	return n
+}
+
+// Bool 解析布尔参数，未提供时返回 false
+func (q *v2Query) Bool(name string) bool {
+	raw := q.String(name)
+	if raw == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(raw)
+	q.Check(err == nil, name, "must be true or false")
+	return b
+}
+
+// Date 解析 YYYY-MM-DD 格式的日期参数，未提供时返回 nil
+func (q *v2Query) Date(name string) *time.Time {
+	raw := q.String(name)
+	if raw == "" {
+		return nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", raw, time.Local)
+	if err != nil {
+		q.Check(false, name, "must be a date in YYYY-MM-DD format")
+		return nil
+	}
+	return &t
+}
+
+// Pagination 解析分页参数：page ≥ 1，1 ≤ pageSize ≤ 100，sortBy 必须是 columns 中的列
+func (q *v2Query) Pagination(columns map[string]bool, defaultSort string) *database.PaginationParams {
+	params := &database.PaginationParams{
+		Page:     q.Int("page", 1, 1, 1<<30),
+		PageSize: q.Int("pageSize", 20, 1, 100),
+		SortBy:   defaultSort,
+		SortDesc: true,
+	}
+	if sortBy := q.String("sortBy"); sortBy != "" {
+		q.Check(columns[sortBy], "sortBy", "unsupported sort column")
+		params.SortBy = sortBy
+	}
+	if q.String("sortDesc") != "" {
+		params.SortDesc = q.Bool("sortDesc")
+	}
+	return params
+}
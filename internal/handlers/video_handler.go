@@ -12,6 +12,7 @@ import (
 
 	"wx_channel/internal/database"
 	"wx_channel/internal/response"
+	"wx_channel/internal/services"
 	"wx_channel/internal/utils"
 
 	"github.com/fatih/color"
@@ -152,6 +153,14 @@ func (h *APIHandler) processVideoData(data map[string]interface{}) {
 		decryptKey = fmt.Sprintf("%.0f", k)
 	}
 
+	// 提取 objectNonceId（链接过期后用于重新获取视频详情）
+	nonceID := ""
+	if n, ok := data["nonce_id"].(string); ok {
+		nonceID = n
+	} else if n, ok := data["objectNonceId"].(string); ok {
+		nonceID = n
+	}
+
 	// 提取分辨率信息：优先从media直接获取宽x高格式
 	resolution := ""
 	// 前端发送的media是单个对象，不是数组
@@ -180,13 +189,27 @@ func (h *APIHandler) processVideoData(data map[string]interface{}) {
 		utils.LogInfo("[分辨率] 未能获取分辨率信息")
 	}
 
+	// 提取所有可选编码（下载时按画质策略选择）
+	var formats []database.VideoFormat
+	if spec, ok := data["spec"].([]interface{}); ok {
+		formats = services.ParseVideoFormats(spec)
+	}
+	if len(formats) == 0 {
+		if mediaItem, ok := data["media"].(map[string]interface{}); ok {
+			if spec, ok := mediaItem["spec"].([]interface{}); ok {
+				formats = services.ParseVideoFormats(spec)
+			}
+		}
+	}
+
 	pageUrl := h.currentURL
 
 	utils.LogInfo("[视频信息] ID=%s | 标题=%s | 作者=%s | 大小=%.2fMB | URL=%s | Key=%s | 分辨率=%s",
 		videoID, title, author, sizeMB, url, decryptKey, resolution)
 
 	// 保存浏览记录到数据库
-	h.saveBrowseRecord(videoID, title, author, authorID, duration, size, coverUrl, url, decryptKey, resolution, likeCount, commentCount, favCount, forwardCount, pageUrl)
+	h.saveBrowseRecord(videoID, title, author, authorID, duration, size, coverUrl, url, decryptKey, nonceID, resolution, likeCount, commentCount, favCount, forwardCount, pageUrl)
+	h.saveVideoFormats(videoID, formats)
 
 	color.Yellow("\n")
 
@@ -271,8 +294,18 @@ func (h *APIHandler) processVideoData(data map[string]interface{}) {
 	color.Yellow("\n\n")
 }
 
+// saveVideoFormats 保存视频的可选编码
+func (h *APIHandler) saveVideoFormats(videoID string, formats []database.VideoFormat) {
+	if videoID == "" || len(formats) == 0 || database.GetDB() == nil {
+		return
+	}
+	if err := services.NewVideoFormatService().Save(videoID, formats); err != nil {
+		utils.Warn("保存视频编码失败: %v", err)
+	}
+}
+
 // saveBrowseRecord 保存浏览记录到数据库
-func (h *APIHandler) saveBrowseRecord(videoID, title, author, authorID string, duration, size int64, coverUrl, videoUrl, decryptKey, resolution string, likeCount, commentCount, favCount, forwardCount int64, pageUrl string) {
+func (h *APIHandler) saveBrowseRecord(videoID, title, author, authorID string, duration, size int64, coverUrl, videoUrl, decryptKey, nonceID, resolution string, likeCount, commentCount, favCount, forwardCount int64, pageUrl string) {
 	// 检查数据库是否已初始化
 	db := database.GetDB()
 	if db == nil {
@@ -303,6 +336,7 @@ func (h *APIHandler) saveBrowseRecord(videoID, title, author, authorID string, d
 		FavCount:     favCount,
 		ForwardCount: forwardCount,
 		PageURL:      pageUrl,
+		NonceID:      nonceID,
 	}
 
 	// 保存到数据库
@@ -325,6 +359,9 @@ func (h *APIHandler) saveBrowseRecord(videoID, title, author, authorID string, d
 			// 保留现有的解密密钥
 			record.DecryptKey = existing.DecryptKey
 		}
+		if record.NonceID == "" {
+			record.NonceID = existing.NonceID
+		}
 		err = repo.Update(record)
 		if err != nil {
 			utils.Warn("更新浏览记录失败: %v", err)
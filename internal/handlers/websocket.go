@@ -22,6 +22,7 @@ const (
 	MessageTypeDownloadProgress = "download_progress"
 	MessageTypeQueueChange      = "queue_change"
 	MessageTypeStatsUpdate      = "stats_update"
+	MessageTypeWatchNewVideos   = "watch_new_videos"
 	MessageTypePing             = "ping"
 	MessageTypePong             = "pong"
 	WSMessageTypeCommand        = "cmd"
@@ -95,6 +96,9 @@ type WebSocketHub struct {
 
 	// 用于队列更新的队列服务
 	queueService *services.QueueService
+
+	// 同时向 SSE 订阅者发布事件
+	events *services.EventBus
 }
 
 // 全局 WebSocket Hub 实例
@@ -121,6 +125,7 @@ func NewWebSocketHub() *WebSocketHub {
 		unregister:   make(chan *WebSocketClient),
 		statsService: services.NewStatisticsService(),
 		queueService: services.NewQueueService(),
+		events:       services.GetEventBus(),
 	}
 }
 
@@ -176,12 +181,12 @@ func (h *WebSocketHub) startStatsUpdateBroadcaster() {
 	defer ticker.Stop()
 
 	for range ticker.C {
-		// 仅当有已连接客户端时才广播
+		// 仅当有已连接客户端或 SSE 订阅者时才广播
 		h.mu.RLock()
 		clientCount := len(h.clients)
 		h.mu.RUnlock()
 
-		if clientCount > 0 {
+		if clientCount > 0 || h.events.SubscriberCount() > 0 {
 			h.BroadcastStatsUpdate()
 		}
 	}
@@ -238,6 +243,36 @@ func (h *WebSocketHub) BroadcastMessage(message interface{}) error {
 	return nil
 }
 
+// broadcastEvent 向所有客户端广播消息，同时发布到 SSE 事件总线
+func (h *WebSocketHub) broadcastEvent(eventType string, message interface{}) error {
+	h.events.Publish(eventType, message)
+	return h.BroadcastMessage(message)
+}
+
+// WatchNewVideosMessage 表示关注作者发布了新视频
+type WatchNewVideosMessage struct {
+	Type   string                `json:"type"`
+	Author *database.WatchAuthor `json:"author"`
+	Videos []database.WatchVideo `json:"videos"`
+	Queued int                   `json:"queued"`
+}
+
+// BroadcastWatchNewVideos 广播关注作者的新视频，自动入队的项目同时广播队列变更
+func (h *WebSocketHub) BroadcastWatchNewVideos(event services.WatchEvent) {
+	msg := WatchNewVideosMessage{
+		Type:   MessageTypeWatchNewVideos,
+		Author: event.Author,
+		Videos: event.Videos,
+		Queued: len(event.Queued),
+	}
+	if err := h.broadcastEvent(MessageTypeWatchNewVideos, msg); err != nil {
+		utils.Warn("[WebSocket] Failed to broadcast watch videos: %v", err)
+	}
+	for i := range event.Queued {
+		h.BroadcastQueueAdd(&event.Queued[i])
+	}
+}
+
 // BroadcastDownloadProgress 向所有客户端广播下载进度
 func (h *WebSocketHub) BroadcastDownloadProgress(queueID string, downloaded, total, speed int64, status string, chunks, chunksDone int) {
 	msg := DownloadProgressMessage{
@@ -250,7 +285,7 @@ func (h *WebSocketHub) BroadcastDownloadProgress(queueID string, downloaded, tot
 		Chunks:     chunks,
 		ChunksDone: chunksDone,
 	}
-	if err := h.BroadcastMessage(msg); err != nil {
+	if err := h.broadcastEvent(MessageTypeDownloadProgress, msg); err != nil {
 		utils.Warn("[WebSocket] Failed to broadcast download progress: %v", err)
 	}
 }
@@ -262,7 +297,7 @@ func (h *WebSocketHub) BroadcastQueueAdd(item *database.QueueItem) {
 		Action: QueueActionAdd,
 		Item:   item,
 	}
-	if err := h.BroadcastMessage(msg); err != nil {
+	if err := h.broadcastEvent(MessageTypeQueueChange, msg); err != nil {
 		utils.Warn("[WebSocket] Failed to broadcast queue add: %v", err)
 	}
 }
@@ -274,7 +309,7 @@ func (h *WebSocketHub) BroadcastQueueRemove(itemID string) {
 		Action: QueueActionRemove,
 		Item:   &database.QueueItem{ID: itemID},
 	}
-	if err := h.BroadcastMessage(msg); err != nil {
+	if err := h.broadcastEvent(MessageTypeQueueChange, msg); err != nil {
 		utils.Warn("[WebSocket] Failed to broadcast queue remove: %v", err)
 	}
 }
@@ -286,7 +321,7 @@ func (h *WebSocketHub) BroadcastQueueUpdate(item *database.QueueItem) {
 		Action: QueueActionUpdate,
 		Item:   item,
 	}
-	if err := h.BroadcastMessage(msg); err != nil {
+	if err := h.broadcastEvent(MessageTypeQueueChange, msg); err != nil {
 		utils.Warn("[WebSocket] Failed to broadcast queue update: %v", err)
 	}
 }
@@ -298,7 +333,7 @@ func (h *WebSocketHub) BroadcastQueueReorder(queue []database.QueueItem) {
 		Action: QueueActionReorder,
 		Queue:  queue,
 	}
-	if err := h.BroadcastMessage(msg); err != nil {
+	if err := h.broadcastEvent(MessageTypeQueueChange, msg); err != nil {
 		utils.Warn("[WebSocket] Failed to broadcast queue reorder: %v", err)
 	}
 }
@@ -315,7 +350,7 @@ func (h *WebSocketHub) BroadcastStatsUpdate() {
 		Type:  MessageTypeStatsUpdate,
 		Stats: stats,
 	}
-	if err := h.BroadcastMessage(msg); err != nil {
+	if err := h.broadcastEvent(MessageTypeStatsUpdate, msg); err != nil {
 		utils.Warn("[WebSocket] Failed to broadcast stats update: %v", err)
 	}
 }
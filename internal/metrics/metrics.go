@@ -77,4 +77,30 @@ var (
 		Name: "wx_channel_active_requests_per_client",
 		Help: "每个客户端的活跃请求数",
 	}, []string{"client_id"})
+
+	// 页面 API 节流指标
+	RateLimitThrottledTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "wx_channel_rate_limit_throttled_total",
+		Help: "因节流而等待的页面 API 调用次数",
+	}, []string{"key"})
+
+	RateLimitWaiting = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "wx_channel_rate_limit_waiting",
+		Help: "正在等待节流的页面 API 调用数",
+	}, []string{"key"})
+
+	RateLimitBackoffSeconds = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "wx_channel_rate_limit_backoff_seconds",
+		Help: "页面 API 当前的退避时间（秒）",
+	}, []string{"key"})
+
+	RateLimitConsecutiveErrors = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "wx_channel_rate_limit_consecutive_errors",
+		Help: "页面 API 连续返回错误码的次数",
+	}, []string{"key"})
+
+	RateLimitClientInflight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "wx_channel_rate_limit_client_inflight",
+		Help: "每个页面正在处理的 API 调用数",
+	}, []string{"client_id"})
 )
@@ -0,0 +1,58 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ErrorCode /api/v2 的错误码，客户端应按错误码而不是错误信息处理错误
+type ErrorCode string
+
+const (
+	CodeInvalidRequest   ErrorCode = "invalid_request"    // 请求无法解析：JSON 格式错误、未知字段、参数类型错误
+	CodeValidationFailed ErrorCode = "validation_failed"  // 参数校验失败，details 列出出错的字段
+	CodeUnauthorized     ErrorCode = "unauthorized"       // 未携带令牌或令牌无效
+	CodeForbidden        ErrorCode = "forbidden"          // 令牌缺少所需的权限范围
+	CodeNotFound         ErrorCode = "not_found"          // 路径或资源不存在
+	CodeMethodNotAllowed ErrorCode = "method_not_allowed" // 路径存在但不支持该方法，Allow 头列出支持的方法
+	CodeConflict         ErrorCode = "conflict"           // 资源当前的状态不允许该操作
+	CodeUnavailable      ErrorCode = "unavailable"        // 依赖的外部程序或服务不可用
+	CodeInternal         ErrorCode = "internal_error"     // 服务端错误
+)
+
+// FieldError 单个字段的校验错误
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// APIError /api/v2 的错误，以 {"error": {...}} 返回
+type APIError struct {
+	Status  int          `json:"-"`
+	Code    ErrorCode    `json:"code"`
+	Message string       `json:"message"`
+	Details []FieldError `json:"details,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	return string(e.Code) + ": " + e.Message
+}
+
+// NewAPIError 创建 /api/v2 错误
+func NewAPIError(status int, code ErrorCode, message string) *APIError {
+	return &APIError{Status: status, Code: code, Message: message}
+}
+
+// V2Success 返回 /api/v2 成功响应：{"data": ...}
+func V2Success(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
+
+// V2Error 返回 /api/v2 错误响应：{"error": {"code", "message", "details"}}
+func V2Error(w http.ResponseWriter, err *APIError) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(err.Status)
+	json.NewEncoder(w).Encode(map[string]*APIError{"error": err})
+}
@@ -2,17 +2,53 @@ package router
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"io"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"wx_channel/internal/utils"
 
 	"github.com/qtgolang/SunnyNet/SunnyNet"
 )
 
-// SunnyNetResponseWriter adapts SunnyNet.HttpConn to http.ResponseWriter
+// streamThreshold is the largest body that is buffered and sent with a
+// Content-Length; anything bigger is streamed to SunnyNet as it is written.
+const streamThreshold = 256 * 1024
+
+// streamWriteTimeout bounds how long a single streamed write may wait for
+// SunnyNet to read. When it expires the stream is aborted and the request
+// context cancelled, so the handler goroutine cannot block forever.
+var streamWriteTimeout = time.Minute
+
+var (
+	errStreamStalled     = errors.New("streamed response stalled: body is not being read")
+	errStreamUnsupported = errors.New("streamed response not supported: no response to attach the body to")
+)
+
+// SunnyNetResponseWriter adapts SunnyNet.HttpConn to http.ResponseWriter.
+//
+// Small responses are buffered and handed to SunnyNet in one StopRequest call.
+// Once the body grows past streamThreshold, or the handler calls Flush, the
+// headers are committed and the rest of the body is piped to SunnyNet while
+// the handler keeps writing, so large files, Range playback and event streams
+// are not held in memory.
 type SunnyNetResponseWriter struct {
 	conn       *SunnyNet.HttpConn
 	headers    http.Header
 	statusCode int
 	body       bytes.Buffer
+
+	mu          sync.Mutex
+	wroteHeader bool
+	closed      bool
+	pipe        *io.PipeWriter // non-nil once the response is streaming
+	reader      *io.PipeReader // read end handed to SunnyNet
+	committed   chan struct{}  // closed when the response has been handed to SunnyNet
+	cancel      context.CancelFunc
 }
 
 // NewSunnyNetResponseWriter creates a new SunnyNetResponseWriter
@@ -21,23 +57,172 @@ func NewSunnyNetResponseWriter(conn *SunnyNet.HttpConn) *SunnyNetResponseWriter
 		conn:       conn,
 		headers:    make(http.Header),
 		statusCode: http.StatusOK,
+		committed:  make(chan struct{}),
+		cancel:     func() {},
 	}
 }
 
+// Serve runs handler for the connection's request and returns once the
+// response has been handed to SunnyNet. Streamed bodies keep being written in
+// the background until the handler returns; the request context is cancelled
+// when the client stops reading.
+func (w *SunnyNetResponseWriter) Serve(handler http.Handler) {
+	ctx, cancel := context.WithCancel(w.conn.Request.Context())
+	w.cancel = cancel
+	req := w.conn.Request.WithContext(ctx)
+
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				utils.GetLogger().Error("Panic recovered in streamed response: %v, path: %s", err, req.URL.Path)
+			}
+			w.Close()
+		}()
+		handler.ServeHTTP(w, req)
+	}()
+	<-w.committed
+}
+
 func (w *SunnyNetResponseWriter) Header() http.Header {
 	return w.headers
 }
 
 func (w *SunnyNetResponseWriter) Write(data []byte) (int, error) {
-	return w.body.Write(data)
+	w.mu.Lock()
+	w.wroteHeader = true
+	if w.closed {
+		w.mu.Unlock()
+		return 0, io.ErrClosedPipe
+	}
+	if w.pipe == nil {
+		if w.body.Len()+len(data) <= streamThreshold {
+			n, err := w.body.Write(data)
+			w.mu.Unlock()
+			return n, err
+		}
+		w.startStreamLocked()
+	}
+	pipe, reader := w.pipe, w.reader
+	w.mu.Unlock()
+
+	// Blocks until SunnyNet has read the data, which keeps memory flat. If
+	// nothing reads within streamWriteTimeout the stream is aborted instead.
+	timer := time.AfterFunc(streamWriteTimeout, func() {
+		utils.Warn("[API] 流式响应长时间未被读取，已中止: %s", w.conn.Request.URL.Path)
+		reader.CloseWithError(errStreamStalled)
+		w.cancel()
+	})
+	defer timer.Stop()
+	return pipe.Write(data)
 }
 
 func (w *SunnyNetResponseWriter) WriteHeader(statusCode int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
 	w.statusCode = statusCode
 }
 
-// Flush sends the response to SunnyNet
+// Flush implements http.Flusher: it commits the headers and switches the
+// response to streaming mode. Later writes go straight to the client.
 func (w *SunnyNetResponseWriter) Flush() {
-	// SunnyNet's StopRequest will interrupt the processing and send the response
-	w.conn.StopRequest(w.statusCode, w.body.String(), w.headers)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed || w.pipe != nil {
+		return
+	}
+	w.wroteHeader = true
+	w.startStreamLocked()
+}
+
+// Close finishes the response: a buffered body is sent with its
+// Content-Length, a streamed body is terminated.
+func (w *SunnyNetResponseWriter) Close() {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return
+	}
+	w.closed = true
+	pipe := w.pipe
+	if pipe == nil {
+		// SunnyNet's StopRequest will interrupt the processing and send the response
+		length := strconv.Itoa(w.body.Len())
+		w.headers.Set("Content-Length", length)
+		w.conn.StopRequest(w.statusCode, w.body.String(), w.headers)
+		if resp := w.conn.Response; resp != nil {
+			resp.ContentLength = int64(w.body.Len())
+			resp.Header.Set("Content-Length", length)
+		}
+		close(w.committed)
+	}
+	w.mu.Unlock()
+
+	if pipe != nil {
+		pipe.Close()
+	}
+	w.cancel()
+}
+
+// startStreamLocked hands the headers to SunnyNet with a body that yields the
+// buffered bytes followed by everything written afterwards. Caller holds w.mu.
+//
+// This relies on SunnyNet v1.0.3 (see go.mod): StopRequest only stores
+// conn.Response, and the response is written to the client after the
+// HttpSendRequest callback returns, reading Body until EOF. Replacing Body
+// here, before Serve returns to the callback, is therefore what gets sent.
+// If a SunnyNet version serializes the response inside StopRequest or never
+// reads the replaced Body, writes fail with errStreamUnsupported or
+// errStreamStalled instead of leaking the handler goroutine.
+func (w *SunnyNetResponseWriter) startStreamLocked() {
+	pr, pw := io.Pipe()
+	w.pipe = pw
+	w.reader = pr
+
+	header := w.headers.Clone()
+	contentLength := int64(-1)
+	if v := header.Get("Content-Length"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			contentLength = n
+		}
+	}
+
+	w.conn.StopRequest(w.statusCode, "", header)
+	if resp := w.conn.Response; resp != nil {
+		buffered := append([]byte(nil), w.body.Bytes()...)
+		w.body.Reset()
+		resp.Body = &streamBody{
+			Reader: io.MultiReader(bytes.NewReader(buffered), pr),
+			pipe:   pr,
+			cancel: w.cancel,
+		}
+		resp.ContentLength = contentLength
+		if contentLength < 0 {
+			resp.Header.Del("Content-Length")
+			resp.TransferEncoding = []string{"chunked"}
+		} else {
+			resp.Header.Set("Content-Length", strconv.FormatInt(contentLength, 10))
+		}
+	} else {
+		pr.CloseWithError(errStreamUnsupported)
+		w.cancel()
+	}
+	close(w.committed)
+}
+
+// streamBody is the response body SunnyNet reads from in streaming mode.
+// Closing it (the client went away) fails pending writes and cancels the
+// request context so the handler can stop early.
+type streamBody struct {
+	io.Reader
+	pipe   *io.PipeReader
+	cancel context.CancelFunc
+}
+
+func (b *streamBody) Close() error {
+	b.cancel()
+	return b.pipe.Close()
 }
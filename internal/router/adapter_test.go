@@ -0,0 +1,154 @@
+package router
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qtgolang/SunnyNet/SunnyNet"
+	"github.com/qtgolang/SunnyNet/public"
+)
+
+func serveSunnyNet(t *testing.T, req *http.Request, handler http.HandlerFunc) *http.Response {
+	t.Helper()
+	conn := &SunnyNet.HttpConn{Type: public.HttpSendRequest, Request: req}
+	NewSunnyNetResponseWriter(conn).Serve(handler)
+	if conn.Response == nil {
+		t.Fatal("Expected response to be set")
+	}
+	return conn.Response
+}
+
+func TestSunnyNetResponseWriterBuffered(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://127.0.0.1/api/health", nil)
+	resp := serveSunnyNet(t, req, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"ok":true}`))
+	})
+
+	if resp.StatusCode != http.StatusCreated || resp.ContentLength != int64(len(`{"ok":true}`)) {
+		t.Errorf("Unexpected buffered response: %d, length %d", resp.StatusCode, resp.ContentLength)
+	}
+	if body, _ := io.ReadAll(resp.Body); string(body) != `{"ok":true}` {
+		t.Errorf("Unexpected body %q", body)
+	}
+}
+
+func TestSunnyNetResponseWriterStreamsLargeBody(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), streamThreshold/8)
+	req, _ := http.NewRequest("GET", "http://127.0.0.1/api/export", nil)
+	resp := serveSunnyNet(t, req, func(w http.ResponseWriter, r *http.Request) {
+		for chunk := data; len(chunk) > 0; {
+			n := min(len(chunk), 32*1024)
+			if _, err := w.Write(chunk[:n]); err != nil {
+				return
+			}
+			chunk = chunk[n:]
+		}
+	})
+
+	if resp.ContentLength != -1 || resp.Header.Get("Content-Length") != "" {
+		t.Errorf("Expected chunked response, got length %d", resp.ContentLength)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || !bytes.Equal(body, data) {
+		t.Errorf("Streamed body mismatch: %d bytes (%v)", len(body), err)
+	}
+}
+
+func TestSunnyNetResponseWriterFlush(t *testing.T) {
+	release := make(chan struct{})
+	done := make(chan error, 1)
+	req, _ := http.NewRequest("GET", "http://127.0.0.1/api/events", nil)
+	resp := serveSunnyNet(t, req, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: 1\n\n"))
+		w.(http.Flusher).Flush()
+		<-release
+		w.Write([]byte("data: 2\n\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+		done <- r.Context().Err()
+	})
+
+	// Serve 在 Flush 之后即返回，处理函数仍在运行
+	buf := make([]byte, len("data: 1\n\n"))
+	if _, err := io.ReadFull(resp.Body, buf); err != nil || string(buf) != "data: 1\n\n" {
+		t.Fatalf("Expected first event, got %q (%v)", buf, err)
+	}
+	close(release)
+	if _, err := io.ReadFull(resp.Body, buf); err != nil || string(buf) != "data: 2\n\n" {
+		t.Fatalf("Expected second event, got %q (%v)", buf, err)
+	}
+
+	// 客户端断开时取消请求上下文
+	resp.Body.Close()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Expected request context to be cancelled")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Handler was not cancelled after the body was closed")
+	}
+}
+
+func TestSunnyNetResponseWriterRange(t *testing.T) {
+	data := strings.Repeat("x", 2*streamThreshold)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "video.mp4", time.Time{}, strings.NewReader(data))
+	}
+
+	req, _ := http.NewRequest("GET", "http://127.0.0.1/api/video/stream", nil)
+	req.Header.Set("Range", "bytes=100-199")
+	resp := serveSunnyNet(t, req, handler)
+	if resp.StatusCode != http.StatusPartialContent || resp.Header.Get("Content-Range") != "bytes 100-199/524288" {
+		t.Errorf("Unexpected range response: %d %q", resp.StatusCode, resp.Header.Get("Content-Range"))
+	}
+	if body, _ := io.ReadAll(resp.Body); len(body) != 100 {
+		t.Errorf("Expected 100 bytes, got %d", len(body))
+	}
+
+	// 超过缓冲上限时流式返回，保留处理函数设置的 Content-Length
+	req, _ = http.NewRequest("GET", "http://127.0.0.1/api/video/stream", nil)
+	req.Header.Set("Range", "bytes=0-")
+	resp = serveSunnyNet(t, req, handler)
+	if resp.StatusCode != http.StatusPartialContent || resp.ContentLength != int64(len(data)) {
+		t.Errorf("Unexpected streamed range response: %d, length %d", resp.StatusCode, resp.ContentLength)
+	}
+	if body, _ := io.ReadAll(resp.Body); len(body) != len(data) {
+		t.Errorf("Expected %d bytes, got %d", len(data), len(body))
+	}
+}
+
+func TestSunnyNetResponseWriterStalledReader(t *testing.T) {
+	defer func(d time.Duration) { streamWriteTimeout = d }(streamWriteTimeout)
+	streamWriteTimeout = 50 * time.Millisecond
+
+	done := make(chan error, 1)
+	req, _ := http.NewRequest("GET", "http://127.0.0.1/api/export", nil)
+	serveSunnyNet(t, req, func(w http.ResponseWriter, r *http.Request) {
+		data := make([]byte, 32*1024)
+		for {
+			if _, err := w.Write(data); err != nil {
+				<-r.Context().Done()
+				done <- err
+				return
+			}
+		}
+	})
+
+	// 响应体始终没有被读取：写入超时后失败，处理函数退出而不是永久阻塞
+	select {
+	case err := <-done:
+		if err != errStreamStalled {
+			t.Errorf("Expected errStreamStalled, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Handler was still blocked after the write timeout")
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"wx_channel/internal/api"
 	"wx_channel/internal/config"
+	"wx_channel/internal/database"
 	"wx_channel/internal/handlers"
 	"wx_channel/internal/websocket"
 
@@ -24,8 +25,12 @@ type APIRouter struct {
 	proxyService       *api.ProxyService
 	certificateService *api.CertificateService
 	versionService     *api.VersionAPI
+	openAPIService     *api.OpenAPIService
+	v2mux              *http.ServeMux // /api/v2 路由，按方法和路径匹配
 	allowedOrigins     []string
 	secretToken        string
+	tokenService       TokenAuthenticator   // 数据库未初始化时为 nil，只使用 secret_token
+	userService        SessionAuthenticator // 数据库未初始化时为 nil，不支持用户登录
 }
 
 // Handle implements Interceptor
@@ -44,9 +49,8 @@ func (r *APIRouter) Handle(Conn *SunnyNet.HttpConn) bool {
 		return false
 	}
 
-	w := NewSunnyNetResponseWriter(Conn)
-	r.ServeHTTP(w, Conn.Request)
-	w.Flush()
+	// 小响应整体返回，大文件、Range 和 Flush 的响应以流式返回
+	NewSunnyNetResponseWriter(Conn).Serve(r)
 	return true
 }
 
@@ -64,10 +68,17 @@ func NewAPIRouter(cfg *config.Config, hub *websocket.Hub, sunny *SunnyNet.Sunny)
 		proxyService:       api.NewProxyService(sunny, cfg.Port),
 		certificateService: api.NewCertificateService(sunny),
 		versionService:     api.NewVersionAPI(),
+		openAPIService:     api.NewOpenAPIService(),
 		allowedOrigins:     cfg.AllowedOrigins,
 		secretToken:        cfg.SecretToken,
 	}
 
+	if database.GetDB() != nil {
+		// 与控制台处理器共用同一实例，令牌/用户变更后中间件的缓存立即失效
+		router.tokenService = router.consoleHandler.TokenService()
+		router.userService = router.consoleHandler.UserService()
+	}
+
 	router.registerRoutes()
 
 	return router
@@ -85,6 +96,7 @@ func (r *APIRouter) registerRoutes() {
 	r.proxyService.RegisterRoutes(r.mux)
 	r.certificateService.RegisterRoutes(r.mux)
 	r.versionService.RegisterRoutes(r.mux)
+	r.openAPIService.RegisterRoutes(r.mux)
 
 	// 控制台 API - 浏览历史
 	r.mux.HandleFunc("/api/browse", r.consoleHandler.HandleBrowseAPI)
@@ -111,6 +123,7 @@ func (r *APIRouter) registerRoutes() {
 
 	// 搜索
 	r.mux.HandleFunc("/api/search", r.consoleHandler.HandleSearch)
+	r.mux.HandleFunc("/api/graphql", r.consoleHandler.HandleGraphQLAPI)
 
 	// 文件操作
 	r.mux.HandleFunc("/api/files/", r.consoleHandler.HandleFilesAPI)
@@ -118,11 +131,56 @@ func (r *APIRouter) registerRoutes() {
 	// 转写 API
 	r.mux.HandleFunc("/api/transcribe/", r.consoleHandler.HandleTranscribeAPI)
 
+	// 本地缩略图
+	r.mux.HandleFunc("/api/thumbnails/", r.consoleHandler.HandleThumbnailsAPI)
+
+	// 评论查询与全文搜索
+	r.mux.HandleFunc("/api/comments", r.consoleHandler.HandleCommentsAPI)
+	r.mux.HandleFunc("/api/comments/analytics", r.consoleHandler.HandleCommentAnalytics)
+	r.mux.HandleFunc("/api/comments/runs", r.consoleHandler.HandleCommentRuns)
+	r.mux.HandleFunc("/api/comments/diff", r.consoleHandler.HandleCommentDiff)
+
+	// 脚本补丁规则
+	r.mux.HandleFunc("/api/script-rules", r.consoleHandler.HandleScriptRulesAPI)
+	r.mux.HandleFunc("/api/script-rules/", r.consoleHandler.HandleScriptRulesAPI)
+
+	// 账号视频抓取
+	r.mux.HandleFunc("/api/crawl", r.consoleHandler.HandleCrawlAPI)
+	r.mux.HandleFunc("/api/crawl/", r.consoleHandler.HandleCrawlAPI)
+
+	// 关注列表
+	r.mux.HandleFunc("/api/watch", r.consoleHandler.HandleWatchAPI)
+	r.mux.HandleFunc("/api/watch/", r.consoleHandler.HandleWatchAPI)
+
+	// API 令牌管理
+	r.mux.HandleFunc("/api/tokens", r.consoleHandler.HandleTokensAPI)
+	r.mux.HandleFunc("/api/tokens/", r.consoleHandler.HandleTokensAPI)
+
+	// 控制台用户登录与用户管理
+	r.mux.HandleFunc("/api/auth/", r.consoleHandler.HandleAuthAPI)
+	r.mux.HandleFunc("/api/users", r.consoleHandler.HandleUsersAPI)
+	r.mux.HandleFunc("/api/users/", r.consoleHandler.HandleUsersAPI)
+
+	// 出站 Webhook
+	r.mux.HandleFunc("/api/webhooks", r.consoleHandler.HandleWebhooksAPI)
+	r.mux.HandleFunc("/api/webhooks/", r.consoleHandler.HandleWebhooksAPI)
+
+	// 回收站
+	r.mux.HandleFunc("/api/trash/", r.consoleHandler.HandleTrashAPI)
+
+	// 审计记录
+	r.mux.HandleFunc("/api/audit", r.consoleHandler.HandleAuditAPI)
+
+	// 控制台事件（SSE）
+	r.mux.HandleFunc("/api/events", r.consoleHandler.HandleEventsAPI)
+
 	// 系统信息
 
 	// 控制台 API - 导出功能
 	r.mux.HandleFunc("/api/export/browse", r.exportService.HandleExportBrowseHistory)
 	r.mux.HandleFunc("/api/export/downloads", r.exportService.HandleExportDownloadRecords)
+	r.mux.HandleFunc("/api/export/comments", r.exportService.HandleExportComments)
+	r.mux.HandleFunc("/api/export/audit", r.exportService.HandleExportAudit)
 
 	// 控制台 API - 视频相关
 	r.mux.HandleFunc("/api/video/stream", r.consoleHandler.HandleVideoStream)
@@ -132,15 +190,31 @@ func (r *APIRouter) registerRoutes() {
 	r.mux.HandleFunc("/api/control/comment/start", r.consoleHandler.HandleStartCommentCollection)
 
 	// v1 版本化路由（别名）
-	r.mux.HandleFunc("/api/v1/browse", r.consoleHandler.HandleBrowseAPI)
-	r.mux.HandleFunc("/api/v1/browse/", r.consoleHandler.HandleBrowseAPI)
-	r.mux.HandleFunc("/api/v1/downloads", r.consoleHandler.HandleDownloadsAPI)
-	r.mux.HandleFunc("/api/v1/downloads/", r.consoleHandler.HandleDownloadsAPI)
-	r.mux.HandleFunc("/api/v1/queue", r.consoleHandler.HandleQueueAPI)
-	r.mux.HandleFunc("/api/v1/queue/", r.consoleHandler.HandleQueueAPI)
-	r.mux.HandleFunc("/api/v1/settings", r.consoleHandler.HandleSettingsAPI)
-	r.mux.HandleFunc("/api/v1/stats", r.consoleHandler.HandleStatsAPI)
-	r.mux.HandleFunc("/api/v1/stats/", r.consoleHandler.HandleStatsAPI)
+	r.mux.HandleFunc("/api/v1/browse", v1Alias(r.consoleHandler.HandleBrowseAPI))
+	r.mux.HandleFunc("/api/v1/browse/", v1Alias(r.consoleHandler.HandleBrowseAPI))
+	r.mux.HandleFunc("/api/v1/downloads", v1Alias(r.consoleHandler.HandleDownloadsAPI))
+	r.mux.HandleFunc("/api/v1/downloads/", v1Alias(r.consoleHandler.HandleDownloadsAPI))
+	r.mux.HandleFunc("/api/v1/queue", v1Alias(r.consoleHandler.HandleQueueAPI))
+	r.mux.HandleFunc("/api/v1/queue/", v1Alias(r.consoleHandler.HandleQueueAPI))
+	r.mux.HandleFunc("/api/v1/settings", v1Alias(r.consoleHandler.HandleSettingsAPI))
+	r.mux.HandleFunc("/api/v1/stats", v1Alias(r.consoleHandler.HandleStatsAPI))
+	r.mux.HandleFunc("/api/v1/stats/", v1Alias(r.consoleHandler.HandleStatsAPI))
+
+	// v2 路由
+	r.registerV2Routes()
+}
+
+// v1Alias 把 /api/v1/xxx 改写为 /api/xxx 再交给控制台处理器，
+// 处理器按 /api/xxx 解析子路径（如 /api/queue/:id/pause）
+func v1Alias(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		u := *req.URL
+		u.Path = "/api/" + strings.TrimPrefix(u.Path, "/api/v1/")
+		u.RawPath = ""
+		aliased := req.WithContext(req.Context())
+		aliased.URL = &u
+		next(w, aliased)
+	}
 }
 
 // Handler 返回带中间件的 HTTP Handler
@@ -151,7 +225,7 @@ func (r *APIRouter) Handler() http.Handler {
 		RecoveryMiddleware,
 		LoggerMiddleware,
 		CORSMiddleware(r.allowedOrigins),
-		AuthMiddleware(r.secretToken),
+		ConsoleAuthMiddleware(r.secretToken, r.tokenService, r.userService),
 	)
 }
 
@@ -0,0 +1,278 @@
+package router
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// HAR 1.2 格式（只包含录制与回放需要的字段）
+// 参考: http://www.softwareishard.com/blog/har-12-spec/
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog HAR 日志
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Entries []HAREntry `json:"entries"`
+}
+
+// HARCreator 录制工具信息
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HAREntry 一次请求/响应
+type HAREntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"` // 毫秒
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+// HARRequest 请求
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARResponse 响应
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARNameValue 头部 / 查询参数
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARPostData 请求体
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// HARContent 响应体，二进制内容使用 base64 编码
+type HARContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// HARTimings 时间信息（录制时只记录总耗时）
+type HARTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// NewHAR 创建一个空的 HAR
+func NewHAR(creatorVersion string) *HAR {
+	return &HAR{Log: HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: "wx_channel", Version: creatorVersion},
+		Entries: []HAREntry{},
+	}}
+}
+
+// LoadHAR 从文件读取 HAR
+func LoadHAR(path string) (*HAR, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HAR file: %w", err)
+	}
+	var har HAR
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("failed to parse HAR file: %w", err)
+	}
+	return &har, nil
+}
+
+// SaveHAR 将 HAR 写入文件（先写临时文件再重命名，避免写到一半被读取）
+func SaveHAR(path string, har *HAR) error {
+	data, err := json.MarshalIndent(har, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode HAR: %w", err)
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create HAR directory: %w", err)
+		}
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write HAR file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write HAR file: %w", err)
+	}
+	return nil
+}
+
+// NewHTTPRequest 根据 HAR 请求构造 http.Request
+func (r *HARRequest) NewHTTPRequest() (*http.Request, error) {
+	var body io.Reader = http.NoBody
+	if r.PostData != nil && r.PostData.Text != "" {
+		body = strings.NewReader(r.PostData.Text)
+	}
+	req, err := http.NewRequest(r.Method, r.URL, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request %s %s: %w", r.Method, r.URL, err)
+	}
+	for _, h := range r.Headers {
+		// HTTP/2 伪头部不属于普通请求头
+		if strings.HasPrefix(h.Name, ":") {
+			continue
+		}
+		if strings.EqualFold(h.Name, "Host") {
+			req.Host = h.Value
+			continue
+		}
+		req.Header.Add(h.Name, h.Value)
+	}
+	if r.PostData != nil && r.PostData.MimeType != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", r.PostData.MimeType)
+	}
+	return req, nil
+}
+
+// NewHTTPResponse 根据 HAR 响应构造 http.Response
+func (r *HARResponse) NewHTTPResponse(req *http.Request) (*http.Response, error) {
+	body, err := r.Content.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	header := make(http.Header)
+	for _, h := range r.Headers {
+		if strings.HasPrefix(h.Name, ":") {
+			continue
+		}
+		header.Add(h.Name, h.Value)
+	}
+	if header.Get("Content-Type") == "" && r.Content.MimeType != "" {
+		header.Set("Content-Type", r.Content.MimeType)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.Status, r.StatusText),
+		StatusCode:    r.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// Bytes 返回解码后的响应体
+func (c *HARContent) Bytes() ([]byte, error) {
+	if c.Encoding == "base64" {
+		data, err := base64.StdEncoding.DecodeString(c.Text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode HAR content: %w", err)
+		}
+		return data, nil
+	}
+	return []byte(c.Text), nil
+}
+
+// newHARContent 根据内容类型选择文本或 base64 编码
+func newHARContent(mimeType string, body []byte) HARContent {
+	content := HARContent{Size: len(body), MimeType: mimeType}
+	if isTextMimeType(mimeType) {
+		content.Text = string(body)
+	} else if len(body) > 0 {
+		content.Text = base64.StdEncoding.EncodeToString(body)
+		content.Encoding = "base64"
+	}
+	return content
+}
+
+// harSensitiveHeaders 默认不以明文录制的头部：微信会话 Cookie 和控制台令牌
+var harSensitiveHeaders = map[string]bool{
+	"Cookie":        true,
+	"Set-Cookie":    true,
+	"Authorization": true,
+	"X-Local-Auth":  true,
+}
+
+// harHeaders 将 http.Header 转为 HAR 头部列表，redact 为 true 时隐藏敏感头部的值
+func harHeaders(header http.Header, redact bool) []HARNameValue {
+	result := []HARNameValue{}
+	for name, values := range header {
+		sensitive := redact && harSensitiveHeaders[http.CanonicalHeaderKey(name)]
+		for _, v := range values {
+			if sensitive {
+				v = harRedactedValue
+			}
+			result = append(result, HARNameValue{Name: name, Value: v})
+		}
+	}
+	return result
+}
+
+// harQueryString 将查询参数转为 HAR 列表
+func harQueryString(u *url.URL) []HARNameValue {
+	result := []HARNameValue{}
+	if u == nil {
+		return result
+	}
+	for name, values := range u.Query() {
+		for _, v := range values {
+			result = append(result, HARNameValue{Name: name, Value: v})
+		}
+	}
+	return result
+}
+
+// isTextMimeType 判断内容是否可以按文本保存
+func isTextMimeType(mimeType string) bool {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(mimeType))
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/javascript", "application/x-javascript",
+		"application/xml", "application/x-www-form-urlencoded":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+}
@@ -0,0 +1,331 @@
+package router
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"wx_channel/internal/utils"
+
+	"github.com/qtgolang/SunnyNet/SunnyNet"
+)
+
+const (
+	harMaxEntries      = 2000            // 超出后丢弃最早的记录
+	harMaxBodySize     = 5 << 20         // 超过该大小的请求体/响应体不保存（视频分片等）
+	harPendingTTL      = 5 * time.Minute // 等待上游响应的最长时间
+	harSaveDebounce    = 2 * time.Second // 合并短时间内的多次写盘
+	harLocalComment    = "handled locally"
+	harTruncateComment = "body omitted: too large"
+	harStreamComment   = "body omitted: streamed response"
+	harRedactedValue   = "[redacted]"
+)
+
+// HARRecorder 将经过拦截器链的请求/响应录制为 HAR 文件，用于离线回放和回归测试。
+// 请求阶段被本地处理的请求（如 /__wx_channels_api/*）记录本地响应；
+// 其余请求记录上游返回的原始响应（拦截器改写之前）。
+type HARRecorder struct {
+	mu        sync.Mutex
+	file      string
+	hosts     []string
+	redact    bool // 隐藏 Cookie、Authorization 等敏感头部的值
+	har       *HAR
+	pending   map[*http.Request]*pendingHAREntry
+	saveTimer *time.Timer
+}
+
+type pendingHAREntry struct {
+	entry   HAREntry
+	started time.Time
+}
+
+// NewHARRecorder 创建录制器。hosts 为空时录制所有主机，否则只录制匹配（含子域名）的请求；
+// keepSensitiveHeaders 为 false 时 Cookie、Set-Cookie、Authorization 和 X-Local-Auth 的值记录为 [redacted]
+func NewHARRecorder(file string, hosts []string, keepSensitiveHeaders bool, version string) *HARRecorder {
+	har := NewHAR(version)
+	// 追加到已有的录制文件
+	if existing, err := LoadHAR(file); err == nil {
+		har.Log.Entries = existing.Log.Entries
+	}
+	return &HARRecorder{
+		file:    file,
+		hosts:   hosts,
+		redact:  !keepSensitiveHeaders,
+		har:     har,
+		pending: make(map[*http.Request]*pendingHAREntry),
+	}
+}
+
+// shouldRecord 判断请求主机是否在录制范围内
+func (r *HARRecorder) shouldRecord(req *http.Request) bool {
+	if req == nil || req.URL == nil {
+		return false
+	}
+	if len(r.hosts) == 0 {
+		return true
+	}
+	host := req.URL.Hostname()
+	if host == "" {
+		host = req.Host
+	}
+	for _, h := range r.hosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// captureRequest 在请求阶段保存请求（读取后恢复请求体，不影响后续拦截器）
+func (r *HARRecorder) captureRequest(conn *SunnyNet.HttpConn) {
+	req := conn.Request
+	if !r.shouldRecord(req) {
+		return
+	}
+
+	var body []byte
+	truncated := false
+	if req.Body != nil && req.Body != http.NoBody {
+		// 与响应体相同，多读一个字节用于判断是否超限，读取的内容会原样放回
+		data, err := io.ReadAll(io.LimitReader(req.Body, harMaxBodySize+1))
+		if err != nil {
+			utils.Warn("[HAR] 读取请求体失败: %v", err)
+		}
+		req.Body = io.NopCloser(io.MultiReader(bytes.NewReader(data), req.Body))
+		if len(data) > harMaxBodySize {
+			truncated = true
+		} else {
+			body = data
+		}
+	}
+
+	entry := HAREntry{
+		StartedDateTime: time.Now(),
+		Request: HARRequest{
+			Method:      req.Method,
+			URL:         req.URL.String(),
+			HTTPVersion: req.Proto,
+			Cookies:     []HARNameValue{},
+			Headers:     harHeaders(req.Header, r.redact),
+			QueryString: harQueryString(req.URL),
+			HeadersSize: -1,
+			BodySize:    len(body),
+		},
+	}
+	if req.Host != "" && req.Header.Get("Host") == "" {
+		entry.Request.Headers = append(entry.Request.Headers, HARNameValue{Name: "Host", Value: req.Host})
+	}
+	if truncated {
+		entry.Request.BodySize = -1
+		entry.Request.PostData = &HARPostData{
+			MimeType: req.Header.Get("Content-Type"),
+			Comment:  harTruncateComment,
+		}
+	} else if len(body) > 0 {
+		entry.Request.PostData = &HARPostData{
+			MimeType: req.Header.Get("Content-Type"),
+			Text:     string(body),
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.evictStaleLocked()
+	r.pending[req] = &pendingHAREntry{entry: entry, started: time.Now()}
+}
+
+// captureLocalResponse 记录被请求阶段拦截器直接返回的本地响应
+func (r *HARRecorder) captureLocalResponse(conn *SunnyNet.HttpConn) {
+	pending := r.takePending(conn.Request)
+	if pending == nil {
+		return
+	}
+	pending.entry.Comment = harLocalComment
+	if conn.Response != nil && conn.Response.ContentLength < 0 {
+		// 本地的流式响应边生成边发送，读取响应体会阻塞，只记录响应头
+		pending.entry.Response = harStreamResponse(conn.Response, r.redact)
+	} else if conn.Response != nil {
+		pending.entry.Response = r.buildResponse(conn.Response)
+	} else {
+		pending.entry.Response = HARResponse{Cookies: []HARNameValue{}, Headers: []HARNameValue{}, HeadersSize: -1}
+	}
+	r.add(pending)
+}
+
+// captureResponse 记录上游返回的原始响应（读取后恢复响应体）
+func (r *HARRecorder) captureResponse(conn *SunnyNet.HttpConn) {
+	if conn.Response == nil {
+		return
+	}
+	pending := r.takePending(conn.Request)
+	if pending == nil {
+		// 请求阶段未录制（例如录制开启之前发出的请求）
+		if !r.shouldRecord(conn.Request) {
+			return
+		}
+		pending = &pendingHAREntry{started: time.Now()}
+		pending.entry = HAREntry{
+			StartedDateTime: pending.started,
+			Request: HARRequest{
+				Method:      conn.Request.Method,
+				URL:         conn.Request.URL.String(),
+				HTTPVersion: conn.Request.Proto,
+				Cookies:     []HARNameValue{},
+				Headers:     harHeaders(conn.Request.Header, r.redact),
+				QueryString: harQueryString(conn.Request.URL),
+				HeadersSize: -1,
+			},
+		}
+	}
+	pending.entry.Response = r.buildResponse(conn.Response)
+	r.add(pending)
+}
+
+// harResponseHeaders 记录响应状态和响应头，不包含响应体
+func harResponseHeaders(resp *http.Response, redact bool) HARResponse {
+	result := HARResponse{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: resp.Proto,
+		Cookies:     []HARNameValue{},
+		Headers:     harHeaders(resp.Header, redact),
+		HeadersSize: -1,
+	}
+	if result.HTTPVersion == "" {
+		result.HTTPVersion = "HTTP/1.1"
+	}
+	return result
+}
+
+// harStreamResponse 记录流式响应：只有响应头，响应体标记为已省略
+func harStreamResponse(resp *http.Response, redact bool) HARResponse {
+	result := harResponseHeaders(resp, redact)
+	result.Content = HARContent{Size: -1, MimeType: resp.Header.Get("Content-Type"), Comment: harStreamComment}
+	result.BodySize = -1
+	return result
+}
+
+// harStreamMimePrefixes 长度未知时按流处理的响应类型
+var harStreamMimePrefixes = []string{
+	"text/event-stream",
+	"application/x-ndjson",
+	"multipart/x-mixed-replace",
+	"application/octet-stream",
+	"video/",
+	"audio/",
+}
+
+// isStreamResponse 判断上游响应是否为长度未知的流（SSE、音视频等）。
+// 这类响应体可能持续很久，读取会阻塞转发，因此不保存
+func isStreamResponse(resp *http.Response) bool {
+	if resp.ContentLength >= 0 {
+		return false
+	}
+	mimeType := strings.ToLower(resp.Header.Get("Content-Type"))
+	for _, prefix := range harStreamMimePrefixes {
+		if strings.HasPrefix(mimeType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildResponse 读取响应体并恢复，超过大小限制或流式响应时不保存响应体
+func (r *HARRecorder) buildResponse(resp *http.Response) HARResponse {
+	if resp.Body != nil && isStreamResponse(resp) {
+		return harStreamResponse(resp, r.redact)
+	}
+	result := harResponseHeaders(resp, r.redact)
+	mimeType := resp.Header.Get("Content-Type")
+	if resp.Body == nil {
+		result.Content = HARContent{MimeType: mimeType}
+		return result
+	}
+	if resp.ContentLength > harMaxBodySize {
+		result.Content = HARContent{Size: int(resp.ContentLength), MimeType: mimeType, Comment: harTruncateComment}
+		result.BodySize = int(resp.ContentLength)
+		return result
+	}
+
+	// 多读一个字节用于判断是否超限，读取的内容会原样放回
+	data, err := io.ReadAll(io.LimitReader(resp.Body, harMaxBodySize+1))
+	if err != nil {
+		utils.Warn("[HAR] 读取响应体失败: %v", err)
+	}
+	resp.Body = io.NopCloser(io.MultiReader(bytes.NewReader(data), resp.Body))
+	if len(data) > harMaxBodySize {
+		result.Content = HARContent{Size: -1, MimeType: mimeType, Comment: harTruncateComment}
+		result.BodySize = -1
+		return result
+	}
+
+	result.Content = newHARContent(mimeType, data)
+	result.BodySize = len(data)
+	return result
+}
+
+// takePending 取出请求阶段保存的记录
+func (r *HARRecorder) takePending(req *http.Request) *pendingHAREntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pending, ok := r.pending[req]
+	if !ok {
+		return nil
+	}
+	delete(r.pending, req)
+	return pending
+}
+
+// evictStaleLocked 清理长时间没有收到响应的请求
+func (r *HARRecorder) evictStaleLocked() {
+	for req, pending := range r.pending {
+		if time.Since(pending.started) > harPendingTTL {
+			delete(r.pending, req)
+		}
+	}
+}
+
+// add 追加一条记录并安排写盘
+func (r *HARRecorder) add(pending *pendingHAREntry) {
+	elapsed := float64(time.Since(pending.started).Microseconds()) / 1000
+	pending.entry.Time = elapsed
+	pending.entry.Timings = HARTimings{Wait: elapsed}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.har.Log.Entries = append(r.har.Log.Entries, pending.entry)
+	if over := len(r.har.Log.Entries) - harMaxEntries; over > 0 {
+		r.har.Log.Entries = r.har.Log.Entries[over:]
+	}
+	if r.saveTimer == nil {
+		r.saveTimer = time.AfterFunc(harSaveDebounce, func() {
+			if err := r.Flush(); err != nil {
+				utils.Warn("[HAR] 保存录制文件失败: %v", err)
+			}
+		})
+	}
+}
+
+// Flush 立即将录制内容写入文件
+func (r *HARRecorder) Flush() error {
+	r.mu.Lock()
+	if r.saveTimer != nil {
+		r.saveTimer.Stop()
+		r.saveTimer = nil
+	}
+	har := &HAR{Log: r.har.Log}
+	har.Log.Entries = append([]HAREntry(nil), r.har.Log.Entries...)
+	r.mu.Unlock()
+
+	return SaveHAR(r.file, har)
+}
+
+// Count 返回已录制的记录数
+func (r *HARRecorder) Count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.har.Log.Entries)
+}
@@ -0,0 +1,78 @@
+package router
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/qtgolang/SunnyNet/SunnyNet"
+	"github.com/qtgolang/SunnyNet/public"
+)
+
+// ReplayResult 一条 HAR 记录经过拦截器链后的结果
+type ReplayResult struct {
+	Entry      *HAREntry
+	Handled    bool // 是否被某个拦截器处理
+	Local      bool // 是否在请求阶段被本地处理（不会访问上游）
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Replay 将一条 HAR 记录送入拦截器链，不需要 SunnyNet 代理或网络。
+// 请求阶段未被处理时，使用记录中的上游响应继续执行响应阶段。
+func (p *Pipeline) Replay(entry *HAREntry) (*ReplayResult, error) {
+	req, err := entry.Request.NewHTTPRequest()
+	if err != nil {
+		return nil, err
+	}
+	result := &ReplayResult{Entry: entry}
+
+	conn := &SunnyNet.HttpConn{Type: public.HttpSendRequest, Request: req}
+	if p.Handle(conn) {
+		result.Handled = true
+		result.Local = true
+		if conn.Response == nil {
+			return nil, fmt.Errorf("request %s %s was handled without a response", req.Method, req.URL)
+		}
+		return result, result.readResponse(conn.Response)
+	}
+
+	resp, err := entry.Response.NewHTTPResponse(req)
+	if err != nil {
+		return nil, err
+	}
+	conn.Type = public.HttpResponseOK
+	conn.Response = resp
+	result.Handled = p.Handle(conn)
+	return result, result.readResponse(conn.Response)
+}
+
+// ReplayHAR 按顺序回放 HAR 中的所有记录
+func (p *Pipeline) ReplayHAR(har *HAR) ([]*ReplayResult, error) {
+	results := make([]*ReplayResult, 0, len(har.Log.Entries))
+	for i := range har.Log.Entries {
+		result, err := p.Replay(&har.Log.Entries[i])
+		if err != nil {
+			return results, fmt.Errorf("entry #%d: %w", i+1, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// readResponse 读取最终响应
+func (r *ReplayResult) readResponse(resp *http.Response) error {
+	r.StatusCode = resp.StatusCode
+	r.Header = resp.Header
+	if resp.Body == nil {
+		return nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read replayed response: %w", err)
+	}
+	r.Body = body
+	return nil
+}
@@ -0,0 +1,257 @@
+package router
+
+import (
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"wx_channel/internal/config"
+	"wx_channel/internal/database"
+	"wx_channel/internal/handlers"
+
+	"github.com/qtgolang/SunnyNet/SunnyNet"
+	"github.com/qtgolang/SunnyNet/public"
+)
+
+func newReplayPipeline(cfg *config.Config) *Pipeline {
+	js := []byte("/*inject*/")
+	scriptHandler := handlers.NewScriptHandler(cfg, []byte("/*core*/"), js, js, js, js, js, js, js, js, js, js, js, js, js, js, "?t=test")
+	return &Pipeline{
+		Request:  []Interceptor{handlers.NewAPIHandler(cfg)},
+		Response: []Interceptor{scriptHandler},
+	}
+}
+
+func TestReplayHARFixture(t *testing.T) {
+	if err := database.Initialize(&database.Config{DBPath: filepath.Join(t.TempDir(), "records.db")}); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	har, err := LoadHAR("testdata/feed_page.har")
+	if err != nil {
+		t.Fatalf("LoadHAR failed: %v", err)
+	}
+
+	results, err := newReplayPipeline(&config.Config{}).ReplayHAR(har)
+	if err != nil {
+		t.Fatalf("ReplayHAR failed: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+
+	// 页面注入
+	page := string(results[0].Body)
+	if !results[0].Handled || results[0].Local {
+		t.Errorf("Expected page to be handled in the response phase, got handled=%v local=%v", results[0].Handled, results[0].Local)
+	}
+	if !strings.Contains(page, "<head>\n<script") || !strings.Contains(page, "/*core*/") {
+		t.Errorf("Expected injected scripts in page")
+	}
+	if !strings.Contains(page, `index.publish.js?t=test"`) {
+		t.Errorf("Expected versioned script reference in page")
+	}
+
+	// JS 补丁规则
+	worker := string(results[1].Body)
+	if !strings.Contains(worker, "decryptor_array:p.decryptor_array,fmp4Index:p.fmp4Index") {
+		t.Errorf("Expected worker_release to be patched, got %s", worker)
+	}
+	if !strings.Contains(worker, `import"./js/chunk.js?t=test"`) {
+		t.Errorf("Expected versioned import in worker_release, got %s", worker)
+	}
+
+	// processVideoData 解析
+	if !results[2].Local || results[2].StatusCode != http.StatusOK {
+		t.Fatalf("Expected profile to be handled locally with 200, got local=%v status=%d", results[2].Local, results[2].StatusCode)
+	}
+	record, err := database.NewBrowseHistoryRepository().GetByID("14252099709604468798")
+	if err != nil || record == nil {
+		t.Fatalf("Expected browse record to be saved: %v", err)
+	}
+	if record.Title != "回放测试视频" || record.Author != "测试作者" {
+		t.Errorf("Unexpected record: title=%q author=%q", record.Title, record.Author)
+	}
+	if record.Resolution != "1080x1920" {
+		t.Errorf("Expected resolution 1080x1920, got %q", record.Resolution)
+	}
+	if record.DecryptKey != "123456789" {
+		t.Errorf("Expected decrypt key 123456789, got %q", record.DecryptKey)
+	}
+}
+
+// echoInterceptor 读取请求体并原样返回
+type echoInterceptor struct{}
+
+func (echoInterceptor) Handle(conn *SunnyNet.HttpConn) bool {
+	if conn.Request.URL.Path != "/__wx_channels_api/echo" {
+		return false
+	}
+	body, _ := io.ReadAll(conn.Request.Body)
+	conn.StopRequest(http.StatusOK, body, http.Header{"Content-Type": []string{"application/json"}})
+	return true
+}
+
+func TestHARRecorderRoundTrip(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "session.har")
+	pipeline := &Pipeline{
+		Request:  []Interceptor{echoInterceptor{}},
+		Recorder: NewHARRecorder(file, []string{"qq.com"}, false, "test"),
+	}
+
+	// 本地处理的请求：请求体在录制后仍可被拦截器读取
+	req, _ := http.NewRequest("POST", "https://channels.weixin.qq.com/__wx_channels_api/echo", strings.NewReader(`{"id":"1"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Cookie", "wxuin=123; pass_ticket=secret")
+	req.Header.Set("X-Local-Auth", "console-token")
+	pipeline.Handle(&SunnyNet.HttpConn{Type: public.HttpSendRequest, Request: req})
+
+	// 上游请求：记录拦截器改写之前的响应
+	req, _ = http.NewRequest("GET", "https://res.wx.qq.com/app.js", nil)
+	conn := &SunnyNet.HttpConn{Type: public.HttpSendRequest, Request: req}
+	pipeline.Handle(conn)
+	conn.Type = public.HttpResponseOK
+	conn.Response = &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/javascript"}, "Set-Cookie": []string{"session=secret"}},
+		Body:       io.NopCloser(strings.NewReader("console.log(1)")),
+	}
+	pipeline.Handle(conn)
+	if body, _ := io.ReadAll(conn.Response.Body); string(body) != "console.log(1)" {
+		t.Errorf("Expected response body to be restored, got %q", body)
+	}
+
+	// 不在录制范围内的主机
+	req, _ = http.NewRequest("GET", "https://example.com/", nil)
+	pipeline.Handle(&SunnyNet.HttpConn{Type: public.HttpSendRequest, Request: req})
+
+	if err := pipeline.Recorder.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	har, err := LoadHAR(file)
+	if err != nil {
+		t.Fatalf("LoadHAR failed: %v", err)
+	}
+	if len(har.Log.Entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(har.Log.Entries))
+	}
+	if har.Log.Entries[0].Response.Content.Text != `{"id":"1"}` {
+		t.Errorf("Expected echoed body, got %q", har.Log.Entries[0].Response.Content.Text)
+	}
+	if har.Log.Entries[1].Response.Content.Text != "console.log(1)" {
+		t.Errorf("Expected upstream body, got %q", har.Log.Entries[1].Response.Content.Text)
+	}
+	// 敏感头部默认不以明文保存
+	for _, h := range append(har.Log.Entries[0].Request.Headers, har.Log.Entries[1].Response.Headers...) {
+		if harSensitiveHeaders[h.Name] && h.Value != harRedactedValue {
+			t.Errorf("Expected %s to be redacted, got %q", h.Name, h.Value)
+		}
+	}
+
+	// 回放录制结果
+	results, err := (&Pipeline{Request: []Interceptor{echoInterceptor{}}}).ReplayHAR(har)
+	if err != nil {
+		t.Fatalf("ReplayHAR failed: %v", err)
+	}
+	if !results[0].Local || string(results[0].Body) != `{"id":"1"}` {
+		t.Errorf("Unexpected replay of local request: local=%v body=%q", results[0].Local, results[0].Body)
+	}
+	if results[1].Handled || string(results[1].Body) != "console.log(1)" {
+		t.Errorf("Unexpected replay of upstream request: handled=%v body=%q", results[1].Handled, results[1].Body)
+	}
+}
+
+func TestHARRecorderLargeRequestBody(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "session.har")
+	pipeline := &Pipeline{
+		Request:  []Interceptor{echoInterceptor{}},
+		Recorder: NewHARRecorder(file, nil, true, "test"),
+	}
+
+	// 超过大小限制的请求体不保存，但拦截器仍能读到完整内容
+	body := strings.Repeat("a", harMaxBodySize+10)
+	req, _ := http.NewRequest("POST", "https://channels.weixin.qq.com/__wx_channels_api/echo", strings.NewReader(body))
+	req.Header.Set("Cookie", "wxuin=123")
+	conn := &SunnyNet.HttpConn{Type: public.HttpSendRequest, Request: req}
+	pipeline.Handle(conn)
+
+	if err := pipeline.Recorder.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	har, err := LoadHAR(file)
+	if err != nil {
+		t.Fatalf("LoadHAR failed: %v", err)
+	}
+	if len(har.Log.Entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(har.Log.Entries))
+	}
+	entry := har.Log.Entries[0]
+	if entry.Request.BodySize != -1 || entry.Request.PostData == nil ||
+		entry.Request.PostData.Comment != harTruncateComment || entry.Request.PostData.Text != "" {
+		t.Errorf("Expected truncated request body, got size=%d postData=%+v", entry.Request.BodySize, entry.Request.PostData)
+	}
+	if entry.Response.Content.Size != len(body) {
+		t.Errorf("Expected interceptor to read the full %d byte body, got %d", len(body), entry.Response.Content.Size)
+	}
+	// 开启 har_record_sensitive_headers 时保留原始值
+	for _, h := range entry.Request.Headers {
+		if h.Name == "Cookie" && h.Value != "wxuin=123" {
+			t.Errorf("Expected Cookie to be kept, got %q", h.Value)
+		}
+	}
+}
+
+func TestHARRecorderStreamResponse(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "session.har")
+	pipeline := &Pipeline{Recorder: NewHARRecorder(file, nil, false, "test")}
+
+	// 长度未知的 SSE 响应：录制不能读取响应体，否则会阻塞到流结束
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	req, _ := http.NewRequest("GET", "https://channels.weixin.qq.com/events", nil)
+	conn := &SunnyNet.HttpConn{Type: public.HttpSendRequest, Request: req}
+	pipeline.Handle(conn)
+	conn.Type = public.HttpResponseOK
+	conn.Response = &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Content-Type": []string{"text/event-stream"}},
+		Body:          pr,
+		ContentLength: -1,
+	}
+
+	done := make(chan struct{})
+	go func() {
+		pipeline.Handle(conn)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Recorder blocked reading a streamed response body")
+	}
+
+	go pw.Write([]byte("data: 1\n\n"))
+	buf := make([]byte, 16)
+	if n, _ := conn.Response.Body.Read(buf); string(buf[:n]) != "data: 1\n\n" {
+		t.Errorf("Expected stream to be passed through, got %q", buf[:n])
+	}
+
+	if err := pipeline.Recorder.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	har, err := LoadHAR(file)
+	if err != nil {
+		t.Fatalf("LoadHAR failed: %v", err)
+	}
+	if len(har.Log.Entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(har.Log.Entries))
+	}
+	content := har.Log.Entries[0].Response.Content
+	if content.Comment != harStreamComment || content.Text != "" || har.Log.Entries[0].Response.BodySize != -1 {
+		t.Errorf("Expected streamed body to be omitted, got %+v", content)
+	}
+}
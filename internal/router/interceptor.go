@@ -1,9 +1,50 @@
 package router
 
-import "github.com/qtgolang/SunnyNet/SunnyNet"
+import (
+	"github.com/qtgolang/SunnyNet/SunnyNet"
+	"github.com/qtgolang/SunnyNet/public"
+)
 
 // Interceptor defines a handler that can process a SunnyNet connection.
 // Returns true if the request was handled and processing should stop.
 type Interceptor interface {
 	Handle(conn *SunnyNet.HttpConn) bool
 }
+
+// Pipeline 按 SunnyNet 回调阶段依次执行拦截器链。
+// 代理回调和离线回放（Replay）共用同一套分发逻辑。
+type Pipeline struct {
+	Request  []Interceptor // 请求阶段（HttpSendRequest）
+	Response []Interceptor // 响应阶段（HttpResponseOK）
+	Recorder *HARRecorder  // 可选，录制经过的请求/响应
+}
+
+// Handle 将连接交给对应阶段的拦截器链，返回是否被某个拦截器处理
+func (p *Pipeline) Handle(conn *SunnyNet.HttpConn) bool {
+	switch conn.Type {
+	case public.HttpSendRequest:
+		conn.Request.Header.Del("Accept-Encoding")
+		if p.Recorder != nil {
+			p.Recorder.captureRequest(conn)
+		}
+		for _, interceptor := range p.Request {
+			if interceptor != nil && interceptor.Handle(conn) {
+				if p.Recorder != nil {
+					p.Recorder.captureLocalResponse(conn)
+				}
+				return true
+			}
+		}
+	case public.HttpResponseOK:
+		// 录制上游的原始响应（改写之前），回放时才能重现改写过程
+		if p.Recorder != nil {
+			p.Recorder.captureResponse(conn)
+		}
+		for _, interceptor := range p.Response {
+			if interceptor != nil && interceptor.Handle(conn) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package router
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"wx_channel/internal/api"
+)
+
+type openAPIDoc struct {
+	OpenAPI string                                `json:"openapi"`
+	Paths   map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+func loadOpenAPISpec(t *testing.T) *openAPIDoc {
+	t.Helper()
+	var doc openAPIDoc
+	if err := json.Unmarshal(api.OpenAPISpec, &doc); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+	return &doc
+}
+
+// registeredPatterns 从源码中收集 HandleFunc/Handle 注册的路由，
+// /api/v2 的路由带方法，如 "GET /api/v2/browse/{id}"
+func registeredPatterns(t *testing.T) []string {
+	t.Helper()
+	files := []string{"api_routes.go", "v2.go"}
+	apiFiles, err := filepath.Glob("../api/*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range apiFiles {
+		if !strings.HasSuffix(f, "_test.go") {
+			files = append(files, f)
+		}
+	}
+
+	var patterns []string
+	fset := token.NewFileSet()
+	for _, file := range files {
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", file, err)
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) != 2 {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || (sel.Sel.Name != "HandleFunc" && sel.Sel.Name != "Handle") {
+				return true
+			}
+			lit, ok := call.Args[0].(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				return true
+			}
+			pattern, _ := strconv.Unquote(lit.Value)
+			patterns = append(patterns, pattern)
+			return true
+		})
+	}
+	if len(patterns) == 0 {
+		t.Fatal("no routes found")
+	}
+	return patterns
+}
+
+func TestOpenAPISpecCoversRoutes(t *testing.T) {
+	doc := loadOpenAPISpec(t)
+
+	for _, pattern := range registeredPatterns(t) {
+		if method, path, ok := strings.Cut(pattern, " "); ok {
+			if _, ok := doc.Paths[path][strings.ToLower(method)]; !ok {
+				t.Errorf("route %s is not documented in openapi.json", pattern)
+			}
+			continue
+		}
+		if !strings.HasSuffix(pattern, "/") {
+			if _, ok := doc.Paths[pattern]; !ok {
+				t.Errorf("route %s is not documented in openapi.json", pattern)
+			}
+			continue
+		}
+		// 以 / 结尾的路由处理子路径，至少要有一个子路径有文档
+		found := false
+		for path := range doc.Paths {
+			if strings.HasPrefix(path, pattern) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("no path under %s is documented in openapi.json", pattern)
+		}
+	}
+}
+
+func TestOpenAPISpecPathsAreRouted(t *testing.T) {
+	doc := loadOpenAPISpec(t)
+	router := newTestRouter()
+	param := regexp.MustCompile(`\{[^}]+\}`)
+	methods := map[string]bool{"get": true, "put": true, "post": true, "delete": true, "head": true, "patch": true, "options": true}
+
+	for path, item := range doc.Paths {
+		if len(item) == 0 {
+			t.Errorf("%s has no operations", path)
+		}
+		for method := range item {
+			if !methods[method] {
+				t.Errorf("%s has unknown method %q", path, method)
+			}
+		}
+
+		if strings.HasPrefix(path, "/api/v2/") {
+			// v2 按方法匹配，每个操作都要有路由
+			for method := range item {
+				req := httptest.NewRequest(strings.ToUpper(method), param.ReplaceAllString(path, "x"), nil)
+				if _, pattern := router.v2mux.Handler(req); pattern == "" {
+					t.Errorf("documented operation %s %s is not routed", strings.ToUpper(method), path)
+				}
+			}
+			continue
+		}
+
+		req := httptest.NewRequest(http.MethodGet, param.ReplaceAllString(path, "x"), nil)
+		if _, pattern := router.mux.Handler(req); pattern == "" {
+			t.Errorf("documented path %s is not routed", path)
+		}
+	}
+}
+
+func TestOpenAPISpecEndpoint(t *testing.T) {
+	router := newTestRouter()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var doc openAPIDoc
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Errorf("Expected OpenAPI 3.x, got %q", doc.OpenAPI)
+	}
+}
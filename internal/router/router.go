@@ -1,11 +1,15 @@
 package router
 
 import (
+	"context"
+	"crypto/subtle"
 	"net/http"
 	"strings"
 	"time"
 
+	"wx_channel/internal/database"
 	"wx_channel/internal/response"
+	"wx_channel/internal/services"
 	"wx_channel/internal/utils"
 )
 
@@ -29,20 +33,41 @@ func LoggerMiddleware(next http.Handler) http.Handler {
 		// 包装 ResponseWriter 以捕获状态码
 		wrapped := &statusResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 
-		next.ServeHTTP(wrapped, r)
+		// 认证中间件在内层，通过 requestAuth 回填令牌名称
+		auth := &requestAuth{}
+		next.ServeHTTP(wrapped, r.WithContext(context.WithValue(r.Context(), requestAuthKey{}, auth)))
 
+		tokenName := auth.tokenName
+		if tokenName == "" {
+			tokenName = "-"
+		}
 		duration := time.Since(start)
 		utils.GetLogger().Info(
-			"API 请求: %s %s [%d] %s from %s",
+			"API 请求: %s %s [%d] %s from %s token=%s",
 			r.Method,
 			r.URL.Path,
 			wrapped.statusCode,
 			duration.String(),
 			r.RemoteAddr,
+			tokenName,
 		)
 	})
 }
 
+// requestAuth 记录请求使用的令牌名称，供请求日志使用
+type requestAuth struct {
+	tokenName string
+}
+
+type requestAuthKey struct{}
+
+// setRequestTokenName 记录请求使用的令牌名称
+func setRequestTokenName(r *http.Request, name string) {
+	if auth, ok := r.Context().Value(requestAuthKey{}).(*requestAuth); ok {
+		auth.tokenName = name
+	}
+}
+
 // statusResponseWriter 包装 ResponseWriter 以捕获状态码
 type statusResponseWriter struct {
 	http.ResponseWriter
@@ -54,6 +79,18 @@ func (w *statusResponseWriter) WriteHeader(code int) {
 	w.ResponseWriter.WriteHeader(code)
 }
 
+// Flush 透传 http.Flusher，支持流式响应
+func (w *statusResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap 供 http.ResponseController 访问底层 ResponseWriter
+func (w *statusResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // CORSMiddleware 跨域中间件
 func CORSMiddleware(allowedOrigins []string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -72,7 +109,7 @@ func CORSMiddleware(allowedOrigins []string) func(http.Handler) http.Handler {
 			if allowed && origin != "" {
 				w.Header().Set("Access-Control-Allow-Origin", origin)
 				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Local-Auth")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Local-Auth, X-CSRF-Token")
 				w.Header().Set("Access-Control-Allow-Credentials", "true")
 				w.Header().Set("Vary", "Origin")
 			}
@@ -88,53 +125,217 @@ func CORSMiddleware(allowedOrigins []string) func(http.Handler) http.Handler {
 	}
 }
 
+// TokenAuthenticator 校验命名 API 令牌
+type TokenAuthenticator interface {
+	// Enabled 是否创建过令牌，创建过令牌后所有非公共端点都需要认证
+	Enabled() bool
+	Authenticate(token string) (*database.APIToken, error)
+}
+
+// SessionAuthenticator 校验控制台用户的登录会话
+type SessionAuthenticator interface {
+	// Enabled 是否创建过用户，创建过用户后所有非公共端点都需要认证
+	Enabled() bool
+	Authenticate(token string) (*database.User, *database.UserSession, error)
+}
+
+// secretTokenName 使用 secret_token 访问时在日志中记录的令牌名称
+const secretTokenName = "secret_token"
+
+// csrfHeader 会话认证的写请求必须携带的 CSRF 令牌请求头
+const csrfHeader = "X-CSRF-Token"
+
 // AuthMiddleware 基于配置的 token 进行可选认证。
 // 当 token 为空时，表示不启用认证。
 func AuthMiddleware(secretToken string) func(http.Handler) http.Handler {
+	return ScopedAuthMiddleware(secretToken, nil)
+}
+
+// ScopedAuthMiddleware 在 AuthMiddleware 的基础上支持命名 API 令牌：
+// secret_token 拥有全部权限，命名令牌按权限范围访问（见 RequiredScope）。
+// 未配置 secret_token 且没有创建过令牌时不启用认证。
+func ScopedAuthMiddleware(secretToken string, tokens TokenAuthenticator) func(http.Handler) http.Handler {
+	return ConsoleAuthMiddleware(secretToken, tokens, nil)
+}
+
+// ConsoleAuthMiddleware 在 ScopedAuthMiddleware 的基础上支持控制台用户的会话 Cookie：
+// 有效会话按用户的权限范围访问，写请求必须携带与会话匹配的 X-CSRF-Token；
+// 会话无效时继续按令牌认证。创建过用户后即启用认证
+func ConsoleAuthMiddleware(secretToken string, tokens TokenAuthenticator, sessions SessionAuthenticator) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// 不启用 token 时直接放行
-			if secretToken == "" {
+			// 允许 CORS 预检请求直接通过
+			if r.Method == http.MethodOptions {
 				next.ServeHTTP(w, r)
 				return
 			}
 
-			// 公共端点放行：用于服务探活和控制台令牌验证
+			user, session := sessionUser(r, sessions)
+			if user != nil {
+				setRequestTokenName(r, "user:"+user.Username)
+			}
+
+			// 公共端点放行：用于服务探活、控制台令牌验证和登录
 			if isPublicAPIPath(r.URL.Path) {
+				if user != nil {
+					r = r.WithContext(services.ContextWithUser(r.Context(), user, session))
+				}
 				next.ServeHTTP(w, r)
 				return
 			}
 
-			// 允许 CORS 预检请求直接通过
-			if r.Method == http.MethodOptions {
-				next.ServeHTTP(w, r)
+			if user != nil {
+				if !isSafeMethod(r.Method) &&
+					subtle.ConstantTimeCompare([]byte(r.Header.Get(csrfHeader)), []byte(session.CSRFToken)) != 1 {
+					writeMiddlewareError(w, r, http.StatusForbidden, "invalid csrf token")
+					return
+				}
+				if scope := RequiredScope(r.Method, r.URL.Path); !user.HasScope(scope) {
+					writeMiddlewareError(w, r, http.StatusForbidden, "user lacks required scope: "+scope)
+					return
+				}
+				next.ServeHTTP(w, r.WithContext(services.ContextWithUser(r.Context(), user, session)))
 				return
 			}
 
-			token := r.Header.Get("X-Local-Auth")
-			if token == "" {
-				auth := r.Header.Get("Authorization")
-				if strings.HasPrefix(strings.ToLower(auth), "bearer ") {
-					token = strings.TrimSpace(auth[len("Bearer "):])
-				}
+			token := requestToken(r)
+			if secretToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(secretToken)) == 1 {
+				setRequestTokenName(r, secretTokenName)
+				secret := &database.APIToken{Name: secretTokenName, Scopes: []string{database.ScopeAdmin}}
+				next.ServeHTTP(w, r.WithContext(services.ContextWithAPIToken(r.Context(), secret)))
+				return
+			}
+
+			tokensEnabled := tokens != nil && tokens.Enabled()
+			usersEnabled := sessions != nil && sessions.Enabled()
+			// 不启用 token 且没有用户时直接放行
+			if secretToken == "" && !tokensEnabled && !usersEnabled {
+				next.ServeHTTP(w, r)
+				return
 			}
-			if token == "" {
-				token = r.URL.Query().Get("token")
+			if token == "" || !tokensEnabled {
+				writeMiddlewareError(w, r, http.StatusUnauthorized, "unauthorized")
+				return
 			}
 
-			if token != secretToken {
-				response.ErrorWithStatus(w, http.StatusUnauthorized, 401, "unauthorized")
+			apiToken, err := tokens.Authenticate(token)
+			if err != nil || apiToken == nil {
+				writeMiddlewareError(w, r, http.StatusUnauthorized, "unauthorized")
+				return
+			}
+			setRequestTokenName(r, apiToken.Name)
+			if scope := RequiredScope(r.Method, r.URL.Path); !apiToken.HasScope(scope) {
+				writeMiddlewareError(w, r, http.StatusForbidden, "token lacks required scope: "+scope)
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, r.WithContext(services.ContextWithAPIToken(r.Context(), apiToken)))
 		})
 	}
 }
 
+// sessionUser 校验请求的会话 Cookie，没有 Cookie 或会话无效时返回 nil
+func sessionUser(r *http.Request, sessions SessionAuthenticator) (*database.User, *database.UserSession) {
+	if sessions == nil {
+		return nil, nil
+	}
+	cookie, err := r.Cookie(services.SessionCookieName)
+	if err != nil || cookie.Value == "" {
+		return nil, nil
+	}
+	user, session, err := sessions.Authenticate(cookie.Value)
+	if err != nil || user == nil || session == nil {
+		return nil, nil
+	}
+	return user, session
+}
+
+// isSafeMethod 判断请求方法是否只读，只读请求不需要 CSRF 令牌
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// requestToken 依次从 X-Local-Auth、Authorization: Bearer 和 ?token= 中读取令牌
+func requestToken(r *http.Request) string {
+	token := r.Header.Get("X-Local-Auth")
+	if token == "" {
+		auth := r.Header.Get("Authorization")
+		if strings.HasPrefix(strings.ToLower(auth), "bearer ") {
+			token = strings.TrimSpace(auth[len("Bearer "):])
+		}
+	}
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+	return token
+}
+
+// scopeRule 路径前缀对应的权限范围，delete 为空时使用 write
+type scopeRule struct {
+	prefix string
+	read   string
+	write  string
+	delete string
+}
+
+// scopeRules 按路径前缀匹配，未匹配的路径读操作需要 read，写操作需要 download
+var scopeRules = []scopeRule{
+	{prefix: "/api/tokens", read: database.ScopeAdmin, write: database.ScopeAdmin},
+	{prefix: "/api/audit", read: database.ScopeAdmin, write: database.ScopeAdmin},
+	{prefix: "/api/webhooks", read: database.ScopeAdmin, write: database.ScopeAdmin},
+	{prefix: "/api/export/audit", read: database.ScopeAdmin, write: database.ScopeAdmin},
+	{prefix: "/api/logs", read: database.ScopeAdmin, write: database.ScopeAdmin},
+	{prefix: "/api/system", read: database.ScopeRead, write: database.ScopeAdmin},
+	{prefix: "/api/proxy", read: database.ScopeRead, write: database.ScopeAdmin},
+	{prefix: "/api/certificate", read: database.ScopeRead, write: database.ScopeAdmin},
+	{prefix: "/api/settings", read: database.ScopeRead, write: database.ScopeSettings},
+	{prefix: "/api/script-rules", read: database.ScopeRead, write: database.ScopeSettings},
+	// 删除浏览记录、下载记录（及文件）和评论需要 admin
+	{prefix: "/api/browse", read: database.ScopeRead, write: database.ScopeDownload, delete: database.ScopeAdmin},
+	{prefix: "/api/downloads", read: database.ScopeRead, write: database.ScopeDownload, delete: database.ScopeAdmin},
+	{prefix: "/api/comments", read: database.ScopeRead, write: database.ScopeDownload, delete: database.ScopeAdmin},
+	// 永久删除回收站中的记录需要 admin，恢复需要 download
+	{prefix: "/api/trash", read: database.ScopeRead, write: database.ScopeDownload, delete: database.ScopeAdmin},
+	{prefix: "/api/users", read: database.ScopeAdmin, write: database.ScopeAdmin},
+	// 登录用户修改自己的偏好和密码只需要 read
+	{prefix: "/api/auth", read: database.ScopeRead, write: database.ScopeRead},
+	// GraphQL 只读，POST 查询也只需要 read
+	{prefix: "/api/graphql", read: database.ScopeRead, write: database.ScopeRead},
+}
+
+// RequiredScope 返回访问 method path 所需的权限范围
+func RequiredScope(method, path string) string {
+	// /api/v1/xxx、/api/v2/xxx 与 /api/xxx 使用相同的规则
+	for _, version := range []string{"/api/v1/", "/api/v2/"} {
+		if strings.HasPrefix(path, version) {
+			path = "/api/" + strings.TrimPrefix(path, version)
+		}
+	}
+	readOnly := method == http.MethodGet || method == http.MethodHead
+
+	for _, rule := range scopeRules {
+		if path != rule.prefix && !strings.HasPrefix(path, rule.prefix+"/") {
+			continue
+		}
+		switch {
+		case readOnly:
+			return rule.read
+		case method == http.MethodDelete && rule.delete != "":
+			return rule.delete
+		default:
+			return rule.write
+		}
+	}
+	if readOnly {
+		return database.ScopeRead
+	}
+	return database.ScopeDownload
+}
+
 func isPublicAPIPath(path string) bool {
 	switch path {
-	case "/api/health", "/api/console/verify-token", "/api/system/health", "/api/v1/system/health":
+	case "/api/health", "/api/console/verify-token", "/api/system/health", "/api/v1/system/health",
+		"/api/auth/login", "/api/auth/me":
 		return true
 	default:
 		return false
@@ -147,7 +348,7 @@ func RecoveryMiddleware(next http.Handler) http.Handler {
 		defer func() {
 			if err := recover(); err != nil {
 				utils.GetLogger().Error("Panic recovered: %v, path: %s", err, r.URL.Path)
-				response.ErrorWithStatus(w, http.StatusInternalServerError, 500, "Internal Server Error")
+				writeMiddlewareError(w, r, http.StatusInternalServerError, "Internal Server Error")
 			}
 		}()
 		next.ServeHTTP(w, r)
@@ -9,6 +9,8 @@ import (
 	"testing"
 
 	"wx_channel/internal/config"
+	"wx_channel/internal/database"
+	"wx_channel/internal/services"
 	"wx_channel/internal/websocket"
 
 	"github.com/qtgolang/SunnyNet/SunnyNet"
@@ -180,6 +182,178 @@ func TestAuthMiddleware_ProtectedPathRequiresToken(t *testing.T) {
 	}
 }
 
+// fakeTokens 以明文为键的测试令牌
+type fakeTokens map[string]*database.APIToken
+
+func (f fakeTokens) Enabled() bool { return len(f) > 0 }
+
+func (f fakeTokens) Authenticate(token string) (*database.APIToken, error) {
+	if t, ok := f[token]; ok {
+		return t, nil
+	}
+	return nil, services.ErrAPITokenInvalid
+}
+
+func TestScopedAuthMiddleware(t *testing.T) {
+	var seen *database.APIToken
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = services.APITokenFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+	tokens := fakeTokens{
+		"wxc_viewer": {Name: "viewer", Scopes: []string{database.ScopeRead}},
+		"wxc_admin":  {Name: "admin", Scopes: []string{database.ScopeAdmin}},
+	}
+	handler := ScopedAuthMiddleware("", tokens)(next)
+
+	cases := []struct {
+		method, path, token string
+		want                int
+	}{
+		{http.MethodGet, "/api/downloads", "", http.StatusUnauthorized},
+		{http.MethodGet, "/api/downloads", "wxc_unknown", http.StatusUnauthorized},
+		{http.MethodGet, "/api/health", "", http.StatusOK},
+		{http.MethodGet, "/api/downloads", "wxc_viewer", http.StatusOK},
+		{http.MethodDelete, "/api/downloads", "wxc_viewer", http.StatusForbidden},
+		{http.MethodPut, "/api/v1/settings", "wxc_viewer", http.StatusForbidden},
+		{http.MethodGet, "/api/tokens", "wxc_viewer", http.StatusForbidden},
+		{http.MethodDelete, "/api/downloads", "wxc_admin", http.StatusOK},
+		{http.MethodDelete, "/api/v2/browse/1", "wxc_viewer", http.StatusForbidden},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, nil)
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != c.want {
+			t.Errorf("%s %s with %q: expected %d, got %d", c.method, c.path, c.token, c.want, w.Code)
+		}
+	}
+
+	// 通过认证的令牌写入请求上下文
+	seen = nil
+	req := httptest.NewRequest(http.MethodGet, "/api/stats?token=wxc_viewer", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if seen == nil || seen.Name != "viewer" {
+		t.Errorf("Expected viewer token in context, got %+v", seen)
+	}
+
+	// 未配置 secret_token 且没有令牌时不启用认证
+	open := ScopedAuthMiddleware("", fakeTokens{})(next)
+	w := httptest.NewRecorder()
+	open.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/downloads", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected open access without tokens, got %d", w.Code)
+	}
+}
+
+// fakeSessions 以会话令牌明文为键的测试会话
+type fakeSessions map[string]*database.UserSession
+
+func (f fakeSessions) Enabled() bool { return len(f) > 0 }
+
+func (f fakeSessions) Authenticate(token string) (*database.User, *database.UserSession, error) {
+	session, ok := f[token]
+	if !ok {
+		return nil, nil, services.ErrSessionInvalid
+	}
+	scopes := []string{database.ScopeRead}
+	if session.UserID == "admin" {
+		scopes = []string{database.ScopeAdmin}
+	}
+	return &database.User{ID: session.UserID, Username: session.UserID, Scopes: scopes}, session, nil
+}
+
+func TestConsoleAuthMiddleware_Sessions(t *testing.T) {
+	var seen *database.User
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = services.UserFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+	sessions := fakeSessions{
+		"wxs_viewer": {ID: "s1", UserID: "viewer", CSRFToken: "csrf-viewer"},
+		"wxs_admin":  {ID: "s2", UserID: "admin", CSRFToken: "csrf-admin"},
+	}
+	tokens := fakeTokens{"wxc_admin": {Name: "admin-token", Scopes: []string{database.ScopeAdmin}}}
+	handler := ConsoleAuthMiddleware("", tokens, sessions)(next)
+
+	cases := []struct {
+		method, path, cookie, csrf, token string
+		want                              int
+	}{
+		{http.MethodGet, "/api/downloads", "", "", "", http.StatusUnauthorized},
+		{http.MethodGet, "/api/downloads", "wxs_unknown", "", "", http.StatusUnauthorized},
+		{http.MethodGet, "/api/downloads", "wxs_viewer", "", "", http.StatusOK},
+		{http.MethodPost, "/api/auth/login", "", "", "", http.StatusOK},
+		{http.MethodGet, "/api/auth/me", "", "", "", http.StatusOK},
+		// 会话认证的写请求必须携带匹配的 CSRF 令牌
+		{http.MethodPut, "/api/auth/me/preferences", "wxs_viewer", "", "", http.StatusForbidden},
+		{http.MethodPut, "/api/auth/me/preferences", "wxs_viewer", "csrf-admin", "", http.StatusForbidden},
+		{http.MethodPut, "/api/auth/me/preferences", "wxs_viewer", "csrf-viewer", "", http.StatusOK},
+		{http.MethodDelete, "/api/downloads", "wxs_viewer", "csrf-viewer", "", http.StatusForbidden},
+		{http.MethodGet, "/api/users", "wxs_viewer", "", "", http.StatusForbidden},
+		{http.MethodDelete, "/api/users/1", "wxs_admin", "csrf-admin", "", http.StatusOK},
+		// 会话无效时按令牌认证，令牌请求不需要 CSRF 令牌
+		{http.MethodDelete, "/api/downloads", "wxs_unknown", "", "wxc_admin", http.StatusOK},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, nil)
+		if c.cookie != "" {
+			req.AddCookie(&http.Cookie{Name: services.SessionCookieName, Value: c.cookie})
+		}
+		if c.csrf != "" {
+			req.Header.Set("X-CSRF-Token", c.csrf)
+		}
+		if c.token != "" {
+			req.Header.Set("X-Local-Auth", c.token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != c.want {
+			t.Errorf("%s %s with cookie %q csrf %q: expected %d, got %d", c.method, c.path, c.cookie, c.csrf, c.want, w.Code)
+		}
+	}
+
+	// 公共端点也会带上已登录的用户，供 /api/auth/me 使用
+	seen = nil
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/me", nil)
+	req.AddCookie(&http.Cookie{Name: services.SessionCookieName, Value: "wxs_viewer"})
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if seen == nil || seen.Username != "viewer" {
+		t.Errorf("Expected viewer in context, got %+v", seen)
+	}
+}
+
+func TestRequiredScope(t *testing.T) {
+	cases := []struct {
+		method, path, want string
+	}{
+		{http.MethodGet, "/api/browse", database.ScopeRead},
+		{http.MethodPost, "/api/queue", database.ScopeDownload},
+		{http.MethodDelete, "/api/queue/1", database.ScopeDownload},
+		{http.MethodDelete, "/api/v1/browse/clear", database.ScopeAdmin},
+		{http.MethodPost, "/api/downloads/1/retry", database.ScopeDownload},
+		{http.MethodPut, "/api/script-rules/1", database.ScopeSettings},
+		{http.MethodGet, "/api/v1/logs", database.ScopeAdmin},
+		{http.MethodPost, "/api/v1/proxy/restart", database.ScopeAdmin},
+		{http.MethodGet, "/api/downloadsx", database.ScopeRead},
+		{http.MethodGet, "/api/webhooks/1/deliveries", database.ScopeAdmin},
+		{http.MethodDelete, "/api/v2/downloads/1", database.ScopeAdmin},
+		{http.MethodPut, "/api/v2/settings", database.ScopeSettings},
+		{http.MethodPost, "/api/v2/queue/1/pause", database.ScopeDownload},
+		{http.MethodPost, "/api/graphql", database.ScopeRead},
+		{http.MethodGet, "/api/users", database.ScopeAdmin},
+		{http.MethodPut, "/api/auth/me/preferences", database.ScopeRead},
+	}
+	for _, c := range cases {
+		if got := RequiredScope(c.method, c.path); got != c.want {
+			t.Errorf("RequiredScope(%s %s) = %s, want %s", c.method, c.path, got, c.want)
+		}
+	}
+}
+
 func TestVideoPlayRoute_UsesPlayHandler(t *testing.T) {
 	router := newTestRouter()
 
@@ -204,3 +378,79 @@ func TestVideoPlayRoute_UsesPlayHandler(t *testing.T) {
 		t.Fatalf("expected play handler error message, got: %v", msg)
 	}
 }
+
+func TestV1AliasRewritesPath(t *testing.T) {
+	var got string
+	handler := v1Alias(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Path
+	})
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/queue/abc/pause?x=1", nil)
+	handler(httptest.NewRecorder(), req)
+
+	if got != "/api/queue/abc/pause" {
+		t.Errorf("Expected /api/queue/abc/pause, got %s", got)
+	}
+	if req.URL.Path != "/api/v1/queue/abc/pause" {
+		t.Errorf("original request was modified: %s", req.URL.Path)
+	}
+}
+
+func TestV2Routes_ErrorEnvelope(t *testing.T) {
+	router := newTestRouter()
+
+	cases := []struct {
+		method, path string
+		status       int
+		code         string
+		allow        string
+	}{
+		{http.MethodGet, "/api/v2/unknown", http.StatusNotFound, "not_found", ""},
+		{http.MethodGet, "/api/v2/browse/1/unknown", http.StatusNotFound, "not_found", ""},
+		{http.MethodPost, "/api/v2/browse/1", http.StatusMethodNotAllowed, "method_not_allowed", "DELETE, GET, HEAD"},
+		{http.MethodGet, "/api/v2/queue/order", http.StatusMethodNotAllowed, "method_not_allowed", "DELETE, PUT"},
+		{http.MethodGet, "/api/v2/stats/chart?days=31", http.StatusUnprocessableEntity, "validation_failed", ""},
+		{http.MethodGet, "/api/v2/search?q=a", http.StatusUnprocessableEntity, "validation_failed", ""},
+		{http.MethodGet, "/api/v2/browse?sortBy=password", http.StatusUnprocessableEntity, "validation_failed", ""},
+		{http.MethodDelete, "/api/v2/downloads", http.StatusBadRequest, "invalid_request", ""},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != c.status {
+			t.Errorf("%s %s: expected status %d, got %d", c.method, c.path, c.status, w.Code)
+			continue
+		}
+		var resp struct {
+			Error struct {
+				Code string `json:"code"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Errorf("%s %s: invalid JSON: %v", c.method, c.path, err)
+			continue
+		}
+		if resp.Error.Code != c.code {
+			t.Errorf("%s %s: expected code %s, got %q", c.method, c.path, c.code, resp.Error.Code)
+		}
+		if got := w.Header().Get("Allow"); got != c.allow {
+			t.Errorf("%s %s: expected Allow %q, got %q", c.method, c.path, c.allow, got)
+		}
+	}
+}
+
+func TestScopedAuthMiddleware_V2ErrorEnvelope(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := ScopedAuthMiddleware("secret", fakeTokens{})(next)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v2/browse", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status 401, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"code":"unauthorized"`) {
+		t.Errorf("Expected v2 error envelope, got %s", w.Body.String())
+	}
+}
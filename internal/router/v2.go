@@ -0,0 +1,104 @@
+package router
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"wx_channel/internal/handlers"
+	"wx_channel/internal/response"
+)
+
+// registerV2Routes 注册 /api/v2 路由。
+// v2 使用 "METHOD /path/{param}" 模式匹配方法和路径，处理器不再自行解析路径；
+// 所有错误（包括未匹配的路径和方法）都以 {"error": {"code", "message"}} 返回
+func (r *APIRouter) registerV2Routes() {
+	h := r.consoleHandler
+	mux := http.NewServeMux()
+
+	// 浏览记录
+	mux.Handle("GET /api/v2/browse", handlers.V2HandlerFunc(h.V2ListBrowse))
+	mux.Handle("DELETE /api/v2/browse", handlers.V2HandlerFunc(h.V2DeleteBrowseMany))
+	mux.Handle("GET /api/v2/browse/{id}", handlers.V2HandlerFunc(h.V2GetBrowse))
+	mux.Handle("DELETE /api/v2/browse/{id}", handlers.V2HandlerFunc(h.V2DeleteBrowse))
+	mux.Handle("GET /api/v2/browse/{id}/formats", handlers.V2HandlerFunc(h.V2GetBrowseFormats))
+
+	// 下载记录
+	mux.Handle("GET /api/v2/downloads", handlers.V2HandlerFunc(h.V2ListDownloads))
+	mux.Handle("DELETE /api/v2/downloads", handlers.V2HandlerFunc(h.V2DeleteDownloadsMany))
+	mux.Handle("GET /api/v2/downloads/{id}", handlers.V2HandlerFunc(h.V2GetDownload))
+	mux.Handle("DELETE /api/v2/downloads/{id}", handlers.V2HandlerFunc(h.V2DeleteDownload))
+	mux.Handle("POST /api/v2/downloads/{id}/probe", handlers.V2HandlerFunc(h.V2ProbeDownload))
+
+	// 下载队列
+	mux.Handle("GET /api/v2/queue", handlers.V2HandlerFunc(h.V2ListQueue))
+	mux.Handle("POST /api/v2/queue", handlers.V2HandlerFunc(h.V2AddToQueue))
+	mux.Handle("PUT /api/v2/queue/order", handlers.V2HandlerFunc(h.V2ReorderQueue))
+	mux.Handle("DELETE /api/v2/queue/{id}", handlers.V2HandlerFunc(h.V2RemoveQueueItem))
+	mux.Handle("POST /api/v2/queue/{id}/pause", handlers.V2HandlerFunc(h.V2PauseQueueItem))
+	mux.Handle("POST /api/v2/queue/{id}/resume", handlers.V2HandlerFunc(h.V2ResumeQueueItem))
+
+	// 设置、统计和搜索
+	mux.Handle("GET /api/v2/settings", handlers.V2HandlerFunc(h.V2GetSettings))
+	mux.Handle("PUT /api/v2/settings", handlers.V2HandlerFunc(h.V2UpdateSettings))
+	mux.Handle("GET /api/v2/stats", handlers.V2HandlerFunc(h.V2GetStats))
+	mux.Handle("GET /api/v2/stats/chart", handlers.V2HandlerFunc(h.V2GetStatsChart))
+	mux.Handle("GET /api/v2/search", handlers.V2HandlerFunc(h.V2Search))
+
+	r.v2mux = mux
+	r.mux.Handle("/api/v2/", v2Fallback(mux))
+}
+
+// v2Methods 探测路径时尝试的方法
+var v2Methods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// v2Fallback 用 v2 的错误格式替换 ServeMux 默认的纯文本 404 和 405
+func v2Fallback(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if _, pattern := mux.Handler(req); pattern != "" {
+			mux.ServeHTTP(w, req)
+			return
+		}
+
+		if allowed := v2AllowedMethods(mux, req); len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			response.V2Error(w, response.NewAPIError(http.StatusMethodNotAllowed, response.CodeMethodNotAllowed,
+				"method "+req.Method+" not allowed, use "+strings.Join(allowed, ", ")))
+			return
+		}
+		response.V2Error(w, response.NewAPIError(http.StatusNotFound, response.CodeNotFound, "no route for "+req.URL.Path))
+	})
+}
+
+// v2AllowedMethods 返回路径支持的方法，路径不存在时返回空
+func v2AllowedMethods(mux *http.ServeMux, req *http.Request) []string {
+	var allowed []string
+	for _, method := range v2Methods {
+		probe := req.Clone(req.Context())
+		probe.Method = method
+		if _, pattern := mux.Handler(probe); pattern != "" {
+			allowed = append(allowed, method)
+			if method == http.MethodGet {
+				allowed = append(allowed, http.MethodHead)
+			}
+		}
+	}
+	sort.Strings(allowed)
+	return allowed
+}
+
+// writeMiddlewareError 中间件的错误响应，/api/v2 使用 v2 的错误格式
+func writeMiddlewareError(w http.ResponseWriter, req *http.Request, status int, message string) {
+	if !strings.HasPrefix(req.URL.Path, "/api/v2/") {
+		response.ErrorWithStatus(w, status, status, message)
+		return
+	}
+	code := response.CodeInternal
+	switch status {
+	case http.StatusUnauthorized:
+		code = response.CodeUnauthorized
+	case http.StatusForbidden:
+		code = response.CodeForbidden
+	}
+	response.V2Error(w, response.NewAPIError(status, code, message))
+}
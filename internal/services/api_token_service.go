@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"wx_channel/internal/database"
+	"wx_channel/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+const (
+	apiTokenPrefix        = "wxc_"      // 令牌明文的前缀，便于在日志和配置中识别
+	apiTokenDisplayLength = 8           // 列表中显示的明文长度
+	apiTokenTouchInterval = time.Minute // 最近使用时间的最小写入间隔
+	existsCacheTTL        = 5 * time.Second
+)
+
+var (
+	// ErrAPITokenNotFound 令牌不存在
+	ErrAPITokenNotFound = errors.New("api token not found")
+	// ErrAPITokenExists 令牌名称已被使用
+	ErrAPITokenExists = errors.New("api token name already exists")
+	// ErrAPITokenInvalid 令牌不存在或已过期
+	ErrAPITokenInvalid = errors.New("invalid or expired api token")
+)
+
+// APITokenRequest 创建令牌的参数
+type APITokenRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expiresAt"` // 为空时永不过期
+}
+
+// APITokenService 管理控制台 API 的命名令牌。令牌明文只在创建时返回一次，
+// 数据库中保存 SHA-256 哈希
+type APITokenService struct {
+	repo   *database.APITokenRepository
+	exists *existsCache
+
+	mu      sync.Mutex
+	touched map[string]time.Time // 令牌 ID -> 最近一次写入的使用时间
+}
+
+// NewAPITokenService 创建一个新的 APITokenService
+func NewAPITokenService() *APITokenService {
+	repo := database.NewAPITokenRepository()
+	return &APITokenService{
+		repo:    repo,
+		exists:  &existsCache{check: repo.Exists},
+		touched: make(map[string]time.Time),
+	}
+}
+
+// Create 创建令牌，返回令牌信息和明文
+func (s *APITokenService) Create(req *APITokenRequest) (*database.APIToken, string, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, "", fmt.Errorf("name is required")
+	}
+	scopes, err := database.ParseScopes(strings.Join(req.Scopes, ","))
+	if err != nil {
+		return nil, "", err
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, "", fmt.Errorf("expiresAt must be in the future")
+	}
+
+	existing, err := s.repo.GetByName(name)
+	if err != nil {
+		return nil, "", err
+	}
+	if existing != nil {
+		return nil, "", ErrAPITokenExists
+	}
+
+	plain, err := generateAPIToken()
+	if err != nil {
+		return nil, "", err
+	}
+	token := &database.APIToken{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Prefix:    plain[:len(apiTokenPrefix)+apiTokenDisplayLength],
+		Scopes:    scopes,
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := s.repo.Create(token, HashAPIToken(plain)); err != nil {
+		return nil, "", err
+	}
+	s.exists.invalidate()
+	utils.Info("🔑 [API令牌] 已创建令牌: %s (%s)", token.Name, strings.Join(token.Scopes, ","))
+	return token, plain, nil
+}
+
+// List 列出全部令牌（不含明文）
+func (s *APITokenService) List() ([]database.APIToken, error) {
+	return s.repo.List()
+}
+
+// Revoke 按 ID 或名称删除令牌
+func (s *APITokenService) Revoke(idOrName string) error {
+	n, err := s.repo.Delete(idOrName)
+	if err != nil {
+		return err
+	}
+	s.exists.invalidate()
+	if n == 0 {
+		return ErrAPITokenNotFound
+	}
+	utils.Info("🔑 [API令牌] 已删除令牌: %s", idOrName)
+	return nil
+}
+
+// Enabled 判断是否创建过令牌；创建过令牌后控制台 API 必须携带令牌访问。
+// 查询失败时按已启用处理，避免数据库异常时认证被绕过
+func (s *APITokenService) Enabled() bool {
+	exists, err := s.exists.get()
+	if err != nil {
+		utils.Warn("[API令牌] 检查令牌失败，按已启用处理: %v", err)
+		return true
+	}
+	return exists
+}
+
+// Authenticate 校验令牌明文，返回对应的令牌信息
+func (s *APITokenService) Authenticate(plain string) (*database.APIToken, error) {
+	if !strings.HasPrefix(plain, apiTokenPrefix) {
+		return nil, ErrAPITokenInvalid
+	}
+	token, err := s.repo.GetByHash(HashAPIToken(plain))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if token == nil || token.Expired(now) {
+		return nil, ErrAPITokenInvalid
+	}
+	s.touch(token.ID, now)
+	return token, nil
+}
+
+// touch 更新最近使用时间，同一令牌每分钟最多写一次数据库
+func (s *APITokenService) touch(id string, now time.Time) {
+	s.mu.Lock()
+	last, ok := s.touched[id]
+	if ok && now.Sub(last) < apiTokenTouchInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.touched[id] = now
+	s.mu.Unlock()
+
+	if err := s.repo.TouchLastUsed(id, now); err != nil {
+		utils.Warn("[API令牌] %v", err)
+	}
+}
+
+// HashAPIToken 计算令牌明文的 SHA-256（十六进制）
+func HashAPIToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateAPIToken 生成 32 字节随机令牌
+func generateAPIToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate api token: %w", err)
+	}
+	return apiTokenPrefix + hex.EncodeToString(buf), nil
+}
+
+type apiTokenContextKey struct{}
+
+// ContextWithAPIToken 在请求上下文中记录通过认证的令牌
+func ContextWithAPIToken(ctx context.Context, token *database.APIToken) context.Context {
+	return context.WithValue(ctx, apiTokenContextKey{}, token)
+}
+
+// APITokenFromContext 返回请求上下文中通过认证的令牌，未认证时返回 nil
+func APITokenFromContext(ctx context.Context) *database.APIToken {
+	token, _ := ctx.Value(apiTokenContextKey{}).(*database.APIToken)
+	return token
+}
+
+// existsCache 缓存“是否存在记录”的查询结果，认证中间件每个请求都会检查。
+// 结果最多保留 existsCacheTTL，以便发现命令行等其他进程创建的记录；查询失败时不缓存
+type existsCache struct {
+	check func() (bool, error)
+
+	mu        sync.Mutex
+	value     bool
+	checkedAt time.Time
+}
+
+// get 返回缓存的结果，过期时重新查询
+func (c *existsCache) get() (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.checkedAt.IsZero() && time.Since(c.checkedAt) < existsCacheTTL {
+		return c.value, nil
+	}
+	value, err := c.check()
+	if err != nil {
+		return false, err
+	}
+	c.value, c.checkedAt = value, time.Now()
+	return value, nil
+}
+
+// invalidate 在本进程创建或删除记录后清除缓存
+func (c *existsCache) invalidate() {
+	c.mu.Lock()
+	c.checkedAt = time.Time{}
+	c.mu.Unlock()
+}
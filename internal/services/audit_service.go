@@ -0,0 +1,162 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"time"
+
+	"wx_channel/internal/database"
+	"wx_channel/internal/utils"
+)
+
+// AuditService 记录删除、配置修改和远程指令等操作的审计日志
+type AuditService struct {
+	repo *database.AuditRepository
+}
+
+// NewAuditService 创建一个新的 AuditService
+func NewAuditService() *AuditService {
+	return &AuditService{
+		repo: database.NewAuditRepository(),
+	}
+}
+
+// Record 追加一条审计记录。审计失败不影响业务操作，只记录警告日志
+func (s *AuditService) Record(entry *database.AuditEntry) {
+	if s == nil || entry == nil {
+		return
+	}
+	if entry.TargetIDs == nil {
+		entry.TargetIDs = []string{}
+	}
+	if err := s.repo.Append(entry); err != nil {
+		utils.Warn("[审计] 记录 %s 失败: %v", entry.Action, err)
+	}
+}
+
+// RecordRequest 以 HTTP 请求的令牌和来源地址作为操作者追加审计记录
+func (s *AuditService) RecordRequest(r *http.Request, entry *database.AuditEntry) {
+	if s == nil || entry == nil {
+		return
+	}
+	entry.Actor, entry.ActorType = AuditActor(r)
+	entry.SourceIP = RequestSourceIP(r)
+	s.Record(entry)
+}
+
+// List 分页查询审计记录
+func (s *AuditService) List(filter *database.AuditFilter, params *database.PaginationParams) (*database.PagedResult[database.AuditEntry], error) {
+	return s.repo.List(filter, params)
+}
+
+// ListForExport 获取符合条件的全部审计记录
+func (s *AuditService) ListForExport(filter *database.AuditFilter) ([]database.AuditEntry, error) {
+	return s.repo.ListForExport(filter)
+}
+
+// ParseAuditFilter 从查询参数解析审计记录过滤条件：
+// actor、actorType、action（以 . 结尾时按前缀匹配）、targetType、targetId、
+// since、until（RFC3339 或 2006-01-02，until 为日期时包含当天）
+func ParseAuditFilter(query url.Values) (*database.AuditFilter, error) {
+	filter := &database.AuditFilter{
+		Actor:      query.Get("actor"),
+		ActorType:  query.Get("actorType"),
+		Action:     query.Get("action"),
+		TargetType: query.Get("targetType"),
+		TargetID:   query.Get("targetId"),
+	}
+	for _, param := range []string{"since", "until"} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			if t, err = time.ParseInLocation("2006-01-02", value, time.Local); err != nil {
+				return nil, fmt.Errorf("invalid %s: %s", param, value)
+			}
+			if param == "until" {
+				t = t.AddDate(0, 0, 1)
+			}
+		}
+		if param == "since" {
+			filter.Since = &t
+		} else {
+			filter.Until = &t
+		}
+	}
+	return filter, nil
+}
+
+// AuditActor 返回请求的操作者：登录的用户名或通过认证的令牌名称，未启用认证时为 anonymous
+func AuditActor(r *http.Request) (string, string) {
+	if user := UserFromContext(r.Context()); user != nil {
+		return user.Username, database.AuditActorUser
+	}
+	if token := APITokenFromContext(r.Context()); token != nil {
+		return token.Name, database.AuditActorToken
+	}
+	return database.AuditActorAnonymous, database.AuditActorAnonymous
+}
+
+// RequestSourceIP 返回请求的来源 IP（不信任 X-Forwarded-For）
+func RequestSourceIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// AuditChanges 比较两个对象的 JSON 字段，返回只包含变化字段的修改前后值（JSON）。
+// 没有变化时返回 nil
+func AuditChanges(before, after interface{}) (json.RawMessage, json.RawMessage) {
+	beforeFields := auditFields(before)
+	afterFields := auditFields(after)
+
+	changedBefore := map[string]interface{}{}
+	changedAfter := map[string]interface{}{}
+	for key, value := range afterFields {
+		if old, ok := beforeFields[key]; !ok || !reflect.DeepEqual(old, value) {
+			changedBefore[key] = beforeFields[key]
+			changedAfter[key] = value
+		}
+	}
+	for key, value := range beforeFields {
+		if _, ok := afterFields[key]; !ok {
+			changedBefore[key] = value
+			changedAfter[key] = nil
+		}
+	}
+	if len(changedAfter) == 0 {
+		return nil, nil
+	}
+	return auditJSON(changedBefore), auditJSON(changedAfter)
+}
+
+// auditFields 将对象转换为 JSON 字段映射
+func auditFields(v interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if v == nil {
+		return fields
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fields
+	}
+	_ = json.Unmarshal(data, &fields)
+	return fields
+}
+
+// auditJSON 将值编码为 JSON，失败时返回 nil
+func auditJSON(v interface{}) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return data
+}
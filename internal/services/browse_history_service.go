@@ -8,13 +8,15 @@ import (
 
 // BrowseHistoryService 处理浏览历史业务逻辑
 type BrowseHistoryService struct {
-	repo *database.BrowseHistoryRepository
+	repo    *database.BrowseHistoryRepository
+	formats *database.VideoFormatRepository
 }
 
 // NewBrowseHistoryService 创建一个新的 BrowseHistoryService
 func NewBrowseHistoryService() *BrowseHistoryService {
 	return &BrowseHistoryService{
-		repo: database.NewBrowseHistoryRepository(),
+		repo:    database.NewBrowseHistoryRepository(),
+		formats: database.NewVideoFormatRepository(),
 	}
 }
 
@@ -46,9 +48,18 @@ func (s *BrowseHistoryService) List(params *database.PaginationParams) (*databas
 	return s.repo.List(params)
 }
 
-// GetByID 按 ID 获取单条浏览记录
+// GetByID 按 ID 获取单条浏览记录（包含可选编码）
 func (s *BrowseHistoryService) GetByID(id string) (*database.BrowseRecord, error) {
-	return s.repo.GetByID(id)
+	record, err := s.repo.GetByID(id)
+	if err != nil || record == nil {
+		return record, err
+	}
+	formats, err := s.formats.List(id)
+	if err != nil {
+		return nil, err
+	}
+	record.Formats = formats
+	return record, nil
 }
 
 // Clear 清空所有浏览记录
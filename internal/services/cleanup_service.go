@@ -2,7 +2,6 @@ package services
 
 import (
 	"fmt"
-	"os"
 	"time"
 
 	"wx_channel/internal/database"
@@ -12,8 +11,8 @@ import (
 type CleanupResult struct {
 	BrowseRecordsDeleted   int64     `json:"browseRecordsDeleted"`
 	DownloadRecordsDeleted int64     `json:"downloadRecordsDeleted"`
-	FilesDeleted           int64     `json:"filesDeleted"`
-	SpaceFreed             int64     `json:"spaceFreed"`
+	FilesDeleted           int64     `json:"filesDeleted"` // 移入回收站的文件数
+	SpaceFreed             int64     `json:"spaceFreed"`   // 移入回收站的文件大小，清空回收站后释放
 	CleanupTime            time.Time `json:"cleanupTime"`
 	Errors                 []string  `json:"errors,omitempty"`
 }
@@ -24,6 +23,7 @@ type CleanupService struct {
 	browseRepo   *database.BrowseHistoryRepository
 	downloadRepo *database.DownloadRecordRepository
 	settingsRepo *database.SettingsRepository
+	audit        *AuditService
 }
 
 // NewCleanupService 创建一个新的 CleanupService
@@ -32,6 +32,7 @@ func NewCleanupService() *CleanupService {
 		browseRepo:   database.NewBrowseHistoryRepository(),
 		downloadRepo: database.NewDownloadRecordRepository(),
 		settingsRepo: database.NewSettingsRepository(),
+		audit:        NewAuditService(),
 	}
 }
 
@@ -48,6 +49,7 @@ func (s *CleanupService) ClearBrowseHistory() (*CleanupResult, error) {
 	if err := s.browseRepo.Clear(); err != nil {
 		return nil, fmt.Errorf("failed to clear browse history: %w", err)
 	}
+	s.record("browse.clear", "browse_record", nil, fmt.Sprintf("deleted=%d", count))
 
 	return &CleanupResult{
 		BrowseRecordsDeleted: count,
@@ -63,34 +65,23 @@ func (s *CleanupService) ClearDownloadRecords(deleteFiles bool) (*CleanupResult,
 		Errors:      []string{},
 	}
 
-	// 清空前获取所有记录（用于删除文件）
+	// 清空前获取所有记录（用于移动文件）
 	records, err := s.downloadRepo.GetAll()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get download records: %w", err)
 	}
 
-	// 如果请求则删除文件
-	if deleteFiles {
-		for _, record := range records {
-			if record.FilePath != "" {
-				fileInfo, err := os.Stat(record.FilePath)
-				if err == nil {
-					result.SpaceFreed += fileInfo.Size()
-					if err := os.Remove(record.FilePath); err != nil {
-						result.Errors = append(result.Errors, fmt.Sprintf("failed to delete file %s: %v", record.FilePath, err))
-					} else {
-						result.FilesDeleted++
-					}
-				}
-			}
-		}
-	}
-
-	// 清空所有记录
+	// 将所有记录移入回收站
 	if err := s.downloadRepo.Clear(); err != nil {
 		return nil, fmt.Errorf("failed to clear download records: %w", err)
 	}
 
+	// 如果请求则将文件移入回收站
+	if deleteFiles {
+		s.trashFiles(records, result)
+	}
+	s.record("downloads.clear", "download_record", nil, fmt.Sprintf("deleted=%d deleteFiles=%t", len(records), deleteFiles))
+
 	result.DownloadRecordsDeleted = int64(len(records))
 	return result, nil
 }
@@ -102,6 +93,7 @@ func (s *CleanupService) DeleteBrowseRecordsBefore(date time.Time) (*CleanupResu
 	if err != nil {
 		return nil, fmt.Errorf("failed to delete browse records before %v: %w", date, err)
 	}
+	s.record("browse.delete_before", "browse_record", nil, fmt.Sprintf("before=%s deleted=%d", date.Format(time.RFC3339), count))
 
 	return &CleanupResult{
 		BrowseRecordsDeleted: count,
@@ -117,34 +109,29 @@ func (s *CleanupService) DeleteDownloadRecordsBefore(date time.Time, deleteFiles
 		Errors:      []string{},
 	}
 
-	// 如果需要删除文件，我们需要先获取记录
+	// 如果需要移动文件，我们需要先获取记录
+	var records []database.DownloadRecord
 	if deleteFiles {
 		// 获取所有记录并按日期过滤
-		records, err := s.downloadRepo.GetAll()
+		all, err := s.downloadRepo.GetAll()
 		if err != nil {
 			return nil, fmt.Errorf("failed to get download records: %w", err)
 		}
-
-		for _, record := range records {
-			if record.DownloadTime.Before(date) && record.FilePath != "" {
-				fileInfo, err := os.Stat(record.FilePath)
-				if err == nil {
-					result.SpaceFreed += fileInfo.Size()
-					if err := os.Remove(record.FilePath); err != nil {
-						result.Errors = append(result.Errors, fmt.Sprintf("failed to delete file %s: %v", record.FilePath, err))
-					} else {
-						result.FilesDeleted++
-					}
-				}
+		for _, record := range all {
+			if record.DownloadTime.Before(date) {
+				records = append(records, record)
 			}
 		}
 	}
 
-	// 从数据库删除记录
+	// 将记录移入回收站
 	count, err := s.downloadRepo.DeleteBefore(date)
 	if err != nil {
 		return nil, fmt.Errorf("failed to delete download records before %v: %w", date, err)
 	}
+	s.trashFiles(records, result)
+	s.record("downloads.delete_before", "download_record", nil,
+		fmt.Sprintf("before=%s deleted=%d deleteFiles=%t", date.Format(time.RFC3339), count, deleteFiles))
 
 	result.DownloadRecordsDeleted = count
 	return result, nil
@@ -188,6 +175,7 @@ func (s *CleanupService) DeleteSelectedBrowseRecords(ids []string) (*CleanupResu
 	if err != nil {
 		return nil, fmt.Errorf("failed to delete selected browse records: %w", err)
 	}
+	s.record("browse.delete", "browse_record", ids, fmt.Sprintf("deleted=%d", count))
 
 	return &CleanupResult{
 		BrowseRecordsDeleted: count,
@@ -203,33 +191,23 @@ func (s *CleanupService) DeleteSelectedDownloadRecords(ids []string, deleteFiles
 		Errors:      []string{},
 	}
 
-	// 如果需要删除文件，先获取记录
+	// 如果需要移动文件，先获取记录
+	var records []database.DownloadRecord
 	if deleteFiles {
-		records, err := s.downloadRepo.GetByIDs(ids)
+		var err error
+		records, err = s.downloadRepo.GetByIDs(ids)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get download records: %w", err)
 		}
-
-		for _, record := range records {
-			if record.FilePath != "" {
-				fileInfo, err := os.Stat(record.FilePath)
-				if err == nil {
-					result.SpaceFreed += fileInfo.Size()
-					if err := os.Remove(record.FilePath); err != nil {
-						result.Errors = append(result.Errors, fmt.Sprintf("failed to delete file %s: %v", record.FilePath, err))
-					} else {
-						result.FilesDeleted++
-					}
-				}
-			}
-		}
 	}
 
-	// 从数据库删除记录
+	// 将记录移入回收站
 	count, err := s.downloadRepo.DeleteMany(ids)
 	if err != nil {
 		return nil, fmt.Errorf("failed to delete selected download records: %w", err)
 	}
+	s.trashFiles(records, result)
+	s.record("downloads.delete", "download_record", ids, fmt.Sprintf("deleted=%d deleteFiles=%t", count, deleteFiles))
 
 	result.DownloadRecordsDeleted = count
 	return result, nil
@@ -262,3 +240,30 @@ func (s *CleanupService) CleanupAll(deleteFiles bool) (*CleanupResult, error) {
 
 	return result, nil
 }
+
+// trashFiles 将已删除记录的文件移入回收站，SpaceFreed 为移入回收站的文件大小
+func (s *CleanupService) trashFiles(records []database.DownloadRecord, result *CleanupResult) {
+	for i := range records {
+		size, err := moveDownloadToTrash(s.downloadRepo, &records[i])
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("failed to move file %s to trash: %v", records[i].FilePath, err))
+			continue
+		}
+		if size > 0 {
+			result.SpaceFreed += size
+			result.FilesDeleted++
+		}
+	}
+}
+
+// record 以 system 身份记录清理操作的审计日志
+func (s *CleanupService) record(action, targetType string, ids []string, detail string) {
+	s.audit.Record(&database.AuditEntry{
+		Actor:      database.AuditActorSystem,
+		ActorType:  database.AuditActorSystem,
+		Action:     action,
+		TargetType: targetType,
+		TargetIDs:  ids,
+		Detail:     detail,
+	})
+}
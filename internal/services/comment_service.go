@@ -0,0 +1,231 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"wx_channel/internal/database"
+)
+
+// CommentThread 表示一个视频的评论楼层结构
+type CommentThread struct {
+	Video    *database.CommentVideo `json:"video"`
+	Comments []database.Comment     `json:"comments"` // 一级评论，回复位于 Replies 中
+	Total    int                    `json:"total"`    // 一级评论 + 回复总数
+}
+
+// CommentService 处理评论存储与查询业务逻辑
+type CommentService struct {
+	repo *database.CommentRepository
+}
+
+// NewCommentService 创建一个新的 CommentService
+func NewCommentService() *CommentService {
+	return &CommentService{
+		repo: database.NewCommentRepository(),
+	}
+}
+
+// Ingest 将注入脚本上报的原始评论数组写入数据库，返回本次所属的采集批次。
+// fullUpdate 表示脚本已完成采集，加载到的评论数也达到原始评论数时，未出现的旧评论会被标记为已删除。
+func (s *CommentService) Ingest(videoID, videoTitle string, originalCount int, raw []map[string]interface{}, collectedAt time.Time, fullUpdate bool) (*database.CommentRun, error) {
+	if videoID == "" {
+		return nil, fmt.Errorf("video ID is required")
+	}
+
+	video := &database.CommentVideo{
+		VideoID:              videoID,
+		VideoTitle:           videoTitle,
+		OriginalCommentCount: int64(originalCount),
+		LastCollectedAt:      collectedAt,
+	}
+	return s.repo.SaveVideoComments(video, FlattenRawComments(raw), fullUpdate)
+}
+
+// GetThread 获取视频评论的楼层结构，视频未采集过时返回 nil
+func (s *CommentService) GetThread(videoID string) (*CommentThread, error) {
+	video, err := s.repo.GetVideo(videoID)
+	if err != nil {
+		return nil, err
+	}
+	if video == nil {
+		return nil, nil
+	}
+
+	comments, err := s.repo.ListByVideo(videoID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CommentThread{
+		Video:    video,
+		Comments: BuildCommentTree(comments),
+		Total:    len(comments),
+	}, nil
+}
+
+// ListVideos 获取已采集评论的视频列表
+func (s *CommentService) ListVideos(params *database.PaginationParams) (*database.PagedResult[database.CommentVideo], error) {
+	return s.repo.ListVideos(params)
+}
+
+// Search 全文搜索评论
+func (s *CommentService) Search(query, videoID string, params *database.PaginationParams) (*database.PagedResult[database.CommentSearchResult], error) {
+	return s.repo.Search(query, videoID, params)
+}
+
+// GetAnalytics 获取评论统计（可按视频或视频作者限定范围）
+func (s *CommentService) GetAnalytics(filter *database.CommentAnalyticsFilter) (*database.CommentAnalytics, error) {
+	if filter == nil {
+		filter = &database.CommentAnalyticsFilter{}
+	}
+	return s.repo.GetAnalytics(filter)
+}
+
+// ListRuns 获取视频的采集批次
+func (s *CommentService) ListRuns(videoID string) ([]database.CommentRun, error) {
+	return s.repo.ListRuns(videoID)
+}
+
+// GetDiff 比较两个采集批次。toRunID 为 0 时取最新批次，fromRunID 为 0 时取 to 的上一个批次
+func (s *CommentService) GetDiff(videoID string, fromRunID, toRunID int64) (*database.CommentRunDiff, error) {
+	if fromRunID == 0 || toRunID == 0 {
+		runs, err := s.repo.ListRuns(videoID)
+		if err != nil {
+			return nil, err
+		}
+		// runs 按 ID 倒序
+		for i, run := range runs {
+			if toRunID == 0 {
+				toRunID = run.ID
+			}
+			if run.ID == toRunID {
+				if fromRunID == 0 && i+1 < len(runs) {
+					fromRunID = runs[i+1].ID
+				}
+				break
+			}
+		}
+		if fromRunID == 0 || toRunID == 0 {
+			return nil, fmt.Errorf("at least two collection runs are required to compute a diff")
+		}
+	}
+	return s.repo.GetRunDiff(videoID, fromRunID, toRunID)
+}
+
+// Delete 删除视频的全部评论
+func (s *CommentService) Delete(videoID string) error {
+	return s.repo.DeleteByVideo(videoID)
+}
+
+// BuildCommentTree 将平铺的评论按 ParentID 组装为一级评论 + 回复的结构。
+// 找不到父评论的回复会作为一级评论返回，避免数据丢失。
+func BuildCommentTree(comments []database.Comment) []database.Comment {
+	topLevel := make([]database.Comment, 0, len(comments))
+	index := make(map[string]int)
+	for _, c := range comments {
+		if c.ParentID == "" {
+			index[c.ID] = len(topLevel)
+			topLevel = append(topLevel, c)
+		}
+	}
+	for _, c := range comments {
+		if c.ParentID == "" {
+			continue
+		}
+		if i, ok := index[c.ParentID]; ok {
+			topLevel[i].Replies = append(topLevel[i].Replies, c)
+		} else {
+			topLevel = append(topLevel, c)
+		}
+	}
+	return topLevel
+}
+
+// FlattenRawComments 将脚本上报的嵌套评论（levelTwoComment）展开为平铺列表，
+// 所有层级的回复都归属到其一级评论下
+func FlattenRawComments(raw []map[string]interface{}) []database.Comment {
+	var result []database.Comment
+	var walk func(item map[string]interface{}, parentID string)
+	walk = func(item map[string]interface{}, parentID string) {
+		c := parseRawComment(item)
+		if c.ID == "" {
+			return
+		}
+		c.ParentID = parentID
+		result = append(result, c)
+
+		rootID := parentID
+		if rootID == "" {
+			rootID = c.ID
+		}
+		if replies, ok := item["levelTwoComment"].([]interface{}); ok {
+			for _, reply := range replies {
+				if m, ok := reply.(map[string]interface{}); ok {
+					walk(m, rootID)
+				}
+			}
+		}
+	}
+	for _, item := range raw {
+		walk(item, "")
+	}
+	return result
+}
+
+// parseRawComment 解析单条原始评论
+func parseRawComment(item map[string]interface{}) database.Comment {
+	c := database.Comment{
+		ID:              rawString(item, "id", "commentId"),
+		ReplyToID:       rawString(item, "replyCommentId"),
+		ReplyToNickname: rawString(item, "replyNickname"),
+		Author:          rawString(item, "nickname"),
+		AuthorAvatar:    rawString(item, "headUrl"),
+		Content:         rawString(item, "content"),
+		LikeCount:       rawInt(item, "likeCount"),
+		ReplyCount:      rawInt(item, "expandCommentCount", "replyCount"),
+		IPRegion:        rawString(item, "ipLocation", "ipRegion"),
+	}
+	if ts := rawInt(item, "createTime", "createtime"); ts > 0 {
+		// 兼容毫秒时间戳
+		if ts > 1e12 {
+			c.CommentTime = time.UnixMilli(ts)
+		} else {
+			c.CommentTime = time.Unix(ts, 0)
+		}
+	}
+	return c
+}
+
+// rawString 按顺序读取第一个非空的字符串字段
+func rawString(item map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		switch v := item[key].(type) {
+		case string:
+			if v != "" {
+				return v
+			}
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+	return ""
+}
+
+// rawInt 按顺序读取第一个非零的数值字段（兼容字符串形式的数字）
+func rawInt(item map[string]interface{}, keys ...string) int64 {
+	for _, key := range keys {
+		switch v := item[key].(type) {
+		case float64:
+			if v != 0 {
+				return int64(v)
+			}
+		case string:
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil && n != 0 {
+				return n
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,555 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"wx_channel/internal/database"
+	"wx_channel/internal/utils"
+	"wx_channel/internal/websocket"
+)
+
+const (
+	defaultCrawlInterval = 3 * time.Second // 两次请求微信之间的默认间隔
+	minCrawlInterval     = time.Second
+	crawlMaxRetries      = 3
+)
+
+// ErrCrawlJobNotFound 抓取任务不存在
+var ErrCrawlJobNotFound = errors.New("crawl job not found")
+
+// CrawlRequest 创建抓取任务的参数
+type CrawlRequest struct {
+	Username   string `json:"username"`
+	Author     string `json:"author"`
+	Enqueue    bool   `json:"enqueue"`    // 是否把符合条件的视频加入下载队列
+	Keyword    string `json:"keyword"`    // 标题关键词，多个用逗号分隔（任一匹配）
+	Since      string `json:"since"`      // 发布日期下限 YYYY-MM-DD
+	Until      string `json:"until"`      // 发布日期上限 YYYY-MM-DD（含当天）
+	MaxPages   int    `json:"maxPages"`   // 最多抓取页数，0 表示直到没有更多
+	IntervalMs int    `json:"intervalMs"` // 请求间隔（毫秒），默认 3000，最小 1000
+}
+
+// crawlRunner 正在运行的任务
+type crawlRunner struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// CrawlService 逐页抓取账号的全部视频，保存发现的视频并按条件加入下载队列
+type CrawlService struct {
+	repo        *database.CrawlRepository
+	queue       *QueueService
+	hub         *websocket.Hub
+	mu          sync.Mutex
+	runners     map[int64]*crawlRunner
+	recoverOnce sync.Once
+	onEnqueued  func([]database.QueueItem)
+}
+
+var (
+	crawlService     *CrawlService
+	crawlServiceOnce sync.Once
+)
+
+// GetCrawlService 获取全局抓取服务（任务状态需要在多个处理器之间共享）
+func GetCrawlService(hub *websocket.Hub) *CrawlService {
+	crawlServiceOnce.Do(func() {
+		crawlService = NewCrawlService(hub)
+	})
+	return crawlService
+}
+
+// NewCrawlService 创建一个新的 CrawlService
+func NewCrawlService(hub *websocket.Hub) *CrawlService {
+	return &CrawlService{
+		repo:    database.NewCrawlRepository(),
+		queue:   NewQueueService(),
+		hub:     hub,
+		runners: make(map[int64]*crawlRunner),
+	}
+}
+
+// SetEnqueuedHandler 设置视频加入下载队列后的回调（用于广播队列变更）
+func (s *CrawlService) SetEnqueuedHandler(fn func([]database.QueueItem)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onEnqueued = fn
+}
+
+// recoverJobs 程序重启后，上次未结束的任务标记为暂停，可手动继续
+func (s *CrawlService) recoverJobs() {
+	s.recoverOnce.Do(func() {
+		jobs, err := s.repo.ListJobsByStatus(database.CrawlStatusRunning)
+		if err != nil {
+			utils.Warn("[抓取] 恢复任务状态失败: %v", err)
+			return
+		}
+		for i := range jobs {
+			jobs[i].Status = database.CrawlStatusPaused
+			if err := s.repo.UpdateJob(&jobs[i]); err != nil {
+				utils.Warn("[抓取] 恢复任务状态失败: %v", err)
+			}
+		}
+	})
+}
+
+// Start 创建并启动抓取任务
+func (s *CrawlService) Start(req *CrawlRequest) (*database.CrawlJob, error) {
+	s.recoverJobs()
+	if s.hub == nil {
+		return nil, fmt.Errorf("websocket hub not available")
+	}
+
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Username == "" {
+		return nil, fmt.Errorf("username is required")
+	}
+	if req.MaxPages < 0 {
+		return nil, fmt.Errorf("maxPages must not be negative")
+	}
+
+	job := &database.CrawlJob{
+		Username:   req.Username,
+		Author:     req.Author,
+		Status:     database.CrawlStatusRunning,
+		Enqueue:    req.Enqueue,
+		Keyword:    strings.TrimSpace(req.Keyword),
+		MaxPages:   req.MaxPages,
+		IntervalMs: req.IntervalMs,
+	}
+	if job.IntervalMs <= 0 {
+		job.IntervalMs = int(defaultCrawlInterval / time.Millisecond)
+	}
+	if job.IntervalMs < int(minCrawlInterval/time.Millisecond) {
+		job.IntervalMs = int(minCrawlInterval / time.Millisecond)
+	}
+	if req.Since != "" {
+		t, err := time.ParseInLocation("2006-01-02", req.Since, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid since date: %s", req.Since)
+		}
+		job.Since = &t
+	}
+	if req.Until != "" {
+		t, err := time.ParseInLocation("2006-01-02", req.Until, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid until date: %s", req.Until)
+		}
+		// 包含当天
+		t = t.Add(24*time.Hour - time.Second)
+		job.Until = &t
+	}
+
+	if err := s.repo.CreateJob(job); err != nil {
+		return nil, err
+	}
+	s.launch(job)
+	return job, nil
+}
+
+// Get 获取任务
+func (s *CrawlService) Get(id int64) (*database.CrawlJob, error) {
+	s.recoverJobs()
+	return s.repo.GetJob(id)
+}
+
+// List 获取所有任务
+func (s *CrawlService) List() ([]database.CrawlJob, error) {
+	s.recoverJobs()
+	return s.repo.ListJobs()
+}
+
+// ListVideos 获取任务发现的视频
+func (s *CrawlService) ListVideos(id int64, params *database.PaginationParams) (*database.PagedResult[database.CrawlVideo], error) {
+	return s.repo.ListVideos(id, params)
+}
+
+// Pause 暂停任务，已抓取的进度会保留
+func (s *CrawlService) Pause(id int64) (*database.CrawlJob, error) {
+	return s.stop(id, database.CrawlStatusPaused)
+}
+
+// Cancel 取消任务
+func (s *CrawlService) Cancel(id int64) (*database.CrawlJob, error) {
+	return s.stop(id, database.CrawlStatusCancelled)
+}
+
+// Resume 从上次的位置继续抓取暂停或失败的任务
+func (s *CrawlService) Resume(id int64) (*database.CrawlJob, error) {
+	s.recoverJobs()
+	if s.hub == nil {
+		return nil, fmt.Errorf("websocket hub not available")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, err := s.repo.GetJob(id)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, ErrCrawlJobNotFound
+	}
+	if job.Status != database.CrawlStatusPaused && job.Status != database.CrawlStatusFailed {
+		return nil, fmt.Errorf("crawl job is %s", job.Status)
+	}
+
+	job.Status = database.CrawlStatusRunning
+	job.ErrorMessage = ""
+	job.FinishedAt = nil
+	if err := s.repo.UpdateJob(job); err != nil {
+		return nil, err
+	}
+	s.launchLocked(job)
+	return job, nil
+}
+
+// stop 停止正在运行的任务并写入最终状态
+func (s *CrawlService) stop(id int64, status string) (*database.CrawlJob, error) {
+	s.recoverJobs()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, err := s.repo.GetJob(id)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, ErrCrawlJobNotFound
+	}
+	if job.Status != database.CrawlStatusRunning && !(status == database.CrawlStatusCancelled && job.Status == database.CrawlStatusPaused) {
+		return nil, fmt.Errorf("crawl job is %s", job.Status)
+	}
+
+	// 持有锁时取消，运行中的任务在写入进度前会检查 ctx，不会覆盖这里的状态
+	if runner, ok := s.runners[id]; ok {
+		runner.cancel()
+		delete(s.runners, id)
+	}
+
+	job.Status = status
+	if status == database.CrawlStatusCancelled {
+		now := time.Now()
+		job.FinishedAt = &now
+	}
+	if err := s.repo.UpdateJob(job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// launch 启动任务协程
+func (s *CrawlService) launch(job *database.CrawlJob) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.launchLocked(job)
+}
+
+func (s *CrawlService) launchLocked(job *database.CrawlJob) {
+	ctx, cancel := context.WithCancel(context.Background())
+	runner := &crawlRunner{ctx: ctx, cancel: cancel}
+	s.runners[job.ID] = runner
+
+	jobCopy := *job
+	go s.run(runner, &jobCopy)
+}
+
+// run 逐页抓取直到没有更多、达到页数上限或早于 since
+func (s *CrawlService) run(runner *crawlRunner, job *database.CrawlJob) {
+	defer func() {
+		if r := recover(); r != nil {
+			utils.LogError("[抓取] 任务 %d panic: %v", job.ID, r)
+			s.finish(runner, job, database.CrawlStatusFailed, fmt.Sprintf("panic: %v", r))
+		}
+	}()
+
+	utils.Info("[抓取] 开始抓取账号 %s (任务 %d)", job.Username, job.ID)
+	interval := time.Duration(job.IntervalMs) * time.Millisecond
+	query := websocket.PageQuery{Key: websocket.APIKeyFeedList, Query: job.Username, Marker: job.NextMarker}
+	retries := 0
+
+	for {
+		if runner.ctx.Err() != nil {
+			return
+		}
+
+		// 微信返回错误码（如频率限制）时 FetchPages 返回 ErrPageErrCode，同样退避重试，
+		// 不能当作没有更多的最后一页结束任务
+		result, err := s.hub.Pager().FetchPages(query, 1, false)
+		if err != nil {
+			retries++
+			utils.Warn("[抓取] 任务 %d 请求失败 (%d/%d): %v", job.ID, retries, crawlMaxRetries, err)
+			if retries >= crawlMaxRetries {
+				s.finish(runner, job, database.CrawlStatusFailed, err.Error())
+				return
+			}
+			if !sleepContext(runner.ctx, interval*time.Duration(1<<retries)) {
+				return
+			}
+			continue
+		}
+		retries = 0
+
+		done, ok := s.processPage(runner, job, result)
+		if !ok {
+			return
+		}
+		if done {
+			s.finish(runner, job, database.CrawlStatusCompleted, "")
+			utils.Info("[抓取] 任务 %d 完成: %d 页, 发现 %d 个视频, 加入队列 %d 个",
+				job.ID, job.Pages, job.DiscoveredCount, job.EnqueuedCount)
+			return
+		}
+		query.Marker = result.NextMarker
+
+		// 缓存命中的页没有请求微信，不需要等待
+		if !result.Cached && !sleepContext(runner.ctx, interval) {
+			return
+		}
+	}
+}
+
+// processPage 保存一页结果并更新进度。ok 为 false 表示任务已被暂停或取消
+func (s *CrawlService) processPage(runner *crawlRunner, job *database.CrawlJob, result *websocket.PageResult) (done bool, ok bool) {
+	videos := make([]database.CrawlVideo, 0, len(result.Items))
+	olderThanSince := job.Since != nil && len(result.Items) > 0
+	for _, item := range result.Items {
+		v, err := ParseCrawlVideo(item)
+		if err != nil || v == nil {
+			continue
+		}
+		videos = append(videos, *v)
+		if v.PublishTime == nil || !v.PublishTime.Before(*job.Since) {
+			olderThanSince = false
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if runner.ctx.Err() != nil {
+		return false, false
+	}
+
+	added, err := s.repo.SaveVideos(job.ID, videos)
+	if err != nil {
+		utils.Warn("[抓取] 任务 %d 保存视频失败: %v", job.ID, err)
+	}
+	if job.Author == "" && len(videos) > 0 {
+		job.Author = videos[0].Author
+	}
+	job.Pages++
+	job.DiscoveredCount += len(added)
+	job.NextMarker = result.NextMarker
+
+	if job.Enqueue {
+		job.EnqueuedCount += s.enqueue(job, added)
+	}
+
+	// 列表按发布时间倒序，整页都早于 since 时后面的页也不会符合条件
+	done = !result.HasMore || (job.MaxPages > 0 && job.Pages >= job.MaxPages) || olderThanSince
+	if err := s.repo.UpdateJob(job); err != nil {
+		utils.Warn("[抓取] 任务 %d 保存进度失败: %v", job.ID, err)
+	}
+	return done, true
+}
+
+// enqueue 将符合条件且不在队列中的视频加入下载队列，返回加入数量
+func (s *CrawlService) enqueue(job *database.CrawlJob, videos []database.CrawlVideo) int {
+	queued := map[string]bool{}
+	if items, err := s.queue.GetQueue(); err == nil {
+		for _, item := range items {
+			if item.Status != database.QueueStatusFailed {
+				queued[item.VideoID] = true
+			}
+		}
+	}
+
+	infos := []VideoInfo{}
+	ids := []string{}
+	for _, v := range videos {
+		if queued[v.VideoID] || !MatchCrawlFilter(job, &v) {
+			continue
+		}
+		infos = append(infos, VideoInfo{
+			VideoID:    v.VideoID,
+			Title:      v.Title,
+			Author:     v.Author,
+			CoverURL:   v.CoverURL,
+			VideoURL:   v.VideoURL,
+			DecryptKey: v.DecryptKey,
+			Duration:   v.Duration,
+			Size:       v.Size,
+			NonceID:    v.NonceID,
+		})
+		ids = append(ids, v.VideoID)
+	}
+	if len(infos) == 0 {
+		return 0
+	}
+
+	items, err := s.queue.AddToQueue(infos)
+	if err != nil {
+		utils.Warn("[抓取] 任务 %d 加入下载队列失败: %v", job.ID, err)
+		return 0
+	}
+	if err := s.repo.MarkEnqueued(job.ID, ids); err != nil {
+		utils.Warn("[抓取] 任务 %d 标记入队失败: %v", job.ID, err)
+	}
+	if s.onEnqueued != nil {
+		s.onEnqueued(items)
+	}
+	return len(items)
+}
+
+// finish 写入任务的最终状态（任务已被暂停或取消时不覆盖）
+func (s *CrawlService) finish(runner *crawlRunner, job *database.CrawlJob, status, errMsg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if runner.ctx.Err() != nil {
+		return
+	}
+	runner.cancel()
+	delete(s.runners, job.ID)
+
+	now := time.Now()
+	job.Status = status
+	job.ErrorMessage = errMsg
+	job.FinishedAt = &now
+	if err := s.repo.UpdateJob(job); err != nil {
+		utils.Warn("[抓取] 任务 %d 保存状态失败: %v", job.ID, err)
+	}
+}
+
+// MatchCrawlFilter 判断视频是否符合任务的入队条件（只有视频类型可下载）
+func MatchCrawlFilter(job *database.CrawlJob, v *database.CrawlVideo) bool {
+	if v.MediaType != 4 || v.VideoURL == "" {
+		return false
+	}
+	if job.Since != nil && (v.PublishTime == nil || v.PublishTime.Before(*job.Since)) {
+		return false
+	}
+	if job.Until != nil && (v.PublishTime == nil || v.PublishTime.After(*job.Until)) {
+		return false
+	}
+	return matchTitleKeywords(job.Keyword, v.Title)
+}
+
+// matchTitleKeywords 标题包含任一关键词（逗号分隔，不区分大小写）时返回 true，没有关键词时总是匹配
+func matchTitleKeywords(keyword, title string) bool {
+	if strings.TrimSpace(keyword) == "" {
+		return true
+	}
+	title = strings.ToLower(title)
+	for _, kw := range strings.Split(keyword, ",") {
+		if kw = strings.TrimSpace(kw); kw != "" && strings.Contains(title, strings.ToLower(kw)) {
+			return true
+		}
+	}
+	return false
+}
+
+// crawlFeedObject 视频列表中的一条动态（只包含需要的字段）
+type crawlFeedObject struct {
+	ID            flexString `json:"id"`
+	ObjectNonceID string     `json:"objectNonceId"`
+	CreateTime    int64      `json:"createtime"`
+	Contact       struct {
+		Nickname string `json:"nickname"`
+	} `json:"contact"`
+	ObjectDesc struct {
+		Description string `json:"description"`
+		MediaType   int    `json:"mediaType"`
+		Media       []struct {
+			URL          string `json:"url"`
+			URLToken     string `json:"urlToken"`
+			DecodeKey    string `json:"decodeKey"`
+			ThumbURL     string `json:"thumbUrl"`
+			CoverURL     string `json:"coverUrl"`
+			FileSize     int64  `json:"fileSize"`
+			VideoPlayLen int64  `json:"videoPlayLen"`
+			Spec         []struct {
+				DurationMs int64 `json:"durationMs"`
+			} `json:"spec"`
+		} `json:"media"`
+	} `json:"objectDesc"`
+}
+
+// ParseCrawlVideo 从视频列表的一条动态中提取视频信息（与注入脚本的 format_feed 一致），
+// 没有 ID 的条目返回 nil
+func ParseCrawlVideo(item json.RawMessage) (*database.CrawlVideo, error) {
+	var obj crawlFeedObject
+	if err := json.Unmarshal(item, &obj); err != nil {
+		return nil, fmt.Errorf("failed to parse feed object: %w", err)
+	}
+	if obj.ID == "" {
+		return nil, nil
+	}
+
+	v := &database.CrawlVideo{
+		VideoID:   string(obj.ID),
+		NonceID:   obj.ObjectNonceID,
+		Title:     strings.TrimSpace(obj.ObjectDesc.Description),
+		Author:    obj.Contact.Nickname,
+		MediaType: obj.ObjectDesc.MediaType,
+	}
+	if obj.CreateTime > 0 {
+		t := time.Unix(obj.CreateTime, 0)
+		v.PublishTime = &t
+	}
+	if len(obj.ObjectDesc.Media) > 0 {
+		media := obj.ObjectDesc.Media[0]
+		v.CoverURL = media.ThumbURL
+		if v.CoverURL == "" {
+			v.CoverURL = media.CoverURL
+		}
+		v.Size = media.FileSize
+		if v.MediaType == 4 {
+			v.VideoURL = media.URL + media.URLToken
+			v.DecryptKey = media.DecodeKey
+		}
+		if len(media.Spec) > 0 && media.Spec[0].DurationMs > 0 {
+			v.Duration = media.Spec[0].DurationMs
+		} else {
+			v.Duration = media.VideoPlayLen * 1000
+		}
+	}
+	return v, nil
+}
+
+// flexString 兼容数字和字符串两种 JSON 表示
+type flexString string
+
+func (f *flexString) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*f = flexString(s)
+		return nil
+	}
+	if string(data) == "null" {
+		return nil
+	}
+	*f = flexString(data)
+	return nil
+}
+
+// sleepContext 等待指定时间，ctx 被取消时返回 false
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
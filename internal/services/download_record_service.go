@@ -1,10 +1,13 @@
 package services
 
 import (
-	"os"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"wx_channel/internal/database"
+	"wx_channel/internal/utils"
 )
 
 // DownloadRecordService 处理下载记录业务逻辑
@@ -40,89 +43,145 @@ func (s *DownloadRecordService) GetByID(id string) (*database.DownloadRecord, er
 	return s.repo.GetByID(id)
 }
 
-// Delete 按 ID 删除下载记录（可选删除文件）
+// Delete 按 ID 将下载记录移入回收站（可选将文件移入回收站）。
+// 先移动文件，移动失败时不删除记录并返回错误
 // Requirements: 5.3 - 删除记录（可选择保留或删除文件）
 func (s *DownloadRecordService) Delete(id string, deleteFile bool) error {
-	if deleteFile {
-		record, err := s.repo.GetByID(id)
-		if err != nil {
+	record, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if deleteFile && record != nil {
+		if _, err := s.trashFiles([]database.DownloadRecord{*record}); err != nil {
 			return err
 		}
-		if record != nil && record.FilePath != "" {
-			// 尝试删除文件，如果文件不存在则忽略错误
-			_ = os.Remove(record.FilePath)
-		}
 	}
 	return s.repo.Delete(id)
 }
 
-// DeleteMany 按 ID 批量删除下载记录（可选删除文件）
+// DeleteMany 按 ID 将下载记录批量移入回收站（可选将文件移入回收站）。
+// 文件移动失败的记录不会删除，其余记录照常删除，返回的错误列出失败的文件
 // Requirements: 5.3 - 批量删除（可选择保留或删除文件）
 func (s *DownloadRecordService) DeleteMany(ids []string, deleteFiles bool) (int64, error) {
+	var moveErr error
 	if deleteFiles {
 		records, err := s.repo.GetByIDs(ids)
 		if err != nil {
 			return 0, err
 		}
-		for _, record := range records {
-			if record.FilePath != "" {
-				// 尝试删除文件，如果文件不存在则忽略错误
-				_ = os.Remove(record.FilePath)
-			}
-		}
+		var failed map[string]bool
+		failed, moveErr = s.trashFiles(records)
+		ids = excludeIDs(ids, failed)
+	}
+	n, err := s.repo.DeleteMany(ids)
+	if err != nil {
+		return 0, err
 	}
-	return s.repo.DeleteMany(ids)
+	return n, moveErr
 }
 
-// Clear 清空所有下载记录（可选删除文件）
+// Clear 将所有下载记录移入回收站（可选将文件移入回收站）。
+// 有文件移动失败时只删除文件已移动的记录，并返回错误
 // Requirements: 5.3 - 清空记录（可选择保留或删除文件）
 func (s *DownloadRecordService) Clear(deleteFiles bool) error {
-	if deleteFiles {
-		records, err := s.repo.GetAll()
+	if !deleteFiles {
+		return s.repo.Clear()
+	}
+	records, err := s.repo.GetAll()
+	if err != nil {
+		return err
+	}
+	failed, moveErr := s.trashFiles(records)
+	if moveErr == nil {
+		return s.repo.Clear()
+	}
+	if _, err := s.repo.DeleteMany(excludeIDs(recordIDs(records), failed)); err != nil {
+		return err
+	}
+	return moveErr
+}
+
+// DeleteBefore 将指定日期前的所有记录移入回收站（可选将文件移入回收站）。
+// 有文件移动失败时只删除文件已移动的记录，并返回错误
+func (s *DownloadRecordService) DeleteBefore(date time.Time, deleteFiles bool) (int64, error) {
+	if !deleteFiles {
+		return s.repo.DeleteBefore(date)
+	}
+
+	// 分页获取日期前的所有记录以移动文件
+	var records []database.DownloadRecord
+	const batchSize = 500
+	page := 1
+	for {
+		params := &database.FilterParams{
+			PaginationParams: database.PaginationParams{
+				Page:     page,
+				PageSize: batchSize,
+			},
+			EndDate: &date,
+		}
+		result, err := s.repo.List(params)
 		if err != nil {
-			return err
+			return 0, err
 		}
-		for _, record := range records {
-			if record.FilePath != "" {
-				// 尝试删除文件，如果文件不存在则忽略错误
-				_ = os.Remove(record.FilePath)
-			}
+		records = append(records, result.Items...)
+		// 如果这一页数据不足一批，说明没有更多了
+		if len(result.Items) < batchSize {
+			break
 		}
+		page++
+	}
+
+	failed, moveErr := s.trashFiles(records)
+	if moveErr == nil {
+		return s.repo.DeleteBefore(date)
+	}
+	n, err := s.repo.DeleteMany(excludeIDs(recordIDs(records), failed))
+	if err != nil {
+		return 0, err
 	}
-	return s.repo.Clear()
+	return n, moveErr
 }
 
-// DeleteBefore 删除指定日期前的所有记录（可选删除文件）
-func (s *DownloadRecordService) DeleteBefore(date time.Time, deleteFiles bool) (int64, error) {
-	if deleteFiles {
-		// 分页获取日期前的所有记录以删除文件
-		const batchSize = 500
-		page := 1
-		for {
-			params := &database.FilterParams{
-				PaginationParams: database.PaginationParams{
-					Page:     page,
-					PageSize: batchSize,
-				},
-				EndDate: &date,
-			}
-			result, err := s.repo.List(params)
-			if err != nil {
-				return 0, err
-			}
-			for _, record := range result.Items {
-				if record.FilePath != "" {
-					_ = os.Remove(record.FilePath)
-				}
-			}
-			// 如果这一页数据不足一批，说明没有更多了
-			if len(result.Items) < batchSize {
-				break
-			}
-			page++
+// trashFiles 在删除记录前将文件移入回收站，返回文件移动失败的记录 ID；
+// 有失败时返回的错误列出每个失败的文件
+func (s *DownloadRecordService) trashFiles(records []database.DownloadRecord) (map[string]bool, error) {
+	failed := make(map[string]bool)
+	var messages []string
+	for i := range records {
+		if _, err := moveDownloadToTrash(s.repo, &records[i]); err != nil {
+			utils.Warn("[回收站] 移动文件失败 %s: %v", records[i].FilePath, err)
+			failed[records[i].ID] = true
+			messages = append(messages, fmt.Sprintf("%s: %v", records[i].ID, err))
+		}
+	}
+	if len(messages) > 0 {
+		return failed, fmt.Errorf("failed to move files to trash, records kept: %s", strings.Join(messages, "; "))
+	}
+	return failed, nil
+}
+
+// recordIDs 返回记录的 ID
+func recordIDs(records []database.DownloadRecord) []string {
+	ids := make([]string, len(records))
+	for i, record := range records {
+		ids[i] = record.ID
+	}
+	return ids
+}
+
+// excludeIDs 返回不在 exclude 中的 ID
+func excludeIDs(ids []string, exclude map[string]bool) []string {
+	if len(exclude) == 0 {
+		return ids
+	}
+	kept := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !exclude[id] {
+			kept = append(kept, id)
 		}
 	}
-	return s.repo.DeleteBefore(date)
+	return kept
 }
 
 // Count 返回下载记录总数
@@ -165,8 +224,18 @@ func (s *DownloadRecordService) GetTotalFileSize() (int64, error) {
 	return s.repo.GetTotalFileSize()
 }
 
-// Create 添加新的下载记录
+// Create 添加新的下载记录。同一视频在回收站中时（记录 ID 即视频 ID），
+// 回收站中的旧记录换成新 ID 后保留在回收站中，文件不变，仍可恢复或永久删除
 func (s *DownloadRecordService) Create(record *database.DownloadRecord) error {
+	err := s.repo.Create(record)
+	if !errors.Is(err, database.ErrDownloadInTrash) {
+		return err
+	}
+	detached, err := s.repo.DetachTrashed(record.ID)
+	if err != nil {
+		return err
+	}
+	utils.Info("🗑️ [回收站] 重新下载 %s，回收站中的旧记录改为 %s", record.ID, detached)
 	return s.repo.Create(record)
 }
 
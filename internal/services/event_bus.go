@@ -0,0 +1,168 @@
+package services
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"wx_channel/internal/utils"
+)
+
+// 控制台事件类型（与控制台 WebSocket 消息类型一致）
+const (
+	EventDownloadProgress      = "download_progress"
+	EventQueueChange           = "queue_change"
+	EventStatsUpdate           = "stats_update"
+	EventWatchNewVideos        = "watch_new_videos"
+	EventTranscriptionProgress = "transcription_progress"
+	EventBatchProgress         = "batch_progress"
+)
+
+const (
+	eventBufferSize       = 256 // 保留最近的事件数，用于 Last-Event-ID 续传
+	eventSubscriberBuffer = 64  // 每个订阅者的待发送事件数，写满时断开该订阅者
+)
+
+// Event 控制台事件，ID 在进程内单调递增
+type Event struct {
+	ID   int64           `json:"id"`
+	Type string          `json:"type"`
+	Time time.Time       `json:"time"`
+	Data json.RawMessage `json:"data"`
+}
+
+// EventSubscription 事件订阅，C 关闭表示订阅已结束（取消或消费过慢）
+type EventSubscription struct {
+	C      chan Event
+	bus    *EventBus
+	topics map[string]bool
+}
+
+// Close 取消订阅
+func (s *EventSubscription) Close() {
+	s.bus.unsubscribe(s)
+}
+
+// matches 判断事件是否属于订阅的类型，未指定类型时订阅全部
+func (s *EventSubscription) matches(eventType string) bool {
+	return len(s.topics) == 0 || s.topics[eventType]
+}
+
+// EventBus 将下载进度、队列变更等控制台事件分发给 SSE 订阅者，
+// 并在环形缓冲区中保留最近的事件供断线重连后续传
+type EventBus struct {
+	mu          sync.Mutex
+	lastID      int64
+	buffer      [eventBufferSize]Event
+	subscribers map[*EventSubscription]struct{}
+}
+
+var (
+	eventBus     *EventBus
+	eventBusOnce sync.Once
+)
+
+// GetEventBus 获取全局事件总线
+func GetEventBus() *EventBus {
+	eventBusOnce.Do(func() {
+		eventBus = NewEventBus()
+	})
+	return eventBus
+}
+
+// NewEventBus 创建一个新的 EventBus
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subscribers: make(map[*EventSubscription]struct{}),
+	}
+}
+
+// Publish 发布事件，data 编码为 JSON。不会阻塞：订阅者缓冲区已满时断开该订阅者，
+// 客户端可带 Last-Event-ID 重连续传
+func (b *EventBus) Publish(eventType string, data interface{}) {
+	if b == nil {
+		return
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		utils.Warn("[事件] 编码 %s 失败: %v", eventType, err)
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastID++
+	event := Event{ID: b.lastID, Type: eventType, Time: time.Now(), Data: payload}
+	b.buffer[(event.ID-1)%eventBufferSize] = event
+
+	for sub := range b.subscribers {
+		if !sub.matches(eventType) {
+			continue
+		}
+		select {
+		case sub.C <- event:
+		default:
+			delete(b.subscribers, sub)
+			close(sub.C)
+		}
+	}
+}
+
+// Subscribe 订阅指定类型的事件（topics 为空时订阅全部），
+// 同时返回缓冲区中 ID 大于 lastEventID 的同类事件。lastEventID 为 0 时不补发；
+// 大于当前最新 ID（服务已重启）时补发缓冲区中的全部事件
+func (b *EventBus) Subscribe(topics []string, lastEventID int64) (*EventSubscription, []Event) {
+	sub := &EventSubscription{
+		C:      make(chan Event, eventSubscriberBuffer),
+		bus:    b,
+		topics: make(map[string]bool, len(topics)),
+	}
+	for _, topic := range topics {
+		if topic != "" {
+			sub.topics[topic] = true
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[sub] = struct{}{}
+	if lastEventID <= 0 {
+		return sub, nil
+	}
+	if lastEventID > b.lastID {
+		lastEventID = 0
+	}
+
+	oldest := b.lastID - eventBufferSize + 1
+	if oldest < 1 {
+		oldest = 1
+	}
+	if lastEventID+1 > oldest {
+		oldest = lastEventID + 1
+	}
+	var replay []Event
+	for id := oldest; id <= b.lastID; id++ {
+		event := b.buffer[(id-1)%eventBufferSize]
+		if sub.matches(event.Type) {
+			replay = append(replay, event)
+		}
+	}
+	return sub, replay
+}
+
+// SubscriberCount 返回当前的订阅者数量
+func (b *EventBus) SubscriberCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subscribers)
+}
+
+// unsubscribe 移除订阅者并关闭其通道，重复调用无效
+func (b *EventBus) unsubscribe(sub *EventSubscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.C)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"wx_channel/internal/database"
@@ -32,6 +33,8 @@ type ExportResult struct {
 type ExportService struct {
 	browseRepo   *database.BrowseHistoryRepository
 	downloadRepo *database.DownloadRecordRepository
+	commentRepo  *database.CommentRepository
+	auditRepo    *database.AuditRepository
 }
 
 // NewExportService 创建一个新的 ExportService
@@ -39,6 +42,8 @@ func NewExportService() *ExportService {
 	return &ExportService{
 		browseRepo:   database.NewBrowseHistoryRepository(),
 		downloadRepo: database.NewDownloadRecordRepository(),
+		commentRepo:  database.NewCommentRepository(),
+		auditRepo:    database.NewAuditRepository(),
 	}
 }
 
@@ -137,6 +142,51 @@ func (s *ExportService) ExportDownloadRecords(format ExportFormat, ids []string)
 	}, nil
 }
 
+// ExportComments 导出已采集的评论，可按视频 ID 或视频作者限定范围
+func (s *ExportService) ExportComments(format ExportFormat, filter *database.CommentAnalyticsFilter) (*ExportResult, error) {
+	if filter == nil {
+		filter = &database.CommentAnalyticsFilter{}
+	}
+	comments, err := s.commentRepo.ListForExport(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comments: %w", err)
+	}
+
+	var data []byte
+	var contentType string
+
+	switch format {
+	case ExportFormatJSON:
+		data, err = json.MarshalIndent(comments, "", "  ")
+		if err != nil {
+			err = fmt.Errorf("failed to marshal comments to JSON: %w", err)
+		}
+		contentType = "application/json"
+	case ExportFormatCSV:
+		data, err = s.exportCommentsToCSV(comments)
+		contentType = "text/csv"
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := "comments"
+	if filter.VideoID != "" {
+		prefix = "comments_" + filter.VideoID
+	}
+
+	return &ExportResult{
+		Data:        data,
+		Filename:    GenerateTimestampFilename(prefix, format),
+		ContentType: contentType,
+		RecordCount: len(comments),
+		ExportTime:  time.Now(),
+	}, nil
+}
+
 // exportBrowseRecordsToJSON 将浏览记录导出为 JSON 格式
 func (s *ExportService) exportBrowseRecordsToJSON(records []database.BrowseRecord) ([]byte, error) {
 	data, err := json.MarshalIndent(records, "", "  ")
@@ -262,6 +312,136 @@ func (s *ExportService) exportDownloadRecordsToCSV(records []database.DownloadRe
 	return buf.Bytes(), nil
 }
 
+// ExportAuditLog 导出审计记录
+func (s *ExportService) ExportAuditLog(format ExportFormat, filter *database.AuditFilter) (*ExportResult, error) {
+	entries, err := s.auditRepo.ListForExport(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit entries: %w", err)
+	}
+
+	var data []byte
+	var contentType string
+
+	switch format {
+	case ExportFormatJSON:
+		data, err = json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			err = fmt.Errorf("failed to marshal audit entries to JSON: %w", err)
+		}
+		contentType = "application/json"
+	case ExportFormatCSV:
+		data, err = s.exportAuditLogToCSV(entries)
+		contentType = "text/csv"
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExportResult{
+		Data:        data,
+		Filename:    GenerateTimestampFilename("audit_log", format),
+		ContentType: contentType,
+		RecordCount: len(entries),
+		ExportTime:  time.Now(),
+	}, nil
+}
+
+// exportCommentsToCSV 将评论导出为 CSV 格式
+func (s *ExportService) exportCommentsToCSV(comments []database.CommentSearchResult) ([]byte, error) {
+	var buf bytes.Buffer
+	// 写入 UTF-8 BOM 以兼容 Excel
+	buf.Write([]byte{0xEF, 0xBB, 0xBF})
+
+	writer := csv.NewWriter(&buf)
+
+	// 写入表头
+	header := []string{
+		"VideoID", "VideoTitle", "CommentID", "ParentID", "ReplyToID", "ReplyToNickname",
+		"Author", "Content", "LikeCount", "ReplyCount", "IPRegion", "CommentTime",
+	}
+	if err := writer.Write(header); err != nil {
+		return nil, fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	// 写入记录
+	for _, c := range comments {
+		commentTime := ""
+		if !c.CommentTime.IsZero() {
+			commentTime = c.CommentTime.Format(time.RFC3339)
+		}
+		row := []string{
+			c.VideoID,
+			c.VideoTitle,
+			c.ID,
+			c.ParentID,
+			c.ReplyToID,
+			c.ReplyToNickname,
+			c.Author,
+			c.Content,
+			fmt.Sprintf("%d", c.LikeCount),
+			fmt.Sprintf("%d", c.ReplyCount),
+			c.IPRegion,
+			commentTime,
+		}
+		if err := writer.Write(row); err != nil {
+			return nil, fmt.Errorf("failed to write CSV row: %w", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, fmt.Errorf("failed to flush CSV writer: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// exportAuditLogToCSV 将审计记录导出为 CSV 格式
+func (s *ExportService) exportAuditLogToCSV(entries []database.AuditEntry) ([]byte, error) {
+	var buf bytes.Buffer
+	// 写入 UTF-8 BOM 以兼容 Excel
+	buf.Write([]byte{0xEF, 0xBB, 0xBF})
+
+	writer := csv.NewWriter(&buf)
+
+	header := []string{
+		"ID", "CreatedAt", "Actor", "ActorType", "Action", "TargetType", "TargetIDs",
+		"Before", "After", "SourceIP", "Detail",
+	}
+	if err := writer.Write(header); err != nil {
+		return nil, fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	for _, e := range entries {
+		row := []string{
+			fmt.Sprintf("%d", e.ID),
+			e.CreatedAt.Format(time.RFC3339),
+			e.Actor,
+			e.ActorType,
+			e.Action,
+			e.TargetType,
+			strings.Join(e.TargetIDs, ","),
+			string(e.Before),
+			string(e.After),
+			e.SourceIP,
+			e.Detail,
+		}
+		if err := writer.Write(row); err != nil {
+			return nil, fmt.Errorf("failed to write CSV row: %w", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, fmt.Errorf("failed to flush CSV writer: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
 // formatDuration 将毫秒持续时间格式化为 MM:SS 字符串
 func formatDuration(ms int64) string {
 	seconds := ms / 1000
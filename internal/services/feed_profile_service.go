@@ -0,0 +1,204 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"wx_channel/internal/database"
+	"wx_channel/internal/utils"
+	"wx_channel/internal/websocket"
+)
+
+const (
+	// DefaultFeedProfileCacheTTL 视频详情默认缓存时间，需要短于视频链接 token 的有效期
+	DefaultFeedProfileCacheTTL = 30 * time.Minute
+	feedProfileCallTimeout     = 60 * time.Second
+	feedProfilePurgeInterval   = time.Hour
+)
+
+// FeedProfileService 获取视频详情（key:channels:feed_profile），结果按 objectId/nonceId
+// 缓存在数据库中，HTTP API 和云端调用共用
+type FeedProfileService struct {
+	repo      *database.FeedProfileCacheRepository
+	hub       *websocket.Hub
+	mu        sync.Mutex
+	ttl       time.Duration
+	lastPurge time.Time
+}
+
+var (
+	feedProfileService     *FeedProfileService
+	feedProfileServiceOnce sync.Once
+)
+
+// GetFeedProfileService 获取全局视频详情服务
+func GetFeedProfileService(hub *websocket.Hub) *FeedProfileService {
+	feedProfileServiceOnce.Do(func() {
+		feedProfileService = NewFeedProfileService(hub)
+	})
+	return feedProfileService
+}
+
+// NewFeedProfileService 创建一个新的 FeedProfileService
+func NewFeedProfileService(hub *websocket.Hub) *FeedProfileService {
+	return &FeedProfileService{
+		repo: database.NewFeedProfileCacheRepository(),
+		hub:  hub,
+		ttl:  DefaultFeedProfileCacheTTL,
+	}
+}
+
+// SetTTL 设置缓存有效期，<= 0 时不缓存
+func (s *FeedProfileService) SetTTL(ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ttl = ttl
+}
+
+// Get 获取视频详情的原始响应。fresh 为 true 时跳过缓存（结果仍会写入缓存），
+// 返回值 cached 表示是否命中缓存
+func (s *FeedProfileService) Get(body websocket.FeedProfileBody, fresh bool) (json.RawMessage, bool, error) {
+	s.mu.Lock()
+	ttl := s.ttl
+	s.mu.Unlock()
+
+	objectID := normalizeObjectID(body.ObjectID)
+	if ttl > 0 && !fresh && objectID != "" && body.NonceID != "" {
+		entry, err := s.repo.Get(objectID, body.NonceID)
+		if err != nil {
+			utils.Warn("[视频详情] 读取缓存失败: %v", err)
+		} else if entry != nil {
+			return entry.Data, true, nil
+		}
+	}
+
+	if s.hub == nil {
+		return nil, false, fmt.Errorf("websocket hub not available")
+	}
+	data, err := s.hub.CallAPI(websocket.APIKeyFeedProfile, body, feedProfileCallTimeout)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if ttl > 0 {
+		s.store(objectID, body.NonceID, data, ttl)
+	}
+	return data, false, nil
+}
+
+// store 缓存成功的响应。通过分享链接查询时请求中没有 ID，使用响应中的 ID
+func (s *FeedProfileService) store(objectID, nonceID string, data json.RawMessage, ttl time.Duration) {
+	var resp struct {
+		ErrCode int `json:"errCode"`
+		Data    struct {
+			Object *struct {
+				ID            json.RawMessage `json:"id"`
+				ObjectNonceID string          `json:"objectNonceId"`
+			} `json:"object"`
+		} `json:"data"`
+	}
+	if json.Unmarshal(data, &resp) != nil || resp.ErrCode != 0 || resp.Data.Object == nil {
+		return
+	}
+	if objectID == "" {
+		var f flexString
+		if json.Unmarshal(resp.Data.Object.ID, &f) == nil {
+			objectID = string(f)
+		}
+	}
+	if nonceID == "" {
+		nonceID = resp.Data.Object.ObjectNonceID
+	}
+	if objectID == "" || nonceID == "" {
+		return
+	}
+
+	now := time.Now()
+	entry := &database.FeedProfileCache{
+		ObjectID:  objectID,
+		NonceID:   nonceID,
+		Data:      data,
+		FetchedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+	if err := s.repo.Put(entry); err != nil {
+		utils.Warn("[视频详情] 写入缓存失败: %v", err)
+	}
+
+	s.mu.Lock()
+	purge := now.Sub(s.lastPurge) >= feedProfilePurgeInterval
+	if purge {
+		s.lastPurge = now
+	}
+	s.mu.Unlock()
+	if purge {
+		if _, err := s.repo.DeleteExpired(); err != nil {
+			utils.Warn("[视频详情] 清理过期缓存失败: %v", err)
+		}
+	}
+}
+
+// ResolveVideo 获取视频当前的下载链接和解密密钥。staleURL 为已失效的链接：
+// 缓存中的链接与它相同时说明缓存也已失效，改为重新请求
+func (s *FeedProfileService) ResolveVideo(objectID, nonceID, staleURL string) (*database.CrawlVideo, error) {
+	if objectID == "" || nonceID == "" {
+		return nil, fmt.Errorf("objectId and nonceId are required")
+	}
+	body := websocket.FeedProfileBody{ObjectID: objectID, NonceID: nonceID}
+
+	data, cached, err := s.Get(body, false)
+	if err != nil {
+		return nil, err
+	}
+	video, err := parseFeedProfileVideo(data)
+	if err == nil && cached && staleURL != "" && video.VideoURL == staleURL {
+		data, _, err = s.Get(body, true)
+		if err != nil {
+			return nil, err
+		}
+		video, err = parseFeedProfileVideo(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return video, nil
+}
+
+// parseFeedProfileVideo 从视频详情响应中提取视频信息
+func parseFeedProfileVideo(data json.RawMessage) (*database.CrawlVideo, error) {
+	var resp struct {
+		ErrCode int    `json:"errCode"`
+		ErrMsg  string `json:"errMsg"`
+		Data    struct {
+			Object json.RawMessage `json:"object"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse feed profile: %w", err)
+	}
+	if resp.ErrCode != 0 {
+		return nil, fmt.Errorf("feed profile error (code=%d): %s", resp.ErrCode, resp.ErrMsg)
+	}
+	if len(resp.Data.Object) == 0 {
+		return nil, fmt.Errorf("feed profile has no object")
+	}
+	video, err := ParseCrawlVideo(resp.Data.Object)
+	if err != nil {
+		return nil, err
+	}
+	if video == nil || video.VideoURL == "" {
+		return nil, fmt.Errorf("feed profile has no video url")
+	}
+	return video, nil
+}
+
+// normalizeObjectID 去掉 objectId 的 "_" 后缀（与注入脚本一致）
+func normalizeObjectID(objectID string) string {
+	if i := strings.Index(objectID, "_"); i >= 0 {
+		return objectID[:i]
+	}
+	return objectID
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"wx_channel/internal/database"
+)
+
+// ffmpegDurationPattern 匹配 FFmpeg 输出中的 "Duration: 00:01:23.45"
+var ffmpegDurationPattern = regexp.MustCompile(`Duration:\s*(\d+):(\d+):(\d+(?:\.\d+)?)`)
+
+// findFFmpegPath 获取 FFmpeg 路径：优先使用设置中的路径，其次查找系统 PATH
+func findFFmpegPath(settingsRepo *database.SettingsRepository) string {
+	if settingsRepo != nil {
+		if path, _ := settingsRepo.Get(database.SettingKeyFFmpegPath); path != "" {
+			return path
+		}
+	}
+	if p, err := exec.LookPath("ffmpeg"); err == nil {
+		return p
+	}
+	return ""
+}
+
+// findFFprobePath 获取 ffprobe 路径：优先使用与已配置 FFmpeg 同目录的 ffprobe，其次查找系统 PATH
+func findFFprobePath(settingsRepo *database.SettingsRepository) string {
+	if settingsRepo != nil {
+		if ffmpegPath, _ := settingsRepo.Get(database.SettingKeyFFmpegPath); ffmpegPath != "" {
+			name := "ffprobe"
+			if strings.EqualFold(filepath.Ext(ffmpegPath), ".exe") {
+				name += ".exe"
+			}
+			candidate := filepath.Join(filepath.Dir(ffmpegPath), name)
+			if _, err := os.Stat(candidate); err == nil {
+				return candidate
+			}
+		}
+	}
+	if p, err := exec.LookPath("ffprobe"); err == nil {
+		return p
+	}
+	return ""
+}
+
+// parseFFmpegDuration 从 FFmpeg 的输出中解析媒体时长（秒）
+func parseFFmpegDuration(output string) float64 {
+	m := ffmpegDurationPattern.FindStringSubmatch(output)
+	if m == nil {
+		return 0
+	}
+	hours, _ := strconv.ParseFloat(m[1], 64)
+	minutes, _ := strconv.ParseFloat(m[2], 64)
+	seconds, _ := strconv.ParseFloat(m[3], 64)
+	return hours*3600 + minutes*60 + seconds
+}
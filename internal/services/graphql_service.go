@@ -0,0 +1,611 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"wx_channel/internal/database"
+	"wx_channel/internal/graphql"
+)
+
+// GraphQL 查询限制
+const (
+	graphQLMaxDepth      = 8
+	graphQLMaxComplexity = 1000
+	graphQLMaxPageSize   = 100
+	// graphQLQueueListSize 下载队列不分页，计算开销时按该长度估算
+	graphQLQueueListSize = 100
+)
+
+// GraphQLService 提供只读的 GraphQL 查询，数据来自浏览记录、下载记录、下载队列和作者汇总
+type GraphQLService struct {
+	browseRepo   *database.BrowseHistoryRepository
+	downloadRepo *database.DownloadRecordRepository
+	queueRepo    *database.QueueRepository
+	authorRepo   *database.AuthorRepository
+	schema       *graphql.Schema
+}
+
+// NewGraphQLService 创建一个新的 GraphQLService
+func NewGraphQLService() *GraphQLService {
+	s := &GraphQLService{
+		browseRepo:   database.NewBrowseHistoryRepository(),
+		downloadRepo: database.NewDownloadRecordRepository(),
+		queueRepo:    database.NewQueueRepository(),
+		authorRepo:   database.NewAuthorRepository(),
+	}
+	s.schema = s.buildSchema()
+	return s
+}
+
+// Execute 执行一次 GraphQL 查询
+func (s *GraphQLService) Execute(ctx context.Context, req *graphql.Request) *graphql.Response {
+	ctx = context.WithValue(ctx, graphQLLoaderKey{}, s.newLoader())
+	return s.schema.Execute(ctx, req)
+}
+
+// ============================================================================
+// 批量加载
+// 列表字段解析后先登记关联的键，嵌套字段第一次访问时一次性查询所有已登记的键，
+// 避免每条记录单独查询一次
+// ============================================================================
+
+type graphQLLoaderKey struct{}
+
+type graphQLLoader struct {
+	browse    *graphQLBatch[database.BrowseRecord]   // 按视频 ID
+	downloads *graphQLBatch[database.DownloadRecord] // 按视频 ID
+	authors   *graphQLBatch[database.AuthorStats]    // 按作者名称
+}
+
+func (s *GraphQLService) newLoader() *graphQLLoader {
+	return &graphQLLoader{
+		browse: newGraphQLBatch(func(ids []string) (map[string][]database.BrowseRecord, error) {
+			records, err := s.browseRepo.GetByIDs(ids)
+			if err != nil {
+				return nil, err
+			}
+			return groupBy(records, func(r *database.BrowseRecord) string { return r.ID }), nil
+		}),
+		downloads: newGraphQLBatch(func(videoIDs []string) (map[string][]database.DownloadRecord, error) {
+			records, err := s.downloadRepo.ListByVideoIDs(videoIDs)
+			if err != nil {
+				return nil, err
+			}
+			return groupBy(records, func(r *database.DownloadRecord) string { return r.VideoID }), nil
+		}),
+		authors: newGraphQLBatch(func(names []string) (map[string][]database.AuthorStats, error) {
+			authors, err := s.authorRepo.GetByNames(names)
+			if err != nil {
+				return nil, err
+			}
+			return groupBy(authors, func(a *database.AuthorStats) string { return a.Name }), nil
+		}),
+	}
+}
+
+func loaderFrom(ctx context.Context) *graphQLLoader {
+	return ctx.Value(graphQLLoaderKey{}).(*graphQLLoader)
+}
+
+// graphQLBatch 按键批量加载，查询在同一请求内串行执行，不需要加锁
+type graphQLBatch[T any] struct {
+	pending []string
+	loaded  map[string][]T
+	fetch   func(keys []string) (map[string][]T, error)
+}
+
+func newGraphQLBatch[T any](fetch func(keys []string) (map[string][]T, error)) *graphQLBatch[T] {
+	return &graphQLBatch[T]{loaded: make(map[string][]T), fetch: fetch}
+}
+
+// prime 登记稍后可能加载的键
+func (b *graphQLBatch[T]) prime(keys ...string) {
+	for _, key := range keys {
+		if _, ok := b.loaded[key]; !ok && key != "" {
+			b.pending = append(b.pending, key)
+		}
+	}
+}
+
+// load 加载一个键，同时加载所有已登记但未加载的键
+func (b *graphQLBatch[T]) load(key string) ([]T, error) {
+	if items, ok := b.loaded[key]; ok {
+		return items, nil
+	}
+	seen := map[string]bool{key: true}
+	keys := []string{key}
+	for _, k := range b.pending {
+		if _, ok := b.loaded[k]; !ok && !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	b.pending = nil
+
+	result, err := b.fetch(keys)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range keys {
+		b.loaded[k] = result[k]
+	}
+	return b.loaded[key], nil
+}
+
+// loadOne 加载一个键对应的第一条记录，不存在时返回 nil
+func (b *graphQLBatch[T]) loadOne(key string) (*T, error) {
+	if key == "" {
+		return nil, nil
+	}
+	items, err := b.load(key)
+	if err != nil || len(items) == 0 {
+		return nil, err
+	}
+	return &items[0], nil
+}
+
+func groupBy[T any](items []T, key func(*T) string) map[string][]T {
+	groups := make(map[string][]T)
+	for i := range items {
+		k := key(&items[i])
+		groups[k] = append(groups[k], items[i])
+	}
+	return groups
+}
+
+// ============================================================================
+// Schema
+// ============================================================================
+
+func (s *GraphQLService) buildSchema() *graphql.Schema {
+	browseType := &graphql.Object{Name: "BrowseRecord", Description: "浏览记录"}
+	downloadType := &graphql.Object{Name: "Download", Description: "下载记录"}
+	queueType := &graphql.Object{Name: "QueueItem", Description: "下载队列项"}
+	authorType := &graphql.Object{Name: "Author", Description: "按作者名称汇总的浏览和下载数据"}
+	transcriptType := &graphql.Object{Name: "Transcript", Description: "转写状态", Fields: graphql.Fields{
+		"status": {Type: graphql.String, Description: "in_progress、completed 或 failed"},
+		"path":   {Type: graphql.String},
+	}}
+	browseConnection := connectionType("BrowseConnection", browseType)
+	downloadConnection := connectionType("DownloadConnection", downloadType)
+	authorConnection := connectionType("AuthorConnection", authorType)
+
+	browseType.Fields = graphql.Fields{
+		"id":           {Type: graphql.ID, Description: "视频 ID"},
+		"title":        {Type: graphql.String},
+		"author":       {Type: graphql.String},
+		"authorId":     {Type: graphql.String},
+		"duration":     {Type: graphql.Int},
+		"size":         {Type: graphql.Int},
+		"resolution":   {Type: graphql.String},
+		"coverUrl":     {Type: graphql.String},
+		"videoUrl":     {Type: graphql.String},
+		"pageUrl":      {Type: graphql.String},
+		"browseTime":   {Type: graphql.DateTime},
+		"likeCount":    {Type: graphql.Int},
+		"commentCount": {Type: graphql.Int},
+		"favCount":     {Type: graphql.Int},
+		"forwardCount": {Type: graphql.Int},
+		"createdAt":    {Type: graphql.DateTime},
+		"updatedAt":    {Type: graphql.DateTime},
+		"downloads": {
+			Type:        &graphql.List{OfType: downloadType},
+			Description: "该视频的下载记录",
+			Cost:        1,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				records, err := loaderFrom(p.Context).downloads.load(p.Source.(*database.BrowseRecord).ID)
+				if err != nil {
+					return nil, err
+				}
+				primeDownloads(p.Context, records)
+				return records, nil
+			},
+		},
+		"authorStats": {
+			Type:        authorType,
+			Description: "作者汇总",
+			Cost:        1,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return loaderFrom(p.Context).authors.loadOne(p.Source.(*database.BrowseRecord).Author)
+			},
+		},
+	}
+
+	downloadType.Fields = graphql.Fields{
+		"id":            {Type: graphql.ID},
+		"videoId":       {Type: graphql.ID},
+		"title":         {Type: graphql.String},
+		"author":        {Type: graphql.String},
+		"coverUrl":      {Type: graphql.String},
+		"duration":      {Type: graphql.Int},
+		"fileSize":      {Type: graphql.Int},
+		"filePath":      {Type: graphql.String},
+		"format":        {Type: graphql.String},
+		"resolution":    {Type: graphql.String},
+		"status":        {Type: graphql.String},
+		"downloadTime":  {Type: graphql.DateTime},
+		"errorMessage":  {Type: graphql.String},
+		"likeCount":     {Type: graphql.Int},
+		"commentCount":  {Type: graphql.Int},
+		"forwardCount":  {Type: graphql.Int},
+		"favCount":      {Type: graphql.Int},
+		"container":     {Type: graphql.String},
+		"videoCodec":    {Type: graphql.String},
+		"audioCodec":    {Type: graphql.String},
+		"bitrate":       {Type: graphql.Int},
+		"fps":           {Type: graphql.Float},
+		"width":         {Type: graphql.Int},
+		"height":        {Type: graphql.Int},
+		"mediaDuration": {Type: graphql.Float},
+		"initiatedBy":   {Type: graphql.String, Description: "发起下载的控制台用户"},
+		"createdAt":     {Type: graphql.DateTime},
+		"updatedAt":     {Type: graphql.DateTime},
+		"transcript": {
+			Type:        transcriptType,
+			Description: "转写状态，未转写时为 null",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				record := p.Source.(*database.DownloadRecord)
+				if record.TranscriptStatus == database.TranscriptStatusNone {
+					return nil, nil
+				}
+				return map[string]interface{}{"status": record.TranscriptStatus, "path": record.TranscriptPath}, nil
+			},
+		},
+		"browse": {
+			Type:        browseType,
+			Description: "对应的浏览记录",
+			Cost:        1,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return loaderFrom(p.Context).browse.loadOne(p.Source.(*database.DownloadRecord).VideoID)
+			},
+		},
+		"authorStats": {
+			Type:        authorType,
+			Description: "作者汇总",
+			Cost:        1,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return loaderFrom(p.Context).authors.loadOne(p.Source.(*database.DownloadRecord).Author)
+			},
+		},
+	}
+
+	queueType.Fields = graphql.Fields{
+		"id":             {Type: graphql.ID},
+		"videoId":        {Type: graphql.ID},
+		"title":          {Type: graphql.String},
+		"author":         {Type: graphql.String},
+		"coverUrl":       {Type: graphql.String},
+		"duration":       {Type: graphql.Int},
+		"resolution":     {Type: graphql.String},
+		"totalSize":      {Type: graphql.Int},
+		"downloadedSize": {Type: graphql.Int},
+		"status":         {Type: graphql.String},
+		"priority":       {Type: graphql.Int},
+		"addedTime":      {Type: graphql.DateTime},
+		"startTime":      {Type: graphql.DateTime},
+		"speed":          {Type: graphql.Int},
+		"retryCount":     {Type: graphql.Int},
+		"errorMessage":   {Type: graphql.String},
+		"quality":        {Type: graphql.String},
+		"initiatedBy":    {Type: graphql.String, Description: "加入队列的控制台用户"},
+		"createdAt":      {Type: graphql.DateTime},
+		"updatedAt":      {Type: graphql.DateTime},
+		"progress": {
+			Type:        graphql.Float,
+			Description: "下载进度（0-100）",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				item := p.Source.(*database.QueueItem)
+				if item.TotalSize <= 0 {
+					return 0.0, nil
+				}
+				return float64(item.DownloadedSize) * 100 / float64(item.TotalSize), nil
+			},
+		},
+		"browse": {
+			Type:        browseType,
+			Description: "对应的浏览记录",
+			Cost:        1,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return loaderFrom(p.Context).browse.loadOne(p.Source.(*database.QueueItem).VideoID)
+			},
+		},
+	}
+
+	authorType.Fields = graphql.Fields{
+		"name":             {Type: graphql.String},
+		"authorId":         {Type: graphql.String},
+		"browseCount":      {Type: graphql.Int},
+		"downloadCount":    {Type: graphql.Int},
+		"downloadedSize":   {Type: graphql.Int},
+		"lastBrowseTime":   {Type: graphql.DateTime},
+		"lastDownloadTime": {Type: graphql.DateTime},
+		"browse": {
+			Type:        browseConnection,
+			Description: "该作者的浏览记录",
+			Args:        browseArgs(false),
+			Cost:        1,
+			ListSize:    pageSizeOf,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return s.resolveBrowse(p, p.Source.(*database.AuthorStats).Name)
+			},
+		},
+		"downloads": {
+			Type:        downloadConnection,
+			Description: "该作者的下载记录",
+			Args:        downloadArgs(false),
+			Cost:        1,
+			ListSize:    pageSizeOf,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return s.resolveDownloads(p, p.Source.(*database.AuthorStats).Name)
+			},
+		},
+	}
+
+	query := &graphql.Object{Name: "Query", Fields: graphql.Fields{
+		"browse": {
+			Type:        browseConnection,
+			Description: "分页查询浏览记录",
+			Args:        browseArgs(true),
+			Cost:        1,
+			ListSize:    pageSizeOf,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return s.resolveBrowse(p, "")
+			},
+		},
+		"browseRecord": {
+			Type:        browseType,
+			Description: "按视频 ID 查询浏览记录",
+			Args:        graphql.Args{"id": {Type: graphql.ID, Required: true}},
+			Cost:        1,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				record, err := s.browseRepo.GetByID(p.Args["id"].(string))
+				if err != nil || record == nil {
+					return nil, err
+				}
+				primeBrowse(p.Context, []database.BrowseRecord{*record})
+				return record, nil
+			},
+		},
+		"downloads": {
+			Type:        downloadConnection,
+			Description: "分页查询下载记录",
+			Args:        downloadArgs(true),
+			Cost:        1,
+			ListSize:    pageSizeOf,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return s.resolveDownloads(p, "")
+			},
+		},
+		"download": {
+			Type:        downloadType,
+			Description: "按 ID 查询下载记录",
+			Args:        graphql.Args{"id": {Type: graphql.ID, Required: true}},
+			Cost:        1,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				record, err := s.downloadRepo.GetByID(p.Args["id"].(string))
+				if err != nil || record == nil {
+					return nil, err
+				}
+				primeDownloads(p.Context, []database.DownloadRecord{*record})
+				return record, nil
+			},
+		},
+		"queue": {
+			Type:        &graphql.List{OfType: queueType},
+			Description: "下载队列，按队列顺序排列",
+			Args: graphql.Args{"status": {Type: graphql.String, Enum: []string{
+				database.QueueStatusPending, database.QueueStatusDownloading, database.QueueStatusPaused,
+				database.QueueStatusCompleted, database.QueueStatusFailed,
+			}}},
+			Cost:     1,
+			ListSize: func(map[string]interface{}) int { return graphQLQueueListSize },
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				var items []database.QueueItem
+				var err error
+				if status, ok := p.Args["status"].(string); ok {
+					items, err = s.queueRepo.ListByStatus(status)
+				} else {
+					items, err = s.queueRepo.List()
+				}
+				if err != nil {
+					return nil, err
+				}
+				loader := loaderFrom(p.Context)
+				for i := range items {
+					loader.browse.prime(items[i].VideoID)
+				}
+				return items, nil
+			},
+		},
+		"authors": {
+			Type:        authorConnection,
+			Description: "分页查询作者汇总",
+			Args: withPageArgs(graphql.Args{
+				"query": {Type: graphql.String, Description: "按作者名称模糊匹配"},
+			}, database.AuthorSortColumns, "last_browse_time"),
+			Cost:     1,
+			ListSize: pageSizeOf,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				params := &database.AuthorFilterParams{}
+				if err := applyPageArgs(&params.PaginationParams, p.Args); err != nil {
+					return nil, err
+				}
+				params.Query, _ = p.Args["query"].(string)
+				return s.authorRepo.List(params)
+			},
+		},
+		"author": {
+			Type:        authorType,
+			Description: "按名称查询作者汇总",
+			Args:        graphql.Args{"name": {Type: graphql.String, Required: true}},
+			Cost:        1,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return loaderFrom(p.Context).authors.loadOne(p.Args["name"].(string))
+			},
+		},
+	}}
+
+	return &graphql.Schema{Query: query, MaxDepth: graphQLMaxDepth, MaxComplexity: graphQLMaxComplexity}
+}
+
+func (s *GraphQLService) resolveBrowse(p graphql.ResolveParams, author string) (interface{}, error) {
+	params, err := filterParamsFromArgs(p.Args)
+	if err != nil {
+		return nil, err
+	}
+	if author != "" {
+		params.Author = author
+	}
+	result, err := s.browseRepo.ListFiltered(params)
+	if err != nil {
+		return nil, err
+	}
+	primeBrowse(p.Context, result.Items)
+	return result, nil
+}
+
+func (s *GraphQLService) resolveDownloads(p graphql.ResolveParams, author string) (interface{}, error) {
+	params, err := filterParamsFromArgs(p.Args)
+	if err != nil {
+		return nil, err
+	}
+	if author != "" {
+		params.Author = author
+	}
+	params.Status, _ = p.Args["status"].(string)
+	params.MinResolution, _ = p.Args["minResolution"].(int)
+	params.VideoCodec, _ = p.Args["videoCodec"].(string)
+	params.TranscriptStatus, _ = p.Args["transcriptStatus"].(string)
+	params.InitiatedBy, _ = p.Args["initiatedBy"].(string)
+	result, err := s.downloadRepo.List(params)
+	if err != nil {
+		return nil, err
+	}
+	primeDownloads(p.Context, result.Items)
+	return result, nil
+}
+
+func primeBrowse(ctx context.Context, records []database.BrowseRecord) {
+	loader := loaderFrom(ctx)
+	for i := range records {
+		loader.downloads.prime(records[i].ID)
+		loader.authors.prime(records[i].Author)
+	}
+}
+
+func primeDownloads(ctx context.Context, records []database.DownloadRecord) {
+	loader := loaderFrom(ctx)
+	for i := range records {
+		loader.browse.prime(records[i].VideoID)
+		loader.authors.prime(records[i].Author)
+	}
+}
+
+// ============================================================================
+// 参数
+// ============================================================================
+
+func connectionType(name string, itemType *graphql.Object) *graphql.Object {
+	return &graphql.Object{Name: name, Fields: graphql.Fields{
+		"items":      {Type: &graphql.List{OfType: itemType}},
+		"total":      {Type: graphql.Int},
+		"page":       {Type: graphql.Int},
+		"pageSize":   {Type: graphql.Int},
+		"totalPages": {Type: graphql.Int},
+	}}
+}
+
+// withPageArgs 添加分页和排序参数，sortBy 只接受 sortColumns 中的列
+func withPageArgs(args graphql.Args, sortColumns map[string]bool, defaultSort string) graphql.Args {
+	columns := make([]string, 0, len(sortColumns))
+	for column := range sortColumns {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	args["page"] = &graphql.Arg{Type: graphql.Int, Default: 1}
+	args["pageSize"] = &graphql.Arg{Type: graphql.Int, Default: 20, Description: fmt.Sprintf("1-%d", graphQLMaxPageSize)}
+	args["sortBy"] = &graphql.Arg{Type: graphql.String, Default: defaultSort, Enum: columns}
+	args["sortDesc"] = &graphql.Arg{Type: graphql.Boolean, Default: true}
+	return args
+}
+
+// browseArgs 浏览记录列表的参数，withAuthor 为 false 时由父字段限定作者
+func browseArgs(withAuthor bool) graphql.Args {
+	args := graphql.Args{
+		"query":     {Type: graphql.String, Description: "按标题或作者模糊匹配"},
+		"startDate": {Type: graphql.Date, Description: "浏览时间起始日期"},
+		"endDate":   {Type: graphql.Date, Description: "浏览时间结束日期（包含当天）"},
+	}
+	if withAuthor {
+		args["author"] = &graphql.Arg{Type: graphql.String, Description: "作者名称，精确匹配"}
+	}
+	return withPageArgs(args, database.BrowseSortColumns, "browse_time")
+}
+
+// downloadArgs 下载记录列表的参数，withAuthor 为 false 时由父字段限定作者
+func downloadArgs(withAuthor bool) graphql.Args {
+	args := graphql.Args{
+		"query":     {Type: graphql.String, Description: "按标题或作者模糊匹配"},
+		"startDate": {Type: graphql.Date, Description: "下载时间起始日期"},
+		"endDate":   {Type: graphql.Date, Description: "下载时间结束日期（包含当天）"},
+		"status": {Type: graphql.String, Enum: []string{
+			database.DownloadStatusPending, database.DownloadStatusInProgress,
+			database.DownloadStatusCompleted, database.DownloadStatusFailed,
+		}},
+		"minResolution": {Type: graphql.Int, Description: "短边像素下限，如 1080"},
+		"videoCodec":    {Type: graphql.String, Description: "如 h264、hevc"},
+		"transcriptStatus": {Type: graphql.String, Description: "none 表示未转写", Enum: []string{
+			"none", database.TranscriptStatusInProgress, database.TranscriptStatusCompleted, database.TranscriptStatusFailed,
+		}},
+		"initiatedBy": {Type: graphql.String, Description: "发起下载的控制台用户"},
+	}
+	if withAuthor {
+		args["author"] = &graphql.Arg{Type: graphql.String, Description: "作者名称，精确匹配"}
+	}
+	return withPageArgs(args, database.DownloadSortColumns, "download_time")
+}
+
+// pageSizeOf 计算开销时的列表长度
+func pageSizeOf(args map[string]interface{}) int {
+	pageSize, _ := args["pageSize"].(int)
+	return min(max(pageSize, 1), graphQLMaxPageSize)
+}
+
+func applyPageArgs(params *database.PaginationParams, args map[string]interface{}) error {
+	params.Page, _ = args["page"].(int)
+	params.PageSize, _ = args["pageSize"].(int)
+	params.SortBy, _ = args["sortBy"].(string)
+	params.SortDesc, _ = args["sortDesc"].(bool)
+	if params.Page < 1 {
+		return fmt.Errorf("page must be at least 1")
+	}
+	if params.PageSize < 1 || params.PageSize > graphQLMaxPageSize {
+		return fmt.Errorf("pageSize must be between 1 and %d", graphQLMaxPageSize)
+	}
+	return nil
+}
+
+func filterParamsFromArgs(args map[string]interface{}) (*database.FilterParams, error) {
+	params := &database.FilterParams{}
+	if err := applyPageArgs(&params.PaginationParams, args); err != nil {
+		return nil, err
+	}
+	params.Query, _ = args["query"].(string)
+	params.Author, _ = args["author"].(string)
+	if start, ok := args["startDate"].(time.Time); ok {
+		params.StartDate = &start
+	}
+	if end, ok := args["endDate"].(time.Time); ok {
+		// 结束日期包含当天
+		end = end.Add(24*time.Hour - time.Nanosecond)
+		params.EndDate = &end
+	}
+	if params.StartDate != nil && params.EndDate != nil && params.EndDate.Before(*params.StartDate) {
+		return nil, fmt.Errorf("endDate must not be before startDate")
+	}
+	return params, nil
+}
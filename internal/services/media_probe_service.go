@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"wx_channel/internal/database"
+	"wx_channel/internal/utils"
+)
+
+// MediaInfo 表示 ffprobe 探测到的媒体信息
+type MediaInfo struct {
+	Container  string  `json:"container"`
+	VideoCodec string  `json:"videoCodec"`
+	AudioCodec string  `json:"audioCodec"`
+	Bitrate    int64   `json:"bitrate"`
+	FPS        float64 `json:"fps"`
+	Width      int     `json:"width"`
+	Height     int     `json:"height"`
+	Duration   float64 `json:"duration"` // 秒
+}
+
+// ffprobeOutput 对应 `ffprobe -print_format json -show_format -show_streams` 的输出
+type ffprobeOutput struct {
+	Format struct {
+		FormatName string `json:"format_name"`
+		Duration   string `json:"duration"`
+		BitRate    string `json:"bit_rate"`
+	} `json:"format"`
+	Streams []struct {
+		CodecType    string            `json:"codec_type"`
+		CodecName    string            `json:"codec_name"`
+		Width        int               `json:"width"`
+		Height       int               `json:"height"`
+		AvgFrameRate string            `json:"avg_frame_rate"`
+		RFrameRate   string            `json:"r_frame_rate"`
+		Duration     string            `json:"duration"`
+		Tags         map[string]string `json:"tags"`
+		SideDataList []ffprobeSideData `json:"side_data_list"`
+	} `json:"streams"`
+}
+
+// ffprobeSideData 视频流的附加数据（如显示矩阵中的旋转角度）
+type ffprobeSideData struct {
+	Rotation float64 `json:"rotation"`
+}
+
+// MediaProbeService 在下载完成后使用 ffprobe 探测真实的编码、码率和分辨率
+type MediaProbeService struct {
+	settingsRepo *database.SettingsRepository
+	downloadRepo *database.DownloadRecordRepository
+}
+
+// NewMediaProbeService 创建一个新的 MediaProbeService
+func NewMediaProbeService() *MediaProbeService {
+	return &MediaProbeService{
+		settingsRepo: database.NewSettingsRepository(),
+		downloadRepo: database.NewDownloadRecordRepository(),
+	}
+}
+
+// IsAvailable 检查 ffprobe 是否可用
+func (s *MediaProbeService) IsAvailable() bool {
+	return findFFprobePath(s.settingsRepo) != ""
+}
+
+// ProbeRecordAsync 异步探测下载记录的媒体信息（ffprobe 不可用时静默跳过）
+func (s *MediaProbeService) ProbeRecordAsync(recordID string) {
+	if !s.IsAvailable() {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		if _, err := s.ProbeRecord(ctx, recordID); err != nil {
+			utils.Warn("探测媒体信息失败 [%s]: %v", recordID, err)
+		}
+	}()
+}
+
+// ProbeRecord 探测下载记录对应文件的媒体信息并写回数据库
+func (s *MediaProbeService) ProbeRecord(ctx context.Context, recordID string) (*database.DownloadRecord, error) {
+	record, err := s.downloadRepo.GetByID(recordID)
+	if err != nil {
+		return nil, fmt.Errorf("获取下载记录失败: %w", err)
+	}
+	if record == nil {
+		return nil, fmt.Errorf("下载记录不存在: %s", recordID)
+	}
+	if record.FilePath == "" {
+		return nil, fmt.Errorf("下载记录没有文件路径: %s", recordID)
+	}
+
+	info, err := s.Probe(ctx, record.FilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	record.Container = info.Container
+	record.VideoCodec = info.VideoCodec
+	record.AudioCodec = info.AudioCodec
+	record.Bitrate = info.Bitrate
+	record.FPS = info.FPS
+	record.Width = info.Width
+	record.Height = info.Height
+	record.MediaDuration = info.Duration
+
+	// 页面数据中的分辨率和时长经常缺失，用真实值补齐
+	if info.Width > 0 && info.Height > 0 {
+		record.Resolution = fmt.Sprintf("%dx%d", info.Width, info.Height)
+	}
+	if record.Duration == 0 && info.Duration > 0 {
+		record.Duration = int64(info.Duration * 1000)
+	}
+
+	if err := s.downloadRepo.UpdateMediaInfo(record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// Probe 使用 ffprobe 读取媒体文件信息
+func (s *MediaProbeService) Probe(ctx context.Context, filePath string) (*MediaInfo, error) {
+	ffprobePath := findFFprobePath(s.settingsRepo)
+	if ffprobePath == "" {
+		return nil, fmt.Errorf("未找到 ffprobe，请确认其与 FFmpeg 位于同一目录或已添加到系统 PATH")
+	}
+	if _, err := os.Stat(filePath); err != nil {
+		return nil, fmt.Errorf("视频文件不存在: %s", filePath)
+	}
+
+	cmd := exec.CommandContext(ctx, ffprobePath,
+		"-v", "error",
+		"-print_format", "json",
+		"-show_format",
+		"-show_streams",
+		filePath,
+	)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe 执行失败: %w", err)
+	}
+
+	return parseFFprobeOutput(output)
+}
+
+// parseFFprobeOutput 解析 ffprobe 的 JSON 输出
+func parseFFprobeOutput(data []byte) (*MediaInfo, error) {
+	var out ffprobeOutput
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("解析 ffprobe 输出失败: %w", err)
+	}
+
+	info := &MediaInfo{
+		Container: out.Format.FormatName,
+	}
+	info.Duration, _ = strconv.ParseFloat(out.Format.Duration, 64)
+	info.Bitrate, _ = strconv.ParseInt(out.Format.BitRate, 10, 64)
+
+	for _, stream := range out.Streams {
+		switch stream.CodecType {
+		case "video":
+			if info.VideoCodec != "" {
+				continue
+			}
+			info.VideoCodec = stream.CodecName
+			info.Width, info.Height = stream.Width, stream.Height
+			if isRotated(stream.Tags["rotate"], stream.SideDataList) {
+				info.Width, info.Height = info.Height, info.Width
+			}
+			info.FPS = parseFrameRate(stream.AvgFrameRate)
+			if info.FPS == 0 {
+				info.FPS = parseFrameRate(stream.RFrameRate)
+			}
+			if info.Duration == 0 {
+				info.Duration, _ = strconv.ParseFloat(stream.Duration, 64)
+			}
+		case "audio":
+			if info.AudioCodec == "" {
+				info.AudioCodec = stream.CodecName
+			}
+		}
+	}
+
+	return info, nil
+}
+
+// isRotated 判断视频流是否被旋转了 90/270 度（竖屏视频常见）
+func isRotated(rotateTag string, sideData []ffprobeSideData) bool {
+	rotation, _ := strconv.ParseFloat(rotateTag, 64)
+	for _, sd := range sideData {
+		if sd.Rotation != 0 {
+			rotation = sd.Rotation
+		}
+	}
+	r := int(rotation) % 180
+	return r == 90 || r == -90
+}
+
+// parseFrameRate 解析 "30000/1001" 形式的帧率
+func parseFrameRate(rate string) float64 {
+	num, den, found := strings.Cut(rate, "/")
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0
+	}
+	if !found {
+		return n
+	}
+	d, err := strconv.ParseFloat(den, 64)
+	if err != nil || d == 0 {
+		return 0
+	}
+	return float64(int(n/d*100+0.5)) / 100
+}
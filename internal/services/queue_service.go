@@ -1,6 +1,7 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -14,6 +15,13 @@ import (
 	"github.com/google/uuid"
 )
 
+var (
+	// ErrQueueItemNotFound 队列项目不存在
+	ErrQueueItemNotFound = errors.New("queue item not found")
+	// ErrQueueItemState 队列项目当前的状态不允许该操作
+	ErrQueueItemState = errors.New("invalid queue item state")
+)
+
 // QueueService 处理下载队列管理操作
 type QueueService struct {
 	repo     *database.QueueRepository
@@ -40,10 +48,17 @@ type VideoInfo struct {
 	Duration   int64  `json:"duration"`
 	Resolution string `json:"resolution"`
 	Size       int64  `json:"size"`
+	NonceID    string `json:"nonceId"` // objectNonceId，链接过期时用于重新获取
+	Quality    string `json:"quality"` // 画质策略，为空时使用默认策略
 }
 
 // AddToQueue 将视频添加到下载队列
 func (s *QueueService) AddToQueue(videos []VideoInfo) ([]database.QueueItem, error) {
+	return s.AddToQueueAs("", videos)
+}
+
+// AddToQueueAs 将视频添加到下载队列，并记录发起下载的控制台用户
+func (s *QueueService) AddToQueueAs(initiatedBy string, videos []VideoInfo) ([]database.QueueItem, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -93,6 +108,9 @@ func (s *QueueService) AddToQueue(videos []VideoInfo) ([]database.QueueItem, err
 			ChunksTotal:     chunksTotal,
 			ChunksCompleted: 0,
 			RetryCount:      0,
+			NonceID:         video.NonceID,
+			Quality:         video.Quality,
+			InitiatedBy:     initiatedBy,
 		}
 
 		if err := s.repo.Add(item); err != nil {
@@ -110,6 +128,13 @@ func (s *QueueService) RemoveFromQueue(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	item, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if item == nil {
+		return fmt.Errorf("%w: %s", ErrQueueItemNotFound, id)
+	}
 	return s.repo.Remove(id)
 }
 
@@ -131,12 +156,12 @@ func (s *QueueService) Pause(id string) error {
 		return err
 	}
 	if item == nil {
-		return fmt.Errorf("queue item not found: %s", id)
+		return fmt.Errorf("%w: %s", ErrQueueItemNotFound, id)
 	}
 
 	// 只能暂停正在下载的项目
 	if item.Status != database.QueueStatusDownloading {
-		return fmt.Errorf("can only pause downloading items, current status: %s", item.Status)
+		return fmt.Errorf("%w: can only pause downloading items, current status: %s", ErrQueueItemState, item.Status)
 	}
 
 	return s.repo.UpdateStatus(id, database.QueueStatusPaused)
@@ -152,12 +177,12 @@ func (s *QueueService) Resume(id string) error {
 		return err
 	}
 	if item == nil {
-		return fmt.Errorf("queue item not found: %s", id)
+		return fmt.Errorf("%w: %s", ErrQueueItemNotFound, id)
 	}
 
 	// 只能恢复暂停的项目
 	if item.Status != database.QueueStatusPaused {
-		return fmt.Errorf("can only resume paused items, current status: %s", item.Status)
+		return fmt.Errorf("%w: can only resume paused items, current status: %s", ErrQueueItemState, item.Status)
 	}
 
 	return s.repo.UpdateStatus(id, database.QueueStatusPending)
@@ -181,7 +206,7 @@ func (s *QueueService) SetPriority(id string, priority int) error {
 		return err
 	}
 	if item == nil {
-		return fmt.Errorf("queue item not found: %s", id)
+		return fmt.Errorf("%w: %s", ErrQueueItemNotFound, id)
 	}
 
 	item.Priority = priority
@@ -257,7 +282,7 @@ func (s *QueueService) CompleteDownload(id string) error {
 		return err
 	}
 	if item == nil {
-		return fmt.Errorf("queue item not found: %s", id)
+		return fmt.Errorf("%w: %s", ErrQueueItemNotFound, id)
 	}
 
 	// 检查是否已完成以避免重复记录
@@ -293,14 +318,23 @@ func (s *QueueService) CompleteDownload(id string) error {
 		Resolution:   item.Resolution, // 使用队列项目中的分辨率
 		Status:       database.DownloadStatusCompleted,
 		DownloadTime: time.Now(),
+		InitiatedBy:  item.InitiatedBy,
 	}
 
-	downloadRepo := database.NewDownloadRecordRepository()
-	if err := downloadRepo.Create(downloadRecord); err != nil {
+	if err := NewDownloadRecordService().Create(downloadRecord); err != nil {
 		// 记录错误但不失败完成
 		fmt.Printf("Warning: failed to create download record: %v\n", err)
 	}
 
+	GetWebhookService().Dispatch(database.WebhookEventDownloadCompleted, WebhookDownloadData{
+		Source:   "queue",
+		QueueID:  item.ID,
+		VideoID:  item.VideoID,
+		Title:    item.Title,
+		Author:   item.Author,
+		FilePath: filePath,
+		FileSize: item.TotalSize,
+	})
 	return nil
 }
 
@@ -391,7 +425,20 @@ func (s *QueueService) FailDownload(id string, errorMessage string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	return s.repo.SetError(id, errorMessage)
+	if err := s.repo.SetError(id, errorMessage); err != nil {
+		return err
+	}
+	if item, err := s.repo.GetByID(id); err == nil && item != nil {
+		GetWebhookService().Dispatch(database.WebhookEventDownloadFailed, WebhookDownloadData{
+			Source:  "queue",
+			QueueID: item.ID,
+			VideoID: item.VideoID,
+			Title:   item.Title,
+			Author:  item.Author,
+			Error:   errorMessage,
+		})
+	}
+	return nil
 }
 
 // IncrementRetryCount 增加项目的重试计数
@@ -491,7 +538,7 @@ func (s *QueueService) ResetRetryCount(id string) error {
 		return err
 	}
 	if item == nil {
-		return fmt.Errorf("queue item not found: %s", id)
+		return fmt.Errorf("%w: %s", ErrQueueItemNotFound, id)
 	}
 
 	item.RetryCount = 0
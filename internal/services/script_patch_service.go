@@ -0,0 +1,424 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"wx_channel/internal/assets"
+	"wx_channel/internal/config"
+	"wx_channel/internal/utils"
+)
+
+// 补丁规则动作
+const (
+	PatchActionReplace      = "replace"       // 正则替换，replace 中可使用 $1 等捕获组
+	PatchActionInsertBefore = "insert_before" // 在每个匹配之前插入 insert
+	PatchActionInsertAfter  = "insert_after"  // 在每个匹配之后插入 insert
+	PatchActionBuiltin      = "builtin"       // 调用内置的改写逻辑（无法用正则表达的改写）
+)
+
+// 规则文件变化检查间隔
+const scriptRulesCheckInterval = 2 * time.Second
+
+// PatchRuleSet 表示一个版本化的脚本补丁规则文件
+type PatchRuleSet struct {
+	Version string      `json:"version"`
+	Rules   []PatchRule `json:"rules"`
+}
+
+// PatchRule 描述对微信前端 JS 文件的一处改写
+type PatchRule struct {
+	Name        string                  `json:"name"`
+	Description string                  `json:"description,omitempty"`
+	Path        string                  `json:"path"`              // 请求路径包含该字符串时生效
+	Action      string                  `json:"action"`            // replace / insert_before / insert_after / builtin
+	Find        string                  `json:"find"`              // 正则表达式
+	Replace     string                  `json:"replace,omitempty"` // replace 动作的替换内容
+	Insert      string                  `json:"insert,omitempty"`  // insert_* 动作插入的内容
+	Builtin     string                  `json:"builtin,omitempty"` // builtin 动作的名称
+	Vars        map[string]PatchRuleVar `json:"vars,omitempty"`    // 从文件中提取的变量，以 {{name}} 引用
+	Headers     map[string]string       `json:"headers,omitempty"` // 路径命中时设置的响应头
+	Required    bool                    `json:"required"`          // 未命中时告警（通常意味着微信前端已更新）
+	Disabled    bool                    `json:"disabled,omitempty"`
+
+	find *regexp.Regexp
+}
+
+// PatchRuleVar 从文件内容中提取变量（取第一个捕获组），未匹配时使用默认值
+type PatchRuleVar struct {
+	Pattern string `json:"pattern"`
+	Default string `json:"default"`
+
+	re *regexp.Regexp
+}
+
+// PatchRuleResult 记录一条规则最近一次改写 JS 文件时的命中情况
+type PatchRuleResult struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Path        string    `json:"path"`
+	Required    bool      `json:"required"`
+	Disabled    bool      `json:"disabled,omitempty"`
+	Matched     bool      `json:"matched"`
+	MatchCount  int       `json:"matchCount"`
+	File        string    `json:"file,omitempty"` // 实际被改写的 JS 路径
+	AppliedAt   time.Time `json:"appliedAt,omitempty"`
+}
+
+// PatchRuleReport 规则加载状态与各规则最近一次改写的命中报告
+type PatchRuleReport struct {
+	Version         string            `json:"version"`
+	Source          string            `json:"source"` // file / embedded
+	File            string            `json:"file"`
+	LoadedAt        time.Time         `json:"loadedAt"`
+	LoadError       string            `json:"loadError,omitempty"`
+	PagePath        string            `json:"pagePath,omitempty"`
+	PageLoadedAt    *time.Time        `json:"pageLoadedAt,omitempty"`
+	Rules           []PatchRuleResult `json:"rules"`
+	MissingRequired []string          `json:"missingRequired"`
+}
+
+// ScriptPatchService 加载并应用声明式的脚本补丁规则，规则文件修改后自动重新加载
+type ScriptPatchService struct {
+	mu          sync.Mutex
+	file        string
+	ruleSet     *PatchRuleSet
+	source      string
+	loadedAt    time.Time
+	loadError   string
+	fileModTime time.Time
+	lastCheck   time.Time
+
+	pagePath     string
+	pageLoadedAt time.Time
+	results      map[string]*PatchRuleResult // 规则名 -> 最近一次改写匹配文件时的结果
+}
+
+var (
+	scriptPatchService     *ScriptPatchService
+	scriptPatchServiceOnce sync.Once
+)
+
+// GetScriptPatchService 返回单例 ScriptPatchService（脚本处理器与控制台 API 共享命中状态），
+// 规则文件路径取自首次调用时传入的配置
+func GetScriptPatchService(cfg *config.Config) *ScriptPatchService {
+	scriptPatchServiceOnce.Do(func() {
+		file := "script_rules.json"
+		if cfg != nil && cfg.ScriptRulesFile != "" {
+			file = cfg.ScriptRulesFile
+		}
+		scriptPatchService = NewScriptPatchService(file)
+	})
+	return scriptPatchService
+}
+
+// NewScriptPatchService 创建一个新的 ScriptPatchService，规则文件不存在时使用内置默认规则
+func NewScriptPatchService(file string) *ScriptPatchService {
+	s := &ScriptPatchService{
+		file:    file,
+		results: make(map[string]*PatchRuleResult),
+	}
+	if err := s.Reload(); err != nil {
+		utils.Warn("[脚本补丁] 加载规则文件失败，使用内置默认规则: %v", err)
+	}
+	return s
+}
+
+// Reload 重新加载规则。文件不存在时回退到内置规则；文件无效时保留当前规则并返回错误
+func (s *ScriptPatchService) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reloadLocked()
+}
+
+func (s *ScriptPatchService) reloadLocked() error {
+	s.lastCheck = time.Now()
+
+	data, source := assets.ScriptRulesJSON, "embedded"
+	info, err := os.Stat(s.file)
+	if err == nil {
+		fileData, readErr := os.ReadFile(s.file)
+		if readErr != nil {
+			return s.failLoad(fmt.Errorf("failed to read script rules: %w", readErr))
+		}
+		data, source = fileData, "file"
+		s.fileModTime = info.ModTime()
+	} else {
+		s.fileModTime = time.Time{}
+	}
+
+	ruleSet, err := ParsePatchRules(data)
+	if err != nil {
+		return s.failLoad(err)
+	}
+
+	s.ruleSet = ruleSet
+	s.source = source
+	s.loadedAt = time.Now()
+	s.loadError = ""
+	utils.Info("[脚本补丁] 已加载 %d 条规则 (版本 %s, 来源 %s)", len(ruleSet.Rules), ruleSet.Version, source)
+	return nil
+}
+
+// failLoad 记录加载错误。首次加载失败时使用内置规则，保证注入功能可用
+func (s *ScriptPatchService) failLoad(err error) error {
+	s.loadError = err.Error()
+	if s.ruleSet == nil {
+		if ruleSet, embeddedErr := ParsePatchRules(assets.ScriptRulesJSON); embeddedErr == nil {
+			s.ruleSet = ruleSet
+			s.source = "embedded"
+			s.loadedAt = time.Now()
+		}
+	}
+	return err
+}
+
+// checkReload 规则文件被修改、创建或删除时自动重新加载（限频检查）
+func (s *ScriptPatchService) checkReload() {
+	if time.Since(s.lastCheck) < scriptRulesCheckInterval {
+		return
+	}
+	s.lastCheck = time.Now()
+
+	var modTime time.Time
+	if info, err := os.Stat(s.file); err == nil {
+		modTime = info.ModTime()
+	}
+	if modTime.Equal(s.fileModTime) {
+		return
+	}
+	if err := s.reloadLocked(); err != nil {
+		utils.Warn("[脚本补丁] 重新加载规则文件失败，继续使用当前规则: %v", err)
+	}
+}
+
+// ParsePatchRules 解析并校验规则文件
+func ParsePatchRules(data []byte) (*PatchRuleSet, error) {
+	var ruleSet PatchRuleSet
+	if err := json.Unmarshal(data, &ruleSet); err != nil {
+		return nil, fmt.Errorf("failed to parse script rules: %w", err)
+	}
+
+	names := make(map[string]bool)
+	for i := range ruleSet.Rules {
+		rule := &ruleSet.Rules[i]
+		if rule.Name == "" {
+			return nil, fmt.Errorf("rule #%d: name is required", i+1)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("rule %s: duplicate name", rule.Name)
+		}
+		names[rule.Name] = true
+
+		if rule.Path == "" || rule.Find == "" {
+			return nil, fmt.Errorf("rule %s: path and find are required", rule.Name)
+		}
+		switch rule.Action {
+		case "":
+			rule.Action = PatchActionReplace
+		case PatchActionReplace, PatchActionInsertBefore, PatchActionInsertAfter:
+		case PatchActionBuiltin:
+			if _, ok := patchBuiltins[rule.Builtin]; !ok {
+				return nil, fmt.Errorf("rule %s: unknown builtin %q", rule.Name, rule.Builtin)
+			}
+		default:
+			return nil, fmt.Errorf("rule %s: unknown action %q", rule.Name, rule.Action)
+		}
+
+		re, err := regexp.Compile(rule.Find)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: invalid find pattern: %w", rule.Name, err)
+		}
+		rule.find = re
+
+		for name, v := range rule.Vars {
+			re, err := regexp.Compile(v.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: invalid pattern for var %s: %w", rule.Name, name, err)
+			}
+			v.re = re
+			rule.Vars[name] = v
+		}
+	}
+	return &ruleSet, nil
+}
+
+// BeginPageLoad 记录最近一次页面加载。命中记录不在这里清空：
+// 浏览器缓存的 JS 不会再次经过代理，之前的改写结果仍然有效，
+// 只有对应文件被重新请求时才由 Apply 更新
+func (s *ScriptPatchService) BeginPageLoad(pagePath string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checkReload()
+	s.pagePath = pagePath
+	s.pageLoadedAt = time.Now()
+}
+
+// Apply 对 JS 文件内容应用所有路径匹配的规则。
+// 返回改写后的内容、需要设置的响应头，以及是否有规则的路径匹配该文件
+func (s *ScriptPatchService) Apply(path, content string) (string, map[string]string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checkReload()
+	if s.ruleSet == nil {
+		return content, nil, false
+	}
+
+	var headers map[string]string
+	applied := false
+	for i := range s.ruleSet.Rules {
+		rule := &s.ruleSet.Rules[i]
+		if rule.Disabled || !strings.Contains(path, rule.Path) {
+			continue
+		}
+		applied = true
+
+		for k, v := range rule.Headers {
+			if headers == nil {
+				headers = make(map[string]string)
+			}
+			headers[k] = v
+		}
+
+		var count int
+		content, count = rule.apply(content)
+		s.results[rule.Name] = &PatchRuleResult{
+			Name:       rule.Name,
+			Matched:    count > 0,
+			MatchCount: count,
+			File:       path,
+			AppliedAt:  time.Now(),
+		}
+
+		if count > 0 {
+			utils.LogFileInfo("[脚本补丁] ✅ %s 命中 %d 处 | Path=%s", rule.Name, count, path)
+		} else if rule.Required {
+			utils.Warn("[脚本补丁] ❌ 必需规则 %s 未命中，微信前端可能已更新 | Path=%s", rule.Name, path)
+		} else {
+			utils.LogFileInfo("[脚本补丁] ⚠️ %s 未命中 | Path=%s", rule.Name, path)
+		}
+	}
+	return content, headers, applied
+}
+
+// apply 执行单条规则，返回改写后的内容和命中次数
+func (r *PatchRule) apply(content string) (string, int) {
+	count := len(r.find.FindAllStringIndex(content, -1))
+	if count == 0 {
+		return content, 0
+	}
+
+	switch r.Action {
+	case PatchActionReplace:
+		return r.find.ReplaceAllString(content, r.expandVars(content, r.Replace)), count
+	case PatchActionInsertBefore:
+		insert := r.expandVars(content, r.Insert)
+		return r.find.ReplaceAllStringFunc(content, func(m string) string { return insert + m }), count
+	case PatchActionInsertAfter:
+		insert := r.expandVars(content, r.Insert)
+		return r.find.ReplaceAllStringFunc(content, func(m string) string { return m + insert }), count
+	case PatchActionBuiltin:
+		result, ok := patchBuiltins[r.Builtin](r.find, content)
+		if !ok {
+			return content, 0
+		}
+		return result, count
+	}
+	return content, 0
+}
+
+// expandVars 将模板中的 {{name}} 替换为从内容中提取到的变量值
+func (r *PatchRule) expandVars(content, tmpl string) string {
+	for name, v := range r.Vars {
+		value := v.Default
+		if m := v.re.FindStringSubmatch(content); len(m) > 1 {
+			value = m[1]
+		}
+		// 变量值会出现在正则替换模板中，需转义 $
+		tmpl = strings.ReplaceAll(tmpl, "{{"+name+"}}", strings.ReplaceAll(value, "$", "$$"))
+	}
+	return tmpl
+}
+
+// Report 返回当前规则状态与各规则最近一次改写的命中情况（包括浏览器仍在使用的缓存文件）
+func (s *ScriptPatchService) Report() *PatchRuleReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checkReload()
+	report := &PatchRuleReport{
+		Source:          s.source,
+		File:            s.file,
+		LoadedAt:        s.loadedAt,
+		LoadError:       s.loadError,
+		PagePath:        s.pagePath,
+		Rules:           []PatchRuleResult{},
+		MissingRequired: []string{},
+	}
+	if !s.pageLoadedAt.IsZero() {
+		t := s.pageLoadedAt
+		report.PageLoadedAt = &t
+	}
+	if s.ruleSet == nil {
+		return report
+	}
+
+	report.Version = s.ruleSet.Version
+	for _, rule := range s.ruleSet.Rules {
+		result := PatchRuleResult{Name: rule.Name, Path: rule.Path}
+		if r, ok := s.results[rule.Name]; ok {
+			result = *r
+		}
+		result.Path = rule.Path
+		result.Description = rule.Description
+		result.Required = rule.Required
+		result.Disabled = rule.Disabled
+		if rule.Required && !rule.Disabled && !result.Matched {
+			report.MissingRequired = append(report.MissingRequired, rule.Name)
+		}
+		report.Rules = append(report.Rules, result)
+	}
+	return report
+}
+
+// patchBuiltins 内置改写逻辑，参数为规则的 find 正则
+var patchBuiltins = map[string]func(find *regexp.Regexp, content string) (string, bool){
+	"export_api": patchExportAPI,
+}
+
+// patchExportAPI 解析 export{a as b,...} 中的本地函数名，在 export 之前广播 APILoaded 事件
+func patchExportAPI(find *regexp.Regexp, content string) (string, bool) {
+	loc := find.FindStringSubmatchIndex(content)
+	if len(loc) < 4 || loc[2] < 0 {
+		return content, false
+	}
+
+	var locals []string
+	for _, item := range strings.Split(content[loc[2]:loc[3]], ",") {
+		local := strings.TrimSpace(item)
+		// 处理 "xxx as yyy" 格式
+		if idx := strings.Index(local, " as "); idx != -1 {
+			local = strings.TrimSpace(local[:idx])
+		}
+		if local != "" {
+			locals = append(locals, local)
+		}
+	}
+	if len(locals) == 0 {
+		return content, false
+	}
+
+	utils.LogFileInfo("[脚本补丁] 提取到 %d 个导出函数", len(locals))
+	apiMethods := "{" + strings.Join(locals, ",") + "}"
+	// 与迁移前一致：每个 export{ 之前都插入事件，并去掉 export 与 { 之间的空白
+	return exportOpenRegex.ReplaceAllLiteralString(content, ";WXU.emit(WXU.Events.APILoaded,"+apiMethods+");export{"), true
+}
+
+var exportOpenRegex = regexp.MustCompile(`export\s*\{`)
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"wx_channel/internal/utils"
+)
+
+// TestMain 把日志写到临时目录，避免在源码目录下生成 logs/wx_channel.log
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "wx_channel_services_test")
+	if err == nil {
+		err = utils.InitLoggerWithRotation(utils.INFO, filepath.Join(dir, "wx_channel.log"), 5)
+	}
+	if err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+const (
+	indexPublishPath  = "/t/wx_fed/finder/web/web-finder/res/js/index.publish.7f3a.js"
+	svgIconsPath      = "/t/wx_fed/finder/web/web-finder/res/js/virtual_svg-icons-register.c21e.js"
+	workerReleasePath = "/t/wx_fed/finder/web/web-finder/res/js/worker_release.9d0b.js"
+	connectPublish    = "/t/wx_fed/finder/web/web-finder/res/js/connect.publish.41aa.js"
+)
+
+// 以下 fixture 截取自微信前端 JS 中被改写的片段
+const (
+	indexPublishFixture = `class S{onData(h){this.sourceBuffer.appendBuffer(h),this.pending--}}` +
+		`function w(f){if(f.cmd===re.MAIN_THREAD_CMD.AUTO_CUT){g(f)}}`
+
+	svgIconsFixture = `var r=1;` +
+		`async finderPcFlow(e){const t=await q("/pcflow",e);return t}async ` +
+		`finderStream(e){return q("/stream",e)}async ` +
+		`finderGetCommentDetail(e){return q("/detail",e)}async ` +
+		`finderUserPage(e){return q("/user",e)}async ` +
+		`finderLiveUserPage(e){return q("/live",e)}async ` +
+		`finderGetRecommend(e){return q("/rec",e)}async ` +
+		`finderPCSearch(n){const t=await q("/pcsearch",n);return log(t),t}async ` +
+		`finderSearch(n){const t=await q("/search",n);return log(t),t}async ` +
+		`other(){}` +
+		`export {Ue as finderPcFlow,$t as finderSearch, Xe as finderUserPage,zz}`
+
+	workerReleaseFixture = `postMessage({cmd:"CUT",fmp4Index:p.fmp4Index,data:d})`
+
+	connectPublishFixture = `const o={flowTab:nn,flowTabId:3,goToNextFlowFeed:Ab,goToPrevFlowFeed:Cd};`
+)
+
+func newTestScriptPatchService(t *testing.T) *ScriptPatchService {
+	t.Helper()
+	// 规则文件不存在时使用内置规则
+	return NewScriptPatchService(filepath.Join(t.TempDir(), "script_rules.json"))
+}
+
+// TestScriptPatchRulesMatchLegacyRewrites 内置规则对各文件的改写结果与迁移前的 handle* 函数一致
+func TestScriptPatchRulesMatchLegacyRewrites(t *testing.T) {
+	noCache := map[string]string{
+		"Cache-Control": "no-cache, no-store, must-revalidate",
+		"Pragma":        "no-cache",
+		"Expires":       "0",
+	}
+	tests := []struct {
+		name    string
+		path    string
+		content string
+		want    string
+		headers map[string]string
+	}{
+		{"index.publish", indexPublishPath, indexPublishFixture, legacyIndexPublish(indexPublishFixture), nil},
+		{"virtual_svg-icons-register", svgIconsPath, svgIconsFixture, legacyVirtualSvgIcons(svgIconsFixture), nil},
+		{"worker_release", workerReleasePath, workerReleaseFixture, legacyWorkerRelease(workerReleaseFixture), nil},
+		{"connect.publish", connectPublish, connectPublishFixture, legacyConnectPublish(connectPublishFixture), noCache},
+		{"connect.publish without flowTab", connectPublish, `x={goToNextFlowFeed:Ab}`, legacyConnectPublish(`x={goToNextFlowFeed:Ab}`), noCache},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestScriptPatchService(t)
+			got, headers, applied := s.Apply(tt.path, tt.content)
+			if !applied {
+				t.Fatal("Expected rules to apply")
+			}
+			if got == tt.content {
+				t.Fatal("Expected content to be rewritten")
+			}
+			if got != tt.want {
+				t.Errorf("Rewrite differs from the legacy handler\n got: %s\nwant: %s", got, tt.want)
+			}
+			if fmt.Sprint(headers) != fmt.Sprint(tt.headers) {
+				t.Errorf("Expected headers %v, got %v", tt.headers, headers)
+			}
+		})
+	}
+
+	// 路径不匹配任何规则时不改写
+	s := newTestScriptPatchService(t)
+	if got, headers, applied := s.Apply("/t/wx_fed/finder/web/web-finder/res/js/other.js", svgIconsFixture); applied || got != svgIconsFixture || headers != nil {
+		t.Errorf("Expected unrelated file to be left alone, applied=%v headers=%v", applied, headers)
+	}
+}
+
+// TestScriptPatchExportAPI 测试 export_api 内置改写
+func TestScriptPatchExportAPI(t *testing.T) {
+	s := newTestScriptPatchService(t)
+	got, _, _ := s.Apply(svgIconsPath, svgIconsFixture)
+	want := `;WXU.emit(WXU.Events.APILoaded,{Ue,$t,Xe,zz});export{`
+	if !strings.Contains(got, want) {
+		t.Errorf("Expected APILoaded event before export, got %s", got[strings.LastIndex(got, "async"):])
+	}
+	for _, r := range s.Report().Rules {
+		if r.Name == "api-loaded" && (!r.Matched || r.MatchCount != 1) {
+			t.Errorf("Expected api-loaded to match once, got %+v", r)
+		}
+	}
+}
+
+// TestScriptPatchMissingRequired 必需规则未命中时出现在报告的 missingRequired 中
+func TestScriptPatchMissingRequired(t *testing.T) {
+	s := newTestScriptPatchService(t)
+	// worker-decryptor 命中，index.publish 的两条必需规则都未命中
+	s.Apply(workerReleasePath, workerReleaseFixture)
+	s.Apply(indexPublishPath, "function changed(){}")
+
+	report := s.Report()
+	missing := strings.Join(report.MissingRequired, ",")
+	for _, name := range []string{"index-publish-buffers", "index-publish-decryptor"} {
+		if !strings.Contains(missing, name) {
+			t.Errorf("Expected %s in missingRequired, got %v", name, report.MissingRequired)
+		}
+	}
+	if strings.Contains(missing, "worker-decryptor") {
+		t.Errorf("Expected matched rule not to be reported missing, got %v", report.MissingRequired)
+	}
+	for _, r := range report.Rules {
+		if r.Name == "index-publish-buffers" && (r.Matched || r.File != indexPublishPath) {
+			t.Errorf("Unexpected result for unmatched rule: %+v", r)
+		}
+	}
+}
+
+// TestParsePatchRules 测试规则文件校验
+func TestParsePatchRules(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want string
+	}{
+		{"bad regex", `{"rules":[{"name":"a","path":"x.js","find":"(unclosed"}]}`, "invalid find pattern"},
+		{"bad var regex", `{"rules":[{"name":"a","path":"x.js","find":"x","vars":{"v":{"pattern":"[z-a]"}}}]}`, "invalid pattern for var v"},
+		{"unknown builtin", `{"rules":[{"name":"a","path":"x.js","find":"x","action":"builtin","builtin":"nope"}]}`, "unknown builtin"},
+		{"unknown action", `{"rules":[{"name":"a","path":"x.js","find":"x","action":"delete"}]}`, "unknown action"},
+		{"duplicate name", `{"rules":[{"name":"a","path":"x.js","find":"x"},{"name":"a","path":"y.js","find":"y"}]}`, "duplicate name"},
+		{"missing find", `{"rules":[{"name":"a","path":"x.js"}]}`, "path and find are required"},
+		{"invalid json", `{"rules":`, "failed to parse script rules"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParsePatchRules([]byte(tt.json))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	ruleSet, err := ParsePatchRules([]byte(`{"version":"1","rules":[{"name":"a","path":"x.js","find":"x"}]}`))
+	if err != nil || ruleSet.Rules[0].Action != PatchActionReplace {
+		t.Errorf("Expected default action replace, got %+v (%v)", ruleSet, err)
+	}
+}
+
+// TestScriptPatchInvalidFileKeepsRules 规则文件无效时保留当前规则
+func TestScriptPatchInvalidFileKeepsRules(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "script_rules.json")
+	if err := os.WriteFile(file, []byte(`{"rules":[{"name":"a","path":"x.js","find":"(bad"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := NewScriptPatchService(file)
+	report := s.Report()
+	if report.Source != "embedded" || !strings.Contains(report.LoadError, "invalid find pattern") || len(report.Rules) == 0 {
+		t.Errorf("Expected embedded rules with a load error, got source=%s error=%q rules=%d", report.Source, report.LoadError, len(report.Rules))
+	}
+}
+
+// ============================================================================
+// 迁移到规则文件之前 ScriptHandler 中的改写逻辑（去掉日志），作为对照
+// ============================================================================
+
+func legacyIndexPublish(content string) string {
+	regexp1 := regexp.MustCompile(`this.sourceBuffer.appendBuffer\(h\),`)
+	replaceStr1 := `(() => {
+if (window.__wx_channels_store__) {
+window.__wx_channels_store__.buffers.push(h);
+// 添加缓存监控
+if (window.__wx_channels_video_cache_monitor) {
+    window.__wx_channels_video_cache_monitor.addBuffer(h);
+}
+}
+})(),this.sourceBuffer.appendBuffer(h),`
+	content = regexp1.ReplaceAllString(content, replaceStr1)
+	regexp2 := regexp.MustCompile(`if\(f.cmd===re.MAIN_THREAD_CMD.AUTO_CUT`)
+	replaceStr2 := `if(f.cmd==="CUT"){
+	if (window.__wx_channels_store__) {
+	// console.log("CUT", f, __wx_channels_store__.profile.key);
+	window.__wx_channels_store__.keys[__wx_channels_store__.profile.key]=f.decryptor_array;
+	}
+}
+if(f.cmd===re.MAIN_THREAD_CMD.AUTO_CUT`
+	return regexp2.ReplaceAllString(content, replaceStr2)
+}
+
+func legacyVirtualSvgIcons(content string) string {
+	replacements := []struct{ find, replace string }{
+		{`(?s)async\s+finderPcFlow\s*\(([^)]+)\)\s*\{(.*?)\}\s*async`, `async finderPcFlow($1){var result=await(async()=>{$2})();if(result&&result.data&&result.data.object){var feeds=result.data.object;console.log("[API拦截] finderPcFlow 触发 PCFlowLoaded",feeds.length);WXU.emit(WXU.Events.PCFlowLoaded,{feeds:feeds,params:$1});}return result;}async`},
+		{`(?s)async\s+finderStream\s*\(([^)]+)\)\s*\{(.*?)\}\s*async`, `async finderStream($1){var result=await(async()=>{$2})();if(result&&result.data&&result.data.object){var feeds=result.data.object;console.log("[API拦截] finderStream 触发 PCFlowLoaded",feeds.length);WXU.emit(WXU.Events.PCFlowLoaded,{feeds:feeds,params:$1});}return result;}async`},
+		{`(?s)async\s+finderGetCommentDetail\s*\(([^)]+)\)\s*\{(.*?)\}\s*async`, `async finderGetCommentDetail($1){var result=await(async()=>{$2})();var feed=result.data.object;console.log("[API拦截] finderGetCommentDetail 触发 FeedProfileLoaded");WXU.emit(WXU.Events.FeedProfileLoaded,feed);return result;}async`},
+		{`(?s)async\s+finderUserPage\s*\(([^)]+)\)\s*\{return(.*?)\}\s*async`, `async finderUserPage($1){console.log("[Profile API] finderUserPage 调用参数:",$1);var result=await(async()=>{return$2})();console.log("[Profile API] finderUserPage 原始结果:",result);if(result&&result.data&&result.data.object){var feeds=result.data.object;console.log("[Profile API] 提取到",feeds.length,"个视频");WXU.emit(WXU.Events.UserFeedsLoaded,feeds);}else{console.warn("[Profile API] result.data.object 为空",result);}return result;}async`},
+		{`(?s)async\s+finderLiveUserPage\s*\(([^)]+)\)\s*\{return(.*?)\}\s*async`, `async finderLiveUserPage($1){console.log("[Profile API] finderLiveUserPage 调用参数:",$1);var result=await(async()=>{return$2})();console.log("[Profile API] finderLiveUserPage 原始结果:",result);if(result&&result.data&&result.data.object){var feeds=result.data.object;console.log("[Profile API] 提取到",feeds.length,"个直播回放");WXU.emit(WXU.Events.UserLiveReplayLoaded,feeds);}else{console.warn("[Profile API] result.data.object 为空",result);}return result;}async`},
+		{`(?s)async\s+finderGetRecommend\s*\(([^)]+)\)\s*\{(.*?)\}\s*async`, `async finderGetRecommend($1){var result=await(async()=>{$2})();if(result&&result.data&&result.data.object){var feeds=result.data.object;WXU.emit(WXU.Events.CategoryFeedsLoaded,{feeds:feeds,params:$1});}return result;}async`},
+		{`(async finderPCSearch\([^)]+\)\{.*?)(,t\}async)`, `$1,t&&t.data&&(function(){var lives=t.data.liveObjectList||[];var accounts=[];var liveCount=0;if(t.data.acctList){t.data.acctList.forEach(function(info){if(info.liveStatus===1){liveCount++;console.log("[搜索API] 发现直播账号:",info.contact?info.contact.nickname:"未知",info.liveStatus,info.liveInfo);}if(info.liveStatus===1&&info.liveInfo){lives.push({id:info.contact.username,objectId:info.contact.username,nickname:info.contact.nickname,username:info.contact.username,description:info.liveInfo.description||"",streamUrl:info.liveInfo.streamUrl,coverUrl:info.liveInfo.media&&info.liveInfo.media[0]?info.liveInfo.media[0].thumbUrl:"",thumbUrl:info.liveInfo.media&&info.liveInfo.media[0]?info.liveInfo.media[0].thumbUrl:"",liveInfo:info.liveInfo,type:"live"});}accounts.push(info);});}if(liveCount>0){console.log("[搜索API] 共发现",liveCount,"个直播账号，成功提取",lives.length,"个");}var searchData={feeds:t.data.objectList||[],accounts:accounts,lives:lives};WXU.emit("SearchResultLoaded",searchData);})()$2`},
+		{`(async finderSearch\([^)]+\)\{.*?)(,t\}async)`, `$1,t&&t.data&&(function(){var lives=[];var accounts=[];var liveCount=0;if(t.data.infoList){t.data.infoList.forEach(function(info){if(info.liveStatus===1){liveCount++;console.log("[搜索API] 发现直播账号:",info.contact?info.contact.nickname:"未知",info.liveStatus,info.liveInfo);}if(info.liveStatus===1&&info.liveInfo){lives.push({id:info.contact.username,objectId:info.contact.username,nickname:info.contact.nickname,username:info.contact.username,description:info.liveInfo.description||"",streamUrl:info.liveInfo.streamUrl,coverUrl:info.liveInfo.media&&info.liveInfo.media[0]?info.liveInfo.media[0].thumbUrl:"",thumbUrl:info.liveInfo.media&&info.liveInfo.media[0]?info.liveInfo.media[0].thumbUrl:"",liveInfo:info.liveInfo,type:"live"});}accounts.push(info);});}if(liveCount>0){console.log("[搜索API] 共发现",liveCount,"个直播账号，成功提取",lives.length,"个");}var searchData={feeds:t.data.objectList||[],accounts:accounts,lives:lives};WXU.emit("SearchResultLoaded",searchData);})()$2`},
+	}
+	for _, r := range replacements {
+		content = regexp.MustCompile(r.find).ReplaceAllString(content, r.replace)
+	}
+
+	exportBlockRegex := regexp.MustCompile(`export\s*\{([^}]+)\}`)
+	exportRegex := regexp.MustCompile(`export\s*\{`)
+	matches := exportBlockRegex.FindStringSubmatch(content)
+	if len(matches) < 2 {
+		return content
+	}
+	var locals []string
+	for _, item := range strings.Split(matches[1], ",") {
+		p := strings.TrimSpace(item)
+		if p == "" {
+			continue
+		}
+		local := p
+		if idx := strings.Index(p, " as "); idx != -1 {
+			local = strings.TrimSpace(p[:idx])
+		}
+		if local != "" && local != " " {
+			locals = append(locals, local)
+		}
+	}
+	if len(locals) == 0 {
+		return content
+	}
+	apiMethods := strings.ReplaceAll("{"+strings.Join(locals, ",")+"}", "$", "$$")
+	return exportRegex.ReplaceAllString(content, ";WXU.emit(WXU.Events.APILoaded,"+apiMethods+");export{")
+}
+
+func legacyWorkerRelease(content string) string {
+	return regexp.MustCompile(`fmp4Index:p.fmp4Index`).ReplaceAllString(content, `decryptor_array:p.decryptor_array,fmp4Index:p.fmp4Index`)
+}
+
+func legacyConnectPublish(content string) string {
+	flowTabVar := "yt"
+	if matches := regexp.MustCompile(`flowTab:([a-zA-Z]{1,}),flowTabId:`).FindStringSubmatch(content); len(matches) > 1 {
+		flowTabVar = matches[1]
+	}
+	v := flowTabVar
+	next := fmt.Sprintf("goToNextFlowFeed:async function(v){await $1(v);console.log('goToNextFlowFeed',%s);if(!%s||!%s.value.feeds){return;}var feed=%s.value.feeds[%s.value.currentFeedIndex];console.log('before GotoNextFeed',%s,feed);WXU.emit(WXU.Events.GotoNextFeed,feed);}", v, v, v, v, v, v)
+	content = regexp.MustCompile(`goToNextFlowFeed:([a-zA-Z]{1,})`).ReplaceAllString(content, next)
+	prev := fmt.Sprintf("goToPrevFlowFeed:async function(v){await $1(v);console.log('goToPrevFlowFeed',%s);if(!%s||!%s.value.feeds){return;}var feed=%s.value.feeds[%s.value.currentFeedIndex];console.log('before GotoPrevFeed',%s,feed);WXU.emit(WXU.Events.GotoPrevFeed,feed);}", v, v, v, v, v, v)
+	return regexp.MustCompile(`goToPrevFlowFeed:([a-zA-Z]{1,})`).ReplaceAllString(content, prev)
+}
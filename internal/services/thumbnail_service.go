@@ -0,0 +1,325 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"wx_channel/internal/config"
+	"wx_channel/internal/database"
+	"wx_channel/internal/utils"
+)
+
+// 缩略图目录与文件名
+const (
+	thumbnailDirName      = ".thumbnails"
+	ThumbnailCoverFile    = "cover.jpg"
+	ThumbnailSheetFile    = "sheet.jpg"
+	thumbnailKeyframeGlob = "keyframe_*.jpg"
+	thumbnailWidth        = 320
+	thumbnailSheetWidth   = 240
+	thumbnailSceneScore   = 0.3
+)
+
+// ThumbnailSet 表示一条下载记录的本地缩略图集合
+type ThumbnailSet struct {
+	ID        string   `json:"id"`
+	Cover     string   `json:"cover,omitempty"`
+	Keyframes []string `json:"keyframes"`
+	Sheet     string   `json:"sheet,omitempty"`
+}
+
+// ThumbnailService 使用 FFmpeg 为已完成的下载生成并缓存本地缩略图
+type ThumbnailService struct {
+	settingsRepo *database.SettingsRepository
+	downloadRepo *database.DownloadRecordRepository
+	mu           sync.Mutex
+	activeJobs   map[string]chan struct{}
+}
+
+// NewThumbnailService 创建一个新的 ThumbnailService
+func NewThumbnailService() *ThumbnailService {
+	return &ThumbnailService{
+		settingsRepo: database.NewSettingsRepository(),
+		downloadRepo: database.NewDownloadRecordRepository(),
+		activeJobs:   make(map[string]chan struct{}),
+	}
+}
+
+// IsEnabled 检查下载完成后是否自动生成缩略图
+func (s *ThumbnailService) IsEnabled() bool {
+	enabled, _ := s.settingsRepo.GetBool(database.SettingKeyThumbnailEnabled, true)
+	return enabled
+}
+
+// GenerateAsync 异步生成缩略图（未启用或 FFmpeg 不可用时静默跳过）
+func (s *ThumbnailService) GenerateAsync(recordID string) {
+	if !s.IsEnabled() || findFFmpegPath(s.settingsRepo) == "" {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+
+		if _, err := s.Generate(ctx, recordID); err != nil {
+			utils.Warn("生成缩略图失败 [%s]: %v", recordID, err)
+		}
+	}()
+}
+
+// Generate 为下载记录生成封面、关键帧和场景切换拼图，并返回生成结果。
+// 同一记录的并发调用会等待正在进行的任务完成。
+func (s *ThumbnailService) Generate(ctx context.Context, recordID string) (*ThumbnailSet, error) {
+	s.mu.Lock()
+	if done, exists := s.activeJobs[recordID]; exists {
+		s.mu.Unlock()
+		select {
+		case <-done:
+			return s.List(recordID)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	done := make(chan struct{})
+	s.activeJobs[recordID] = done
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.activeJobs, recordID)
+		s.mu.Unlock()
+		close(done)
+	}()
+
+	record, err := s.downloadRepo.GetByID(recordID)
+	if err != nil {
+		return nil, fmt.Errorf("获取下载记录失败: %w", err)
+	}
+	if record == nil {
+		return nil, fmt.Errorf("下载记录不存在: %s", recordID)
+	}
+	if record.FilePath == "" {
+		return nil, fmt.Errorf("下载记录没有文件路径: %s", recordID)
+	}
+	if _, err := os.Stat(record.FilePath); err != nil {
+		return nil, fmt.Errorf("视频文件不存在: %s", record.FilePath)
+	}
+
+	ffmpegPath := findFFmpegPath(s.settingsRepo)
+	if ffmpegPath == "" {
+		return nil, fmt.Errorf("未找到 FFmpeg，请在设置中配置 FFmpeg 路径或将其添加到系统 PATH")
+	}
+
+	dir, err := s.thumbnailDir(recordID)
+	if err != nil {
+		return nil, err
+	}
+	// 重新生成时清理旧文件，避免关键帧数量变化后残留
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, fmt.Errorf("清理缩略图目录失败: %w", err)
+	}
+	if err := utils.EnsureDir(dir); err != nil {
+		return nil, fmt.Errorf("创建缩略图目录失败: %w", err)
+	}
+
+	duration := s.probeDuration(ctx, ffmpegPath, record.FilePath)
+
+	if err := s.extractCover(ctx, ffmpegPath, record.FilePath, dir, duration); err != nil {
+		return nil, err
+	}
+
+	keyframes, _ := s.settingsRepo.GetInt(database.SettingKeyThumbnailKeyframes, 6)
+	if keyframes > 0 {
+		if err := s.extractKeyframes(ctx, ffmpegPath, record.FilePath, dir, duration, keyframes); err != nil {
+			utils.Warn("提取关键帧失败 [%s]: %v", recordID, err)
+		}
+		if err := s.extractContactSheet(ctx, ffmpegPath, record.FilePath, dir); err != nil {
+			utils.Warn("生成场景拼图失败 [%s]: %v", recordID, err)
+		}
+	}
+
+	utils.Info("🖼️ 缩略图已生成: %s", record.Title)
+	return s.List(recordID)
+}
+
+// List 返回已缓存的缩略图文件名（不触发生成）
+func (s *ThumbnailService) List(recordID string) (*ThumbnailSet, error) {
+	dir, err := s.thumbnailDir(recordID)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &ThumbnailSet{ID: recordID, Keyframes: []string{}}
+	if _, err := os.Stat(filepath.Join(dir, ThumbnailCoverFile)); err == nil {
+		set.Cover = ThumbnailCoverFile
+	}
+	if _, err := os.Stat(filepath.Join(dir, ThumbnailSheetFile)); err == nil {
+		set.Sheet = ThumbnailSheetFile
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, thumbnailKeyframeGlob))
+	sort.Strings(matches)
+	for _, m := range matches {
+		set.Keyframes = append(set.Keyframes, filepath.Base(m))
+	}
+	return set, nil
+}
+
+// GetPath 返回指定缩略图文件的绝对路径，文件不存在时返回 os.ErrNotExist
+func (s *ThumbnailService) GetPath(recordID, name string) (string, error) {
+	if !isValidThumbnailName(name) {
+		return "", fmt.Errorf("invalid thumbnail name: %s", name)
+	}
+	dir, err := s.thumbnailDir(recordID)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, name)
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Delete 删除记录对应的缩略图缓存
+func (s *ThumbnailService) Delete(recordID string) error {
+	dir, err := s.thumbnailDir(recordID)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// thumbnailDir 返回记录的缩略图缓存目录: {downloadsDir}/.thumbnails/{id}
+func (s *ThumbnailService) thumbnailDir(recordID string) (string, error) {
+	safeID := sanitizeThumbnailID(recordID)
+	if safeID == "" {
+		return "", fmt.Errorf("invalid record ID: %q", recordID)
+	}
+
+	var downloadsDir string
+	if cfg := config.Get(); cfg != nil {
+		downloadsDir, _ = cfg.GetResolvedDownloadsDir()
+	}
+	if downloadsDir == "" {
+		baseDir, err := utils.GetBaseDir()
+		if err != nil {
+			baseDir = "."
+		}
+		downloadsDir = filepath.Join(baseDir, "downloads")
+	}
+
+	return filepath.Join(downloadsDir, thumbnailDirName, safeID), nil
+}
+
+// probeDuration 通过 FFmpeg 的输入信息获取视频时长（秒），失败时返回 0
+func (s *ThumbnailService) probeDuration(ctx context.Context, ffmpegPath, videoPath string) float64 {
+	// 未指定输出时 FFmpeg 会以非零状态退出，但仍会打印输入信息
+	output, _ := exec.CommandContext(ctx, ffmpegPath, "-hide_banner", "-i", videoPath).CombinedOutput()
+	return parseFFmpegDuration(string(output))
+}
+
+// extractCover 截取封面：默认取 10% 处（最多第 3 秒），过短的视频回退到第一帧
+func (s *ThumbnailService) extractCover(ctx context.Context, ffmpegPath, videoPath, dir string, duration float64) error {
+	coverPath := filepath.Join(dir, ThumbnailCoverFile)
+	offset := math.Min(duration*0.1, 3)
+
+	args := []string{"-hide_banner", "-loglevel", "error"}
+	if offset > 0 {
+		args = append(args, "-ss", fmt.Sprintf("%.2f", offset))
+	}
+	args = append(args,
+		"-i", videoPath,
+		"-frames:v", "1",
+		"-vf", fmt.Sprintf("scale=%d:-2", thumbnailWidth),
+		"-q:v", "3",
+		"-y", coverPath,
+	)
+
+	output, err := exec.CommandContext(ctx, ffmpegPath, args...).CombinedOutput()
+	if err == nil {
+		if _, statErr := os.Stat(coverPath); statErr == nil {
+			return nil
+		}
+	}
+	if offset > 0 {
+		return s.extractCover(ctx, ffmpegPath, videoPath, dir, 0)
+	}
+	return fmt.Errorf("FFmpeg 截取封面失败: %v, 输出: %s", err, string(output))
+}
+
+// extractKeyframes 仅解码关键帧，并按视频时长均匀挑选 count 张
+func (s *ThumbnailService) extractKeyframes(ctx context.Context, ffmpegPath, videoPath, dir string, duration float64, count int) error {
+	interval := 2.0
+	if duration > 0 {
+		interval = math.Max(duration/float64(count), 0.5)
+	}
+
+	filter := fmt.Sprintf(
+		"select='isnan(prev_selected_t)+gte(t-prev_selected_t\\,%.2f)',scale=%d:-2",
+		interval, thumbnailWidth,
+	)
+	args := []string{
+		"-hide_banner", "-loglevel", "error",
+		"-skip_frame", "nokey",
+		"-i", videoPath,
+		"-vf", filter,
+		"-vsync", "vfr",
+		"-frames:v", fmt.Sprintf("%d", count),
+		"-q:v", "4",
+		"-y", filepath.Join(dir, "keyframe_%02d.jpg"),
+	}
+
+	if output, err := exec.CommandContext(ctx, ffmpegPath, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%v, 输出: %s", err, string(output))
+	}
+	return nil
+}
+
+// extractContactSheet 将场景切换帧拼接为一张 4x3 的预览图
+func (s *ThumbnailService) extractContactSheet(ctx context.Context, ffmpegPath, videoPath, dir string) error {
+	filter := fmt.Sprintf(
+		"select='eq(n\\,0)+gt(scene\\,%.2f)',scale=%d:-2,tile=4x3:padding=4:margin=4",
+		thumbnailSceneScore, thumbnailSheetWidth,
+	)
+	args := []string{
+		"-hide_banner", "-loglevel", "error",
+		"-i", videoPath,
+		"-vf", filter,
+		"-vsync", "vfr",
+		"-frames:v", "1",
+		"-q:v", "4",
+		"-y", filepath.Join(dir, ThumbnailSheetFile),
+	}
+
+	if output, err := exec.CommandContext(ctx, ffmpegPath, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%v, 输出: %s", err, string(output))
+	}
+	return nil
+}
+
+// sanitizeThumbnailID 只保留可安全用作目录名的字符
+func sanitizeThumbnailID(id string) string {
+	var b strings.Builder
+	for _, r := range id {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// isValidThumbnailName 检查文件名是否为本服务生成的缩略图
+func isValidThumbnailName(name string) bool {
+	if name == ThumbnailCoverFile || name == ThumbnailSheetFile {
+		return true
+	}
+	matched, _ := filepath.Match(thumbnailKeyframeGlob, name)
+	return matched && !strings.ContainsAny(name, `/\`)
+}
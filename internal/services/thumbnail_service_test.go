@@ -0,0 +1,50 @@
+package services
+
+import "testing"
+
+func TestIsValidThumbnailName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{ThumbnailCoverFile, true},
+		{ThumbnailSheetFile, true},
+		{"keyframe_01.jpg", true},
+		{"keyframe_.jpg", true},
+		{"", false},
+		{"cover.png", false},
+		{"other.jpg", false},
+		{"../cover.jpg", false},
+		{"../../config.yaml", false},
+		{"keyframe_../../x.jpg", false},
+		{`keyframe_..\..\x.jpg`, false},
+		{"keyframe_01.jpg/../../x", false},
+		{"/etc/passwd", false},
+	}
+	for _, tt := range tests {
+		if got := isValidThumbnailName(tt.name); got != tt.want {
+			t.Errorf("isValidThumbnailName(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeThumbnailID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want string
+	}{
+		{"abc-123_XYZ", "abc-123_XYZ"},
+		{"../../etc", "etc"},
+		{`..\..\windows`, "windows"},
+		{"a/b", "ab"},
+		{"..", ""},
+		{"id with spaces", "idwithspaces"},
+		{"视频1", "1"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := sanitizeThumbnailID(tt.id); got != tt.want {
+			t.Errorf("sanitizeThumbnailID(%q) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
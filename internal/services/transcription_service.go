@@ -30,6 +30,14 @@ type TranscriptionService struct {
 	serverRunning bool
 }
 
+// TranscriptionEvent 转写状态变化事件
+type TranscriptionEvent struct {
+	Type           string `json:"type"`
+	RecordID       string `json:"recordId"`
+	Status         string `json:"status"`
+	TranscriptPath string `json:"transcriptPath,omitempty"`
+}
+
 // NewTranscriptionService 创建一个新的 TranscriptionService
 func NewTranscriptionService() *TranscriptionService {
 	return &TranscriptionService{
@@ -130,24 +138,24 @@ func (s *TranscriptionService) TranscribeVideo(ctx context.Context, recordID str
 	txtPath := strings.TrimSuffix(record.FilePath, ext) + ".txt"
 
 	// 标记状态为转写中
-	if err := s.downloadRepo.UpdateTranscriptStatus(recordID, database.TranscriptStatusInProgress, ""); err != nil {
+	if err := s.updateStatus(recordID, database.TranscriptStatusInProgress, ""); err != nil {
 		utils.Error("更新转写状态失败: %v", err)
 	}
 
 	// 执行转写
 	if err := s.doTranscribe(ctx, record.FilePath, txtPath); err != nil {
-		_ = s.downloadRepo.UpdateTranscriptStatus(recordID, database.TranscriptStatusFailed, "")
+		_ = s.updateStatus(recordID, database.TranscriptStatusFailed, "")
 		return fmt.Errorf("转写失败: %w", err)
 	}
 
 	// 验证输出文件
 	if _, err := os.Stat(txtPath); os.IsNotExist(err) {
-		_ = s.downloadRepo.UpdateTranscriptStatus(recordID, database.TranscriptStatusFailed, "")
+		_ = s.updateStatus(recordID, database.TranscriptStatusFailed, "")
 		return fmt.Errorf("转写完成但输出文件不存在: %s", txtPath)
 	}
 
 	// 标记完成
-	if err := s.downloadRepo.UpdateTranscriptStatus(recordID, database.TranscriptStatusCompleted, txtPath); err != nil {
+	if err := s.updateStatus(recordID, database.TranscriptStatusCompleted, txtPath); err != nil {
 		return fmt.Errorf("更新转写状态失败: %w", err)
 	}
 
@@ -188,10 +196,41 @@ func (s *TranscriptionService) CancelTranscription(recordID string) error {
 	}
 
 	cancel()
+	// 转写任务退出时会再次标记失败并发布事件
 	_ = s.downloadRepo.UpdateTranscriptStatus(recordID, database.TranscriptStatusFailed, "")
 	return nil
 }
 
+// updateStatus 更新转写状态并发布 transcription_progress 事件，完成或失败时推送 Webhook
+func (s *TranscriptionService) updateStatus(recordID, status, transcriptPath string) error {
+	if err := s.downloadRepo.UpdateTranscriptStatus(recordID, status, transcriptPath); err != nil {
+		return err
+	}
+	GetEventBus().Publish(EventTranscriptionProgress, TranscriptionEvent{
+		Type:           EventTranscriptionProgress,
+		RecordID:       recordID,
+		Status:         status,
+		TranscriptPath: transcriptPath,
+	})
+
+	event := database.WebhookEventTranscriptReady
+	switch status {
+	case database.TranscriptStatusCompleted:
+	case database.TranscriptStatusFailed:
+		event = database.WebhookEventTranscriptFailed
+	default:
+		return nil
+	}
+	data := WebhookTranscriptData{RecordID: recordID, TranscriptPath: transcriptPath}
+	if record, err := s.downloadRepo.GetByID(recordID); err == nil && record != nil {
+		data.VideoID = record.VideoID
+		data.Title = record.Title
+		data.Author = record.Author
+	}
+	GetWebhookService().Dispatch(event, data)
+	return nil
+}
+
 // GetTranscript 获取转写文本内容
 func (s *TranscriptionService) GetTranscript(recordID string) (string, error) {
 	record, err := s.downloadRepo.GetByID(recordID)
@@ -462,14 +501,7 @@ func (s *TranscriptionService) postInference(ctx context.Context, wavPath string
 
 // getFFmpegPath 获取 FFmpeg 路径
 func (s *TranscriptionService) getFFmpegPath() string {
-	path, _ := s.settingsRepo.Get(database.SettingKeyFFmpegPath)
-	if path != "" {
-		return path
-	}
-	if p, err := exec.LookPath("ffmpeg"); err == nil {
-		return p
-	}
-	return ""
+	return findFFmpegPath(s.settingsRepo)
 }
 
 // getWhisperServerPath 获取 whisper-server 路径
@@ -0,0 +1,346 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"wx_channel/internal/config"
+	"wx_channel/internal/database"
+	"wx_channel/internal/utils"
+)
+
+const (
+	trashDirName       = ".trash"  // 下载目录下的回收站目录
+	trashPurgeInterval = time.Hour // 自动清空过期记录的检查间隔
+)
+
+// 回收站中的记录类型
+const (
+	TrashTypeDownloads = "downloads"
+	TrashTypeBrowse    = "browse"
+)
+
+var (
+	// ErrTrashType 不支持的回收站记录类型
+	ErrTrashType = errors.New("trash type must be downloads or browse")
+)
+
+// TrashResult 恢复或永久删除的结果
+type TrashResult struct {
+	Records int64    `json:"records"`
+	Files   int64    `json:"files"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// TrashService 管理回收站：删除的下载记录和浏览记录先标记 deleted_at，
+// 删除文件时文件移动到 download_dir/.trash，恢复时移回原路径
+type TrashService struct {
+	browseRepo   *database.BrowseHistoryRepository
+	downloadRepo *database.DownloadRecordRepository
+	thumbnails   *ThumbnailService
+	audit        *AuditService
+
+	mu      sync.Mutex
+	started bool
+}
+
+var (
+	trashService     *TrashService
+	trashServiceOnce sync.Once
+)
+
+// GetTrashService 获取全局回收站服务
+func GetTrashService() *TrashService {
+	trashServiceOnce.Do(func() {
+		trashService = NewTrashService()
+	})
+	return trashService
+}
+
+// NewTrashService 创建一个新的 TrashService
+func NewTrashService() *TrashService {
+	return &TrashService{
+		browseRepo:   database.NewBrowseHistoryRepository(),
+		downloadRepo: database.NewDownloadRecordRepository(),
+		thumbnails:   NewThumbnailService(),
+		audit:        NewAuditService(),
+	}
+}
+
+// List 分页列出回收站中的记录
+func (s *TrashService) List(trashType string, params *database.PaginationParams) (interface{}, error) {
+	switch trashType {
+	case TrashTypeDownloads:
+		filter := &database.FilterParams{PaginationParams: *params, Trashed: true}
+		filter.SortBy = "deleted_at"
+		filter.SortDesc = true
+		return s.downloadRepo.List(filter)
+	case TrashTypeBrowse:
+		return s.browseRepo.ListTrashed(params)
+	default:
+		return nil, ErrTrashType
+	}
+}
+
+// Restore 恢复回收站中的记录，移入回收站的文件移回原路径
+func (s *TrashService) Restore(trashType string, ids []string) (*TrashResult, error) {
+	result := &TrashResult{}
+	switch trashType {
+	case TrashTypeBrowse:
+		n, err := s.browseRepo.Restore(ids)
+		if err != nil {
+			return nil, err
+		}
+		result.Records = n
+		return result, nil
+	case TrashTypeDownloads:
+	default:
+		return nil, ErrTrashType
+	}
+
+	records, err := s.downloadRepo.GetTrashed(ids, nil)
+	if err != nil {
+		return nil, err
+	}
+	restored := make([]string, 0, len(records))
+	for _, record := range records {
+		if record.TrashPath != "" {
+			if _, err := os.Stat(record.FilePath); err == nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: file already exists at %s", record.ID, record.FilePath))
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(record.FilePath), 0755); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", record.ID, err))
+				continue
+			}
+			if err := moveFile(record.TrashPath, record.FilePath); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", record.ID, err))
+				continue
+			}
+			os.Remove(filepath.Dir(record.TrashPath))
+			result.Files++
+		}
+		restored = append(restored, record.ID)
+	}
+
+	n, err := s.downloadRepo.Restore(restored)
+	if err != nil {
+		return nil, err
+	}
+	result.Records = n
+	return result, nil
+}
+
+// Purge 永久删除回收站中的记录和移入回收站的文件；ids 为空时清空该类型的回收站
+func (s *TrashService) Purge(trashType string, ids []string) (*TrashResult, error) {
+	switch trashType {
+	case TrashTypeDownloads:
+		records, err := s.downloadRepo.GetTrashed(ids, nil)
+		if err != nil {
+			return nil, err
+		}
+		return s.purgeDownloads(records)
+	case TrashTypeBrowse:
+		var n int64
+		var err error
+		if len(ids) == 0 {
+			n, err = s.browseRepo.PurgeBefore(time.Now().Add(time.Second))
+		} else {
+			n, err = s.browseRepo.Purge(ids)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &TrashResult{Records: n}, nil
+	default:
+		return nil, ErrTrashType
+	}
+}
+
+// PurgeExpired 永久删除移入回收站超过 days 天的记录
+func (s *TrashService) PurgeExpired(days int) (*TrashResult, error) {
+	if days <= 0 {
+		return &TrashResult{}, nil
+	}
+	cutoff := time.Now().AddDate(0, 0, -days)
+
+	records, err := s.downloadRepo.GetTrashed(nil, &cutoff)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.purgeDownloads(records)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) > 0 {
+		s.recordPurge(TrashTypeDownloads, result, days)
+	}
+
+	n, err := s.browseRepo.PurgeBefore(cutoff)
+	if err != nil {
+		return nil, err
+	}
+	if n > 0 {
+		s.recordPurge(TrashTypeBrowse, &TrashResult{Records: n}, days)
+	}
+	result.Records += n
+	return result, nil
+}
+
+// Start 按 trash_retention_days 定期清空过期的回收站记录，重复调用无效
+func (s *TrashService) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+
+	go func() {
+		ticker := time.NewTicker(trashPurgeInterval)
+		defer ticker.Stop()
+		for {
+			s.purgeDue()
+			<-ticker.C
+		}
+	}()
+}
+
+// purgeDue 按当前配置清空过期的回收站记录
+func (s *TrashService) purgeDue() {
+	defer func() {
+		if r := recover(); r != nil {
+			utils.LogError("[回收站] 自动清空 panic: %v", r)
+		}
+	}()
+
+	cfg := config.Get()
+	if cfg == nil || cfg.TrashRetentionDays <= 0 {
+		return
+	}
+	result, err := s.PurgeExpired(cfg.TrashRetentionDays)
+	if err != nil {
+		utils.Warn("[回收站] 自动清空失败: %v", err)
+		return
+	}
+	if result.Records > 0 {
+		utils.Info("🗑️ [回收站] 已永久删除 %d 条过期记录（%d 个文件）", result.Records, result.Files)
+	}
+}
+
+// purgeDownloads 删除回收站中的文件和缩略图缓存后永久删除下载记录
+func (s *TrashService) purgeDownloads(records []database.DownloadRecord) (*TrashResult, error) {
+	result := &TrashResult{}
+	ids := make([]string, 0, len(records))
+	for _, record := range records {
+		if record.TrashPath != "" {
+			if err := os.Remove(record.TrashPath); err != nil && !os.IsNotExist(err) {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", record.ID, err))
+				continue
+			}
+			os.Remove(filepath.Dir(record.TrashPath))
+			result.Files++
+		}
+		if err := s.thumbnails.Delete(record.ID); err != nil {
+			utils.Warn("[回收站] 删除缩略图失败 %s: %v", record.ID, err)
+		}
+		ids = append(ids, record.ID)
+	}
+
+	n, err := s.downloadRepo.Purge(ids)
+	if err != nil {
+		return nil, err
+	}
+	result.Records = n
+	return result, nil
+}
+
+// recordPurge 以 system 身份记录自动清空
+func (s *TrashService) recordPurge(trashType string, result *TrashResult, days int) {
+	s.audit.Record(&database.AuditEntry{
+		Actor:      database.AuditActorSystem,
+		ActorType:  database.AuditActorSystem,
+		Action:     "trash.purge",
+		TargetType: trashType,
+		Detail:     fmt.Sprintf("expired=%dd records=%d files=%d", days, result.Records, result.Files),
+	})
+}
+
+// trashDir 返回下载目录下的回收站目录
+func trashDir() string {
+	var downloadsDir string
+	if cfg := config.Get(); cfg != nil {
+		downloadsDir, _ = cfg.GetResolvedDownloadsDir()
+	}
+	if downloadsDir == "" {
+		baseDir, err := utils.GetBaseDir()
+		if err != nil {
+			baseDir = "."
+		}
+		downloadsDir = filepath.Join(baseDir, "downloads")
+	}
+	return filepath.Join(downloadsDir, trashDirName)
+}
+
+// moveDownloadToTrash 将下载记录的文件移动到回收站并记录位置，文件不存在时忽略
+func moveDownloadToTrash(repo *database.DownloadRecordRepository, record *database.DownloadRecord) (int64, error) {
+	if record.FilePath == "" {
+		return 0, nil
+	}
+	info, err := os.Stat(record.FilePath)
+	if err != nil {
+		return 0, nil
+	}
+
+	// 重新下载后再次删除的同一视频不能覆盖回收站中旧记录的文件
+	dir := filepath.Join(trashDir(), sanitizeThumbnailID(record.ID))
+	target := filepath.Join(dir, filepath.Base(record.FilePath))
+	if _, err := os.Stat(target); err == nil {
+		dir = fmt.Sprintf("%s-%d", dir, time.Now().UnixNano())
+		target = filepath.Join(dir, filepath.Base(record.FilePath))
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create trash dir: %w", err)
+	}
+	if err := moveFile(record.FilePath, target); err != nil {
+		return 0, err
+	}
+	if err := repo.SetTrashPath(record.ID, target); err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// moveFile 移动文件，跨分区时复制后删除原文件
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dst, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	in.Close()
+	return os.Remove(src)
+}
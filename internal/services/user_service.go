@@ -0,0 +1,504 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"wx_channel/internal/database"
+	"wx_channel/internal/utils"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// SessionCookieName 控制台登录会话的 Cookie 名称
+	SessionCookieName = "wx_console_session"
+	// SessionTTL 会话的有效期，每次使用后顺延
+	SessionTTL = 7 * 24 * time.Hour
+
+	sessionTokenPrefix   = "wxs_"          // 会话令牌明文的前缀
+	sessionTouchInterval = time.Minute     // 会话最近使用时间的最小写入间隔
+	loginMaxFailures     = 5               // 连续失败多少次后锁定
+	loginLockDuration    = 5 * time.Minute // 锁定时长
+	minPasswordLength    = 6               // 密码最短长度
+	maxPasswordLength    = 72              // bcrypt 最多处理 72 字节
+)
+
+var (
+	// ErrUserNotFound 用户不存在
+	ErrUserNotFound = errors.New("user not found")
+	// ErrUserExists 用户名已被使用
+	ErrUserExists = errors.New("username already exists")
+	// ErrInvalidCredentials 用户名或密码错误
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	// ErrLoginLocked 连续登录失败次数过多，暂时锁定
+	ErrLoginLocked = errors.New("too many failed login attempts, try again later")
+	// ErrSessionInvalid 会话不存在或已过期
+	ErrSessionInvalid = errors.New("invalid or expired session")
+	// ErrLastAdmin 不能删除最后一个管理员或移除其 admin 权限
+	ErrLastAdmin = errors.New("cannot remove the last admin user")
+)
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_.\-]{1,32}$`)
+
+// UserRequest 创建用户的参数
+type UserRequest struct {
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	Scopes   []string `json:"scopes"`
+}
+
+// UserUpdateRequest 管理员修改用户的参数，字段为空时不修改
+type UserUpdateRequest struct {
+	Scopes   []string `json:"scopes"`
+	Password string   `json:"password"` // 重置密码，同时注销该用户的全部会话
+}
+
+// loginAttempt 记录某个用户名的连续登录失败
+type loginAttempt struct {
+	failures    int
+	lockedUntil time.Time
+}
+
+// UserService 管理控制台的本地用户和登录会话。密码以 bcrypt 哈希保存，
+// 会话令牌明文只保存在浏览器 Cookie 中，数据库中保存 SHA-256 哈希
+type UserService struct {
+	repo     *database.UserRepository
+	sessions *database.UserSessionRepository
+	exists   *existsCache
+
+	mu       sync.Mutex
+	touched  map[string]time.Time     // 会话 ID -> 最近一次写入的使用时间
+	attempts map[string]*loginAttempt // 小写用户名 -> 登录失败记录
+}
+
+// NewUserService 创建一个新的 UserService
+func NewUserService() *UserService {
+	repo := database.NewUserRepository()
+	return &UserService{
+		repo:     repo,
+		sessions: database.NewUserSessionRepository(),
+		exists:   &existsCache{check: repo.Exists},
+		touched:  make(map[string]time.Time),
+		attempts: make(map[string]*loginAttempt),
+	}
+}
+
+// Enabled 判断是否创建过用户；创建过用户后控制台必须登录（或携带令牌）访问。
+// 查询失败时按已启用处理，避免数据库异常时认证被绕过
+func (s *UserService) Enabled() bool {
+	exists, err := s.exists.get()
+	if err != nil {
+		utils.Warn("[用户] 检查用户失败，按已启用处理: %v", err)
+		return true
+	}
+	return exists
+}
+
+// Create 创建用户。第一个用户总是拥有 admin 权限，避免启用登录后无人能管理用户
+func (s *UserService) Create(req *UserRequest) (*database.User, error) {
+	username := strings.TrimSpace(req.Username)
+	if !usernamePattern.MatchString(username) {
+		return nil, fmt.Errorf("username must be 1-32 letters, digits, '_', '.' or '-'")
+	}
+	if err := validatePassword(req.Password); err != nil {
+		return nil, err
+	}
+	scopes, err := database.ParseScopes(strings.Join(req.Scopes, ","))
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.GetByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrUserExists
+	}
+	exists, err := s.repo.Exists()
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		scopes = []string{database.ScopeAdmin}
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+	user := &database.User{
+		ID:       uuid.New().String(),
+		Username: username,
+		Scopes:   scopes,
+	}
+	if err := s.repo.Create(user, string(hash)); err != nil {
+		return nil, err
+	}
+	s.exists.invalidate()
+	utils.Info("👤 [用户] 已创建用户: %s (%s)", user.Username, strings.Join(user.Scopes, ","))
+	return user, nil
+}
+
+// List 列出全部用户
+func (s *UserService) List() ([]database.User, error) {
+	return s.repo.List()
+}
+
+// Get 按 ID 或用户名查找用户
+func (s *UserService) Get(idOrName string) (*database.User, error) {
+	user, err := s.repo.GetByID(idOrName)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		if user, err = s.repo.GetByUsername(idOrName); err != nil {
+			return nil, err
+		}
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// Update 修改用户的权限范围或重置密码
+func (s *UserService) Update(idOrName string, req *UserUpdateRequest) (*database.User, error) {
+	user, err := s.Get(idOrName)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Scopes) == 0 && req.Password == "" {
+		return nil, fmt.Errorf("scopes or password is required")
+	}
+
+	var scopes []string
+	if len(req.Scopes) > 0 {
+		if scopes, err = database.ParseScopes(strings.Join(req.Scopes, ",")); err != nil {
+			return nil, err
+		}
+		if user.HasScope(database.ScopeAdmin) && !(&database.User{Scopes: scopes}).HasScope(database.ScopeAdmin) {
+			if err := s.ensureOtherAdmin(user.ID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	var hash []byte
+	if req.Password != "" {
+		if err := validatePassword(req.Password); err != nil {
+			return nil, err
+		}
+		if hash, err = bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost); err != nil {
+			return nil, fmt.Errorf("failed to hash password: %w", err)
+		}
+	}
+
+	if scopes != nil {
+		if err := s.repo.UpdateScopes(user.ID, scopes); err != nil {
+			return nil, err
+		}
+	}
+	if hash != nil {
+		if err := s.repo.UpdatePassword(user.ID, string(hash)); err != nil {
+			return nil, err
+		}
+		if _, err := s.sessions.DeleteByUser(user.ID, ""); err != nil {
+			return nil, err
+		}
+		utils.Info("👤 [用户] 已重置用户 %s 的密码并注销其会话", user.Username)
+	}
+	return s.repo.GetByID(user.ID)
+}
+
+// Delete 按 ID 或用户名删除用户及其会话
+func (s *UserService) Delete(idOrName string) (*database.User, error) {
+	user, err := s.Get(idOrName)
+	if err != nil {
+		return nil, err
+	}
+	if user.HasScope(database.ScopeAdmin) {
+		if err := s.ensureOtherAdmin(user.ID); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := s.repo.Delete(user.ID); err != nil {
+		return nil, err
+	}
+	s.exists.invalidate()
+	utils.Info("👤 [用户] 已删除用户: %s", user.Username)
+	return user, nil
+}
+
+// ensureOtherAdmin 确认除 userID 以外还有其他管理员
+func (s *UserService) ensureOtherAdmin(userID string) error {
+	users, err := s.repo.List()
+	if err != nil {
+		return err
+	}
+	for i := range users {
+		if users[i].ID != userID && users[i].HasScope(database.ScopeAdmin) {
+			return nil
+		}
+	}
+	return ErrLastAdmin
+}
+
+// UpdatePreferences 保存用户的控制台偏好
+func (s *UserService) UpdatePreferences(userID string, prefs *database.UserPreferences) (*database.User, error) {
+	if err := prefs.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdatePreferences(userID, prefs); err != nil {
+		return nil, err
+	}
+	user, err := s.repo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// ChangePassword 校验当前密码后修改密码，并注销除当前会话以外的全部会话
+func (s *UserService) ChangePassword(userID, sessionID, current, password string) error {
+	hash, err := s.repo.GetPasswordHash(userID)
+	if err != nil {
+		return err
+	}
+	if hash == "" {
+		return ErrUserNotFound
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(current)) != nil {
+		return ErrInvalidCredentials
+	}
+	if err := validatePassword(password); err != nil {
+		return err
+	}
+	newHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	if err := s.repo.UpdatePassword(userID, string(newHash)); err != nil {
+		return err
+	}
+	_, err = s.sessions.DeleteByUser(userID, sessionID)
+	return err
+}
+
+// Login 校验用户名和密码并创建会话，返回用户、会话和会话令牌明文
+func (s *UserService) Login(username, password, userAgent, sourceIP string) (*database.User, *database.UserSession, string, error) {
+	username = strings.TrimSpace(username)
+	key := strings.ToLower(username)
+	now := time.Now()
+	if s.locked(key, now) {
+		return nil, nil, "", ErrLoginLocked
+	}
+
+	user, err := s.repo.GetByUsername(username)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	hash := dummyPasswordHash()
+	if user != nil {
+		if hash, err = s.repo.GetPasswordHash(user.ID); err != nil {
+			return nil, nil, "", err
+		}
+	}
+	// 用户不存在时也比较一次哈希，避免通过响应时间判断用户名是否存在
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil || user == nil {
+		s.recordFailure(key, now)
+		utils.Warn("[用户] 登录失败: %s (%s)", username, sourceIP)
+		return nil, nil, "", ErrInvalidCredentials
+	}
+	s.clearFailures(key)
+
+	plain, err := generateSecret(sessionTokenPrefix)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	csrf, err := generateSecret("")
+	if err != nil {
+		return nil, nil, "", err
+	}
+	session := &database.UserSession{
+		ID:         HashAPIToken(plain),
+		UserID:     user.ID,
+		CSRFToken:  csrf,
+		UserAgent:  userAgent,
+		SourceIP:   sourceIP,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(SessionTTL),
+	}
+	if err := s.sessions.Create(session); err != nil {
+		return nil, nil, "", err
+	}
+	if err := s.repo.TouchLogin(user.ID, now); err != nil {
+		utils.Warn("[用户] %v", err)
+	}
+	user.LastLoginAt = &now
+	if _, err := s.sessions.DeleteExpired(now); err != nil {
+		utils.Warn("[用户] %v", err)
+	}
+	utils.Info("👤 [用户] %s 已登录 (%s)", user.Username, sourceIP)
+	return user, session, plain, nil
+}
+
+// Logout 删除会话令牌对应的会话
+func (s *UserService) Logout(plain string) error {
+	if plain == "" {
+		return nil
+	}
+	id := HashAPIToken(plain)
+	s.mu.Lock()
+	delete(s.touched, id)
+	s.mu.Unlock()
+	return s.sessions.Delete(id)
+}
+
+// Authenticate 校验会话令牌明文，返回对应的用户和会话，并顺延会话有效期
+func (s *UserService) Authenticate(plain string) (*database.User, *database.UserSession, error) {
+	if !strings.HasPrefix(plain, sessionTokenPrefix) {
+		return nil, nil, ErrSessionInvalid
+	}
+	session, err := s.sessions.Get(HashAPIToken(plain))
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	if session == nil || !now.Before(session.ExpiresAt) {
+		return nil, nil, ErrSessionInvalid
+	}
+	user, err := s.repo.GetByID(session.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user == nil {
+		return nil, nil, ErrSessionInvalid
+	}
+	s.touch(session, now)
+	return user, session, nil
+}
+
+// touch 顺延会话有效期，同一会话每分钟最多写一次数据库
+func (s *UserService) touch(session *database.UserSession, now time.Time) {
+	s.mu.Lock()
+	last, ok := s.touched[session.ID]
+	if ok && now.Sub(last) < sessionTouchInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.touched[session.ID] = now
+	s.mu.Unlock()
+
+	session.LastSeenAt = now
+	session.ExpiresAt = now.Add(SessionTTL)
+	if err := s.sessions.Touch(session.ID, session.LastSeenAt, session.ExpiresAt); err != nil {
+		utils.Warn("[用户] %v", err)
+	}
+}
+
+// locked 判断用户名是否处于锁定期
+func (s *UserService) locked(key string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	attempt, ok := s.attempts[key]
+	return ok && now.Before(attempt.lockedUntil)
+}
+
+// recordFailure 记录一次登录失败，连续失败达到上限后锁定
+func (s *UserService) recordFailure(key string, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	attempt, ok := s.attempts[key]
+	if !ok {
+		attempt = &loginAttempt{}
+		s.attempts[key] = attempt
+	}
+	attempt.failures++
+	if attempt.failures >= loginMaxFailures {
+		attempt.failures = 0
+		attempt.lockedUntil = now.Add(loginLockDuration)
+	}
+}
+
+// clearFailures 登录成功后清除失败记录
+func (s *UserService) clearFailures(key string) {
+	s.mu.Lock()
+	delete(s.attempts, key)
+	s.mu.Unlock()
+}
+
+// validatePassword 校验密码长度
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+	if len(password) > maxPasswordLength {
+		return fmt.Errorf("password must be at most %d bytes", maxPasswordLength)
+	}
+	return nil
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// dummyPasswordHash 返回用于用户不存在时比较的哈希
+func dummyPasswordHash() string {
+	dummyHashOnce.Do(func() {
+		hash, _ := bcrypt.GenerateFromPassword([]byte("wx_channel"), bcrypt.DefaultCost)
+		dummyHash = string(hash)
+	})
+	return dummyHash
+}
+
+// generateSecret 生成带前缀的 32 字节随机令牌
+func generateSecret(prefix string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return prefix + hex.EncodeToString(buf), nil
+}
+
+type userContextKey struct{}
+type userSessionContextKey struct{}
+
+// ContextWithUser 在请求上下文中记录通过会话登录的用户和会话
+func ContextWithUser(ctx context.Context, user *database.User, session *database.UserSession) context.Context {
+	ctx = context.WithValue(ctx, userContextKey{}, user)
+	return context.WithValue(ctx, userSessionContextKey{}, session)
+}
+
+// UserFromContext 返回请求上下文中登录的用户，未登录时返回 nil
+func UserFromContext(ctx context.Context) *database.User {
+	user, _ := ctx.Value(userContextKey{}).(*database.User)
+	return user
+}
+
+// UserSessionFromContext 返回请求上下文中的登录会话，未登录时返回 nil
+func UserSessionFromContext(ctx context.Context) *database.UserSession {
+	session, _ := ctx.Value(userSessionContextKey{}).(*database.UserSession)
+	return session
+}
+
+// InitiatedBy 返回请求对应的控制台用户名，用于记录下载的发起人；未登录时为空
+func InitiatedBy(ctx context.Context) string {
+	if user := UserFromContext(ctx); user != nil {
+		return user.Username
+	}
+	return ""
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"wx_channel/internal/config"
+	"wx_channel/internal/database"
+	"wx_channel/internal/utils"
+)
+
+// videoFormatParam 视频号链接中指定编码的参数
+const videoFormatParam = "X-snsvideoflag"
+
+var (
+	videoFormatParamPattern = regexp.MustCompile(`([?&])` + videoFormatParam + `=[^&]*&?`)
+	videoFormatValuePattern = regexp.MustCompile(`[?&]` + videoFormatParam + `=([^&]*)`)
+)
+
+// VideoFormatService 记录视频的可选编码，并按画质策略选择下载链接
+type VideoFormatService struct {
+	repo     *database.VideoFormatRepository
+	settings *database.SettingsRepository
+}
+
+// NewVideoFormatService 创建一个新的 VideoFormatService
+func NewVideoFormatService() *VideoFormatService {
+	return &VideoFormatService{
+		repo:     database.NewVideoFormatRepository(),
+		settings: database.NewSettingsRepository(),
+	}
+}
+
+// Save 保存视频的可选编码
+func (s *VideoFormatService) Save(videoID string, formats []database.VideoFormat) error {
+	if videoID == "" || len(formats) == 0 {
+		return nil
+	}
+	return s.repo.Save(videoID, formats)
+}
+
+// List 获取视频的可选编码
+func (s *VideoFormatService) List(videoID string) ([]database.VideoFormat, error) {
+	return s.repo.List(videoID)
+}
+
+// DefaultQuality 返回默认画质策略：配置文件或命令行中的 download_quality 优先，其次是控制台设置
+func (s *VideoFormatService) DefaultQuality() string {
+	if cfg := config.Get(); cfg != nil && cfg.DownloadQuality != "" {
+		return cfg.DownloadQuality
+	}
+	settings, err := s.settings.Load()
+	if err != nil {
+		return database.QualityOriginal
+	}
+	return settings.DownloadQuality
+}
+
+// Resolve 按画质策略为视频选择编码，返回下载链接和选中的编码。quality 为空时使用默认策略；
+// 策略为 original、没有记录可选编码或没有符合的编码时返回原链接和 nil
+func (s *VideoFormatService) Resolve(videoID, videoURL, quality string) (string, *database.VideoFormat, error) {
+	if quality == "" {
+		quality = s.DefaultQuality()
+	}
+	policy, err := database.ParseQualityPolicy(quality)
+	if err != nil {
+		return videoURL, nil, err
+	}
+	if policy.Mode == database.QualityOriginal || videoID == "" {
+		return videoURL, nil, nil
+	}
+
+	formats, err := s.repo.List(videoID)
+	if err != nil {
+		return videoURL, nil, err
+	}
+	selected := SelectVideoFormat(formats, policy)
+	if selected == nil {
+		if len(formats) > 0 {
+			utils.Warn("[画质] 视频 %s 没有符合 %s 的编码，使用原链接", videoID, quality)
+		}
+		return videoURL, nil, nil
+	}
+	return ApplyVideoFormat(videoURL, selected.FileFormat), selected, nil
+}
+
+// SelectVideoFormat 按策略从可选编码中选择一个，original 或没有符合的编码时返回 nil。
+// 不超过指定分辨率的编码都没有时选择最小的
+func SelectVideoFormat(formats []database.VideoFormat, policy database.QualityPolicy) *database.VideoFormat {
+	var selected *database.VideoFormat
+	for i := range formats {
+		f := &formats[i]
+		switch policy.Mode {
+		case database.QualityBest:
+			if selected == nil || betterFormat(f, selected) {
+				selected = f
+			}
+		case database.QualitySmallest:
+			if selected == nil || smallerFormat(f, selected) {
+				selected = f
+			}
+		case database.QualityModeMaxHeight:
+			if f.ShortSide() <= policy.MaxHeight && (selected == nil || betterFormat(f, selected)) {
+				selected = f
+			}
+		case database.QualityModeFormat:
+			if strings.EqualFold(f.FileFormat, policy.Format) {
+				return f
+			}
+		}
+	}
+	if selected == nil && policy.Mode == database.QualityModeMaxHeight {
+		return SelectVideoFormat(formats, database.QualityPolicy{Mode: database.QualitySmallest})
+	}
+	return selected
+}
+
+// betterFormat 分辨率更高，或分辨率相同码率更高
+func betterFormat(a, b *database.VideoFormat) bool {
+	if pa, pb := a.Width*a.Height, b.Width*b.Height; pa != pb {
+		return pa > pb
+	}
+	return a.BitRate > b.BitRate
+}
+
+// smallerFormat 码率更低，码率相同时分辨率更低
+func smallerFormat(a, b *database.VideoFormat) bool {
+	if a.BitRate != b.BitRate && a.BitRate > 0 && b.BitRate > 0 {
+		return a.BitRate < b.BitRate
+	}
+	return a.Width*a.Height < b.Width*b.Height
+}
+
+// ApplyVideoFormat 在链接上指定编码（替换已有的编码参数）
+func ApplyVideoFormat(videoURL, fileFormat string) string {
+	base := StripVideoFormat(videoURL)
+	if fileFormat == "" || base == "" {
+		return base
+	}
+	sep := "?"
+	if strings.Contains(base, "?") {
+		sep = "&"
+	}
+	return base + sep + videoFormatParam + "=" + url.QueryEscape(fileFormat)
+}
+
+// StripVideoFormat 去掉链接中的编码参数
+func StripVideoFormat(videoURL string) string {
+	stripped := videoFormatParamPattern.ReplaceAllString(videoURL, "$1")
+	return strings.TrimRight(stripped, "?&")
+}
+
+// VideoURLFormat 返回链接中指定的编码，没有时返回空字符串
+func VideoURLFormat(videoURL string) string {
+	m := videoFormatValuePattern.FindStringSubmatch(videoURL)
+	if m == nil {
+		return ""
+	}
+	format, err := url.QueryUnescape(m[1])
+	if err != nil {
+		return m[1]
+	}
+	return format
+}
+
+// ParseVideoFormats 从 media.spec 中提取可选编码
+func ParseVideoFormats(spec []interface{}) []database.VideoFormat {
+	formats := []database.VideoFormat{}
+	seen := make(map[string]bool)
+	for _, item := range spec {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		fileFormat, _ := m["fileFormat"].(string)
+		if fileFormat == "" || seen[fileFormat] {
+			continue
+		}
+		seen[fileFormat] = true
+		codingFormat, _ := m["codingFormat"].(string)
+		formats = append(formats, database.VideoFormat{
+			FileFormat:   fileFormat,
+			Width:        int(specNumber(m["width"])),
+			Height:       int(specNumber(m["height"])),
+			BitRate:      specNumber(m["bitRate"]),
+			CodingFormat: codingFormat,
+		})
+	}
+	return formats
+}
+
+// specNumber 读取 spec 中的数字字段（可能是数字或字符串）
+func specNumber(v interface{}) int64 {
+	switch n := v.(type) {
+	case float64:
+		return int64(n)
+	case string:
+		x, _ := strconv.ParseInt(strings.TrimSpace(n), 10, 64)
+		return x
+	}
+	return 0
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"wx_channel/internal/database"
+	"wx_channel/internal/utils"
+	"wx_channel/internal/websocket"
+)
+
+const videoURLProbeTimeout = 15 * time.Second
+
+// expiryQueryParams 视频链接中可能携带过期时间（Unix 秒）的参数
+var expiryQueryParams = []string{"expire", "expires", "x-expires", "x-expire"}
+
+// VideoURLExpired 根据链接中的过期时间判断链接是否已过期，链接中没有过期时间时返回 false
+func VideoURLExpired(videoURL string, now time.Time) bool {
+	u, err := url.Parse(videoURL)
+	if err != nil {
+		return false
+	}
+	for key, values := range u.Query() {
+		if len(values) == 0 || !containsFold(expiryQueryParams, key) {
+			continue
+		}
+		ts, err := strconv.ParseInt(values[0], 10, 64)
+		if err != nil || ts <= 0 {
+			continue
+		}
+		// 兼容毫秒时间戳
+		if ts > 1e12 {
+			ts /= 1000
+		}
+		return !now.Before(time.Unix(ts, 0))
+	}
+	return false
+}
+
+// IsExpiredStatus 视频号 CDN 对过期的链接返回 403 或 404
+func IsExpiredStatus(code int) bool {
+	return code == http.StatusForbidden || code == http.StatusNotFound
+}
+
+// VideoURLRefresher 在下载前或下载失败后检测视频链接是否过期，过期时通过
+// key:channels:feed_profile 重新获取链接和密钥，并更新浏览记录和下载队列
+type VideoURLRefresher struct {
+	profiles *FeedProfileService
+	browse   *database.BrowseHistoryRepository
+	queue    *database.QueueRepository
+	client   *http.Client
+}
+
+// NewVideoURLRefresher 创建一个新的 VideoURLRefresher
+func NewVideoURLRefresher(hub *websocket.Hub) *VideoURLRefresher {
+	return &VideoURLRefresher{
+		profiles: GetFeedProfileService(hub),
+		browse:   database.NewBrowseHistoryRepository(),
+		queue:    database.NewQueueRepository(),
+		client:   &http.Client{Timeout: videoURLProbeTimeout},
+	}
+}
+
+// Probe 请求链接的第一个字节，返回 HTTP 状态码
+func (r *VideoURLRefresher) Probe(ctx context.Context, videoURL string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, videoURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create probe request: %w", err)
+	}
+	req.Header.Set("Range", "bytes=0-0")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to probe video url: %w", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// Expired 判断链接是否已过期：先检查链接中的过期时间，再请求链接查看状态码
+func (r *VideoURLRefresher) Expired(ctx context.Context, videoURL string) bool {
+	if videoURL == "" {
+		return false
+	}
+	if VideoURLExpired(videoURL, time.Now()) {
+		return true
+	}
+	code, err := r.Probe(ctx, videoURL)
+	return err == nil && IsExpiredStatus(code)
+}
+
+// Refresh 重新获取视频的下载链接和解密密钥，并写回浏览记录和下载队列。
+// nonceID 为空时从浏览记录或下载队列中查找；新链接没有密钥时沿用 staleKey。
+func (r *VideoURLRefresher) Refresh(videoID, nonceID, staleURL, staleKey string) (videoURL, decryptKey string, err error) {
+	if videoID == "" {
+		return "", "", fmt.Errorf("video id is required")
+	}
+	if nonceID == "" {
+		nonceID, err = r.lookupNonceID(videoID)
+		if err != nil {
+			return "", "", err
+		}
+		if nonceID == "" {
+			return "", "", fmt.Errorf("no nonce id recorded for video %s", videoID)
+		}
+	}
+
+	// 记录中保存的是不带编码参数的链接，刷新后再指定原来选择的编码
+	staleBase := StripVideoFormat(staleURL)
+	video, err := r.profiles.ResolveVideo(videoID, nonceID, staleBase)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to resolve video: %w", err)
+	}
+	if video.VideoURL == staleBase {
+		return "", "", fmt.Errorf("feed profile returned the same video url")
+	}
+	decryptKey = video.DecryptKey
+	if decryptKey == "" {
+		decryptKey = staleKey
+	}
+
+	if _, err := r.browse.UpdateVideoSource(videoID, video.VideoURL, decryptKey); err != nil {
+		utils.Warn("[链接刷新] 更新浏览记录失败: %v", err)
+	}
+	if _, err := r.queue.UpdateVideoSource(videoID, video.VideoURL, decryptKey); err != nil {
+		utils.Warn("[链接刷新] 更新下载队列失败: %v", err)
+	}
+	utils.Info("🔄 [链接刷新] 已重新获取视频链接: %s", videoID)
+
+	videoURL = video.VideoURL
+	if format := VideoURLFormat(staleURL); format != "" {
+		videoURL = ApplyVideoFormat(videoURL, format)
+	}
+	return videoURL, decryptKey, nil
+}
+
+// lookupNonceID 从浏览记录或下载队列中查找视频的 objectNonceId
+func (r *VideoURLRefresher) lookupNonceID(videoID string) (string, error) {
+	record, err := r.browse.GetByID(videoID)
+	if err != nil {
+		return "", err
+	}
+	if record != nil && record.NonceID != "" {
+		return record.NonceID, nil
+	}
+	return r.queue.GetNonceID(videoID)
+}
+
+// containsFold 判断 list 中是否有与 s 忽略大小写相等的元素
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
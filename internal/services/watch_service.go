@@ -0,0 +1,391 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"wx_channel/internal/database"
+	"wx_channel/internal/utils"
+	"wx_channel/internal/websocket"
+)
+
+const (
+	defaultWatchIntervalMinutes = 30
+	minWatchIntervalMinutes     = 5
+	watchTickInterval           = time.Minute // 检查哪些作者到期的频率
+)
+
+var (
+	// ErrWatchAuthorNotFound 作者不在关注列表中
+	ErrWatchAuthorNotFound = errors.New("watch author not found")
+	// ErrWatchAuthorExists 作者已在关注列表中
+	ErrWatchAuthorExists = errors.New("author already watched")
+)
+
+// WatchAuthorRequest 添加或修改关注作者的参数，修改时为 nil 的字段保持不变
+type WatchAuthorRequest struct {
+	Username        string  `json:"username"`
+	Nickname        *string `json:"nickname"`
+	HeadURL         *string `json:"headUrl"`
+	Policy          *string `json:"policy"`  // notify（默认）或 enqueue
+	Keyword         *string `json:"keyword"` // 仅 enqueue：标题关键词，多个用逗号分隔
+	IntervalMinutes *int    `json:"intervalMinutes"`
+	Enabled         *bool   `json:"enabled"`
+}
+
+// WatchEvent 一次检查发现的新视频
+type WatchEvent struct {
+	Author *database.WatchAuthor
+	Videos []database.WatchVideo
+	Queued []database.QueueItem
+}
+
+// WatchService 定期检查关注作者的视频列表，发现新视频后通知或自动加入下载队列。
+// 只在有视频号页面连接时检查，没有页面时跳过，等页面连接后补上。
+type WatchService struct {
+	repo     *database.WatchRepository
+	queue    *QueueService
+	hub      *websocket.Hub
+	mu       sync.Mutex
+	checking map[string]bool
+	onNew    func(WatchEvent)
+	started  bool
+}
+
+var (
+	watchService     *WatchService
+	watchServiceOnce sync.Once
+)
+
+// GetWatchService 获取全局关注列表服务
+func GetWatchService(hub *websocket.Hub) *WatchService {
+	watchServiceOnce.Do(func() {
+		watchService = NewWatchService(hub)
+	})
+	return watchService
+}
+
+// NewWatchService 创建一个新的 WatchService
+func NewWatchService(hub *websocket.Hub) *WatchService {
+	return &WatchService{
+		repo:     database.NewWatchRepository(),
+		queue:    NewQueueService(),
+		hub:      hub,
+		checking: make(map[string]bool),
+	}
+}
+
+// SetNewVideosHandler 设置发现新视频后的回调（用于向控制台广播）
+func (s *WatchService) SetNewVideosHandler(fn func(WatchEvent)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onNew = fn
+}
+
+// Start 启动后台轮询，重复调用无效
+func (s *WatchService) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started || s.hub == nil {
+		return
+	}
+	s.started = true
+
+	go func() {
+		ticker := time.NewTicker(watchTickInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.checkDue()
+		}
+	}()
+}
+
+// checkDue 检查所有到期的作者
+func (s *WatchService) checkDue() {
+	defer func() {
+		if r := recover(); r != nil {
+			utils.LogError("[关注] 轮询 panic: %v", r)
+		}
+	}()
+
+	if s.hub.ClientCount() == 0 {
+		return
+	}
+	authors, err := s.repo.ListAuthors()
+	if err != nil {
+		utils.Warn("[关注] 获取关注列表失败: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for i := range authors {
+		a := &authors[i]
+		if !a.Enabled {
+			continue
+		}
+		if a.LastCheckedAt != nil && now.Sub(*a.LastCheckedAt) < time.Duration(a.IntervalMinutes)*time.Minute {
+			continue
+		}
+		if _, err := s.Check(a.Username); err != nil {
+			utils.Warn("[关注] 检查 %s 失败: %v", a.Username, err)
+		}
+		// 依次检查，避免同时向页面发出大量请求
+		if s.hub.ClientCount() == 0 {
+			return
+		}
+	}
+}
+
+// Add 添加关注作者
+func (s *WatchService) Add(req *WatchAuthorRequest) (*database.WatchAuthor, error) {
+	username := strings.TrimSpace(req.Username)
+	if username == "" {
+		return nil, fmt.Errorf("username is required")
+	}
+	existing, err := s.repo.GetAuthor(username)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrWatchAuthorExists
+	}
+
+	a := &database.WatchAuthor{
+		Username:        username,
+		Policy:          database.WatchPolicyNotify,
+		IntervalMinutes: defaultWatchIntervalMinutes,
+		Enabled:         true,
+	}
+	if err := applyWatchRequest(a, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateAuthor(a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Update 修改关注作者的设置
+func (s *WatchService) Update(username string, req *WatchAuthorRequest) (*database.WatchAuthor, error) {
+	a, err := s.repo.GetAuthor(username)
+	if err != nil {
+		return nil, err
+	}
+	if a == nil {
+		return nil, ErrWatchAuthorNotFound
+	}
+	if err := applyWatchRequest(a, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateAuthor(a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// applyWatchRequest 校验并应用请求中的字段
+func applyWatchRequest(a *database.WatchAuthor, req *WatchAuthorRequest) error {
+	if req.Policy != nil {
+		switch *req.Policy {
+		case database.WatchPolicyNotify, database.WatchPolicyEnqueue:
+			a.Policy = *req.Policy
+		default:
+			return fmt.Errorf("invalid policy: %s", *req.Policy)
+		}
+	}
+	if req.IntervalMinutes != nil {
+		if *req.IntervalMinutes < minWatchIntervalMinutes {
+			return fmt.Errorf("intervalMinutes must be at least %d", minWatchIntervalMinutes)
+		}
+		a.IntervalMinutes = *req.IntervalMinutes
+	}
+	if req.Nickname != nil {
+		a.Nickname = *req.Nickname
+	}
+	if req.HeadURL != nil {
+		a.HeadURL = *req.HeadURL
+	}
+	if req.Keyword != nil {
+		a.Keyword = strings.TrimSpace(*req.Keyword)
+	}
+	if req.Enabled != nil {
+		a.Enabled = *req.Enabled
+	}
+	return nil
+}
+
+// Remove 取消关注
+func (s *WatchService) Remove(username string) error {
+	err := s.repo.DeleteAuthor(username)
+	if err == sql.ErrNoRows {
+		return ErrWatchAuthorNotFound
+	}
+	return err
+}
+
+// Get 获取关注作者
+func (s *WatchService) Get(username string) (*database.WatchAuthor, error) {
+	return s.repo.GetAuthor(username)
+}
+
+// List 获取关注列表
+func (s *WatchService) List() ([]database.WatchAuthor, error) {
+	return s.repo.ListAuthors()
+}
+
+// ListVideos 获取检测到的新视频
+func (s *WatchService) ListVideos(username string, params *database.PaginationParams) (*database.PagedResult[database.WatchVideo], error) {
+	return s.repo.ListVideos(username, params)
+}
+
+// Check 立即检查作者的视频列表第一页，返回发现的新视频。
+// 首次检查只记录最新发布时间作为基线，不把已有视频当作新视频。
+func (s *WatchService) Check(username string) ([]database.WatchVideo, error) {
+	if s.hub == nil {
+		return nil, fmt.Errorf("websocket hub not available")
+	}
+
+	s.mu.Lock()
+	if s.checking[username] {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("check already in progress")
+	}
+	s.checking[username] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.checking, username)
+		s.mu.Unlock()
+	}()
+
+	a, err := s.repo.GetAuthor(username)
+	if err != nil {
+		return nil, err
+	}
+	if a == nil {
+		return nil, ErrWatchAuthorNotFound
+	}
+
+	// 跳过分页缓存，否则 TTL 内看不到新发布的视频
+	result, fetchErr := s.hub.Pager().FetchPages(websocket.PageQuery{Key: websocket.APIKeyFeedList, Query: username}, 1, true)
+	now := time.Now()
+	a.LastCheckedAt = &now
+	if fetchErr != nil {
+		a.ErrorMessage = fetchErr.Error()
+		if err := s.repo.UpdateCheckResult(a, 0); err != nil {
+			utils.Warn("[关注] 保存 %s 检查状态失败: %v", username, err)
+		}
+		return nil, fetchErr
+	}
+	a.ErrorMessage = ""
+
+	var fresh []database.WatchVideo
+	var newest time.Time
+	for _, item := range result.Items {
+		v, err := ParseCrawlVideo(item)
+		if err != nil || v == nil || v.PublishTime == nil {
+			continue
+		}
+		if a.Nickname == "" {
+			a.Nickname = v.Author
+		}
+		if v.PublishTime.After(newest) {
+			newest = *v.PublishTime
+		}
+		// 置顶视频可能很旧，逐条与基线比较而不是依赖列表顺序
+		if a.LastSeenAt != nil && v.PublishTime.After(*a.LastSeenAt) {
+			fresh = append(fresh, database.WatchVideo{
+				Username:    username,
+				VideoID:     v.VideoID,
+				NonceID:     v.NonceID,
+				Title:       v.Title,
+				Author:      v.Author,
+				CoverURL:    v.CoverURL,
+				VideoURL:    v.VideoURL,
+				DecryptKey:  v.DecryptKey,
+				Duration:    v.Duration,
+				Size:        v.Size,
+				MediaType:   v.MediaType,
+				PublishTime: v.PublishTime,
+			})
+		}
+	}
+	if !newest.IsZero() && (a.LastSeenAt == nil || newest.After(*a.LastSeenAt)) {
+		a.LastSeenAt = &newest
+	}
+
+	added, err := s.repo.SaveVideos(fresh)
+	if err != nil {
+		return nil, err
+	}
+	// 页面请求可能耗时很久，只写回检查结果，再重新读取作者，使用检查期间修改后的策略
+	if err := s.repo.UpdateCheckResult(a, len(added)); err != nil {
+		return nil, err
+	}
+	if len(added) == 0 {
+		return added, nil
+	}
+	latest, err := s.repo.GetAuthor(username)
+	if err != nil {
+		return nil, err
+	}
+	if latest == nil {
+		// 检查期间被取消关注
+		return added, nil
+	}
+	a = latest
+
+	utils.Info("[关注] %s 发布了 %d 个新视频", a.Nickname, len(added))
+	event := WatchEvent{Author: a, Videos: added}
+	if a.Policy == database.WatchPolicyEnqueue {
+		event.Queued = s.enqueue(a, added)
+	}
+
+	s.mu.Lock()
+	onNew := s.onNew
+	s.mu.Unlock()
+	if onNew != nil {
+		onNew(event)
+	}
+	return added, nil
+}
+
+// enqueue 将符合关键词条件的新视频加入下载队列
+func (s *WatchService) enqueue(a *database.WatchAuthor, videos []database.WatchVideo) []database.QueueItem {
+	infos := []VideoInfo{}
+	ids := []string{}
+	for _, v := range videos {
+		if v.MediaType != 4 || v.VideoURL == "" || !matchTitleKeywords(a.Keyword, v.Title) {
+			continue
+		}
+		infos = append(infos, VideoInfo{
+			VideoID:    v.VideoID,
+			Title:      v.Title,
+			Author:     v.Author,
+			CoverURL:   v.CoverURL,
+			VideoURL:   v.VideoURL,
+			DecryptKey: v.DecryptKey,
+			Duration:   v.Duration,
+			Size:       v.Size,
+			NonceID:    v.NonceID,
+		})
+		ids = append(ids, v.VideoID)
+	}
+	if len(infos) == 0 {
+		return nil
+	}
+
+	items, err := s.queue.AddToQueue(infos)
+	if err != nil {
+		utils.Warn("[关注] %s 的新视频加入下载队列失败: %v", a.Username, err)
+		return nil
+	}
+	if err := s.repo.MarkEnqueued(a.Username, ids); err != nil {
+		utils.Warn("[关注] 标记入队失败: %v", err)
+	}
+	return items
+}
@@ -0,0 +1,394 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"wx_channel/internal/database"
+	"wx_channel/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+const (
+	webhookMaxAttempts     = 5                // 每次推送的最大尝试次数
+	webhookTimeout         = 10 * time.Second // 单次请求超时
+	webhookDeliveryHistory = 500              // 每个 Webhook 保留的推送记录数
+	webhookErrorBodyLimit  = 512              // 失败时记录的响应内容长度
+	webhookRetryBase       = time.Second      // 重试间隔的基数，第 n 次重试前等待 base*2^n
+)
+
+// Webhook 请求头
+const (
+	WebhookHeaderEvent     = "X-WX-Channel-Event"
+	WebhookHeaderDelivery  = "X-WX-Channel-Delivery"
+	WebhookHeaderSignature = "X-WX-Channel-Signature" // sha256=<hex(HMAC-SHA256(secret, body))>
+)
+
+var (
+	// ErrWebhookNotFound Webhook 不存在
+	ErrWebhookNotFound = errors.New("webhook not found")
+)
+
+// WebhookRequest 创建或修改 Webhook 的参数，修改时只更新出现的字段
+type WebhookRequest struct {
+	Name    *string  `json:"name"`
+	URL     *string  `json:"url"`
+	Events  []string `json:"events"`
+	Secret  *string  `json:"secret"` // 为空字符串时取消签名
+	Enabled *bool    `json:"enabled"`
+}
+
+// WebhookPayload 推送的 JSON 内容
+type WebhookPayload struct {
+	Event     string      `json:"event"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookDownloadData download.completed / download.failed 事件内容
+type WebhookDownloadData struct {
+	Source   string `json:"source"` // queue 或 batch
+	QueueID  string `json:"queueId,omitempty"`
+	VideoID  string `json:"videoId"`
+	Title    string `json:"title"`
+	Author   string `json:"author"`
+	FilePath string `json:"filePath,omitempty"`
+	FileSize int64  `json:"fileSize,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// WebhookTranscriptData transcript.ready / transcript.failed 事件内容
+type WebhookTranscriptData struct {
+	RecordID       string `json:"recordId"`
+	VideoID        string `json:"videoId"`
+	Title          string `json:"title"`
+	Author         string `json:"author"`
+	TranscriptPath string `json:"transcriptPath,omitempty"`
+}
+
+// WebhookBatchData batch.finished 事件内容
+type WebhookBatchData struct {
+	Total     int  `json:"total"`
+	Done      int  `json:"done"`
+	Failed    int  `json:"failed"`
+	Cancelled bool `json:"cancelled"`
+}
+
+// WebhookService 管理出站 Webhook，按订阅的事件推送签名的 JSON，失败时指数退避重试
+type WebhookService struct {
+	repo      *database.WebhookRepository
+	client    *http.Client
+	retryBase time.Duration
+}
+
+var (
+	webhookService     *WebhookService
+	webhookServiceOnce sync.Once
+)
+
+// GetWebhookService 获取全局 Webhook 服务
+func GetWebhookService() *WebhookService {
+	webhookServiceOnce.Do(func() {
+		webhookService = NewWebhookService()
+	})
+	return webhookService
+}
+
+// NewWebhookService 创建一个新的 WebhookService
+func NewWebhookService() *WebhookService {
+	return &WebhookService{
+		repo: database.NewWebhookRepository(),
+		client: &http.Client{
+			Timeout: webhookTimeout,
+		},
+		retryBase: webhookRetryBase,
+	}
+}
+
+// List 列出全部 Webhook
+func (s *WebhookService) List() ([]database.Webhook, error) {
+	return s.repo.List()
+}
+
+// Get 获取 Webhook
+func (s *WebhookService) Get(id string) (*database.Webhook, error) {
+	hook, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if hook == nil {
+		return nil, ErrWebhookNotFound
+	}
+	return hook, nil
+}
+
+// Create 创建 Webhook，未指定名称时使用 URL 的主机名，默认启用
+func (s *WebhookService) Create(req *WebhookRequest) (*database.Webhook, error) {
+	if req.URL == nil {
+		return nil, fmt.Errorf("url is required")
+	}
+	hook := &database.Webhook{
+		ID:      uuid.New().String(),
+		Enabled: true,
+	}
+	if err := applyWebhookRequest(hook, req); err != nil {
+		return nil, err
+	}
+	if hook.Events == nil {
+		return nil, fmt.Errorf("at least one event is required")
+	}
+	if err := s.repo.Create(hook); err != nil {
+		return nil, err
+	}
+	utils.Info("🪝 [Webhook] 已添加: %s (%s)", hook.Name, strings.Join(hook.Events, ","))
+	return hook, nil
+}
+
+// Update 修改 Webhook
+func (s *WebhookService) Update(id string, req *WebhookRequest) (*database.Webhook, error) {
+	hook, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyWebhookRequest(hook, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(hook); err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+// Delete 删除 Webhook 及其推送记录
+func (s *WebhookService) Delete(id string) error {
+	n, err := s.repo.Delete(id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// Deliveries 分页列出 Webhook 的推送记录
+func (s *WebhookService) Deliveries(id string, params *database.PaginationParams) (*database.PagedResult[database.WebhookDelivery], error) {
+	if _, err := s.Get(id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListDeliveries(id, params)
+}
+
+// Test 立即向 Webhook 发送一次 webhook.test 事件（不重试），返回推送结果
+func (s *WebhookService) Test(id string) (*database.WebhookDelivery, error) {
+	hook, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	delivery, err := s.newDelivery(hook, database.WebhookEventTest, map[string]string{"message": "webhook test"})
+	if err != nil {
+		return nil, err
+	}
+	s.deliver(hook, delivery, 1)
+	return delivery, nil
+}
+
+// Dispatch 异步向所有订阅了 event 的已启用 Webhook 推送 data
+func (s *WebhookService) Dispatch(event string, data interface{}) {
+	if s == nil {
+		return
+	}
+	hooks, err := s.repo.ListEnabled()
+	if err != nil {
+		utils.Warn("[Webhook] 获取 Webhook 列表失败: %v", err)
+		return
+	}
+	for i := range hooks {
+		hook := &hooks[i]
+		if !hook.Subscribes(event) {
+			continue
+		}
+		delivery, err := s.newDelivery(hook, event, data)
+		if err != nil {
+			utils.Warn("[Webhook] 创建推送记录失败 %s: %v", hook.Name, err)
+			continue
+		}
+		go s.deliver(hook, delivery, webhookMaxAttempts)
+	}
+}
+
+// ResumePending 启动时接着发送上次退出前未完成的推送；Webhook 已删除、已停用
+// 或重试次数已用完的推送标记为失败
+func (s *WebhookService) ResumePending() {
+	deliveries, err := s.repo.ListPendingDeliveries()
+	if err != nil {
+		utils.Warn("[Webhook] 获取未完成的推送失败: %v", err)
+		return
+	}
+	resumed := 0
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		hook, err := s.repo.GetByID(delivery.WebhookID)
+		if err != nil {
+			utils.Warn("[Webhook] 获取 Webhook 失败 %s: %v", delivery.WebhookID, err)
+			continue
+		}
+		switch {
+		case hook == nil || !hook.Enabled:
+			delivery.Status = database.WebhookDeliveryFailed
+			delivery.Error = "webhook was disabled or deleted before delivery"
+			s.saveDelivery(delivery)
+		case delivery.Attempts >= webhookMaxAttempts:
+			delivery.Status = database.WebhookDeliveryFailed
+			if delivery.Error == "" {
+				delivery.Error = "interrupted by restart"
+			}
+			s.saveDelivery(delivery)
+		default:
+			go s.deliver(hook, delivery, webhookMaxAttempts)
+			resumed++
+		}
+	}
+	if resumed > 0 {
+		utils.Info("🪝 [Webhook] 继续发送 %d 个未完成的推送", resumed)
+	}
+}
+
+// newDelivery 编码推送内容并保存为待发送的推送记录
+func (s *WebhookService) newDelivery(hook *database.Webhook, event string, data interface{}) (*database.WebhookDelivery, error) {
+	payload, err := json.Marshal(WebhookPayload{Event: event, Timestamp: time.Now(), Data: data})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+	delivery := &database.WebhookDelivery{
+		WebhookID: hook.ID,
+		Event:     event,
+		Payload:   payload,
+		Status:    database.WebhookDeliveryPending,
+	}
+	if err := s.repo.CreateDelivery(delivery, webhookDeliveryHistory); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// deliver 发送推送，网络错误、429 和 5xx 时指数退避重试，最多 maxAttempts 次
+// （包括 delivery 之前已经尝试过的次数）
+func (s *WebhookService) deliver(hook *database.Webhook, delivery *database.WebhookDelivery, maxAttempts int) {
+	for attempt := delivery.Attempts + 1; attempt <= maxAttempts; attempt++ {
+		if attempt > 1 {
+			// 指数退避 + 随机抖动：2s, 4s, 8s...
+			delay := s.retryBase<<uint(attempt-1) + time.Duration(rand.Int63n(int64(s.retryBase)))
+			time.Sleep(delay)
+		}
+
+		code, err := s.send(hook, delivery)
+		delivery.Attempts = attempt
+		delivery.ResponseCode = code
+		if err == nil {
+			delivery.Status = database.WebhookDeliverySuccess
+			delivery.Error = ""
+			s.saveDelivery(delivery)
+			return
+		}
+
+		delivery.Error = err.Error()
+		retryable := code == 0 || code == http.StatusTooManyRequests || code >= 500
+		if !retryable || attempt == maxAttempts {
+			delivery.Status = database.WebhookDeliveryFailed
+			s.saveDelivery(delivery)
+			utils.Warn("[Webhook] 推送 %s 到 %s 失败（%d 次）: %v", delivery.Event, hook.Name, attempt, err)
+			return
+		}
+		s.saveDelivery(delivery)
+	}
+}
+
+// send 发送一次请求，返回响应状态码（网络错误时为 0）
+func (s *WebhookService) send(hook *database.Webhook, delivery *database.WebhookDelivery) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "wx_channel-webhook")
+	req.Header.Set(WebhookHeaderEvent, delivery.Event)
+	req.Header.Set(WebhookHeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	if hook.Secret != "" {
+		req.Header.Set(WebhookHeaderSignature, SignWebhookPayload(hook.Secret, delivery.Payload))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, webhookErrorBodyLimit))
+		return resp.StatusCode, fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+// saveDelivery 保存推送结果，失败时只记录警告
+func (s *WebhookService) saveDelivery(delivery *database.WebhookDelivery) {
+	if err := s.repo.UpdateDelivery(delivery); err != nil {
+		utils.Warn("[Webhook] 更新推送记录失败: %v", err)
+	}
+}
+
+// SignWebhookPayload 计算推送内容的签名：sha256=<hex(HMAC-SHA256(secret, body))>
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// applyWebhookRequest 校验并应用请求中出现的字段
+func applyWebhookRequest(hook *database.Webhook, req *WebhookRequest) error {
+	if req.URL != nil {
+		u, err := url.Parse(strings.TrimSpace(*req.URL))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("url must be an http or https URL")
+		}
+		hook.URL = u.String()
+		if hook.Name == "" {
+			hook.Name = u.Hostname()
+		}
+	}
+	if req.Name != nil {
+		if name := strings.TrimSpace(*req.Name); name != "" {
+			hook.Name = name
+		}
+	}
+	if req.Events != nil {
+		events, err := database.ParseWebhookEvents(req.Events)
+		if err != nil {
+			return err
+		}
+		hook.Events = events
+	}
+	if req.Secret != nil {
+		hook.Secret = *req.Secret
+	}
+	if req.Enabled != nil {
+		hook.Enabled = *req.Enabled
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"wx_channel/internal/database"
+)
+
+// setupWebhookService 在临时目录初始化数据库，并缩短重试间隔
+func setupWebhookService(t *testing.T) *WebhookService {
+	t.Helper()
+	if err := database.Initialize(&database.Config{DBPath: filepath.Join(t.TempDir(), "records.db")}); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	s := NewWebhookService()
+	s.retryBase = time.Millisecond
+	return s
+}
+
+// createTestWebhook 创建订阅 events 的 Webhook
+func createTestWebhook(t *testing.T, s *WebhookService, url, secret string, events ...string) *database.Webhook {
+	t.Helper()
+	hook, err := s.Create(&WebhookRequest{URL: &url, Secret: &secret, Events: events})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return hook
+}
+
+// waitDeliveries 等待 Webhook 的推送全部结束，返回推送记录（新的在前）
+func waitDeliveries(t *testing.T, s *WebhookService, hookID string) []database.WebhookDelivery {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		result, err := s.Deliveries(hookID, &database.PaginationParams{Page: 1, PageSize: 100})
+		if err != nil {
+			t.Fatalf("Deliveries() error = %v", err)
+		}
+		done := true
+		for _, d := range result.Items {
+			if d.Status == database.WebhookDeliveryPending {
+				done = false
+			}
+		}
+		if done {
+			return result.Items
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("deliveries still pending: %+v", result.Items)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	s := setupWebhookService(t)
+
+	var (
+		mu      sync.Mutex
+		headers http.Header
+		body    []byte
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		headers = r.Header.Clone()
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	hook := createTestWebhook(t, s, server.URL, "my-secret", database.WebhookEventDownloadCompleted)
+	delivery, err := s.Test(hook.ID)
+	if err != nil {
+		t.Fatalf("Test() error = %v", err)
+	}
+	if delivery.Status != database.WebhookDeliverySuccess || delivery.Attempts != 1 {
+		t.Fatalf("delivery = %+v, want success after 1 attempt", delivery)
+	}
+
+	mu.Lock()
+	mac := hmac.New(sha256.New, []byte("my-secret"))
+	mac.Write(body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got := headers.Get(WebhookHeaderSignature); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+	if got := headers.Get(WebhookHeaderEvent); got != database.WebhookEventTest {
+		t.Errorf("event header = %q, want %q", got, database.WebhookEventTest)
+	}
+	if string(body) != string(delivery.Payload) {
+		t.Errorf("body = %s, want stored payload %s", body, delivery.Payload)
+	}
+	mu.Unlock()
+
+	// 未设置密钥时不签名
+	unsigned := createTestWebhook(t, s, server.URL, "", database.WebhookEventDownloadCompleted)
+	if _, err := s.Test(unsigned.ID); err != nil {
+		t.Fatalf("Test() error = %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if got := headers.Get(WebhookHeaderSignature); got != "" {
+		t.Errorf("unsigned webhook sent signature %q", got)
+	}
+}
+
+func TestWebhookRetry(t *testing.T) {
+	tests := []struct {
+		status       int
+		wantAttempts int
+	}{
+		{http.StatusInternalServerError, webhookMaxAttempts},
+		{http.StatusBadGateway, webhookMaxAttempts},
+		{http.StatusTooManyRequests, webhookMaxAttempts},
+		{http.StatusBadRequest, 1},
+		{http.StatusNotFound, 1},
+	}
+
+	s := setupWebhookService(t)
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			var mu sync.Mutex
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				calls++
+				mu.Unlock()
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			hook := createTestWebhook(t, s, server.URL, "", database.WebhookEventDownloadFailed)
+			defer s.Delete(hook.ID) // 后续子测试不再推送到这里
+			s.Dispatch(database.WebhookEventDownloadFailed, WebhookDownloadData{VideoID: "v1"})
+
+			deliveries := waitDeliveries(t, s, hook.ID)
+			if len(deliveries) != 1 {
+				t.Fatalf("got %d deliveries, want 1", len(deliveries))
+			}
+			d := deliveries[0]
+			if d.Status != database.WebhookDeliveryFailed || d.Attempts != tt.wantAttempts || d.ResponseCode != tt.status {
+				t.Errorf("delivery = status %s, attempts %d, code %d; want failed, %d, %d",
+					d.Status, d.Attempts, d.ResponseCode, tt.wantAttempts, tt.status)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if calls != tt.wantAttempts {
+				t.Errorf("server got %d requests, want %d", calls, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestWebhookRetryThenSuccess(t *testing.T) {
+	s := setupWebhookService(t)
+
+	var mu sync.Mutex
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	hook := createTestWebhook(t, s, server.URL, "", database.WebhookEventBatchFinished)
+	s.Dispatch(database.WebhookEventBatchFinished, WebhookBatchData{Total: 1, Done: 1})
+
+	deliveries := waitDeliveries(t, s, hook.ID)
+	if len(deliveries) != 1 {
+		t.Fatalf("got %d deliveries, want 1", len(deliveries))
+	}
+	d := deliveries[0]
+	if d.Status != database.WebhookDeliverySuccess || d.Attempts != 3 || d.Error != "" {
+		t.Errorf("delivery = %+v, want success after 3 attempts", d)
+	}
+}
+
+func TestWebhookEventFilter(t *testing.T) {
+	s := setupWebhookService(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	subscribed := createTestWebhook(t, s, server.URL, "", database.WebhookEventDownloadCompleted)
+	other := createTestWebhook(t, s, server.URL, "", database.WebhookEventBatchFinished)
+	all := createTestWebhook(t, s, server.URL, "", database.WebhookEventAll)
+	disabled := createTestWebhook(t, s, server.URL, "", database.WebhookEventDownloadCompleted)
+	enabled := false
+	if _, err := s.Update(disabled.ID, &WebhookRequest{Enabled: &enabled}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	s.Dispatch(database.WebhookEventDownloadCompleted, WebhookDownloadData{VideoID: "v1"})
+
+	for _, tt := range []struct {
+		name string
+		hook *database.Webhook
+		want int
+	}{
+		{"subscribed", subscribed, 1},
+		{"other event", other, 0},
+		{"all events", all, 1},
+		{"disabled", disabled, 0},
+	} {
+		deliveries := waitDeliveries(t, s, tt.hook.ID)
+		if len(deliveries) != tt.want {
+			t.Errorf("%s: got %d deliveries, want %d", tt.name, len(deliveries), tt.want)
+			continue
+		}
+		for _, d := range deliveries {
+			if d.Event != database.WebhookEventDownloadCompleted || d.Status != database.WebhookDeliverySuccess {
+				t.Errorf("%s: delivery = %+v", tt.name, d)
+			}
+		}
+	}
+}
+
+func TestWebhookResumePending(t *testing.T) {
+	s := setupWebhookService(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	active := createTestWebhook(t, s, server.URL, "", database.WebhookEventDownloadCompleted)
+	disabled := createTestWebhook(t, s, server.URL, "", database.WebhookEventDownloadCompleted)
+	enabled := false
+	if _, err := s.Update(disabled.ID, &WebhookRequest{Enabled: &enabled}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	// 模拟上次退出时第一次尝试失败、尚未重试的推送
+	for _, hook := range []*database.Webhook{active, disabled} {
+		delivery, err := s.newDelivery(hook, database.WebhookEventDownloadCompleted, WebhookDownloadData{VideoID: "v1"})
+		if err != nil {
+			t.Fatalf("newDelivery() error = %v", err)
+		}
+		delivery.Attempts = 1
+		delivery.ResponseCode = http.StatusBadGateway
+		s.saveDelivery(delivery)
+	}
+
+	s.ResumePending()
+
+	if d := waitDeliveries(t, s, active.ID)[0]; d.Status != database.WebhookDeliverySuccess || d.Attempts != 2 {
+		t.Errorf("active delivery = %+v, want success after 2 attempts", d)
+	}
+	if d := waitDeliveries(t, s, disabled.ID)[0]; d.Status != database.WebhookDeliveryFailed || d.Attempts != 1 {
+		t.Errorf("disabled delivery = %+v, want failed without another attempt", d)
+	}
+}
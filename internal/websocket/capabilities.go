@@ -0,0 +1,157 @@
+package websocket
+
+import (
+	"sort"
+	"time"
+)
+
+// 能力握手阶段
+const (
+	CapabilityStageConnect   = "connect"    // WebSocket 连接建立时
+	CapabilityStageAPILoaded = "api_loaded" // APILoaded 事件触发后
+	CapabilityStageSettled   = "settled"    // 页面加载完成一段时间后（最终结果）
+)
+
+// ChannelAPIRequirements 每个 key:channels:* 调用依赖的前端函数（对应 api_client.js 中的实现）
+var ChannelAPIRequirements = map[string][]string{
+	"key:channels:contact_list": {"API2.finderSearch"},
+	"key:channels:feed_list":    {"API.finderUserPage"},
+	"key:channels:feed_profile": {"API.finderGetCommentDetail", "API.decodeBase64ToUint64String"},
+}
+
+// RequiredHooks 注入脚本正常工作必须安装的钩子
+var RequiredHooks = []string{"eventbus", "utils", "api_loaded"}
+
+// CapabilityReport 注入脚本上报的能力握手
+type CapabilityReport struct {
+	Stage string          `json:"stage"`
+	Page  string          `json:"page"`  // 页面路径，如 /web/pages/feed
+	URL   string          `json:"url"`   // 完整地址
+	Hooks map[string]bool `json:"hooks"` // 钩子名 -> 是否已安装
+	APIs  []string        `json:"apis"`  // 找到的前端函数，如 API.finderUserPage、API2.finderSearch
+}
+
+// ClientCapabilities 单个页面（客户端）的能力状态
+type ClientCapabilities struct {
+	ClientAddr   string          `json:"client_addr"`
+	Stage        string          `json:"stage"`
+	Page         string          `json:"page"`
+	URL          string          `json:"url"`
+	Hooks        map[string]bool `json:"hooks"`
+	APIs         map[string]bool `json:"apis"`     // 只包含 ChannelAPIRequirements 中用到的函数
+	Callable     map[string]bool `json:"callable"` // key:channels:* -> 是否可调用
+	MissingHooks []string        `json:"missing_hooks"`
+	MissingAPIs  []string        `json:"missing_apis"` // 不可调用的 key:channels:*
+	Degraded     bool            `json:"degraded"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+}
+
+// CapabilityStatus 所有页面的能力汇总
+type CapabilityStatus struct {
+	Degraded bool                 `json:"degraded"`
+	Reported bool                 `json:"reported"` // 是否有页面完成过握手
+	Callable map[string]bool      `json:"callable"` // 任一页面可调用即为 true
+	Pages    []ClientCapabilities `json:"pages"`
+}
+
+// CapabilityRegression 能力退化事件
+type CapabilityRegression struct {
+	Page         string    `json:"page"`
+	URL          string    `json:"url"`
+	ClientAddr   string    `json:"client_addr"`
+	LostHooks    []string  `json:"lost_hooks"`
+	LostAPIs     []string  `json:"lost_apis"`
+	MissingHooks []string  `json:"missing_hooks"`
+	MissingAPIs  []string  `json:"missing_apis"`
+	DetectedAt   time.Time `json:"detected_at"`
+}
+
+// EvaluateCapabilities 根据上报内容计算可调用的 API 和缺失项。
+// 只有 settled 阶段的结果才会被判定为降级，之前的阶段脚本可能仍在初始化。
+func EvaluateCapabilities(clientAddr string, report CapabilityReport) ClientCapabilities {
+	caps := ClientCapabilities{
+		ClientAddr:   clientAddr,
+		Stage:        report.Stage,
+		Page:         report.Page,
+		URL:          report.URL,
+		Hooks:        report.Hooks,
+		APIs:         make(map[string]bool),
+		Callable:     make(map[string]bool, len(ChannelAPIRequirements)),
+		MissingHooks: []string{},
+		MissingAPIs:  []string{},
+		UpdatedAt:    time.Now(),
+	}
+	if caps.Hooks == nil {
+		caps.Hooks = map[string]bool{}
+	}
+	found := make(map[string]bool, len(report.APIs))
+	for _, fn := range report.APIs {
+		found[fn] = true
+	}
+
+	for _, hook := range RequiredHooks {
+		if !caps.Hooks[hook] {
+			caps.MissingHooks = append(caps.MissingHooks, hook)
+		}
+	}
+	for key, funcs := range ChannelAPIRequirements {
+		callable := true
+		for _, fn := range funcs {
+			caps.APIs[fn] = found[fn]
+			if !found[fn] {
+				callable = false
+			}
+		}
+		caps.Callable[key] = callable
+		if !callable {
+			caps.MissingAPIs = append(caps.MissingAPIs, key)
+		}
+	}
+	sort.Strings(caps.MissingAPIs)
+
+	caps.Degraded = report.Stage == CapabilityStageSettled &&
+		(len(caps.MissingHooks) > 0 || len(caps.MissingAPIs) > 0)
+	return caps
+}
+
+// newCapabilityRegression 比较同一页面上一次的缺失项，返回新增缺失的部分。
+// 没有新增缺失时返回 nil。
+func newCapabilityRegression(prev *ClientCapabilities, cur ClientCapabilities) *CapabilityRegression {
+	if !cur.Degraded {
+		return nil
+	}
+	var prevHooks, prevAPIs []string
+	if prev != nil && prev.Degraded {
+		prevHooks, prevAPIs = prev.MissingHooks, prev.MissingAPIs
+	}
+	lostHooks := subtractStrings(cur.MissingHooks, prevHooks)
+	lostAPIs := subtractStrings(cur.MissingAPIs, prevAPIs)
+	if len(lostHooks) == 0 && len(lostAPIs) == 0 {
+		return nil
+	}
+	return &CapabilityRegression{
+		Page:         cur.Page,
+		URL:          cur.URL,
+		ClientAddr:   cur.ClientAddr,
+		LostHooks:    lostHooks,
+		LostAPIs:     lostAPIs,
+		MissingHooks: cur.MissingHooks,
+		MissingAPIs:  cur.MissingAPIs,
+		DetectedAt:   cur.UpdatedAt,
+	}
+}
+
+// subtractStrings 返回 a 中不在 b 中的元素
+func subtractStrings(a, b []string) []string {
+	exclude := make(map[string]bool, len(b))
+	for _, s := range b {
+		exclude[s] = true
+	}
+	result := []string{}
+	for _, s := range a {
+		if !exclude[s] {
+			result = append(result, s)
+		}
+	}
+	return result
+}
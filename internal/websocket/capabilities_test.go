@@ -0,0 +1,131 @@
+package websocket
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wx_channel/internal/utils"
+)
+
+// TestMain 把日志写到临时目录，避免在源码目录下生成 logs/wx_channel.log
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "wx_channel_websocket_test")
+	if err == nil {
+		err = utils.InitLoggerWithRotation(utils.INFO, filepath.Join(dir, "wx_channel.log"), 5)
+	}
+	if err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// 完整的能力上报
+func fullCapabilityReport(stage string) CapabilityReport {
+	return CapabilityReport{
+		Stage: stage,
+		Page:  "/web/pages/feed",
+		URL:   "https://channels.weixin.qq.com/web/pages/feed",
+		Hooks: map[string]bool{"eventbus": true, "utils": true, "api_loaded": true},
+		APIs: []string{
+			"API.finderUserPage", "API.finderGetCommentDetail",
+			"API.decodeBase64ToUint64String", "API2.finderSearch",
+		},
+	}
+}
+
+// TestEvaluateCapabilities 测试可调用 API 的计算
+func TestEvaluateCapabilities(t *testing.T) {
+	caps := EvaluateCapabilities("127.0.0.1", fullCapabilityReport(CapabilityStageSettled))
+	if caps.Degraded {
+		t.Errorf("Expected full report not to be degraded, missing=%v %v", caps.MissingHooks, caps.MissingAPIs)
+	}
+	for key := range ChannelAPIRequirements {
+		if !caps.Callable[key] {
+			t.Errorf("Expected %s to be callable", key)
+		}
+	}
+
+	report := fullCapabilityReport(CapabilityStageSettled)
+	report.APIs = []string{"API.finderUserPage", "API2.finderSearch"}
+	report.Hooks["api_loaded"] = false
+	caps = EvaluateCapabilities("127.0.0.1", report)
+	if !caps.Degraded {
+		t.Fatal("Expected report to be degraded")
+	}
+	if caps.Callable["key:channels:feed_profile"] || !caps.Callable["key:channels:feed_list"] {
+		t.Errorf("Unexpected callable map: %v", caps.Callable)
+	}
+	if len(caps.MissingAPIs) != 1 || caps.MissingAPIs[0] != "key:channels:feed_profile" {
+		t.Errorf("Expected feed_profile to be missing, got %v", caps.MissingAPIs)
+	}
+	if len(caps.MissingHooks) != 1 || caps.MissingHooks[0] != "api_loaded" {
+		t.Errorf("Expected api_loaded hook to be missing, got %v", caps.MissingHooks)
+	}
+
+	// 初始化阶段缺失不算降级
+	report.Stage = CapabilityStageConnect
+	if caps = EvaluateCapabilities("127.0.0.1", report); caps.Degraded {
+		t.Error("Expected connect stage not to be degraded")
+	}
+}
+
+// TestCapabilityRegression 测试能力退化通知只在新增缺失时触发
+func TestCapabilityRegression(t *testing.T) {
+	hub := NewHub()
+	regressions := make(chan CapabilityRegression, 4)
+	hub.SetCapabilityRegressionHandler(func(r CapabilityRegression) {
+		regressions <- r
+	})
+	client := createTestClient("client1", 0)
+	client.RemoteAddr = "127.0.0.1"
+
+	expectRegression := func(lost string) {
+		t.Helper()
+		select {
+		case r := <-regressions:
+			if len(r.LostAPIs) != 1 || r.LostAPIs[0] != lost {
+				t.Errorf("Expected lost %s, got %v", lost, r.LostAPIs)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected regression for %s", lost)
+		}
+	}
+	expectNone := func() {
+		t.Helper()
+		select {
+		case r := <-regressions:
+			t.Errorf("Unexpected regression: %+v", r)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	hub.handleCapabilities(client, fullCapabilityReport(CapabilityStageSettled))
+	expectNone()
+	if status := hub.CapabilityStatus(); status.Degraded || !status.Reported || len(status.Pages) != 1 {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+
+	// 微信更新后 finderGetCommentDetail 消失
+	report := fullCapabilityReport(CapabilityStageSettled)
+	report.APIs = []string{"API.finderUserPage", "API.decodeBase64ToUint64String", "API2.finderSearch"}
+	hub.handleCapabilities(client, report)
+	expectRegression("key:channels:feed_profile")
+
+	// 重复上报相同结果不再通知
+	hub.handleCapabilities(client, report)
+	expectNone()
+
+	// 进一步退化只通知新增部分
+	report.APIs = []string{"API.decodeBase64ToUint64String", "API2.finderSearch"}
+	hub.handleCapabilities(client, report)
+	expectRegression("key:channels:feed_list")
+
+	status := hub.CapabilityStatus()
+	if !status.Degraded || status.Callable["key:channels:feed_list"] || !status.Callable["key:channels:contact_list"] {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+}
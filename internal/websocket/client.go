@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -27,10 +28,19 @@ type Client struct {
 	activeRequests int32 // 活跃请求数（原子操作）
 }
 
+// clientSeq 客户端序号，用于生成唯一的客户端 ID
+var clientSeq uint64
+
+// newClientID 生成客户端 ID：远程地址加序号，同一地址的多个页面也能区分
+func newClientID(remoteAddr string) string {
+	return fmt.Sprintf("%s#%d", remoteAddr, atomic.AddUint64(&clientSeq, 1))
+}
+
 // NewClient 创建新的客户端
 func NewClient(conn *websocket.Conn, hub *Hub) *Client {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Client{
+		ID:         newClientID("unknown"),
 		Conn:       conn,
 		RemoteAddr: "unknown",
 		send:       make(chan []byte, 256),
@@ -45,6 +55,7 @@ func NewClient(conn *websocket.Conn, hub *Hub) *Client {
 func NewClientWithAddr(conn *websocket.Conn, hub *Hub, remoteAddr string) *Client {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Client{
+		ID:         newClientID(remoteAddr),
 		Conn:       conn,
 		RemoteAddr: remoteAddr,
 		send:       make(chan []byte, 256),
@@ -119,6 +130,16 @@ func (c *Client) ReadPump() {
 				c.hub.handleAPIResponse(resp)
 			}(msg)
 		}
+
+		// 处理能力握手
+		if msg.Type == WSMessageTypeCapabilities {
+			var report CapabilityReport
+			if err := json.Unmarshal(msg.Data, &report); err != nil {
+				utils.LogError("能力握手解析失败: %v", err)
+				continue
+			}
+			c.hub.handleCapabilities(c, report)
+		}
 	}
 }
 
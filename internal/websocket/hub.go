@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -26,17 +27,45 @@ type Hub struct {
 
 	// 负载均衡选择器
 	selector ClientSelector
+
+	// 分页缓存
+	pager *Pager
+
+	// 页面 API 节流
+	limiter *RateLimiter
+
+	// 能力握手
+	capabilities     map[*Client]*ClientCapabilities
+	pageCapabilities map[string]*ClientCapabilities // 页面路径 -> 最近一次 settled 结果，用于判断退化
+	capMu            sync.RWMutex
+	onRegression     func(CapabilityRegression)
 }
 
 // NewHub 创建新的 Hub
 func NewHub() *Hub {
-	return &Hub{
+	h := &Hub{
 		clients:    make(map[*Client]bool),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		requests:   make(map[string]chan APICallResponse),
 		selector:   NewLeastConnectionSelector(), // 默认使用最少连接选择器
+
+		capabilities:     make(map[*Client]*ClientCapabilities),
+		pageCapabilities: make(map[string]*ClientCapabilities),
 	}
+	h.pager = NewPager(h, DefaultPageCacheTTL)
+	h.limiter = NewRateLimiter(DefaultRateLimitConfig())
+	return h
+}
+
+// Pager 返回带分页缓存的调用器
+func (h *Hub) Pager() *Pager {
+	return h.pager
+}
+
+// RateLimiter 返回页面 API 节流器
+func (h *Hub) RateLimiter() *RateLimiter {
+	return h.limiter
 }
 
 // Run 启动 Hub
@@ -68,6 +97,12 @@ func (h *Hub) Run() {
 				utils.LogInfo("WebSocket 客户端已断开: %s", addr)
 			}
 			h.mu.Unlock()
+
+			h.capMu.Lock()
+			delete(h.capabilities, client)
+			h.capMu.Unlock()
+
+			h.limiter.RemoveClient(client)
 		}
 	}
 }
@@ -119,11 +154,24 @@ func (h *Hub) ClientCount() int {
 
 // CallAPI 调用前端 API
 func (h *Hub) CallAPI(key string, body interface{}, timeout time.Duration) (json.RawMessage, error) {
+	deadline := time.Now().Add(timeout)
+
+	// 节流：同一接口的调用按间隔排队，超时前排不上则直接失败
+	if err := h.limiter.Wait(key, timeout); err != nil {
+		utils.LogWarn("API 调用被节流: Key=%s, Error=%v", key, err)
+		return nil, err
+	}
+
 	client, err := h.GetClient()
 	if err != nil {
 		return nil, err
 	}
 
+	if err := h.limiter.Acquire(client, time.Until(deadline)); err != nil {
+		return nil, err
+	}
+	defer h.limiter.Release(client)
+
 	// 增加活跃请求计数
 	client.IncrementActiveRequests()
 	defer client.DecrementActiveRequests()
@@ -189,6 +237,14 @@ func (h *Hub) CallAPI(key string, body interface{}, timeout time.Duration) (json
 		}
 		
 		duration := time.Since(startTime)
+		// 页面或微信返回错误码时退避，避免持续触发风控
+		if resp.ErrCode != 0 {
+			h.limiter.Report(key, true, resp.ErrMsg)
+		} else if code := responseErrCode(resp.Data); code != 0 {
+			h.limiter.Report(key, true, fmt.Sprintf("errCode=%d", code))
+		} else {
+			h.limiter.Report(key, false, "")
+		}
 		if resp.ErrCode != 0 {
 			utils.LogError("API 调用失败: ID=%s, Duration=%v, ErrCode=%d, ErrMsg=%s", 
 				reqID, duration, resp.ErrCode, resp.ErrMsg)
@@ -199,7 +255,7 @@ func (h *Hub) CallAPI(key string, body interface{}, timeout time.Duration) (json
 			reqID, duration, len(resp.Data))
 		return resp.Data, nil
 		
-	case <-time.After(timeout):
+	case <-time.After(time.Until(deadline)):
 		utils.LogError("API 调用超时: ID=%s, Timeout=%v", reqID, timeout)
 		return nil, fmt.Errorf("request timeout after %v", timeout)
 	}
@@ -281,3 +337,74 @@ func (h *Hub) Broadcast(message interface{}) error {
 
 	return nil
 }
+
+// SetCapabilityRegressionHandler 设置能力退化回调（在独立 goroutine 中调用）
+func (h *Hub) SetCapabilityRegressionHandler(fn func(CapabilityRegression)) {
+	h.capMu.Lock()
+	defer h.capMu.Unlock()
+	h.onRegression = fn
+}
+
+// handleCapabilities 处理注入脚本上报的能力握手
+func (h *Hub) handleCapabilities(client *Client, report CapabilityReport) {
+	caps := EvaluateCapabilities(client.RemoteAddr, report)
+
+	h.capMu.Lock()
+	h.capabilities[client] = &caps
+	var regression *CapabilityRegression
+	if caps.Stage == CapabilityStageSettled {
+		regression = newCapabilityRegression(h.pageCapabilities[caps.Page], caps)
+		h.pageCapabilities[caps.Page] = &caps
+	}
+	onRegression := h.onRegression
+	h.capMu.Unlock()
+
+	if caps.Degraded {
+		utils.LogWarn("注入脚本能力降级: page=%s, 缺失钩子=%v, 不可用 API=%v", caps.Page, caps.MissingHooks, caps.MissingAPIs)
+	} else {
+		utils.LogInfo("注入脚本能力握手: page=%s, stage=%s, callable=%v", caps.Page, caps.Stage, caps.Callable)
+	}
+
+	if regression != nil && onRegression != nil {
+		go func(r CapabilityRegression) {
+			defer func() {
+				if rec := recover(); rec != nil {
+					utils.LogError("能力退化回调 panic: %v", rec)
+				}
+			}()
+			onRegression(r)
+		}(*regression)
+	}
+}
+
+// CapabilityStatus 返回当前所有已连接页面的能力汇总
+func (h *Hub) CapabilityStatus() CapabilityStatus {
+	h.capMu.RLock()
+	defer h.capMu.RUnlock()
+
+	status := CapabilityStatus{
+		Callable: make(map[string]bool, len(ChannelAPIRequirements)),
+		Pages:    []ClientCapabilities{},
+	}
+	for key := range ChannelAPIRequirements {
+		status.Callable[key] = false
+	}
+	for _, caps := range h.capabilities {
+		status.Pages = append(status.Pages, *caps)
+		if caps.Stage == CapabilityStageSettled {
+			status.Reported = true
+		}
+		if caps.Degraded {
+			status.Degraded = true
+		}
+		for key, ok := range caps.Callable {
+			if ok {
+				status.Callable[key] = true
+			}
+		}
+	}
+	sort.Slice(status.Pages, func(i, j int) bool {
+		return status.Pages[i].UpdatedAt.After(status.Pages[j].UpdatedAt)
+	})
+	return status
+}
@@ -0,0 +1,320 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"wx_channel/internal/metrics"
+)
+
+// APIKeyFeedProfile 视频详情接口
+const APIKeyFeedProfile = "key:channels:feed_profile"
+
+const (
+	DefaultRateInterval           = 2 * time.Second
+	DefaultRateJitter             = 500 * time.Millisecond
+	DefaultMaxConcurrentPerClient = 2
+	DefaultMaxBackoff             = time.Minute
+)
+
+// defaultRateIntervals 各接口两次调用之间的最小间隔
+var defaultRateIntervals = map[string]time.Duration{
+	APIKeyContactList: DefaultRateInterval,
+	APIKeyFeedList:    DefaultRateInterval,
+	APIKeyFeedProfile: time.Second,
+}
+
+// RateLimitConfig 调用页面 API 的节流配置
+type RateLimitConfig struct {
+	Intervals              map[string]time.Duration // 每个接口的最小间隔，未配置的接口不节流
+	Jitter                 time.Duration            // 在间隔上随机增加 [0, Jitter)
+	MaxConcurrentPerClient int                      // 每个页面同时处理的请求数，<= 0 不限制
+	MaxBackoff             time.Duration            // 连续出错时退避的上限
+}
+
+// DefaultRateLimitConfig 返回默认的节流配置
+func DefaultRateLimitConfig() RateLimitConfig {
+	intervals := make(map[string]time.Duration, len(defaultRateIntervals))
+	for k, v := range defaultRateIntervals {
+		intervals[k] = v
+	}
+	return RateLimitConfig{
+		Intervals:              intervals,
+		Jitter:                 DefaultRateJitter,
+		MaxConcurrentPerClient: DefaultMaxConcurrentPerClient,
+		MaxBackoff:             DefaultMaxBackoff,
+	}
+}
+
+// keyLimiter 单个接口的节流状态
+type keyLimiter struct {
+	interval   time.Duration
+	next       time.Time     // 下一次调用最早可以开始的时间
+	backoff    time.Duration // 当前退避时间
+	errors     int           // 连续出错次数
+	waiting    int
+	calls      int64
+	throttled  int64 // 需要等待的调用次数
+	lastErrMsg string
+}
+
+// KeyLimitState 单个接口的节流状态快照
+type KeyLimitState struct {
+	Key               string `json:"key"`
+	IntervalMs        int64  `json:"interval_ms"`
+	BackoffMs         int64  `json:"backoff_ms"`
+	ConsecutiveErrors int    `json:"consecutive_errors"`
+	Waiting           int    `json:"waiting"`
+	Calls             int64  `json:"calls"`
+	Throttled         int64  `json:"throttled"`
+	NextInMs          int64  `json:"next_in_ms"`
+	LastError         string `json:"last_error,omitempty"`
+}
+
+// RateLimiterState 节流器状态快照
+type RateLimiterState struct {
+	Keys                   []KeyLimitState `json:"keys"`
+	MaxConcurrentPerClient int             `json:"max_concurrent_per_client"`
+	ClientInflight         map[string]int  `json:"client_inflight"`
+}
+
+// RateLimiter 对调用页面 API 进行集中节流：按接口限制调用间隔（带随机抖动），
+// 限制每个页面的并发数，页面返回错误码时按接口指数退避，成功后恢复。
+type RateLimiter struct {
+	mu       sync.Mutex
+	cfg      RateLimitConfig
+	keys     map[string]*keyLimiter
+	inflight map[*Client]int
+	released *sync.Cond
+	rand     *rand.Rand
+}
+
+// NewRateLimiter 创建节流器
+func NewRateLimiter(cfg RateLimitConfig) *RateLimiter {
+	l := &RateLimiter{
+		keys:     make(map[string]*keyLimiter),
+		inflight: make(map[*Client]int),
+		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	l.released = sync.NewCond(&l.mu)
+	l.SetConfig(cfg)
+	return l
+}
+
+// SetConfig 更新节流配置，已有的退避状态保留
+func (l *RateLimiter) SetConfig(cfg RateLimitConfig) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = DefaultMaxBackoff
+	}
+	l.cfg = cfg
+	for key, k := range l.keys {
+		k.interval = cfg.Intervals[key]
+	}
+	l.released.Broadcast()
+}
+
+// Wait 等待直到可以调用 key。等待时间超过 timeout 时不占用名额并返回错误
+func (l *RateLimiter) Wait(key string, timeout time.Duration) error {
+	l.mu.Lock()
+	k := l.keyLocked(key)
+	if k.interval <= 0 && k.backoff <= 0 {
+		k.calls++
+		l.mu.Unlock()
+		return nil
+	}
+
+	now := time.Now()
+	start := k.next
+	if start.Before(now) {
+		start = now
+	}
+	delay := start.Sub(now)
+	if delay > timeout {
+		l.mu.Unlock()
+		return fmt.Errorf("rate limited: %s available in %v", key, delay.Round(time.Millisecond))
+	}
+
+	// 预约时间片：后来的调用排在后面
+	gap := k.interval + k.backoff
+	if l.cfg.Jitter > 0 {
+		gap += time.Duration(l.rand.Int63n(int64(l.cfg.Jitter)))
+	}
+	k.next = start.Add(gap)
+	k.calls++
+	if delay > 0 {
+		k.throttled++
+		k.waiting++
+	}
+	l.mu.Unlock()
+	l.updateMetrics(key)
+
+	if delay > 0 {
+		metrics.RateLimitThrottledTotal.WithLabelValues(key).Inc()
+		time.Sleep(delay)
+		l.mu.Lock()
+		k.waiting--
+		l.mu.Unlock()
+		l.updateMetrics(key)
+	}
+	return nil
+}
+
+// Acquire 占用页面的一个并发名额，超过 timeout 仍无名额时返回错误
+func (l *RateLimiter) Acquire(client *Client, timeout time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.cfg.MaxConcurrentPerClient > 0 && l.inflight[client] >= l.cfg.MaxConcurrentPerClient {
+		deadline := time.Now().Add(timeout)
+		// sync.Cond 不支持超时，到期后唤醒一次让等待方检查截止时间
+		timer := time.AfterFunc(timeout, func() {
+			l.mu.Lock()
+			l.released.Broadcast()
+			l.mu.Unlock()
+		})
+		defer timer.Stop()
+
+		for l.cfg.MaxConcurrentPerClient > 0 && l.inflight[client] >= l.cfg.MaxConcurrentPerClient {
+			if !time.Now().Before(deadline) {
+				return fmt.Errorf("client busy: %d requests in flight", l.inflight[client])
+			}
+			l.released.Wait()
+		}
+	}
+
+	l.inflight[client]++
+	metrics.RateLimitClientInflight.WithLabelValues(client.ID).Set(float64(l.inflight[client]))
+	return nil
+}
+
+// Release 释放 Acquire 占用的名额
+func (l *RateLimiter) Release(client *Client) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// 页面已断开时 RemoveClient 已清理计数和指标，不再重新创建
+	if _, ok := l.inflight[client]; !ok {
+		l.released.Broadcast()
+		return
+	}
+	if l.inflight[client] > 0 {
+		l.inflight[client]--
+	}
+	metrics.RateLimitClientInflight.WithLabelValues(client.ID).Set(float64(l.inflight[client]))
+	if l.inflight[client] == 0 {
+		delete(l.inflight, client)
+	}
+	l.released.Broadcast()
+}
+
+// RemoveClient 页面断开时清理其并发计数和 client_inflight 指标
+func (l *RateLimiter) RemoveClient(client *Client) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.inflight, client)
+	metrics.RateLimitClientInflight.DeleteLabelValues(client.ID)
+	l.released.Broadcast()
+}
+
+// Report 记录一次调用的结果：出错时加倍退避（从接口间隔开始，不超过上限），成功后清零
+func (l *RateLimiter) Report(key string, failed bool, errMsg string) {
+	l.mu.Lock()
+	k := l.keyLocked(key)
+	if failed {
+		k.errors++
+		k.lastErrMsg = errMsg
+		base := k.interval
+		if base <= 0 {
+			base = time.Second
+		}
+		if k.backoff <= 0 {
+			k.backoff = base
+		} else {
+			k.backoff *= 2
+		}
+		if k.backoff > l.cfg.MaxBackoff {
+			k.backoff = l.cfg.MaxBackoff
+		}
+		// 退避立即生效，而不是等到已预约的下一次调用之后
+		if next := time.Now().Add(k.backoff); next.After(k.next) {
+			k.next = next
+		}
+	} else {
+		k.errors = 0
+		k.backoff = 0
+		k.lastErrMsg = ""
+	}
+	l.mu.Unlock()
+	l.updateMetrics(key)
+}
+
+// State 返回节流器状态
+func (l *RateLimiter) State() RateLimiterState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	state := RateLimiterState{
+		Keys:                   []KeyLimitState{},
+		MaxConcurrentPerClient: l.cfg.MaxConcurrentPerClient,
+		ClientInflight:         make(map[string]int),
+	}
+	for key, k := range l.keys {
+		s := KeyLimitState{
+			Key:               key,
+			IntervalMs:        k.interval.Milliseconds(),
+			BackoffMs:         k.backoff.Milliseconds(),
+			ConsecutiveErrors: k.errors,
+			Waiting:           k.waiting,
+			Calls:             k.calls,
+			Throttled:         k.throttled,
+			LastError:         k.lastErrMsg,
+		}
+		if k.next.After(now) {
+			s.NextInMs = k.next.Sub(now).Milliseconds()
+		}
+		state.Keys = append(state.Keys, s)
+	}
+	sort.Slice(state.Keys, func(i, j int) bool { return state.Keys[i].Key < state.Keys[j].Key })
+	for client, n := range l.inflight {
+		state.ClientInflight[client.ID] = n
+	}
+	return state
+}
+
+func (l *RateLimiter) keyLocked(key string) *keyLimiter {
+	k, ok := l.keys[key]
+	if !ok {
+		k = &keyLimiter{interval: l.cfg.Intervals[key]}
+		l.keys[key] = k
+	}
+	return k
+}
+
+// updateMetrics 同步接口的节流状态到 Prometheus
+func (l *RateLimiter) updateMetrics(key string) {
+	l.mu.Lock()
+	k := l.keyLocked(key)
+	backoff, waiting, errors := k.backoff, k.waiting, k.errors
+	l.mu.Unlock()
+
+	metrics.RateLimitBackoffSeconds.WithLabelValues(key).Set(backoff.Seconds())
+	metrics.RateLimitWaiting.WithLabelValues(key).Set(float64(waiting))
+	metrics.RateLimitConsecutiveErrors.WithLabelValues(key).Set(float64(errors))
+}
+
+// responseErrCode 提取微信响应中的 errCode（没有时为 0）
+func responseErrCode(data json.RawMessage) int {
+	var resp struct {
+		ErrCode int `json:"errCode"`
+	}
+	if json.Unmarshal(data, &resp) != nil {
+		return 0
+	}
+	return resp.ErrCode
+}
@@ -0,0 +1,152 @@
+package websocket
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"wx_channel/internal/metrics"
+)
+
+// TestRateLimiterPacing 测试同一接口的调用按间隔排队
+func TestRateLimiterPacing(t *testing.T) {
+	l := NewRateLimiter(RateLimitConfig{Intervals: map[string]time.Duration{APIKeyFeedList: 30 * time.Millisecond}})
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(APIKeyFeedList, time.Second); err != nil {
+			t.Fatalf("Wait failed: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("Expected 3 calls to take at least 60ms, took %v", elapsed)
+	}
+
+	// 未配置间隔的接口不节流
+	start = time.Now()
+	for i := 0; i < 3; i++ {
+		l.Wait(APIKeyContactList, time.Second)
+	}
+	if elapsed := time.Since(start); elapsed > 20*time.Millisecond {
+		t.Errorf("Expected unthrottled key, took %v", elapsed)
+	}
+
+	// 排队时间超过超时时直接失败
+	l.Wait(APIKeyFeedList, time.Second)
+	if err := l.Wait(APIKeyFeedList, time.Millisecond); err == nil {
+		t.Error("Expected rate limited error when wait exceeds timeout")
+	}
+
+	state := l.State()
+	if len(state.Keys) != 2 || state.Keys[1].Key != APIKeyFeedList || state.Keys[1].Throttled < 2 {
+		t.Errorf("Unexpected state: %+v", state.Keys)
+	}
+}
+
+// TestRateLimiterBackoff 测试出错时指数退避、成功后恢复
+func TestRateLimiterBackoff(t *testing.T) {
+	l := NewRateLimiter(RateLimitConfig{
+		Intervals:  map[string]time.Duration{APIKeyFeedProfile: 10 * time.Millisecond},
+		MaxBackoff: 30 * time.Millisecond,
+	})
+
+	backoff := func() int64 {
+		return l.State().Keys[0].BackoffMs
+	}
+	l.Report(APIKeyFeedProfile, true, "errCode=-1")
+	if backoff() != 10 {
+		t.Errorf("Expected first backoff to equal interval, got %dms", backoff())
+	}
+	l.Report(APIKeyFeedProfile, true, "errCode=-1")
+	l.Report(APIKeyFeedProfile, true, "errCode=-1")
+	if backoff() != 30 {
+		t.Errorf("Expected backoff capped at 30ms, got %dms", backoff())
+	}
+	if s := l.State().Keys[0]; s.ConsecutiveErrors != 3 || s.LastError != "errCode=-1" {
+		t.Errorf("Unexpected state: %+v", s)
+	}
+
+	// 退避期间的调用需要等待
+	start := time.Now()
+	l.Wait(APIKeyFeedProfile, time.Second)
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Expected call to wait for backoff, took %v", elapsed)
+	}
+
+	l.Report(APIKeyFeedProfile, false, "")
+	if s := l.State().Keys[0]; s.BackoffMs != 0 || s.ConsecutiveErrors != 0 {
+		t.Errorf("Expected backoff reset after success, got %+v", s)
+	}
+}
+
+// TestRateLimiterClientConcurrency 测试每个页面的并发上限
+func TestRateLimiterClientConcurrency(t *testing.T) {
+	l := NewRateLimiter(RateLimitConfig{MaxConcurrentPerClient: 2})
+	client := createTestClient("c1", 0)
+	other := createTestClient("c2", 0)
+
+	if err := l.Acquire(client, time.Second); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if err := l.Acquire(client, time.Second); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if err := l.Acquire(client, 10*time.Millisecond); err == nil {
+		t.Fatal("Expected third acquire to time out")
+	}
+	// 其他页面不受影响
+	if err := l.Acquire(other, 10*time.Millisecond); err != nil {
+		t.Fatalf("Expected other client to acquire, got %v", err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	var waitErr error
+	go func() {
+		defer wg.Done()
+		waitErr = l.Acquire(client, time.Second)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	l.Release(client)
+	wg.Wait()
+	if waitErr != nil {
+		t.Errorf("Expected waiter to acquire after release, got %v", waitErr)
+	}
+	if n := l.State().ClientInflight["c1"]; n != 2 {
+		t.Errorf("Expected 2 in flight for c1, got %d", n)
+	}
+}
+
+// TestRateLimiterRemoveClient 测试同一地址的页面分开计数，断开后清理计数和指标
+func TestRateLimiterRemoveClient(t *testing.T) {
+	l := NewRateLimiter(RateLimitConfig{MaxConcurrentPerClient: 2})
+	a := NewClientWithAddr(nil, nil, "127.0.0.1:50000")
+	b := NewClientWithAddr(nil, nil, "127.0.0.1:50000")
+	if a.ID == "" || a.ID == b.ID {
+		t.Fatalf("Expected distinct client IDs, got %q and %q", a.ID, b.ID)
+	}
+
+	for _, c := range []*Client{a, a, b} {
+		if err := l.Acquire(c, time.Second); err != nil {
+			t.Fatalf("Acquire failed: %v", err)
+		}
+	}
+	inflight := l.State().ClientInflight
+	if inflight[a.ID] != 2 || inflight[b.ID] != 1 {
+		t.Fatalf("Expected 2 and 1 in flight, got %v", inflight)
+	}
+
+	l.RemoveClient(a)
+	if metrics.RateLimitClientInflight.DeleteLabelValues(a.ID) {
+		t.Error("Expected client_inflight series to be deleted on disconnect")
+	}
+	// 断开前发出的请求结束时不再重新创建计数
+	l.Release(a)
+	inflight = l.State().ClientInflight
+	if _, ok := inflight[a.ID]; ok {
+		t.Errorf("Expected removed client to be gone, got %v", inflight)
+	}
+	if inflight[b.ID] != 1 {
+		t.Errorf("Expected other client to keep 1 in flight, got %v", inflight)
+	}
+}
@@ -0,0 +1,345 @@
+package websocket
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"wx_channel/internal/utils"
+)
+
+const (
+	DefaultPageCacheTTL = 5 * time.Minute
+	pageCacheMaxEntries = 500
+	pageCallTimeout     = 60 * time.Second
+	// MaxPagesPerRequest 单次聚合最多请求的页数
+	MaxPagesPerRequest = 50
+)
+
+// 支持分页缓存的 API
+const (
+	APIKeyContactList = "key:channels:contact_list"
+	APIKeyFeedList    = "key:channels:feed_list"
+)
+
+var pageableAPIKeys = map[string]bool{
+	APIKeyContactList: true,
+	APIKeyFeedList:    true,
+}
+
+// ErrInvalidCursor 游标无法解析或与当前查询不匹配
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrPageErrCode 微信返回了非 0 的 errCode（如频率限制），响应里没有有效的分页数据
+var ErrPageErrCode = errors.New("page returned an error code")
+
+// PageQuery 分页查询条件
+type PageQuery struct {
+	Key    string // APIKeyContactList / APIKeyFeedList
+	Query  string // 搜索关键词或账号 username
+	Type   int    // 搜索类型（仅 contact_list）：1=账号 2=直播 3=视频
+	Marker string // 微信返回的 lastBuff / lastBuffer，首页为空
+}
+
+// PageCursor 对外暴露的不透明游标，编码了查询条件和微信的分页标记
+type PageCursor struct {
+	Key    string `json:"k"`
+	Query  string `json:"q"`
+	Type   int    `json:"t,omitempty"`
+	Marker string `json:"m"`
+}
+
+// Encode 编码为 URL 安全的字符串
+func (c PageCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodePageCursor 解析游标
+func DecodePageCursor(s string) (PageCursor, error) {
+	var cursor PageCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Key == "" || cursor.Marker == "" {
+		return cursor, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// CursorQuery 将游标应用到查询：游标必须属于同一接口和查询条件
+func (q PageQuery) CursorQuery(cursor string) (PageQuery, error) {
+	c, err := DecodePageCursor(cursor)
+	if err != nil {
+		return q, err
+	}
+	if c.Key != q.Key || c.Query != q.Query || c.Type != q.Type {
+		return q, ErrInvalidCursor
+	}
+	q.Marker = c.Marker
+	return q, nil
+}
+
+// PageResult 一页或多页聚合后的结果
+type PageResult struct {
+	Items      []json.RawMessage `json:"list"`
+	Cursor     string            `json:"cursor"`      // 下一页游标，没有更多时为空
+	NextMarker string            `json:"next_marker"` // 微信原始分页标记
+	HasMore    bool              `json:"has_more"`
+	Pages      int               `json:"pages"`
+	Cached     bool              `json:"cached"` // 所有页都来自缓存
+	Raw        json.RawMessage   `json:"-"`      // 最后一页的原始响应
+}
+
+type pageCacheEntry struct {
+	data      json.RawMessage
+	expiresAt time.Time
+	createdAt time.Time
+}
+
+// Pager 带 TTL 缓存的分页调用。同一查询的同一页在 TTL 内只请求一次微信，
+// HTTP API 和云端 Hub 都通过它遍历账号的视频列表。
+type Pager struct {
+	hub     *Hub
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]pageCacheEntry
+}
+
+// NewPager 创建分页器
+func NewPager(hub *Hub, ttl time.Duration) *Pager {
+	return &Pager{
+		hub:     hub,
+		ttl:     ttl,
+		entries: make(map[string]pageCacheEntry),
+	}
+}
+
+// SetTTL 设置缓存有效期，<= 0 时关闭缓存
+func (p *Pager) SetTTL(ttl time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ttl = ttl
+	if ttl <= 0 {
+		p.entries = make(map[string]pageCacheEntry)
+	}
+}
+
+// CallAPI 与 Hub.CallAPI 相同，但会缓存可分页接口的响应
+func (p *Pager) CallAPI(key string, body interface{}, timeout time.Duration) (json.RawMessage, error) {
+	data, _, err := p.call(key, body, timeout, false)
+	return data, err
+}
+
+// call 调用前端 API，返回数据以及是否命中缓存
+func (p *Pager) call(key string, body interface{}, timeout time.Duration, refresh bool) (json.RawMessage, bool, error) {
+	if !pageableAPIKeys[key] {
+		data, err := p.hub.CallAPI(key, body, timeout)
+		return data, false, err
+	}
+
+	cacheKey, err := pageCacheKey(key, body)
+	if err != nil {
+		return nil, false, err
+	}
+	if !refresh {
+		if data, ok := p.get(cacheKey); ok {
+			return data, true, nil
+		}
+	}
+
+	data, err := p.hub.CallAPI(key, body, timeout)
+	if err != nil {
+		return nil, false, err
+	}
+	// 错误响应不缓存，也不能当作空的最后一页，否则调用方会认为已经遍历完
+	if code := responseErrCode(data); code != 0 {
+		return nil, false, fmt.Errorf("%w (errCode=%d)", ErrPageErrCode, code)
+	}
+	p.put(cacheKey, data)
+	return data, false, nil
+}
+
+// FetchPages 从 q.Marker 开始连续获取最多 maxPages 页并合并列表
+func (p *Pager) FetchPages(q PageQuery, maxPages int, refresh bool) (*PageResult, error) {
+	if !pageableAPIKeys[q.Key] {
+		return nil, fmt.Errorf("unsupported paged API: %s", q.Key)
+	}
+	if maxPages <= 0 {
+		maxPages = 1
+	}
+	if maxPages > MaxPagesPerRequest {
+		maxPages = MaxPagesPerRequest
+	}
+
+	result := &PageResult{Items: []json.RawMessage{}, Cached: true}
+	marker := q.Marker
+	seen := map[string]bool{}
+	for result.Pages < maxPages {
+		data, cached, err := p.call(q.Key, q.body(marker), pageCallTimeout, refresh)
+		if err != nil {
+			if result.Pages == 0 {
+				return nil, err
+			}
+			// 已经拿到的页仍然返回，调用方可以用游标继续
+			utils.LogWarn("分页获取中断: key=%s, pages=%d, err=%v", q.Key, result.Pages, err)
+			break
+		}
+
+		items, next, hasMore, err := parsePage(q.Key, q.Type, data)
+		if err != nil {
+			return nil, err
+		}
+		seen[marker] = true
+		result.Items = append(result.Items, items...)
+		result.Pages++
+		result.Cached = result.Cached && cached
+		result.Raw = data
+		result.NextMarker = next
+		result.HasMore = hasMore && next != "" && !seen[next]
+		if !result.HasMore || len(items) == 0 {
+			result.HasMore = result.HasMore && len(items) > 0
+			break
+		}
+		marker = next
+	}
+
+	if result.HasMore {
+		result.Cursor = PageCursor{Key: q.Key, Query: q.Query, Type: q.Type, Marker: result.NextMarker}.Encode()
+	}
+	return result, nil
+}
+
+// body 构造前端 API 请求体
+func (q PageQuery) body(marker string) interface{} {
+	if q.Key == APIKeyContactList {
+		return SearchContactBody{Keyword: q.Query, Type: q.Type, NextMarker: marker}
+	}
+	return FeedListBody{Username: q.Query, NextMarker: marker}
+}
+
+// parsePage 从微信响应中提取列表、下一页标记和是否还有更多
+func parsePage(key string, searchType int, data json.RawMessage) ([]json.RawMessage, string, bool, error) {
+	var resp struct {
+		Data struct {
+			InfoList     []json.RawMessage `json:"infoList"`
+			ObjectList   []json.RawMessage `json:"objectList"`
+			Object       []json.RawMessage `json:"object"`
+			LastBuff     string            `json:"lastBuff"`
+			LastBuffer   string            `json:"lastBuffer"`
+			ContinueFlag *int              `json:"continueFlag"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, "", false, fmt.Errorf("failed to parse page: %w", err)
+	}
+
+	var items []json.RawMessage
+	var marker string
+	if key == APIKeyContactList {
+		// 找人使用 infoList，找直播/找视频使用 objectList
+		if searchType == 2 || searchType == 3 {
+			items = resp.Data.ObjectList
+		} else {
+			items = resp.Data.InfoList
+		}
+		marker = resp.Data.LastBuff
+	} else {
+		items = resp.Data.Object
+		marker = resp.Data.LastBuffer
+	}
+	if items == nil {
+		items = []json.RawMessage{}
+	}
+	// 两个接口的字段名不一致，缺失时尝试另一个
+	if marker == "" && key == APIKeyContactList {
+		marker = resp.Data.LastBuffer
+	} else if marker == "" {
+		marker = resp.Data.LastBuff
+	}
+
+	hasMore := marker != ""
+	if resp.Data.ContinueFlag != nil {
+		hasMore = hasMore && *resp.Data.ContinueFlag != 0
+	}
+	return items, marker, hasMore, nil
+}
+
+// pageCacheKey 使用规范化后的请求体作为缓存键
+func pageCacheKey(key string, body interface{}) (string, error) {
+	var raw []byte
+	switch b := body.(type) {
+	case json.RawMessage:
+		raw = b
+	default:
+		data, err := json.Marshal(body)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode request body: %w", err)
+		}
+		raw = data
+	}
+
+	var normalized interface{}
+	if err := json.Unmarshal(raw, &normalized); err != nil {
+		return "", fmt.Errorf("failed to decode request body: %w", err)
+	}
+	// request_id 每次都不同，不参与缓存键
+	if m, ok := normalized.(map[string]interface{}); ok {
+		delete(m, "request_id")
+	}
+	data, _ := json.Marshal(normalized)
+	return key + "|" + string(data), nil
+}
+
+func (p *Pager) get(cacheKey string) (json.RawMessage, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	entry, ok := p.entries[cacheKey]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(p.entries, cacheKey)
+		return nil, false
+	}
+	return entry.data, true
+}
+
+func (p *Pager) put(cacheKey string, data json.RawMessage) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ttl <= 0 {
+		return
+	}
+
+	now := time.Now()
+	if len(p.entries) >= pageCacheMaxEntries {
+		// 先清理过期项，仍然超出时淘汰最早写入的一项
+		var oldestKey string
+		var oldest time.Time
+		for k, e := range p.entries {
+			if now.After(e.expiresAt) {
+				delete(p.entries, k)
+				continue
+			}
+			if oldestKey == "" || e.createdAt.Before(oldest) {
+				oldestKey, oldest = k, e.createdAt
+			}
+		}
+		if len(p.entries) >= pageCacheMaxEntries {
+			delete(p.entries, oldestKey)
+		}
+	}
+	p.entries[cacheKey] = pageCacheEntry{data: data, expiresAt: now.Add(p.ttl), createdAt: now}
+}
+
+// CacheSize 返回当前缓存的页数
+func (p *Pager) CacheSize() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.entries)
+}
@@ -0,0 +1,162 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startFakePage 模拟注入脚本：按 next_marker 返回三页视频列表，账号 limited 返回频率限制错误码
+func startFakePage(t *testing.T, hub *Hub) *int32 {
+	t.Helper()
+	client := createTestClient("page", 0)
+	hub.clients[client] = true
+	// 分页测试不需要节流间隔
+	hub.RateLimiter().SetConfig(RateLimitConfig{})
+
+	var calls int32
+	go func() {
+		for msg := range client.send {
+			var wsMsg WSMessage
+			var req struct {
+				ID   string       `json:"id"`
+				Body FeedListBody `json:"body"`
+			}
+			if json.Unmarshal(msg, &wsMsg) != nil || json.Unmarshal(wsMsg.Data, &req) != nil {
+				continue
+			}
+			atomic.AddInt32(&calls, 1)
+
+			page := 0
+			fmt.Sscanf(req.Body.NextMarker, "m%d", &page)
+			next, cont := fmt.Sprintf("m%d", page+1), 1
+			if page == 2 {
+				next, cont = "", 0
+			}
+			if req.Body.Username == "limited" {
+				hub.handleAPIResponse(APICallResponse{ID: req.ID, Data: json.RawMessage(`{"errCode":-4011,"data":{}}`)})
+				continue
+			}
+			data, _ := json.Marshal(map[string]interface{}{
+				"errCode": 0,
+				"data": map[string]interface{}{
+					"object":       []map[string]string{{"id": fmt.Sprintf("v%d", page)}},
+					"lastBuffer":   next,
+					"continueFlag": cont,
+				},
+			})
+			hub.handleAPIResponse(APICallResponse{ID: req.ID, Data: data})
+		}
+	}()
+	t.Cleanup(func() { close(client.send) })
+	return &calls
+}
+
+// TestPagerFetchPages 测试多页聚合、游标和缓存
+func TestPagerFetchPages(t *testing.T) {
+	hub := NewHub()
+	calls := startFakePage(t, hub)
+	pager := hub.Pager()
+	query := PageQuery{Key: APIKeyFeedList, Query: "author"}
+
+	first, err := pager.FetchPages(query, 1, false)
+	if err != nil {
+		t.Fatalf("FetchPages failed: %v", err)
+	}
+	if len(first.Items) != 1 || !first.HasMore || first.Cursor == "" || first.NextMarker != "m1" {
+		t.Fatalf("Unexpected first page: %+v", first)
+	}
+
+	// 用游标继续获取剩余所有页
+	next, err := query.CursorQuery(first.Cursor)
+	if err != nil {
+		t.Fatalf("CursorQuery failed: %v", err)
+	}
+	rest, err := pager.FetchPages(next, 10, false)
+	if err != nil {
+		t.Fatalf("FetchPages failed: %v", err)
+	}
+	if rest.Pages != 2 || len(rest.Items) != 2 || rest.HasMore || rest.Cursor != "" {
+		t.Fatalf("Unexpected remaining pages: %+v", rest)
+	}
+	if atomic.LoadInt32(calls) != 3 {
+		t.Errorf("Expected 3 calls, got %d", atomic.LoadInt32(calls))
+	}
+
+	// 完整遍历全部来自缓存
+	all, err := pager.FetchPages(query, 10, false)
+	if err != nil {
+		t.Fatalf("FetchPages failed: %v", err)
+	}
+	if all.Pages != 3 || !all.Cached || atomic.LoadInt32(calls) != 3 {
+		t.Errorf("Expected cached walk of 3 pages, got pages=%d cached=%v calls=%d", all.Pages, all.Cached, atomic.LoadInt32(calls))
+	}
+
+	// refresh 跳过缓存
+	if _, err := pager.FetchPages(query, 1, true); err != nil {
+		t.Fatalf("FetchPages failed: %v", err)
+	}
+	if atomic.LoadInt32(calls) != 4 {
+		t.Errorf("Expected refresh to call the page, got %d calls", atomic.LoadInt32(calls))
+	}
+
+	// 缓存过期
+	pager.SetTTL(time.Millisecond)
+	pager.put("expired", json.RawMessage(`{}`))
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := pager.get("expired"); ok {
+		t.Error("Expected expired entry to be evicted")
+	}
+}
+
+// TestPagerErrCodeNotCached 测试微信返回错误码时报错且不缓存
+func TestPagerErrCodeNotCached(t *testing.T) {
+	hub := NewHub()
+	calls := startFakePage(t, hub)
+	pager := hub.Pager()
+	query := PageQuery{Key: APIKeyFeedList, Query: "limited"}
+
+	for i := 1; i <= 2; i++ {
+		result, err := pager.FetchPages(query, 1, false)
+		if !errors.Is(err, ErrPageErrCode) {
+			t.Fatalf("Expected ErrPageErrCode, got result=%+v err=%v", result, err)
+		}
+		if got := atomic.LoadInt32(calls); got != int32(i) {
+			t.Errorf("Expected %d calls, got %d", i, got)
+		}
+	}
+	if pager.CacheSize() != 0 {
+		t.Errorf("Expected error responses not to be cached, got %d entries", pager.CacheSize())
+	}
+}
+
+// TestPageCursorMismatch 测试游标与查询条件不匹配
+func TestPageCursorMismatch(t *testing.T) {
+	cursor := PageCursor{Key: APIKeyFeedList, Query: "a", Marker: "m1"}.Encode()
+	if _, err := (PageQuery{Key: APIKeyFeedList, Query: "b"}).CursorQuery(cursor); err != ErrInvalidCursor {
+		t.Errorf("Expected ErrInvalidCursor for another username, got %v", err)
+	}
+	if _, err := (PageQuery{Key: APIKeyFeedList, Query: "a"}).CursorQuery("not-a-cursor"); err != ErrInvalidCursor {
+		t.Errorf("Expected ErrInvalidCursor for garbage, got %v", err)
+	}
+	q, err := (PageQuery{Key: APIKeyFeedList, Query: "a"}).CursorQuery(cursor)
+	if err != nil || q.Marker != "m1" {
+		t.Errorf("Expected marker m1, got %q (%v)", q.Marker, err)
+	}
+}
+
+// TestPageCacheKeyIgnoresRequestID 测试缓存键忽略每次变化的 request_id
+func TestPageCacheKeyIgnoresRequestID(t *testing.T) {
+	b, _ := pageCacheKey(APIKeyContactList, SearchContactBody{Keyword: "x", RequestId: "2"})
+	c, _ := pageCacheKey(APIKeyContactList, json.RawMessage(`{ "type":0, "next_marker":"", "keyword":"x" }`))
+	if b != c {
+		t.Errorf("Expected equal cache keys, got %q and %q", b, c)
+	}
+	d, _ := pageCacheKey(APIKeyContactList, SearchContactBody{Keyword: "x", NextMarker: "m1"})
+	if b == d {
+		t.Errorf("Expected different cache keys for different pages")
+	}
+}
@@ -11,6 +11,8 @@ const (
 	WSMessageTypePing        WSMessageType = "ping"
 	WSMessageTypePong        WSMessageType = "pong"
 	WSMessageTypeCommand     WSMessageType = "cmd"
+	// 注入脚本上报的能力握手（已安装的钩子、已找到的 API）
+	WSMessageTypeCapabilities WSMessageType = "capabilities"
 )
 
 // WebSocket 消息
@@ -0,0 +1,155 @@
+// Package client 是本地 API 的 Go 客户端，接口与 /api/v1/openapi.json 描述的一致。
+//
+//	c := client.New("http://127.0.0.1:2025", client.WithToken("wxc_..."))
+//	page, err := c.ListDownloads(ctx, &client.DownloadFilter{Status: "completed"})
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL 本地服务的默认地址
+const DefaultBaseURL = "http://127.0.0.1:2025"
+
+// Client 本地 API 客户端，可并发使用
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// Option 客户端选项
+type Option func(*Client)
+
+// WithToken 设置认证令牌（secret_token 或 API 令牌），通过 X-Local-Auth 请求头发送
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithHTTPClient 使用自定义的 http.Client
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// New 创建客户端，baseURL 为空时使用 DefaultBaseURL
+func New(baseURL string, opts ...Option) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// APIError 接口返回的错误
+type APIError struct {
+	StatusCode int    // HTTP 状态码
+	Code       int    // /api/v1 接口的业务错误码，控制台接口为 0
+	Message    string // 错误信息
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("wx_channel api: %d %s", e.StatusCode, e.Message)
+}
+
+// NotConnected 判断错误是否因为没有已连接的微信客户端
+func (e *APIError) NotConnected() bool {
+	return e.StatusCode == http.StatusServiceUnavailable
+}
+
+// envelope 同时兼容控制台接口的 {success, data, error, message}
+// 和 /api/v1 接口的 {code, message, data} 两种响应格式
+type envelope struct {
+	Success *bool           `json:"success"`
+	Code    *int            `json:"code"`
+	Message string          `json:"message"`
+	Error   string          `json:"error"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// do 发送请求并把响应中的 data 解码到 out（out 为 nil 时忽略）
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("X-Local-Auth", c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var env envelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		if resp.StatusCode >= 400 {
+			return &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(raw))}
+		}
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	failed := resp.StatusCode >= 400 ||
+		(env.Success != nil && !*env.Success) ||
+		(env.Code != nil && *env.Code != 0)
+	if failed {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: env.Error}
+		if env.Code != nil {
+			apiErr.Code = *env.Code
+		}
+		if apiErr.Message == "" {
+			apiErr.Message = env.Message
+		}
+		return apiErr
+	}
+
+	if out == nil || len(env.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(env.Data, out); err != nil {
+		return fmt.Errorf("failed to decode response data: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,325 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"wx_channel/internal/api"
+)
+
+// openAPIOperation OpenAPI 文档中的一个操作，只解析参数
+type openAPIOperation struct {
+	Parameters []struct {
+		Name string `json:"name"`
+		In   string `json:"in"`
+	} `json:"parameters"`
+}
+
+// recordedRequest 测试服务器收到的请求
+type recordedRequest struct {
+	Method string
+	Path   string // 未解码的路径
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+}
+
+// newTestClient 启动测试服务器，respond 为空时按接口类型返回空的成功响应
+func newTestClient(t *testing.T, respond http.HandlerFunc) (*Client, func() []recordedRequest) {
+	t.Helper()
+	var (
+		mu       sync.Mutex
+		requests []recordedRequest
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, recordedRequest{
+			Method: r.Method,
+			Path:   r.URL.EscapedPath(),
+			Query:  r.URL.Query(),
+			Header: r.Header.Clone(),
+			Body:   body,
+		})
+		mu.Unlock()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		if respond != nil {
+			respond(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasPrefix(r.URL.Path, "/api/v1/") {
+			io.WriteString(w, `{"code":0,"message":"success","data":null}`)
+		} else {
+			io.WriteString(w, `{"success":true,"data":null}`)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	c := New(server.URL, WithToken("wxc_test"))
+	return c, func() []recordedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]recordedRequest(nil), requests...)
+	}
+}
+
+// matchSpecPath 返回与请求路径匹配的文档路径，{param} 匹配任意一段；优先精确匹配
+func matchSpecPath(paths map[string]map[string]openAPIOperation, path string) (string, bool) {
+	if _, ok := paths[path]; ok {
+		return path, true
+	}
+	segments := strings.Split(path, "/")
+	var candidates []string
+	for specPath := range paths {
+		specSegments := strings.Split(specPath, "/")
+		if len(specSegments) != len(segments) {
+			continue
+		}
+		matched := true
+		for i, s := range specSegments {
+			isParam := strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}")
+			if s != segments[i] && !(isParam && segments[i] != "") {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			candidates = append(candidates, specPath)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+	// 多个模板匹配时取参数最少的，例如 /api/queue/reorder 优先于 /api/queue/{id}
+	sort.Slice(candidates, func(i, j int) bool {
+		return strings.Count(candidates[i], "{") < strings.Count(candidates[j], "{")
+	})
+	return candidates[0], true
+}
+
+// TestClientCallsMatchOpenAPISpec 检查客户端的每个方法请求的路径、方法和查询参数都在 OpenAPI 文档中
+func TestClientCallsMatchOpenAPISpec(t *testing.T) {
+	var doc struct {
+		Paths map[string]map[string]openAPIOperation `json:"paths"`
+	}
+	if err := json.Unmarshal(api.OpenAPISpec, &doc); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+
+	c, requests := newTestClient(t, nil)
+	ctx := context.Background()
+	opts := &ListOptions{Page: 2, PageSize: 10, SortBy: "title", SortDesc: true}
+
+	// 新增客户端方法时需要在这里调用一次
+	calls := map[string]func() error{
+		"ListQueue":       func() error { _, err := c.ListQueue(ctx); return err },
+		"AddToQueue":      func() error { _, err := c.AddToQueue(ctx, VideoInfo{VideoID: "v1"}); return err },
+		"PauseQueueItem":  func() error { return c.PauseQueueItem(ctx, "q1") },
+		"ResumeQueueItem": func() error { return c.ResumeQueueItem(ctx, "q1") },
+		"RemoveFromQueue": func() error { return c.RemoveFromQueue(ctx, "q1") },
+		"ReorderQueue":    func() error { return c.ReorderQueue(ctx, "q1", "q2") },
+		"ListBrowse":      func() error { _, err := c.ListBrowse(ctx, "keyword", opts); return err },
+		"GetBrowse":       func() error { _, err := c.GetBrowse(ctx, "b1"); return err },
+		"GetBrowseFormats": func() error {
+			_, err := c.GetBrowseFormats(ctx, "b1", "best")
+			return err
+		},
+		"DeleteBrowse": func() error { _, err := c.DeleteBrowse(ctx, "b1"); return err },
+		"ClearBrowse":  func() error { return c.ClearBrowse(ctx) },
+		"ListDownloads": func() error {
+			_, err := c.ListDownloads(ctx, &DownloadFilter{
+				ListOptions: *opts, StartDate: "2026-01-01", EndDate: "2026-12-31", Status: "completed",
+				Query: "keyword", MinResolution: 720, VideoCodec: "h264",
+			})
+			return err
+		},
+		"GetDownload":     func() error { _, err := c.GetDownload(ctx, "d1"); return err },
+		"ProbeDownload":   func() error { _, err := c.ProbeDownload(ctx, "d1"); return err },
+		"DeleteDownloads": func() error { _, err := c.DeleteDownloads(ctx, true, "d1"); return err },
+		"ClearDownloads":  func() error { return c.ClearDownloads(ctx, true) },
+		"Search":          func() error { _, err := c.Search(ctx, "keyword", 5); return err },
+		"SearchContact": func() error {
+			_, err := c.SearchContact(ctx, &SearchContactRequest{Keyword: "k", Type: 1, Cursor: "c", MaxPages: 2, Refresh: true})
+			return err
+		},
+		"GetFeedList": func() error {
+			_, err := c.GetFeedList(ctx, &FeedListRequest{Username: "u", Cursor: "c", MaxPages: 2, Refresh: true})
+			return err
+		},
+		"GetFeedProfile": func() error {
+			_, err := c.GetFeedProfile(ctx, &FeedProfileRequest{ObjectID: "o", NonceID: "n", URL: "https://x", Fresh: true})
+			return err
+		},
+		"GetSettings":    func() error { _, err := c.GetSettings(ctx); return err },
+		"UpdateSettings": func() error { return c.UpdateSettings(ctx, &Settings{Theme: "dark"}) },
+	}
+
+	clientType := reflect.TypeOf(c)
+	for i := 0; i < clientType.NumMethod(); i++ {
+		if name := clientType.Method(i).Name; calls[name] == nil {
+			t.Errorf("client method %s is not covered by this test", name)
+		}
+	}
+
+	for name, call := range calls {
+		before := len(requests())
+		if err := call(); err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		sent := requests()[before:]
+		if len(sent) != 1 {
+			t.Errorf("%s: sent %d requests, want 1", name, len(sent))
+			continue
+		}
+		req := sent[0]
+
+		specPath, ok := matchSpecPath(doc.Paths, req.Path)
+		if !ok {
+			t.Errorf("%s: %s %s is not documented in openapi.json", name, req.Method, req.Path)
+			continue
+		}
+		op, ok := doc.Paths[specPath][strings.ToLower(req.Method)]
+		if !ok {
+			t.Errorf("%s: %s is not documented for %s", name, req.Method, specPath)
+			continue
+		}
+		documented := make(map[string]bool)
+		for _, p := range op.Parameters {
+			if p.In == "query" {
+				documented[p.Name] = true
+			}
+		}
+		for key := range req.Query {
+			if !documented[key] {
+				t.Errorf("%s: query parameter %q is not documented for %s %s", name, key, req.Method, specPath)
+			}
+		}
+		if got := req.Header.Get("X-Local-Auth"); got != "wxc_test" {
+			t.Errorf("%s: X-Local-Auth = %q, want token", name, got)
+		}
+	}
+}
+
+func TestClientRoundTrip(t *testing.T) {
+	c, requests := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/downloads":
+			io.WriteString(w, `{"success":true,"data":{"items":[{"id":"d1","title":"视频","status":"completed","fileSize":1024}],"total":11,"page":2,"pageSize":10,"totalPages":2}}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/api/queue":
+			var body struct {
+				Videos []VideoInfo `json:"videos"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			items := make([]QueueItem, len(body.Videos))
+			for i, v := range body.Videos {
+				items[i] = QueueItem{ID: "q" + v.VideoID, VideoID: v.VideoID, Title: v.Title, Status: "pending"}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "data": items})
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/search/feed/profile":
+			io.WriteString(w, `{"code":0,"message":"success","data":{"object":{"id":"o1"}}}`)
+		case r.Method == http.MethodDelete && r.URL.Path == "/api/downloads":
+			io.WriteString(w, `{"success":true,"data":{"deleted":2}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	ctx := context.Background()
+
+	page, err := c.ListDownloads(ctx, &DownloadFilter{ListOptions: ListOptions{Page: 2, PageSize: 10}, Status: "completed"})
+	if err != nil {
+		t.Fatalf("ListDownloads() error = %v", err)
+	}
+	if page.Total != 11 || page.Page != 2 || len(page.Items) != 1 || page.Items[0].ID != "d1" || page.Items[0].FileSize != 1024 {
+		t.Errorf("ListDownloads() = %+v", page)
+	}
+	if q := requests()[0].Query; q.Get("page") != "2" || q.Get("pageSize") != "10" || q.Get("status") != "completed" {
+		t.Errorf("ListDownloads() query = %v", q)
+	}
+
+	items, err := c.AddToQueue(ctx, VideoInfo{VideoID: "v1", Title: "a"}, VideoInfo{VideoID: "v2", Title: "b"})
+	if err != nil {
+		t.Fatalf("AddToQueue() error = %v", err)
+	}
+	if len(items) != 2 || items[0].ID != "qv1" || items[1].Title != "b" {
+		t.Errorf("AddToQueue() = %+v", items)
+	}
+	if ct := requests()[1].Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("AddToQueue() Content-Type = %q", ct)
+	}
+
+	profile, err := c.GetFeedProfile(ctx, &FeedProfileRequest{ObjectID: "o1"})
+	if err != nil {
+		t.Fatalf("GetFeedProfile() error = %v", err)
+	}
+	if string(profile) != `{"object":{"id":"o1"}}` {
+		t.Errorf("GetFeedProfile() = %s", profile)
+	}
+
+	deleted, err := c.DeleteDownloads(ctx, true, "d1", "d2")
+	if err != nil {
+		t.Fatalf("DeleteDownloads() error = %v", err)
+	}
+	var body struct {
+		IDs         []string `json:"ids"`
+		DeleteFiles bool     `json:"deleteFiles"`
+	}
+	json.Unmarshal(requests()[3].Body, &body)
+	if deleted != 2 || len(body.IDs) != 2 || !body.DeleteFiles {
+		t.Errorf("DeleteDownloads() = %d, request body %+v", deleted, body)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		want     APIError
+		notReady bool
+	}{
+		{"console error", http.StatusBadRequest, `{"success":false,"error":"invalid request"}`,
+			APIError{StatusCode: http.StatusBadRequest, Message: "invalid request"}, false},
+		{"console failure with 200", http.StatusOK, `{"success":false,"error":"queue item not found"}`,
+			APIError{StatusCode: http.StatusOK, Message: "queue item not found"}, false},
+		{"v1 not connected", http.StatusServiceUnavailable, `{"code":1003,"message":"no client connected"}`,
+			APIError{StatusCode: http.StatusServiceUnavailable, Code: 1003, Message: "no client connected"}, true},
+		{"v1 error code with 200", http.StatusOK, `{"code":2001,"message":"api error"}`,
+			APIError{StatusCode: http.StatusOK, Code: 2001, Message: "api error"}, false},
+		{"plain text", http.StatusBadGateway, "bad gateway\n",
+			APIError{StatusCode: http.StatusBadGateway, Message: "bad gateway"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			})
+			_, err := c.GetSettings(context.Background())
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("error = %v, want *APIError", err)
+			}
+			if *apiErr != tt.want {
+				t.Errorf("error = %+v, want %+v", *apiErr, tt.want)
+			}
+			if apiErr.NotConnected() != tt.notReady {
+				t.Errorf("NotConnected() = %v, want %v", apiErr.NotConnected(), tt.notReady)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// ListQueue 获取下载队列
+func (c *Client) ListQueue(ctx context.Context) ([]QueueItem, error) {
+	var items []QueueItem
+	if err := c.do(ctx, http.MethodGet, "/api/queue", nil, nil, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// AddToQueue 添加视频到下载队列，返回新建的队列项
+func (c *Client) AddToQueue(ctx context.Context, videos ...VideoInfo) ([]QueueItem, error) {
+	var items []QueueItem
+	body := map[string][]VideoInfo{"videos": videos}
+	if err := c.do(ctx, http.MethodPost, "/api/queue", nil, body, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// PauseQueueItem 暂停下载
+func (c *Client) PauseQueueItem(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPut, "/api/queue/"+url.PathEscape(id)+"/pause", nil, nil, nil)
+}
+
+// ResumeQueueItem 恢复下载
+func (c *Client) ResumeQueueItem(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPut, "/api/queue/"+url.PathEscape(id)+"/resume", nil, nil, nil)
+}
+
+// RemoveFromQueue 从队列移除
+func (c *Client) RemoveFromQueue(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/api/queue/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// ReorderQueue 按给定顺序重新排列队列
+func (c *Client) ReorderQueue(ctx context.Context, ids ...string) error {
+	body := map[string][]string{"ids": ids}
+	return c.do(ctx, http.MethodPut, "/api/queue/reorder", nil, body, nil)
+}
@@ -0,0 +1,105 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ListBrowse 分页列出浏览记录，query 不为空时按标题和作者搜索
+func (c *Client) ListBrowse(ctx context.Context, query string, opts *ListOptions) (*PagedResult[BrowseRecord], error) {
+	q := opts.values()
+	if query != "" {
+		q.Set("query", query)
+	}
+	var result PagedResult[BrowseRecord]
+	if err := c.do(ctx, http.MethodGet, "/api/browse", q, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetBrowse 获取单条浏览记录
+func (c *Client) GetBrowse(ctx context.Context, id string) (*BrowseRecord, error) {
+	var record BrowseRecord
+	if err := c.do(ctx, http.MethodGet, "/api/browse/"+url.PathEscape(id), nil, nil, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// GetBrowseFormats 获取视频的可选编码，quality 为空时按设置中的默认画质策略选择
+func (c *Client) GetBrowseFormats(ctx context.Context, id, quality string) (*Formats, error) {
+	q := url.Values{}
+	if quality != "" {
+		q.Set("quality", quality)
+	}
+	var formats Formats
+	if err := c.do(ctx, http.MethodGet, "/api/browse/"+url.PathEscape(id)+"/formats", q, nil, &formats); err != nil {
+		return nil, err
+	}
+	return &formats, nil
+}
+
+// DeleteBrowse 删除浏览记录（移入回收站），返回删除的数量
+func (c *Client) DeleteBrowse(ctx context.Context, ids ...string) (int64, error) {
+	var result struct {
+		Deleted int64 `json:"deleted"`
+	}
+	body := map[string][]string{"ids": ids}
+	if err := c.do(ctx, http.MethodDelete, "/api/browse", nil, body, &result); err != nil {
+		return 0, err
+	}
+	return result.Deleted, nil
+}
+
+// ClearBrowse 清空浏览记录（移入回收站）
+func (c *Client) ClearBrowse(ctx context.Context) error {
+	return c.do(ctx, http.MethodDelete, "/api/browse/clear", nil, nil, nil)
+}
+
+// ListDownloads 分页列出下载记录
+func (c *Client) ListDownloads(ctx context.Context, filter *DownloadFilter) (*PagedResult[DownloadRecord], error) {
+	var result PagedResult[DownloadRecord]
+	if err := c.do(ctx, http.MethodGet, "/api/downloads", filter.values(), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetDownload 获取单条下载记录
+func (c *Client) GetDownload(ctx context.Context, id string) (*DownloadRecord, error) {
+	var record DownloadRecord
+	if err := c.do(ctx, http.MethodGet, "/api/downloads/"+url.PathEscape(id), nil, nil, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// ProbeDownload 用 ffprobe 重新探测文件的编码信息
+func (c *Client) ProbeDownload(ctx context.Context, id string) (*DownloadRecord, error) {
+	var record DownloadRecord
+	if err := c.do(ctx, http.MethodPost, "/api/downloads/"+url.PathEscape(id)+"/probe", nil, nil, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// DeleteDownloads 删除下载记录（移入回收站），deleteFiles 表示永久删除时是否同时删除文件
+func (c *Client) DeleteDownloads(ctx context.Context, deleteFiles bool, ids ...string) (int64, error) {
+	var result struct {
+		Deleted int64 `json:"deleted"`
+	}
+	body := map[string]interface{}{"ids": ids, "deleteFiles": deleteFiles}
+	if err := c.do(ctx, http.MethodDelete, "/api/downloads", nil, body, &result); err != nil {
+		return 0, err
+	}
+	return result.Deleted, nil
+}
+
+// ClearDownloads 清空下载记录（移入回收站）
+func (c *Client) ClearDownloads(ctx context.Context, deleteFiles bool) error {
+	q := url.Values{"deleteFiles": {strconv.FormatBool(deleteFiles)}}
+	return c.do(ctx, http.MethodDelete, "/api/downloads/clear", q, nil, nil)
+}
@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Search 在浏览和下载记录中全局搜索，query 至少 2 个字符，limit 为 0 时每类返回 20 条
+func (c *Client) Search(ctx context.Context, query string, limit int) (*SearchResult, error) {
+	q := url.Values{"q": {query}}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	var result SearchResult
+	if err := c.do(ctx, http.MethodGet, "/api/search", q, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SearchContact 搜索微信视频号账号、直播或视频。
+// 没有已连接的微信客户端时返回 NotConnected 的 *APIError
+func (c *Client) SearchContact(ctx context.Context, req *SearchContactRequest) (*PageResult, error) {
+	var result PageResult
+	if err := c.do(ctx, http.MethodGet, "/api/v1/search/contact", req.values(), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetFeedList 获取账号的视频列表，用返回的 Cursor 获取下一页
+func (c *Client) GetFeedList(ctx context.Context, req *FeedListRequest) (*PageResult, error) {
+	var result PageResult
+	if err := c.do(ctx, http.MethodGet, "/api/v1/search/feed", req.values(), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetFeedProfile 获取视频详情，返回微信的原始 JSON
+func (c *Client) GetFeedProfile(ctx context.Context, req *FeedProfileRequest) (json.RawMessage, error) {
+	var result json.RawMessage
+	if err := c.do(ctx, http.MethodGet, "/api/v1/search/feed/profile", req.values(), nil, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
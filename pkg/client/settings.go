@@ -0,0 +1,21 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// GetSettings 获取设置
+func (c *Client) GetSettings(ctx context.Context) (*Settings, error) {
+	var settings Settings
+	if err := c.do(ctx, http.MethodGet, "/api/settings", nil, nil, &settings); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// UpdateSettings 更新设置，服务端校验失败时返回 400 的 *APIError。
+// 会覆盖全部字段，修改部分字段时先用 GetSettings 获取当前设置
+func (c *Client) UpdateSettings(ctx context.Context, settings *Settings) error {
+	return c.do(ctx, http.MethodPut, "/api/settings", nil, settings, nil)
+}
@@ -0,0 +1,307 @@
+package client
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// PagedResult 分页结果
+type PagedResult[T any] struct {
+	Items      []T   `json:"items"`
+	Total      int64 `json:"total"`
+	Page       int   `json:"page"`
+	PageSize   int   `json:"pageSize"`
+	TotalPages int   `json:"totalPages"`
+}
+
+// ListOptions 分页和排序参数，零值使用服务端默认值
+type ListOptions struct {
+	Page     int
+	PageSize int // 最大 100
+	SortBy   string
+	SortDesc bool
+}
+
+func (o *ListOptions) values() url.Values {
+	q := url.Values{}
+	if o == nil {
+		return q
+	}
+	if o.Page > 0 {
+		q.Set("page", strconv.Itoa(o.Page))
+	}
+	if o.PageSize > 0 {
+		q.Set("pageSize", strconv.Itoa(o.PageSize))
+	}
+	if o.SortBy != "" {
+		q.Set("sortBy", o.SortBy)
+	}
+	if o.SortDesc {
+		q.Set("sortDesc", "true")
+	}
+	return q
+}
+
+// BrowseRecord 浏览记录
+type BrowseRecord struct {
+	ID           string        `json:"id"`
+	Title        string        `json:"title"`
+	Author       string        `json:"author"`
+	AuthorID     string        `json:"authorId"`
+	Duration     int64         `json:"duration"`
+	Size         int64         `json:"size"`
+	Resolution   string        `json:"resolution"`
+	CoverURL     string        `json:"coverUrl"`
+	VideoURL     string        `json:"videoUrl"`
+	DecryptKey   string        `json:"decryptKey"`
+	BrowseTime   time.Time     `json:"browseTime"`
+	LikeCount    int64         `json:"likeCount"`
+	CommentCount int64         `json:"commentCount"`
+	FavCount     int64         `json:"favCount"`
+	ForwardCount int64         `json:"forwardCount"`
+	PageURL      string        `json:"pageUrl"`
+	NonceID      string        `json:"nonceId"`
+	CreatedAt    time.Time     `json:"createdAt"`
+	UpdatedAt    time.Time     `json:"updatedAt"`
+	Formats      []VideoFormat `json:"formats,omitempty"`
+}
+
+// VideoFormat 视频的一种可选编码
+type VideoFormat struct {
+	VideoID      string    `json:"videoId"`
+	FileFormat   string    `json:"fileFormat"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	BitRate      int64     `json:"bitRate"` // kbps
+	CodingFormat string    `json:"codingFormat"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// Formats 视频的可选编码及按画质策略选中的编码
+type Formats struct {
+	VideoID  string        `json:"videoId"`
+	Formats  []VideoFormat `json:"formats"`
+	Quality  string        `json:"quality"`
+	Selected *VideoFormat  `json:"selected"`
+}
+
+// DownloadRecord 下载记录
+type DownloadRecord struct {
+	ID               string    `json:"id"`
+	VideoID          string    `json:"videoId"`
+	Title            string    `json:"title"`
+	Author           string    `json:"author"`
+	CoverURL         string    `json:"coverUrl"`
+	Duration         int64     `json:"duration"`
+	FileSize         int64     `json:"fileSize"`
+	FilePath         string    `json:"filePath"`
+	Format           string    `json:"format"`
+	Resolution       string    `json:"resolution"`
+	Status           string    `json:"status"` // pending, in_progress, completed, failed
+	DownloadTime     time.Time `json:"downloadTime"`
+	ErrorMessage     string    `json:"errorMessage"`
+	LikeCount        int64     `json:"likeCount"`
+	CommentCount     int64     `json:"commentCount"`
+	ForwardCount     int64     `json:"forwardCount"`
+	FavCount         int64     `json:"favCount"`
+	TranscriptPath   string    `json:"transcriptPath"`
+	TranscriptStatus string    `json:"transcriptStatus"`
+	Container        string    `json:"container"`
+	VideoCodec       string    `json:"videoCodec"`
+	AudioCodec       string    `json:"audioCodec"`
+	Bitrate          int64     `json:"bitrate"`
+	FPS              float64   `json:"fps"`
+	Width            int       `json:"width"`
+	Height           int       `json:"height"`
+	MediaDuration    float64   `json:"mediaDuration"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+// DownloadFilter 下载记录的过滤条件
+type DownloadFilter struct {
+	ListOptions
+	StartDate     string // YYYY-MM-DD
+	EndDate       string // YYYY-MM-DD
+	Status        string
+	Query         string // 标题或作者关键词
+	MinResolution int    // 最低分辨率（短边像素）
+	VideoCodec    string // 如 h264、hevc
+}
+
+func (f *DownloadFilter) values() url.Values {
+	if f == nil {
+		return url.Values{}
+	}
+	q := f.ListOptions.values()
+	for key, value := range map[string]string{
+		"startDate":  f.StartDate,
+		"endDate":    f.EndDate,
+		"status":     f.Status,
+		"query":      f.Query,
+		"videoCodec": f.VideoCodec,
+	} {
+		if value != "" {
+			q.Set(key, value)
+		}
+	}
+	if f.MinResolution > 0 {
+		q.Set("minResolution", strconv.Itoa(f.MinResolution))
+	}
+	return q
+}
+
+// QueueItem 下载队列中的项目
+type QueueItem struct {
+	ID              string    `json:"id"`
+	VideoID         string    `json:"videoId"`
+	Title           string    `json:"title"`
+	Author          string    `json:"author"`
+	CoverURL        string    `json:"coverUrl"`
+	VideoURL        string    `json:"videoUrl"`
+	DecryptKey      string    `json:"decryptKey"`
+	Duration        int64     `json:"duration"`
+	Resolution      string    `json:"resolution"`
+	TotalSize       int64     `json:"totalSize"`
+	DownloadedSize  int64     `json:"downloadedSize"`
+	Status          string    `json:"status"` // pending, downloading, paused, completed, failed
+	Priority        int       `json:"priority"`
+	AddedTime       time.Time `json:"addedTime"`
+	StartTime       time.Time `json:"startTime"`
+	Speed           int64     `json:"speed"`
+	ChunkSize       int64     `json:"chunkSize"`
+	ChunksTotal     int       `json:"chunksTotal"`
+	ChunksCompleted int       `json:"chunksCompleted"`
+	RetryCount      int       `json:"retryCount"`
+	ErrorMessage    string    `json:"errorMessage"`
+	NonceID         string    `json:"nonceId"`
+	Quality         string    `json:"quality"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+// VideoInfo 添加到下载队列的视频
+type VideoInfo struct {
+	VideoID    string `json:"videoId"`
+	Title      string `json:"title"`
+	Author     string `json:"author"`
+	CoverURL   string `json:"coverUrl"`
+	VideoURL   string `json:"videoUrl"`
+	DecryptKey string `json:"decryptKey"`
+	Duration   int64  `json:"duration"`
+	Resolution string `json:"resolution"`
+	Size       int64  `json:"size"`
+	NonceID    string `json:"nonceId"`
+	Quality    string `json:"quality"` // 画质策略，为空时使用默认策略
+}
+
+// Settings 设置
+type Settings struct {
+	DownloadDir                string `json:"downloadDir"`
+	ChunkSize                  int64  `json:"chunkSize"`
+	ConcurrentLimit            int    `json:"concurrentLimit"`
+	AutoCleanupEnabled         bool   `json:"autoCleanupEnabled"`
+	AutoCleanupDays            int    `json:"autoCleanupDays"`
+	MaxRetries                 int    `json:"maxRetries"`
+	Theme                      string `json:"theme"`
+	TranscriptionEnabled       bool   `json:"transcriptionEnabled"`
+	TranscriptionAutoRun       bool   `json:"transcriptionAutoRun"`
+	WhisperServerPath          string `json:"whisperServerPath"`
+	WhisperServerPort          int    `json:"whisperServerPort"`
+	FFmpegPath                 string `json:"ffmpegPath"`
+	WhisperModelPath           string `json:"whisperModelPath"`
+	TranscriptionLanguage      string `json:"transcriptionLanguage"`
+	DeleteVideoAfterTranscript bool   `json:"deleteVideoAfterTranscript"`
+	ThumbnailEnabled           bool   `json:"thumbnailEnabled"`
+	ThumbnailKeyframes         int    `json:"thumbnailKeyframes"`
+	DownloadQuality            string `json:"downloadQuality"`
+}
+
+// SearchResult 本地全局搜索结果
+type SearchResult struct {
+	BrowseResults   []BrowseRecord   `json:"browseResults"`
+	DownloadResults []DownloadRecord `json:"downloadResults"`
+	BrowseCount     int64            `json:"browseCount"`
+	DownloadCount   int64            `json:"downloadCount"`
+	TotalCount      int64            `json:"totalCount"`
+}
+
+// SearchContactRequest 搜索微信视频号账号的参数
+type SearchContactRequest struct {
+	Keyword  string
+	Type     int    // 1=账号（默认） 2=直播 3=视频
+	Cursor   string // 上一次返回的游标
+	MaxPages int    // 连续获取的页数，默认 1
+	Refresh  bool   // 跳过缓存
+}
+
+func (r *SearchContactRequest) values() url.Values {
+	q := pageValues(r.Cursor, r.MaxPages, r.Refresh)
+	q.Set("keyword", r.Keyword)
+	if r.Type > 0 {
+		q.Set("type", strconv.Itoa(r.Type))
+	}
+	return q
+}
+
+// FeedListRequest 获取账号视频列表的参数
+type FeedListRequest struct {
+	Username string
+	Cursor   string
+	MaxPages int
+	Refresh  bool
+}
+
+func (r *FeedListRequest) values() url.Values {
+	q := pageValues(r.Cursor, r.MaxPages, r.Refresh)
+	q.Set("username", r.Username)
+	return q
+}
+
+// pageValues 微信搜索的分页参数
+func pageValues(cursor string, maxPages int, refresh bool) url.Values {
+	q := url.Values{}
+	if cursor != "" {
+		q.Set("cursor", cursor)
+	}
+	if maxPages > 0 {
+		q.Set("max_pages", strconv.Itoa(maxPages))
+	}
+	if refresh {
+		q.Set("refresh", "true")
+	}
+	return q
+}
+
+// FeedProfileRequest 获取视频详情的参数，ObjectID 和 URL 二选一
+type FeedProfileRequest struct {
+	ObjectID string
+	NonceID  string
+	URL      string
+	Fresh    bool // 跳过缓存
+}
+
+func (r *FeedProfileRequest) values() url.Values {
+	q := url.Values{}
+	for key, value := range map[string]string{"object_id": r.ObjectID, "nonce_id": r.NonceID, "url": r.URL} {
+		if value != "" {
+			q.Set(key, value)
+		}
+	}
+	if r.Fresh {
+		q.Set("fresh", "true")
+	}
+	return q
+}
+
+// PageResult 微信搜索的分页结果，列表项保持微信返回的原始 JSON
+type PageResult struct {
+	List       []json.RawMessage `json:"list"`
+	Cursor     string            `json:"cursor"` // 下一页游标，没有更多时为空
+	NextMarker string            `json:"next_marker"`
+	HasMore    bool              `json:"has_more"`
+	Pages      int               `json:"pages"`
+	Cached     bool              `json:"cached"`
+}
@@ -1239,6 +1239,7 @@
                 videoId: record.id,
                 videoUrl: record.videoUrl,
                 decryptKey: record.decryptKey || '',
+                nonceId: record.nonceId || '',
                 coverUrl: record.coverUrl || '',
                 duration: record.duration || 0,
                 resolution: record.resolution || parseResolutionFromUrl(record.videoUrl) || '',
@@ -1303,6 +1304,7 @@
             videoId: r.id,
             videoUrl: r.videoUrl,
             decryptKey: r.decryptKey || '',
+            nonceId: r.nonceId || '',
             coverUrl: r.coverUrl || '',
             duration: r.duration || 0,
             resolution: r.resolution || parseResolutionFromUrl(r.videoUrl) || '',
@@ -2548,6 +2550,7 @@
                 videoId: record.videoId || record.id,
                 videoUrl: record.videoUrl || '',
                 decryptKey: record.decryptKey || '',
+                nonceId: record.nonceId || '',
                 coverUrl: record.coverUrl || '',
                 duration: record.duration || 0,
                 resolution: record.resolution || parseResolutionFromUrl(record.videoUrl) || '',
@@ -2986,7 +2989,9 @@
                 title: item.title,
                 url: item.videoUrl,
                 authorName: item.author,
-                key: item.decryptKey  // Decrypt key for encrypted videos
+                key: item.decryptKey,  // Decrypt key for encrypted videos
+                nonceId: item.nonceId || '',
+                quality: item.quality || ''
             }];
             
             const result = await ApiClient.startBatchDownload(videos, false);
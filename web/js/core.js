@@ -7,6 +7,7 @@
 // Constants and Configuration
 // ============================================
 const STORAGE_KEY_SERVICE_URL = 'wx_channel_service_url';
+const STORAGE_KEY_API_TOKEN = 'wx_channel_api_token';
 const DEFAULT_SERVICE_URL = `${window.location.protocol}//${window.location.host}`;
 const RECONNECT_DELAYS = [1000, 2000, 4000, 8000, 16000, 30000];
 
@@ -33,6 +34,29 @@ const StorageManager = {
         }
     },
 
+    saveApiToken(token) {
+        try {
+            if (token) {
+                localStorage.setItem(STORAGE_KEY_API_TOKEN, token);
+            } else {
+                localStorage.removeItem(STORAGE_KEY_API_TOKEN);
+            }
+            return true;
+        } catch (e) {
+            console.error('Failed to save API token:', e);
+            return false;
+        }
+    },
+
+    loadApiToken() {
+        try {
+            return localStorage.getItem(STORAGE_KEY_API_TOKEN) || '';
+        } catch (e) {
+            console.error('Failed to load API token:', e);
+            return '';
+        }
+    },
+
     saveSettings(settings) {
         try {
             localStorage.setItem('wx_channel_settings', JSON.stringify(settings));
@@ -216,8 +240,17 @@ const ApiClient = {
         const url = `${ConnectionManager.serviceUrl}/api${endpoint}`;
         const options = {
             method,
-            headers: { 'Content-Type': 'application/json' }
+            headers: { 'Content-Type': 'application/json' },
+            credentials: 'same-origin'
         };
+        const token = StorageManager.loadApiToken();
+        if (token) {
+            options.headers['X-Local-Auth'] = token;
+        }
+        // 会话登录时写请求必须携带 CSRF 令牌
+        if (method !== 'GET' && SessionManager.csrfToken) {
+            options.headers['X-CSRF-Token'] = SessionManager.csrfToken;
+        }
 
         if (data && (method === 'POST' || method === 'PUT' || method === 'DELETE')) {
             options.body = JSON.stringify(data);
@@ -226,6 +259,9 @@ const ApiClient = {
         const response = await fetch(url, options);
         
         if (!response.ok) {
+            if (response.status === 401 && SessionManager.usersEnabled) {
+                SessionManager.showLogin();
+            }
             const error = await response.json().catch(() => ({ error: 'Unknown error' }));
             throw new Error(error.error || `HTTP ${response.status}`);
         }
@@ -281,6 +317,17 @@ const ApiClient = {
         return await this.request('GET', `/export/${type}?${query}`);
     },
 
+    // Comments
+    async getComments(videoId) { return await this.request('GET', `/comments?videoId=${encodeURIComponent(videoId)}`); },
+    async searchComments(q, params = {}) {
+        const query = new URLSearchParams({ ...params, q }).toString();
+        return await this.request('GET', `/comments?${query}`);
+    },
+    async getCommentAnalytics(params = {}) {
+        const query = new URLSearchParams(params).toString();
+        return await this.request('GET', `/comments/analytics?${query}`);
+    },
+
     // Search
     async search(query) { return await this.request('GET', `/search?q=${encodeURIComponent(query)}`); },
 
@@ -305,6 +352,21 @@ const ApiClient = {
         return await response.json();
     },
 
+    // Thumbnails
+    thumbnailUrl(id, name = '') {
+        const base = `${ConnectionManager.serviceUrl}/api/thumbnails/${encodeURIComponent(id)}`;
+        return this.withToken(name ? `${base}/${name}` : base);
+    },
+
+    // <img>/<video> 无法携带请求头，通过 ?token= 传递 API 令牌
+    withToken(url) {
+        const token = StorageManager.loadApiToken();
+        if (!token) return url;
+        return `${url}${url.includes('?') ? '&' : '?'}token=${encodeURIComponent(token)}`;
+    },
+    async getThumbnails(id) { return await this.request('GET', `/thumbnails/${encodeURIComponent(id)}/list`); },
+    async regenerateThumbnails(id) { return await this.request('POST', `/thumbnails/${encodeURIComponent(id)}`); },
+
     // File operations
     async openFolder(filePath) { return await this.request('POST', '/files/open-folder', { path: filePath }); },
     async playVideo(filePath) { return await this.request('POST', '/files/play', { path: filePath }); },
@@ -318,6 +380,163 @@ const ApiClient = {
     async openTranscript(id) { return await this.request('POST', `/transcribe/${id}/open`); }
 };
 
+// ============================================
+// Session Manager - 控制台用户登录与偏好
+// ============================================
+const SessionManager = {
+    user: null,
+    csrfToken: '',
+    usersEnabled: false,
+
+    // 读取当前登录用户；创建过用户但未登录时显示登录框
+    async load() {
+        try {
+            const response = await fetch(`${ConnectionManager.serviceUrl}/api/auth/me`, { credentials: 'same-origin' });
+            if (!response.ok) return;
+            const result = await response.json();
+            const data = result.data || {};
+            this.usersEnabled = !!data.usersEnabled;
+            this.setUser(data.user, data.csrfToken);
+            if (this.usersEnabled && !this.user) {
+                this.showLogin();
+            }
+        } catch (e) {
+            console.warn('Failed to load session:', e);
+        }
+    },
+
+    async login(username, password) {
+        const response = await fetch(`${ConnectionManager.serviceUrl}/api/auth/login`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            credentials: 'same-origin',
+            body: JSON.stringify({ username, password })
+        });
+        const result = await response.json().catch(() => ({}));
+        if (!response.ok) {
+            throw new Error(result.error || `HTTP ${response.status}`);
+        }
+        this.setUser(result.data.user, result.data.csrfToken);
+        this.hideLogin();
+        return this.user;
+    },
+
+    async logout() {
+        try {
+            await ApiClient.request('POST', '/auth/logout');
+        } catch (e) {
+            console.warn('Logout failed:', e);
+        }
+        this.setUser(null, '');
+        if (this.usersEnabled) {
+            this.showLogin();
+        }
+    },
+
+    setUser(user, csrfToken) {
+        this.user = user || null;
+        this.csrfToken = csrfToken || '';
+        this.applyPreferences();
+        this.updateUI();
+    },
+
+    preferences() {
+        const prefs = (this.user && this.user.preferences) || {};
+        return { theme: prefs.theme || '', pageSizes: prefs.pageSizes || {} };
+    },
+
+    // 返回页面的每页条数偏好，未设置时返回 fallback
+    pageSize(page, fallback) {
+        return this.preferences().pageSizes[page] || fallback;
+    },
+
+    // 将登录用户的主题和每页条数应用到页面
+    applyPreferences() {
+        if (!this.user) return;
+        const prefs = this.preferences();
+        if (prefs.theme && typeof applyTheme === 'function') {
+            applyTheme(prefs.theme);
+        }
+        if (typeof browseState !== 'undefined') {
+            browseState.pageSize = this.pageSize('browse', browseState.pageSize);
+            const select = document.getElementById('browsePageSize');
+            if (select) select.value = String(browseState.pageSize);
+        }
+        if (typeof downloadState !== 'undefined') {
+            downloadState.pageSize = this.pageSize('downloads', downloadState.pageSize);
+            const select = document.getElementById('downloadPageSize');
+            if (select) select.value = String(downloadState.pageSize);
+        }
+    },
+
+    // 保存偏好（只有以用户身份登录时才保存到服务端）
+    async savePreferences(changes) {
+        if (!this.user) return;
+        const prefs = this.preferences();
+        const next = {
+            theme: changes.theme !== undefined ? changes.theme : prefs.theme,
+            pageSizes: Object.assign({}, prefs.pageSizes, changes.pageSizes || {})
+        };
+        try {
+            const result = await ApiClient.request('PUT', '/auth/me/preferences', next);
+            this.user = result.data || this.user;
+        } catch (e) {
+            console.warn('Failed to save preferences:', e);
+        }
+    },
+
+    savePageSize(page, size) {
+        return this.savePreferences({ pageSizes: { [page]: parseInt(size) } });
+    },
+
+    updateUI() {
+        const panel = document.getElementById('sessionUser');
+        const name = document.getElementById('sessionUserName');
+        if (!panel || !name) return;
+        if (this.user) {
+            name.textContent = this.user.username;
+            panel.style.display = 'flex';
+        } else {
+            panel.style.display = 'none';
+        }
+    },
+
+    showLogin() {
+        const overlay = document.getElementById('loginOverlay');
+        if (!overlay || overlay.classList.contains('active')) return;
+        overlay.classList.add('active');
+        const input = document.getElementById('loginUsername');
+        if (input) input.focus();
+    },
+
+    hideLogin() {
+        const overlay = document.getElementById('loginOverlay');
+        if (overlay) overlay.classList.remove('active');
+        const error = document.getElementById('loginError');
+        if (error) error.textContent = '';
+    }
+};
+
+// 登录表单提交
+async function submitLogin(event) {
+    event.preventDefault();
+    const username = document.getElementById('loginUsername').value.trim();
+    const passwordInput = document.getElementById('loginPassword');
+    const error = document.getElementById('loginError');
+    error.textContent = '';
+    try {
+        await SessionManager.login(username, passwordInput.value);
+        passwordInput.value = '';
+        showMessage(`欢迎，${username}`, 'success');
+        if (typeof loadPageData === 'function' && typeof currentPage !== 'undefined') {
+            loadPageData(currentPage);
+        }
+    } catch (e) {
+        error.textContent = e.message === 'invalid username or password' ? '用户名或密码错误'
+            : e.message.startsWith('too many') ? '登录失败次数过多，请稍后再试' : e.message;
+    }
+}
+
 // ============================================
 // WebSocket Client
 // ============================================
@@ -401,4 +620,62 @@ const WebSocketClient = {
     onStatsUpdate(callback) { this.callbacks.statsUpdate.push(callback); }
 };
 
+// ============================================
+// Capability Monitor - 注入脚本自检
+// ============================================
+const CapabilityMonitor = {
+    timer: null,
+    interval: 30000,
+
+    start() {
+        this.stop();
+        this.check();
+        this.timer = setInterval(() => this.check(), this.interval);
+    },
+
+    stop() {
+        if (this.timer) {
+            clearInterval(this.timer);
+            this.timer = null;
+        }
+    },
+
+    async check() {
+        try {
+            const result = await ApiClient.request('GET', '/v1/status');
+            this.render(result.data || {});
+        } catch (e) {
+            console.warn('Capability check failed:', e);
+        }
+    },
+
+    render(status) {
+        const banner = document.getElementById('capabilityBanner');
+        const detail = document.getElementById('capabilityBannerDetail');
+        if (!banner || !detail) return;
+
+        if (!status.degraded) {
+            banner.style.display = 'none';
+            return;
+        }
+
+        const pages = (status.capabilities && status.capabilities.pages) || [];
+        const lines = pages.filter(p => p.degraded).map(p => {
+            const missing = [].concat(p.missing_apis || [], (p.missing_hooks || []).map(h => 'hook:' + h));
+            return `${p.page}：${missing.join('、')}`;
+        });
+        detail.textContent = lines.join('；');
+        banner.style.display = 'flex';
+    }
+};
+
+ConnectionManager.onStatusChange((status) => {
+    if (status === 'connected') {
+        SessionManager.load();
+        CapabilityMonitor.start();
+    } else {
+        CapabilityMonitor.stop();
+    }
+});
+
 console.log('Core module loaded');
@@ -236,6 +236,7 @@ function goToBrowsePage(page) {
 function changeBrowsePageSize(size) {
     browseState.pageSize = parseInt(size);
     browseState.currentPage = 1;
+    SessionManager.savePageSize('browse', size);
     loadBrowseHistory();
 }
 
@@ -466,6 +467,7 @@ async function downloadBrowseRecord(id) {
             videoId: record.id,
             videoUrl: record.videoUrl,
             decryptKey: record.decryptKey || '',
+            nonceId: record.nonceId || '',
             coverUrl: record.coverUrl || '',
             duration: record.duration || 0,
             resolution: record.resolution || parseResolutionFromUrl(record.videoUrl) || '',
@@ -530,6 +532,7 @@ async function downloadSelectedBrowse() {
         videoId: r.id,
         videoUrl: r.videoUrl,
         decryptKey: r.decryptKey || '',
+        nonceId: r.nonceId || '',
         coverUrl: r.coverUrl || '',
         duration: r.duration || 0,
         resolution: r.resolution || parseResolutionFromUrl(r.videoUrl) || '',
@@ -19,6 +19,7 @@ const downloadState = {
     totalCount: 0,
     totalPages: 0,
     statusFilter: '',
+    resolutionFilter: '',
     dateStart: '',
     dateEnd: '',
     selectedIds: new Set(),
@@ -42,6 +43,11 @@ async function loadDownloadRecords() {
         if (downloadState.statusFilter) {
             params.status = downloadState.statusFilter;
         }
+
+        // Add resolution filter (based on ffprobe metadata)
+        if (downloadState.resolutionFilter) {
+            params.minResolution = downloadState.resolutionFilter;
+        }
         
         // Add date range filter - Requirements: 2.3
         if (downloadState.dateStart) {
@@ -99,9 +105,14 @@ function renderDownloadTable() {
         const statusClass = record.status || 'completed';
         const statusText = getStatusText(record.status);
         
-        // 封面图处理
-        const thumbnail = record.coverUrl 
-            ? `<img class="table-thumbnail" src="${escapeHtml(record.coverUrl)}" alt="" onerror="this.style.display='none';this.nextElementSibling.style.display='flex'"><div class="table-thumbnail-placeholder" style="display:none"><svg viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2"><path d="M14 2H6a2 2 0 0 0-2 2v16a2 2 0 0 0 2 2h12a2 2 0 0 0 2-2V8z"/><polyline points="14 2 14 8 20 8"/></svg></div>`
+        // 封面图处理：已完成的下载优先使用本地缩略图，失败时回退到远程封面
+        const localThumb = record.status === 'completed' ? ApiClient.thumbnailUrl(record.id) : '';
+        const thumbSrc = localThumb || record.coverUrl;
+        const thumbFallback = localThumb && record.coverUrl
+            ? `if(!this.dataset.fallback){this.dataset.fallback=1;this.src='${escapeHtml(record.coverUrl)}';return;}`
+            : '';
+        const thumbnail = thumbSrc 
+            ? `<img class="table-thumbnail" src="${escapeHtml(thumbSrc)}" alt="" onerror="${thumbFallback}this.style.display='none';this.nextElementSibling.style.display='flex'"><div class="table-thumbnail-placeholder" style="display:none"><svg viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2"><path d="M14 2H6a2 2 0 0 0-2 2v16a2 2 0 0 0 2 2h12a2 2 0 0 0 2-2V8z"/><polyline points="14 2 14 8 20 8"/></svg></div>`
             : `<div class="table-thumbnail-placeholder"><svg viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2"><path d="M14 2H6a2 2 0 0 0-2 2v16a2 2 0 0 0 2 2h12a2 2 0 0 0 2-2V8z"/><polyline points="14 2 14 8 20 8"/></svg></div>`;
         
         // 构建视频信息摘要
@@ -278,12 +289,14 @@ function goToDownloadPage(page) {
 function changeDownloadPageSize(size) {
     downloadState.pageSize = parseInt(size);
     downloadState.currentPage = 1;
+    SessionManager.savePageSize('downloads', size);
     loadDownloadRecords();
 }
 
 // Filter downloads - Requirements: 2.3, 2.4
 function filterDownloads() {
     downloadState.statusFilter = document.getElementById('downloadStatusFilter').value;
+    downloadState.resolutionFilter = document.getElementById('downloadResolutionFilter').value;
     downloadState.dateStart = document.getElementById('downloadDateStart').value;
     downloadState.dateEnd = document.getElementById('downloadDateEnd').value;
     downloadState.currentPage = 1;
@@ -292,15 +305,17 @@ function filterDownloads() {
 
 // Check if any filters are active
 function hasDownloadFilters() {
-    return downloadState.statusFilter || downloadState.dateStart || downloadState.dateEnd;
+    return downloadState.statusFilter || downloadState.resolutionFilter || downloadState.dateStart || downloadState.dateEnd;
 }
 
 // Clear all filters
 function clearDownloadFilters() {
     document.getElementById('downloadStatusFilter').value = '';
+    document.getElementById('downloadResolutionFilter').value = '';
     document.getElementById('downloadDateStart').value = '';
     document.getElementById('downloadDateEnd').value = '';
     downloadState.statusFilter = '';
+    downloadState.resolutionFilter = '';
     downloadState.dateStart = '';
     downloadState.dateEnd = '';
     downloadState.currentPage = 1;
@@ -388,6 +403,15 @@ function renderDownloadDetailPanel(record) {
                     <span class="video-detail-meta-label">格式</span>
                     <span class="video-detail-meta-value">${escapeHtml(record.format || '-')}</span>
                 </div>
+                ${record.videoCodec ? `
+                <div class="video-detail-meta-item">
+                    <span class="video-detail-meta-label">编码</span>
+                    <span class="video-detail-meta-value">${escapeHtml([record.videoCodec, record.audioCodec].filter(Boolean).join(' / '))}</span>
+                </div>
+                <div class="video-detail-meta-item">
+                    <span class="video-detail-meta-label">码率 / 帧率</span>
+                    <span class="video-detail-meta-value">${record.bitrate ? (record.bitrate / 1000).toFixed(0) + ' kbps' : '-'} / ${record.fps ? record.fps + ' fps' : '-'}</span>
+                </div>` : ''}
                 <div class="video-detail-meta-item">
                     <span class="video-detail-meta-label">下载时间</span>
                     <span class="video-detail-meta-value">${record.downloadTime ? formatDateTime(record.downloadTime) : '-'}</span>
@@ -690,6 +714,7 @@ async function retryDownload(id) {
             videoId: record.videoId || record.id,
             videoUrl: record.videoUrl || '',
             decryptKey: record.decryptKey || '',
+            nonceId: record.nonceId || '',
             coverUrl: record.coverUrl || '',
             duration: record.duration || 0,
             resolution: record.resolution || parseResolutionFromUrl(record.videoUrl) || '',
@@ -1128,7 +1153,9 @@ async function startQueueItemDownload(id) {
             title: item.title,
             url: item.videoUrl,
             authorName: item.author,
-            key: item.decryptKey  // Decrypt key for encrypted videos
+            key: item.decryptKey,  // Decrypt key for encrypted videos
+            nonceId: item.nonceId || '',
+            quality: item.quality || ''
         }];
         
         const result = await ApiClient.startBatchDownload(videos, false);
@@ -35,6 +35,7 @@ async function loadSettings() {
     // Load service URL from localStorage - Requirements: 13.7
     const savedUrl = StorageManager.loadServiceUrl();
     document.getElementById('settingServiceUrl').value = savedUrl;
+    document.getElementById('settingApiToken').value = StorageManager.loadApiToken();
 
     // Initialize auto cleanup days visibility
     toggleAutoCleanupDays();
@@ -48,6 +49,7 @@ async function loadSettings() {
                 document.getElementById('settingDownloadDir').value = settings.downloadDir || '';
                 document.getElementById('settingChunkSize').value = (settings.chunkSize || 10485760) / 1048576;
                 document.getElementById('settingConcurrentLimit').value = settings.concurrentLimit || 3;
+                document.getElementById('settingDownloadQuality').value = settings.downloadQuality || 'original';
                 document.getElementById('settingAutoCleanup').checked = settings.autoCleanupEnabled || false;
                 document.getElementById('settingAutoCleanupDays').value = settings.autoCleanupDays || 30;
                 toggleAutoCleanupDays();
@@ -288,13 +290,14 @@ async function saveServiceUrl() {
     
     // Save to localStorage - Requirements: 13.7
     StorageManager.saveServiceUrl(serviceUrl);
+    StorageManager.saveApiToken(document.getElementById('settingApiToken').value.trim());
     
     // If URL changed, reconnect
     if (serviceUrl !== ConnectionManager.serviceUrl) {
         ConnectionManager.connect(serviceUrl);
     }
     
-    showMessage('服务地址已保存', 'success');
+    showMessage('连接设置已保存', 'success');
 }
 
 // Save download settings - Requirements: 11.1, 11.2, 11.3, 11.4, 11.6
@@ -322,7 +325,8 @@ async function saveDownloadSettings() {
             ...currentSettings.data,
             downloadDir: document.getElementById('settingDownloadDir').value.trim(),
             chunkSize: parseInt(document.getElementById('settingChunkSize').value) * 1048576, // Convert MB to bytes
-            concurrentLimit: parseInt(document.getElementById('settingConcurrentLimit').value)
+            concurrentLimit: parseInt(document.getElementById('settingConcurrentLimit').value),
+            downloadQuality: document.getElementById('settingDownloadQuality').value
         };
         
         await ApiClient.updateSettings(settings);
@@ -498,6 +502,7 @@ async function resetSettings() {
     document.getElementById('settingDownloadDir').value = '';
     document.getElementById('settingChunkSize').value = 10;
     document.getElementById('settingConcurrentLimit').value = 3;
+    document.getElementById('settingDownloadQuality').value = 'original';
     document.getElementById('settingAutoCleanup').checked = false;
     document.getElementById('settingAutoCleanupDays').value = 30;
     
@@ -393,20 +393,26 @@ console.log('UI module loaded');
 // Theme Toggle - 主题切换
 // ============================================
 function toggleTheme() {
-    const html = document.documentElement;
-    const currentTheme = html.getAttribute('data-theme') || 'light';
+    const currentTheme = document.documentElement.getAttribute('data-theme') || 'light';
     const newTheme = currentTheme === 'light' ? 'dark' : 'light';
     
-    html.setAttribute('data-theme', newTheme);
-    localStorage.setItem('theme', newTheme);
-    
-    // 更新图标和文字
-    updateThemeUI(newTheme);
+    applyTheme(newTheme);
+    // 以用户身份登录时同步保存到用户偏好
+    SessionManager.savePreferences({ theme: newTheme });
     
     // 显示提示
     showMessage(`已切换到${newTheme === 'light' ? '浅色' : '深色'}模式`, 'success');
 }
 
+// 应用主题并保存到本地
+function applyTheme(theme) {
+    document.documentElement.setAttribute('data-theme', theme);
+    localStorage.setItem('theme', theme);
+    
+    // 更新图标和文字
+    updateThemeUI(theme);
+}
+
 function updateThemeUI(theme) {
     const lightIcon = document.querySelector('.theme-icon-light');
     const darkIcon = document.querySelector('.theme-icon-dark');
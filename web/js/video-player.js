@@ -206,7 +206,7 @@ function openVideoPlayer(videoSource, title, isLocalFile = false) {
     if (isLocalFile) {
         // For local files, we need to use a special API endpoint
         // The backend should serve the file through an API
-        video.src = `${ConnectionManager.serviceUrl}/api/video/stream?path=${encodeURIComponent(videoSource)}`;
+        video.src = ApiClient.withToken(`${ConnectionManager.serviceUrl}/api/video/stream?path=${encodeURIComponent(videoSource)}`);
     } else {
         video.src = videoSource;
     }